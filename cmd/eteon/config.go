@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"os/signal"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"eteonbot/internal/app"
+)
+
+// fileConfig is the YAML config file. Every setting also has an environment
+// variable, which wins over the file; the file only fills in what the
+// environment leaves unset.
+type fileConfig struct {
+	Telegram struct {
+		Token     string `yaml:"token"`      // TELEGRAM_BOT_TOKEN
+		TestToken string `yaml:"test_token"` // TELEGRAM_TEST_BOT_TOKEN
+	} `yaml:"telegram"`
+	Gemini struct {
+		APIKey        string `yaml:"api_key"`        // GEMINI_API_KEY
+		TestAPIKey    string `yaml:"test_api_key"`   // GEMINI_TEST_API_KEY
+		Model         string `yaml:"model"`          // GEMINI_MODEL
+		FallbackModel string `yaml:"fallback_model"` // GEMINI_FALLBACK_MODEL
+		Thinking      string `yaml:"thinking"`       // THINKING_MODE
+		SystemPrompt  string `yaml:"system_prompt"`  // SYSTEM_PROMPT
+	} `yaml:"gemini"`
+	// RateLimit is in updates per user and minute.
+	RateLimit int `yaml:"rate_limit"` // RATE_LIMIT
+	// Load sets when new requests go light: a smaller model, less thinking.
+	Load struct {
+		SheddingDepth    int     `yaml:"shedding_depth"`     // LOAD_SHEDDING_DEPTH
+		MonthlyBudgetUSD float64 `yaml:"monthly_budget_usd"` // MONTHLY_BUDGET_USD
+		// Daily limits make requests go light, or refuse them with
+		// over_budget: refuse, until the day ends.
+		DailyBudgetUSD     float64 `yaml:"daily_budget_usd"`      // DAILY_BUDGET_USD
+		UserDailyBudgetUSD float64 `yaml:"user_daily_budget_usd"` // USER_DAILY_BUDGET_USD
+		OverBudget         string  `yaml:"over_budget"`           // OVER_BUDGET
+		// ConcurrentCalls caps the model calls running at once; the others
+		// wait in line.
+		ConcurrentCalls int `yaml:"concurrent_calls"` // MAX_CONCURRENT_CALLS
+	} `yaml:"load"`
+	Allow struct {
+		Users []int64 `yaml:"users"` // ALLOWED_USER_IDS
+		Chats []int64 `yaml:"chats"` // ALLOWED_CHAT_IDS
+	} `yaml:"allow"`
+	Admins struct {
+		Users           []int64 `yaml:"users"`            // ADMIN_USER_IDS
+		Chat            int64   `yaml:"chat"`             // ADMIN_CHAT_ID
+		SentimentAlerts bool    `yaml:"sentiment_alerts"` // SENTIMENT_ALERTS
+	} `yaml:"admins"`
+	Webhook struct {
+		URL    string `yaml:"url"`    // WEBHOOK_URL
+		Listen string `yaml:"listen"` // WEBHOOK_LISTEN
+		Secret string `yaml:"secret"` // WEBHOOK_SECRET
+	} `yaml:"webhook"`
+	// Control is the gRPC control API for orchestration tooling.
+	Control struct {
+		Listen string `yaml:"listen"` // CONTROL_LISTEN
+		Token  string `yaml:"token"`  // CONTROL_TOKEN
+	} `yaml:"control"`
+	Tools struct {
+		Disabled          []string `yaml:"disabled"`            // DISABLED_TOOLS
+		Routing           string   `yaml:"routing"`             // TOOL_ROUTING
+		UserAgent         string   `yaml:"user_agent"`          // TOOLS_USER_AGENT
+		OpenWeatherAPIKey string   `yaml:"openweather_api_key"` // OPENWEATHER_API_KEY
+		ActionsFile       string   `yaml:"actions_file"`        // ACTIONS_FILE
+	} `yaml:"tools"`
+	PII struct {
+		Detectors     []string `yaml:"detectors"`      // PII_DETECTORS
+		KeepOriginals bool     `yaml:"keep_originals"` // PII_KEEP_ORIGINALS
+	} `yaml:"pii"`
+	Reply struct {
+		Hooks         []string `yaml:"hooks"`          // REPLY_HOOKS
+		LinkShortener string   `yaml:"link_shortener"` // LINK_SHORTENER_URL
+	} `yaml:"reply"`
+	Storage struct {
+		DataDir            string  `yaml:"data_dir"`                 // DATA_DIR
+		TestDataDir        string  `yaml:"test_data_dir"`            // TEST_DATA_DIR
+		PrefsEncryptionKey string  `yaml:"prefs_encryption_key"`     // PREFS_ENCRYPTION_KEY
+		RedisURL           string  `yaml:"redis_url"`                // REDIS_URL
+		SQLitePath         string  `yaml:"sqlite_path"`              // SQLITE_PATH
+		TestSQLitePath     string  `yaml:"test_sqlite_path"`         // TEST_SQLITE_PATH
+		InactiveChatGrace  float64 `yaml:"inactive_chat_grace_days"` // INACTIVE_CHAT_GRACE_DAYS
+	} `yaml:"storage"`
+	InlineMediaLimit float64 `yaml:"inline_media_limit_mb"` // INLINE_MEDIA_LIMIT_MB
+	GroupContext     bool    `yaml:"group_context"`         // GROUP_CONTEXT
+	Log              struct {
+		Level  string `yaml:"level"`  // LOG_LEVEL
+		Format string `yaml:"format"` // LOG_FORMAT
+	} `yaml:"log"`
+	// Pipelines can only be set in the file.
+	Pipelines []pipelineConfig `yaml:"pipelines"`
+	// Providers add model backends besides Gemini. OPENAI_API_KEY,
+	// ANTHROPIC_API_KEY and OLLAMA_URL fill in the one named after their kind.
+	Providers []providerConfig `yaml:"providers"`
+}
+
+// providerConfig is a model backend besides Gemini; see app.ProviderConfig.
+type providerConfig struct {
+	Name       string `yaml:"name"`
+	Kind       string `yaml:"kind"`
+	BaseURL    string `yaml:"base_url"`
+	APIKey     string `yaml:"api_key"`
+	Thinking   *bool  `yaml:"thinking"`
+	Tools      *bool  `yaml:"tools"`
+	Multimodal *bool  `yaml:"multimodal"`
+}
+
+// providerShortcuts are the environment variables that configure the
+// provider named after their kind.
+var providerShortcuts = []struct {
+	kind, env string
+	isURL     bool
+}{
+	{"openai", "OPENAI_API_KEY", false},
+	{"anthropic", "ANTHROPIC_API_KEY", false},
+	{"ollama", "OLLAMA_URL", true},
+}
+
+// providers converts the providers of the file for app.Config and applies
+// the environment variables of providerShortcuts, adding the provider when
+// the file has none of that name.
+func (fc fileConfig) providers() []app.ProviderConfig {
+	var providers []app.ProviderConfig
+	for _, p := range fc.Providers {
+		providers = append(providers, app.ProviderConfig{
+			Name: p.Name, Kind: p.Kind, BaseURL: p.BaseURL, APIKey: p.APIKey,
+			Thinking: p.Thinking, Tools: p.Tools, Multimodal: p.Multimodal,
+		})
+	}
+	for _, sc := range providerShortcuts {
+		value := strings.TrimSpace(os.Getenv(sc.env))
+		if value == "" {
+			continue
+		}
+		i := slices.IndexFunc(providers, func(p app.ProviderConfig) bool {
+			return p.Name == sc.kind || (p.Name == "" && p.Kind == sc.kind)
+		})
+		if i < 0 {
+			providers = append(providers, app.ProviderConfig{Kind: sc.kind})
+			i = len(providers) - 1
+		}
+		if sc.isURL {
+			providers[i].BaseURL = value
+		} else {
+			providers[i].APIKey = value
+		}
+	}
+	return providers
+}
+
+// pipelineConfig is a prompt chain run by its own command; see
+// app.PipelineConfig.
+type pipelineConfig struct {
+	Command     string `yaml:"command"`
+	Title       string `yaml:"title"`
+	Instruction string `yaml:"instruction"`
+	Output      string `yaml:"output"`
+	Steps       []struct {
+		Title  string   `yaml:"title"`
+		Prompt string   `yaml:"prompt"`
+		Model  string   `yaml:"model"`
+		Tools  []string `yaml:"tools"`
+	} `yaml:"steps"`
+}
+
+// pipelines converts the pipelines of the file for app.Config.
+func (fc fileConfig) pipelines() []app.PipelineConfig {
+	var pipelines []app.PipelineConfig
+	for _, p := range fc.Pipelines {
+		pc := app.PipelineConfig{Command: p.Command, Title: p.Title, Instruction: p.Instruction, Output: p.Output}
+		for _, step := range p.Steps {
+			pc.Steps = append(pc.Steps, app.PipelineStepConfig{Title: step.Title, Prompt: step.Prompt, Model: step.Model, Tools: step.Tools})
+		}
+		pipelines = append(pipelines, pc)
+	}
+	return pipelines
+}
+
+// loadConfigFile reads and checks the config file at path; an empty path
+// yields an empty config.
+func loadConfigFile(path string) (fileConfig, error) {
+	var fc fileConfig
+	if path == "" {
+		return fc, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fc, fmt.Errorf("read config: %w", err)
+	}
+	if fc, err = parseConfig(data); err != nil {
+		return fc, fmt.Errorf("config %s: %w", path, err)
+	}
+	return fc, nil
+}
+
+// parseConfig decodes a config file. Its errors name the offending field by
+// its path in the file, such as gemini.thinking.
+func parseConfig(data []byte) (fileConfig, error) {
+	var fc fileConfig
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return fc, err
+	}
+	paths := make(map[int]string)
+	fieldPaths(&root, "", paths)
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&fc); err != nil && !errors.Is(err, io.EOF) {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return fc, err
+		}
+		var errs []error
+		for _, msg := range typeErr.Errors {
+			errs = append(errs, fieldError(msg, paths))
+		}
+		return fc, errors.Join(errs...)
+	}
+	return fc, fc.validate()
+}
+
+// fieldPaths maps the lines of the keys and list items under node to their
+// dotted paths.
+func fieldPaths(node *yaml.Node, prefix string, paths map[int]string) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			fieldPaths(child, prefix, paths)
+		}
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			path := key.Value
+			if prefix != "" {
+				path = prefix + "." + key.Value
+			}
+			paths[key.Line] = path
+			fieldPaths(value, path, paths)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			if _, ok := paths[item.Line]; !ok {
+				paths[item.Line] = fmt.Sprintf("%s[%d]", prefix, i)
+			}
+			fieldPaths(item, fmt.Sprintf("%s[%d]", prefix, i), paths)
+		}
+	}
+}
+
+var (
+	yamlLineError  = regexp.MustCompile(`^line (\d+): (.*)$`)
+	yamlFieldError = regexp.MustCompile(`^field \S+ not found in type`)
+)
+
+// fieldError rewrites a decoder error such as "line 3: cannot unmarshal ..."
+// to name the field on that line.
+func fieldError(msg string, paths map[int]string) error {
+	m := yamlLineError.FindStringSubmatch(msg)
+	if m == nil {
+		return errors.New(msg)
+	}
+	line, _ := strconv.Atoi(m[1])
+	path, ok := paths[line]
+	if !ok {
+		return errors.New(msg)
+	}
+	if yamlFieldError.MatchString(m[2]) {
+		return fmt.Errorf("%s: unknown field", path)
+	}
+	return fmt.Errorf("%s: %s", path, m[2])
+}
+
+// validate checks the values the types alone do not.
+func (fc fileConfig) validate() error {
+	var errs []error
+	switch fc.Gemini.Thinking {
+	case "", "low", "medium", "high", "dynamic":
+	default:
+		errs = append(errs, fmt.Errorf("gemini.thinking: unknown mode %q, want low, medium, high or dynamic", fc.Gemini.Thinking))
+	}
+	if fc.RateLimit < 0 {
+		errs = append(errs, fmt.Errorf("rate_limit: %d is negative", fc.RateLimit))
+	}
+	if fc.Load.MonthlyBudgetUSD < 0 {
+		errs = append(errs, fmt.Errorf("load.monthly_budget_usd: %g is negative", fc.Load.MonthlyBudgetUSD))
+	}
+	if fc.Load.DailyBudgetUSD < 0 {
+		errs = append(errs, fmt.Errorf("load.daily_budget_usd: %g is negative", fc.Load.DailyBudgetUSD))
+	}
+	if fc.Load.UserDailyBudgetUSD < 0 {
+		errs = append(errs, fmt.Errorf("load.user_daily_budget_usd: %g is negative", fc.Load.UserDailyBudgetUSD))
+	}
+	switch fc.Load.OverBudget {
+	case "", "light", "refuse":
+	default:
+		errs = append(errs, fmt.Errorf("load.over_budget: unknown action %q, want light or refuse", fc.Load.OverBudget))
+	}
+	switch fc.Tools.Routing {
+	case "", "model", "functions", "builtin":
+	default:
+		errs = append(errs, fmt.Errorf("tools.routing: unknown routing %q, want model, functions or builtin", fc.Tools.Routing))
+	}
+	if raw := fc.Webhook.URL; raw != "" {
+		if u, err := url.Parse(raw); err != nil || u.Scheme != "https" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("webhook.url: %q is not an https URL", raw))
+		}
+	}
+	if listen := fc.Webhook.Listen; listen != "" {
+		if _, _, err := net.SplitHostPort(listen); err != nil {
+			errs = append(errs, fmt.Errorf("webhook.listen: %q is not a host:port address", listen))
+		}
+	}
+	for i, name := range fc.Tools.Disabled {
+		if strings.TrimSpace(name) == "" {
+			errs = append(errs, fmt.Errorf("tools.disabled[%d]: empty tool name", i))
+		}
+	}
+	switch strings.ToLower(fc.Log.Level) {
+	case "", "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("log.level: unknown level %q, want debug, info, warn or error", fc.Log.Level))
+	}
+	switch strings.ToLower(fc.Log.Format) {
+	case "", "text", "json":
+	default:
+		errs = append(errs, fmt.Errorf("log.format: unknown format %q, want text or json", fc.Log.Format))
+	}
+	for i, p := range fc.Providers {
+		switch p.Kind {
+		case "openai", "anthropic", "ollama":
+		default:
+			errs = append(errs, fmt.Errorf("providers[%d].kind: unknown kind %q, want openai, anthropic or ollama", i, p.Kind))
+		}
+		if raw := p.BaseURL; raw != "" {
+			if u, err := url.Parse(raw); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+				errs = append(errs, fmt.Errorf("providers[%d].base_url: %q is not an http or https URL", i, raw))
+			}
+		}
+	}
+	for i, p := range fc.Pipelines {
+		if strings.TrimSpace(p.Command) == "" {
+			errs = append(errs, fmt.Errorf("pipelines[%d].command: missing", i))
+		}
+		switch p.Output {
+		case "", "document", "message":
+		default:
+			errs = append(errs, fmt.Errorf("pipelines[%d].output: unknown output %q, want document or message", i, p.Output))
+		}
+		if len(p.Steps) == 0 {
+			errs = append(errs, fmt.Errorf("pipelines[%d].steps: missing", i))
+		}
+		for j, step := range p.Steps {
+			if strings.TrimSpace(step.Prompt) == "" {
+				errs = append(errs, fmt.Errorf("pipelines[%d].steps[%d].prompt: missing", i, j))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// configPollInterval is how often watchConfig looks for changes to the file.
+const configPollInterval = 5 * time.Second
+
+// watchConfig reloads the configuration on SIGHUP and, when there is a config
+// file, whenever the file changes, until ctx ends. Only the tunables App.Reload
+// covers take effect; the .env file is read at startup only.
+func watchConfig(ctx context.Context, bot *app.App, path string) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var poll <-chan time.Time
+	last := fileStamp(path)
+	if path != "" {
+		ticker := time.NewTicker(configPollInterval)
+		defer ticker.Stop()
+		poll = ticker.C
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			slog.Info("reloading on SIGHUP", "result", bot.Reload())
+		case <-poll:
+			stamp := fileStamp(path)
+			if stamp == last {
+				continue
+			}
+			last = stamp
+			slog.Info("config file changed, reloading", "path", path, "result", bot.Reload())
+		}
+	}
+}
+
+// fileStamp identifies the version of the file at path by its size and
+// modification time, or is empty when the file cannot be read.
+func fileStamp(path string) string {
+	info, err := os.Stat(path)
+	if err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%d/%d", info.Size(), info.ModTime().UnixNano())
+}
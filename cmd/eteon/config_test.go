@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	fc, err := parseConfig([]byte(`
+gemini:
+  model: gemini-2.5-flash
+  thinking: high
+rate_limit: 20
+allow:
+  users: [1, 2]
+tools:
+  disabled:
+    - code_execution
+`))
+	if err != nil {
+		t.Fatalf("parseConfig: %v", err)
+	}
+	if fc.Gemini.Model != "gemini-2.5-flash" || fc.Gemini.Thinking != "high" || fc.RateLimit != 20 || len(fc.Allow.Users) != 2 || fc.Tools.Disabled[0] != "code_execution" {
+		t.Errorf("parsed %+v", fc)
+	}
+
+	if _, err := parseConfig(nil); err != nil {
+		t.Errorf("empty file: %v", err)
+	}
+}
+
+func TestParseConfigNamesField(t *testing.T) {
+	cases := map[string]string{
+		"gemini:\n  modle: x\n":                                          "gemini.modle: unknown field",
+		"rate_limit: lots\n":                                             "rate_limit: cannot unmarshal",
+		"allow:\n  users:\n    - 1\n    - me\n":                          "allow.users[1]: cannot unmarshal",
+		"gemini:\n  thinking: extreme\n":                                 "gemini.thinking: unknown mode",
+		"webhook:\n  url: http://example.com\n":                          "webhook.url:",
+		"pipelines:\n  - command: notes\n    steps:\n      - promt: x\n": "pipelines[0].steps[0].promt: unknown field",
+		"pipelines:\n  - command: notes\n    steps:\n      - title: x\n": "pipelines[0].steps[0].prompt: missing",
+	}
+	for doc, want := range cases {
+		_, err := parseConfig([]byte(doc))
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("parseConfig(%q) = %v, want an error with %q", doc, err, want)
+		}
+	}
+}
+
+func TestExampleConfigParses(t *testing.T) {
+	data, err := os.ReadFile("eteon.example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parseConfig(data); err != nil {
+		t.Errorf("example config: %v", err)
+	}
+}
+
+func TestProvidersFromEnvironment(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-env")
+	t.Setenv("OLLAMA_URL", "http://gpu:11434/v1")
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	fc, err := parseConfig([]byte("providers:\n  - kind: openai\n    api_key: sk-file\n    tools: false\n"))
+	if err != nil {
+		t.Fatalf("parseConfig: %v", err)
+	}
+	providers := fc.providers()
+	if len(providers) != 2 {
+		t.Fatalf("providers = %+v, want openai and ollama", providers)
+	}
+	if p := providers[0]; p.APIKey != "sk-env" || p.Tools == nil || *p.Tools {
+		t.Errorf("openai = %+v, want the key of the environment and tools off", p)
+	}
+	if p := providers[1]; p.Kind != "ollama" || p.BaseURL != "http://gpu:11434/v1" {
+		t.Errorf("ollama = %+v", p)
+	}
+}
+
+func TestBuildConfigRejectsMalformedEnvironment(t *testing.T) {
+	t.Setenv("RATE_LIMIT", "ten")
+	t.Setenv("GROUP_CONTEXT", "yes please")
+	t.Setenv("ADMIN_CHAT_ID", "-100x")
+	t.Setenv("ALLOWED_USER_IDS", "1, two")
+	t.Setenv("MONTHLY_BUDGET_USD", "5")
+	_, err := buildConfig("prod", fileConfig{})
+	if err == nil {
+		t.Fatal("buildConfig accepted malformed variables")
+	}
+	for _, key := range []string{"RATE_LIMIT", "GROUP_CONTEXT", "ADMIN_CHAT_ID", "ALLOWED_USER_IDS"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("error %q does not name %s", err, key)
+		}
+	}
+	if strings.Contains(err.Error(), "MONTHLY_BUDGET_USD") {
+		t.Errorf("error %q names a valid variable", err)
+	}
+
+	t.Setenv("RATE_LIMIT", "10")
+	t.Setenv("GROUP_CONTEXT", "false")
+	t.Setenv("ADMIN_CHAT_ID", "-100")
+	t.Setenv("ALLOWED_USER_IDS", "1, 2")
+	cfg, err := buildConfig("prod", fileConfig{GroupContext: true})
+	if err != nil {
+		t.Fatalf("buildConfig: %v", err)
+	}
+	if cfg.RateLimit != 10 || cfg.GroupContext || cfg.AdminChatID != -100 || len(cfg.AllowedUserIDs) != 2 || cfg.MonthlyBudgetUSD != 5 {
+		t.Errorf("config = %+v", cfg)
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	tests := []struct {
+		level, format string
+		debug         bool
+		json          bool
+		wantErr       string
+	}{
+		{"", "", false, false, ""},
+		{"debug", "JSON", true, true, ""},
+		{" warn ", "text", false, false, ""},
+		{"loud", "", false, false, "LOG_LEVEL"},
+		{"info", "xml", false, false, "LOG_FORMAT"},
+	}
+	for _, tt := range tests {
+		logger, err := newLogger(tt.level, tt.format)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("newLogger(%q, %q) err = %v, want %s", tt.level, tt.format, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("newLogger(%q, %q): %v", tt.level, tt.format, err)
+		}
+		if got := logger.Enabled(context.Background(), slog.LevelDebug); got != tt.debug {
+			t.Errorf("newLogger(%q, %q) logs debug = %v, want %v", tt.level, tt.format, got, tt.debug)
+		}
+		if _, isJSON := logger.Handler().(*slog.JSONHandler); isJSON != tt.json {
+			t.Errorf("newLogger(%q, %q) JSON = %v, want %v", tt.level, tt.format, isJSON, tt.json)
+		}
+	}
+}
@@ -2,26 +2,74 @@ package main
 
 import (
     "context"
-    "log"
+    "errors"
+    "flag"
+    "fmt"
+    "log/slog"
     "os"
     "os/signal"
+    "runtime/debug"
+    "strconv"
+    "strings"
     "syscall"
+    "time"
 
     "github.com/joho/godotenv"
 
     "eteonbot/internal/app"
 )
 
+// version is set at build time with -ldflags "-X main.version=v1.2.3".
+var version string
+
 func main() {
-    if err := godotenv.Load(); err != nil {
-        if !os.IsNotExist(err) {
-            log.Printf("warning: could not load .env: %v", err)
-        }
+    env := flag.String("env", "prod", "environment to run against: prod, or test for Telegram's test servers and a sandbox Gemini key")
+    configPath := flag.String("config", "", "YAML config file; environment variables override its settings (default $CONFIG_FILE)")
+    flag.Parse()
+
+    envErr := godotenv.Load()
+
+    if *configPath == "" {
+        *configPath = os.Getenv("CONFIG_FILE")
+    }
+    file, err := loadConfigFile(*configPath)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
     }
 
-    cfg := app.Config{
-        TelegramToken: os.Getenv("TELEGRAM_BOT_TOKEN"),
-        GeminiAPIKey:  os.Getenv("GEMINI_API_KEY"),
+    logger, err := newLogger(envString("LOG_LEVEL", file.Log.Level), envString("LOG_FORMAT", file.Log.Format))
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    slog.SetDefault(logger)
+    if envErr != nil && !os.IsNotExist(envErr) {
+        slog.Warn("could not load .env", "err", envErr)
+    }
+
+    if *configPath != "" {
+        slog.Info("loaded config file", "path", *configPath)
+    }
+
+    switch *env {
+    case "prod", "test":
+    default:
+        fmt.Fprintf(os.Stderr, "unknown --env %q, want prod or test\n", *env)
+        os.Exit(2)
+    }
+    cfg, err := buildConfig(*env, file)
+    if err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        os.Exit(1)
+    }
+    cfg.Version = buildVersion()
+    cfg.Reload = func() (app.Config, error) {
+        file, err := loadConfigFile(*configPath)
+        if err != nil {
+            return app.Config{}, err
+        }
+        return buildConfig(*env, file)
     }
 
     ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
@@ -29,11 +77,247 @@ func main() {
 
     botApp, err := app.New(ctx, cfg)
     if err != nil {
-        log.Fatalf("failed to initialise application: %v", err)
+        slog.Error("failed to initialise application", "err", err)
+        os.Exit(1)
     }
 
-    log.Println("Eteon bot is running")
+    go watchConfig(ctx, botApp, *configPath)
+
+    slog.Info("Eteon bot is running")
     if err := botApp.Run(ctx); err != nil {
-        log.Fatalf("bot stopped with error: %v", err)
+        slog.Error("bot stopped with error", "err", err)
+        os.Exit(1)
+    }
+}
+
+// buildConfig combines the environment with the config file, the environment
+// winning, for the environment env runs against. Its error lists every
+// variable that does not parse.
+func buildConfig(env string, file fileConfig) (app.Config, error) {
+    var errs []error
+    toInt, toFloat, toBool := keepValue[int](&errs), keepValue[float64](&errs), keepValue[bool](&errs)
+    toInt64, toIDs, toDuration := keepValue[int64](&errs), keepValue[[]int64](&errs), keepValue[time.Duration](&errs)
+    cfg := app.Config{
+        TelegramToken: envString("TELEGRAM_BOT_TOKEN", file.Telegram.Token),
+        GeminiAPIKey:  envString("GEMINI_API_KEY", file.Gemini.APIKey),
+
+        PIIDetectors:        envList("PII_DETECTORS", file.PII.Detectors),
+        KeepOriginalHistory: toBool(envBool("PII_KEEP_ORIGINALS", file.PII.KeepOriginals)),
+        ReplyHooks:          envList("REPLY_HOOKS", file.Reply.Hooks),
+        LinkShortenerURL:    envString("LINK_SHORTENER_URL", file.Reply.LinkShortener),
+        Model:               envString("GEMINI_MODEL", file.Gemini.Model),
+        FallbackModel:       envString("GEMINI_FALLBACK_MODEL", file.Gemini.FallbackModel),
+        ThinkingMode:        envString("THINKING_MODE", file.Gemini.Thinking),
+        OpenWeatherAPIKey:   envString("OPENWEATHER_API_KEY", file.Tools.OpenWeatherAPIKey),
+        ToolsUserAgent:      envString("TOOLS_USER_AGENT", file.Tools.UserAgent),
+        DisabledTools:       envList("DISABLED_TOOLS", file.Tools.Disabled),
+        ToolRouting:         envString("TOOL_ROUTING", file.Tools.Routing),
+        ActionsFile:         envString("ACTIONS_FILE", file.Tools.ActionsFile),
+        DataDir:             envString("DATA_DIR", file.Storage.DataDir),
+        PrefsEncryptionKey:  envString("PREFS_ENCRYPTION_KEY", file.Storage.PrefsEncryptionKey),
+        InlineMediaLimit:    toInt64(envMegabytes("INLINE_MEDIA_LIMIT_MB", file.InlineMediaLimit)),
+        InactiveChatGrace:   toDuration(envDays("INACTIVE_CHAT_GRACE_DAYS", file.Storage.InactiveChatGrace)),
+        RedisURL:            envString("REDIS_URL", file.Storage.RedisURL),
+        SQLitePath:          envString("SQLITE_PATH", file.Storage.SQLitePath),
+        RateLimit:           toInt(envInt("RATE_LIMIT", file.RateLimit)),
+        LoadSheddingDepth:   toInt(envInt("LOAD_SHEDDING_DEPTH", file.Load.SheddingDepth)),
+        MonthlyBudgetUSD:    toFloat(envFloat("MONTHLY_BUDGET_USD", file.Load.MonthlyBudgetUSD)),
+        DailyBudgetUSD:      toFloat(envFloat("DAILY_BUDGET_USD", file.Load.DailyBudgetUSD)),
+        UserDailyBudgetUSD:  toFloat(envFloat("USER_DAILY_BUDGET_USD", file.Load.UserDailyBudgetUSD)),
+        OverBudget:          envString("OVER_BUDGET", file.Load.OverBudget),
+        MaxConcurrentCalls:  toInt(envInt("MAX_CONCURRENT_CALLS", file.Load.ConcurrentCalls)),
+        AllowedUserIDs:      toIDs(envIDs("ALLOWED_USER_IDS", file.Allow.Users)),
+        AllowedChatIDs:      toIDs(envIDs("ALLOWED_CHAT_IDS", file.Allow.Chats)),
+        WebhookURL:          envString("WEBHOOK_URL", file.Webhook.URL),
+        WebhookListen:       envString("WEBHOOK_LISTEN", file.Webhook.Listen),
+        WebhookSecret:       envString("WEBHOOK_SECRET", file.Webhook.Secret),
+        ControlListen:       envString("CONTROL_LISTEN", file.Control.Listen),
+        ControlToken:        envString("CONTROL_TOKEN", file.Control.Token),
+        SystemPrompt:        envString("SYSTEM_PROMPT", file.Gemini.SystemPrompt),
+        AdminUserIDs:        toIDs(envIDs("ADMIN_USER_IDS", file.Admins.Users)),
+        AdminChatID:         toInt64(envID("ADMIN_CHAT_ID", file.Admins.Chat)),
+        SentimentAlerts:     toBool(envBool("SENTIMENT_ALERTS", file.Admins.SentimentAlerts)),
+        GroupContext:        toBool(envBool("GROUP_CONTEXT", file.GroupContext)),
+        Pipelines:           file.pipelines(),
+        Providers:           file.providers(),
+    }
+    if env == "test" {
+        // The production credentials are never used as a fallback, so a
+        // contributor cannot reach real users by running with --env=test.
+        cfg.TestEnvironment = true
+        cfg.TelegramToken = envString("TELEGRAM_TEST_BOT_TOKEN", file.Telegram.TestToken)
+        cfg.GeminiAPIKey = envString("GEMINI_TEST_API_KEY", file.Gemini.TestAPIKey)
+        cfg.DataDir = envString("TEST_DATA_DIR", file.Storage.TestDataDir)
+        cfg.SQLitePath = envString("TEST_SQLITE_PATH", file.Storage.TestSQLitePath)
+    }
+    return cfg, errors.Join(errs...)
+}
+
+// keepValue returns a function that passes a parsed value through and records
+// its error in errs, so buildConfig reports every malformed variable at once.
+func keepValue[T any](errs *[]error) func(T, error) T {
+    return func(v T, err error) T {
+        if err != nil {
+            *errs = append(*errs, err)
+        }
+        return v
+    }
+}
+
+// newLogger builds the process logger from LOG_LEVEL (debug, info, warn or error)
+// and LOG_FORMAT (text or json); empty values select info and text.
+func newLogger(level, format string) (*slog.Logger, error) {
+    var lvl slog.Level
+    if strings.TrimSpace(level) != "" {
+        if err := lvl.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+            return nil, fmt.Errorf("LOG_LEVEL: %w", err)
+        }
+    }
+    opts := &slog.HandlerOptions{Level: lvl}
+    switch strings.ToLower(strings.TrimSpace(format)) {
+    case "", "text":
+        return slog.New(slog.NewTextHandler(os.Stderr, opts)), nil
+    case "json":
+        return slog.New(slog.NewJSONHandler(os.Stderr, opts)), nil
+    default:
+        return nil, fmt.Errorf("LOG_FORMAT: unknown format %q, want text or json", format)
+    }
+}
+
+// envString returns the variable, or fallback when it is unset or empty.
+func envString(key, fallback string) string {
+    if v := os.Getenv(key); v != "" {
+        return v
+    }
+    return fallback
+}
+
+// envList splits a comma-separated variable; unset yields fallback and "none" an empty list.
+func envList(key string, fallback []string) []string {
+    raw, ok := os.LookupEnv(key)
+    if !ok || strings.TrimSpace(raw) == "" {
+        return fallback
+    }
+    if strings.EqualFold(strings.TrimSpace(raw), "none") {
+        return []string{}
+    }
+    var out []string
+    for _, item := range strings.Split(raw, ",") {
+        if item = strings.TrimSpace(item); item != "" {
+            out = append(out, item)
+        }
+    }
+    return out
+}
+
+// envID parses a single Telegram ID, returning fallback when it is unset.
+func envID(key string, fallback int64) (int64, error) {
+    raw := strings.TrimSpace(os.Getenv(key))
+    if raw == "" {
+        return fallback, nil
+    }
+    id, err := strconv.ParseInt(raw, 10, 64)
+    if err != nil {
+        return fallback, fmt.Errorf("%s: %q is not a Telegram ID", key, raw)
+    }
+    return id, nil
+}
+
+// envIDs parses a comma-separated list of Telegram IDs; unset yields fallback.
+func envIDs(key string, fallback []int64) ([]int64, error) {
+    items := envList(key, nil)
+    if items == nil {
+        return fallback, nil
+    }
+    var ids []int64
+    for _, item := range items {
+        id, err := strconv.ParseInt(item, 10, 64)
+        if err != nil {
+            return fallback, fmt.Errorf("%s: %q is not a Telegram ID", key, item)
+        }
+        ids = append(ids, id)
+    }
+    return ids, nil
+}
+
+// envBool reads a flag such as true, false, 1 or 0, returning fallback when
+// it is unset.
+func envBool(key string, fallback bool) (bool, error) {
+    raw := strings.TrimSpace(os.Getenv(key))
+    if raw == "" {
+        return fallback, nil
+    }
+    v, err := strconv.ParseBool(raw)
+    if err != nil {
+        return fallback, fmt.Errorf("%s: %q is not true or false", key, raw)
+    }
+    return v, nil
+}
+
+// envInt reads a whole number, returning fallback when it is unset.
+func envInt(key string, fallback int) (int, error) {
+    raw := strings.TrimSpace(os.Getenv(key))
+    if raw == "" {
+        return fallback, nil
+    }
+    v, err := strconv.Atoi(raw)
+    if err != nil {
+        return fallback, fmt.Errorf("%s: %q is not a whole number", key, raw)
+    }
+    return v, nil
+}
+
+// envFloat reads a number, returning fallback when it is unset.
+func envFloat(key string, fallback float64) (float64, error) {
+    raw := strings.TrimSpace(os.Getenv(key))
+    if raw == "" {
+        return fallback, nil
+    }
+    v, err := strconv.ParseFloat(raw, 64)
+    if err != nil {
+        return fallback, fmt.Errorf("%s: %q is not a number", key, raw)
+    }
+    return v, nil
+}
+
+// envMegabytes reads a size in megabytes, or takes fallback when it is unset, and
+// returns it in bytes; negative values pass through.
+func envMegabytes(key string, fallback float64) (int64, error) {
+    v, err := envFloat(key, fallback)
+    return int64(v * (1 << 20)), err
+}
+
+// envDays reads a number of days, or takes fallback when it is unset, as a
+// duration; negative values pass through.
+func envDays(key string, fallback float64) (time.Duration, error) {
+    v, err := envFloat(key, fallback)
+    return time.Duration(v * 24 * float64(time.Hour)), err
+}
+
+// buildVersion returns the version set at build time, or else the VCS revision
+// the binary was built from.
+func buildVersion() string {
+    if version != "" {
+        return version
+    }
+    info, ok := debug.ReadBuildInfo()
+    if !ok {
+        return ""
+    }
+    var revision, modified string
+    for _, setting := range info.Settings {
+        switch setting.Key {
+        case "vcs.revision":
+            revision = setting.Value
+        case "vcs.modified":
+            modified = setting.Value
+        }
+    }
+    if len(revision) > 12 {
+        revision = revision[:12]
+    }
+    if revision != "" && modified == "true" {
+        revision += "-dirty"
     }
-}
+    return revision
+}
@@ -0,0 +1,150 @@
+package app
+
+import (
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// rateWindow is the span Config.RateLimit counts updates over.
+const rateWindow = time.Minute
+
+// accessControl holds the allowlists and the per-user rate limit.
+type accessControl struct {
+	mu           sync.Mutex
+	users, chats []int64
+	limit        int
+	hits         map[int64][]time.Time
+	warned       map[int64]time.Time
+	lastSweep    time.Time
+}
+
+func newAccessControl(users, chats []int64, limit int) *accessControl {
+	return &accessControl{
+		users:  users,
+		chats:  chats,
+		limit:  limit,
+		hits:   make(map[int64][]time.Time),
+		warned: make(map[int64]time.Time),
+	}
+}
+
+// configure replaces the allowlists and the rate limit and reports which of
+// them changed.
+func (ac *accessControl) configure(users, chats []int64, limit int) (allowChanged, limitChanged bool) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	allowChanged = !sameIDs(ac.users, users) || !sameIDs(ac.chats, chats)
+	limitChanged = ac.limit != limit
+	ac.users, ac.chats, ac.limit = users, chats, limit
+	return allowChanged, limitChanged
+}
+
+// setLimit replaces the rate limit alone; zero lifts it.
+func (ac *accessControl) setLimit(limit int) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	ac.limit = limit
+}
+
+// rateLimit returns the updates a user may send per minute, zero for any.
+func (ac *accessControl) rateLimit() int {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	return ac.limit
+}
+
+// restricted reports whether an allowlist is set.
+func (ac *accessControl) restricted() bool {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	return len(ac.users) > 0 || len(ac.chats) > 0
+}
+
+// allows reports whether the allowlists let an update from userID in chatID
+// through.
+func (ac *accessControl) allows(userID, chatID int64) bool {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	if len(ac.users) == 0 && len(ac.chats) == 0 {
+		return true
+	}
+	return slices.Contains(ac.users, userID) || (chatID != 0 && slices.Contains(ac.chats, chatID))
+}
+
+// take counts an update of userID at now against the rate limit. When the
+// user is over it, it returns how long until the next update is accepted and
+// whether the user has yet to be told in this window.
+func (ac *accessControl) take(userID int64, now time.Time) (wait time.Duration, notify bool) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	if ac.limit <= 0 {
+		return 0, false
+	}
+	if now.Sub(ac.lastSweep) > rateWindow {
+		for id, hits := range ac.hits {
+			if len(hits) == 0 || now.Sub(hits[len(hits)-1]) >= rateWindow {
+				delete(ac.hits, id)
+				delete(ac.warned, id)
+			}
+		}
+		ac.lastSweep = now
+	}
+
+	hits := slices.DeleteFunc(ac.hits[userID], func(at time.Time) bool {
+		return now.Sub(at) >= rateWindow
+	})
+	if len(hits) >= ac.limit {
+		ac.hits[userID] = hits
+		wait = hits[0].Add(rateWindow).Sub(now)
+		if now.Sub(ac.warned[userID]) >= rateWindow {
+			ac.warned[userID] = now
+			notify = true
+		}
+		return wait, notify
+	}
+	ac.hits[userID] = append(hits, now)
+	return 0, false
+}
+
+// accessMiddleware drops updates from outside the allowlists and updates over
+// the rate limit, telling the user once per window when to try again.
+// Messages only buffered for /catchup are not counted.
+func (a *App) accessMiddleware(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		sender := c.Sender()
+		if sender == nil || a.isOperator(sender.ID) {
+			return next(c)
+		}
+		var chatID int64
+		if chat := c.Chat(); chat != nil {
+			chatID = chat.ID
+		}
+		if !a.access.allows(sender.ID, chatID) {
+			slog.Debug("update outside the allowlist dropped", "chat_id", chatID, "user_id", sender.ID)
+			return nil
+		}
+		if msg := c.Message(); c.Callback() == nil && msg != nil && isGroupChat(msg.Chat) && a.catchup.enabled(msg.Chat.ID) && !a.addressedToBot(msg) {
+			return next(c)
+		}
+
+		wait, notify := a.access.take(sender.ID, time.Now())
+		if wait <= 0 {
+			return next(c)
+		}
+		slog.Info("update over the rate limit dropped", "chat_id", chatID, "user_id", sender.ID)
+		lang := a.chatLanguage(c.Chat(), sender)
+		notice := tr(lang, "rate_limited", int((wait+time.Second-1)/time.Second))
+		if c.Callback() != nil {
+			return c.Respond(&tele.CallbackResponse{Text: notice})
+		}
+		if !notify || c.Chat() == nil {
+			return nil
+		}
+		_, err := a.sendWithFallback(c.Chat(), notice, &tele.SendOptions{ThreadID: messageTopic(c.Message()), DisableWebPagePreview: true})
+		return err
+	}
+}
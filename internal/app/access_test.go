@@ -0,0 +1,77 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestAccessMiddleware(t *testing.T) {
+	app, apis := newTestApp(t, Config{AllowedChatIDs: []int64{42}, RateLimit: 2})
+	handled := 0
+	handler := app.accessMiddleware(func(tele.Context) error {
+		handled++
+		return nil
+	})
+	send := func(chatID int64) {
+		t.Helper()
+		if err := handler(app.bot.NewContext(tele.Update{Message: testMessage(chatID, "hi")})); err != nil {
+			t.Fatalf("handler: %v", err)
+		}
+	}
+
+	send(7)
+	if handled != 0 {
+		t.Error("handled an update from outside the allowlist")
+	}
+	for range 4 {
+		send(42)
+	}
+	if handled != 2 {
+		t.Errorf("handled %d updates, want the 2 the rate limit allows", handled)
+	}
+	var notices int
+	for _, text := range apis.sentTexts() {
+		if strings.Contains(text, "too fast") {
+			notices++
+		}
+	}
+	if notices != 1 {
+		t.Errorf("sent %d rate limit notices, want 1", notices)
+	}
+}
+
+func TestRateLimitWindow(t *testing.T) {
+	ac := newAccessControl(nil, nil, 1)
+	now := time.Now()
+	if wait, _ := ac.take(1, now); wait != 0 {
+		t.Fatal("first update limited")
+	}
+	if wait, notify := ac.take(1, now.Add(10*time.Second)); wait != 50*time.Second || !notify {
+		t.Errorf("second update: wait %v notify %v, want 50s and a notice", wait, notify)
+	}
+	if wait, _ := ac.take(2, now.Add(10*time.Second)); wait != 0 {
+		t.Error("another user limited")
+	}
+	if wait, _ := ac.take(1, now.Add(rateWindow)); wait != 0 {
+		t.Error("update after the window limited")
+	}
+}
+
+func TestDisabledTools(t *testing.T) {
+	app, _ := newTestApp(t, Config{DisabledTools: []string{codeExecutionTool, "convert_currency"}})
+	if len(app.tools) != 1 || app.tools[0].CodeExecution != nil || app.tools[0].GoogleSearch == nil {
+		t.Errorf("built-in tools %+v", app.tools)
+	}
+	if app.functions.has("convert_currency") || !app.functions.has("convert_units") {
+		t.Error("convert_currency still registered or convert_units missing")
+	}
+	if tools := builtinTools([]string{googleSearchTool, urlContextTool, codeExecutionTool}); tools != nil {
+		t.Errorf("all disabled: %+v", tools)
+	}
+	if _, err := New(t.Context(), Config{TelegramToken: "t", GeminiAPIKey: "k", DataDir: t.TempDir(), DisabledTools: []string{"teleport"}}); err == nil || !strings.Contains(err.Error(), "teleport") {
+		t.Errorf("New with an unknown tool: %v", err)
+	}
+}
@@ -0,0 +1,326 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	confirmActionUnique = "confirm_action"
+	cancelActionUnique  = "cancel_action"
+	pendingActionTTL    = 10 * time.Minute
+	actionResponseLimit = 2048
+)
+
+var actionNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]{0,63}$`)
+
+// webhookAction is an operator-defined HTTP call exposed to the model as a function.
+type webhookAction struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	URL         string                 `json:"url"`
+	Method      string                 `json:"method"`
+	Headers     map[string]string      `json:"headers"`
+	Parameters  map[string]actionParam `json:"parameters"`
+	Required    []string               `json:"required"`
+	// AllowedChats restricts the action to these chat IDs; empty allows every chat.
+	AllowedChats []int64 `json:"allowed_chats"`
+	// Confirm asks the user to approve each call before the webhook fires.
+	Confirm bool `json:"confirm"`
+}
+
+type actionParam struct {
+	Type        string   `json:"type"`
+	Description string   `json:"description"`
+	Enum        []string `json:"enum"`
+}
+
+// loadWebhookActions reads action definitions from a JSON array file.
+func loadWebhookActions(path string) ([]webhookAction, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var actions []webhookAction
+	if err := json.Unmarshal(data, &actions); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	for i := range actions {
+		act := &actions[i]
+		if !actionNamePattern.MatchString(act.Name) {
+			return nil, fmt.Errorf("action %d: invalid name %q", i, act.Name)
+		}
+		if !strings.HasPrefix(act.URL, "http://") && !strings.HasPrefix(act.URL, "https://") {
+			return nil, fmt.Errorf("action %s: url must be http or https", act.Name)
+		}
+		act.Method = strings.ToUpper(strings.TrimSpace(act.Method))
+		if act.Method == "" {
+			act.Method = http.MethodPost
+		}
+		for name, param := range act.Parameters {
+			if _, err := actionSchemaType(param.Type); err != nil {
+				return nil, fmt.Errorf("action %s parameter %s: %w", act.Name, name, err)
+			}
+		}
+	}
+	return actions, nil
+}
+
+func actionSchemaType(t string) (genai.Type, error) {
+	switch strings.ToLower(strings.TrimSpace(t)) {
+	case "", "string":
+		return genai.TypeString, nil
+	case "number":
+		return genai.TypeNumber, nil
+	case "integer":
+		return genai.TypeInteger, nil
+	case "boolean":
+		return genai.TypeBoolean, nil
+	default:
+		return "", fmt.Errorf("unsupported type %q", t)
+	}
+}
+
+func (act webhookAction) declaration() *genai.FunctionDeclaration {
+	props := make(map[string]*genai.Schema, len(act.Parameters))
+	for name, param := range act.Parameters {
+		typ, _ := actionSchemaType(param.Type)
+		props[name] = &genai.Schema{Type: typ, Description: param.Description, Enum: param.Enum}
+	}
+	decl := &genai.FunctionDeclaration{Name: act.Name, Description: act.Description}
+	if len(props) > 0 {
+		decl.Parameters = &genai.Schema{Type: genai.TypeObject, Properties: props, Required: act.Required}
+	}
+	return decl
+}
+
+func (act webhookAction) allows(chatID int64) bool {
+	return len(act.AllowedChats) == 0 || slices.Contains(act.AllowedChats, chatID)
+}
+
+func (act webhookAction) call(ctx context.Context, args map[string]any) (map[string]any, error) {
+	var body io.Reader
+	target := act.URL
+	if act.Method == http.MethodGet || act.Method == http.MethodDelete {
+		if len(args) > 0 {
+			q := url.Values{}
+			for k, v := range args {
+				q.Set(k, fmt.Sprint(v))
+			}
+			sep := "?"
+			if strings.Contains(target, "?") {
+				sep = "&"
+			}
+			target += sep + q.Encode()
+		}
+	} else {
+		payload, err := json.Marshal(args)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, act.Method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, v := range act.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := toolHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("call %s: %w", act.Name, err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, actionResponseLimit))
+	result := map[string]any{"status": resp.StatusCode, "body": string(data)}
+	if resp.StatusCode >= 300 {
+		return result, fmt.Errorf("call %s: status %s", act.Name, resp.Status)
+	}
+	return result, nil
+}
+
+type pendingAction struct {
+	action webhookAction
+	args   map[string]any
+	chatID int64
+	// userID is the user whose request led to the action; only they may
+	// confirm or cancel it.
+	userID  int64
+	created time.Time
+}
+
+var (
+	errActionExpired  = errors.New("action request expired")
+	errActionNotYours = errors.New("action requested by another user")
+)
+
+// actionConfirmations parks calls that need the user's approval.
+type actionConfirmations struct {
+	mu      sync.Mutex
+	pending map[string]*pendingAction
+	counter uint64
+}
+
+func newActionConfirmations() *actionConfirmations {
+	return &actionConfirmations{pending: make(map[string]*pendingAction)}
+}
+
+func (c *actionConfirmations) put(p *pendingAction) string {
+	id := strconv.FormatUint(atomic.AddUint64(&c.counter, 1), 10)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, item := range c.pending {
+		if time.Since(item.created) > pendingActionTTL {
+			delete(c.pending, key)
+		}
+	}
+	c.pending[id] = p
+	return id
+}
+
+// take removes and returns the pending action id of chatID when userID
+// requested it. Another user's attempt leaves it in place.
+func (c *actionConfirmations) take(id string, chatID, userID int64) (*pendingAction, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, ok := c.pending[id]
+	if !ok || p.chatID != chatID || time.Since(p.created) > pendingActionTTL {
+		return nil, errActionExpired
+	}
+	if p.userID != userID {
+		return nil, errActionNotYours
+	}
+	delete(c.pending, id)
+	return p, nil
+}
+
+func (a *App) registerWebhookActions(actions []webhookAction) {
+	a.functions.unregister(a.actionNames...)
+	a.actionNames = a.actionNames[:0]
+	for _, act := range actions {
+		a.actionNames = append(a.actionNames, act.Name)
+		a.functions.registerFor(act.declaration(), func(ctx context.Context, args map[string]any) (map[string]any, error) {
+			if !act.Confirm {
+				return act.call(ctx, args)
+			}
+			chatID, ok := chatIDFromContext(ctx)
+			if !ok {
+				return nil, errors.New("confirmation requires a chat")
+			}
+			userID, _ := userIDFromContext(ctx)
+			if err := a.requestActionConfirmation(chatID, threadFromContext(ctx), userID, act, args); err != nil {
+				return nil, err
+			}
+			return map[string]any{
+				"status": "awaiting_user_confirmation",
+				"note":   "The user must press Confirm before the action runs. Tell them to confirm it.",
+			}, nil
+		}, act.allows)
+	}
+}
+
+func (a *App) requestActionConfirmation(chatID int64, thread int, userID int64, act webhookAction, args map[string]any) error {
+	id := a.actionConfirms.put(&pendingAction{action: act, args: args, chatID: chatID, userID: userID, created: time.Now()})
+
+	lang := a.chatLanguage(&tele.Chat{ID: chatID}, nil)
+	var b strings.Builder
+	b.WriteString(tr(lang, "action_confirm_q", act.Name))
+	if len(args) > 0 {
+		encoded, _ := json.MarshalIndent(args, "", "  ")
+		b.WriteString("\n")
+		b.Write(encoded)
+	}
+
+	markup := &tele.ReplyMarkup{}
+	markup.Inline(markup.Row(
+		markup.Data(tr(lang, "action_confirm"), confirmActionUnique, id),
+		markup.Data(tr(lang, "action_cancel"), cancelActionUnique, id),
+	))
+	_, err := a.sendWithFallback(chatTopic(&tele.Chat{ID: chatID}, thread), b.String(), &tele.SendOptions{ReplyMarkup: markup, DisableWebPagePreview: true})
+	return err
+}
+
+// takeAction takes the pending action a Confirm or Cancel button names. A
+// user other than the requester is told so and gets nothing.
+func (a *App) takeAction(c tele.Context) (*pendingAction, error) {
+	var userID int64
+	if sender := c.Sender(); sender != nil {
+		userID = sender.ID
+	}
+	pending, err := a.actionConfirms.take(c.Callback().Data, c.Chat().ID, userID)
+	if errors.Is(err, errActionNotYours) {
+		if err := c.Respond(&tele.CallbackResponse{Text: tr(a.chatLanguage(c.Chat(), c.Sender()), "action_not_yours")}); err != nil {
+			slog.Warn("callback acknowledge failed", "err", err)
+		}
+		return nil, err
+	}
+	if err := c.Respond(); err != nil {
+		slog.Warn("callback acknowledge failed", "err", err)
+	}
+	return pending, err
+}
+
+func (a *App) handleConfirmAction(c tele.Context) error {
+	pending, err := a.takeAction(c)
+	if errors.Is(err, errActionNotYours) {
+		return nil
+	}
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	if err != nil {
+		_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), tr(lang, "action_expired"), &tele.SendOptions{DisableWebPagePreview: true})
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	result, err := pending.action.call(ctx, pending.args)
+
+	body := tr(lang, "action_done", pending.action.Name)
+	if err != nil {
+		a.chatLog(c.Chat().ID).Warn("webhook action failed", "chat_id", c.Chat().ID, "err", err)
+		body = tr(lang, "action_failed", pending.action.Name)
+	}
+	if text, _ := result["body"].(string); strings.TrimSpace(text) != "" {
+		body += "\n" + strings.TrimSpace(text)
+	}
+	_, sendErr := a.sendWithFallback(inTopic(c.Chat(), c.Message()), body, &tele.SendOptions{DisableWebPagePreview: true})
+	return sendErr
+}
+
+func (a *App) handleCancelAction(c tele.Context) error {
+	_, err := a.takeAction(c)
+	if errors.Is(err, errActionNotYours) {
+		return nil
+	}
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	body := tr(lang, "action_cancelled")
+	if err != nil {
+		body = tr(lang, "action_expired")
+	}
+	_, err = a.sendWithFallback(inTopic(c.Chat(), c.Message()), body, &tele.SendOptions{DisableWebPagePreview: true})
+	return err
+}
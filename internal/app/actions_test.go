@@ -0,0 +1,57 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+func actionCallback(app *App, chatID, userID int64, data string) tele.Context {
+	return app.bot.NewContext(tele.Update{Callback: &tele.Callback{
+		ID:      "callback",
+		Data:    data,
+		Sender:  &tele.User{ID: userID},
+		Message: &tele.Message{ID: 5, Chat: &tele.Chat{ID: chatID, Type: tele.ChatGroup}},
+	}})
+}
+
+func TestConfirmActionOnlyByRequester(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.handle("hooks.example.com", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("done"))
+	}))
+	const chatID, requester, other = -100, 7, 8
+	act := webhookAction{Name: "deploy", URL: "https://hooks.example.com/deploy", Method: http.MethodPost, Confirm: true}
+	app.registerWebhookActions([]webhookAction{act})
+	ctx := withUserID(withChatID(context.Background(), chatID), requester)
+	app.functions.invoke(ctx, &genai.FunctionCall{Name: "deploy"})
+	var id string
+	for key, pending := range app.actionConfirms.pending {
+		id = key
+		if pending.userID != requester {
+			t.Fatalf("pending action of user %d, want %d", pending.userID, requester)
+		}
+	}
+	if id == "" {
+		t.Fatal("no confirmation requested")
+	}
+
+	if err := app.handleConfirmAction(actionCallback(app, chatID, other, id)); err != nil {
+		t.Fatal(err)
+	}
+	if calls := apis.callsTo("hooks.example.com", "/deploy"); len(calls) != 0 {
+		t.Fatal("another user's confirmation ran the action")
+	}
+	if err := app.handleCancelAction(actionCallback(app, chatID, other, id)); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.handleConfirmAction(actionCallback(app, chatID, requester, id)); err != nil {
+		t.Fatal(err)
+	}
+	if calls := apis.callsTo("hooks.example.com", "/deploy"); len(calls) != 1 {
+		t.Fatalf("action ran %d times after the requester confirmed, want 1", len(calls))
+	}
+}
@@ -0,0 +1,432 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	broadcastPause   = 50 * time.Millisecond
+	adminDrainLimit  = 2 * time.Minute
+	shutdownDrainCap = 30 * time.Second
+)
+
+// operatorState is what /admin keeps across restarts: the chats the bot has
+// seen, so broadcasts reach them, the chats Telegram no longer lets it reach
+// and the banned users.
+type operatorState struct {
+	mu       sync.Mutex
+	path     string
+	chats    map[int64]bool
+	inactive map[int64]inactiveChat
+	banned   map[int64]bool
+}
+
+// inactiveChat records why and since when the bot cannot send to a chat.
+type inactiveChat struct {
+	Since  time.Time `json:"since"`
+	Reason string    `json:"reason"`
+}
+
+type operatorFile struct {
+	Chats    []int64                `json:"chats"`
+	Inactive map[int64]inactiveChat `json:"inactive,omitempty"`
+	Banned   []int64                `json:"banned"`
+}
+
+func openOperatorState(path string) (*operatorState, error) {
+	s := &operatorState{path: path}
+	return s, s.load()
+}
+
+// load replaces the state with the file contents; a missing file is empty.
+func (s *operatorState) load() error {
+	var file operatorFile
+	if err := readJSONFile(s.path, &file); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chats = make(map[int64]bool, len(file.Chats))
+	for _, id := range file.Chats {
+		s.chats[id] = true
+	}
+	s.inactive = make(map[int64]inactiveChat, len(file.Inactive))
+	for id, chat := range file.Inactive {
+		s.inactive[id] = chat
+	}
+	s.banned = make(map[int64]bool, len(file.Banned))
+	for _, id := range file.Banned {
+		s.banned[id] = true
+	}
+	return nil
+}
+
+func (s *operatorState) flushLocked() error {
+	file := operatorFile{Chats: sortedIDs(s.chats), Inactive: s.inactive, Banned: sortedIDs(s.banned)}
+	return writeJSONFile(s.path, file)
+}
+
+func sortedIDs(set map[int64]bool) []int64 {
+	ids := make([]int64, 0, len(set))
+	for id := range set {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// seeChat remembers chatID for broadcasts. An update from a chat marked
+// inactive means the bot can reach it again.
+func (s *operatorState) seeChat(chatID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, inactive := s.inactive[chatID]
+	if s.chats[chatID] && !inactive {
+		return nil
+	}
+	s.chats[chatID] = true
+	delete(s.inactive, chatID)
+	return s.flushLocked()
+}
+
+// markInactive stops sending to chatID until it writes to the bot again.
+func (s *operatorState) markInactive(chatID int64, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.inactive[chatID]; ok {
+		return nil
+	}
+	s.inactive[chatID] = inactiveChat{Since: time.Now().UTC(), Reason: reason}
+	return s.flushLocked()
+}
+
+// inactiveBefore returns the chats that became inactive before t.
+func (s *operatorState) inactiveBefore(t time.Time) []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []int64
+	for id, chat := range s.inactive {
+		if chat.Since.Before(t) {
+			ids = append(ids, id)
+		}
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// migrate replaces chat from with chat to, which Telegram moved it to.
+func (s *operatorState) migrate(from, to int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.chats[from] && s.chats[to] {
+		return nil
+	}
+	delete(s.chats, from)
+	delete(s.inactive, from)
+	s.chats[to] = true
+	return s.flushLocked()
+}
+
+// forgetChat removes chatID from the known and inactive chats.
+func (s *operatorState) forgetChat(chatID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.chats, chatID)
+	delete(s.inactive, chatID)
+	return s.flushLocked()
+}
+
+func (s *operatorState) isInactive(chatID int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.inactive[chatID]
+	return ok
+}
+
+// knownChats returns the chats the bot has seen and can still send to.
+func (s *operatorState) knownChats() []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := sortedIDs(s.chats)
+	return slices.DeleteFunc(ids, func(id int64) bool {
+		_, ok := s.inactive[id]
+		return ok
+	})
+}
+
+func (s *operatorState) isBanned(userID int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.banned[userID]
+}
+
+func (s *operatorState) setBanned(userID int64, banned bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.banned[userID] == banned {
+		return nil
+	}
+	if banned {
+		s.banned[userID] = true
+	} else {
+		delete(s.banned, userID)
+	}
+	return s.flushLocked()
+}
+
+func (s *operatorState) counts() (chats, inactive, banned int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.chats), len(s.inactive), len(s.banned)
+}
+
+// isOperator reports whether userID may use /admin. Unlike isAdmin it requires
+// an explicit ADMIN_USER_IDS entry, since the commands reach every chat.
+func (a *App) isOperator(userID int64) bool {
+	return slices.Contains(a.admins, userID)
+}
+
+// operatorMiddleware records the chats the bot sees and drops updates from
+// banned users before any handler runs.
+func (a *App) operatorMiddleware(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		if chat := c.Chat(); chat != nil {
+			if err := a.operator.seeChat(chat.ID); err != nil {
+				slog.Warn("remember chat failed", "chat_id", chat.ID, "err", err)
+			}
+		}
+		if sender := c.Sender(); sender != nil && a.operator.isBanned(sender.ID) && !a.isOperator(sender.ID) {
+			return nil
+		}
+		return next(c)
+	}
+}
+
+func (a *App) handleAdmin(c tele.Context) error {
+	if c.Sender() == nil || !a.isOperator(c.Sender().ID) {
+		_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), tr(a.chatLanguage(c.Chat(), c.Sender()), "admin_only"), &tele.SendOptions{DisableWebPagePreview: true})
+		return err
+	}
+
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	command, arg, _ := strings.Cut(strings.TrimSpace(c.Message().Payload), " ")
+	arg = strings.TrimSpace(arg)
+
+	var body string
+	switch strings.ToLower(command) {
+	case "stats":
+		body = a.adminStats(lang)
+	case "broadcast":
+		if arg == "" {
+			body = tr(lang, "admin_bcast_usage")
+			break
+		}
+		chats := a.operator.knownChats()
+		go a.broadcast(c.Chat(), lang, arg, chats)
+		body = tr(lang, "admin_broadcasting", len(chats))
+	case "ban", "unban":
+		userID, ok := adminTargetUser(c.Message(), arg)
+		if !ok {
+			body = tr(lang, "admin_ban_usage", command)
+			break
+		}
+		if a.isOperator(userID) {
+			body = tr(lang, "admin_ban_admin")
+			break
+		}
+		banned := strings.EqualFold(command, "ban")
+		if err := a.operator.setBanned(userID, banned); err != nil {
+			return err
+		}
+		body = tr(lang, "admin_unbanned", userID)
+		if banned {
+			body = tr(lang, "admin_banned", userID)
+		}
+	case "artifacts":
+		body = a.adminArtifacts(lang, arg)
+	case "reload":
+		body = a.reloadReport(lang)
+	case "drain":
+		ctx, cancel := context.WithTimeout(context.Background(), adminDrainLimit)
+		defer cancel()
+		if err := a.queue.drain(ctx); err != nil {
+			busy, pending := a.queue.stats()
+			body = tr(lang, "admin_drain_busy", busy, pending)
+			break
+		}
+		body = tr(lang, "admin_drained")
+	case "resume":
+		a.queue.resume()
+		body = tr(lang, "admin_resumed")
+	default:
+		body = tr(lang, "admin_usage")
+	}
+	_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), body, &tele.SendOptions{DisableWebPagePreview: true})
+	return err
+}
+
+// adminTargetUser reads the user to ban from the argument or the replied-to message.
+func adminTargetUser(msg *tele.Message, arg string) (int64, bool) {
+	if arg != "" {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		return id, err == nil
+	}
+	if msg.ReplyTo != nil && msg.ReplyTo.Sender != nil {
+		return msg.ReplyTo.Sender.ID, true
+	}
+	return 0, false
+}
+
+func (a *App) adminStats(lang language) string {
+	chats, inactive, banned := a.operator.counts()
+	busy, pending := a.queue.stats()
+	day, month := a.usage.globalSnapshot()
+	held, reused := a.generations.stats()
+
+	version := a.version
+	if version == "" {
+		version = tr(lang, "admin_unknown")
+	}
+	slots, running, waiting := a.calls.stats()
+	lines := []string{
+		tr(lang, "admin_stats"),
+		tr(lang, "admin_version", version, time.Since(a.started).Round(time.Second)),
+		tr(lang, "admin_chats", chats, inactive, a.sessions.count()),
+		tr(lang, "admin_busy", busy, pending),
+		formatCallStats(lang, slots, running, waiting),
+		tr(lang, "admin_banned_num", banned),
+		formatArtifactStats(lang, a.artifacts.stats()),
+		tr(lang, "admin_generations", held, reused),
+		formatUsageLine(lang, tr(lang, "admin_today"), day),
+		formatUsageLine(lang, tr(lang, "admin_month"), month),
+	}
+	if reason := a.shedding(); reason != "" {
+		lines = append(lines, tr(lang, "admin_shedding", reason, loadSheddingModel))
+	}
+	if t := a.tuned(); t.dailyBudget > 0 && a.budgetExceeded(0) == budgetDaily {
+		if t.overBudget == overBudgetRefuse {
+			lines = append(lines, tr(lang, "admin_budget_stop", t.dailyBudget, a.usage.nextDay().Format("15:04 MST")))
+		} else {
+			lines = append(lines, tr(lang, "admin_budget_lite", t.dailyBudget, loadSheddingModel, a.usage.nextDay().Format("15:04 MST")))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// broadcast sends text to chats at a pace Telegram accepts and reports the result
+// to the admin chat.
+func (a *App) broadcast(admin *tele.Chat, lang language, text string, chats []int64) {
+	sent := 0
+	for i, id := range chats {
+		if i > 0 {
+			time.Sleep(broadcastPause)
+		}
+		if _, err := a.sendWithFallback(&tele.Chat{ID: id}, text, &tele.SendOptions{DisableWebPagePreview: true}); err != nil {
+			slog.Warn("broadcast failed", "chat_id", id, "err", err)
+			continue
+		}
+		sent++
+	}
+	body := tr(lang, "admin_bcast_done", sent, len(chats))
+	if _, err := a.sendWithFallback(admin, body, &tele.SendOptions{DisableWebPagePreview: true}); err != nil {
+		slog.Warn("broadcast report failed", "err", err)
+	}
+}
+
+// Reload reads the configuration, the webhook actions and the operator state
+// again and describes the outcome in English; SIGHUP runs it.
+func (a *App) Reload() string {
+	return a.reloadReport(defaultLanguage)
+}
+
+// reloadReport is Reload with the outcome described in lang, for /admin reload.
+func (a *App) reloadReport(lang language) string {
+	var lines []string
+	if a.reload != nil {
+		lines = append(lines, a.reloadTunables(lang)...)
+	}
+	if a.actionsFile != "" {
+		actions, err := loadWebhookActions(a.actionsFile)
+		if err != nil {
+			lines = append(lines, tr(lang, "reload_actions_err", err.Error()))
+		} else {
+			a.registerWebhookActions(actions)
+			lines = append(lines, tr(lang, "reload_actions", len(actions)))
+		}
+	}
+	if err := a.operator.load(); err != nil {
+		lines = append(lines, tr(lang, "reload_state_err", err.Error()))
+	} else {
+		chats, _, banned := a.operator.counts()
+		lines = append(lines, tr(lang, "reload_state", chats, banned))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func formatArtifactStats(lang language, st artifactStats) string {
+	hitRate := tr(lang, "admin_na")
+	if lookups := st.Hits + st.Misses; lookups > 0 {
+		hitRate = fmt.Sprintf("%.0f%%", float64(st.Hits)*100/float64(lookups))
+	}
+	return tr(lang, "admin_art_stats", st.Items, formatBytes(st.Bytes), st.Hits, st.Misses, hitRate, st.Evicted)
+}
+
+func formatBytes(n int) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
+
+// adminArtifacts shows the artifact store, lists the replies of one chat or purges them.
+func (a *App) adminArtifacts(lang language, arg string) string {
+	if arg == "" {
+		return formatArtifactStats(lang, a.artifacts.stats())
+	}
+	purge := false
+	if rest, ok := strings.CutPrefix(arg, "purge"); ok {
+		purge, arg = true, strings.TrimSpace(rest)
+	}
+	chatID, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return tr(lang, "admin_art_usage")
+	}
+	if purge {
+		return tr(lang, "admin_art_purged", a.artifacts.purgeChat(chatID), chatID)
+	}
+
+	infos := a.artifacts.forChat(chatID)
+	if len(infos) == 0 {
+		return tr(lang, "admin_art_none", chatID)
+	}
+	const shown = 20
+	var b strings.Builder
+	b.WriteString(tr(lang, "admin_art_list", chatID, len(infos)))
+	if len(infos) > shown {
+		b.WriteString(" " + tr(lang, "admin_art_newest", shown))
+		infos = infos[len(infos)-shown:]
+	}
+	for _, info := range infos {
+		prompt := strings.Join(strings.Fields(info.Prompt), " ")
+		if utf8.RuneCountInString(prompt) > 40 {
+			prompt = string([]rune(prompt)[:39]) + "…"
+		}
+		b.WriteString("\n" + tr(lang, "admin_art_item", info.ID, time.Since(info.CreatedAt).Round(time.Minute), formatBytes(info.Bytes), prompt))
+	}
+	return b.String()
+}
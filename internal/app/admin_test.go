@@ -0,0 +1,53 @@
+package app
+
+import (
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestBannedUserIsIgnored(t *testing.T) {
+	app, apis := newTestApp(t, Config{AdminUserIDs: []int64{7}})
+
+	ban := testMessage(7, "/admin ban 42")
+	ban.Payload = "ban 42"
+	if err := app.handleAdmin(app.bot.NewContext(tele.Update{Message: ban})); err != nil {
+		t.Fatalf("handleAdmin: %v", err)
+	}
+	if texts := apis.sentTexts(); !containsText(texts, "User 42 is banned") {
+		t.Fatalf("sent %q, want the ban confirmed", texts)
+	}
+
+	handled := false
+	handler := app.operatorMiddleware(func(tele.Context) error {
+		handled = true
+		return nil
+	})
+	if err := handler(app.bot.NewContext(tele.Update{Message: testMessage(42, "Hi")})); err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	if handled {
+		t.Fatal("a message from a banned user reached the handler")
+	}
+
+	reopened, err := openOperatorState(app.operator.path)
+	if err != nil {
+		t.Fatalf("openOperatorState: %v", err)
+	}
+	if !reopened.isBanned(42) || len(reopened.knownChats()) != 1 {
+		t.Fatalf("ban or chat list not persisted: banned=%v chats=%v", reopened.isBanned(42), reopened.knownChats())
+	}
+}
+
+func TestAdminRequiresAnExplicitEntry(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+
+	msg := testMessage(7, "/admin stats")
+	msg.Payload = "stats"
+	if err := app.handleAdmin(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+		t.Fatalf("handleAdmin: %v", err)
+	}
+	if texts := apis.sentTexts(); !containsText(texts, "limited to bot administrators") {
+		t.Fatalf("sent %q, want the command refused", texts)
+	}
+}
@@ -0,0 +1,157 @@
+package app
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	maxStopSequences = 5
+	maxPenalty       = 2.0
+)
+
+// generationOverrides holds the expert sampling settings a chat configured with /advanced.
+type generationOverrides struct {
+	stopSequences    []string
+	presencePenalty  *float32
+	frequencyPenalty *float32
+	seed             *int32
+}
+
+func (o generationOverrides) apply(cfg *genai.GenerateContentConfig) {
+	if len(o.stopSequences) > 0 {
+		cfg.StopSequences = slices.Clone(o.stopSequences)
+	}
+	if o.presencePenalty != nil {
+		cfg.PresencePenalty = o.presencePenalty
+	}
+	if o.frequencyPenalty != nil {
+		cfg.FrequencyPenalty = o.frequencyPenalty
+	}
+	if o.seed != nil {
+		cfg.Seed = o.seed
+	}
+}
+
+func (o generationOverrides) describe(lang language) string {
+	var lines []string
+	if len(o.stopSequences) > 0 {
+		quoted := make([]string, len(o.stopSequences))
+		for i, s := range o.stopSequences {
+			quoted[i] = strconv.Quote(s)
+		}
+		lines = append(lines, tr(lang, "advanced_stops", strings.Join(quoted, ", ")))
+	}
+	if o.presencePenalty != nil {
+		lines = append(lines, tr(lang, "advanced_presence", *o.presencePenalty))
+	}
+	if o.frequencyPenalty != nil {
+		lines = append(lines, tr(lang, "advanced_frequency", *o.frequencyPenalty))
+	}
+	if o.seed != nil {
+		lines = append(lines, tr(lang, "advanced_seed", *o.seed))
+	}
+	if len(lines) == 0 {
+		return tr(lang, "advanced_none")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// isAdmin reports whether userID may change expert settings. Without a configured
+// admin list every user is trusted with them.
+func (a *App) isAdmin(userID int64) bool {
+	return len(a.admins) == 0 || slices.Contains(a.admins, userID)
+}
+
+// parsePenalty reads a presence or frequency penalty; ok is false when v is
+// not a number between -maxPenalty and maxPenalty.
+func parsePenalty(v string) (*float32, bool) {
+	f, err := strconv.ParseFloat(v, 32)
+	if err != nil || f < -maxPenalty || f > maxPenalty {
+		return nil, false
+	}
+	p := float32(f)
+	return &p, true
+}
+
+func (a *App) handleAdvanced(c tele.Context) error {
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	if c.Sender() == nil || !a.isAdmin(c.Sender().ID) {
+		_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), tr(lang, "advanced_admins"), &tele.SendOptions{DisableWebPagePreview: true})
+		return err
+	}
+	session := a.sessionOf(c.Message())
+
+	option, value, _ := strings.Cut(strings.TrimSpace(c.Message().Payload), " ")
+	option = strings.ToLower(option)
+	value = strings.TrimSpace(value)
+	off := strings.EqualFold(value, "off")
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	o := &session.overrides
+
+	var problem string
+	switch {
+	case option == "":
+	case option == "reset":
+		*o = generationOverrides{}
+	case option == "stop" && off:
+		o.stopSequences = nil
+	case option == "stop":
+		var seqs []string
+		for _, s := range strings.Split(value, "|") {
+			if s = strings.TrimSpace(s); s != "" {
+				seqs = append(seqs, s)
+			}
+		}
+		if len(seqs) == 0 || len(seqs) > maxStopSequences {
+			problem = tr(lang, "advanced_stop_num", maxStopSequences)
+			break
+		}
+		o.stopSequences = seqs
+	case (option == "presence" || option == "frequency") && off:
+		if option == "presence" {
+			o.presencePenalty = nil
+		} else {
+			o.frequencyPenalty = nil
+		}
+	case option == "presence" || option == "frequency":
+		p, ok := parsePenalty(value)
+		if !ok {
+			problem = tr(lang, "advanced_penalty", -maxPenalty, maxPenalty)
+			break
+		}
+		if option == "presence" {
+			o.presencePenalty = p
+		} else {
+			o.frequencyPenalty = p
+		}
+	case option == "seed" && off:
+		o.seed = nil
+	case option == "seed":
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			problem = tr(lang, "advanced_seed_int")
+			break
+		}
+		seed := int32(n)
+		o.seed = &seed
+	default:
+		problem = tr(lang, "advanced_usage")
+	}
+
+	body := problem
+	if body == "" {
+		body = o.describe(lang)
+		if option == "" {
+			body += "\n\n" + tr(lang, "advanced_usage")
+		}
+	}
+	_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), body, &tele.SendOptions{DisableWebPagePreview: true})
+	return err
+}
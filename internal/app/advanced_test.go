@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestAdvancedCommand(t *testing.T) {
+	app, apis := newTestApp(t, Config{AdminUserIDs: []int64{42}})
+	advanced := func(sender int64, payload string) string {
+		t.Helper()
+		msg := testMessage(42, "/advanced "+payload)
+		msg.Sender.ID = sender
+		msg.Payload = payload
+		if err := app.handleAdvanced(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("handleAdvanced(%q): %v", payload, err)
+		}
+		texts := apis.sentTexts()
+		return strings.ReplaceAll(texts[len(texts)-1], `\`, "")
+	}
+
+	tests := []struct {
+		payload string
+		reply   string
+	}{
+		{"", "No advanced generation settings. Model defaults apply.\n\nUsage: /advanced"},
+		{"stop END | ### |", `Stop sequences: "END", "###"`},
+		{"stop a|b|c|d|e|f", "Give between 1 and 5 stop sequences"},
+		{"presence 0.5", "Presence penalty: 0.5"},
+		{"frequency 2.5", "The penalty must be a number between -2 and 2."},
+		{"Frequency -1", "Frequency penalty: -1"},
+		{"seed 7", "Seed: 7"},
+		{"seed seven", "The seed must be a whole number."},
+		{"temperature 1", "Usage: /advanced"},
+		{"stop off", "Presence penalty: 0.5\nFrequency penalty: -1\nSeed: 7"},
+	}
+	for _, tt := range tests {
+		if got := advanced(42, tt.payload); !strings.Contains(got, tt.reply) {
+			t.Errorf("/advanced %s replied %q, want %q", tt.payload, got, tt.reply)
+		}
+	}
+
+	if err := app.processMessage(context.Background(), testMessage(42, "Hi"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	calls := apis.callsTo(geminiHost, ":generateContent")
+	body := string(calls[len(calls)-1].body)
+	for _, want := range []string{`"presencePenalty":0.5`, `"frequencyPenalty":-1`, `"seed":7`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("request lacks %s", want)
+		}
+	}
+	if strings.Contains(body, "stopSequences") {
+		t.Error("request keeps the stop sequences that were turned off")
+	}
+
+	if got := advanced(42, "reset"); !strings.Contains(got, "No advanced generation settings") {
+		t.Errorf("/advanced reset replied %q", got)
+	}
+	if got := advanced(7, "seed 1"); !strings.Contains(got, "limited to bot administrators") {
+		t.Errorf("a user who is not an admin got %q", got)
+	}
+}
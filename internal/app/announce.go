@@ -0,0 +1,146 @@
+package app
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	versionFile          = "version"
+	maxChangelogDigest   = 3000
+	changelogSectionMark = "## "
+)
+
+//go:embed CHANGELOG.md
+var changelog string
+
+// noteStartup records a configuration or migration step taken while starting,
+// reported to the admins together with a new version.
+func (a *App) noteStartup(format string, args ...any) {
+	a.startupNotes = append(a.startupNotes, fmt.Sprintf(format, args...))
+}
+
+// changelogSince returns the changelog sections above the one for previous, or
+// only the newest section when previous is unknown or not listed.
+func changelogSince(text, previous string) string {
+	var sections []string
+	var current []string
+	flush := func() {
+		if len(current) > 0 {
+			sections = append(sections, strings.TrimSpace(strings.Join(current, "\n")))
+		}
+		current = nil
+	}
+	found := false
+	for _, line := range strings.Split(text, "\n") {
+		if header, ok := strings.CutPrefix(line, changelogSectionMark); ok {
+			flush()
+			if previous != "" && sameVersion(header, previous) {
+				found = true
+				break
+			}
+			current = []string{line}
+			continue
+		}
+		if current != nil {
+			current = append(current, line)
+		}
+	}
+	if !found {
+		flush()
+		if len(sections) > 1 {
+			sections = sections[:1]
+		}
+	}
+	digest := strings.Join(sections, "\n\n")
+	if utf8.RuneCountInString(digest) > maxChangelogDigest {
+		digest = strings.TrimSpace(string([]rune(digest)[:maxChangelogDigest-1])) + "…"
+	}
+	return digest
+}
+
+func sameVersion(header, version string) bool {
+	norm := func(v string) string {
+		v = strings.TrimSpace(v)
+		if fields := strings.Fields(v); len(fields) > 0 {
+			v = fields[0]
+		}
+		return strings.TrimPrefix(strings.ToLower(v), "v")
+	}
+	return norm(header) == norm(version)
+}
+
+// versionDigest is the message the admins receive when a new version starts.
+func versionDigest(previous, version, changes string, notes []string) string {
+	var b strings.Builder
+	if previous == "" {
+		fmt.Fprintf(&b, "Eteon %s started for the first time.", version)
+	} else {
+		fmt.Fprintf(&b, "Eteon was updated from %s to %s.", previous, version)
+	}
+	if changes != "" {
+		b.WriteString("\n\nWhat's new:\n")
+		b.WriteString(changes)
+	}
+	if len(notes) > 0 {
+		b.WriteString("\n\nStartup actions:")
+		for _, note := range notes {
+			b.WriteString("\n- ")
+			b.WriteString(note)
+		}
+	}
+	return b.String()
+}
+
+// announceVersion tells the admins about a version change since the last start
+// and records the running version in the data directory.
+func (a *App) announceVersion() {
+	if a.version == "" {
+		return
+	}
+	path := filepath.Join(a.dataDir, versionFile)
+	raw, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("read version file failed", "err", err)
+		return
+	}
+	previous := strings.TrimSpace(string(raw))
+	if previous == a.version {
+		return
+	}
+
+	if err := os.MkdirAll(a.dataDir, 0o700); err != nil {
+		slog.Warn("create data directory failed", "err", err)
+		return
+	}
+	if err := os.WriteFile(path, []byte(a.version+"\n"), 0o600); err != nil {
+		slog.Warn("write version file failed", "err", err)
+		return
+	}
+	a.noteStartup("Recorded version %s in %s", a.version, path)
+	slog.Info("new version started", "previous", previous, "version", a.version)
+
+	digest := versionDigest(previous, a.version, changelogSince(changelog, previous), a.startupNotes)
+	for _, id := range a.announceChats() {
+		if _, err := a.sendWithFallback(&tele.Chat{ID: id}, digest, &tele.SendOptions{DisableWebPagePreview: true}); err != nil {
+			slog.Warn("send version announcement failed", "chat_id", id, "err", err)
+		}
+	}
+}
+
+// announceChats returns the configured admin chat, or else the private chats of
+// the admin users.
+func (a *App) announceChats() []int64 {
+	if a.adminChatID != 0 {
+		return []int64{a.adminChatID}
+	}
+	return a.admins
+}
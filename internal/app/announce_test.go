@@ -0,0 +1,22 @@
+package app
+
+import "testing"
+
+func TestChangelogSince(t *testing.T) {
+	text := "# Changelog\n\nIntro.\n\n## v1.2.0\n\n- Replay.\n\n## v1.1.0\n\n- Summaries.\n\n## v1.0.0\n\n- First release.\n"
+
+	cases := []struct {
+		previous string
+		want     string
+	}{
+		{"v1.0.0", "## v1.2.0\n\n- Replay.\n\n## v1.1.0\n\n- Summaries."},
+		{"1.1.0", "## v1.2.0\n\n- Replay."},
+		{"", "## v1.2.0\n\n- Replay."},
+		{"abc123", "## v1.2.0\n\n- Replay."},
+	}
+	for _, tc := range cases {
+		if got := changelogSince(text, tc.previous); got != tc.want {
+			t.Errorf("changelogSince(%q) = %q, want %q", tc.previous, got, tc.want)
+		}
+	}
+}
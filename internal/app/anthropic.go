@@ -0,0 +1,213 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+const (
+	anthropicVersion = "2023-06-01"
+	// anthropicMaxTokens is the answer length asked for when the request sets
+	// none; the Messages API requires one. A thinking budget comes on top.
+	anthropicMaxTokens = 8192
+	// anthropicMinThinking is the smallest thinking budget the API accepts.
+	anthropicMinThinking = 1024
+)
+
+// anthropicProvider talks to the Messages API of Anthropic.
+type anthropicProvider struct {
+	baseURL string
+	apiKey  string
+	caps    capabilities
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"`
+	Content []map[string]any `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model         string             `json:"model"`
+	System        string             `json:"system,omitempty"`
+	Messages      []anthropicMessage `json:"messages"`
+	MaxTokens     int32              `json:"max_tokens"`
+	Temperature   *float32           `json:"temperature,omitempty"`
+	TopP          *float32           `json:"top_p,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Tools         []any              `json:"tools,omitempty"`
+	Thinking      any                `json:"thinking,omitempty"`
+}
+
+type anthropicResponse struct {
+	Model   string `json:"model"`
+	Content []struct {
+		Type      string         `json:"type"`
+		Text      string         `json:"text"`
+		Thinking  string         `json:"thinking"`
+		Signature string         `json:"signature"`
+		ID        string         `json:"id"`
+		Name      string         `json:"name"`
+		Input     map[string]any `json:"input"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int32 `json:"input_tokens"`
+		OutputTokens int32 `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+func (p *anthropicProvider) capabilities() capabilities {
+	return p.caps
+}
+
+func (p *anthropicProvider) generateContent(ctx context.Context, model string, contents []*genai.Content, cfg *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	if cfg == nil {
+		cfg = &genai.GenerateContentConfig{}
+	}
+	req := anthropicRequest{
+		Model:         model,
+		System:        systemText(cfg.SystemInstruction),
+		Messages:      anthropicMessages(contents),
+		MaxTokens:     cfg.MaxOutputTokens,
+		TopP:          cfg.TopP,
+		StopSequences: cfg.StopSequences,
+	}
+	if req.MaxTokens <= 0 {
+		req.MaxTokens = anthropicMaxTokens
+	}
+	if t := cfg.Temperature; t != nil {
+		// Gemini goes up to 2, Anthropic to 1.
+		req.Temperature = genai.Ptr(min(*t, 1))
+	}
+	if cfg.ResponseMIMEType == "application/json" {
+		req.System = strings.TrimSpace(req.System + "\n\n" + jsonAnswerInstruction(cfg.ResponseSchema))
+	}
+	for _, decl := range functionDeclarations(cfg.Tools) {
+		req.Tools = append(req.Tools, map[string]any{
+			"name":         decl.Name,
+			"description":  decl.Description,
+			"input_schema": declarationSchema(decl),
+		})
+	}
+	if budget := anthropicThinkingBudget(cfg.ThinkingConfig); budget > 0 {
+		req.Thinking = map[string]any{"type": "enabled", "budget_tokens": budget}
+		req.MaxTokens += budget
+		// Thinking only runs with the default sampling.
+		req.Temperature, req.TopP = nil, nil
+	}
+
+	header := http.Header{}
+	header.Set("x-api-key", p.apiKey)
+	header.Set("anthropic-version", anthropicVersion)
+	var resp anthropicResponse
+	if err := postJSON(ctx, p.baseURL+"/messages", header, req, &resp); err != nil {
+		return nil, err
+	}
+	var parts []*genai.Part
+	for _, block := range resp.Content {
+		switch block.Type {
+		case "text":
+			parts = append(parts, genai.NewPartFromText(block.Text))
+		case "thinking":
+			// The signature lets the thinking go back with the tool results.
+			parts = append(parts, &genai.Part{Text: block.Thinking, Thought: true, ThoughtSignature: []byte(block.Signature)})
+		case "tool_use":
+			part := genai.NewPartFromFunctionCall(block.Name, block.Input)
+			part.FunctionCall.ID = block.ID
+			parts = append(parts, part)
+		}
+	}
+	finish := genai.FinishReasonStop
+	switch resp.StopReason {
+	case "max_tokens":
+		finish = genai.FinishReasonMaxTokens
+	case "refusal":
+		finish = genai.FinishReasonSafety
+	}
+	return modelResponse(resp.Model, parts, finish, resp.Usage.InputTokens, resp.Usage.OutputTokens), nil
+}
+
+// anthropicThinkingBudget maps a Gemini thinking configuration to a budget
+// the API accepts; zero turns thinking off.
+func anthropicThinkingBudget(thinking *genai.ThinkingConfig) int32 {
+	if thinking == nil || thinking.ThinkingBudget == nil {
+		return 0
+	}
+	switch budget := *thinking.ThinkingBudget; {
+	case budget == 0:
+		return 0
+	case budget < 0:
+		// Dynamic thinking has no counterpart; take a medium budget.
+		return 8192
+	default:
+		return max(budget, anthropicMinThinking)
+	}
+}
+
+// anthropicMessages converts a conversation to messages, merging contents of
+// the same role since the API wants the roles to alternate.
+func anthropicMessages(contents []*genai.Content) []anthropicMessage {
+	var messages []anthropicMessage
+	for _, content := range contents {
+		if content == nil {
+			continue
+		}
+		role := "user"
+		if content.Role == genai.RoleModel {
+			role = "assistant"
+		}
+		var blocks []map[string]any
+		for _, part := range content.Parts {
+			switch {
+			case part == nil:
+			case part.Thought:
+				if len(part.ThoughtSignature) > 0 && role == "assistant" {
+					blocks = append(blocks, map[string]any{"type": "thinking", "thinking": part.Text, "signature": string(part.ThoughtSignature)})
+				}
+			case part.Text != "":
+				blocks = append(blocks, map[string]any{"type": "text", "text": part.Text})
+			case part.InlineData != nil:
+				blocks = append(blocks, anthropicMedia(part.InlineData))
+			case part.FileData != nil:
+				blocks = append(blocks, map[string]any{"type": "text", "text": "[An attachment stored with Gemini was left out.]"})
+			case part.FunctionCall != nil:
+				input := part.FunctionCall.Args
+				if input == nil {
+					input = map[string]any{}
+				}
+				blocks = append(blocks, map[string]any{"type": "tool_use", "id": toolCallID(part.FunctionCall.ID, part.FunctionCall.Name), "name": part.FunctionCall.Name, "input": input})
+			case part.FunctionResponse != nil:
+				result, _ := json.Marshal(part.FunctionResponse.Response)
+				blocks = append(blocks, map[string]any{"type": "tool_result", "tool_use_id": toolCallID(part.FunctionResponse.ID, part.FunctionResponse.Name), "content": string(result)})
+			}
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+		if n := len(messages); n > 0 && messages[n-1].Role == role {
+			messages[n-1].Content = append(messages[n-1].Content, blocks...)
+			continue
+		}
+		messages = append(messages, anthropicMessage{Role: role, Content: blocks})
+	}
+	return messages
+}
+
+// anthropicMedia converts an attachment to a content block: images and PDFs
+// as base64, anything else as a note.
+func anthropicMedia(blob *genai.Blob) map[string]any {
+	source := map[string]string{"type": "base64", "media_type": blob.MIMEType, "data": base64.StdEncoding.EncodeToString(blob.Data)}
+	switch {
+	case strings.HasPrefix(blob.MIMEType, "image/"):
+		return map[string]any{"type": "image", "source": source}
+	case blob.MIMEType == "application/pdf":
+		return map[string]any{"type": "document", "source": source}
+	}
+	return map[string]any{"type": "text", "text": fmt.Sprintf("[A %s attachment was left out: this model cannot read it.]", blob.MIMEType)}
+}
@@ -5,11 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"mime"
 	"net/http"
+	"net/url"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"google.golang.org/genai"
@@ -28,27 +30,288 @@ const (
 type Config struct {
 	TelegramToken string
 	GeminiAPIKey  string
+
+	// TestEnvironment connects to Telegram's test environment, whose bots and
+	// users are separate from production. TelegramToken must then come from the
+	// test BotFather and GeminiAPIKey should belong to a sandbox project.
+	TestEnvironment bool
+
+	// PIIDetectors selects which detectors mask stored history; nil enables all of them.
+	PIIDetectors []string
+	// KeepOriginalHistory disables masking for deployments that must retain verbatim turns.
+	KeepOriginalHistory bool
+
+	// FallbackModel answers when the primary model keeps failing; empty selects the
+	// default and "none" disables the fallback.
+	FallbackModel string
+
+	// OpenWeatherAPIKey enables the current_weather tool.
+	OpenWeatherAPIKey string
+	// ToolsUserAgent identifies the bot to public APIs such as Nominatim and
+	// to the sites it fetches pages from when URL context cannot.
+	ToolsUserAgent string
+
+	// ActionsFile points to a JSON list of webhook actions the model may invoke.
+	ActionsFile string
+
+	// DataDir holds files the bot persists between restarts.
+	DataDir string
+	// PrefsEncryptionKey enables the encrypted per-user preferences store.
+	PrefsEncryptionKey string
+
+	// GroupContext seeds group sessions with the chat description and pinned
+	// message so the bot knows the group's purpose and rules.
+	GroupContext bool
+
+	// AdminUserIDs may change expert settings such as /advanced; empty allows everyone.
+	// The /admin commands always require an entry.
+	AdminUserIDs []int64
+	// AdminChatID receives announcements such as new versions; zero sends them to
+	// each admin user privately.
+	AdminChatID int64
+
+	// Version identifies the running build. When it differs from the version of
+	// the previous start, the admins get a digest of the changes.
+	Version string
+
+	// InlineMediaLimit is the largest file, in bytes, sent inline; bigger files go
+	// through the Gemini Files API. Zero selects the default of 4 MB, negative
+	// disables uploads. Telegram lets bots download files up to 20 MB; larger
+	// ones need a local Bot API server and are turned down.
+	InlineMediaLimit int64
+
+	// InactiveChatGrace is how long the data of a chat that blocked or removed
+	// the bot is kept before it is purged. Zero selects 30 days, negative keeps
+	// it for good.
+	InactiveChatGrace time.Duration
+
+	// RedisURL, a redis:// or rediss:// URL, keeps sessions and reply artifacts
+	// in Redis so several instances of the bot can share them.
+	RedisURL string
+	// SQLitePath is the SQLite database that keeps the sessions, with their
+	// history and settings, and the reply artifacts behind the buttons across
+	// restarts when RedisURL is empty: eteon.db in DataDir when empty, none
+	// to keep them in memory only.
+	SQLitePath string
+
+	// Model answers the turns; empty selects gemini-2.5-pro.
+	Model string
+	// ThinkingMode is the thinking budget new chats start with: low, medium,
+	// high or dynamic. Empty selects medium.
+	ThinkingMode string
+
+	// RateLimit is how many messages and button taps a user may send per
+	// minute; zero disables the limit. Operators are exempt.
+	RateLimit int
+
+	// AllowedUserIDs and AllowedChatIDs restrict the bot to these users and
+	// chats: an update passes when its sender or its chat is listed. Both empty
+	// allow everyone; operators always pass.
+	AllowedUserIDs []int64
+	AllowedChatIDs []int64
+
+	// WebhookURL, a public https URL, makes Telegram push updates to the bot
+	// instead of the bot polling for them. WebhookListen is the address the
+	// bot serves it on, :8443 when empty, and WebhookSecret the token Telegram
+	// sends along so forged updates are turned away.
+	WebhookURL    string
+	WebhookListen string
+	WebhookSecret string
+
+	// DisabledTools turns tools off by name: the built-in google_search,
+	// url_context and code_execution, or a function such as current_weather.
+	DisabledTools []string
+	// ToolRouting picks the tools of a turn that could use both functions and
+	// the built-in tools, which Gemini rejects in one request: "model" (the
+	// default) asks a small model first, "functions" and "builtin" always
+	// offer those without the extra call.
+	ToolRouting string
+
+	// SystemPrompt replaces the default system instruction.
+	SystemPrompt string
+
+	// Pipelines are prompt chains run by their own commands, so new workflows
+	// need no code. Changes need a restart.
+	Pipelines []PipelineConfig
+
+	// Providers add model backends besides Gemini, addressed as
+	// name:model. Changes need a restart.
+	Providers []ProviderConfig
+
+	// ReplyHooks rewrite every reply before it is sent: disclaimers,
+	// no_self_reference and shorten_links. Changes need a restart.
+	ReplyHooks []string
+	// LinkShortenerURL is the service shorten_links asks, with {url} standing
+	// for the escaped link; it answers with the short link as plain text.
+	LinkShortenerURL string
+
+	// LoadSheddingDepth is how many requests, running or waiting across all
+	// chats, make new requests use gemini-2.5-flash in place of a Pro model
+	// and think less, with a notice; zero uses 20 and a negative value turns
+	// this off.
+	LoadSheddingDepth int
+	// MonthlyBudgetUSD is the estimated spend per month at 90% of which new
+	// requests go light the same way; zero sets no budget. It never blocks
+	// requests.
+	MonthlyBudgetUSD float64
+	// DailyBudgetUSD is the estimated spend per day of the whole bot, and
+	// UserDailyBudgetUSD that of the turns each user starts, from which
+	// requests do what OverBudget says until the day ends: "light" (the
+	// default) answers the same way as under load, "refuse" turns them down
+	// politely. Zero sets no limit, and admins have no limit of their own.
+	DailyBudgetUSD     float64
+	UserDailyBudgetUSD float64
+	OverBudget         string
+	// MaxConcurrentCalls is how many model calls run at once across all
+	// chats; further calls wait in line, at most four per slot, and the user
+	// learns their place and the expected wait. Zero uses 8 and a negative
+	// value sets no limit. Changes need a restart.
+	MaxConcurrentCalls int
+
+	// ControlListen is the address of the gRPC control API for orchestration
+	// tooling: the standard health service plus status, drain, feature
+	// switches, quotas and session eviction. Empty turns it off. ControlToken
+	// is the bearer token the control calls must carry.
+	ControlListen string
+	ControlToken  string
+
+	// SentimentAlerts rates how frustrated users are with a small model and
+	// alerts the admin chat, or the admins, when a chat grows increasingly
+	// frustrated or asks for a person.
+	SentimentAlerts bool
+
+	// Reload reads the configuration again for /admin reload; nil disables the
+	// command. Only the tunables App.Reload lists take effect without a restart.
+	Reload func() (Config, error)
 }
 
 // Validate ensures the configuration includes mandatory values.
 func (c Config) Validate() error {
+	tokenVar, keyVar := "TELEGRAM_BOT_TOKEN", "GEMINI_API_KEY"
+	if c.TestEnvironment {
+		tokenVar, keyVar = "TELEGRAM_TEST_BOT_TOKEN", "GEMINI_TEST_API_KEY"
+	}
 	if strings.TrimSpace(c.TelegramToken) == "" {
-		return errors.New("TELEGRAM_BOT_TOKEN is required")
+		return fmt.Errorf("%s is required", tokenVar)
 	}
 	if strings.TrimSpace(c.GeminiAPIKey) == "" {
-		return errors.New("GEMINI_API_KEY is required")
+		return fmt.Errorf("%s is required", keyVar)
 	}
-	return nil
+	if err := validatePIIDetectors(c.PIIDetectors); err != nil {
+		return fmt.Errorf("PII_DETECTORS: %w", err)
+	}
+	if mode := strings.TrimSpace(c.ThinkingMode); mode != "" && parseThinkingMode(mode) != thinkingMode(mode) {
+		return fmt.Errorf("THINKING_MODE: unknown mode %q, want low, medium, high or dynamic", mode)
+	}
+	if c.MonthlyBudgetUSD < 0 {
+		return fmt.Errorf("MONTHLY_BUDGET_USD: %g is negative", c.MonthlyBudgetUSD)
+	}
+	if c.DailyBudgetUSD < 0 {
+		return fmt.Errorf("DAILY_BUDGET_USD: %g is negative", c.DailyBudgetUSD)
+	}
+	if c.UserDailyBudgetUSD < 0 {
+		return fmt.Errorf("USER_DAILY_BUDGET_USD: %g is negative", c.UserDailyBudgetUSD)
+	}
+	if _, err := parseOverBudget(c.OverBudget); err != nil {
+		return fmt.Errorf("OVER_BUDGET: %w", err)
+	}
+	if _, err := parseToolRouting(c.ToolRouting); err != nil {
+		return fmt.Errorf("TOOL_ROUTING: %w", err)
+	}
+	if strings.TrimSpace(c.ControlListen) != "" && strings.TrimSpace(c.ControlToken) == "" {
+		return fmt.Errorf("CONTROL_LISTEN needs CONTROL_TOKEN to authenticate the control calls")
+	}
+	if c.RateLimit < 0 {
+		return fmt.Errorf("RATE_LIMIT: %d is negative", c.RateLimit)
+	}
+	if raw := strings.TrimSpace(c.WebhookURL); raw != "" {
+		if u, err := url.Parse(raw); err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("WEBHOOK_URL: %q is not an https URL", raw)
+		}
+	}
+	if err := validatePipelines(c.Pipelines, c.DisabledTools); err != nil {
+		return err
+	}
+	if c.SentimentAlerts && c.AdminChatID == 0 && len(c.AdminUserIDs) == 0 {
+		return fmt.Errorf("SENTIMENT_ALERTS needs ADMIN_CHAT_ID or ADMIN_USER_IDS to send the alerts to")
+	}
+	if err := validateReplyHooks(c.ReplyHooks, strings.TrimSpace(c.LinkShortenerURL)); err != nil {
+		return fmt.Errorf("REPLY_HOOKS: %w", err)
+	}
+	models := []string{strings.TrimSpace(c.Model), strings.TrimSpace(c.FallbackModel)}
+	for _, p := range c.Pipelines {
+		for _, step := range p.Steps {
+			models = append(models, strings.TrimSpace(step.Model))
+		}
+	}
+	return validateProviders(c.Providers, models...)
 }
 
 // App wires Telegram updates to the Gemini client.
 type App struct {
-	bot               *tele.Bot
-	client            *genai.Client
-	sessions          *sessionManager
-	artifacts         *artifactStore
-	systemInstruction *genai.Content
-	tools             []*genai.Tool
+	bot              *tele.Bot
+	client           *genai.Client
+	sessions         *sessionManager
+	catchup          *catchupBuffers
+	artifacts        *artifactStore
+	pii              *piiMasker
+	usage            *usageTracker
+	generations      *generationCache
+	tuning           atomic.Pointer[tunables]
+	reload           func() (Config, error)
+	queue            *chatQueue
+	calls            *callLimiter
+	access           *accessControl
+	functions        *functionRegistry
+	rates            *rateCache
+	extracts         *extractCache
+	media            *mediaCache
+	geo              *geoClient
+	robots           *robotsCache
+	actionConfirms   *actionConfirmations
+	prefs            *prefsStore
+	inlineMediaLimit int64
+	inactiveGrace    time.Duration
+	groupContext     bool
+	testEnvironment  bool
+	admins           []int64
+	adminChatID      int64
+	version          string
+	dataDir          string
+	startupNotes     []string
+	started          time.Time
+	operator         *operatorState
+	sampling         *samplingStore
+	privacy          *privacyStore
+	ephemeral        *ephemeralStore
+	replyTo          *replyToStore
+	topics           *topicStore
+	sendTargets      *sendTargetStore
+	sendDrafts       *sendDrafts
+	comparisons      *docComparisons
+	pipelines        []pipeline
+	providers        map[string]provider
+	replyHooks       *replyHooks
+	contextCaches    *contextCaches
+	features         featureFlags
+	controlListen    string
+	controlToken     string
+	// sentiment is nil unless Config.SentimentAlerts is set.
+	sentiment   *sentimentTracker
+	reminders   *reminderStore
+	bookmarks   *bookmarkStore
+	styles      *styleStore
+	memories    *memoryStore
+	kb          *kbStore
+	recall      *recallStore
+	shared      *redisState
+	sqlite      *sqliteState
+	repos       *repoStore
+	actionsFile string
+	actionNames []string
+	tools       []*genai.Tool
+	// toolRouting is Config.ToolRouting, parsed.
+	toolRouting string
 }
 
 // New initialises the Telegram bot and Gemini client.
@@ -62,52 +325,395 @@ func New(ctx context.Context, cfg Config) (*App, error) {
 		return nil, fmt.Errorf("create genai client: %w", err)
 	}
 
+	// app is set before the bot handles any update.
+	var app *App
 	bot, err := tele.NewBot(tele.Settings{
-		Token:     cfg.TelegramToken,
+		Token:     telegramToken(cfg),
 		ParseMode: tele.ModeMarkdownV2,
-		Poller:    &tele.LongPoller{Timeout: 10 * time.Second},
+		Poller:    newPoller(cfg),
+		OnError: func(err error, c tele.Context) {
+			if c != nil && c.Chat() != nil {
+				app.chatLog(c.Chat().ID).Error("telegram handler failed", "chat_id", c.Chat().ID, "err", err)
+				return
+			}
+			slog.Error("telegram handler failed", "err", err)
+		},
 	})
 	if err != nil {
 		return nil, fmt.Errorf("create telebot: %w", err)
 	}
 
-	app := &App{
-		bot:               bot,
-		client:            client,
-		sessions:          newSessionManager(defaultThinkingMode()),
-		artifacts:         newArtifactStore(),
-		systemInstruction: buildSystemInstruction(),
-		tools: []*genai.Tool{
-			{
-				GoogleSearchRetrieval: &genai.GoogleSearchRetrieval{},
-				URLContext:            &genai.URLContext{},
-				CodeExecution:         &genai.ToolCodeExecution{},
-			},
-		},
+	app = &App{
+		bot:             bot,
+		client:          client,
+		sessions:        newSessionManager(parseThinkingMode(strings.TrimSpace(cfg.ThinkingMode))),
+		catchup:         newCatchupBuffers(),
+		sendDrafts:      newSendDrafts(),
+		comparisons:     newDocComparisons(),
+		artifacts:       newArtifactStore(),
+		usage:           newUsageTracker(),
+		generations:     newGenerationCache(),
+		contextCaches:   newContextCaches(),
+		repos:           newRepoStore(),
+		queue:           newChatQueue(),
+		calls:           newCallLimiter(cfg.MaxConcurrentCalls),
+		access:          newAccessControl(cfg.AllowedUserIDs, cfg.AllowedChatIDs, cfg.RateLimit),
+		functions:       newFunctionRegistry(),
+		rates:           newRateCache(),
+		extracts:        newExtractCache(),
+		media:           newMediaCache(),
+		robots:          newRobotsCache(),
+		actionConfirms:  newActionConfirmations(),
+		groupContext:    cfg.GroupContext,
+		testEnvironment: cfg.TestEnvironment,
+		admins:          cfg.AdminUserIDs,
+		adminChatID:     cfg.AdminChatID,
+		version:         strings.TrimSpace(cfg.Version),
+		dataDir:         dataDir(cfg),
+		started:         time.Now(),
+		actionsFile:     strings.TrimSpace(cfg.ActionsFile),
+		tools:           builtinTools(cfg.DisabledTools),
+		reload:          cfg.Reload,
+	}
+	app.tuning.Store(newTunables(cfg))
+	app.toolRouting, _ = parseToolRouting(cfg.ToolRouting)
+
+	if !cfg.KeepOriginalHistory {
+		app.pii = newPIIMasker(cfg.PIIDetectors)
+	} else {
+		app.noteStartup("PII masking is off; history is stored verbatim")
+	}
+	userAgent := strings.TrimSpace(cfg.ToolsUserAgent)
+	if userAgent == "" {
+		userAgent = defaultToolsUserAgent
+	}
+	app.geo = &geoClient{userAgent: userAgent, openWeatherKey: strings.TrimSpace(cfg.OpenWeatherAPIKey)}
+
+	app.inactiveGrace = cfg.InactiveChatGrace
+	if app.inactiveGrace == 0 {
+		app.inactiveGrace = defaultInactiveGrace
+	}
+
+	switch {
+	case cfg.InlineMediaLimit == 0:
+		app.inlineMediaLimit = defaultInlineMediaLimit
+	case cfg.InlineMediaLimit > 0:
+		app.inlineMediaLimit = cfg.InlineMediaLimit
+	}
+
+	if url := strings.TrimSpace(cfg.RedisURL); url != "" {
+		shared, err := openRedisState(ctx, url)
+		if err != nil {
+			return nil, fmt.Errorf("open shared state: %w", err)
+		}
+		if err := app.usage.load(ctx, shared); err != nil {
+			shared.Close()
+			return nil, fmt.Errorf("load usage from redis: %w", err)
+		}
+		app.shared = shared
+		app.sessions.shared = shared
+		app.artifacts.shared = shared
+		app.noteStartup("Sharing sessions and replies through Redis at %s", shared.addr())
+	} else if err := app.openSQLite(ctx, cfg.SQLitePath); err != nil {
+		return nil, err
+	}
+
+	if cfg.PrefsEncryptionKey != "" {
+		path := filepath.Join(app.dataDir, "prefs.enc")
+		store, err := openPrefsStore(path, cfg.PrefsEncryptionKey)
+		if err != nil {
+			return nil, fmt.Errorf("open preferences: %w", err)
+		}
+		app.prefs = store
+		app.noteStartup("Opened the encrypted preferences store at %s", path)
+	} else {
+		app.noteStartup("Per-user preferences are off without an encryption key: /languages, /calendar and Notion exports are unavailable")
+	}
+
+	operator, err := openOperatorState(filepath.Join(app.dataDir, "admin.json"))
+	if err != nil {
+		return nil, fmt.Errorf("open admin state: %w", err)
+	}
+	app.operator = operator
+
+	sampling, err := openSamplingStore(filepath.Join(app.dataDir, "sampling.json"))
+	if err != nil {
+		return nil, fmt.Errorf("open sampling settings: %w", err)
+	}
+	app.sampling = sampling
+
+	privacy, err := openPrivacyStore(ctx, filepath.Join(app.dataDir, "privacy.json"), app.settingsBackend())
+	if err != nil {
+		return nil, fmt.Errorf("open privacy mode: %w", err)
+	}
+	app.privacy = privacy
+
+	ephemeral, err := openEphemeralStore(filepath.Join(app.dataDir, "ephemeral.json"))
+	if err != nil {
+		return nil, fmt.Errorf("open ephemeral mode: %w", err)
+	}
+	app.ephemeral = ephemeral
+
+	replyTo, err := openReplyToStore(ctx, filepath.Join(app.dataDir, "replyto.json"), app.settingsBackend())
+	if err != nil {
+		return nil, fmt.Errorf("open reply mode: %w", err)
+	}
+	app.replyTo = replyTo
+
+	topics, err := openTopicStore(filepath.Join(app.dataDir, "topics.json"))
+	if err != nil {
+		return nil, fmt.Errorf("open topic settings: %w", err)
+	}
+	app.topics = topics
+
+	sendTargets, err := openSendTargetStore(filepath.Join(app.dataDir, "sendto.json"))
+	if err != nil {
+		return nil, fmt.Errorf("open send targets: %w", err)
+	}
+	app.sendTargets = sendTargets
+
+	reminders, err := openReminderStore(filepath.Join(app.dataDir, "reminders.json"))
+	if err != nil {
+		return nil, fmt.Errorf("open reminders: %w", err)
+	}
+	app.reminders = reminders
+
+	bookmarks, err := openBookmarkStore(filepath.Join(app.dataDir, "bookmarks.json"))
+	if err != nil {
+		return nil, fmt.Errorf("open bookmarks: %w", err)
+	}
+	app.bookmarks = bookmarks
+
+	styles, err := openStyleStore(filepath.Join(app.dataDir, "styles.json"))
+	if err != nil {
+		return nil, fmt.Errorf("open styles: %w", err)
+	}
+	app.styles = styles
+
+	memories, err := openMemoryStore(filepath.Join(app.dataDir, "memories.json"))
+	if err != nil {
+		return nil, fmt.Errorf("open memories: %w", err)
+	}
+	if err := memories.adoptStyleNotes(styles); err != nil {
+		return nil, fmt.Errorf("move style notes to memories: %w", err)
+	}
+	app.memories = memories
+	app.kb = newKBStore(filepath.Join(app.dataDir, "kb"))
+	app.recall = newRecallStore(filepath.Join(app.dataDir, "recall"))
+
+	app.registerConversionTools()
+	app.registerGeoTools()
+	if app.prefs != nil {
+		app.registerCalendarTools()
+	}
+	if path := app.actionsFile; path != "" {
+		actions, err := loadWebhookActions(path)
+		if err != nil {
+			return nil, fmt.Errorf("load actions: %w", err)
+		}
+		app.registerWebhookActions(actions)
+		app.noteStartup("Loaded %d webhook actions from %s", len(actions), path)
+	}
+
+	if err := app.disableTools(cfg.DisabledTools); err != nil {
+		return nil, err
+	}
+
+	tuned := app.tuned()
+	if tuned.fallbackModel == "" {
+		app.noteStartup("Model fallback is disabled")
+	} else {
+		app.noteStartup("Fallback model: %s", tuned.fallbackModel)
+	}
+	if tuned.model != geminiModel {
+		app.noteStartup("Model: %s", tuned.model)
+	}
+	if cfg.SystemPrompt != "" {
+		app.noteStartup("Using the configured system prompt")
+	}
+	if app.access.restricted() {
+		app.noteStartup("Access is limited to %d users and %d chats", len(cfg.AllowedUserIDs), len(cfg.AllowedChatIDs))
+	}
+	if cfg.RateLimit > 0 {
+		app.noteStartup("Rate limit: %d updates per user and minute", cfg.RateLimit)
+	}
+	if cfg.WebhookURL != "" {
+		app.noteStartup("Receiving updates through the webhook at %s", strings.TrimSpace(cfg.WebhookURL))
+	}
+	app.providers = make(map[string]provider)
+	for _, pc := range cfg.Providers {
+		app.providers[providerName(pc)] = newProvider(pc)
+		app.noteStartup("Models of %s are available as %s:<model>", pc.Kind, providerName(pc))
+	}
+	for _, pc := range cfg.Pipelines {
+		app.pipelines = append(app.pipelines, newPipeline(pc))
+	}
+	if len(app.pipelines) > 0 {
+		commands := make([]string, len(app.pipelines))
+		for i, p := range app.pipelines {
+			commands[i] = "/" + p.name
+		}
+		app.noteStartup("Pipelines: %s", strings.Join(commands, ", "))
+	}
+	app.replyHooks = newReplyHooks(cfg)
+	app.controlListen = strings.TrimSpace(cfg.ControlListen)
+	app.controlToken = strings.TrimSpace(cfg.ControlToken)
+	if cfg.SentimentAlerts {
+		app.sentiment = newSentimentTracker()
+		app.noteStartup("Alerting the operators when users grow frustrated")
+	}
+	if len(app.replyHooks.names) > 0 {
+		app.noteStartup("Reply hooks: %s", strings.Join(app.replyHooks.names, ", "))
 	}
 
 	app.registerHandlers()
 	return app, nil
 }
 
-// Run starts the Telegram polling loop.
+func dataDir(cfg Config) string {
+	if dir := strings.TrimSpace(cfg.DataDir); dir != "" {
+		return dir
+	}
+	if cfg.TestEnvironment {
+		return "data-test"
+	}
+	return "data"
+}
+
+// telegramToken returns the token as telebot puts it into request paths. The
+// test environment serves both the API methods and file downloads under
+// /bot<token>/test/, so the suffix routes every request there.
+func telegramToken(cfg Config) string {
+	if cfg.TestEnvironment {
+		return cfg.TelegramToken + "/test"
+	}
+	return cfg.TelegramToken
+}
+
+// defaultWebhookListen is the address the webhook is served on when
+// Config.WebhookListen is empty; Telegram delivers to ports 443, 80, 88 and
+// 8443, and a proxy usually forwards one of them here.
+const defaultWebhookListen = ":8443"
+
+// newPoller receives updates through the webhook when one is configured and
+// polls Telegram otherwise.
+func newPoller(cfg Config) tele.Poller {
+	public := strings.TrimSpace(cfg.WebhookURL)
+	if public == "" {
+		return &tele.LongPoller{Timeout: 10 * time.Second}
+	}
+	listen := strings.TrimSpace(cfg.WebhookListen)
+	if listen == "" {
+		listen = defaultWebhookListen
+	}
+	return &tele.Webhook{
+		Listen:      listen,
+		SecretToken: cfg.WebhookSecret,
+		Endpoint:    &tele.WebhookEndpoint{PublicURL: public},
+	}
+}
+
+// Run checks the configuration, starts the control API if configured and
+// the Telegram polling loop.
 func (a *App) Run(ctx context.Context) error {
+	if err := a.preflight(ctx); err != nil {
+		return err
+	}
+	// The control API outlives the drain below, so probes see it.
+	stopControl, err := a.serveControl()
+	if err != nil {
+		return err
+	}
+	defer stopControl()
 	go func() {
 		<-ctx.Done()
 		a.bot.Stop()
 	}()
+	go a.runScheduler(ctx)
+	a.announceVersion()
 	a.bot.Start()
+
+	// Let the requests that are already running finish before exiting.
+	drainCtx, cancel := context.WithTimeout(context.Background(), shutdownDrainCap)
+	defer cancel()
+	if err := a.queue.drain(drainCtx); err != nil {
+		busy, _ := a.queue.stats()
+		slog.Warn("stopping with requests still running", "busy_chats", busy)
+	}
+	if a.shared != nil {
+		if err := a.shared.Close(); err != nil {
+			slog.Warn("close redis failed", "err", err)
+		}
+	}
+	if a.sqlite != nil {
+		if err := a.sqlite.Close(); err != nil {
+			slog.Warn("close sqlite failed", "err", err)
+		}
+	}
 	return nil
 }
 
 func (a *App) registerHandlers() {
+	a.bot.Use(a.operatorMiddleware)
+	a.bot.Use(a.accessMiddleware)
+	a.bot.Use(a.ephemeralMiddleware)
+	a.bot.Use(a.sharedStateMiddleware)
+
+	a.registerPipelines()
+
 	a.bot.Handle("/start", func(c tele.Context) error {
-		welcome := "Hi, I am Eteon. Share a prompt, a link, or media and I will respond concisely."
-		_, err := a.sendWithFallback(c.Chat(), welcome, &tele.SendOptions{DisableWebPagePreview: true})
+		welcome := tr(a.chatLanguage(c.Chat(), c.Sender()), "welcome")
+		_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), welcome, &tele.SendOptions{DisableWebPagePreview: true})
 		return err
 	})
 
 	a.bot.Handle("/settings", a.handleSettings)
+	a.bot.Handle("/usage", a.handleUsage)
+	a.bot.Handle("/selfcheck", a.handleSelfCheck)
+	a.bot.Handle("/cancel", a.handleCancelRequest)
+	a.bot.Handle("/calc", a.handleCalc)
+	a.bot.Handle("/search", a.handleSearch)
+	a.bot.Handle("/json", a.handleJSON)
+	a.bot.Handle("/persona", a.handlePersona)
+	a.bot.Handle("/topic", a.handleTopic)
+	a.bot.Handle("/calendar", a.handleCalendar)
+	a.bot.Handle("/notion", a.handleNotion)
+	a.bot.Handle("/languages", a.handleLanguages)
+	a.bot.Handle("/verbosity", a.handleVerbosity)
+	a.bot.Handle("/tone", a.handleTone)
+	a.bot.Handle(compareDocsCommand, a.handleCompareDocs)
+	a.bot.Handle(contractCommand, a.handleContract)
+	a.bot.Handle(transcribeCommand, a.handleTranscribe)
+	a.bot.Handle(translateCommand, a.handleTranslate)
+	a.bot.Handle(ocrCommand, a.handleOCR)
+	a.bot.Handle(summarizeCommand, a.handleSummarize)
+	a.bot.Handle("/advanced", a.handleAdvanced)
+	a.bot.Handle("/model", a.handleModel)
+	a.bot.Handle("/deterministic", a.handleDeterministic)
+	a.bot.Handle("/devmode", a.handleDevMode)
+	a.bot.Handle("/repo", a.handleRepo)
+	a.bot.Handle(kbCommand, a.handleKB)
+	a.bot.Handle(recallCommand, a.handleRecall)
+	a.bot.Handle("/replay", a.handleReplay)
+	a.bot.Handle("/export", a.handleExport)
+	a.bot.Handle("/import", a.handleImport)
+	a.bot.Handle("/chat", a.handleChat)
+	a.bot.Handle("/bookmarks", a.handleBookmarks)
+	a.bot.Handle("/remember", a.handleRemember)
+	a.bot.Handle("/memories", a.handleMemories)
+	a.bot.Handle("/forget", a.handleForget)
+	a.bot.Handle("/checkpoint", a.handleCheckpoint)
+	a.bot.Handle("/rollback", a.handleRollback)
+	a.bot.Handle("/catchup", a.handleCatchup)
+	a.bot.Handle("/sendto", a.handleSendTo)
+	a.bot.Handle("/remind", a.handleRemind)
+	a.bot.Handle("/digest", a.handleDigest)
+	a.bot.Handle("/privacy", a.handlePrivacy)
+	a.bot.Handle("/ephemeral", a.handleEphemeral)
+	a.bot.Handle("/replyto", a.handleReplyTo)
+	a.bot.Handle(tele.OnMigration, a.handleMigration)
+	a.bot.Handle("/admin", a.handleAdmin)
+	a.bot.Handle("/language", a.handleLanguage)
 
 	messageHandler := func(c tele.Context) error {
 		return a.handleUserMessage(c)
@@ -120,136 +726,485 @@ func (a *App) registerHandlers() {
 	a.bot.Handle(tele.OnDocument, messageHandler)
 	a.bot.Handle(tele.OnVoice, messageHandler)
 	a.bot.Handle(tele.OnVideoNote, messageHandler)
+	a.bot.Handle(tele.OnLocation, messageHandler)
+	a.bot.Handle(tele.OnVenue, messageHandler)
+	a.bot.Handle(tele.OnPinned, a.handlePinned)
+	a.bot.Handle(tele.OnEdited, a.handleEdited)
 
 	a.bot.Handle(&tele.InlineButton{Unique: showThoughtsUnique}, a.handleShowThoughts)
+	a.bot.Handle(&tele.InlineButton{Unique: fullThoughtsUnique}, a.handleFullThoughts)
 	a.bot.Handle(&tele.InlineButton{Unique: showSourcesUnique}, a.handleShowSources)
 	a.bot.Handle(&tele.InlineButton{Unique: showCodeUnique}, a.handleShowCode)
 	a.bot.Handle(&tele.InlineButton{Unique: selectThinkingModeUnique}, a.handleModeSelection)
+	a.bot.Handle(&tele.InlineButton{Unique: selectVerbosityUnique}, a.handleVerbositySelection)
+	a.bot.Handle(&tele.InlineButton{Unique: selectToneUnique}, a.handleToneSelection)
+	a.bot.Handle(&tele.InlineButton{Unique: selectSamplingUnique}, a.handleSamplingSelection)
+	a.bot.Handle(&tele.InlineButton{Unique: toggleToolUnique}, a.handleToolToggle)
+	a.bot.Handle(&tele.InlineButton{Unique: cancelRequestUnique}, a.handleCancelRequest)
+	a.bot.Handle(&tele.InlineButton{Unique: confirmActionUnique}, a.handleConfirmAction)
+	a.bot.Handle(&tele.InlineButton{Unique: cancelActionUnique}, a.handleCancelAction)
+	a.bot.Handle(&tele.InlineButton{Unique: bookmarkUnique}, a.handleBookmark)
+	a.bot.Handle(&tele.InlineButton{Unique: memoryKeepUnique}, a.handleMemoryKeep)
+	a.bot.Handle(&tele.InlineButton{Unique: memoryDropUnique}, a.handleMemoryDrop)
+	a.bot.Handle(&tele.InlineButton{Unique: exportObsidianUnique}, a.handleExportObsidian)
+	a.bot.Handle(&tele.InlineButton{Unique: saveNotionUnique}, a.handleSaveNotion)
+	a.bot.Handle(&tele.InlineButton{Unique: likeReplyUnique}, a.handleLikeReply)
+	a.bot.Handle(&tele.InlineButton{Unique: dislikeReplyUnique}, a.handleDislikeReply)
+	a.bot.Handle(&tele.InlineButton{Unique: regenerateUnique}, a.handleRegenerate)
+	a.bot.Handle(&tele.InlineButton{Unique: continueReplyUnique}, a.handleContinueReply)
+	a.bot.Handle(&tele.InlineButton{Unique: exportReviewUnique}, a.handleExportReview)
+	a.bot.Handle(&tele.InlineButton{Unique: compareReplyUnique}, a.handleCompareReply)
+	a.bot.Handle(&tele.InlineButton{Unique: sendDraftUnique}, a.handleSendDraft)
+	a.bot.Handle(&tele.InlineButton{Unique: discardDraftUnique}, a.handleDiscardDraft)
 }
 
 func (a *App) handleSettings(c tele.Context) error {
-	session := a.sessions.get(c.Chat().ID)
+	if payload := strings.TrimSpace(c.Message().Payload); payload != "" {
+		control, value, _ := strings.Cut(payload, " ")
+		if isToolSetting(control) {
+			return a.changeTool(c, control, strings.TrimSpace(value))
+		}
+		return a.changeSampling(c, control, strings.TrimSpace(value))
+	}
+	session := a.sessionOf(c.Message())
+	lang := a.chatLanguage(c.Chat(), c.Sender())
 
 	menu := &tele.ReplyMarkup{}
-	btnLow := menu.Data("Low - 4,096 tokens", selectThinkingModeUnique, string(thinkingModeLow))
-	btnMed := menu.Data("Medium - 16,384 tokens", selectThinkingModeUnique, string(thinkingModeMedium))
-	btnHigh := menu.Data("High - 32,768 tokens", selectThinkingModeUnique, string(thinkingModeHigh))
-	btnDyn := menu.Data("Dynamic reasoning", selectThinkingModeUnique, string(thinkingModeDynamic))
-
-	menu.Inline(menu.Row(btnLow))
-	menu.Inline(menu.Row(btnMed))
-	menu.Inline(menu.Row(btnHigh))
-	menu.Inline(menu.Row(btnDyn))
-
-	body := fmt.Sprintf("Current thinking budget: %s", session.currentThinking().label())
-	_, err := a.sendWithFallback(c.Chat(), body, &tele.SendOptions{ReplyMarkup: menu, DisableWebPagePreview: true})
+	btnLow := menu.Data(thinkingModeLow.label(lang), selectThinkingModeUnique, string(thinkingModeLow))
+	btnMed := menu.Data(thinkingModeMedium.label(lang), selectThinkingModeUnique, string(thinkingModeMedium))
+	btnHigh := menu.Data(thinkingModeHigh.label(lang), selectThinkingModeUnique, string(thinkingModeHigh))
+	btnDyn := menu.Data(thinkingModeDynamic.label(lang), selectThinkingModeUnique, string(thinkingModeDynamic))
+
+	rows := []tele.Row{menu.Row(btnLow), menu.Row(btnMed), menu.Row(btnHigh), menu.Row(btnDyn)}
+	session.mu.Lock()
+	current := session.currentThinking()
+	toolsOff := session.toolsOff
+	session.mu.Unlock()
+	rows = append(rows, samplingRows(menu, lang)...)
+	menu.Inline(append(rows, a.toolRows(menu, lang, toolsOff)...)...)
+
+	body := tr(lang, "thinking_current", current.label(lang)) + "\n" + a.sampling.get(c.Chat().ID).describe(lang)
+	if tools := a.describeTools(lang, toolsOff); tools != "" {
+		body += "\n" + tools
+	}
+	_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), body, &tele.SendOptions{ReplyMarkup: menu, DisableWebPagePreview: true})
 	return err
 }
 
 func (a *App) handleModeSelection(c tele.Context) error {
 	if err := c.Respond(); err != nil {
-		log.Println("callback acknowledge error:", err)
+		slog.Warn("callback acknowledge failed", "err", err)
 	}
 
 	payload := c.Callback().Data
 	mode := parseThinkingMode(payload)
-	session := a.sessions.get(c.Chat().ID)
+	session := a.sessionOf(c.Message())
 
 	session.mu.Lock()
 	session.setThinking(mode)
 	session.mu.Unlock()
 
-	confirmation := fmt.Sprintf("Thinking budget switched to %s", mode.label())
-	_, err := a.sendWithFallback(c.Chat(), confirmation, &tele.SendOptions{DisableWebPagePreview: true})
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	confirmation := tr(lang, "thinking_switched", mode.label(lang))
+	_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), confirmation, &tele.SendOptions{DisableWebPagePreview: true})
 	return err
 }
 
 func (a *App) handleUserMessage(c tele.Context) error {
 	msg := c.Message()
-	if msg == nil {
+	if msg == nil || a.recordForCatchup(msg) {
+		return nil
+	}
+	if isImportCaption(msg) {
+		return a.handleImport(c)
+	}
+	switch command, _ := captionCommand(msg); command {
+	case transcribeCommand:
+		return a.handleTranscribe(c)
+	case translateCommand:
+		return a.handleTranslate(c)
+	case ocrCommand:
+		return a.handleOCR(c)
+	case summarizeCommand:
+		return a.handleSummarize(c)
+	}
+	if command, rest := captionCommand(msg); msg.Document != nil {
+		switch command {
+		case compareDocsCommand:
+			return a.handleCompareDocs(c)
+		case contractCommand:
+			return a.handleContract(c)
+		case kbCommand:
+			return a.handleKB(c)
+		}
+		if p, ok := a.configuredPipeline(command); ok {
+			return a.startPipeline(msg, p, rest)
+		}
+	}
+	if a.takeComparisonDocument(msg) || a.takeContractDocument(msg) {
 		return nil
 	}
 
-	session := a.sessions.get(msg.Chat.ID)
-	session.mu.Lock()
-	defer session.mu.Unlock()
+	return a.enqueueTurn(msg, turnOptions{})
+}
+
+// enqueueTurn schedules msg on its chat's queue and tells the user when it has to wait.
+func (a *App) enqueueTurn(msg *tele.Message, opts turnOptions) error {
+	return a.enqueueJob(msg, func(ctx context.Context) error {
+		return a.processMessage(ctx, msg, opts)
+	})
+}
+
+// enqueueJob schedules job on the chat's queue on behalf of the sender of msg,
+// the message that asked for it, and tells the user when it has to wait.
+func (a *App) enqueueJob(msg *tele.Message, job chatJob) error {
+	chat, sender := msg.Chat, msg.Sender
+	if refused, err := a.refuseOverBudget(msg); refused {
+		return err
+	}
+	ahead, err := a.queue.enqueue(chat.ID, userIDOf(sender), func(ctx context.Context) error {
+		defer a.sessions.persist(chat.ID)
+		ctx = withThread(ctx, messageTopic(msg))
+		if sender != nil {
+			ctx = withUserID(ctx, sender.ID)
+		}
+		if a.privacy.enabled(chat.ID) {
+			ctx = withPrivacy(ctx)
+		}
+		return job(ctx)
+	})
+	lang := a.chatLanguage(chat, sender)
+	if err != nil {
+		notice := tr(lang, "queue_full")
+		if errors.Is(err, errDraining) {
+			notice = tr(lang, "queue_draining")
+		}
+		_, err := a.sendWithFallback(inTopic(chat, msg), notice, &tele.SendOptions{DisableWebPagePreview: true})
+		return err
+	}
+	if ahead > 0 {
+		return a.notifyQueued(inTopic(chat, msg), lang, ahead)
+	}
+	return nil
+}
+
+// processMessage answers a single user message. It runs on the chat's queue, so
+// the session lock is only held while reading or updating state.
+func (a *App) processMessage(ctx context.Context, msg *tele.Message, opts turnOptions) error {
+	session := a.sessionOf(msg)
+	lang := a.chatLanguage(msg.Chat, msg.Sender)
+	private := a.privacy.enabled(msg.Chat.ID)
+	if private {
+		ctx = withPrivacy(ctx)
+	}
+	start := time.Now()
+	logger := logFrom(ctx).With("message_id", msg.ID)
+	ctx = withLogger(ctx, logger)
 
-	parts, err := a.collectParts(msg)
+	parts, err := a.collectParts(ctx, msg)
 	if err != nil {
-		log.Println("collect parts:", err)
-		_, sendErr := a.sendWithFallback(msg.Chat, "I could not process that input.", &tele.SendOptions{DisableWebPagePreview: true})
+		logger.Warn("collect parts failed", "err", err)
+		notice := tr(lang, "input_failed")
+		if errors.Is(err, errFileTooLarge) {
+			notice = tr(lang, "file_too_large")
+		}
+		_, sendErr := a.sendWithFallback(msg.Chat, notice, &tele.SendOptions{ThreadID: messageTopic(msg), DisableWebPagePreview: true})
 		if sendErr != nil {
-			log.Println("notify failure:", sendErr)
+			logger.Warn("notify failed", "err", sendErr)
 		}
 		return err
 	}
 	if len(parts) == 0 {
-		_, err := a.sendWithFallback(msg.Chat, "Please send text or supported media.", &tele.SendOptions{DisableWebPagePreview: true})
+		_, err := a.sendWithFallback(msg.Chat, tr(lang, "input_empty"), &tele.SendOptions{ThreadID: messageTopic(msg), DisableWebPagePreview: true})
 		return err
 	}
 
+	a.ensureGroupContext(msg.Chat, session)
 	userContent := genai.NewContentFromParts(parts, genai.RoleUser)
+	session.mu.Lock()
+	if opts.edited && session.lastTurn.promptID != msg.ID {
+		// A newer prompt was answered since the edit was queued.
+		session.mu.Unlock()
+		return nil
+	}
 	conversation := session.conversationWith(userContent)
-	cfg := a.buildGenerateConfig(session.currentThinking())
+	if opts.edited {
+		conversation = session.conversationReplacingLast(userContent)
+	}
+	if private {
+		// Privacy mode answers every message on its own.
+		conversation = []*genai.Content{userContent}
+	}
+	previousReply := session.lastTurn.replyID
+	prefs := session.prefs()
+	session.mu.Unlock()
+	topic := a.topics.get(msg.Chat.ID, messageTopic(msg))
+	if topic.Persona != "" {
+		prefs.persona = topic.Persona
+	}
+	cfg := a.buildGenerateConfig(msg.Chat.ID, prefs)
+	topic.restrict(cfg)
+	opts.apply(cfg)
+	if style := a.styleInstruction(msg); style != "" {
+		cfg.SystemInstruction = appendInstruction(cfg.SystemInstruction, style)
+	}
+	if memories := a.memoryInstruction(msg); memories != "" {
+		cfg.SystemInstruction = appendInstruction(cfg.SystemInstruction, memories)
+	}
+	if recall := a.recallInstruction(ctx, msg.Chat.ID, messagePrompt(msg), opts.recall); recall != "" {
+		cfg.SystemInstruction = appendInstruction(cfg.SystemInstruction, recall)
+	}
+	if hint := a.speechInstruction(lang, msg); hint != "" {
+		cfg.SystemInstruction = appendInstruction(cfg.SystemInstruction, hint)
+	}
+	if topic.allows("repo") {
+		if repo := a.repoInstruction(ctx, msg.Chat.ID, messagePrompt(msg)); repo != "" {
+			cfg.SystemInstruction = appendInstruction(cfg.SystemInstruction, repo)
+		}
+		if kb := a.kbInstruction(ctx, msg.Chat.ID, messagePrompt(msg)); kb != "" {
+			cfg.SystemInstruction = appendInstruction(cfg.SystemInstruction, kb)
+		}
+	}
+	patch := a.messagePatch(msg)
+	if patch != "" {
+		cfg.SystemInstruction = appendInstruction(cfg.SystemInstruction, reviewInstruction)
+	} else if prefs.devMode && looksLikeError(messagePrompt(msg)) {
+		cfg.SystemInstruction = appendInstruction(cfg.SystemInstruction, debugInstruction)
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	ctx = withModel(withThread(withChatID(ctx, msg.Chat.ID), messageTopic(msg)), prefs.model)
+	if msg.Sender != nil {
+		ctx = withUserID(ctx, msg.Sender.ID)
+	}
+	light, lightNotice := false, "light_notice"
+	reason := a.shedding()
+	if over := a.budgetExceeded(userIDOf(msg.Sender)); over != "" {
+		reason, lightNotice = over, "budget_notice"
+	}
+	if reason != "" {
+		if ctx, cfg, light = a.goLight(ctx, cfg); light {
+			logger.Info("going light", "reason", reason)
+		}
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
 	defer cancel()
+	ctx = withCallWaitNotice(ctx, func(position int, wait time.Duration) {
+		if _, err := a.sendWithFallback(msg.Chat, tr(lang, "calls_waiting", position, formatWait(wait)), &tele.SendOptions{ThreadID: messageTopic(msg), DisableWebPagePreview: true}); err != nil {
+			logger.Warn("notify failed", "err", err)
+		}
+	})
+	genCtx := withContextCaching(ctx)
+	if private {
+		// A context cache would keep the prompt at Gemini.
+		genCtx = ctx
+	}
 
-	resp, err := a.client.Models.GenerateContent(ctx, geminiModel, conversation, cfg)
+	var (
+		resp     *genai.GenerateContentResponse
+		model    string
+		codeRuns = true
+		searched = true
+	)
+	if opts.requireCode {
+		resp, model, codeRuns, err = a.generateWithCode(genCtx, msg.Chat.ID, conversation, cfg)
+	} else if opts.requireSearch {
+		resp, model, searched, err = a.generateWithSearch(genCtx, msg.Chat.ID, conversation, cfg)
+	} else {
+		resp, model, err = a.generateWithFunctions(genCtx, conversation, cfg)
+		if pages := a.fetchFailedPages(ctx, resp); len(pages) > 0 {
+			// The URL context tool could not read a link: answer again with
+			// the pages the bot fetched itself, kept in the prompt for the
+			// rest of the conversation.
+			logger.Info("retrying with fetched pages", "pages", len(pages))
+			a.recordUsage(ctx, msg.Chat.ID, model, resp.UsageMetadata)
+			userContent.Parts = append(userContent.Parts, pages...)
+			resp, model, err = a.generateWithFunctions(genCtx, conversation, cfg)
+		}
+	}
 	if err != nil {
-		log.Println("genai request:", err)
-		_, sendErr := a.sendWithFallback(msg.Chat, "Eteon could not complete that request.", &tele.SendOptions{DisableWebPagePreview: true})
+		notice := tr(lang, "request_failed")
+		switch {
+		case errors.Is(err, context.Canceled):
+			notice = tr(lang, "request_cancelled")
+		case errors.Is(err, errCallsSaturated):
+			notice = tr(lang, "calls_saturated")
+		}
+		logger.Error("genai request failed", "latency_ms", time.Since(start).Milliseconds(), "err", err)
+		_, sendErr := a.sendWithFallback(msg.Chat, notice, &tele.SendOptions{ThreadID: messageTopic(msg), DisableWebPagePreview: true})
 		if sendErr != nil {
-			log.Println("notify failure:", sendErr)
+			logger.Warn("notify failed", "err", sendErr)
 		}
 		return err
 	}
+	used := a.recordUsage(ctx, msg.Chat.ID, model, resp.UsageMetadata)
+	logger = logger.With("model", model, "prompt_tokens", used.Prompt, "output_tokens", used.Candidates, "thought_tokens", used.Thoughts)
+	if !codeRuns {
+		_, err := a.sendWithFallback(msg.Chat, tr(lang, "calc_no_code"), &tele.SendOptions{ThreadID: messageTopic(msg), DisableWebPagePreview: true})
+		return err
+	}
 
-	if resp.PromptFeedback != nil && resp.PromptFeedback.BlockReason != genai.BlockedReasonUnspecified {
-		warning := "The request was blocked by safety filters."
-		_, sendErr := a.sendWithFallback(msg.Chat, warning, &tele.SendOptions{DisableWebPagePreview: true})
+	if promptBlocked(resp) {
+		logger.Info("request blocked", "reason", resp.PromptFeedback.BlockReason)
+		warning := blockedNotice(lang, resp.PromptFeedback)
+		_, sendErr := a.sendWithFallback(msg.Chat, warning, &tele.SendOptions{ThreadID: messageTopic(msg), DisableWebPagePreview: true})
 		if sendErr != nil {
-			log.Println("notify failure:", sendErr)
+			logger.Warn("notify failed", "err", sendErr)
 		}
 		return nil
 	}
 
 	reply, artifacts := a.renderResponse(resp)
+	corrected := false
+	if cfg.ResponseMIMEType == jsonMIMEType && reply != "" {
+		// JSON replies reach the chat as they are, to stay parseable.
+		if reply, err = a.jsonReply(msg.Chat, messageTopic(msg), lang, reply); err != nil {
+			logger.Warn("send json document failed", "err", err)
+		}
+	} else {
+		reply = formatCodeBlocks(ctx, reply)
+		for i, snippet := range artifacts.CodeSnippets {
+			artifacts.CodeSnippets[i].Code = formatCode(ctx, snippet.Language, snippet.Code)
+		}
+		reply, corrected = a.maybeVerify(ctx, lang, prefs.selfCheck, msg.Chat.ID, messagePrompt(msg), reply)
+		reply = prefs.tone.filter(reply)
+		if a.features.enabled(featureReplyHooks) {
+			reply = a.replyHooks.apply(ctx, lang, messagePrompt(msg), reply)
+		}
+	}
 	if reply == "" {
-		reply = "No content received."
+		reply = tr(lang, "no_content")
+	}
+	finish, truncated := finishNotice(lang, firstCandidate(resp))
+	if finish != "" {
+		logger.Info("answer ended early", "finish_reason", firstCandidate(resp).FinishReason)
+		reply += "\n\n" + finish
+	}
+	if label := groundingLabel(lang, firstCandidate(resp)); label != "" {
+		reply += "\n\n" + label
+	}
+	if opts.requireSearch {
+		if !searched {
+			reply += "\n\n" + tr(lang, "search_unsourced")
+		} else if len(artifacts.Sources) > 0 {
+			reply += "\n\n" + sourcesList(lang, artifacts.Sources)
+		}
+	}
+	if notice := a.fallbackNotice(ctx, lang, model); notice != "" {
+		reply += "\n\n" + notice
+	}
+	if light {
+		reply += "\n\n" + tr(lang, lightNotice)
 	}
 
-	if candidate := firstCandidate(resp); candidate != nil && candidate.Content != nil {
-		session.appendTurn(userContent, filterModelContent(candidate.Content))
+	session.mu.Lock()
+	if opts.edited && session.lastTurn.promptID == msg.ID {
+		session.dropLastTurn()
+	}
+	if private {
+		// Privacy mode keeps no history.
+	} else if corrected {
+		session.appendTurn(a.pii.maskContent(userContent), a.pii.maskContent(genai.NewContentFromText(reply, genai.RoleModel)), model)
+	} else if candidate := firstCandidate(resp); candidate != nil && candidate.Content != nil {
+		session.appendTurn(a.pii.maskContent(userContent), a.pii.maskContent(filterModelContent(candidate.Content)), model)
 	} else {
-		session.appendTurn(userContent, nil)
+		session.appendTurn(a.pii.maskContent(userContent), nil, model)
+	}
+	session.mu.Unlock()
+
+	artifacts.Prompt = messagePrompt(msg)
+	artifacts.Reply = reply
+	artifacts.CreatedAt = time.Now()
+	artifacts.ChatID = msg.Chat.ID
+	artifacts.MessageID = msg.ID
+	artifacts.Previous = opts.previous
+	artifacts.Truncated = truncated
+	if patch != "" {
+		if comments := parseReviewComments(reply); len(comments) > 0 {
+			artifacts.Review = &patchReview{Patch: patch, Comments: comments}
+		}
 	}
 
-	var markup *tele.ReplyMarkup
-	recordID := a.artifacts.put(artifacts)
-	if recordID != "" {
-		markup = a.buildResponseMarkup(recordID, artifacts)
+	media := artifacts.Media
+	artifacts.Media = nil
+	var (
+		markup   *tele.ReplyMarkup
+		recordID string
+	)
+	if !private {
+		// The buttons of a reply need its stored contents.
+		if recordID = a.artifacts.put(artifacts); recordID != "" {
+			markup = a.buildResponseMarkup(lang, recordID, artifacts)
+		}
 	}
 
-	_, sendErr := a.sendWithFallback(msg.Chat, reply, &tele.SendOptions{ReplyMarkup: markup, DisableWebPagePreview: true})
-	if sendErr != nil {
+	sendOpts := &tele.SendOptions{ReplyMarkup: markup, ThreadID: messageTopic(msg), DisableWebPagePreview: true}
+	if prompt := a.replyToPrompt(msg); prompt != nil {
+		// A deleted prompt leaves the answer on its own.
+		sendOpts.ReplyTo, sendOpts.AllowWithoutReply = prompt, true
+	}
+	text := reply
+	if len(opts.previous) > 0 {
+		// The compare button shows the full difference.
+		text += "\n\n" + countReplyDiff(lang, opts.previous[len(opts.previous)-1], reply)
+	}
+	var sent *tele.Message
+	var sendErr error
+	if opts.edited && previousReply != 0 {
+		sent, sendErr = a.replaceReply(msg.Chat, previousReply, text, sendOpts)
+	} else {
+		sent, sendErr = a.sendWithFallback(msg.Chat, text, sendOpts)
+	}
+	if sendErr == nil {
+		logger.Info("reply sent", "latency_ms", time.Since(start).Milliseconds(), "edited", opts.edited)
+		if err := a.sendResponseMedia(msg.Chat, messageTopic(msg), media); err != nil {
+			logger.Warn("send media failed", "err", err)
+		}
+		session.mu.Lock()
+		session.lastTurn.promptID = msg.ID
+		if !private {
+			// Privacy mode keeps no prompt to regenerate or continue from.
+			session.lastTurn.prompt = msg
+			session.lastTurn.opts = opts
+		}
+		if sent != nil {
+			session.lastTurn.replyID = sent.ID
+		}
+		session.mu.Unlock()
+		if sent != nil && recordID != "" {
+			a.artifacts.setReply(recordID, sent.ID)
+		}
+	}
+	if private {
 		return sendErr
 	}
-	return nil
+	if a.sentiment != nil && !opts.edited && a.features.enabled(featureSentiment) {
+		a.trackSentiment(ctx, msg)
+	}
+	if !opts.edited {
+		a.extractMemories(ctx, msg, session)
+	}
+	a.trimHistory(ctx, msg.Chat.ID, session)
+	return sendErr
 }
 
 func (a *App) handleShowThoughts(c tele.Context) error {
 	if err := c.Respond(); err != nil {
-		log.Println("callback acknowledge error:", err)
+		slog.Warn("callback acknowledge failed", "err", err)
 	}
+	lang := a.chatLanguage(c.Chat(), c.Sender())
 	id := c.Callback().Data
 	art, ok := a.artifacts.get(id)
-	prompt := "Reasoning summary is unavailable."
+	prompt := tr(lang, "thoughts_none")
+	opts := &tele.SendOptions{DisableWebPagePreview: true}
 	if ok && len(art.Thoughts) > 0 {
-		steps := summarizeThoughts(art.Thoughts, 5)
+		steps := summarizeThoughts(art.Thoughts, thoughtsSummarySteps)
+		if len(steps) > thoughtsSummarySteps {
+			opts.ReplyMarkup = fullThoughtsMarkup(lang, id)
+		}
 		if len(steps) > 0 {
 			var b strings.Builder
-			b.WriteString("Reasoning summary:\n")
+			b.WriteString(tr(lang, "thoughts_header"))
+			b.WriteString("\n")
 			for _, step := range steps {
 				b.WriteString("- ")
 				b.WriteString(step)
@@ -259,60 +1214,66 @@ func (a *App) handleShowThoughts(c tele.Context) error {
 		}
 	}
 
-	placeholder, err := a.sendWithFallback(c.Chat(), "Summarising thoughts...", &tele.SendOptions{DisableWebPagePreview: true})
+	placeholder, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), tr(lang, "thoughts_working"), &tele.SendOptions{DisableWebPagePreview: true})
 	if err != nil {
 		return err
 	}
-	_, err = a.editWithFallback(placeholder, prompt, &tele.SendOptions{DisableWebPagePreview: true})
+	_, err = a.editWithFallback(placeholder, prompt, opts)
 	return err
 }
 
 func (a *App) handleShowSources(c tele.Context) error {
 	if err := c.Respond(); err != nil {
-		log.Println("callback acknowledge error:", err)
+		slog.Warn("callback acknowledge failed", "err", err)
 	}
+	lang := a.chatLanguage(c.Chat(), c.Sender())
 	id := c.Callback().Data
 	art, ok := a.artifacts.get(id)
 	if !ok || len(art.Sources) == 0 {
-		_, err := a.sendWithFallback(c.Chat(), "No sources available for this reply.", &tele.SendOptions{DisableWebPagePreview: true})
+		_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), tr(lang, "sources_none"), &tele.SendOptions{DisableWebPagePreview: true})
 		return err
 	}
 
-	var b strings.Builder
-	b.WriteString("Sources:\n")
-	for i, src := range art.Sources {
-		title := src.Title
-		if title == "" {
-			title = "Untitled"
-		}
-		b.WriteString(fmt.Sprintf("%d. %s - %s\n", i+1, title, src.URI))
-	}
-	body := strings.TrimRight(b.String(), "\n")
-	_, err := a.sendWithFallback(c.Chat(), body, &tele.SendOptions{DisableWebPagePreview: false})
+	_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), sourcesList(lang, art.Sources), &tele.SendOptions{DisableWebPagePreview: false})
 	return err
 }
 
 func (a *App) handleShowCode(c tele.Context) error {
 	if err := c.Respond(); err != nil {
-		log.Println("callback acknowledge error:", err)
+		slog.Warn("callback acknowledge failed", "err", err)
 	}
 	id := c.Callback().Data
+	lang := a.chatLanguage(c.Chat(), c.Sender())
 	art, ok := a.artifacts.get(id)
 	if !ok || len(art.CodeSnippets) == 0 {
-		_, err := a.sendWithFallback(c.Chat(), "No executable code was used for this reply.", &tele.SendOptions{DisableWebPagePreview: true})
+		_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), tr(lang, "code_none"), &tele.SendOptions{DisableWebPagePreview: true})
 		return err
 	}
 
 	var sections []string
+	var large []int
 	for idx, snippet := range art.CodeSnippets {
-		sections = append(sections, formatCodeSnippet(idx+1, snippet))
+		if snippet.large() {
+			large = append(large, idx)
+			continue
+		}
+		sections = append(sections, formatCodeSnippet(lang, idx+1, snippet))
 	}
-	body := strings.Join(sections, "\n\n")
-	_, err := a.sendWithFallback(c.Chat(), body, &tele.SendOptions{DisableWebPagePreview: true})
-	return err
+	if len(sections) > 0 {
+		body := strings.Join(sections, "\n\n")
+		if _, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), body, &tele.SendOptions{DisableWebPagePreview: true}); err != nil {
+			return err
+		}
+	}
+	for _, idx := range large {
+		if err := a.sendCodeFiles(inTopic(c.Chat(), c.Message()), lang, idx+1, art.CodeSnippets[idx]); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (a *App) collectParts(msg *tele.Message) ([]*genai.Part, error) {
+func (a *App) collectParts(ctx context.Context, msg *tele.Message) ([]*genai.Part, error) {
 	var parts []*genai.Part
 
 	text := strings.TrimSpace(msg.Text)
@@ -322,9 +1283,33 @@ func (a *App) collectParts(msg *tele.Message) ([]*genai.Part, error) {
 	if caption := strings.TrimSpace(msg.Caption); caption != "" && caption != text {
 		parts = append(parts, genai.NewPartFromText(caption))
 	}
+	parts = append(parts, a.videoLinkParts(ctx, msg)...)
+
+	media, err := a.mediaParts(ctx, msg)
+	if err != nil {
+		return nil, err
+	}
+	parts = append(parts, media...)
+
+	if len(parts) == 0 {
+		return nil, nil
+	}
+	if part := forwardPart(msg); part != nil {
+		parts = append([]*genai.Part{part}, parts...)
+	}
+	return append(a.replyContextParts(ctx, msg), parts...), nil
+}
+
+// mediaParts converts the location and attachments of msg into model parts.
+func (a *App) mediaParts(ctx context.Context, msg *tele.Message) ([]*genai.Part, error) {
+	var parts []*genai.Part
+
+	if part := locationPart(msg); part != nil {
+		parts = append(parts, part)
+	}
 
 	if msg.Photo != nil {
-		if part, err := a.partFromFile(msg.Photo.MediaFile(), ""); err == nil {
+		if part, err := a.partFromFile(ctx, msg.Photo.MediaFile(), ""); err == nil {
 			parts = append(parts, part)
 		} else {
 			return nil, err
@@ -332,15 +1317,15 @@ func (a *App) collectParts(msg *tele.Message) ([]*genai.Part, error) {
 	}
 
 	if msg.Document != nil {
-		if part, err := a.partFromFile(msg.Document.MediaFile(), msg.Document.MIME); err == nil {
-			parts = append(parts, part)
+		if docParts, err := a.documentParts(ctx, msg.Document); err == nil {
+			parts = append(parts, docParts...)
 		} else {
 			return nil, err
 		}
 	}
 
 	if msg.Video != nil {
-		if part, err := a.partFromFile(msg.Video.MediaFile(), msg.Video.MIME); err == nil {
+		if part, err := a.partFromFile(ctx, msg.Video.MediaFile(), msg.Video.MIME); err == nil {
 			parts = append(parts, part)
 		} else {
 			return nil, err
@@ -348,7 +1333,7 @@ func (a *App) collectParts(msg *tele.Message) ([]*genai.Part, error) {
 	}
 
 	if msg.Audio != nil {
-		if part, err := a.partFromFile(msg.Audio.MediaFile(), msg.Audio.MIME); err == nil {
+		if part, err := a.partFromFile(ctx, msg.Audio.MediaFile(), msg.Audio.MIME); err == nil {
 			parts = append(parts, part)
 		} else {
 			return nil, err
@@ -356,7 +1341,7 @@ func (a *App) collectParts(msg *tele.Message) ([]*genai.Part, error) {
 	}
 
 	if msg.Voice != nil {
-		if part, err := a.partFromFile(msg.Voice.MediaFile(), msg.Voice.MIME); err == nil {
+		if part, err := a.partFromFile(ctx, msg.Voice.MediaFile(), msg.Voice.MIME); err == nil {
 			parts = append(parts, part)
 		} else {
 			return nil, err
@@ -364,7 +1349,7 @@ func (a *App) collectParts(msg *tele.Message) ([]*genai.Part, error) {
 	}
 
 	if msg.VideoNote != nil {
-		if part, err := a.partFromFile(msg.VideoNote.MediaFile(), ""); err == nil {
+		if part, err := a.partFromFile(ctx, msg.VideoNote.MediaFile(), ""); err == nil {
 			parts = append(parts, part)
 		} else {
 			return nil, err
@@ -374,10 +1359,25 @@ func (a *App) collectParts(msg *tele.Message) ([]*genai.Part, error) {
 	return parts, nil
 }
 
-func (a *App) partFromFile(file *tele.File, explicitMIME string) (*genai.Part, error) {
+// messagePrompt returns the textual prompt of a message, preferring its text over the caption.
+func messagePrompt(msg *tele.Message) string {
+	if text := strings.TrimSpace(msg.Text); text != "" {
+		return text
+	}
+	return strings.TrimSpace(msg.Caption)
+}
+
+func (a *App) partFromFile(ctx context.Context, file *tele.File, explicitMIME string) (*genai.Part, error) {
 	if file == nil {
 		return nil, errors.New("nil media reference")
 	}
+	if file.FileSize > telegramDownloadLimit {
+		return nil, errFileTooLarge
+	}
+	key := mediaKey(file, explicitMIME)
+	if part, ok := a.media.get(key); ok {
+		return part, nil
+	}
 
 	reader, err := a.bot.File(file)
 	if err != nil {
@@ -385,6 +1385,17 @@ func (a *App) partFromFile(file *tele.File, explicitMIME string) (*genai.Part, e
 	}
 	defer reader.Close()
 
+	if a.inlineMediaLimit > 0 && file.FileSize > a.inlineMediaLimit {
+		part, err := a.uploadPart(ctx, reader, file, explicitMIME)
+		if err != nil {
+			return nil, err
+		}
+		if !isPrivate(ctx) {
+			a.media.put(key, part)
+		}
+		return part, nil
+	}
+
 	data, err := io.ReadAll(reader)
 	if err != nil {
 		return nil, fmt.Errorf("read file: %w", err)
@@ -393,47 +1404,68 @@ func (a *App) partFromFile(file *tele.File, explicitMIME string) (*genai.Part, e
 		return nil, errors.New("empty media payload")
 	}
 
-	mimeType := explicitMIME
-	if mimeType == "" {
-		if ext := strings.ToLower(filepath.Ext(file.FilePath)); ext != "" {
-			if detected := mime.TypeByExtension(ext); detected != "" {
-				mimeType = detected
-			}
-		}
-		if mimeType == "" && file.FileURL != "" {
-			if ext := strings.ToLower(filepath.Ext(file.FileURL)); ext != "" {
-				if detected := mime.TypeByExtension(ext); detected != "" {
-					mimeType = detected
-				}
-			}
+	mimeType := detectMIME(file, explicitMIME, data)
+	part := &genai.Part{InlineData: &genai.Blob{Data: data, MIMEType: mimeType}}
+	if !isPrivate(ctx) {
+		a.media.put(key, part)
+	}
+	return part, nil
+}
+
+// detectMIME picks a MIME type from the explicit value, the file name, or the content.
+func detectMIME(file *tele.File, explicitMIME string, head []byte) string {
+	if explicitMIME != "" {
+		return explicitMIME
+	}
+	for _, name := range []string{file.FilePath, file.FileURL, file.FileLocal} {
+		if name == "" {
+			continue
 		}
-		if mimeType == "" && file.FileLocal != "" {
-			if ext := strings.ToLower(filepath.Ext(file.FileLocal)); ext != "" {
-				if detected := mime.TypeByExtension(ext); detected != "" {
-					mimeType = detected
-				}
+		if ext := strings.ToLower(filepath.Ext(name)); ext != "" {
+			if detected := mime.TypeByExtension(ext); detected != "" {
+				return detected
 			}
 		}
-		if mimeType == "" {
-			mimeType = http.DetectContentType(data)
-		}
 	}
-
-	return &genai.Part{InlineData: &genai.Blob{Data: data, MIMEType: mimeType}}, nil
+	return http.DetectContentType(head)
 }
 
-func (a *App) buildGenerateConfig(mode thinkingMode) *genai.GenerateContentConfig {
-	budget := mode.budgetTokens()
+func (a *App) buildGenerateConfig(chatID int64, prefs chatPrefs) *genai.GenerateContentConfig {
+	budget := prefs.thinking.budgetTokens()
 	thinkingConfig := &genai.ThinkingConfig{IncludeThoughts: true}
 	if budget != nil {
 		thinkingConfig.ThinkingBudget = budget
 	}
 
-	return &genai.GenerateContentConfig{
-		SystemInstruction: a.systemInstruction,
-		Tools:             a.tools,
+	instruction := appendInstruction(a.tuned().systemInstruction, prefs.groupContext)
+	if prefs.persona != "" {
+		instruction = appendInstruction(instruction, personaInstruction(prefs.persona))
+	}
+	instruction = appendInstruction(instruction, prefs.verbosity.instruction())
+	instruction = appendInstruction(instruction, prefs.tone.instruction())
+
+	tools := withoutTools(a.tools, prefs.toolsOff)
+	// generateWithFunctions routes each turn to either the functions or the
+	// built-in tools; other requests keep only the built-in ones.
+	if fnTool := a.functions.toolFor(chatID); fnTool != nil {
+		tools = append(append([]*genai.Tool{}, tools...), fnTool)
+	}
+
+	cfg := &genai.GenerateContentConfig{
+		SystemInstruction: instruction,
+		Tools:             tools,
 		ThinkingConfig:    thinkingConfig,
+		MaxOutputTokens:   maxOutputTokens(prefs.verbosity, prefs.thinking),
+	}
+	a.sampling.get(chatID).apply(cfg, prefs.thinking)
+	if prefs.deterministic {
+		applyDeterministic(cfg)
+	}
+	prefs.overrides.apply(cfg)
+	if prefs.jsonMode != "" {
+		applyJSONMode(cfg, prefs.jsonMode)
 	}
+	return cfg
 }
 
 func (a *App) renderResponse(resp *genai.GenerateContentResponse) (string, *responseArtifacts) {
@@ -445,8 +1477,10 @@ func (a *App) renderResponse(resp *genai.GenerateContentResponse) (string, *resp
 	var mainParts []string
 	var thoughtParts []string
 	var codeSnippets []codeSnippet
+	var media []responseMedia
+	sources := collectSources(cand)
 
-	for _, part := range cand.Content.Parts {
+	for i, part := range cand.Content.Parts {
 		if part == nil {
 			continue
 		}
@@ -456,12 +1490,20 @@ func (a *App) renderResponse(resp *genai.GenerateContentResponse) (string, *resp
 			}
 			continue
 		}
-		if text := strings.TrimSpace(part.Text); text != "" {
+		if m, ok := mediaOfPart(part); ok && len(media) < responseMediaLimit {
+			media = append(media, m)
+		}
+		if text := strings.TrimSpace(footnotedText(part.Text, i, cand.GroundingMetadata, sources)); text != "" {
 			mainParts = append(mainParts, text)
 		}
 		if part.CodeExecutionResult != nil {
 			if out := strings.TrimSpace(part.CodeExecutionResult.Output); out != "" {
-				mainParts = append(mainParts, fmt.Sprintf("Result:\n%s", out))
+				mainParts = append(mainParts, resultText(out))
+			}
+			// The result usually follows its code in a part of its own.
+			if last := len(codeSnippets) - 1; part.ExecutableCode == nil && last >= 0 && codeSnippets[last].Outcome == "" {
+				codeSnippets[last].Outcome = string(part.CodeExecutionResult.Outcome)
+				codeSnippets[last].Output = part.CodeExecutionResult.Output
 			}
 		}
 		if part.ExecutableCode != nil {
@@ -477,33 +1519,51 @@ func (a *App) renderResponse(resp *genai.GenerateContentResponse) (string, *resp
 		}
 	}
 
-	sources := collectSources(cand)
-
 	reply := strings.TrimSpace(strings.Join(mainParts, "\n\n"))
 	art := &responseArtifacts{
 		Thoughts:     thoughtParts,
 		Sources:      sources,
 		CodeSnippets: codeSnippets,
+		Media:        media,
 	}
 	return reply, art
 }
 
-func (a *App) buildResponseMarkup(id string, art *responseArtifacts) *tele.ReplyMarkup {
+func (a *App) buildResponseMarkup(lang language, id string, art *responseArtifacts) *tele.ReplyMarkup {
 	if id == "" || art == nil {
 		return nil
 	}
 	markup := &tele.ReplyMarkup{}
-	thoughtBtn := markup.Data("Show thoughts", showThoughtsUnique, id)
+	thoughtBtn := markup.Data(tr(lang, "btn_thoughts"), showThoughtsUnique, id)
 	markup.Inline(markup.Row(thoughtBtn))
 
 	if len(art.Sources) > 0 {
-		sourcesBtn := markup.Data("Show sources", showSourcesUnique, id)
+		sourcesBtn := markup.Data(tr(lang, "btn_sources"), showSourcesUnique, id)
 		markup.Inline(markup.Row(sourcesBtn))
 	}
 	if len(art.CodeSnippets) > 0 {
-		codeBtn := markup.Data("Show code", showCodeUnique, id)
+		codeBtn := markup.Data(tr(lang, "btn_code"), showCodeUnique, id)
 		markup.Inline(markup.Row(codeBtn))
 	}
+
+	exportRow := []tele.Btn{markup.Data(tr(lang, "btn_bookmark"), bookmarkUnique, id), markup.Data(tr(lang, "btn_obsidian"), exportObsidianUnique, id)}
+	if a.prefs != nil {
+		exportRow = append(exportRow, markup.Data(tr(lang, "btn_notion"), saveNotionUnique, id))
+	}
+	markup.Inline(markup.Row(exportRow...))
+	if art.Review != nil {
+		markup.Inline(markup.Row(markup.Data(tr(lang, "btn_review_patch"), exportReviewUnique, id)))
+	}
+
+	regenerateRow := []tele.Btn{markup.Data("👍", likeReplyUnique, id), markup.Data("👎", dislikeReplyUnique, id)}
+	if art.Truncated {
+		regenerateRow = append(regenerateRow, markup.Data(tr(lang, "btn_continue"), continueReplyUnique, id))
+	}
+	regenerateRow = append(regenerateRow, markup.Data(tr(lang, "btn_regenerate"), regenerateUnique, id))
+	if len(art.Previous) > 0 {
+		regenerateRow = append(regenerateRow, markup.Data(tr(lang, "btn_compare"), compareReplyUnique, id))
+	}
+	markup.Inline(markup.Row(regenerateRow...))
 	return markup
 }
 
@@ -515,7 +1575,14 @@ func (a *App) sendWithFallback(recipient tele.Recipient, text string, opts *tele
 	if cloned.ParseMode == "" {
 		cloned.ParseMode = tele.ModeMarkdownV2
 	}
-	msg, err := a.bot.Send(recipient, text, &cloned)
+	formatted := text
+	if cloned.ParseMode == tele.ModeMarkdownV2 {
+		formatted = markdownToV2(text)
+	}
+	msg, err := a.botSend(recipient, formatted, &cloned)
+	if fault, _ := classifyTelegramError(err); fault == faultTooLong {
+		return a.sendInParts(recipient, text, opts)
+	}
 	if err == nil || !isParseError(err) {
 		return msg, err
 	}
@@ -523,7 +1590,29 @@ func (a *App) sendWithFallback(recipient tele.Recipient, text string, opts *tele
 	cloned = *opts
 	cloned.ParseMode = tele.ModeMarkdownV2
 	safe := escapeMarkdownV2(text)
-	return a.bot.Send(recipient, safe, &cloned)
+	return a.botSend(recipient, safe, &cloned)
+}
+
+// sendInParts sends a text Telegram rejected as too long as several messages,
+// with the reply markup on the last one, and returns the last.
+func (a *App) sendInParts(recipient tele.Recipient, text string, opts *tele.SendOptions) (*tele.Message, error) {
+	parts := splitLongText(text, longMessagePart)
+	if len(parts) < 2 {
+		return nil, tele.ErrTooLongMessage
+	}
+	var last *tele.Message
+	for i, part := range parts {
+		partOpts := *opts
+		if i < len(parts)-1 {
+			partOpts.ReplyMarkup = nil
+		}
+		msg, err := a.sendWithFallback(recipient, part, &partOpts)
+		if err != nil {
+			return last, err
+		}
+		last = msg
+	}
+	return last, nil
 }
 
 func (a *App) editWithFallback(msg *tele.Message, text string, opts *tele.SendOptions) (*tele.Message, error) {
@@ -534,14 +1623,22 @@ func (a *App) editWithFallback(msg *tele.Message, text string, opts *tele.SendOp
 	if cloned.ParseMode == "" {
 		cloned.ParseMode = tele.ModeMarkdownV2
 	}
-	edited, err := a.bot.Edit(msg, text, &cloned)
+	formatted := text
+	if cloned.ParseMode == tele.ModeMarkdownV2 {
+		formatted = markdownToV2(text)
+	}
+	edited, err := a.botEdit(msg, formatted, &cloned)
+	if fault, _ := classifyTelegramError(err); fault == faultTooLong && len(text) > longMessagePart {
+		// An edit cannot grow into several messages, so keep its start.
+		return a.editWithFallback(msg, truncateText(text, longMessagePart), opts)
+	}
 	if err == nil || !isParseError(err) {
 		return edited, err
 	}
 
 	cloned.ParseMode = tele.ModeMarkdownV2
 	safe := escapeMarkdownV2(text)
-	return a.bot.Edit(msg, safe, &cloned)
+	return a.botEdit(msg, safe, &cloned)
 }
 
 func isParseError(err error) bool {
@@ -552,15 +1649,20 @@ func isParseError(err error) bool {
 	return strings.Contains(msg, "can't parse entities") || strings.Contains(msg, "can't parse message")
 }
 
-func buildSystemInstruction() *genai.Content {
-	prompt := strings.Join([]string{
+// buildSystemInstruction returns prompt as the system instruction, or the
+// default one when prompt is empty.
+func buildSystemInstruction(prompt string) *genai.Content {
+	if prompt = strings.TrimSpace(prompt); prompt != "" {
+		return genai.NewContentFromText(prompt, genai.Role("system"))
+	}
+	prompt = strings.Join([]string{
 		"You are Eteon, a concise assistant powered by Gemini 2.5 Pro.",
 		"Always provide focused, high-signal answers and respect the user's language.",
 		"When information may be outdated or needs verification, use the available web grounding search before responding.",
 		"Run calculations and data transformations through the code execution tool whenever computation is involved, and use its results in the final answer.",
 		"Load any user-provided URLs via the URL context tool to ground your responses in those sources.",
 		"Handle multimodal inputs such as images, audio, and video without asking the user to reformat them.",
-		"Produce replies that comply with Telegram MarkdownV2 formatting rules.",
+		"Format replies with standard Markdown; it is converted for Telegram before delivery.",
 	}, " ")
 	return genai.NewContentFromText(prompt, genai.Role("system"))
 }
@@ -623,28 +1725,28 @@ func collectSources(candidate *genai.Candidate) []sourceRef {
 	return sources
 }
 
-func formatCodeSnippet(index int, snippet codeSnippet) string {
+func formatCodeSnippet(lang language, index int, snippet codeSnippet) string {
 	language := strings.ToLower(strings.TrimSpace(snippet.Language))
 	if language == "" {
 		language = "text"
 	}
 
 	var b strings.Builder
-	b.WriteString(fmt.Sprintf("Code snippet %d:\n", index))
+	b.WriteString(tr(lang, "code_snippet", index) + ":\n")
 	b.WriteString("```")
 	b.WriteString(language)
 	b.WriteString("\n")
-	b.WriteString(escapeCode(snippet.Code))
+	b.WriteString(snippet.Code)
 	b.WriteString("\n```")
 	if snippet.Outcome != "" {
-		b.WriteString("\nOutcome: ")
+		b.WriteString("\n" + tr(lang, "code_outcome") + ": ")
 		b.WriteString(snippet.Outcome)
 	}
 	if strings.TrimSpace(snippet.Output) != "" {
-		b.WriteString("\nOutput:\n```")
+		b.WriteString("\n" + tr(lang, "code_output") + ":\n```")
 		b.WriteString(language)
 		b.WriteString("\n")
-		b.WriteString(escapeCode(snippet.Output))
+		b.WriteString(snippet.Output)
 		b.WriteString("\n```")
 	}
 	return b.String()
@@ -0,0 +1,548 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	telegramHost = "api.telegram.org"
+	geminiHost   = "generativelanguage.googleapis.com"
+)
+
+// fakeCall is a request one of the fake APIs received.
+type fakeCall struct {
+	host string
+	// method is the Bot API method for Telegram, else the request path.
+	method string
+	body   []byte
+}
+
+// fakeAPIs answers the requests of the bot in place of Telegram, Gemini and
+// any other host a test registers. It is installed as http.DefaultTransport,
+// which every client of the bot ends up using.
+type fakeAPIs struct {
+	mu     sync.Mutex
+	calls  []fakeCall
+	nextID int
+	// reply is the text of every Gemini answer.
+	reply string
+	// answer, when set, returns the parts of a Gemini answer in place of
+	// reply; model is the path segment naming the model.
+	answer func(model string, body []byte) []any
+	// metadata, when set, returns fields added to the candidate of a Gemini
+	// answer, such as its URL context metadata.
+	metadata func(body []byte) map[string]any
+	// tokens, when set, sizes a countTokens request in place of its length.
+	tokens func(body []byte) int
+	// telegramError, when set, returns the error code and description
+	// Telegram answers a request with, or zero to let it succeed.
+	telegramError func(method string, body []byte) (int, string)
+	// geminiError, when set, returns the status Gemini answers a request
+	// with, or zero to let it succeed.
+	geminiError func(path string, body []byte) int
+	// files holds the contents of the files Telegram serves, by file ID.
+	files map[string]string
+	// chats holds what getChat answers, by chat ID; other chats are not found.
+	chats map[string]map[string]any
+	hosts map[string]http.Handler
+}
+
+func (f *fakeAPIs) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	rec := httptest.NewRecorder()
+	switch host := req.URL.Hostname(); host {
+	case telegramHost:
+		f.serveTelegram(rec, req, body)
+	case geminiHost:
+		f.record(host, req.URL.Path, body)
+		f.serveGemini(rec, req, body)
+	default:
+		f.mu.Lock()
+		handler, ok := f.hosts[host]
+		f.mu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("unexpected request to %s", req.URL)
+		}
+		f.record(host, req.URL.Path, body)
+		handler.ServeHTTP(rec, req)
+	}
+	resp := rec.Result()
+	resp.Request = req
+	return resp, nil
+}
+
+func (f *fakeAPIs) record(host, method string, body []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = append(f.calls, fakeCall{host: host, method: method, body: body})
+}
+
+// handle serves the requests to host, for example a webhook.
+func (f *fakeAPIs) handle(host string, handler http.Handler) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.hosts[host] = handler
+}
+
+// callsTo returns the requests host received whose method or path contains
+// method.
+func (f *fakeAPIs) callsTo(host, method string) []fakeCall {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var calls []fakeCall
+	for _, call := range f.calls {
+		if call.host == host && strings.Contains(call.method, method) {
+			calls = append(calls, call)
+		}
+	}
+	return calls
+}
+
+// sentTexts returns the texts of the messages sent to Telegram.
+func (f *fakeAPIs) sentTexts() []string {
+	var texts []string
+	for _, call := range f.callsTo(telegramHost, "sendMessage") {
+		var params struct {
+			Text string `json:"text"`
+		}
+		if err := json.Unmarshal(call.body, &params); err == nil {
+			texts = append(texts, params.Text)
+		}
+	}
+	return texts
+}
+
+func (f *fakeAPIs) serveTelegram(w http.ResponseWriter, req *http.Request, body []byte) {
+	method := req.URL.Path[strings.LastIndex(req.URL.Path, "/")+1:]
+	if strings.HasPrefix(req.URL.Path, "/file/") {
+		f.record(telegramHost, "file", body)
+		f.mu.Lock()
+		content, ok := f.files[method]
+		f.mu.Unlock()
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		io.WriteString(w, content)
+		return
+	}
+	f.record(telegramHost, method, body)
+	f.mu.Lock()
+	fail := f.telegramError
+	f.mu.Unlock()
+	if fail != nil {
+		if code, description := fail(method, body); code != 0 {
+			writeJSON(w, map[string]any{"ok": false, "error_code": code, "description": description})
+			return
+		}
+	}
+	var result any = true
+	switch {
+	case method == "getMe":
+		result = map[string]any{"id": 1, "is_bot": true, "first_name": "Eteon", "username": "eteon_bot"}
+	case method == "getChat":
+		var params struct {
+			ChatID string `json:"chat_id"`
+		}
+		_ = json.Unmarshal(body, &params)
+		f.mu.Lock()
+		chat, ok := f.chats[params.ChatID]
+		f.mu.Unlock()
+		if !ok {
+			writeJSON(w, map[string]any{"ok": false, "error_code": 400, "description": "Bad Request: chat not found"})
+			return
+		}
+		result = chat
+	case method == "getFile":
+		var params struct {
+			FileID string `json:"file_id"`
+		}
+		_ = json.Unmarshal(body, &params)
+		result = map[string]any{"file_id": params.FileID, "file_path": "documents/" + params.FileID}
+	case strings.HasPrefix(method, "send"), strings.HasPrefix(method, "edit"):
+		var params struct {
+			ChatID string `json:"chat_id"`
+			Text   string `json:"text"`
+		}
+		_ = json.Unmarshal(body, &params)
+		chatID, _ := strconv.ParseInt(params.ChatID, 10, 64)
+		f.mu.Lock()
+		f.nextID++
+		id := 1000 + f.nextID
+		f.mu.Unlock()
+		sent := map[string]any{
+			"message_id": id,
+			"date":       time.Now().Unix(),
+			"chat":       map[string]any{"id": chatID, "type": "private"},
+			"text":       params.Text,
+		}
+		if method == "sendPhoto" {
+			// telebot reads the sent photo back from the answer.
+			fileID := fmt.Sprintf("photo%d", id)
+			sent["photo"] = []any{map[string]any{"file_id": fileID, "file_unique_id": fileID, "width": 640, "height": 480}}
+		}
+		result = sent
+	}
+	writeJSON(w, map[string]any{"ok": true, "result": result})
+}
+
+func (f *fakeAPIs) serveGemini(w http.ResponseWriter, req *http.Request, body []byte) {
+	path := req.URL.Path
+	f.mu.Lock()
+	geminiError := f.geminiError
+	f.mu.Unlock()
+	if geminiError != nil {
+		if code := geminiError(path, body); code != 0 {
+			http.Error(w, fmt.Sprintf(`{"error":{"code":%d,"message":"failed","status":"FAILED"}}`, code), code)
+			return
+		}
+	}
+	switch {
+	case strings.HasSuffix(path, ":generateContent"):
+		f.mu.Lock()
+		parts, answer, metadata := []any{map[string]any{"text": f.reply}}, f.answer, f.metadata
+		f.mu.Unlock()
+		if answer != nil {
+			model := strings.TrimSuffix(path[strings.LastIndex(path, "/")+1:], ":generateContent")
+			parts = answer(model, body)
+		}
+		candidate := map[string]any{
+			"content":      map[string]any{"role": "model", "parts": parts},
+			"finishReason": "STOP",
+		}
+		if metadata != nil {
+			maps.Copy(candidate, metadata(body))
+		}
+		writeJSON(w, map[string]any{
+			"candidates":    []any{candidate},
+			"usageMetadata": map[string]any{"promptTokenCount": 10, "candidatesTokenCount": 5, "totalTokenCount": 15},
+		})
+	case req.Method == http.MethodGet && strings.Contains(path, "/models/"):
+		name := path[strings.LastIndex(path, "/")+1:]
+		if _, ok := modelPricing[name]; !ok {
+			http.Error(w, `{"error":{"code":404,"message":"model not found","status":"NOT_FOUND"}}`, http.StatusNotFound)
+			return
+		}
+		writeJSON(w, map[string]any{"name": "models/" + name, "inputTokenLimit": 1048576, "outputTokenLimit": 65536})
+	case strings.HasSuffix(path, ":countTokens"):
+		f.mu.Lock()
+		total, tokens := len(body)/4, f.tokens
+		f.mu.Unlock()
+		if tokens != nil {
+			total = tokens(body)
+		}
+		writeJSON(w, map[string]any{"totalTokens": total})
+	case strings.HasSuffix(path, "/cachedContents") && req.Method == http.MethodPost:
+		writeJSON(w, map[string]any{"name": "cachedContents/history1", "expireTime": time.Now().Add(time.Hour).Format(time.RFC3339)})
+	case strings.Contains(path, "/cachedContents/"):
+		writeJSON(w, map[string]any{"name": path[strings.Index(path, "cachedContents/"):], "expireTime": time.Now().Add(time.Hour).Format(time.RFC3339)})
+	case strings.HasSuffix(path, ":batchEmbedContents"):
+		var req struct {
+			Requests []struct {
+				Content struct {
+					Parts []struct {
+						Text string `json:"text"`
+					} `json:"parts"`
+				} `json:"content"`
+			} `json:"requests"`
+		}
+		_ = json.Unmarshal(body, &req)
+		var embeddings []any
+		for _, r := range req.Requests {
+			var text string
+			for _, p := range r.Content.Parts {
+				text += p.Text
+			}
+			embeddings = append(embeddings, map[string]any{"values": fakeEmbedding(text)})
+		}
+		writeJSON(w, map[string]any{"embeddings": embeddings})
+	default:
+		http.Error(w, `{"error":{"code":404,"message":"not found","status":"NOT_FOUND"}}`, http.StatusNotFound)
+	}
+}
+
+// fakeEmbedding hashes the words of text into a small vector, so texts sharing
+// words come out similar.
+func fakeEmbedding(text string) []float32 {
+	vec := make([]float32, 32)
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		h := fnv.New32a()
+		h.Write([]byte(word))
+		vec[h.Sum32()%32]++
+	}
+	return vec
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// newTestApp builds an App through New from cfg, with the APIs it talks to
+// replaced by fakes. Unset credentials and the data directory are filled in.
+func newTestApp(t *testing.T, cfg Config) (*App, *fakeAPIs) {
+	t.Helper()
+	apis := &fakeAPIs{reply: "Sure.", hosts: make(map[string]http.Handler)}
+	transport := http.DefaultTransport
+	http.DefaultTransport = apis
+	// The clients for links users post only dial public addresses; the fakes
+	// answer in their place.
+	pageTransport, videoTransport := pageHTTPClient.Transport, videoLinkHTTPClient.Transport
+	pageHTTPClient.Transport, videoLinkHTTPClient.Transport = apis, apis
+	t.Cleanup(func() {
+		http.DefaultTransport = transport
+		pageHTTPClient.Transport, videoLinkHTTPClient.Transport = pageTransport, videoTransport
+	})
+
+	if cfg.TelegramToken == "" {
+		cfg.TelegramToken = "123:test-token"
+	}
+	if cfg.GeminiAPIKey == "" {
+		cfg.GeminiAPIKey = "test-key"
+	}
+	if cfg.DataDir == "" {
+		cfg.DataDir = t.TempDir()
+	}
+	app, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return app, apis
+}
+
+// testMessage is a text message from a user in a private chat.
+func testMessage(chatID int64, text string) *tele.Message {
+	return &tele.Message{
+		ID:       1,
+		Chat:     &tele.Chat{ID: chatID, Type: tele.ChatPrivate},
+		Sender:   &tele.User{ID: chatID, FirstName: "Test"},
+		Text:     text,
+		Unixtime: time.Now().Unix(),
+	}
+}
+
+func TestNewAnswersATurn(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.reply = "Hello there."
+
+	if err := app.processMessage(context.Background(), testMessage(42, "Hi"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	if texts := apis.sentTexts(); !containsText(texts, "Hello there") {
+		t.Fatalf("sent %q, want the answer", texts)
+	}
+}
+
+func containsText(texts []string, want string) bool {
+	for _, text := range texts {
+		if strings.Contains(text, want) {
+			return true
+		}
+	}
+	return false
+}
+
+// toolsOf decodes the tools a generateContent request offered.
+func toolsOf(t *testing.T, body []byte) (builtin, functions int) {
+	t.Helper()
+	var req struct {
+		Tools []map[string]json.RawMessage `json:"tools"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		t.Fatalf("decode request: %v", err)
+	}
+	for _, tool := range req.Tools {
+		if _, ok := tool["functionDeclarations"]; ok {
+			functions++
+		} else {
+			builtin++
+		}
+	}
+	return builtin, functions
+}
+
+func TestTurnRoutesToTheFunctions(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.answer = func(model string, body []byte) []any {
+		switch {
+		case model == toolRouterModel, !bytes.Contains(body, []byte("functionResponse")):
+			return []any{map[string]any{"functionCall": map[string]any{
+				"name": "convert_units",
+				"args": map[string]any{"value": 5, "from": "km", "to": "m"},
+			}}}
+		}
+		return []any{map[string]any{"text": "That is 5000 m."}}
+	}
+
+	if err := app.processMessage(context.Background(), testMessage(42, "5 km in m?"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	calls := apis.callsTo(geminiHost, ":generateContent")
+	if len(calls) != 3 || !strings.Contains(calls[0].method, toolRouterModel) {
+		t.Fatalf("got %d requests, want the router, the call and the answer", len(calls))
+	}
+	for i, call := range calls {
+		if builtin, functions := toolsOf(t, call.body); builtin != 0 || functions != 1 {
+			t.Fatalf("request %d offered %d built-in and %d function tools, want the functions only", i, builtin, functions)
+		}
+	}
+	if !bytes.Contains(calls[2].body, []byte(`"value":5000`)) {
+		t.Fatalf("answer request lacks the conversion: %s", calls[2].body)
+	}
+	if texts := apis.sentTexts(); !containsText(texts, "5000 m") {
+		t.Fatalf("sent %q, want the answer", texts)
+	}
+}
+
+func TestTurnRoutesToTheBuiltinTools(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.reply = "Here is the news."
+
+	if err := app.processMessage(context.Background(), testMessage(42, "Any news today?"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	calls := apis.callsTo(geminiHost, ":generateContent")
+	if len(calls) != 2 {
+		t.Fatalf("got %d requests, want the router and the answer", len(calls))
+	}
+	if builtin, functions := toolsOf(t, calls[0].body); builtin != 0 || functions != 1 {
+		t.Fatalf("router offered %d built-in and %d function tools, want the functions only", builtin, functions)
+	}
+	if builtin, functions := toolsOf(t, calls[1].body); builtin != 1 || functions != 0 {
+		t.Fatalf("answer offered %d built-in and %d function tools, want the built-in tools only", builtin, functions)
+	}
+}
+
+func TestToolRoutingSkipsTheRouter(t *testing.T) {
+	for routing, wantFunctions := range map[string]int{"functions": 1, "builtin": 0} {
+		app, apis := newTestApp(t, Config{ToolRouting: routing})
+		apis.reply = "Done."
+		if err := app.processMessage(context.Background(), testMessage(42, "5 km in m?"), turnOptions{}); err != nil {
+			t.Fatalf("processMessage: %v", err)
+		}
+		calls := apis.callsTo(geminiHost, ":generateContent")
+		if len(calls) != 1 {
+			t.Fatalf("routing %s made %d requests, want the answer only", routing, len(calls))
+		}
+		if _, functions := toolsOf(t, calls[0].body); functions != wantFunctions {
+			t.Errorf("routing %s offered %d function tools, want %d", routing, functions, wantFunctions)
+		}
+	}
+	if err := (Config{TelegramToken: "t", GeminiAPIKey: "k", ToolRouting: "random"}).Validate(); err == nil {
+		t.Error("an unknown routing was accepted")
+	}
+}
+
+func TestToolRouterIsCharged(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.reply = "Here is the news."
+	if err := app.processMessage(context.Background(), testMessage(42, "Any news today?"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	if day, _ := app.usage.chatSnapshot(42); day.Requests != 2 {
+		t.Errorf("recorded %d requests, want the router and the answer", day.Requests)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+func TestTestEnvironmentUsesTestServers(t *testing.T) {
+	if err := (Config{TestEnvironment: true, GeminiAPIKey: "key"}).Validate(); err == nil || !strings.Contains(err.Error(), "TELEGRAM_TEST_BOT_TOKEN") {
+		t.Fatalf("Validate = %v, want it to name the test token", err)
+	}
+
+	app, apis := newTestApp(t, Config{TestEnvironment: true})
+	var mu sync.Mutex
+	var paths []string
+	http.DefaultTransport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Hostname() == telegramHost {
+			mu.Lock()
+			paths = append(paths, req.URL.Path)
+			mu.Unlock()
+		}
+		return apis.RoundTrip(req)
+	})
+
+	if err := app.processMessage(context.Background(), testMessage(42, "Hi"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(paths) == 0 {
+		t.Fatal("no requests reached Telegram")
+	}
+	for _, path := range paths {
+		if !strings.HasPrefix(path, "/bot123:test-token/test/") {
+			t.Errorf("request to %s, want the test environment", path)
+		}
+	}
+}
+
+func TestDeterministicModePinsSampling(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	command := func(handler tele.HandlerFunc, text, payload string) {
+		t.Helper()
+		msg := testMessage(42, text)
+		msg.Payload = payload
+		if err := handler(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("%s: %v", text, err)
+		}
+	}
+	lastRequest := func() string {
+		t.Helper()
+		if err := app.processMessage(context.Background(), testMessage(42, "Pick a number"), turnOptions{}); err != nil {
+			t.Fatalf("processMessage: %v", err)
+		}
+		calls := apis.callsTo(geminiHost, ":generateContent")
+		return string(calls[len(calls)-1].body)
+	}
+
+	command(app.handleDeterministic, "/deterministic on", "on")
+	if texts := apis.sentTexts(); !containsText(texts, "seed 42") {
+		t.Errorf("sent %q, want the seed named", texts)
+	}
+	if body := lastRequest(); !strings.Contains(body, `"temperature":0`) || !strings.Contains(body, `"seed":42`) {
+		t.Errorf("deterministic request = %.300s", body)
+	}
+
+	command(app.handleAdvanced, "/advanced seed 7", "seed 7")
+	if body := lastRequest(); !strings.Contains(body, `"temperature":0`) || !strings.Contains(body, `"seed":7`) {
+		t.Errorf("the seed of /advanced does not win: %.300s", body)
+	}
+
+	command(app.handleAdvanced, "/advanced reset", "reset")
+	command(app.handleDeterministic, "/deterministic", "")
+	if texts := apis.sentTexts(); !containsText(texts, "Deterministic mode disabled") {
+		t.Errorf("sent %q, want the toggle to turn it off", texts)
+	}
+	if body := lastRequest(); strings.Contains(body, `"seed"`) {
+		t.Errorf("request after turning it off keeps a seed: %.300s", body)
+	}
+}
@@ -1,20 +1,49 @@
 package app
 
 import (
+    "context"
+    "fmt"
+    "log/slog"
+    "slices"
     "strconv"
     "sync"
     "sync/atomic"
+    "time"
+)
+
+const (
+    // artifactTTL is how long the buttons under a reply keep working.
+    artifactTTL = 48 * time.Hour
+    // artifactSweepInterval limits how often put scans for expired entries.
+    artifactSweepInterval = time.Minute
 )
 
 type responseArtifacts struct {
-    Thoughts    []string
-    Sources     []sourceRef
+    Thoughts     []string
+    Sources      []sourceRef
     CodeSnippets []codeSnippet
+    Prompt       string
+    Reply        string
+    CreatedAt    time.Time
+    ChatID       int64
+    // MessageID is the prompt the reply answers; with ChatID it makes the
+    // key of the entry. ReplyID is the reply itself once sent.
+    MessageID int
+    ReplyID   int
+    // Previous holds the earlier texts of a regenerated reply, oldest first.
+    Previous []string
+    // Review holds the reviewed patch and the comments on it.
+    Review *patchReview
+    // Truncated marks a reply cut off at the token limit.
+    Truncated bool
+    // Media holds the images and files of the answer until they are sent;
+    // the store does not keep them.
+    Media []responseMedia `json:"-"`
 }
 
 type sourceRef struct {
-    Title string
-    URI   string
+    Title string `json:"title,omitempty"`
+    URI   string `json:"uri"`
 }
 
 type codeSnippet struct {
@@ -24,15 +53,87 @@ type codeSnippet struct {
     Output   string
 }
 
+// size approximates the memory the artifacts hold, counting their text.
+func (a *responseArtifacts) size() int {
+    n := len(a.Prompt) + len(a.Reply)
+    for _, p := range a.Previous {
+        n += len(p)
+    }
+    if a.Review != nil {
+        n += a.Review.size()
+    }
+    for _, t := range a.Thoughts {
+        n += len(t)
+    }
+    for _, s := range a.Sources {
+        n += len(s.Title) + len(s.URI)
+    }
+    for _, c := range a.CodeSnippets {
+        n += len(c.Language) + len(c.Code) + len(c.Outcome) + len(c.Output)
+    }
+    return n
+}
+
+// artifactBackend keeps the artifacts outside the process: Redis shares them
+// between instances, SQLite keeps them across restarts of a single one. Both
+// drop an entry once ttl has passed.
+type artifactBackend interface {
+    saveArtifact(ctx context.Context, id string, art *responseArtifacts, ttl time.Duration) error
+    // loadArtifact returns the entry stored under id, or nil when there is
+    // none or it expired.
+    loadArtifact(ctx context.Context, id string) (*responseArtifacts, error)
+    // purgeArtifacts drops the entries of a chat and returns how many there were.
+    purgeArtifacts(ctx context.Context, chatID int64) (int, error)
+    // migrateArtifacts moves the entries of chat from to chat to.
+    migrateArtifacts(ctx context.Context, from, to int64, ttl time.Duration) error
+}
+
 type artifactStore struct {
-    mu      sync.RWMutex
-    items   map[string]*responseArtifacts
-    counter uint64
+    mu        sync.RWMutex
+    items     map[string]*responseArtifacts
+    counter   uint64
+    // epoch sets the IDs of entries without a prompt apart from those handed
+    // out before a restart.
+    epoch     string
+    ttl       time.Duration
+    now       func() time.Time
+    lastSweep time.Time
+
+    // hits and misses count button presses that found or missed their reply;
+    // evicted counts entries dropped by the TTL or a purge.
+    hits    uint64
+    misses  uint64
+    evicted uint64
+
+    // shared, when set, keeps the artifacts in Redis or SQLite so the buttons
+    // under a reply keep working after a restart and, with Redis, on every
+    // instance of the bot; items then caches them.
+    shared artifactBackend
+}
+
+// artifactStats describes the store for /admin.
+type artifactStats struct {
+    Items   int
+    Bytes   int
+    Hits    uint64
+    Misses  uint64
+    Evicted uint64
+}
+
+// artifactInfo summarises one stored reply for /admin.
+type artifactInfo struct {
+    ID        string
+    Prompt    string
+    Bytes     int
+    CreatedAt time.Time
 }
 
 func newArtifactStore() *artifactStore {
     return &artifactStore{
         items: make(map[string]*responseArtifacts),
+        epoch: strconv.FormatInt(time.Now().Unix(), 36),
+        ttl:   artifactTTL,
+        now:   time.Now,
     }
 }
 
@@ -40,17 +141,194 @@ func (s *artifactStore) put(art *responseArtifacts) string {
     if art == nil {
         return ""
     }
-    id := atomic.AddUint64(&s.counter, 1)
-    key := strconv.FormatUint(id, 10)
+    now := s.now()
+    if art.CreatedAt.IsZero() {
+        art.CreatedAt = now
+    }
+    key := s.nextID(art)
     s.mu.Lock()
     s.items[key] = art
+    if now.Sub(s.lastSweep) >= artifactSweepInterval {
+        s.sweepLocked(now)
+    }
     s.mu.Unlock()
+    if s.shared != nil {
+        ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+        defer cancel()
+        if err := s.shared.saveArtifact(ctx, key, art, s.ttl); err != nil {
+            slog.Warn("save shared artifact failed", "id", key, "err", err)
+        }
+    }
     return key
 }
 
+// nextID returns the key of a new entry. It derives from the chat and the
+// prompt, so no instance and no later run of the bot hands out the same one;
+// another reply to the same prompt, such as a regenerated one, replaces the
+// entry along with the message. Entries without a prompt count up from the
+// start of the process.
+func (s *artifactStore) nextID(art *responseArtifacts) string {
+    if art.ChatID != 0 && art.MessageID != 0 {
+        return fmt.Sprintf("%d_%d", art.ChatID, art.MessageID)
+    }
+    return s.epoch + "-" + strconv.FormatUint(atomic.AddUint64(&s.counter, 1), 10)
+}
+
 func (s *artifactStore) get(id string) (*responseArtifacts, bool) {
     s.mu.RLock()
-    defer s.mu.RUnlock()
     art, ok := s.items[id]
+    s.mu.RUnlock()
+    if !ok && s.shared != nil {
+        art, ok = s.loadShared(id)
+    }
+    if ok && s.expired(art, s.now()) {
+        ok = false
+    }
+    if ok {
+        atomic.AddUint64(&s.hits, 1)
+    } else {
+        atomic.AddUint64(&s.misses, 1)
+    }
     return art, ok
-}
+}
+
+// setReply records the message that carries the reply stored under id. The
+// entry is replaced by a copy, since readers hold the published one without
+// the lock.
+func (s *artifactStore) setReply(id string, replyID int) {
+    s.mu.Lock()
+    art, ok := s.items[id]
+    if ok {
+        updated := *art
+        updated.ReplyID = replyID
+        art = &updated
+        s.items[id] = art
+    }
+    s.mu.Unlock()
+    if !ok || s.shared == nil {
+        return
+    }
+    ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+    defer cancel()
+    if err := s.shared.saveArtifact(ctx, id, art, s.ttl); err != nil {
+        slog.Warn("save shared artifact failed", "id", id, "err", err)
+    }
+}
+
+// forReply returns the entry of the reply replyID in chatID, looking only at
+// the replies this instance holds.
+func (s *artifactStore) forReply(chatID int64, replyID int) (*responseArtifacts, bool) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    now := s.now()
+    for _, art := range s.items {
+        if art.ChatID == chatID && art.ReplyID == replyID && !s.expired(art, now) {
+            return art, true
+        }
+    }
+    return nil, false
+}
+
+// loadShared fetches an entry another instance stored and caches it.
+func (s *artifactStore) loadShared(id string) (*responseArtifacts, bool) {
+    ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+    defer cancel()
+    art, err := s.shared.loadArtifact(ctx, id)
+    if err != nil {
+        slog.Warn("load shared artifact failed", "id", id, "err", err)
+    }
+    if art == nil {
+        return nil, false
+    }
+    s.mu.Lock()
+    s.items[id] = art
+    s.mu.Unlock()
+    return art, true
+}
+
+func (s *artifactStore) expired(art *responseArtifacts, now time.Time) bool {
+    return s.ttl > 0 && now.Sub(art.CreatedAt) > s.ttl
+}
+
+func (s *artifactStore) sweepLocked(now time.Time) {
+    s.lastSweep = now
+    for id, art := range s.items {
+        if s.expired(art, now) {
+            delete(s.items, id)
+            s.evicted++
+        }
+    }
+}
+
+func (s *artifactStore) stats() artifactStats {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    st := artifactStats{
+        Items:   len(s.items),
+        Hits:    atomic.LoadUint64(&s.hits),
+        Misses:  atomic.LoadUint64(&s.misses),
+        Evicted: s.evicted,
+    }
+    for _, art := range s.items {
+        st.Bytes += art.size()
+    }
+    return st
+}
+
+// forChat lists the replies stored for chatID, oldest first.
+func (s *artifactStore) forChat(chatID int64) []artifactInfo {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    var infos []artifactInfo
+    for id, art := range s.items {
+        if art.ChatID == chatID {
+            infos = append(infos, artifactInfo{ID: id, Prompt: art.Prompt, Bytes: art.size(), CreatedAt: art.CreatedAt})
+        }
+    }
+    slices.SortFunc(infos, func(a, b artifactInfo) int {
+        return a.CreatedAt.Compare(b.CreatedAt)
+    })
+    return infos
+}
+
+// migrate moves the replies stored for chat from to chat to.
+func (s *artifactStore) migrate(from, to int64) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    for _, art := range s.items {
+        if art.ChatID == from {
+            art.ChatID = to
+        }
+    }
+    if s.shared != nil {
+        ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+        defer cancel()
+        if err := s.shared.migrateArtifacts(ctx, from, to, s.ttl); err != nil {
+            slog.Warn("migrate shared artifacts failed", "from", from, "to", to, "err", err)
+        }
+    }
+}
+
+// purgeChat drops every reply stored for chatID and returns how many there were.
+func (s *artifactStore) purgeChat(chatID int64) int {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    n := 0
+    for id, art := range s.items {
+        if art.ChatID == chatID {
+            delete(s.items, id)
+            n++
+        }
+    }
+    s.evicted += uint64(n)
+    if s.shared != nil {
+        ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+        defer cancel()
+        shared, err := s.shared.purgeArtifacts(ctx, chatID)
+        if err != nil {
+            slog.Warn("purge shared artifacts failed", "chat_id", chatID, "err", err)
+        }
+        n = max(n, shared)
+    }
+    return n
+}
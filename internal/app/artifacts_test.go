@@ -0,0 +1,72 @@
+package app
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestArtifactStoreExpiresAndPurges(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := newArtifactStore()
+	store.now = func() time.Time { return now }
+
+	old := store.put(&responseArtifacts{ChatID: 1, Reply: "old"})
+	now = now.Add(artifactTTL + time.Minute)
+	fresh := store.put(&responseArtifacts{ChatID: 1, Reply: "fresh"})
+	other := store.put(&responseArtifacts{ChatID: 2, Reply: "other"})
+
+	if _, ok := store.get(old); ok {
+		t.Fatal("expired reply is still served")
+	}
+	if _, ok := store.get(fresh); !ok {
+		t.Fatal("fresh reply is missing")
+	}
+	st := store.stats()
+	if st.Items != 2 || st.Evicted != 1 || st.Hits != 1 || st.Misses != 1 {
+		t.Fatalf("stats = %+v, want 2 items, 1 evicted, 1 hit and 1 miss", st)
+	}
+
+	if n := store.purgeChat(1); n != 1 {
+		t.Fatalf("purged %d replies, want 1", n)
+	}
+	if infos := store.forChat(2); len(infos) != 1 || infos[0].ID != other {
+		t.Fatalf("chat 2 has %+v after purging chat 1", infos)
+	}
+}
+
+func TestArtifactStoreSetReplyWhileRead(t *testing.T) {
+	store := newArtifactStore()
+	id := store.put(&responseArtifacts{ChatID: 1, MessageID: 5, Reply: "answer"})
+
+	// Readers look at the entry outside the lock, as the button handlers do,
+	// until they see the reply.
+	var wg, started sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		started.Add(1)
+		go func() {
+			defer wg.Done()
+			started.Done()
+			for {
+				art, ok := store.get(id)
+				if !ok {
+					t.Error("entry is missing")
+					return
+				}
+				runtime.Gosched()
+				if art.ReplyID == 6 {
+					return
+				}
+			}
+		}()
+	}
+	started.Wait()
+	store.setReply(id, 6)
+	wg.Wait()
+
+	if art, ok := store.forReply(1, 6); !ok || art.Reply != "answer" {
+		t.Fatalf("forReply = %+v, %v", art, ok)
+	}
+}
@@ -0,0 +1,248 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	bookmarkUnique = "bookmark_reply"
+	// maxBookmarksPerUser bounds the bookmarks of a user.
+	maxBookmarksPerUser = 200
+	// bookmarkListSize is how many bookmarks /bookmarks shows at once.
+	bookmarkListSize = 10
+)
+
+// bookmark is an answer a user saved with the Bookmark button.
+type bookmark struct {
+	ID      int64       `json:"id"`
+	SavedAt time.Time   `json:"saved_at"`
+	ChatID  int64       `json:"chat_id"`
+	Prompt  string      `json:"prompt,omitempty"`
+	Text    string      `json:"text"`
+	Sources []sourceRef `json:"sources,omitempty"`
+}
+
+// title is the prompt of the bookmark, or the start of its text, in one line.
+func (b bookmark) title() string {
+	title := b.Prompt
+	if title == "" {
+		title = b.Text
+	}
+	title = strings.Join(strings.Fields(title), " ")
+	if utf8.RuneCountInString(title) > 60 {
+		title = string([]rune(title)[:60]) + "…"
+	}
+	return title
+}
+
+func (b bookmark) matches(query string) bool {
+	query = strings.ToLower(query)
+	return strings.Contains(strings.ToLower(b.Prompt), query) || strings.Contains(strings.ToLower(b.Text), query)
+}
+
+// bookmarkStore keeps the bookmarks of every user across restarts in a JSON
+// file. Every change rewrites the file atomically.
+type bookmarkStore struct {
+	mu   sync.Mutex
+	path string
+	data bookmarkData
+}
+
+type bookmarkData struct {
+	NextID int64                `json:"next_id"`
+	Users  map[int64][]bookmark `json:"users"`
+}
+
+func openBookmarkStore(path string) (*bookmarkStore, error) {
+	s := &bookmarkStore{path: path, data: bookmarkData{Users: make(map[int64][]bookmark)}}
+	if err := readJSONFile(path, &s.data); err != nil {
+		return nil, err
+	}
+	if s.data.Users == nil {
+		s.data.Users = make(map[int64][]bookmark)
+	}
+	return s, nil
+}
+
+// save writes the store; the caller holds s.mu.
+func (s *bookmarkStore) save() error {
+	return writeJSONFile(s.path, s.data)
+}
+
+var (
+	errTooManyBookmarks = errors.New("too many bookmarks")
+	errBookmarkExists   = errors.New("already bookmarked")
+)
+
+// add saves b for userID and returns it with its ID.
+func (s *bookmarkStore) add(userID int64, b bookmark) (bookmark, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := s.data.Users[userID]
+	if i := slices.IndexFunc(list, func(other bookmark) bool { return other.Text == b.Text }); i >= 0 {
+		return list[i], errBookmarkExists
+	}
+	if len(list) >= maxBookmarksPerUser {
+		return b, errTooManyBookmarks
+	}
+	s.data.NextID++
+	b.ID = s.data.NextID
+	s.data.Users[userID] = append(list, b)
+	return b, s.save()
+}
+
+// find returns the bookmarks of userID matching query, newest first.
+func (s *bookmarkStore) find(userID int64, query string) []bookmark {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var found []bookmark
+	list := s.data.Users[userID]
+	for i := len(list) - 1; i >= 0; i-- {
+		if query == "" || list[i].matches(query) {
+			found = append(found, list[i])
+		}
+	}
+	return found
+}
+
+func (s *bookmarkStore) get(userID, id int64) (bookmark, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.data.Users[userID], func(b bookmark) bool { return b.ID == id })
+	if i < 0 {
+		return bookmark{}, false
+	}
+	return s.data.Users[userID][i], true
+}
+
+func (s *bookmarkStore) remove(userID, id int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := s.data.Users[userID]
+	i := slices.IndexFunc(list, func(b bookmark) bool { return b.ID == id })
+	if i < 0 {
+		return false, nil
+	}
+	s.data.Users[userID] = slices.Delete(list, i, i+1)
+	if len(s.data.Users[userID]) == 0 {
+		delete(s.data.Users, userID)
+	}
+	return true, s.save()
+}
+
+// forgetUser removes every bookmark of userID.
+func (s *bookmarkStore) forgetUser(userID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data.Users[userID]; !ok {
+		return nil
+	}
+	delete(s.data.Users, userID)
+	return s.save()
+}
+
+// handleBookmark saves the reply under the pressed button to the presser's
+// bookmarks.
+func (a *App) handleBookmark(c tele.Context) error {
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	art, ok := a.artifacts.get(c.Callback().Data)
+	if !ok {
+		return c.Respond(&tele.CallbackResponse{Text: tr(lang, "bookmark_expired")})
+	}
+	b, err := a.bookmarks.add(userIDOf(c.Sender()), bookmark{
+		SavedAt: time.Now().UTC(),
+		ChatID:  c.Chat().ID,
+		Prompt:  art.Prompt,
+		Text:    art.Reply,
+		Sources: art.Sources,
+	})
+	var notice string
+	switch {
+	case errors.Is(err, errBookmarkExists):
+		notice = tr(lang, "bookmark_exists", b.ID)
+	case errors.Is(err, errTooManyBookmarks):
+		notice = tr(lang, "bookmark_too_many", maxBookmarksPerUser)
+	case err != nil:
+		a.chatLog(c.Chat().ID).Warn("save bookmarks failed", "chat_id", c.Chat().ID, "err", err)
+		notice = tr(lang, "bookmark_failed")
+	default:
+		notice = tr(lang, "bookmark_saved", b.ID)
+	}
+	return c.Respond(&tele.CallbackResponse{Text: notice})
+}
+
+// handleBookmarks browses the sender's bookmarks:
+//
+//	/bookmarks               lists the newest
+//	/bookmarks <words>       lists those containing the words
+//	/bookmarks <id>          shows one with its sources
+//	/bookmarks delete <id>   deletes one
+func (a *App) handleBookmarks(c tele.Context) error {
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	reply := func(body string) error {
+		_, err := a.sendWithFallback(c.Chat(), body, &tele.SendOptions{ThreadID: messageTopic(c.Message()), DisableWebPagePreview: true})
+		return err
+	}
+	userID := userIDOf(c.Sender())
+	payload := strings.TrimSpace(c.Message().Payload)
+	bookmarkID := func(s string) (int64, bool) {
+		id, err := strconv.ParseInt(strings.TrimPrefix(s, "#"), 10, 64)
+		return id, err == nil
+	}
+
+	if command, rest, _ := strings.Cut(payload, " "); strings.EqualFold(command, "delete") {
+		id, ok := bookmarkID(strings.TrimSpace(rest))
+		if !ok {
+			return reply(tr(lang, "bookmarks_usage"))
+		}
+		removed, err := a.bookmarks.remove(userID, id)
+		if err != nil {
+			a.chatLog(c.Chat().ID).Warn("save bookmarks failed", "chat_id", c.Chat().ID, "err", err)
+		}
+		if !removed {
+			return reply(tr(lang, "bookmark_unknown", id))
+		}
+		return reply(tr(lang, "bookmark_deleted", id))
+	}
+	if id, ok := bookmarkID(payload); ok {
+		b, found := a.bookmarks.get(userID, id)
+		if !found {
+			return reply(tr(lang, "bookmark_unknown", id))
+		}
+		var body strings.Builder
+		fmt.Fprintf(&body, "*#%d · %s*\n\n%s", b.ID, b.title(), b.Text)
+		if len(b.Sources) > 0 {
+			body.WriteString("\n\n" + tr(lang, "sources_header"))
+			for i, src := range b.Sources {
+				title := src.Title
+				if title == "" {
+					title = tr(lang, "source_untitled")
+				}
+				fmt.Fprintf(&body, "\n%d. %s - %s", i+1, title, src.URI)
+			}
+		}
+		return reply(body.String())
+	}
+
+	found := a.bookmarks.find(userID, payload)
+	if len(found) == 0 {
+		if payload != "" {
+			return reply(tr(lang, "bookmarks_no_match", payload))
+		}
+		return reply(tr(lang, "bookmarks_none"))
+	}
+	lines := make([]string, 0, bookmarkListSize)
+	for _, b := range found[:min(len(found), bookmarkListSize)] {
+		lines = append(lines, fmt.Sprintf("#%d · %s · %s", b.ID, b.SavedAt.Format("2006-01-02"), b.title()))
+	}
+	return reply(tr(lang, "bookmarks_list", len(found), strings.Join(lines, "\n")))
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestBookmarkReplyAndBrowse(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.reply = "Sourdough needs a 12 hour cold proof."
+	if err := app.processMessage(context.Background(), testMessage(42, "How long to proof sourdough?"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	var id string
+	for key := range app.artifacts.items {
+		id = key
+	}
+	press := func() string {
+		t.Helper()
+		if err := app.handleBookmark(actionCallback(app, 42, 42, id)); err != nil {
+			t.Fatalf("handleBookmark: %v", err)
+		}
+		calls := apis.callsTo(telegramHost, "answerCallbackQuery")
+		return string(calls[len(calls)-1].body)
+	}
+	bookmarks := func(payload string) string {
+		t.Helper()
+		msg := testMessage(42, "/bookmarks "+payload)
+		msg.Payload = payload
+		if err := app.handleBookmarks(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("handleBookmarks(%q): %v", payload, err)
+		}
+		texts := apis.sentTexts()
+		return texts[len(texts)-1]
+	}
+
+	if got := bookmarks(""); !strings.Contains(got, "no bookmarks yet") {
+		t.Errorf("empty list answered %q", got)
+	}
+	if got := press(); !strings.Contains(got, "Bookmarked as #1") {
+		t.Fatalf("bookmark answered %s", got)
+	}
+	if got := press(); !strings.Contains(got, "Already bookmarked as #1") {
+		t.Errorf("second press answered %s", got)
+	}
+	if got := bookmarks(""); !strings.Contains(got, "How long to proof sourdough?") {
+		t.Errorf("list answered %q", got)
+	}
+	if got := bookmarks("cold proof"); !strings.Contains(got, "#1") {
+		t.Errorf("search answered %q", got)
+	}
+	if got := bookmarks("croissant"); !strings.Contains(got, "No bookmarks contain") {
+		t.Errorf("search without match answered %q", got)
+	}
+	if got := bookmarks("1"); !strings.Contains(got, "12 hour cold proof") {
+		t.Errorf("open answered %q", got)
+	}
+
+	reopened, err := openBookmarkStore(filepath.Join(app.dataDir, "bookmarks.json"))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if b, ok := reopened.get(42, 1); !ok || b.Prompt != "How long to proof sourdough?" {
+		t.Errorf("persisted bookmark = %+v", b)
+	}
+	if _, ok := reopened.get(7, 1); ok {
+		t.Error("another user sees the bookmark")
+	}
+
+	if got := bookmarks("delete 1"); !strings.Contains(got, "1 deleted") {
+		t.Errorf("delete answered %q", got)
+	}
+	if got := bookmarks("1"); !strings.Contains(got, "no bookmark") {
+		t.Errorf("deleted bookmark answered %q", got)
+	}
+}
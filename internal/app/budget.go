@@ -0,0 +1,75 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// What requests do once a daily spend limit is reached, as set by
+// Config.OverBudget.
+const (
+	overBudgetLight  = "light"
+	overBudgetRefuse = "refuse"
+)
+
+// Daily spend limits a request can be over, as logged and shown in /admin
+// stats.
+const (
+	budgetDaily = "daily budget"
+	budgetUser  = "user budget"
+)
+
+// parseOverBudget reads Config.OverBudget, where empty means light.
+func parseOverBudget(raw string) (string, error) {
+	switch action := strings.ToLower(strings.TrimSpace(raw)); action {
+	case "", overBudgetLight:
+		return overBudgetLight, nil
+	case overBudgetRefuse:
+		return overBudgetRefuse, nil
+	default:
+		return "", fmt.Errorf("unknown action %q, want light or refuse", raw)
+	}
+}
+
+// budgetExceeded reports which daily spend limit a request of userID is over:
+// budgetDaily when the whole bot spent DailyBudgetUSD today, budgetUser when
+// the user spent UserDailyBudgetUSD, or "". The users of ADMIN_USER_IDS have no
+// limit of their own.
+func (a *App) budgetExceeded(userID int64) string {
+	t := a.tuned()
+	if t.dailyBudget > 0 {
+		if day, _ := a.usage.globalSnapshot(); day.CostUSD >= t.dailyBudget {
+			return budgetDaily
+		}
+	}
+	if t.userDailyBudget > 0 && userID != 0 && !slices.Contains(a.admins, userID) {
+		if a.usage.userSpentToday(userID) >= t.userDailyBudget {
+			return budgetUser
+		}
+	}
+	return ""
+}
+
+// refuseOverBudget tells the sender of msg that their request was turned
+// down because a daily spend limit is reached, when the limits refuse rather
+// than go light. It reports whether it did.
+func (a *App) refuseOverBudget(msg *tele.Message) (bool, error) {
+	chat, sender := msg.Chat, msg.Sender
+	if a.tuned().overBudget != overBudgetRefuse {
+		return false, nil
+	}
+	over := a.budgetExceeded(userIDOf(sender))
+	if over == "" {
+		return false, nil
+	}
+	key := "budget_refused"
+	if over == budgetUser {
+		key = "budget_user_limit"
+	}
+	reset := a.usage.nextDay().Format("15:04 MST")
+	_, err := a.sendWithFallback(inTopic(chat, msg), tr(a.chatLanguage(chat, sender), key, reset), &tele.SendOptions{DisableWebPagePreview: true})
+	return true, err
+}
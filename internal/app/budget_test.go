@@ -0,0 +1,87 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestUserDailyBudgetGoesLight(t *testing.T) {
+	app, apis := newTestApp(t, Config{UserDailyBudgetUSD: 1, AdminUserIDs: []int64{9}})
+	apis.reply = "Short answer."
+	if err := app.processMessage(context.Background(), testMessage(42, "Hi"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	if spent := app.usage.userSpentToday(42); spent <= 0 {
+		t.Fatalf("the turn charged the user $%g", spent)
+	}
+
+	app.usage.charge(42, tokenUsage{CostUSD: 1})
+	app.usage.charge(9, tokenUsage{CostUSD: 1})
+	for _, chatID := range []int64{7, 9, 42} {
+		if err := app.processMessage(context.Background(), testMessage(chatID, "Hi again"), turnOptions{}); err != nil {
+			t.Fatalf("processMessage: %v", err)
+		}
+	}
+	calls := apis.callsTo(geminiHost, loadSheddingModel+":generateContent")
+	if len(calls) != 1 {
+		t.Fatalf("%s answered %d times, want only the user over the limit moved to it", loadSheddingModel, len(calls))
+	}
+	texts := apis.sentTexts()
+	if reply := texts[len(texts)-1]; !strings.Contains(reply, "smaller model") || strings.Contains(reply, "busy") {
+		t.Errorf("reply = %q, want the budget notice", reply)
+	}
+}
+
+func TestCommandsChargeTheUser(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.reply = "Guten Morgen"
+	msg := testMessage(42, "/translate de Good morning")
+	msg.Payload = "de Good morning"
+	if err := app.handleTranslate(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+		t.Fatalf("handleTranslate: %v", err)
+	}
+	app.queue.drain(context.Background())
+	if spent := app.usage.userSpentToday(42); spent <= 0 {
+		t.Errorf("/translate charged the user $%g", spent)
+	}
+	if day, _ := app.usage.chatSnapshot(42); day.Requests != 1 {
+		t.Errorf("/translate recorded %d requests for the chat, want 1", day.Requests)
+	}
+}
+
+func TestDailyBudgetRefuses(t *testing.T) {
+	app, apis := newTestApp(t, Config{DailyBudgetUSD: 1, OverBudget: "refuse"})
+	// 1M output tokens of gemini-2.5-pro cost $10.
+	app.usage.record(1, "gemini-2.5-pro", &genai.GenerateContentResponseUsageMetadata{CandidatesTokenCount: 100_000})
+
+	msg := testMessage(42, "Hi")
+	if err := app.enqueueTurn(msg, turnOptions{}); err != nil {
+		t.Fatalf("enqueueTurn: %v", err)
+	}
+	app.queue.drain(context.Background())
+	if calls := apis.callsTo(geminiHost, ":generateContent"); len(calls) != 0 {
+		t.Fatalf("answered %d times over the daily budget", len(calls))
+	}
+	texts := apis.sentTexts()
+	if len(texts) != 1 || !strings.Contains(texts[0], "usage limit for today") {
+		t.Errorf("sent %q, want the refusal", texts)
+	}
+	if stats := app.adminStats(defaultLanguage); !strings.Contains(stats, "Daily budget of $1.00 reached: new requests are refused") {
+		t.Errorf("stats = %q", stats)
+	}
+}
+
+func TestOverBudgetActions(t *testing.T) {
+	for raw, want := range map[string]string{"": overBudgetLight, "Refuse": overBudgetRefuse} {
+		if got, err := parseOverBudget(raw); err != nil || got != want {
+			t.Errorf("parseOverBudget(%q) = %q, %v; want %q", raw, got, err, want)
+		}
+	}
+	if _, err := parseOverBudget("block"); err == nil {
+		t.Error("parseOverBudget accepted an unknown action")
+	}
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const calcInstruction = "Math mode: compute every number, statistic, and unit conversion with the code execution tool and report only values printed by the executed code. " +
+	"If a value cannot be computed with code, say so instead of estimating it."
+
+func (a *App) handleCalc(c tele.Context) error {
+	msg := c.Message()
+	payload := strings.TrimSpace(msg.Payload)
+	if payload == "" {
+		_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), tr(a.chatLanguage(c.Chat(), c.Sender()), "calc_usage"), &tele.SendOptions{DisableWebPagePreview: true})
+		return err
+	}
+	opts := turnOptions{
+		instruction: calcInstruction,
+		tools:       []*genai.Tool{{CodeExecution: &genai.ToolCodeExecution{}}},
+		requireCode: true,
+	}
+	return a.enqueueTurn(withText(msg, payload), opts)
+}
+
+// executedCodeSucceeded reports whether the response contains a successful code execution result.
+func executedCodeSucceeded(resp *genai.GenerateContentResponse) bool {
+	cand := firstCandidate(resp)
+	if cand == nil || cand.Content == nil {
+		return false
+	}
+	for _, part := range cand.Content.Parts {
+		if part != nil && part.CodeExecutionResult != nil && part.CodeExecutionResult.Outcome == genai.OutcomeOK {
+			return true
+		}
+	}
+	return false
+}
+
+// generateWithCode retries once with firmer guidance when the model answered
+// without executing code, returning ok=false if it still did not.
+func (a *App) generateWithCode(ctx context.Context, chatID int64, conversation []*genai.Content, cfg *genai.GenerateContentConfig) (*genai.GenerateContentResponse, string, bool, error) {
+	resp, model, err := a.generate(ctx, conversation, cfg)
+	if err != nil || executedCodeSucceeded(resp) {
+		return resp, model, err == nil, err
+	}
+	a.recordUsage(ctx, chatID, model, resp.UsageMetadata)
+
+	retryCfg := *cfg
+	retryCfg.SystemInstruction = appendInstruction(cfg.SystemInstruction, "Your previous attempt did not execute code. You must run code before giving any number.")
+	resp, model, err = a.generate(ctx, conversation, &retryCfg)
+	if err != nil {
+		return nil, "", false, err
+	}
+	return resp, model, executedCodeSucceeded(resp), nil
+}
@@ -0,0 +1,450 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	calendarPrefKey       = "calendar"
+	calendarDefaultWindow = 7 * 24 * time.Hour
+	calendarMaxEvents     = 25
+	icsUTCLayout          = "20060102T150405Z"
+	icsLocalLayout        = "20060102T150405"
+	icsDateLayout         = "20060102"
+)
+
+// errCalendarNotHTTPS rejects CalDAV URLs that would send the app password in
+// the clear.
+var errCalendarNotHTTPS = errors.New("calendar URL must use https")
+
+// calendarHTTPClient reaches CalDAV servers. Users choose the URL, so it only
+// connects to public addresses and never follows a redirect off https.
+var calendarHTTPClient = &http.Client{
+	Timeout:   20 * time.Second,
+	Transport: publicTransport(),
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if req.URL.Scheme != "https" {
+			return errCalendarNotHTTPS
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	},
+}
+
+// publicTransport only connects to public addresses, for requests to URLs
+// that come from users.
+func publicTransport() *http.Transport {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: func(_, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		ip := net.ParseIP(host)
+		if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+			return fmt.Errorf("refusing to fetch from %s", host)
+		}
+		return nil
+	}}
+	return &http.Transport{DialContext: dialer.DialContext, TLSHandshakeTimeout: 10 * time.Second}
+}
+
+// publicHTTPClient fetches links users post. Every connection, those of
+// redirects included, goes through publicTransport, so a link cannot reach
+// the bot's own network or a cloud metadata endpoint.
+func publicHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: publicTransport(),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("refusing to follow a redirect to %s", req.URL.Scheme)
+			}
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+}
+
+// calendarAccount is a user's CalDAV connection, kept in the encrypted prefs store.
+type calendarAccount struct {
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type calendarEvent struct {
+	Summary  string
+	Start    time.Time
+	End      time.Time
+	AllDay   bool
+	Location string
+}
+
+func (acct calendarAccount) do(ctx context.Context, method, target string, body []byte, headers map[string]string) (*http.Response, error) {
+	if u, err := url.Parse(target); err != nil || u.Scheme != "https" || u.Host == "" {
+		return nil, errCalendarNotHTTPS
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(acct.Username, acct.Password)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return calendarHTTPClient.Do(req)
+}
+
+func (acct calendarAccount) events(ctx context.Context, from, to time.Time) ([]calendarEvent, error) {
+	query := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<C:calendar-query xmlns:D="DAV:" xmlns:C="urn:ietf:params:xml:ns:caldav">
+  <D:prop><D:getetag/><C:calendar-data/></D:prop>
+  <C:filter><C:comp-filter name="VCALENDAR"><C:comp-filter name="VEVENT">
+    <C:time-range start="%s" end="%s"/>
+  </C:comp-filter></C:comp-filter></C:filter>
+</C:calendar-query>`, from.UTC().Format(icsUTCLayout), to.UTC().Format(icsUTCLayout))
+
+	resp, err := acct.do(ctx, "REPORT", acct.URL, []byte(query), map[string]string{
+		"Content-Type": "application/xml; charset=utf-8",
+		"Depth":        "1",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("calendar query: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusMultiStatus && resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("calendar query: unexpected status %s", resp.Status)
+	}
+
+	var multistatus struct {
+		Responses []struct {
+			CalendarData string `xml:"propstat>prop>calendar-data"`
+		} `xml:"response"`
+	}
+	if err := xml.NewDecoder(resp.Body).Decode(&multistatus); err != nil {
+		return nil, fmt.Errorf("decode calendar query: %w", err)
+	}
+
+	var events []calendarEvent
+	for _, r := range multistatus.Responses {
+		events = append(events, parseICSEvents(r.CalendarData)...)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Start.Before(events[j].Start) })
+	if len(events) > calendarMaxEvents {
+		events = events[:calendarMaxEvents]
+	}
+	return events, nil
+}
+
+func (acct calendarAccount) create(ctx context.Context, ev calendarEvent, description string) error {
+	uid := newEventUID()
+	var b strings.Builder
+	b.WriteString("BEGIN:VCALENDAR\r\nVERSION:2.0\r\nPRODID:-//eteonbot//calendar//EN\r\nBEGIN:VEVENT\r\n")
+	fmt.Fprintf(&b, "UID:%s\r\n", uid)
+	fmt.Fprintf(&b, "DTSTAMP:%s\r\n", time.Now().UTC().Format(icsUTCLayout))
+	fmt.Fprintf(&b, "DTSTART:%s\r\n", ev.Start.UTC().Format(icsUTCLayout))
+	fmt.Fprintf(&b, "DTEND:%s\r\n", ev.End.UTC().Format(icsUTCLayout))
+	fmt.Fprintf(&b, "SUMMARY:%s\r\n", escapeICSText(ev.Summary))
+	if ev.Location != "" {
+		fmt.Fprintf(&b, "LOCATION:%s\r\n", escapeICSText(ev.Location))
+	}
+	if description != "" {
+		fmt.Fprintf(&b, "DESCRIPTION:%s\r\n", escapeICSText(description))
+	}
+	b.WriteString("END:VEVENT\r\nEND:VCALENDAR\r\n")
+
+	target := strings.TrimRight(acct.URL, "/") + "/" + uid + ".ics"
+	resp, err := acct.do(ctx, http.MethodPut, target, []byte(b.String()), map[string]string{
+		"Content-Type":  "text/calendar; charset=utf-8",
+		"If-None-Match": "*",
+	})
+	if err != nil {
+		return fmt.Errorf("create event: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("create event: unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+func newEventUID() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return hex.EncodeToString(buf) + "@eteonbot"
+}
+
+var icsTextEscaper = strings.NewReplacer("\\", "\\\\", ";", "\\;", ",", "\\,", "\n", "\\n")
+
+func escapeICSText(s string) string {
+	return icsTextEscaper.Replace(s)
+}
+
+var icsTextUnescaper = strings.NewReplacer("\\n", "\n", "\\N", "\n", "\\,", ",", "\\;", ";", "\\\\", "\\")
+
+// parseICSEvents extracts VEVENT blocks from an iCalendar payload, unfolding continuation lines.
+func parseICSEvents(data string) []calendarEvent {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\n ", "")
+	data = strings.ReplaceAll(data, "\n\t", "")
+
+	var events []calendarEvent
+	var cur *calendarEvent
+	for _, line := range strings.Split(data, "\n") {
+		switch {
+		case line == "BEGIN:VEVENT":
+			cur = &calendarEvent{}
+		case line == "END:VEVENT":
+			if cur != nil && !cur.Start.IsZero() {
+				events = append(events, *cur)
+			}
+			cur = nil
+		case cur != nil:
+			name, params, value := splitICSLine(line)
+			switch name {
+			case "SUMMARY":
+				cur.Summary = icsTextUnescaper.Replace(value)
+			case "LOCATION":
+				cur.Location = icsTextUnescaper.Replace(value)
+			case "DTSTART":
+				cur.Start, cur.AllDay = parseICSTime(params, value)
+			case "DTEND":
+				cur.End, _ = parseICSTime(params, value)
+			}
+		}
+	}
+	return events
+}
+
+func splitICSLine(line string) (name string, params map[string]string, value string) {
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return "", nil, ""
+	}
+	head, value := line[:colon], line[colon+1:]
+	fields := strings.Split(head, ";")
+	params = make(map[string]string)
+	for _, f := range fields[1:] {
+		if k, v, ok := strings.Cut(f, "="); ok {
+			params[strings.ToUpper(k)] = v
+		}
+	}
+	return strings.ToUpper(fields[0]), params, value
+}
+
+func parseICSTime(params map[string]string, value string) (time.Time, bool) {
+	if params["VALUE"] == "DATE" || len(value) == len(icsDateLayout) {
+		t, err := time.Parse(icsDateLayout, value)
+		return t, err == nil
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, _ := time.Parse(icsUTCLayout, value)
+		return t, false
+	}
+	loc := time.UTC
+	if tz := params["TZID"]; tz != "" {
+		if l, err := time.LoadLocation(tz); err == nil {
+			loc = l
+		}
+	}
+	t, _ := time.ParseInLocation(icsLocalLayout, value, loc)
+	return t, false
+}
+
+func (a *App) calendarAccountFor(userID int64) (calendarAccount, error) {
+	var acct calendarAccount
+	if a.prefs == nil {
+		return acct, errors.New("calendar storage is not configured on this bot")
+	}
+	ok, err := a.prefs.get(userID, calendarPrefKey, &acct)
+	if err != nil {
+		return acct, err
+	}
+	if !ok {
+		return acct, errors.New("no calendar connected; ask the user to run /calendar connect")
+	}
+	return acct, nil
+}
+
+func (a *App) registerCalendarTools() {
+	a.functions.register(&genai.FunctionDeclaration{
+		Name:        "calendar_upcoming_events",
+		Description: "List events from the user's connected calendar. Also reports the current time, so call it first when you need today's date.",
+		Parameters: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"start": {Type: genai.TypeString, Description: "RFC 3339 start of the range; defaults to now."},
+				"end":   {Type: genai.TypeString, Description: "RFC 3339 end of the range; defaults to seven days after start."},
+			},
+		},
+	}, func(ctx context.Context, args map[string]any) (map[string]any, error) {
+		userID, _ := userIDFromContext(ctx)
+		acct, err := a.calendarAccountFor(userID)
+		if err != nil {
+			return nil, err
+		}
+		now := time.Now()
+		from, to := now, time.Time{}
+		if v := argString(args, "start"); v != "" {
+			if from, err = time.Parse(time.RFC3339, v); err != nil {
+				return nil, fmt.Errorf("start: %w", err)
+			}
+		}
+		if v := argString(args, "end"); v != "" {
+			if to, err = time.Parse(time.RFC3339, v); err != nil {
+				return nil, fmt.Errorf("end: %w", err)
+			}
+		} else {
+			to = from.Add(calendarDefaultWindow)
+		}
+
+		events, err := acct.events(ctx, from, to)
+		if err != nil {
+			return nil, err
+		}
+		list := make([]map[string]any, 0, len(events))
+		for _, ev := range events {
+			item := map[string]any{"summary": ev.Summary, "start": ev.Start.Format(time.RFC3339), "all_day": ev.AllDay}
+			if !ev.End.IsZero() {
+				item["end"] = ev.End.Format(time.RFC3339)
+			}
+			if ev.Location != "" {
+				item["location"] = ev.Location
+			}
+			list = append(list, item)
+		}
+		return map[string]any{"current_time": now.Format(time.RFC3339), "events": list}, nil
+	})
+
+	a.functions.register(&genai.FunctionDeclaration{
+		Name:        "calendar_create_event",
+		Description: "Create an event in the user's connected calendar.",
+		Parameters: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"title":            {Type: genai.TypeString, Description: "Event title."},
+				"start":            {Type: genai.TypeString, Description: "RFC 3339 start time including the offset."},
+				"duration_minutes": {Type: genai.TypeInteger, Description: "Length of the event; defaults to 30."},
+				"location":         {Type: genai.TypeString, Description: "Optional location."},
+				"description":      {Type: genai.TypeString, Description: "Optional notes."},
+			},
+			Required: []string{"title", "start"},
+		},
+	}, func(ctx context.Context, args map[string]any) (map[string]any, error) {
+		userID, _ := userIDFromContext(ctx)
+		acct, err := a.calendarAccountFor(userID)
+		if err != nil {
+			return nil, err
+		}
+		title := argString(args, "title")
+		if title == "" {
+			return nil, errors.New("missing title")
+		}
+		start, err := time.Parse(time.RFC3339, argString(args, "start"))
+		if err != nil {
+			return nil, fmt.Errorf("start: %w", err)
+		}
+		minutes := 30.0
+		if v, err := argNumber(args, "duration_minutes"); err == nil && v > 0 {
+			minutes = v
+		}
+		ev := calendarEvent{
+			Summary:  title,
+			Start:    start,
+			End:      start.Add(time.Duration(minutes * float64(time.Minute))),
+			Location: argString(args, "location"),
+		}
+		if err := acct.create(ctx, ev, argString(args, "description")); err != nil {
+			return nil, err
+		}
+		return map[string]any{"created": true, "start": ev.Start.Format(time.RFC3339), "end": ev.End.Format(time.RFC3339)}, nil
+	})
+}
+
+func (a *App) handleCalendar(c tele.Context) error {
+	msg := c.Message()
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	if a.prefs == nil {
+		_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), tr(lang, "calendar_off"), &tele.SendOptions{DisableWebPagePreview: true})
+		return err
+	}
+	if msg.Sender == nil {
+		return nil
+	}
+
+	args := strings.Fields(msg.Payload)
+	var body string
+	switch {
+	case (len(args) == 0 || args[0] == "status") && c.Chat().Type != tele.ChatPrivate:
+		// The status names the calendar server and account.
+		body = tr(lang, "calendar_private")
+	case len(args) == 0 || args[0] == "status":
+		if acct, err := a.calendarAccountFor(msg.Sender.ID); err == nil {
+			body = tr(lang, "calendar_status", acct.URL, acct.Username)
+		} else {
+			body = tr(lang, "calendar_none")
+		}
+	case args[0] == "connect":
+		if len(args) != 4 {
+			body = tr(lang, "calendar_conn_use")
+			break
+		}
+		// The message carries a password, so remove it from the chat right away.
+		if err := a.bot.Delete(msg); err != nil {
+			a.telegramFailed("delete credentials message", c.Chat().ID, err)
+		}
+		if c.Chat().Type != tele.ChatPrivate {
+			body = tr(lang, "calendar_unsafe")
+			break
+		}
+		acct := calendarAccount{URL: args[1], Username: args[2], Password: args[3]}
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+		_, err := acct.events(ctx, time.Now(), time.Now().Add(time.Hour))
+		cancel()
+		if errors.Is(err, errCalendarNotHTTPS) {
+			body = tr(lang, "calendar_https")
+			break
+		}
+		if err != nil {
+			a.chatLog(c.Chat().ID).Warn("calendar connect failed", "chat_id", c.Chat().ID, "err", err)
+			body = tr(lang, "calendar_failed")
+			break
+		}
+		if err := a.prefs.set(msg.Sender.ID, calendarPrefKey, acct); err != nil {
+			return err
+		}
+		body = tr(lang, "calendar_on")
+	case args[0] == "disconnect":
+		if err := a.prefs.delete(msg.Sender.ID, calendarPrefKey); err != nil {
+			return err
+		}
+		body = tr(lang, "calendar_removed")
+	default:
+		body = tr(lang, "calendar_usage")
+	}
+
+	_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), body, &tele.SendOptions{DisableWebPagePreview: true})
+	return err
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestCalendarRequiresHTTPS(t *testing.T) {
+	acct := calendarAccount{URL: "http://calendar.example.com/dav/", Username: "ana", Password: "secret"}
+	_, err := acct.events(context.Background(), time.Now(), time.Now().Add(time.Hour))
+	if !errors.Is(err, errCalendarNotHTTPS) {
+		t.Fatalf("events over http: err = %v, want errCalendarNotHTTPS", err)
+	}
+}
+
+func TestCalendarRefusesLocalServers(t *testing.T) {
+	reached := false
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	}))
+	defer server.Close()
+
+	acct := calendarAccount{URL: server.URL + "/dav/", Username: "ana", Password: "secret"}
+	_, err := acct.events(context.Background(), time.Now(), time.Now().Add(time.Hour))
+	if err == nil || !strings.Contains(err.Error(), "refusing") || reached {
+		t.Fatalf("events on %s: err = %v, reached = %v, want the connection refused", server.URL, err, reached)
+	}
+}
+
+func TestCalendarStatusOnlyInPrivateChats(t *testing.T) {
+	app, apis := newTestApp(t, Config{PrefsEncryptionKey: "test-key"})
+	const userID = 7
+	acct := calendarAccount{URL: "https://calendar.example.com/dav/", Username: "ana", Password: "secret"}
+	if err := app.prefs.set(userID, calendarPrefKey, acct); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+
+	status := func(chat *tele.Chat) {
+		msg := &tele.Message{ID: 5, Chat: chat, Sender: &tele.User{ID: userID}, Text: "/calendar status", Payload: "status"}
+		if err := app.handleCalendar(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("handleCalendar: %v", err)
+		}
+	}
+	status(&tele.Chat{ID: -100, Type: tele.ChatSuperGroup})
+	if texts := apis.sentTexts(); containsText(texts, "Connected to") || !containsText(texts, "private chat") {
+		t.Fatalf("group status sent %q, want a pointer to the private chat", texts)
+	}
+	status(&tele.Chat{ID: userID, Type: tele.ChatPrivate})
+	if texts := apis.sentTexts(); !containsText(texts, "Connected to https://calendar") {
+		t.Fatalf("private status sent %q, want the connection", texts)
+	}
+}
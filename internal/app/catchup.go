@@ -0,0 +1,261 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	// catchupWindow is how long a message stays in the buffer of a group.
+	catchupWindow = 24 * time.Hour
+	// catchupDefaultHours is the span /catchup summarises without an argument.
+	catchupDefaultHours = 8
+	catchupMaxMessages  = 1000
+	catchupMaxBytes     = 256 << 10
+	catchupMaxTokens    = 1024
+)
+
+// catchupInstruction shapes the summary of a group discussion for a member
+// who was away.
+const catchupInstruction = "You summarize a Telegram group discussion for a member who was offline. " +
+	"Group the summary by topic. For each, say what was discussed, what was decided and who is doing what, " +
+	"naming people as they appear in the transcript. End with the open questions, if any. " +
+	"Write compact bullet points in the language most of the discussion uses, without any preamble. " +
+	"Do not invent anything the transcript does not say."
+
+type catchupEntry struct {
+	at     time.Time
+	author string
+	text   string
+}
+
+// catchupBuffer is the rolling record of a group's messages. It lives only in
+// memory and forgets messages older than catchupWindow or beyond its limits.
+type catchupBuffer struct {
+	entries []catchupEntry
+	bytes   int
+}
+
+// prune drops the entries that fell out of the window or exceed the limits.
+func (b *catchupBuffer) prune(now time.Time) {
+	drop := 0
+	for drop < len(b.entries) {
+		e := b.entries[drop]
+		if now.Sub(e.at) <= catchupWindow && len(b.entries)-drop <= catchupMaxMessages && b.bytes <= catchupMaxBytes {
+			break
+		}
+		b.bytes -= len(e.author) + len(e.text)
+		drop++
+	}
+	if drop > 0 {
+		b.entries = append([]catchupEntry{}, b.entries[drop:]...)
+	}
+}
+
+// catchupBuffers holds the buffers of the groups that enabled /catchup; a
+// group without one records nothing.
+type catchupBuffers struct {
+	mu    sync.Mutex
+	chats map[int64]*catchupBuffer
+	now   func() time.Time
+}
+
+func newCatchupBuffers() *catchupBuffers {
+	return &catchupBuffers{chats: make(map[int64]*catchupBuffer), now: time.Now}
+}
+
+func (c *catchupBuffers) enabled(chatID int64) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.chats[chatID]
+	return ok
+}
+
+// enable starts recording a chat; disable stops and forgets what was recorded.
+func (c *catchupBuffers) enable(chatID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.chats[chatID]; !ok {
+		c.chats[chatID] = &catchupBuffer{}
+	}
+}
+
+func (c *catchupBuffers) disable(chatID int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.chats, chatID)
+}
+
+// migrate moves the buffer of chat from to chat to.
+func (c *catchupBuffers) migrate(from, to int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	moveKey(c.chats, from, to)
+}
+
+// record adds the text of msg to its chat's buffer when the chat enabled it.
+func (c *catchupBuffers) record(msg *tele.Message) {
+	text := messagePrompt(msg)
+	if text == "" || strings.HasPrefix(text, "/") {
+		return
+	}
+	author := "Someone"
+	if msg.Sender != nil {
+		if name := displayName(msg.Sender); name != "" {
+			author = name
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	buf, ok := c.chats[msg.Chat.ID]
+	if !ok {
+		return
+	}
+	at := msg.Time()
+	if msg.Unixtime == 0 {
+		at = c.now()
+	}
+	buf.entries = append(buf.entries, catchupEntry{at: at, author: author, text: text})
+	buf.bytes += len(author) + len(text)
+	buf.prune(c.now())
+}
+
+// since returns the transcript of the messages of the last span, or "".
+func (c *catchupBuffers) since(chatID int64, span time.Duration) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	buf, ok := c.chats[chatID]
+	if !ok {
+		return ""
+	}
+	now := c.now()
+	buf.prune(now)
+	var b strings.Builder
+	for _, e := range buf.entries {
+		if now.Sub(e.at) > span {
+			continue
+		}
+		fmt.Fprintf(&b, "[%s] %s: %s\n", e.at.UTC().Format("15:04"), e.author, e.text)
+	}
+	return b.String()
+}
+
+// addressedToBot reports whether a group message asks the bot for an answer:
+// a command, a mention or a reply to one of its messages.
+func (a *App) addressedToBot(msg *tele.Message) bool {
+	if !isGroupChat(msg.Chat) {
+		return true
+	}
+	text := messagePrompt(msg)
+	if strings.HasPrefix(text, "/") {
+		return true
+	}
+	me := a.bot.Me
+	if me == nil {
+		return true
+	}
+	if msg.ReplyTo != nil && msg.ReplyTo.Sender != nil && msg.ReplyTo.Sender.ID == me.ID {
+		return true
+	}
+	return me.Username != "" && strings.Contains(strings.ToLower(text), "@"+strings.ToLower(me.Username))
+}
+
+// handleCatchup turns the buffer of a group on or off, for its admins, or
+// summarises the last hours of the discussion.
+func (a *App) handleCatchup(c tele.Context) error {
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	reply := func(body string) error {
+		_, err := a.sendWithFallback(c.Chat(), body, &tele.SendOptions{ThreadID: messageTopic(c.Message()), DisableWebPagePreview: true})
+		return err
+	}
+	if !isGroupChat(c.Chat()) {
+		return reply(tr(lang, "catchup_groups"))
+	}
+
+	payload := strings.ToLower(strings.TrimSpace(c.Message().Payload))
+	switch payload {
+	case "on", "off":
+		if !a.isChatAdmin(c) {
+			return reply(tr(lang, "catchup_admins"))
+		}
+		if payload == "off" {
+			a.catchup.disable(c.Chat().ID)
+			return reply(tr(lang, "catchup_off"))
+		}
+		if a.privacy.enabled(c.Chat().ID) {
+			return reply(tr(lang, "catchup_private"))
+		}
+		a.catchup.enable(c.Chat().ID)
+		return reply(tr(lang, "catchup_on", int(catchupWindow.Hours()), catchupMaxMessages))
+	}
+
+	if !a.catchup.enabled(c.Chat().ID) {
+		return reply(tr(lang, "catchup_disabled"))
+	}
+	hours := catchupDefaultHours
+	if payload != "" {
+		n, err := strconv.Atoi(strings.TrimSuffix(payload, "h"))
+		if err != nil || n < 1 || n > int(catchupWindow.Hours()) {
+			return reply(tr(lang, "catchup_usage", int(catchupWindow.Hours())))
+		}
+		hours = n
+	}
+	log := a.catchup.since(c.Chat().ID, time.Duration(hours)*time.Hour)
+	if log == "" {
+		return reply(tr(lang, "catchup_empty", hours))
+	}
+
+	chat := c.Chat()
+	thread := messageTopic(c.Message())
+	return a.enqueueJob(c.Message(), func(ctx context.Context) error {
+		summary, err := a.summarizeCatchup(ctx, chat.ID, log)
+		if err != nil {
+			logFrom(ctx).Warn("catch-up summary failed", "err", err)
+			summary = tr(lang, "catchup_failed")
+		} else {
+			summary = tr(lang, "catchup_header", hours) + "\n\n" + summary
+		}
+		_, err = a.sendWithFallback(chat, summary, &tele.SendOptions{ThreadID: thread, DisableWebPagePreview: true})
+		return err
+	})
+}
+
+func (a *App) summarizeCatchup(ctx context.Context, chatID int64, log string) (string, error) {
+	contents := []*genai.Content{genai.NewContentFromText("Transcript:\n"+log, genai.RoleUser)}
+	cfg := &genai.GenerateContentConfig{
+		SystemInstruction: genai.NewContentFromText(catchupInstruction, genai.Role("system")),
+		MaxOutputTokens:   catchupMaxTokens,
+	}
+	resp, err := a.generateWithRetry(ctx, summaryModel, contents, cfg)
+	if err != nil {
+		return "", err
+	}
+	a.recordUsage(ctx, chatID, summaryModel, resp.UsageMetadata)
+	summary, _ := a.renderResponse(resp)
+	if summary == "" {
+		return "", fmt.Errorf("empty summary from %s", summaryModel)
+	}
+	return summary, nil
+}
+
+// recordForCatchup buffers a group message for /catchup and reports whether the
+// bot should leave it unanswered because it was not addressed to the bot.
+func (a *App) recordForCatchup(msg *tele.Message) (skip bool) {
+	if !isGroupChat(msg.Chat) || !a.catchup.enabled(msg.Chat.ID) {
+		return false
+	}
+	a.catchup.record(msg)
+	if a.addressedToBot(msg) {
+		return false
+	}
+	slog.Debug("message buffered for catch-up", "chat_id", msg.Chat.ID)
+	return true
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestCatchupSummarizesRecentGroupMessages(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.reply = "- The release moves to Friday."
+	group := func(text string, age time.Duration) *tele.Message {
+		msg := testMessage(-200, text)
+		msg.Chat.Type = tele.ChatSuperGroup
+		msg.Sender = &tele.User{ID: 7, FirstName: "Ana"}
+		msg.Unixtime = time.Now().Add(-age).Unix()
+		return msg
+	}
+	catchup := func(payload string, admin bool) {
+		t.Helper()
+		msg := group("/catchup "+payload, 0)
+		msg.Payload = payload
+		if admin {
+			msg.SenderChat = msg.Chat
+		}
+		if err := app.handleCatchup(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("handleCatchup(%q): %v", payload, err)
+		}
+	}
+	lastText := func() string {
+		texts := apis.sentTexts()
+		return texts[len(texts)-1]
+	}
+
+	catchup("", false)
+	if !strings.Contains(lastText(), "is off in this group") {
+		t.Fatalf("disabled catch-up answered %q", lastText())
+	}
+	catchup("on", false)
+	if !strings.Contains(lastText(), "Only group admins") {
+		t.Fatalf("non-admin enable answered %q", lastText())
+	}
+	catchup("on", true)
+
+	for _, msg := range []*tele.Message{
+		group("Old news from yesterday morning", 30*time.Hour),
+		group("Standup notes: API done", 5*time.Hour),
+		group("We move the release to Friday", time.Hour),
+	} {
+		if err := app.handleUserMessage(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("handleUserMessage: %v", err)
+		}
+	}
+	if calls := apis.callsTo(geminiHost, ":generateContent"); len(calls) != 0 {
+		t.Fatalf("unaddressed group messages reached Gemini %d times", len(calls))
+	}
+
+	catchup("2", false)
+	app.queue.drain(context.Background())
+	app.queue.resume()
+	calls := apis.callsTo(geminiHost, ":generateContent")
+	if len(calls) != 1 {
+		t.Fatalf("got %d summary requests, want 1", len(calls))
+	}
+	body := string(calls[0].body)
+	if !strings.Contains(body, "Ana: We move the release to Friday") || strings.Contains(body, "Standup") || strings.Contains(body, "Old news") {
+		t.Errorf("summary request does not hold exactly the last two hours: %s", body)
+	}
+	if !strings.Contains(lastText(), "release moves to Friday") {
+		t.Errorf("catch-up answered %q", lastText())
+	}
+
+	catchup("off", true)
+	if app.catchup.enabled(-200) || app.catchup.since(-200, catchupWindow) != "" {
+		t.Error("buffer kept after /catchup off")
+	}
+}
+
+func TestCatchupBufferLimits(t *testing.T) {
+	buffers := newCatchupBuffers()
+	buffers.enable(1)
+	for i := 0; i < catchupMaxMessages+10; i++ {
+		msg := testMessage(1, "message")
+		buffers.record(msg)
+	}
+	if n := len(buffers.chats[1].entries); n != catchupMaxMessages {
+		t.Errorf("buffer holds %d messages, want %d", n, catchupMaxMessages)
+	}
+
+	buffers.record(testMessage(1, strings.Repeat("x", catchupMaxBytes)))
+	if buf := buffers.chats[1]; buf.bytes > catchupMaxBytes {
+		t.Errorf("buffer holds %d bytes, limit %d", buf.bytes, catchupMaxBytes)
+	}
+
+	buffers.now = func() time.Time { return time.Now().Add(catchupWindow + time.Minute) }
+	if got := buffers.since(1, catchupWindow); got != "" {
+		t.Errorf("expired messages returned: %q", got)
+	}
+}
@@ -0,0 +1,164 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const toggleToolUnique = "toggle_tool"
+
+// chatTools are the built-in tools a chat can switch off in /settings, in
+// menu order.
+var chatTools = []string{googleSearchTool, urlContextTool, codeExecutionTool}
+
+// toolLabelKeys are the catalog keys of the chat tools' names.
+var toolLabelKeys = map[string]string{
+	googleSearchTool:  "tool_search",
+	urlContextTool:    "tool_links",
+	codeExecutionTool: "tool_code",
+}
+
+// withoutTools returns tools without the built-in tools named in off,
+// leaving tools itself untouched. An entry left with nothing is dropped.
+func withoutTools(tools []*genai.Tool, off []string) []*genai.Tool {
+	if len(off) == 0 {
+		return tools
+	}
+	var kept []*genai.Tool
+	for _, tool := range tools {
+		t := *tool
+		if slices.Contains(off, googleSearchTool) {
+			t.GoogleSearch = nil
+		}
+		if slices.Contains(off, urlContextTool) {
+			t.URLContext = nil
+		}
+		if slices.Contains(off, codeExecutionTool) {
+			t.CodeExecution = nil
+		}
+		if t.GoogleSearch == nil && t.URLContext == nil && t.CodeExecution == nil && len(t.FunctionDeclarations) == 0 {
+			continue
+		}
+		kept = append(kept, &t)
+	}
+	return kept
+}
+
+// availableTools are the chat tools the deployment has not disabled.
+func (a *App) availableTools() []string {
+	var names []string
+	for _, name := range chatTools {
+		for _, tool := range a.tools {
+			if (name == googleSearchTool && tool.GoogleSearch != nil) ||
+				(name == urlContextTool && tool.URLContext != nil) ||
+				(name == codeExecutionTool && tool.CodeExecution != nil) {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// toolRows are the /settings buttons switching each tool of the chat.
+func (a *App) toolRows(menu *tele.ReplyMarkup, lang language, off []string) []tele.Row {
+	var btns []tele.Btn
+	for _, name := range a.availableTools() {
+		btns = append(btns, menu.Data(toolLabel(lang, name, off), toggleToolUnique, name))
+	}
+	if len(btns) == 0 {
+		return nil
+	}
+	return []tele.Row{menu.Row(btns...)}
+}
+
+func toolLabel(lang language, name string, off []string) string {
+	state := tr(lang, "tool_on")
+	if slices.Contains(off, name) {
+		state = tr(lang, "tool_off")
+	}
+	return fmt.Sprintf("%s: %s", tr(lang, toolLabelKeys[name]), state)
+}
+
+// describeTools is the /settings line listing the state of each tool.
+func (a *App) describeTools(lang language, off []string) string {
+	var labels []string
+	for _, name := range a.availableTools() {
+		labels = append(labels, toolLabel(lang, name, off))
+	}
+	if len(labels) == 0 {
+		return ""
+	}
+	return tr(lang, "tools_current", strings.Join(labels, ", "))
+}
+
+// setChatTool switches the tool name of the session on or off; "tools"
+// switches all of them.
+func setChatTool(session *sessionState, name string, on bool) {
+	names := []string{name}
+	if name == "tools" {
+		names = chatTools
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	for _, n := range names {
+		session.toolsOff = slices.DeleteFunc(session.toolsOff, func(off string) bool { return off == n })
+		if !on {
+			session.toolsOff = append(session.toolsOff, n)
+		}
+	}
+	slices.Sort(session.toolsOff)
+	if len(session.toolsOff) == 0 {
+		session.toolsOff = nil
+	}
+}
+
+// isToolSetting reports whether a /settings control switches tools.
+func isToolSetting(control string) bool {
+	return control == "tools" || slices.Contains(chatTools, control)
+}
+
+// changeTool applies "/settings <tool> on|off" and reports the tools of the
+// chat.
+func (a *App) changeTool(c tele.Context, name, value string) error {
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	session := a.sessionOf(c.Message())
+	body := tr(lang, "tools_usage")
+	switch strings.ToLower(value) {
+	case "on", "off":
+		setChatTool(session, name, strings.EqualFold(value, "on"))
+		session.mu.Lock()
+		body = a.describeTools(lang, session.toolsOff)
+		session.mu.Unlock()
+	}
+	_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), body, &tele.SendOptions{DisableWebPagePreview: true})
+	return err
+}
+
+// handleToolToggle flips a tool from the /settings buttons and updates them.
+func (a *App) handleToolToggle(c tele.Context) error {
+	if err := c.Respond(); err != nil {
+		slog.Warn("callback acknowledge failed", "err", err)
+	}
+	name := c.Callback().Data
+	if !slices.Contains(chatTools, name) {
+		return nil
+	}
+	session := a.sessionOf(c.Message())
+	session.mu.Lock()
+	on := slices.Contains(session.toolsOff, name)
+	session.mu.Unlock()
+	setChatTool(session, name, on)
+
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	session.mu.Lock()
+	body := a.describeTools(lang, session.toolsOff)
+	session.mu.Unlock()
+	_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), body, &tele.SendOptions{DisableWebPagePreview: true})
+	return err
+}
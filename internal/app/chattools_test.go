@@ -0,0 +1,53 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestChatToolToggles(t *testing.T) {
+	app, apis := newTestApp(t, Config{DisabledTools: []string{codeExecutionTool}})
+	settings := func(payload string) {
+		t.Helper()
+		msg := testMessage(42, "/settings "+payload)
+		msg.Payload = payload
+		if err := app.handleSettings(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("handleSettings(%q): %v", payload, err)
+		}
+	}
+
+	settings("google_search off")
+	cfg := app.buildGenerateConfig(42, app.sessions.get(42).prefs())
+	if len(cfg.Tools) == 0 || cfg.Tools[0].GoogleSearch != nil || cfg.Tools[0].URLContext == nil {
+		t.Errorf("search is still offered with search off")
+	}
+	if app.tools[0].GoogleSearch == nil {
+		t.Fatal("switching a chat's tool changed the shared tools")
+	}
+	if texts := apis.sentTexts(); !strings.Contains(texts[len(texts)-1], "Web search: off, Links: on") || strings.Contains(texts[len(texts)-1], "Code") {
+		t.Errorf("settings answered %q", texts[len(texts)-1])
+	}
+
+	if err := app.handleToolToggle(actionCallback(app, 42, 7, urlContextTool)); err != nil {
+		t.Fatalf("handleToolToggle: %v", err)
+	}
+	if err := app.processMessage(context.Background(), testMessage(42, "Hi"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	calls := apis.callsTo(geminiHost, "gemini-2.5-pro:generateContent")
+	if body := string(calls[len(calls)-1].body); strings.Contains(body, "googleSearch") || strings.Contains(body, "urlContext") {
+		t.Errorf("pure model chat still offers web tools: %s", body)
+	}
+
+	settings("tools on")
+	if off := app.sessions.get(42).prefs().toolsOff; len(off) != 0 {
+		t.Errorf("tools on left %v off", off)
+	}
+	settings("tools maybe")
+	if texts := apis.sentTexts(); !strings.Contains(texts[len(texts)-1], "Usage") {
+		t.Errorf("bad tool setting answered %q, want the usage", texts[len(texts)-1])
+	}
+}
@@ -0,0 +1,134 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+// maxCheckpoints bounds the checkpoints of a chat; saving another drops the oldest.
+const maxCheckpoints = 10
+
+var checkpointName = regexp.MustCompile(`^[\pL\pN_.-]{1,32}$`)
+
+// checkpoint is a saved copy of a session's history.
+type checkpoint struct {
+	name          string
+	at            time.Time
+	summary       string
+	history       []*genai.Content
+	historyTokens []int
+	historyMeta   []historyMeta
+}
+
+// saveCheckpoint copies the history under name, or the next free "cpN" when
+// name is empty, replacing a checkpoint of the same name; the caller holds
+// session.mu.
+func (s *sessionState) saveCheckpoint(name string) checkpoint {
+	if name == "" {
+		for name == "" || s.checkpointIndex(name) >= 0 {
+			s.checkpointSeq++
+			name = "cp" + strconv.Itoa(s.checkpointSeq)
+		}
+	}
+	cp := checkpoint{
+		name:    name,
+		at:      time.Now(),
+		summary: s.summary,
+		// The history is truncated and appended to in place, so the checkpoint
+		// needs its own copy.
+		history:       slices.Clone(s.history),
+		historyTokens: slices.Clone(s.historyTokens),
+		historyMeta:   slices.Clone(s.historyMeta),
+	}
+	if i := s.checkpointIndex(name); i >= 0 {
+		s.checkpoints = slices.Delete(s.checkpoints, i, i+1)
+	}
+	s.checkpoints = append(s.checkpoints, cp)
+	if len(s.checkpoints) > maxCheckpoints {
+		s.checkpoints = slices.Delete(s.checkpoints, 0, len(s.checkpoints)-maxCheckpoints)
+	}
+	return cp
+}
+
+func (s *sessionState) checkpointIndex(name string) int {
+	return slices.IndexFunc(s.checkpoints, func(cp checkpoint) bool { return strings.EqualFold(cp.name, name) })
+}
+
+// rollback replaces the history with checkpoint cp; the caller holds session.mu.
+func (s *sessionState) rollback(cp checkpoint) {
+	s.summary = cp.summary
+	s.history = slices.Clone(cp.history)
+	s.historyTokens = slices.Clone(cp.historyTokens)
+	s.historyMeta = slices.Clone(cp.historyMeta)
+	s.lastTurn = lastTurn{}
+}
+
+// handleCheckpoint saves the conversation so far for /rollback.
+func (a *App) handleCheckpoint(c tele.Context) error {
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	reply := func(body string) error {
+		_, err := a.sendWithFallback(c.Chat(), body, &tele.SendOptions{ThreadID: messageTopic(c.Message()), DisableWebPagePreview: true})
+		return err
+	}
+	name := strings.TrimSpace(c.Message().Payload)
+	if name != "" && !checkpointName.MatchString(name) {
+		return reply(tr(lang, "checkpoint_usage"))
+	}
+
+	session := a.sessionOf(c.Message())
+	session.mu.Lock()
+	if len(session.history) == 0 && session.summary == "" {
+		session.mu.Unlock()
+		return reply(tr(lang, "checkpoint_empty"))
+	}
+	cp := session.saveCheckpoint(name)
+	session.mu.Unlock()
+	return reply(tr(lang, "checkpoint_saved", cp.name, len(cp.history), cp.name))
+}
+
+// handleRollback lists the checkpoints or returns the conversation to one. The
+// conversation it leaves is saved first, so a branch is never lost.
+func (a *App) handleRollback(c tele.Context) error {
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	reply := func(body string) error {
+		_, err := a.sendWithFallback(c.Chat(), body, &tele.SendOptions{ThreadID: messageTopic(c.Message()), DisableWebPagePreview: true})
+		return err
+	}
+	name := strings.TrimSpace(c.Message().Payload)
+
+	session := a.sessionOf(c.Message())
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if len(session.checkpoints) == 0 {
+		return reply(tr(lang, "rollback_none"))
+	}
+	if name == "" {
+		lines := make([]string, 0, len(session.checkpoints))
+		for i := len(session.checkpoints) - 1; i >= 0; i-- {
+			cp := session.checkpoints[i]
+			lines = append(lines, fmt.Sprintf("%s · %s · %d", cp.name, cp.at.UTC().Format("2006-01-02 15:04 UTC"), len(cp.history)))
+		}
+		return reply(tr(lang, "rollback_list", strings.Join(lines, "\n")))
+	}
+	i := session.checkpointIndex(name)
+	if i < 0 {
+		return reply(tr(lang, "rollback_unknown", name))
+	}
+	target := session.checkpoints[i]
+	left := ""
+	if len(session.history) > 0 || session.summary != "" {
+		left = session.saveCheckpoint("").name
+	}
+	session.rollback(target)
+	if left == "" {
+		return reply(tr(lang, "rollback_done", target.name, len(target.history)))
+	}
+	return reply(tr(lang, "rollback_done", target.name, len(target.history)) + " " + tr(lang, "rollback_saved", left))
+}
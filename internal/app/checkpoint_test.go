@@ -0,0 +1,75 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestCheckpointAndRollback(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	command := func(handler tele.HandlerFunc, text, payload string) string {
+		t.Helper()
+		msg := testMessage(42, text)
+		msg.Payload = payload
+		if err := handler(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("%s: %v", text, err)
+		}
+		texts := apis.sentTexts()
+		return texts[len(texts)-1]
+	}
+	ask := func(prompt, answer string) {
+		t.Helper()
+		apis.reply = answer
+		if err := app.processMessage(context.Background(), testMessage(42, prompt), turnOptions{}); err != nil {
+			t.Fatalf("processMessage: %v", err)
+		}
+	}
+	historyText := func() string {
+		session := app.sessions.get(42)
+		session.mu.Lock()
+		defer session.mu.Unlock()
+		var texts []string
+		for _, content := range session.history {
+			texts = append(texts, contentText(content))
+		}
+		return strings.Join(texts, " | ")
+	}
+
+	if got := command(app.handleCheckpoint, "/checkpoint", ""); !strings.Contains(got, "no conversation") {
+		t.Errorf("empty checkpoint answered %q", got)
+	}
+	ask("Plan a trip to Rome.", "Day one: the Colosseum.")
+	if got := command(app.handleCheckpoint, "/checkpoint plan", "plan"); !strings.Contains(got, "plan saved with 2 messages") {
+		t.Fatalf("checkpoint answered %q", got)
+	}
+	if got := command(app.handleCheckpoint, "/checkpoint two words", "two words"); !strings.Contains(got, "Usage") {
+		t.Errorf("bad name answered %q", got)
+	}
+	ask("Make it a food tour instead.", "Day one: Trastevere trattorias.")
+
+	if got := command(app.handleRollback, "/rollback missing", "missing"); !strings.Contains(got, "no checkpoint missing") {
+		t.Errorf("unknown checkpoint answered %q", got)
+	}
+	if got := command(app.handleRollback, "/rollback plan", "plan"); !strings.Contains(got, "saved as cp1") {
+		t.Fatalf("rollback answered %q", got)
+	}
+	if got := historyText(); strings.Contains(got, "food tour") || !strings.Contains(got, "Colosseum") {
+		t.Fatalf("history after rollback: %s", got)
+	}
+
+	ask("Add a museum day.", "Day two: the Vatican Museums.")
+	if got := command(app.handleRollback, "/rollback", ""); !strings.Contains(got, "cp1") || !strings.Contains(got, "plan") {
+		t.Errorf("list answered %q", got)
+	}
+	command(app.handleRollback, "/rollback cp1", "cp1")
+	if got := historyText(); !strings.Contains(got, "food tour") || strings.Contains(got, "Vatican") {
+		t.Fatalf("history after returning to the branch: %s", got)
+	}
+	command(app.handleRollback, "/rollback cp2", "cp2")
+	if got := historyText(); !strings.Contains(got, "Vatican") || strings.Contains(got, "food tour") {
+		t.Errorf("history after returning to the second branch: %s", got)
+	}
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// codeFileThreshold is the size above which code or its output is sent as a
+// file instead of a code block, which Telegram would split or cut short.
+const codeFileThreshold = 3000
+
+// codeFileExtensions name the file of a snippet by its language.
+var codeFileExtensions = map[string]string{
+	"python":     ".py",
+	"go":         ".go",
+	"javascript": ".js",
+	"typescript": ".ts",
+	"bash":       ".sh",
+	"shell":      ".sh",
+	"sql":        ".sql",
+	"java":       ".java",
+	"c":          ".c",
+	"cpp":        ".cpp",
+	"rust":       ".rs",
+	"ruby":       ".rb",
+}
+
+// large reports whether the code or the output of the snippet is sent as a
+// file.
+func (s codeSnippet) large() bool {
+	return len(s.Code) > codeFileThreshold || len(s.Output) > codeFileThreshold
+}
+
+// codeFileName is the name of the file holding snippet index: main.py for
+// the first Python snippet, main_2.py for the second.
+func codeFileName(language string, index int) string {
+	ext, ok := codeFileExtensions[strings.ToLower(strings.TrimSpace(language))]
+	if !ok {
+		ext = ".txt"
+	}
+	if index > 1 {
+		return fmt.Sprintf("main_%d%s", index, ext)
+	}
+	return "main" + ext
+}
+
+// outputFileName is the name of the file holding the output of snippet
+// index.
+func outputFileName(index int) string {
+	if index > 1 {
+		return fmt.Sprintf("output_%d.txt", index)
+	}
+	return "output.txt"
+}
+
+// sendCodeFiles sends a large snippet as its source file and, when it
+// printed anything, its output as a text file.
+func (a *App) sendCodeFiles(to tele.Recipient, lang language, index int, snippet codeSnippet) error {
+	caption := tr(lang, "code_snippet", index)
+	if err := a.sendTextDocument(to, codeFileName(snippet.Language, index), "text/plain", snippet.Code, caption); err != nil {
+		return err
+	}
+	if strings.TrimSpace(snippet.Output) == "" {
+		return nil
+	}
+	if snippet.Outcome != "" {
+		caption = tr(lang, "code_file_outcome", index, snippet.Outcome)
+	}
+	return a.sendTextDocument(to, outputFileName(index), "text/plain", snippet.Output, caption)
+}
+
+// resultText is the code execution output as shown in a reply. Output too
+// long for a message is left to the code button, which sends it as a file.
+func resultText(output string) string {
+	if len(output) <= codeFileThreshold {
+		return "Result:\n" + output
+	}
+	return fmt.Sprintf("Result: %d lines, sent as a file by the code button.", strings.Count(output, "\n")+1)
+}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestLargeCodeSentAsFiles(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	output := strings.Repeat("row\n", 1500)
+	resp := &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{Content: &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{
+		{ExecutableCode: &genai.ExecutableCode{Language: genai.LanguagePython, Code: "for _ in range(1500):\n    print('row')"}},
+		{CodeExecutionResult: &genai.CodeExecutionResult{Outcome: genai.OutcomeOK, Output: output}},
+		{ExecutableCode: &genai.ExecutableCode{Language: genai.LanguagePython, Code: "print(2 + 2)"}},
+		{CodeExecutionResult: &genai.CodeExecutionResult{Outcome: genai.OutcomeOK, Output: "4\n"}},
+		{Text: "Printed the rows."},
+	}}}}}
+
+	reply, art := app.renderResponse(resp)
+	if strings.Contains(reply, "row\nrow") || !strings.Contains(reply, "Result: 1500 lines") || !strings.Contains(reply, "Result:\n4") {
+		t.Errorf("reply = %q", reply)
+	}
+	if len(art.CodeSnippets) != 2 || art.CodeSnippets[0].Output != output {
+		t.Fatalf("snippets do not keep the full output: %+v", art.CodeSnippets)
+	}
+
+	art.ChatID = 42
+	id := app.artifacts.put(art)
+	if err := app.handleShowCode(actionCallback(app, 42, 7, id)); err != nil {
+		t.Fatalf("handleShowCode: %v", err)
+	}
+	docs := apis.callsTo(telegramHost, "sendDocument")
+	if len(docs) != 2 {
+		t.Fatalf("sent %d documents, want the code and its output", len(docs))
+	}
+	if body := string(docs[0].body); !strings.Contains(body, `filename="main.py"`) {
+		t.Errorf("code document: %.300s", body)
+	}
+	if body := string(docs[1].body); !strings.Contains(body, `filename="output.txt"`) || strings.Count(body, "row") != 1500 {
+		t.Errorf("output document misses the full output")
+	}
+	if texts := apis.sentTexts(); len(texts) != 1 || !strings.Contains(texts[0], "Code snippet 2") || strings.Contains(texts[0], "Code snippet 1") {
+		t.Errorf("inline snippets = %q", texts)
+	}
+}
+
+func TestCodeFileName(t *testing.T) {
+	for _, tc := range []struct {
+		language string
+		index    int
+		want     string
+	}{
+		{"PYTHON", 1, "main.py"},
+		{"PYTHON", 3, "main_3.py"},
+		{"LANGUAGE_UNSPECIFIED", 1, "main.txt"},
+	} {
+		if got := codeFileName(tc.language, tc.index); got != tc.want {
+			t.Errorf("codeFileName(%q, %d) = %q, want %q", tc.language, tc.index, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// codeFormatTimeout bounds each external formatter run.
+const codeFormatTimeout = 5 * time.Second
+
+// codeFormatter rewrites source in the canonical style of its language.
+type codeFormatter func(ctx context.Context, src string) (string, error)
+
+// codeFormatters maps fence languages to formatters. Go and JSON are formatted
+// in-process; the others run a formatter found on PATH and are skipped when it
+// is not installed.
+var codeFormatters = map[string]codeFormatter{
+	"go":         formatGo,
+	"golang":     formatGo,
+	"json":       formatJSON,
+	"python":     externalFormatter("black", "--quiet", "-"),
+	"py":         externalFormatter("black", "--quiet", "-"),
+	"rust":       externalFormatter("rustfmt", "--emit", "stdout", "--edition", "2021"),
+	"rs":         externalFormatter("rustfmt", "--emit", "stdout", "--edition", "2021"),
+	"javascript": externalFormatter("prettier", "--stdin-filepath", "snippet.js"),
+	"js":         externalFormatter("prettier", "--stdin-filepath", "snippet.js"),
+	"typescript": externalFormatter("prettier", "--stdin-filepath", "snippet.ts"),
+	"ts":         externalFormatter("prettier", "--stdin-filepath", "snippet.ts"),
+	"css":        externalFormatter("prettier", "--stdin-filepath", "snippet.css"),
+}
+
+// formatGo runs gofmt; format.Source also accepts declaration and statement
+// lists, which covers most snippets.
+func formatGo(_ context.Context, src string) (string, error) {
+	out, err := format.Source([]byte(src))
+	return string(out), err
+}
+
+func formatJSON(_ context.Context, src string) (string, error) {
+	var out bytes.Buffer
+	if err := json.Indent(&out, []byte(strings.TrimSpace(src)), "", "  "); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// externalFormatter pipes the code through name with args, reading the
+// formatted code from stdout.
+func externalFormatter(name string, args ...string) codeFormatter {
+	var once sync.Once
+	var path string
+	return func(ctx context.Context, src string) (string, error) {
+		once.Do(func() { path, _ = exec.LookPath(name) })
+		if path == "" {
+			return "", fmt.Errorf("%s is not installed", name)
+		}
+		ctx, cancel := context.WithTimeout(ctx, codeFormatTimeout)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, path, args...)
+		cmd.Stdin = strings.NewReader(src)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("%s: %v: %s", name, err, strings.TrimSpace(stderr.String()))
+		}
+		return stdout.String(), nil
+	}
+}
+
+// formatCode formats src as lang. Code that does not parse, or a language
+// without a formatter, is returned unchanged.
+func formatCode(ctx context.Context, lang, src string) string {
+	formatter, ok := codeFormatters[strings.ToLower(lang)]
+	if !ok || strings.TrimSpace(src) == "" {
+		return src
+	}
+	out, err := formatter(ctx, src)
+	if err != nil {
+		logFrom(ctx).Debug("code left unformatted", "language", lang, "err", err)
+		return src
+	}
+	return strings.TrimRight(out, "\n")
+}
+
+// formatCodeBlocks formats the fenced code blocks of a Markdown reply so that
+// copied code is ready to use. Unclosed fences are left alone.
+func formatCodeBlocks(ctx context.Context, reply string) string {
+	lines := strings.Split(reply, "\n")
+	out := make([]string, 0, len(lines))
+	for i := 0; i < len(lines); i++ {
+		fence, lang, ok := openingFence(strings.TrimSpace(lines[i]))
+		if !ok {
+			out = append(out, lines[i])
+			continue
+		}
+		end := i + 1
+		for end < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[end]), fence) {
+			end++
+		}
+		if end == len(lines) {
+			out = append(out, lines[i:]...)
+			break
+		}
+		code := strings.Join(lines[i+1:end], "\n")
+		out = append(out, lines[i], formatCode(ctx, lang, code), lines[end])
+		i = end
+	}
+	return strings.Join(out, "\n")
+}
@@ -0,0 +1,45 @@
+package app
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFormatCodeBlocks(t *testing.T) {
+	tests := []struct {
+		name  string
+		reply string
+		want  string
+	}{
+		{
+			"go",
+			"Try this:\n```go\nfunc add(a,b int) int {\nreturn a+b\n}\n```\nDone.",
+			"Try this:\n```go\nfunc add(a, b int) int {\n\treturn a + b\n}\n```\nDone.",
+		},
+		{
+			"json",
+			"```json\n{\"a\":1,\"b\":[true,null]}\n```",
+			"```json\n{\n  \"a\": 1,\n  \"b\": [\n    true,\n    null\n  ]\n}\n```",
+		},
+		{
+			"invalid go kept",
+			"```go\nfunc (\n```",
+			"```go\nfunc (\n```",
+		},
+		{
+			"unknown language kept",
+			"```haskell\nmain=print  1\n```",
+			"```haskell\nmain=print  1\n```",
+		},
+		{
+			"unclosed fence kept",
+			"```go\nx:=1",
+			"```go\nx:=1",
+		},
+	}
+	for _, tt := range tests {
+		if got := formatCodeBlocks(context.Background(), tt.reply); got != tt.want {
+			t.Errorf("%s: formatCodeBlocks =\n%s\nwant\n%s", tt.name, got, tt.want)
+		}
+	}
+}
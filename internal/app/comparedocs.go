@@ -0,0 +1,289 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	// compareDocsCommand is also recognised as the caption of a document,
+	// since Telegram albums carry no command text of their own.
+	compareDocsCommand = "/compare_docs"
+	// compareDocsWait is how long /compare_docs waits for its documents.
+	compareDocsWait = 10 * time.Minute
+	// compareDocsInlineLimit is the longest comparison sent as a message; a
+	// longer one arrives as a Markdown document.
+	compareDocsInlineLimit = 3500
+)
+
+const compareDocsInstruction = "Compare the two documents the user sent, labelled A and B. " +
+	"List the substantive differences aspect by aspect with what each document says, the points they share, " +
+	"and judge which one is newer and which is more complete, citing the evidence such as dates, versions or missing sections. " +
+	"Answer \"unknown\" when the documents do not tell. Keep each cell short. Write all texts in %s."
+
+// docComparison is the structured answer of the model.
+type docComparison struct {
+	Summary     string `json:"summary"`
+	Differences []struct {
+		Aspect string `json:"aspect"`
+		A      string `json:"a"`
+		B      string `json:"b"`
+	} `json:"differences"`
+	Common      []string `json:"common"`
+	Newer       string   `json:"newer"`
+	NewerWhy    string   `json:"newer_reason"`
+	Complete    string   `json:"more_complete"`
+	CompleteWhy string   `json:"more_complete_reason"`
+}
+
+func docComparisonSchema() *genai.Schema {
+	text := &genai.Schema{Type: genai.TypeString}
+	verdict := &genai.Schema{Type: genai.TypeString, Enum: []string{"a", "b", "same", "unknown"}}
+	return &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"summary": text,
+			"differences": {Type: genai.TypeArray, Items: &genai.Schema{
+				Type:       genai.TypeObject,
+				Properties: map[string]*genai.Schema{"aspect": text, "a": text, "b": text},
+				Required:   []string{"aspect", "a", "b"},
+			}},
+			"common":               {Type: genai.TypeArray, Items: text},
+			"newer":                verdict,
+			"newer_reason":         text,
+			"more_complete":        verdict,
+			"more_complete_reason": text,
+		},
+		Required:         []string{"summary", "differences", "common", "newer", "more_complete"},
+		PropertyOrdering: []string{"summary", "differences", "common", "newer", "newer_reason", "more_complete", "more_complete_reason"},
+	}
+}
+
+// markdown renders the comparison with a table of the differences.
+func (d docComparison) markdown(lang language, nameA, nameB string) string {
+	cell := func(s string) string {
+		return strings.ReplaceAll(strings.Join(strings.Fields(s), " "), "|", "/")
+	}
+	verdict := func(v, why string) string {
+		var who string
+		switch strings.ToLower(v) {
+		case "a":
+			who = "A · " + nameA
+		case "b":
+			who = "B · " + nameB
+		case "same":
+			who = tr(lang, "comparedocs_same")
+		default:
+			who = tr(lang, "comparedocs_unsure")
+		}
+		if why = strings.TrimSpace(why); why != "" {
+			who += " - " + why
+		}
+		return who
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s*\n\n", tr(lang, "comparedocs_title", nameA, nameB))
+	if s := strings.TrimSpace(d.Summary); s != "" {
+		b.WriteString(s + "\n\n")
+	}
+	if len(d.Differences) > 0 {
+		fmt.Fprintf(&b, "| %s | A | B |\n|---|---|---|\n", tr(lang, "comparedocs_aspect"))
+		for _, diff := range d.Differences {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", cell(diff.Aspect), cell(diff.A), cell(diff.B))
+		}
+		b.WriteString("\n")
+	}
+	if len(d.Common) > 0 {
+		fmt.Fprintf(&b, "*%s*\n", tr(lang, "comparedocs_common"))
+		for _, point := range d.Common {
+			b.WriteString("- " + point + "\n")
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "%s\n%s", tr(lang, "comparedocs_newer", verdict(d.Newer, d.NewerWhy)), tr(lang, "comparedocs_done", verdict(d.Complete, d.CompleteWhy)))
+	return b.String()
+}
+
+// docCompareKey identifies the user a /compare_docs waits for documents from.
+type docCompareKey struct {
+	chatID, userID int64
+}
+
+// pendingComparison collects the documents of one /compare_docs. album is
+// the media group the command came with; only its documents count then. focus
+// is what the user asked to look at, the text after the command.
+type pendingComparison struct {
+	album   string
+	focus   string
+	docs    []*tele.Message
+	expires time.Time
+}
+
+// docComparisons holds the /compare_docs still waiting for documents.
+type docComparisons struct {
+	mu      sync.Mutex
+	pending map[docCompareKey]*pendingComparison
+}
+
+func newDocComparisons() *docComparisons {
+	return &docComparisons{pending: make(map[docCompareKey]*pendingComparison)}
+}
+
+// start begins a comparison for key with the documents given so far.
+func (d *docComparisons) start(key docCompareKey, album, focus string, docs ...*tele.Message) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pending[key] = &pendingComparison{album: album, focus: focus, docs: docs, expires: time.Now().Add(compareDocsWait)}
+}
+
+// offer adds msg to the comparison waiting for its sender. It reports whether
+// msg was taken and, once two documents are in, returns the comparison.
+func (d *docComparisons) offer(msg *tele.Message) (bool, *pendingComparison) {
+	if msg.Document == nil {
+		return false, nil
+	}
+	key := docCompareKey{chatID: msg.Chat.ID, userID: userIDOf(msg.Sender)}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	p, ok := d.pending[key]
+	switch {
+	case !ok:
+		return false, nil
+	case time.Now().After(p.expires):
+		delete(d.pending, key)
+		return false, nil
+	case p.album != "" && msg.AlbumID != p.album:
+		return false, nil
+	}
+	p.docs = append(p.docs, msg)
+	if len(p.docs) < 2 {
+		return true, nil
+	}
+	delete(d.pending, key)
+	return true, p
+}
+
+// handleCompareDocs compares two documents:
+//
+//	an album of two documents captioned /compare_docs
+//	/compare_docs, then two documents
+//	/compare_docs as a reply to a document, then the second one
+func (a *App) handleCompareDocs(c tele.Context) error {
+	msg := c.Message()
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	key := docCompareKey{chatID: msg.Chat.ID, userID: userIDOf(msg.Sender)}
+	reply := func(body string) error {
+		_, err := a.sendWithFallback(c.Chat(), body, &tele.SendOptions{ThreadID: messageTopic(msg), DisableWebPagePreview: true})
+		return err
+	}
+
+	focus := msg.Payload
+	if msg.Document != nil {
+		_, focus = captionCommand(msg)
+	}
+
+	switch {
+	case msg.Document != nil:
+		a.comparisons.start(key, msg.AlbumID, focus, msg)
+		if msg.AlbumID != "" {
+			// The other document of the album follows as its own update.
+			return nil
+		}
+		return reply(tr(lang, "comparedocs_next", msg.Document.FileName))
+	case msg.ReplyTo != nil && msg.ReplyTo.Document != nil:
+		a.comparisons.start(key, "", focus, msg.ReplyTo)
+		return reply(tr(lang, "comparedocs_next", msg.ReplyTo.Document.FileName))
+	}
+	a.comparisons.start(key, "", focus)
+	return reply(tr(lang, "comparedocs_usage"))
+}
+
+// takeComparisonDocument routes a document a /compare_docs waits for to the
+// comparison and reports whether it did.
+func (a *App) takeComparisonDocument(msg *tele.Message) bool {
+	taken, pending := a.comparisons.offer(msg)
+	if !taken {
+		return false
+	}
+	if pending == nil {
+		lang := a.chatLanguage(msg.Chat, msg.Sender)
+		if msg.AlbumID == "" {
+			if _, err := a.sendWithFallback(msg.Chat, tr(lang, "comparedocs_next", msg.Document.FileName), &tele.SendOptions{ThreadID: messageTopic(msg), DisableWebPagePreview: true}); err != nil {
+				slog.Warn("notify failed", "err", err)
+			}
+		}
+		return true
+	}
+	if err := a.enqueueJob(msg, func(ctx context.Context) error {
+		return a.compareDocuments(ctx, pending.docs[0], pending.docs[1], strings.TrimSpace(pending.focus))
+	}); err != nil {
+		slog.Warn("enqueue comparison failed", "err", err)
+	}
+	return true
+}
+
+// compareDocuments asks the model for a structured comparison of the
+// documents of first and second and sends it as a table, or as a Markdown
+// file when it is too long for a message.
+func (a *App) compareDocuments(ctx context.Context, first, second *tele.Message, focus string) error {
+	chat := first.Chat
+	lang := a.chatLanguage(chat, first.Sender)
+	opts := &tele.SendOptions{ThreadID: messageTopic(first), DisableWebPagePreview: true}
+	fail := func(key string) error {
+		_, err := a.sendWithFallback(chat, tr(lang, key), opts)
+		return err
+	}
+
+	parts := []*genai.Part{}
+	for i, msg := range []*tele.Message{first, second} {
+		docParts, err := a.documentParts(ctx, msg.Document)
+		if err != nil {
+			logFrom(ctx).Warn("read document failed", "file", msg.Document.FileName, "err", err)
+			if errors.Is(err, errFileTooLarge) {
+				return fail("file_too_large")
+			}
+			return fail("input_failed")
+		}
+		parts = append(parts, genai.NewPartFromText(fmt.Sprintf("Document %c: %s", 'A'+i, msg.Document.FileName)))
+		parts = append(parts, docParts...)
+	}
+	if focus != "" {
+		parts = append(parts, genai.NewPartFromText("Focus on: "+focus))
+	}
+
+	cfg := &genai.GenerateContentConfig{
+		SystemInstruction: genai.NewContentFromText(fmt.Sprintf(compareDocsInstruction, languageNames[lang]), genai.Role("system")),
+		ResponseMIMEType:  "application/json",
+		ResponseSchema:    docComparisonSchema(),
+	}
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+	resp, model, err := a.generate(ctx, []*genai.Content{genai.NewContentFromParts(parts, genai.RoleUser)}, cfg)
+	if err != nil {
+		logFrom(ctx).Warn("compare documents failed", "err", err)
+		return fail("comparedocs_failed")
+	}
+	a.recordUsage(ctx, chat.ID, model, resp.UsageMetadata)
+	var result docComparison
+	if err := json.Unmarshal([]byte(resp.Text()), &result); err != nil {
+		logFrom(ctx).Warn("decode comparison failed", "err", err)
+		return fail("comparedocs_failed")
+	}
+
+	body := result.markdown(lang, first.Document.FileName, second.Document.FileName)
+	if len(body) > compareDocsInlineLimit {
+		return a.sendTextDocument(chat, "comparison.md", "text/markdown", body, tr(lang, "comparedocs_title", first.Document.FileName, second.Document.FileName), opts)
+	}
+	_, err = a.sendWithFallback(chat, body, opts)
+	return err
+}
@@ -0,0 +1,76 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestCompareDocsAlbum(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.files = map[string]string{
+		"v1": "Release plan v1, 2024-03-01: ship in May.",
+		"v2": "Release plan v2, 2024-05-10: ship in June, with a rollback section.",
+	}
+	apis.reply = `{"summary":"v2 moves the release.","differences":[{"aspect":"Ship date","a":"May","b":"June"}],` +
+		`"common":["Same scope"],"newer":"b","newer_reason":"dated 2024-05-10","more_complete":"b","more_complete_reason":"adds a rollback section"}`
+
+	first := testMessage(42, "")
+	first.AlbumID = "album"
+	first.Caption = "/compare_docs dates"
+	first.Document = &tele.Document{File: tele.File{FileID: "v1", UniqueID: "v1"}, FileName: "plan-v1.txt", MIME: "text/plain"}
+	second := testMessage(42, "")
+	second.AlbumID = "album"
+	second.Document = &tele.Document{File: tele.File{FileID: "v2", UniqueID: "v2"}, FileName: "plan-v2.txt", MIME: "text/plain"}
+	for _, msg := range []*tele.Message{first, second} {
+		if err := app.handleUserMessage(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("handleUserMessage: %v", err)
+		}
+	}
+	app.queue.drain(context.Background())
+
+	calls := apis.callsTo(geminiHost, ":generateContent")
+	if len(calls) != 1 {
+		t.Fatalf("generateContent called %d times, want 1", len(calls))
+	}
+	body := string(calls[0].body)
+	for _, want := range []string{"Document A: plan-v1.txt", "Document B: plan-v2.txt", "Focus on: dates", "application/json"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("request misses %q", want)
+		}
+	}
+	texts := apis.sentTexts()
+	if len(texts) == 0 {
+		t.Fatal("no comparison sent")
+	}
+	reply := texts[len(texts)-1]
+	for _, want := range []string{"Ship date", "June", "Same scope", "B · plan", "rollback section"} {
+		if !strings.Contains(reply, want) {
+			t.Errorf("comparison %q misses %q", reply, want)
+		}
+	}
+}
+
+func TestCompareDocsWaitsForDocuments(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	cmd := testMessage(42, "/compare_docs")
+	if err := app.handleCompareDocs(app.bot.NewContext(tele.Update{Message: cmd})); err != nil {
+		t.Fatalf("handleCompareDocs: %v", err)
+	}
+	doc := testMessage(42, "")
+	doc.Document = &tele.Document{File: tele.File{FileID: "a"}, FileName: "a.pdf"}
+	if !app.takeComparisonDocument(doc) {
+		t.Fatal("first document not taken")
+	}
+	if texts := apis.sentTexts(); !strings.Contains(texts[len(texts)-1], "Got a") {
+		t.Errorf("sent %q, want a prompt for the second document", texts[len(texts)-1])
+	}
+
+	other := testMessage(43, "")
+	other.Document = &tele.Document{File: tele.File{FileID: "b"}, FileName: "b.pdf"}
+	if app.takeComparisonDocument(other) {
+		t.Error("took a document from another chat")
+	}
+}
@@ -0,0 +1,242 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+const (
+	// contextCacheMinChars is the text a message needs, about 10k tokens,
+	// before the history up to it is cached. Smaller prefixes cost more to
+	// cache than they save, and Gemini refuses the smallest.
+	contextCacheMinChars = 40000
+	contextCacheTTL      = 30 * time.Minute
+	// contextCacheRenewal is how close to its expiry a cache in use gets
+	// another contextCacheTTL.
+	contextCacheRenewal = 10 * time.Minute
+	contextCacheTimeout = time.Minute
+)
+
+type contextCachingKey struct{}
+
+// withContextCaching lets the requests made with ctx cache a large document
+// in the chat history. Only the chat's own turns set it, so requests that
+// send the same history with other instructions do not replace the cache.
+func withContextCaching(ctx context.Context) context.Context {
+	return context.WithValue(ctx, contextCachingKey{}, true)
+}
+
+func contextCachingFrom(ctx context.Context) bool {
+	on, _ := ctx.Value(contextCachingKey{}).(bool)
+	return on
+}
+
+// contextCaches remembers the Gemini context cache of each chat: the history
+// up to its latest large document, with the instruction and tools the cache
+// was created with.
+type contextCaches struct {
+	mu     sync.Mutex
+	byChat map[int64]contextCache
+}
+
+type contextCache struct {
+	// key hashes the model, the cached contents, the instruction and the tools.
+	key string
+	// name is the cache to reference, empty when Gemini refused to create it.
+	name    string
+	expires time.Time
+}
+
+func newContextCaches() *contextCaches {
+	return &contextCaches{byChat: make(map[int64]contextCache)}
+}
+
+func (c *contextCaches) get(chatID int64) (contextCache, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.byChat[chatID]
+	return entry, ok
+}
+
+func (c *contextCaches) put(chatID int64, entry contextCache) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.byChat[chatID] = entry
+}
+
+// forget drops the cache called name, so the next turn creates a new one.
+func (c *contextCaches) forget(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for chatID, entry := range c.byChat {
+		if entry.name == name {
+			delete(c.byChat, chatID)
+		}
+	}
+}
+
+// generateCached calls model like generateWithRetry, taking the history up to a
+// large document from the chat's context cache. A request the cache fails,
+// such as one for a cache that expired early, goes out again in full.
+func (a *App) generateCached(ctx context.Context, model string, contents []*genai.Content, cfg *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	if rest, cached, ok := a.withContextCache(ctx, model, contents, cfg); ok {
+		resp, err := a.generateWithRetry(ctx, model, rest, cached)
+		if err == nil || ctx.Err() != nil || isRetryableGenAIError(err) {
+			return resp, err
+		}
+		logFrom(ctx).Warn("cached request failed, sending the whole history", "cache", cached.CachedContent, "err", err)
+		a.contextCaches.forget(cached.CachedContent)
+	}
+	return a.generateWithRetry(ctx, model, contents, cfg)
+}
+
+// withContextCache returns the rest of contents and a config referencing the
+// context cache of their prefix, creating or renewing the cache as needed.
+// ok is false when the request goes out as it is.
+func (a *App) withContextCache(ctx context.Context, model string, contents []*genai.Content, cfg *genai.GenerateContentConfig) ([]*genai.Content, *genai.GenerateContentConfig, bool) {
+	chatID, ok := chatIDFromContext(ctx)
+	if !ok || !contextCachingFrom(ctx) || !a.features.enabled(featureContextCaching) || cfg == nil || cfg.CachedContent != "" || !a.isGeminiModel(model) {
+		return nil, nil, false
+	}
+	prefix := cachePrefix(contents)
+	if prefix == 0 {
+		return nil, nil, false
+	}
+	cfg = withoutMixedTools(cfg)
+	_, name := a.providerFor(model)
+	key, err := contextCacheKey(name, contents[:prefix], cfg)
+	if err != nil {
+		logFrom(ctx).Warn("cannot key context cache", "err", err)
+		return nil, nil, false
+	}
+
+	entry, found := a.contextCaches.get(chatID)
+	switch {
+	case !found || entry.key != key || !time.Now().Before(entry.expires):
+		if found && entry.name != "" {
+			a.deleteContextCache(ctx, entry.name)
+		}
+		entry = a.createContextCache(ctx, name, key, contents[:prefix], cfg)
+		a.contextCaches.put(chatID, entry)
+	case entry.name != "" && time.Until(entry.expires) < contextCacheRenewal:
+		if expires, err := a.renewContextCache(ctx, entry.name); err == nil {
+			entry.expires = expires
+			a.contextCaches.put(chatID, entry)
+		} else {
+			logFrom(ctx).Warn("renew context cache failed", "cache", entry.name, "err", err)
+		}
+	}
+	if entry.name == "" {
+		return nil, nil, false
+	}
+
+	// A cached request takes the instruction and tools from the cache and
+	// must not repeat them.
+	cached := *cfg
+	cached.CachedContent = entry.name
+	cached.SystemInstruction, cached.Tools, cached.ToolConfig = nil, nil, nil
+	return contents[prefix:], &cached, true
+}
+
+// cachePrefix returns how many contents lead up to and include the latest
+// large one, or zero when there is none. The last content never counts: it
+// is the new message, which is stored in the history in another form when
+// personal data is masked, so caching it would not help the next turn.
+func cachePrefix(contents []*genai.Content) int {
+	for i := len(contents) - 2; i >= 0; i-- {
+		if largeContent(contents[i]) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// largeContent reports whether content holds a document worth caching: an
+// uploaded file or contextCacheMinChars of text.
+func largeContent(content *genai.Content) bool {
+	if content == nil {
+		return false
+	}
+	chars := 0
+	for _, part := range content.Parts {
+		if part == nil {
+			continue
+		}
+		if part.FileData != nil {
+			return true
+		}
+		chars += len(part.Text)
+	}
+	return chars >= contextCacheMinChars
+}
+
+func contextCacheKey(model string, contents []*genai.Content, cfg *genai.GenerateContentConfig) (string, error) {
+	encoded, err := json.Marshal(struct {
+		Model       string
+		Contents    []*genai.Content
+		Instruction *genai.Content
+		Tools       []*genai.Tool
+		ToolConfig  *genai.ToolConfig
+	}{model, contents, cfg.SystemInstruction, cfg.Tools, cfg.ToolConfig})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// createContextCache caches contents with the instruction and tools of cfg.
+// A failure is remembered as an entry without a name, so the chat does not
+// try again until the entry expires.
+func (a *App) createContextCache(ctx context.Context, model, key string, contents []*genai.Content, cfg *genai.GenerateContentConfig) contextCache {
+	entry := contextCache{key: key, expires: time.Now().Add(contextCacheTTL)}
+	ctx, cancel := context.WithTimeout(ctx, contextCacheTimeout)
+	defer cancel()
+	created, err := a.client.Caches.Create(ctx, model, &genai.CreateCachedContentConfig{
+		TTL:               contextCacheTTL,
+		DisplayName:       "chat history",
+		Contents:          contents,
+		SystemInstruction: cfg.SystemInstruction,
+		Tools:             cfg.Tools,
+		ToolConfig:        cfg.ToolConfig,
+	})
+	if err != nil {
+		logFrom(ctx).Warn("create context cache failed", "model", model, "err", err)
+		return entry
+	}
+	entry.name = created.Name
+	if !created.ExpireTime.IsZero() {
+		entry.expires = created.ExpireTime
+	}
+	logFrom(ctx).Info("cached the history up to a large document", "cache", created.Name, "contents", len(contents))
+	return entry
+}
+
+func (a *App) renewContextCache(ctx context.Context, name string) (time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, contextCacheTimeout)
+	defer cancel()
+	updated, err := a.client.Caches.Update(ctx, name, &genai.UpdateCachedContentConfig{TTL: contextCacheTTL})
+	if err != nil {
+		return time.Time{}, err
+	}
+	if updated.ExpireTime.IsZero() {
+		return time.Now().Add(contextCacheTTL), nil
+	}
+	return updated.ExpireTime, nil
+}
+
+// deleteContextCache removes a cache the chat no longer uses rather than
+// paying for it until it expires.
+func (a *App) deleteContextCache(ctx context.Context, name string) {
+	ctx, cancel := context.WithTimeout(ctx, contextCacheTimeout)
+	defer cancel()
+	if _, err := a.client.Caches.Delete(ctx, name, nil); err != nil {
+		logFrom(ctx).Warn("delete context cache failed", "cache", name, "err", err)
+	}
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestLargeDocumentIsCachedAcrossTurns(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.reply = "The report covers the third quarter."
+	document := "Quarterly report.\n" + strings.Repeat("Revenue grew in every region. ", contextCacheMinChars/30+1)
+
+	for _, text := range []string{document, "Which regions grew?", "And the outlook?"} {
+		if err := app.processMessage(context.Background(), testMessage(42, text), turnOptions{}); err != nil {
+			t.Fatalf("processMessage(%.20q): %v", text, err)
+		}
+	}
+
+	if creates := apis.callsTo(geminiHost, "/cachedContents"); len(creates) != 1 {
+		t.Fatalf("cache requests = %d, want one cache for both questions", len(creates))
+	}
+	// The chat model answers; gemini-2.5-flash-lite only routes the tools.
+	calls := apis.callsTo(geminiHost, "gemini-2.5-pro:generateContent")
+	if len(calls) != 3 {
+		t.Fatalf("generateContent called %d times", len(calls))
+	}
+	if !strings.Contains(string(calls[0].body), "Revenue grew") || strings.Contains(string(calls[0].body), "cachedContent") {
+		t.Error("first turn did not send the document itself")
+	}
+	for i, call := range calls[1:] {
+		var req struct {
+			CachedContent     string           `json:"cachedContent"`
+			SystemInstruction any              `json:"systemInstruction"`
+			Contents          []map[string]any `json:"contents"`
+		}
+		if err := json.Unmarshal(call.body, &req); err != nil {
+			t.Fatal(err)
+		}
+		if req.CachedContent != "cachedContents/history1" || req.SystemInstruction != nil {
+			t.Errorf("turn %d: cachedContent = %q, systemInstruction = %v", i+2, req.CachedContent, req.SystemInstruction)
+		}
+		if strings.Contains(string(call.body), "Revenue grew") {
+			t.Errorf("turn %d sent the document again", i+2)
+		}
+	}
+}
+
+func TestExpiredContextCacheFallsBack(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.reply = "Rent is due on the first."
+	document := strings.Repeat("Clause 1. The tenant pays rent monthly. ", contextCacheMinChars/40+1)
+	ask := func(text string) {
+		t.Helper()
+		if err := app.processMessage(context.Background(), testMessage(42, text), turnOptions{}); err != nil {
+			t.Fatalf("processMessage: %v", err)
+		}
+	}
+	ask(document)
+	ask("When is rent due?")
+
+	apis.mu.Lock()
+	apis.geminiError = func(path string, body []byte) int {
+		if strings.HasSuffix(path, ":generateContent") && strings.Contains(string(body), `"cachedContent"`) {
+			return http.StatusNotFound
+		}
+		return 0
+	}
+	apis.mu.Unlock()
+	ask("Can I pay weekly?")
+
+	calls := apis.callsTo(geminiHost, "gemini-2.5-pro:generateContent")
+	if last := string(calls[len(calls)-1].body); strings.Contains(last, `"cachedContent"`) || !strings.Contains(last, "Clause 1") {
+		t.Error("the turn after a failed cached request did not send the whole history")
+	}
+	if texts := apis.sentTexts(); !strings.Contains(texts[len(texts)-1], "Rent is due") {
+		t.Errorf("reply = %q", texts[len(texts)-1])
+	}
+	if _, ok := app.contextCaches.get(42); ok {
+		t.Error("the failed cache is still remembered")
+	}
+}
@@ -0,0 +1,121 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const contractCommand = "/contract"
+
+// contractInstruction frames every step of the contract review.
+const contractInstruction = "You review a contract for the user. Cite clause or section numbers for every finding, " +
+	"quote sparingly, and state only what the document supports; say so when a point is not covered. " +
+	"Do not repeat findings from earlier sections."
+
+// contractPipeline reviews a contract in four passes: a summary, the
+// obligations of each party, the risks and the unusual or missing clauses.
+func contractPipeline(lang language) pipeline {
+	return pipeline{
+		name:        "contract-review",
+		title:       tr(lang, "contract_title"),
+		instruction: contractInstruction,
+		footer:      tr(lang, "contract_caveat"),
+		steps: []pipelineStep{
+			{
+				title: tr(lang, "contract_summary"),
+				prompt: "Summarize the contract as bullets: the parties and their roles, the subject matter, " +
+					"the term and how it ends, the payment terms, the governing law and the venue.",
+			},
+			{
+				title: tr(lang, "contract_duties"),
+				prompt: "List the obligations of each party as a table with the columns Party, Obligation, " +
+					"Deadline and Clause. Include conditions and notice periods.",
+			},
+			{
+				title: tr(lang, "contract_risks"),
+				prompt: "List the risks for the party that uploaded the contract as a table with the columns Risk, " +
+					"Severity (high, medium or low), Clause and Suggested change, most severe first. Cover liability, " +
+					"indemnities, termination, renewal, penalties, intellectual property and confidentiality.",
+			},
+			{
+				title: tr(lang, "contract_unusual"),
+				prompt: "Name clauses that are unusual for a contract of this kind and explain why, " +
+					"then the clauses such a contract usually has that are missing here.",
+			},
+		},
+	}
+}
+
+// handleContract switches the contract review of uploaded documents on or
+// off, or reviews a single document:
+//
+//	/contract on|off
+//	/contract as a reply to a document
+//	a document captioned /contract
+func (a *App) handleContract(c tele.Context) error {
+	msg := c.Message()
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	reply := func(body string) error {
+		_, err := a.sendWithFallback(c.Chat(), body, &tele.SendOptions{ThreadID: messageTopic(msg), DisableWebPagePreview: true})
+		return err
+	}
+
+	switch {
+	case msg.Document != nil:
+		return a.enqueueContractReview(msg)
+	case msg.ReplyTo != nil && msg.ReplyTo.Document != nil:
+		return a.enqueueContractReview(msg.ReplyTo)
+	}
+
+	session := a.sessionOf(msg)
+	session.mu.Lock()
+	switch strings.ToLower(strings.TrimSpace(msg.Payload)) {
+	case "on":
+		session.contractReview = true
+	case "off":
+		session.contractReview = false
+	default:
+		enabled := session.contractReview
+		session.mu.Unlock()
+		state := tr(lang, "contract_off")
+		if enabled {
+			state = tr(lang, "contract_on")
+		}
+		return reply(tr(lang, "contract_usage") + "\n\n" + state)
+	}
+	enabled := session.contractReview
+	session.mu.Unlock()
+
+	if enabled {
+		return reply(tr(lang, "contract_on"))
+	}
+	return reply(tr(lang, "contract_off"))
+}
+
+// takeContractDocument reviews an uncaptioned document when the contract
+// review is on for its chat and reports whether it did.
+func (a *App) takeContractDocument(msg *tele.Message) bool {
+	if msg.Document == nil || strings.TrimSpace(msg.Caption) != "" {
+		return false
+	}
+	session := a.sessionOf(msg)
+	session.mu.Lock()
+	enabled := session.contractReview
+	session.mu.Unlock()
+	if !enabled {
+		return false
+	}
+	if err := a.enqueueContractReview(msg); err != nil {
+		slog.Warn("enqueue contract review failed", "err", err)
+	}
+	return true
+}
+
+func (a *App) enqueueContractReview(doc *tele.Message) error {
+	return a.enqueueJob(doc, func(ctx context.Context) error {
+		return a.runPipeline(ctx, doc, contractPipeline(a.chatLanguage(doc.Chat, doc.Sender)), "")
+	})
+}
@@ -0,0 +1,59 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestContractReviewRunsEveryStep(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.files = map[string]string{"nda": "Mutual NDA. 1. Term: two years. 2. Penalty: 1000 EUR per breach."}
+	apis.reply = "Clause 2 sets a flat penalty."
+
+	toggle := testMessage(42, "/contract on")
+	toggle.Payload = "on"
+	if err := app.handleContract(app.bot.NewContext(tele.Update{Message: toggle})); err != nil {
+		t.Fatalf("handleContract: %v", err)
+	}
+	doc := testMessage(42, "")
+	doc.Document = &tele.Document{File: tele.File{FileID: "nda", UniqueID: "nda"}, FileName: "nda.txt", MIME: "text/plain"}
+	if err := app.handleUserMessage(app.bot.NewContext(tele.Update{Message: doc})); err != nil {
+		t.Fatalf("handleUserMessage: %v", err)
+	}
+	app.queue.drain(context.Background())
+
+	calls := apis.callsTo(geminiHost, ":generateContent")
+	if len(calls) != 4 {
+		t.Fatalf("generateContent called %d times, want one per step", len(calls))
+	}
+	if body := string(calls[0].body); strings.Contains(body, "Findings so far") {
+		t.Error("first step sees findings")
+	}
+	if body := string(calls[3].body); !strings.Contains(body, "Findings so far") || !strings.Contains(body, "unusual") {
+		t.Errorf("last step request %q misses the earlier findings or its prompt", body)
+	}
+
+	docs := apis.callsTo(telegramHost, "sendDocument")
+	if len(docs) != 1 {
+		t.Fatalf("sent %d documents, want the report", len(docs))
+	}
+	report := string(docs[0].body)
+	for _, want := range []string{"contract-review-nda.md", "## Obligations", "## Unusual and missing clauses", "not legal advice"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report misses %q", want)
+		}
+	}
+}
+
+func TestContractReviewIsOptIn(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.files = map[string]string{"nda": "Mutual NDA."}
+	doc := testMessage(42, "")
+	doc.Document = &tele.Document{File: tele.File{FileID: "nda"}, FileName: "nda.txt", MIME: "text/plain"}
+	if app.takeContractDocument(doc) {
+		t.Error("reviewed a document with contract review off")
+	}
+}
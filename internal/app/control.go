@@ -0,0 +1,401 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	// controlService is the gRPC service of the control API. Its methods take
+	// and return a google.protobuf.Struct, so orchestration tooling needs no
+	// generated code; the server answers reflection requests for grpcurl.
+	controlService   = "eteon.control.v1.Control"
+	controlProtoFile = "eteon/control/v1/control.proto"
+	// controlStopGrace is how long open control calls get on shutdown.
+	controlStopGrace = 5 * time.Second
+)
+
+// Features the control API switches at run time.
+const (
+	featureContextCaching = "context_caching"
+	featureLoadShedding   = "load_shedding"
+	featureReplyHooks     = "reply_hooks"
+	featureSentiment      = "sentiment_alerts"
+)
+
+var featureNames = []string{featureContextCaching, featureLoadShedding, featureReplyHooks, featureSentiment}
+
+// featureFlags holds the features switched off through the control API. They
+// stay off until switched on again or the bot restarts; Reload leaves them
+// alone. The zero value has every feature on.
+type featureFlags struct {
+	mu  sync.RWMutex
+	off map[string]bool
+}
+
+func (f *featureFlags) enabled(name string) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return !f.off[name]
+}
+
+func (f *featureFlags) set(name string, on bool) error {
+	if !slices.Contains(featureNames, name) {
+		return fmt.Errorf("no feature named %q, want one of %s", name, strings.Join(featureNames, ", "))
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.off == nil {
+		f.off = make(map[string]bool)
+	}
+	f.off[name] = !on
+	return nil
+}
+
+// controlMethod is a method of the control API.
+type controlMethod func(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error)
+
+// controlMethods lists the control API:
+//
+//	Status {} returns the queue, the spend, the features and the quotas
+//	Drain {timeout_seconds} turns new messages away and waits for the running ones
+//	Resume {} accepts messages again
+//	SetFeature {name, enabled} switches one of featureNames
+//	SetQuota {rate_limit, load_shedding_depth, monthly_budget_usd, daily_budget_usd, user_daily_budget_usd}
+//	  changes any of them until the next reload; 0 lifts a limit. From 90%
+//	  of the monthly budget requests go light; once the bot or the user who
+//	  sent a request spent their daily budget, requests go light or are
+//	  refused as Config.OverBudget says until the day ends. The spend counts
+//	  every model call and is kept across restarts.
+//	EvictSession {chat_id} forgets the conversations and settings of a chat
+func (a *App) controlMethods() map[string]controlMethod {
+	return map[string]controlMethod{
+		"Status":       a.controlStatus,
+		"Drain":        a.controlDrain,
+		"Resume":       a.controlResume,
+		"SetFeature":   a.controlSetFeature,
+		"SetQuota":     a.controlSetQuota,
+		"EvictSession": a.controlEvictSession,
+	}
+}
+
+// serveControl starts the control API on Config.ControlListen, if set, and
+// returns the function that stops it.
+func (a *App) serveControl() (func(), error) {
+	if a.controlListen == "" {
+		return func() {}, nil
+	}
+	listener, err := net.Listen("tcp", a.controlListen)
+	if err != nil {
+		return nil, fmt.Errorf("control API: %w", err)
+	}
+	server, err := a.newControlServer()
+	if err != nil {
+		listener.Close()
+		return nil, err
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil {
+			slog.Warn("control API stopped", "err", err)
+		}
+	}()
+	slog.Info("control API listening", "addr", listener.Addr().String())
+	return func() {
+		done := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(controlStopGrace):
+			server.Stop()
+		}
+	}, nil
+}
+
+func (a *App) newControlServer() (*grpc.Server, error) {
+	if err := registerControlFile(a.controlMethods()); err != nil {
+		return nil, fmt.Errorf("control API: %w", err)
+	}
+	server := grpc.NewServer(grpc.UnaryInterceptor(a.controlAuth))
+	server.RegisterService(a.controlServiceDesc(), a)
+	healthpb.RegisterHealthServer(server, controlHealth{app: a})
+	reflection.Register(server)
+	return server, nil
+}
+
+func (a *App) controlServiceDesc() *grpc.ServiceDesc {
+	desc := &grpc.ServiceDesc{
+		ServiceName: controlService,
+		HandlerType: (*any)(nil),
+		Metadata:    controlProtoFile,
+	}
+	methods := a.controlMethods()
+	for _, name := range slices.Sorted(maps.Keys(methods)) {
+		method := methods[name]
+		fullMethod := "/" + controlService + "/" + name
+		desc.Methods = append(desc.Methods, grpc.MethodDesc{
+			MethodName: name,
+			Handler: func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+				in := new(structpb.Struct)
+				if err := dec(in); err != nil {
+					return nil, err
+				}
+				if interceptor == nil {
+					return method(ctx, in)
+				}
+				info := &grpc.UnaryServerInfo{Server: srv, FullMethod: fullMethod}
+				return interceptor(ctx, in, info, func(ctx context.Context, req any) (any, error) {
+					return method(ctx, req.(*structpb.Struct))
+				})
+			},
+		})
+	}
+	return desc
+}
+
+var registerControlOnce sync.Once
+
+// registerControlFile describes the control service to the protobuf registry,
+// once per process, so reflection can serve it.
+func registerControlFile(methods map[string]controlMethod) error {
+	var err error
+	registerControlOnce.Do(func() {
+		service := &descriptorpb.ServiceDescriptorProto{Name: proto.String("Control")}
+		for _, name := range slices.Sorted(maps.Keys(methods)) {
+			service.Method = append(service.Method, &descriptorpb.MethodDescriptorProto{
+				Name:       proto.String(name),
+				InputType:  proto.String(".google.protobuf.Struct"),
+				OutputType: proto.String(".google.protobuf.Struct"),
+			})
+		}
+		file, fileErr := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+			Name:       proto.String(controlProtoFile),
+			Package:    proto.String("eteon.control.v1"),
+			Dependency: []string{"google/protobuf/struct.proto"},
+			Service:    []*descriptorpb.ServiceDescriptorProto{service},
+			Syntax:     proto.String("proto3"),
+		}, protoregistry.GlobalFiles)
+		if fileErr != nil {
+			err = fileErr
+			return
+		}
+		err = protoregistry.GlobalFiles.RegisterFile(file)
+	})
+	return err
+}
+
+// controlAuth lets control calls through only with the bearer token; the
+// health service stays open for probes.
+func (a *App) controlAuth(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if !strings.HasPrefix(info.FullMethod, "/"+controlService+"/") {
+		return handler(ctx, req)
+	}
+	var got string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			got = values[0]
+		}
+	}
+	if subtle.ConstantTimeCompare([]byte(got), []byte("Bearer "+a.controlToken)) != 1 {
+		return nil, status.Error(codes.Unauthenticated, "missing or wrong control token")
+	}
+	slog.Info("control call", "method", info.FullMethod)
+	return handler(ctx, req)
+}
+
+// controlHealth answers the standard health checks: serving, or not serving
+// while the queue drains so the orchestrator stops routing to the bot.
+type controlHealth struct {
+	healthpb.UnimplementedHealthServer
+	app *App
+}
+
+func (h controlHealth) Check(_ context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	switch req.GetService() {
+	case "", controlService:
+	default:
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", req.GetService())
+	}
+	if h.app.queue.isDraining() {
+		return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING}, nil
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func (a *App) controlStatus(context.Context, *structpb.Struct) (*structpb.Struct, error) {
+	return structpb.NewStruct(a.controlState())
+}
+
+// controlState is what Status reports, the same figures as /admin stats.
+func (a *App) controlState() map[string]any {
+	chats, inactive, banned := a.operator.counts()
+	busy, pending := a.queue.stats()
+	day, month := a.usage.globalSnapshot()
+	t := a.tuned()
+	features := make(map[string]any, len(featureNames))
+	for _, name := range featureNames {
+		features[name] = a.features.enabled(name) && (name != featureSentiment || a.sentiment != nil)
+	}
+	return map[string]any{
+		"version":         a.version,
+		"uptime_seconds":  int64(time.Since(a.started).Seconds()),
+		"model":           t.model,
+		"draining":        a.queue.isDraining(),
+		"busy_chats":      busy,
+		"queued_messages": pending,
+		"known_chats":     chats,
+		"inactive_chats":  inactive,
+		"banned_users":    banned,
+		"sessions":        a.sessions.count(),
+		"cost_today_usd":  day.CostUSD,
+		"cost_month_usd":  month.CostUSD,
+		"going_light":     a.shedding(),
+		"features":        features,
+		"quotas": map[string]any{
+			"rate_limit":            a.access.rateLimit(),
+			"load_shedding_depth":   t.shedDepth,
+			"monthly_budget_usd":    t.monthlyBudget,
+			"daily_budget_usd":      t.dailyBudget,
+			"user_daily_budget_usd": t.userDailyBudget,
+			"over_budget":           t.overBudget,
+		},
+	}
+}
+
+func (a *App) controlDrain(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	wait := adminDrainLimit
+	if v, ok := in.GetFields()["timeout_seconds"]; ok {
+		seconds := v.GetNumberValue()
+		if seconds <= 0 || math.IsInf(seconds, 0) || math.IsNaN(seconds) {
+			return nil, status.Error(codes.InvalidArgument, "timeout_seconds must be positive")
+		}
+		wait = time.Duration(seconds * float64(time.Second))
+	}
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+	drained := a.queue.drain(ctx) == nil
+	slog.Info("draining through the control API", "drained", drained)
+	state := a.controlState()
+	state["drained"] = drained
+	return structpb.NewStruct(state)
+}
+
+func (a *App) controlResume(context.Context, *structpb.Struct) (*structpb.Struct, error) {
+	a.queue.resume()
+	slog.Info("resumed through the control API")
+	return structpb.NewStruct(a.controlState())
+}
+
+func (a *App) controlSetFeature(_ context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	fields := in.GetFields()
+	name := fields["name"].GetStringValue()
+	enabled, ok := fields["enabled"].GetKind().(*structpb.Value_BoolValue)
+	if !ok {
+		return nil, status.Error(codes.InvalidArgument, "enabled must be true or false")
+	}
+	if name == featureSentiment && enabled.BoolValue && a.sentiment == nil {
+		return nil, status.Error(codes.FailedPrecondition, "sentiment alerts need SENTIMENT_ALERTS")
+	}
+	if err := a.features.set(name, enabled.BoolValue); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	slog.Info("feature switched through the control API", "feature", name, "enabled", enabled.BoolValue)
+	return structpb.NewStruct(a.controlState())
+}
+
+// controlSetQuota changes the quotas it is given. Like the other tunables
+// they fall back to the configuration on the next reload.
+func (a *App) controlSetQuota(_ context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	fields := in.GetFields()
+	number := func(key string, whole bool) (float64, bool, error) {
+		v, ok := fields[key]
+		if !ok {
+			return 0, false, nil
+		}
+		n, isNumber := v.GetKind().(*structpb.Value_NumberValue)
+		if !isNumber || n.NumberValue < 0 || math.IsInf(n.NumberValue, 0) || (whole && n.NumberValue != math.Trunc(n.NumberValue)) {
+			return 0, false, status.Errorf(codes.InvalidArgument, "%s must be a non-negative number", key)
+		}
+		return n.NumberValue, true, nil
+	}
+	limit, setLimit, err := number("rate_limit", true)
+	if err != nil {
+		return nil, err
+	}
+	depth, setDepth, err := number("load_shedding_depth", true)
+	if err != nil {
+		return nil, err
+	}
+	budget, setBudget, err := number("monthly_budget_usd", false)
+	if err != nil {
+		return nil, err
+	}
+	daily, setDaily, err := number("daily_budget_usd", false)
+	if err != nil {
+		return nil, err
+	}
+	userDaily, setUserDaily, err := number("user_daily_budget_usd", false)
+	if err != nil {
+		return nil, err
+	}
+	if !setLimit && !setDepth && !setBudget && !setDaily && !setUserDaily {
+		return nil, status.Error(codes.InvalidArgument, "set rate_limit, load_shedding_depth, monthly_budget_usd, daily_budget_usd or user_daily_budget_usd")
+	}
+
+	if setLimit {
+		a.access.setLimit(int(limit))
+	}
+	if setDepth || setBudget || setDaily || setUserDaily {
+		a.updateTunables(func(t *tunables) {
+			if setDepth {
+				t.shedDepth = int(depth)
+			}
+			if setBudget {
+				t.monthlyBudget = budget
+			}
+			if setDaily {
+				t.dailyBudget = daily
+			}
+			if setUserDaily {
+				t.userDailyBudget = userDaily
+			}
+		})
+	}
+	slog.Info("quotas changed through the control API", "quotas", in.AsMap())
+	return structpb.NewStruct(a.controlState())
+}
+
+func (a *App) controlEvictSession(_ context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	v, ok := in.GetFields()["chat_id"].GetKind().(*structpb.Value_NumberValue)
+	if !ok || v.NumberValue == 0 || v.NumberValue != math.Trunc(v.NumberValue) {
+		return nil, status.Error(codes.InvalidArgument, "chat_id must be a chat ID")
+	}
+	chatID := int64(v.NumberValue)
+	evicted := len(a.sessions.ofChat(chatID))
+	a.sessions.remove(chatID)
+	slog.Info("session evicted through the control API", "chat_id", chatID, "sessions", evicted)
+	return structpb.NewStruct(map[string]any{"chat_id": chatID, "evicted": evicted})
+}
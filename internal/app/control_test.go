@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func dialControl(t *testing.T, app *App) *grpc.ClientConn {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server, err := app.newControlServer()
+	if err != nil {
+		t.Fatalf("newControlServer: %v", err)
+	}
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+	conn, err := grpc.NewClient("passthrough:///control",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func callControl(ctx context.Context, conn *grpc.ClientConn, method string, in map[string]any) (map[string]any, error) {
+	req, err := structpb.NewStruct(in)
+	if err != nil {
+		return nil, err
+	}
+	out := new(structpb.Struct)
+	if err := conn.Invoke(ctx, "/"+controlService+"/"+method, req, out); err != nil {
+		return nil, err
+	}
+	return out.AsMap(), nil
+}
+
+func TestControlAPI(t *testing.T) {
+	app, _ := newTestApp(t, Config{ControlListen: "127.0.0.1:0", ControlToken: "secret"})
+	conn := dialControl(t, app)
+	ctx := context.Background()
+	authed := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret")
+
+	if _, err := callControl(ctx, conn, "Status", nil); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("Status without a token: %v, want Unauthenticated", err)
+	}
+	state, err := callControl(authed, conn, "Status", nil)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	if state["draining"] != false || state["features"].(map[string]any)[featureLoadShedding] != true {
+		t.Errorf("Status = %v", state)
+	}
+
+	if _, err := callControl(authed, conn, "SetFeature", map[string]any{"name": featureLoadShedding, "enabled": false}); err != nil {
+		t.Fatalf("SetFeature: %v", err)
+	}
+	if app.features.enabled(featureLoadShedding) {
+		t.Error("load shedding still on")
+	}
+	if _, err := callControl(authed, conn, "SetFeature", map[string]any{"name": featureSentiment, "enabled": true}); status.Code(err) != codes.FailedPrecondition {
+		t.Errorf("enabling unconfigured sentiment alerts: %v", err)
+	}
+
+	if _, err := callControl(authed, conn, "SetQuota", map[string]any{"rate_limit": 5, "monthly_budget_usd": 12.5}); err != nil {
+		t.Fatalf("SetQuota: %v", err)
+	}
+	if got := app.access.rateLimit(); got != 5 {
+		t.Errorf("rate limit = %d, want 5", got)
+	}
+	if got := app.tuned().monthlyBudget; got != 12.5 {
+		t.Errorf("monthly budget = %g, want 12.5", got)
+	}
+	if _, err := callControl(authed, conn, "SetQuota", map[string]any{"rate_limit": -1}); status.Code(err) != codes.InvalidArgument {
+		t.Errorf("negative rate limit: %v", err)
+	}
+
+	app.sessions.get(42)
+	evicted, err := callControl(authed, conn, "EvictSession", map[string]any{"chat_id": 42})
+	if err != nil || evicted["evicted"] != float64(1) {
+		t.Errorf("EvictSession = %v, %v", evicted, err)
+	}
+
+	health := healthpb.NewHealthClient(conn)
+	check := func() healthpb.HealthCheckResponse_ServingStatus {
+		resp, err := health.Check(ctx, &healthpb.HealthCheckRequest{})
+		if err != nil {
+			t.Fatalf("health check: %v", err)
+		}
+		return resp.GetStatus()
+	}
+	if got := check(); got != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("health = %v, want SERVING", got)
+	}
+	if state, err := callControl(authed, conn, "Drain", map[string]any{"timeout_seconds": 1}); err != nil || state["drained"] != true {
+		t.Fatalf("Drain = %v, %v", state, err)
+	}
+	if got := check(); got != healthpb.HealthCheckResponse_NOT_SERVING {
+		t.Errorf("health while draining = %v, want NOT_SERVING", got)
+	}
+	if _, err := callControl(authed, conn, "Resume", nil); err != nil {
+		t.Fatalf("Resume: %v", err)
+	}
+	if got := check(); got != healthpb.HealthCheckResponse_SERVING {
+		t.Errorf("health after resume = %v, want SERVING", got)
+	}
+}
+
+func TestControlNeedsToken(t *testing.T) {
+	cfg := Config{TelegramToken: "t", GeminiAPIKey: "k", ControlListen: ":9090"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("CONTROL_LISTEN without CONTROL_TOKEN passed validation")
+	}
+}
@@ -0,0 +1,254 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+const (
+	exchangeRatesURL = "https://api.frankfurter.app/latest"
+	exchangeRatesTTL = time.Hour
+)
+
+type unitDef struct {
+	dimension string
+	factor    float64 // multiplier to the dimension's base unit
+}
+
+var unitTable = map[string]unitDef{
+	// length, base metre
+	"mm": {"length", 0.001}, "cm": {"length", 0.01}, "m": {"length", 1}, "km": {"length", 1000},
+	"in": {"length", 0.0254}, "ft": {"length", 0.3048}, "yd": {"length", 0.9144}, "mi": {"length", 1609.344},
+	"nmi": {"length", 1852},
+	// mass, base kilogram
+	"mg": {"mass", 1e-6}, "g": {"mass", 0.001}, "kg": {"mass", 1}, "t": {"mass", 1000},
+	"oz": {"mass", 0.028349523125}, "lb": {"mass", 0.45359237}, "st": {"mass", 6.35029318},
+	// volume, base litre
+	"ml": {"volume", 0.001}, "l": {"volume", 1}, "m3": {"volume", 1000},
+	"tsp": {"volume", 0.00492892159}, "tbsp": {"volume", 0.0147867648}, "cup": {"volume", 0.2365882365},
+	"floz": {"volume", 0.0295735296}, "pt": {"volume", 0.473176473}, "qt": {"volume", 0.946352946},
+	"gal": {"volume", 3.785411784},
+	// area, base square metre
+	"mm2": {"area", 1e-6}, "cm2": {"area", 1e-4}, "m2": {"area", 1}, "km2": {"area", 1e6},
+	"ha": {"area", 1e4}, "acre": {"area", 4046.8564224}, "ft2": {"area", 0.09290304}, "mi2": {"area", 2589988.110336},
+	// speed, base metre per second
+	"m/s": {"speed", 1}, "km/h": {"speed", 1 / 3.6}, "mph": {"speed", 0.44704}, "kn": {"speed", 0.514444},
+	// time, base second
+	"ms": {"time", 0.001}, "s": {"time", 1}, "min": {"time", 60}, "h": {"time", 3600},
+	"day": {"time", 86400}, "week": {"time", 604800},
+	// data, base byte
+	"bit": {"data", 0.125}, "b": {"data", 1}, "kb": {"data", 1e3}, "mb": {"data", 1e6}, "gb": {"data", 1e9},
+	"tb": {"data", 1e12}, "kib": {"data", 1024}, "mib": {"data", 1 << 20}, "gib": {"data", 1 << 30},
+	// energy, base joule
+	"j": {"energy", 1}, "kj": {"energy", 1e3}, "cal": {"energy", 4.184}, "kcal": {"energy", 4184},
+	"wh": {"energy", 3600}, "kwh": {"energy", 3.6e6},
+	// pressure, base pascal
+	"pa": {"pressure", 1}, "kpa": {"pressure", 1e3}, "bar": {"pressure", 1e5}, "atm": {"pressure", 101325},
+	"psi": {"pressure", 6894.757293168},
+}
+
+var unitAliases = map[string]string{
+	"meter": "m", "meters": "m", "metre": "m", "metres": "m", "kilometer": "km", "kilometers": "km",
+	"centimeter": "cm", "centimeters": "cm", "millimeter": "mm", "millimeters": "mm",
+	"inch": "in", "inches": "in", "foot": "ft", "feet": "ft", "yard": "yd", "yards": "yd",
+	"mile": "mi", "miles": "mi", "gram": "g", "grams": "g", "kilogram": "kg", "kilograms": "kg",
+	"pound": "lb", "pounds": "lb", "lbs": "lb", "ounce": "oz", "ounces": "oz", "tonne": "t", "tonnes": "t",
+	"liter": "l", "liters": "l", "litre": "l", "litres": "l", "milliliter": "ml", "milliliters": "ml",
+	"gallon": "gal", "gallons": "gal", "cups": "cup", "kph": "km/h", "kmh": "km/h", "knots": "kn",
+	"second": "s", "seconds": "s", "sec": "s", "minute": "min", "minutes": "min", "hour": "h", "hours": "h",
+	"days": "day", "weeks": "week", "celsius": "c", "°c": "c", "fahrenheit": "f", "°f": "f", "kelvin": "k",
+}
+
+func normalizeUnit(u string) string {
+	u = strings.ToLower(strings.TrimSpace(u))
+	if alias, ok := unitAliases[u]; ok {
+		return alias
+	}
+	return u
+}
+
+func convertUnits(value float64, from, to string) (float64, error) {
+	from, to = normalizeUnit(from), normalizeUnit(to)
+	if isTemperatureUnit(from) || isTemperatureUnit(to) {
+		if !isTemperatureUnit(from) || !isTemperatureUnit(to) {
+			return 0, fmt.Errorf("cannot convert %s to %s", from, to)
+		}
+		return convertTemperature(value, from, to), nil
+	}
+	src, ok := unitTable[from]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", from)
+	}
+	dst, ok := unitTable[to]
+	if !ok {
+		return 0, fmt.Errorf("unknown unit %q", to)
+	}
+	if src.dimension != dst.dimension {
+		return 0, fmt.Errorf("cannot convert %s (%s) to %s (%s)", from, src.dimension, to, dst.dimension)
+	}
+	return value * src.factor / dst.factor, nil
+}
+
+func isTemperatureUnit(u string) bool {
+	return u == "c" || u == "f" || u == "k"
+}
+
+func convertTemperature(value float64, from, to string) float64 {
+	var celsius float64
+	switch from {
+	case "f":
+		celsius = (value - 32) * 5 / 9
+	case "k":
+		celsius = value - 273.15
+	default:
+		celsius = value
+	}
+	switch to {
+	case "f":
+		return celsius*9/5 + 32
+	case "k":
+		return celsius + 273.15
+	default:
+		return celsius
+	}
+}
+
+// rateCache keeps exchange rates per base currency for exchangeRatesTTL.
+type rateCache struct {
+	mu      sync.Mutex
+	entries map[string]rateEntry
+}
+
+type rateEntry struct {
+	date    string
+	rates   map[string]float64
+	fetched time.Time
+}
+
+func newRateCache() *rateCache {
+	return &rateCache{entries: make(map[string]rateEntry)}
+}
+
+func (c *rateCache) rates(ctx context.Context, base string) (rateEntry, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[base]
+	c.mu.Unlock()
+	if ok && time.Since(entry.fetched) < exchangeRatesTTL {
+		return entry, nil
+	}
+
+	var payload struct {
+		Date  string             `json:"date"`
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := getJSON(ctx, exchangeRatesURL+"?from="+url.QueryEscape(base), "", &payload); err != nil {
+		return rateEntry{}, fmt.Errorf("fetch rates: %w", err)
+	}
+	entry = rateEntry{date: payload.Date, rates: payload.Rates, fetched: time.Now()}
+	c.mu.Lock()
+	c.entries[base] = entry
+	c.mu.Unlock()
+	return entry, nil
+}
+
+func (a *App) registerConversionTools() {
+	a.functions.register(&genai.FunctionDeclaration{
+		Name:        "convert_units",
+		Description: "Convert a value between physical units (length, mass, volume, area, speed, time, data, energy, pressure, temperature). Use short unit symbols such as km, lb, gal, km/h, kwh, c, f.",
+		Parameters: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"value": {Type: genai.TypeNumber, Description: "Quantity to convert."},
+				"from":  {Type: genai.TypeString, Description: "Source unit."},
+				"to":    {Type: genai.TypeString, Description: "Target unit."},
+			},
+			Required: []string{"value", "from", "to"},
+		},
+	}, func(ctx context.Context, args map[string]any) (map[string]any, error) {
+		value, err := argNumber(args, "value")
+		if err != nil {
+			return nil, err
+		}
+		from, to := argString(args, "from"), argString(args, "to")
+		result, err := convertUnits(value, from, to)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"value": roundSignificant(result, 10), "unit": normalizeUnit(to)}, nil
+	})
+
+	a.functions.register(&genai.FunctionDeclaration{
+		Name:        "convert_currency",
+		Description: "Convert an amount between currencies using live reference exchange rates. Currencies are ISO 4217 codes such as USD, EUR, JPY.",
+		Parameters: &genai.Schema{
+			Type: genai.TypeObject,
+			Properties: map[string]*genai.Schema{
+				"amount": {Type: genai.TypeNumber, Description: "Amount in the source currency."},
+				"from":   {Type: genai.TypeString, Description: "Source currency code."},
+				"to":     {Type: genai.TypeString, Description: "Target currency code."},
+			},
+			Required: []string{"amount", "from", "to"},
+		},
+	}, func(ctx context.Context, args map[string]any) (map[string]any, error) {
+		amount, err := argNumber(args, "amount")
+		if err != nil {
+			return nil, err
+		}
+		from := strings.ToUpper(argString(args, "from"))
+		to := strings.ToUpper(argString(args, "to"))
+		if from == to {
+			return map[string]any{"amount": amount, "currency": to}, nil
+		}
+		entry, err := a.rates.rates(ctx, from)
+		if err != nil {
+			return nil, err
+		}
+		rate, ok := entry.rates[to]
+		if !ok {
+			return nil, fmt.Errorf("no rate for %s to %s", from, to)
+		}
+		return map[string]any{
+			"amount":     math.Round(amount*rate*100) / 100,
+			"currency":   to,
+			"rate":       rate,
+			"rates_date": entry.date,
+		}, nil
+	})
+}
+
+func argNumber(args map[string]any, key string) (float64, error) {
+	switch v := args[key].(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case json.Number:
+		return v.Float64()
+	case nil:
+		return 0, fmt.Errorf("missing %s", key)
+	default:
+		return 0, errors.New(key + " must be a number")
+	}
+}
+
+func argString(args map[string]any, key string) string {
+	v, _ := args[key].(string)
+	return strings.TrimSpace(v)
+}
+
+func roundSignificant(v float64, digits int) float64 {
+	if v == 0 || math.IsInf(v, 0) || math.IsNaN(v) {
+		return v
+	}
+	scale := math.Pow(10, float64(digits)-math.Ceil(math.Log10(math.Abs(v))))
+	return math.Round(v*scale) / scale
+}
@@ -0,0 +1,143 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+const (
+	// generationDedupeWindow is how long a successful response answers an
+	// identical request.
+	generationDedupeWindow = 2 * time.Minute
+	maxDedupedGenerations  = 64
+)
+
+// generationCache dedupes identical GenerateContent requests. Gemini takes no
+// idempotency keys, so the request itself is the key: a caller in the same
+// chat sending the same model, contents and config while an earlier call runs
+// waits for it, and one arriving within the window after it succeeded gets its
+// response. A turn that is retried after a network failure then neither bills
+// nor answers twice. Requests of chats in privacy mode are not kept.
+type generationCache struct {
+	mu      sync.Mutex
+	entries map[string]*generation
+	window  time.Duration
+	now     func() time.Time
+	reused  atomic.Uint64
+}
+
+type generation struct {
+	done     chan struct{}
+	resp     *genai.GenerateContentResponse
+	err      error
+	finished time.Time
+}
+
+func newGenerationCache() *generationCache {
+	return &generationCache{
+		entries: make(map[string]*generation),
+		window:  generationDedupeWindow,
+		now:     time.Now,
+	}
+}
+
+// generationKey hashes everything that shapes a response, and the chat it is
+// for, so one chat never gets the answer generated for another.
+func generationKey(chatID int64, model string, contents []*genai.Content, cfg *genai.GenerateContentConfig) (string, error) {
+	h := sha256.New()
+	enc := json.NewEncoder(h)
+	for _, v := range []any{chatID, model, contents, cfg} {
+		if err := enc.Encode(v); err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// do returns the response of an identical recent or running request, else runs
+// call and shares its result. Failures are not kept: callers waiting on a
+// failed call run their own.
+func (c *generationCache) do(ctx context.Context, key string, call func() (*genai.GenerateContentResponse, error)) (*genai.GenerateContentResponse, error) {
+	for {
+		c.mu.Lock()
+		c.sweepLocked()
+		g, ok := c.entries[key]
+		if !ok {
+			g = &generation{done: make(chan struct{})}
+			c.entries[key] = g
+			c.mu.Unlock()
+			return c.run(key, g, call)
+		}
+		c.mu.Unlock()
+
+		select {
+		case <-g.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if g.err == nil {
+			c.reused.Add(1)
+			logFrom(ctx).Info("reused identical generation")
+			reused := *g.resp
+			return &reused, nil
+		}
+	}
+}
+
+func (c *generationCache) run(key string, g *generation, call func() (*genai.GenerateContentResponse, error)) (*genai.GenerateContentResponse, error) {
+	resp, err := call()
+	c.mu.Lock()
+	if err == nil {
+		// The tokens were billed to this caller, which may go on to rewrite
+		// its usage metadata, so the shared copy carries none.
+		shared := *resp
+		shared.UsageMetadata = nil
+		g.resp = &shared
+	}
+	g.err, g.finished = err, c.now()
+	if err != nil {
+		delete(c.entries, key)
+	}
+	c.mu.Unlock()
+	close(g.done)
+	return resp, err
+}
+
+// sweepLocked drops responses older than the window and, over the size limit,
+// the oldest finished ones.
+func (c *generationCache) sweepLocked() {
+	cutoff := c.now().Add(-c.window)
+	var oldestKey string
+	var oldest time.Time
+	finished := 0
+	for key, g := range c.entries {
+		if g.finished.IsZero() {
+			continue
+		}
+		if g.finished.Before(cutoff) {
+			delete(c.entries, key)
+			continue
+		}
+		finished++
+		if oldestKey == "" || g.finished.Before(oldest) {
+			oldestKey, oldest = key, g.finished
+		}
+	}
+	if finished >= maxDedupedGenerations {
+		delete(c.entries, oldestKey)
+	}
+}
+
+// stats reports the responses held and how many requests they answered.
+func (c *generationCache) stats() (held int, reused uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries), c.reused.Load()
+}
@@ -0,0 +1,123 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+func TestIdenticalGenerationIsReused(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	contents := []*genai.Content{genai.NewContentFromText("Hi", genai.RoleUser)}
+	cfg := &genai.GenerateContentConfig{Temperature: genai.Ptr[float32](0)}
+
+	first, err := app.generateWithRetry(context.Background(), geminiModel, contents, cfg)
+	if err != nil {
+		t.Fatalf("first generation: %v", err)
+	}
+	second, err := app.generateWithRetry(context.Background(), geminiModel, contents, cfg)
+	if err != nil {
+		t.Fatalf("second generation: %v", err)
+	}
+	if calls := apis.callsTo(geminiHost, ":generateContent"); len(calls) != 1 {
+		t.Fatalf("Gemini got %d requests, want 1", len(calls))
+	}
+	if second.Text() != first.Text() || second.UsageMetadata != nil {
+		t.Errorf("reused response = %q with usage %v, want %q without usage", second.Text(), second.UsageMetadata, first.Text())
+	}
+
+	other := []*genai.Content{genai.NewContentFromText("Hello", genai.RoleUser)}
+	if _, err := app.generateWithRetry(context.Background(), geminiModel, other, cfg); err != nil {
+		t.Fatalf("other generation: %v", err)
+	}
+	if calls := apis.callsTo(geminiHost, ":generateContent"); len(calls) != 2 {
+		t.Fatalf("Gemini got %d requests, want a new one for other contents", len(calls))
+	}
+}
+
+func TestGenerationCacheSharesRunningCalls(t *testing.T) {
+	c := newGenerationCache()
+	release := make(chan struct{})
+	var mu sync.Mutex
+	calls := 0
+	call := func() (*genai.GenerateContentResponse, error) {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+		if n == 1 {
+			<-release
+			return nil, errors.New("network down")
+		}
+		return &genai.GenerateContentResponse{}, nil
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = c.do(context.Background(), "k", call)
+		}()
+		if i == 0 {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	// The first call fails; one waiter retries and the other reuses its answer.
+	if failed != 1 || calls != 2 {
+		t.Errorf("failed %d, calls %d; want 1 failure and 2 calls", failed, calls)
+	}
+
+	c.now = func() time.Time { return time.Now().Add(2 * generationDedupeWindow) }
+	if _, err := c.do(context.Background(), "k", call); err != nil || calls != 3 {
+		t.Errorf("after the window: err %v, calls %d; want a new call", err, calls)
+	}
+}
+
+func TestGenerationCacheKeepsChatsApart(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	if err := app.privacy.set(43, true); err != nil {
+		t.Fatalf("enable privacy mode: %v", err)
+	}
+	contents := []*genai.Content{genai.NewContentFromText("Hi", genai.RoleUser)}
+	cfg := &genai.GenerateContentConfig{Temperature: genai.Ptr[float32](0)}
+
+	tests := []struct {
+		name  string
+		chats []int64
+		calls int
+	}{
+		{"same chat", []int64{41, 41}, 1},
+		{"different chats", []int64{42, 44}, 2},
+		{"privacy mode", []int64{43, 43}, 2},
+	}
+	for _, tt := range tests {
+		before := len(apis.callsTo(geminiHost, ":generateContent"))
+		for _, chatID := range tt.chats {
+			if _, err := app.generateWithRetry(withChatID(context.Background(), chatID), geminiModel, contents, cfg); err != nil {
+				t.Fatalf("%s: generation: %v", tt.name, err)
+			}
+		}
+		if calls := len(apis.callsTo(geminiHost, ":generateContent")) - before; calls != tt.calls {
+			t.Errorf("%s: Gemini got %d requests, want %d", tt.name, calls, tt.calls)
+		}
+	}
+	if held, _ := app.generations.stats(); held != 3 {
+		t.Errorf("cache holds %d responses, want those of chats 41, 42 and 44 only", held)
+	}
+}
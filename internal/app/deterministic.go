@@ -0,0 +1,45 @@
+package app
+
+import (
+	"strings"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+// deterministicSeed is used when a chat turns on /deterministic without choosing
+// its own seed through /advanced.
+const deterministicSeed int32 = 42
+
+// applyDeterministic pins sampling so that the same conversation yields the same
+// answer. An explicit /advanced seed still wins because overrides apply later.
+func applyDeterministic(cfg *genai.GenerateContentConfig) {
+	temperature := float32(0)
+	seed := deterministicSeed
+	cfg.Temperature = &temperature
+	cfg.Seed = &seed
+}
+
+func (a *App) handleDeterministic(c tele.Context) error {
+	session := a.sessionOf(c.Message())
+
+	session.mu.Lock()
+	switch strings.ToLower(strings.TrimSpace(c.Message().Payload)) {
+	case "on":
+		session.deterministic = true
+	case "off":
+		session.deterministic = false
+	default:
+		session.deterministic = !session.deterministic
+	}
+	enabled := session.deterministic
+	session.mu.Unlock()
+
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	body := tr(lang, "deterministic_off")
+	if enabled {
+		body = tr(lang, "deterministic_on", deterministicSeed)
+	}
+	_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), body, &tele.SendOptions{DisableWebPagePreview: true})
+	return err
+}
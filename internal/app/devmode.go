@@ -0,0 +1,62 @@
+package app
+
+import (
+	"regexp"
+	"strings"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// debugInstruction structures the answer to a pasted error or stack trace.
+const debugInstruction = "The user's message contains an error message or stack trace. " +
+	"Answer as a debugging assistant in three short sections: " +
+	"**Probable cause**, naming the frame or line that matters and why it fails; " +
+	"**Fix**, with the concrete change as code; " +
+	"**Minimal repro**, the smallest program or command that triggers the same error. " +
+	"If the cause is ambiguous, list the likely causes in order and say what output would tell them apart."
+
+var (
+	// errorHeaders start a stack trace or crash report on their own.
+	errorHeaders = regexp.MustCompile(`(?m)^(Traceback \(most recent call last\):|panic: |goroutine \d+ \[|Exception in thread |Caused by: |fatal error: |Segmentation fault|thread '.+' panicked at |npm ERR! |Unhandled exception)`)
+	// errorFrames are stack frames and file:line locations.
+	errorFrames = regexp.MustCompile(`(?m)^\s*(at \S+ ?\(?.*:\d+|File ".+", line \d+|\S+\.\w+:\d+(:\d+)?|#\d+ +0x[0-9a-f]+|--> \S+:\d+:\d+)`)
+	// errorLines name an error the way compilers and runtimes print it.
+	errorLines = regexp.MustCompile(`(?m)(^|\s)(\w+(Error|Exception)\b:?|error(\[E\d+\]| TS\d+| CS\d+)?:|ERROR\b|undefined(: | reference to)|cannot find symbol|is not defined|has no attribute)`)
+)
+
+// looksLikeError reports whether text reads as a pasted stack trace or compiler
+// output rather than a question about one.
+func looksLikeError(text string) bool {
+	if errorHeaders.MatchString(text) {
+		return true
+	}
+	frames := len(errorFrames.FindAllStringIndex(text, -1))
+	if frames >= 2 {
+		return true
+	}
+	return frames == 1 && errorLines.MatchString(text)
+}
+
+func (a *App) handleDevMode(c tele.Context) error {
+	session := a.sessionOf(c.Message())
+
+	session.mu.Lock()
+	switch strings.ToLower(strings.TrimSpace(c.Message().Payload)) {
+	case "on":
+		session.devMode = true
+	case "off":
+		session.devMode = false
+	default:
+		session.devMode = !session.devMode
+	}
+	enabled := session.devMode
+	session.mu.Unlock()
+
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	body := tr(lang, "devmode_off")
+	if enabled {
+		body = tr(lang, "devmode_on")
+	}
+	_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), body, &tele.SendOptions{DisableWebPagePreview: true})
+	return err
+}
@@ -0,0 +1,26 @@
+package app
+
+import "testing"
+
+func TestLooksLikeError(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want bool
+	}{
+		{"python", "Traceback (most recent call last):\n  File \"app.py\", line 3, in <module>\n    main()\nKeyError: 'id'", true},
+		{"go panic", "panic: runtime error: index out of range [3] with length 3\n\ngoroutine 1 [running]:\nmain.main()\n\t/tmp/x.go:8 +0x1d", true},
+		{"java", "java.lang.NullPointerException\n\tat com.example.App.run(App.java:42)\n\tat com.example.App.main(App.java:10)", true},
+		{"go compiler", "./main.go:12:2: undefined: foo", true},
+		{"typescript", "src/index.ts:4:7 - error TS2322: Type 'string' is not assignable to type 'number'.", true},
+		{"rust", "error[E0382]: borrow of moved value: `v`\n --> src/main.rs:4:20", true},
+		{"question", "Why does my Python script raise a KeyError when the key exists?", false},
+		{"version", "I upgraded to node 20.11 and go 1.22:1 looks fine", false},
+		{"plain", "What is the capital of France?", false},
+	}
+	for _, tt := range tests {
+		if got := looksLikeError(tt.text); got != tt.want {
+			t.Errorf("%s: looksLikeError = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
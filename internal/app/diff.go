@@ -0,0 +1,164 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	diffMaxUnits   = 200
+	diffMaxItems   = 3
+	diffSnippetLen = 60
+)
+
+// diffSentenceSplitter only breaks on terminators followed by whitespace so decimals stay intact.
+var diffSentenceSplitter = regexp.MustCompile(`[.!?]+(?:\s+|$)|\n+`)
+
+type diffOp int
+
+const (
+	diffEqual diffOp = iota
+	diffDelete
+	diffInsert
+)
+
+type diffEdit struct {
+	op   diffOp
+	text string
+}
+
+// summarizeReplyDiff compares two replies sentence by sentence and returns a compact
+// "changed: ...; added: ...; removed: ..." line, or "" when nothing differs.
+func summarizeReplyDiff(lang language, previous, current string) string {
+	changed, added, removed := diffReplies(previous, current)
+	var sections []string
+	if s := joinDiffItems(lang, "diff_changed", changed); s != "" {
+		sections = append(sections, s)
+	}
+	if s := joinDiffItems(lang, "diff_added", added); s != "" {
+		sections = append(sections, s)
+	}
+	if s := joinDiffItems(lang, "diff_removed", removed); s != "" {
+		sections = append(sections, s)
+	}
+	return strings.Join(sections, "; ")
+}
+
+// countReplyDiff is the one-line version of summarizeReplyDiff a regenerated
+// reply carries: how many sentences changed, were added and were removed.
+func countReplyDiff(lang language, previous, current string) string {
+	changed, added, removed := diffReplies(previous, current)
+	if len(changed)+len(added)+len(removed) == 0 {
+		return tr(lang, "compare_none")
+	}
+	return tr(lang, "diff_counts", len(changed), len(added), len(removed))
+}
+
+// diffReplies lists the quoted sentences that changed, were added and were
+// removed between two replies.
+func diffReplies(previous, current string) (changed, added, removed []string) {
+	before := diffUnits(previous)
+	after := diffUnits(current)
+	edits := diffSequences(before, after)
+
+	for i := 0; i < len(edits); i++ {
+		e := edits[i]
+		switch e.op {
+		case diffDelete:
+			if i+1 < len(edits) && edits[i+1].op == diffInsert {
+				changed = append(changed, fmt.Sprintf("%q → %q", clipSnippet(e.text), clipSnippet(edits[i+1].text)))
+				i++
+				continue
+			}
+			removed = append(removed, fmt.Sprintf("%q", clipSnippet(e.text)))
+		case diffInsert:
+			added = append(added, fmt.Sprintf("%q", clipSnippet(e.text)))
+		}
+	}
+	return changed, added, removed
+}
+
+func diffUnits(text string) []string {
+	var units []string
+	for _, chunk := range diffSentenceSplitter.Split(text, -1) {
+		chunk = strings.Join(strings.Fields(chunk), " ")
+		if chunk == "" {
+			continue
+		}
+		units = append(units, chunk)
+		if len(units) == diffMaxUnits {
+			break
+		}
+	}
+	return units
+}
+
+// diffSequences returns the edit script turning a into b using a longest common subsequence table.
+func diffSequences(a, b []string) []diffEdit {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if strings.EqualFold(a[i], b[j]) {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var edits []diffEdit
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case strings.EqualFold(a[i], b[j]):
+			edits = append(edits, diffEdit{op: diffEqual, text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, diffEdit{op: diffDelete, text: a[i]})
+			i++
+		default:
+			edits = append(edits, diffEdit{op: diffInsert, text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		edits = append(edits, diffEdit{op: diffDelete, text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		edits = append(edits, diffEdit{op: diffInsert, text: b[j]})
+	}
+	return edits
+}
+
+// joinDiffItems renders one section of the summary with the catalog entry key,
+// listing at most diffMaxItems items.
+func joinDiffItems(lang language, key string, items []string) string {
+	if len(items) == 0 {
+		return ""
+	}
+	extra := 0
+	if len(items) > diffMaxItems {
+		extra = len(items) - diffMaxItems
+		items = items[:diffMaxItems]
+	}
+	out := tr(lang, key, strings.Join(items, ", "))
+	if extra > 0 {
+		out += " " + tr(lang, "diff_more", extra)
+	}
+	return out
+}
+
+func clipSnippet(text string) string {
+	runes := []rune(text)
+	if len(runes) <= diffSnippetLen {
+		return text
+	}
+	return string(runes[:diffSnippetLen-1]) + "…"
+}
@@ -0,0 +1,56 @@
+package app
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSummarizeReplyDiff(t *testing.T) {
+	tests := []struct {
+		name, previous, current, want string
+	}{
+		{"same", "It costs 3.50 euros. Pay by card.", "it costs 3.50  euros! Pay by card", ""},
+		{"changed", "Rome is the capital. It is old.", "Rome is the capital. It is ancient.", `changed: "It is old" → "It is ancient"`},
+		{"added", "First.", "First. Second.", `added: "Second"`},
+		{"removed", "First.\nSecond.", "Second.", `removed: "First"`},
+		{
+			"all kinds", "Intro. Keep. Old one. End.", "Keep. Old two. End. Outro.",
+			`changed: "Old one" → "Old two"; added: "Outro"; removed: "Intro"`,
+		},
+	}
+	for _, tt := range tests {
+		if got := summarizeReplyDiff(defaultLanguage, tt.previous, tt.current); got != tt.want {
+			t.Errorf("%s: summarizeReplyDiff = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSummarizeReplyDiffLimits(t *testing.T) {
+	got := summarizeReplyDiff(defaultLanguage, "", "One. Two. Three. Four. Five.")
+	if want := `added: "One", "Two", "Three" (+2 more)`; got != want {
+		t.Errorf("summarizeReplyDiff = %q, want %q", got, want)
+	}
+
+	long := strings.Repeat("é", diffSnippetLen+10)
+	got = summarizeReplyDiff(defaultLanguage, "", long)
+	if want := `added: "` + strings.Repeat("é", diffSnippetLen-1) + `…"`; got != want {
+		t.Errorf("long sentence: summarizeReplyDiff = %q", got)
+	}
+
+	if got, want := summarizeReplyDiff("de", "", "One. Two. Three. Four."), `hinzugefügt: "One", "Two", "Three" (+1 weitere)`; got != want {
+		t.Errorf("German: summarizeReplyDiff = %q, want %q", got, want)
+	}
+
+	if units := diffUnits(strings.Repeat("x. ", diffMaxUnits+5)); len(units) != diffMaxUnits {
+		t.Errorf("diffUnits kept %d units, want %d", len(units), diffMaxUnits)
+	}
+}
+
+func TestCountReplyDiff(t *testing.T) {
+	if got, want := countReplyDiff(defaultLanguage, "Keep. Old.", "Keep. New. More."), "Compared with the previous answer: 1 sentences changed, 1 added, 0 removed."; got != want {
+		t.Errorf("countReplyDiff = %q, want %q", got, want)
+	}
+	if got := countReplyDiff("uk", "Same.", "same"); got != tr("uk", "compare_none") {
+		t.Errorf("unchanged: countReplyDiff = %q", got)
+	}
+}
@@ -0,0 +1,146 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// maxDigestsPerUser bounds the recurring digests of a user across chats.
+const maxDigestsPerUser = 10
+
+// digestInstruction is added to the prompt of a digest when it runs, with
+// Google Search enabled so the answer reflects the day's sources.
+const digestInstruction = "This is a scheduled digest the user subscribed to, not a live conversation. " +
+	"Search the web for current information and answer with the latest items only, each with its date and source link. " +
+	"Start with a one-line title naming the digest and today's date, keep it compact, and do not ask follow-up questions."
+
+var digestWeekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// parseDigestSchedule reads "daily|weekdays|weekends|mon,thu HH:MM" from the
+// start of payload and returns the schedule and the rest as the prompt.
+func parseDigestSchedule(payload string) (*reminderRepeat, string, error) {
+	fields := strings.Fields(payload)
+	if len(fields) < 3 {
+		return nil, "", errors.New("missing schedule, time or prompt")
+	}
+	repeat := &reminderRepeat{}
+	switch days := strings.ToLower(fields[0]); days {
+	case "daily", "everyday":
+	case "weekdays":
+		repeat.Days = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	case "weekends":
+		repeat.Days = []time.Weekday{time.Saturday, time.Sunday}
+	default:
+		for _, name := range strings.Split(days, ",") {
+			day, ok := digestWeekdays[name[:min(len(name), 3)]]
+			if !ok {
+				return nil, "", fmt.Errorf("unknown day %q", name)
+			}
+			if !slices.Contains(repeat.Days, day) {
+				repeat.Days = append(repeat.Days, day)
+			}
+		}
+		slices.Sort(repeat.Days)
+	}
+	m := reminderClock.FindStringSubmatch(fields[1])
+	if m == nil {
+		return nil, "", fmt.Errorf("bad time %q", fields[1])
+	}
+	repeat.Hour, _ = strconv.Atoi(m[1])
+	repeat.Minute, _ = strconv.Atoi(m[2])
+	return repeat, strings.Join(fields[2:], " "), nil
+}
+
+// describe renders the schedule as "daily 08:00" or "mon,thu 08:00".
+func (r *reminderRepeat) describe() string {
+	days := "daily"
+	if len(r.Days) > 0 {
+		names := make([]string, len(r.Days))
+		for i, d := range r.Days {
+			names[i] = strings.ToLower(d.String()[:3])
+		}
+		days = strings.Join(names, ",")
+	}
+	return fmt.Sprintf("%s %02d:%02d", days, r.Hour, r.Minute)
+}
+
+// handleDigest subscribes to, lists and cancels recurring digests, prompts the
+// scheduler answers with web search at a time of day:
+//
+//	/digest <daily|weekdays|weekends|mon,thu> <HH:MM> <prompt>
+//	/digest                 lists the sender's digests in this chat
+//	/digest cancel <id>     cancels one
+//
+// Digests use the chat's time zone from /remind tz.
+func (a *App) handleDigest(c tele.Context) error {
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	thread := messageTopic(c.Message())
+	reply := func(body string) error {
+		_, err := a.sendWithFallback(c.Chat(), body, &tele.SendOptions{ThreadID: thread, DisableWebPagePreview: true})
+		return err
+	}
+	userID := userIDOf(c.Sender())
+	chatID := c.Chat().ID
+	loc := a.reminders.zone(chatID)
+	payload := strings.TrimSpace(c.Message().Payload)
+	command, arg, _ := strings.Cut(payload, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch strings.ToLower(command) {
+	case "":
+		list := a.reminders.pending(chatID, userID, true)
+		if len(list) == 0 {
+			return reply(tr(lang, "digest_none"))
+		}
+		lines := make([]string, 0, len(list))
+		for _, r := range list {
+			lines = append(lines, fmt.Sprintf("#%d · %s · %s", r.ID, r.Repeat.describe(), r.Text))
+		}
+		return reply(tr(lang, "digest_list", loc.String(), strings.Join(lines, "\n")))
+	case "cancel":
+		id, err := strconv.ParseInt(strings.TrimPrefix(arg, "#"), 10, 64)
+		if err != nil {
+			return reply(tr(lang, "digest_usage"))
+		}
+		ok, err := a.reminders.cancel(chatID, userID, id)
+		if err != nil {
+			a.chatLog(chatID).Warn("save reminders failed", "chat_id", chatID, "err", err)
+		}
+		if !ok {
+			return reply(tr(lang, "digest_unknown", id))
+		}
+		return reply(tr(lang, "digest_cancelled", id))
+	}
+
+	repeat, prompt, err := parseDigestSchedule(payload)
+	if err != nil || strings.TrimSpace(prompt) == "" {
+		return reply(tr(lang, "digest_usage"))
+	}
+	r := reminder{
+		ChatID:   chatID,
+		ChatType: string(c.Chat().Type),
+		ThreadID: thread,
+		UserID:   userID,
+		Due:      repeat.next(time.Now(), loc),
+		Text:     prompt,
+		Ask:      true,
+		Repeat:   repeat,
+	}
+	r, err = a.reminders.add(r)
+	switch {
+	case errors.Is(err, errTooManyReminders):
+		return reply(tr(lang, "digest_too_many", maxDigestsPerUser))
+	case err != nil:
+		a.chatLog(chatID).Warn("save reminders failed", "chat_id", chatID, "err", err)
+	}
+	return reply(tr(lang, "digest_set", r.ID, repeat.describe(), loc.String(), r.Due.In(loc).Format("2006-01-02 15:04 MST")))
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestReminderRepeatNext(t *testing.T) {
+	// Sunday 2025-06-01 20:30 UTC.
+	now := time.Date(2025, 6, 1, 20, 30, 0, 0, time.UTC)
+	tests := []struct {
+		payload string
+		next    string
+		prompt  string
+	}{
+		{"daily 8:00 summarize HN", "2025-06-02 08:00", "summarize HN"},
+		{"daily 21:00 evening news", "2025-06-01 21:00", "evening news"},
+		{"weekdays 7.30 markets", "2025-06-02 07:30", "markets"},
+		{"weekends 10:00 football", "2025-06-07 10:00", "football"},
+		{"thu,mon 08:00 releases", "2025-06-02 08:00", "releases"},
+		{"sunday 20:00 week ahead", "2025-06-08 20:00", "week ahead"},
+	}
+	for _, tt := range tests {
+		repeat, prompt, err := parseDigestSchedule(tt.payload)
+		if err != nil {
+			t.Errorf("%q: %v", tt.payload, err)
+			continue
+		}
+		if got := repeat.next(now, time.UTC).Format("2006-01-02 15:04"); got != tt.next || prompt != tt.prompt {
+			t.Errorf("%q = %s %q, want %s %q", tt.payload, got, prompt, tt.next, tt.prompt)
+		}
+	}
+	for _, bad := range []string{"daily summarize", "often 8:00 x", "mon,funday 8:00 x", "daily 8:00"} {
+		if _, _, err := parseDigestSchedule(bad); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
+
+func TestDigestRunsWithSearchAndRepeats(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.reply = "Top stories today."
+	digest := func(payload string) string {
+		t.Helper()
+		msg := testMessage(42, "/digest "+payload)
+		msg.Payload = payload
+		if err := app.handleDigest(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("handleDigest(%q): %v", payload, err)
+		}
+		texts := apis.sentTexts()
+		return texts[len(texts)-1]
+	}
+
+	if got := digest("daily 8:00 summarize the Hacker News front page"); !strings.Contains(got, "scheduled daily 08:00") {
+		t.Fatalf("subscribe answered %q", got)
+	}
+	if got := digest("whenever summarize"); !strings.Contains(got, "Usage") {
+		t.Errorf("bad schedule answered %q", got)
+	}
+	if got := digest(""); !strings.Contains(got, "Hacker News front page") {
+		t.Errorf("list answered %q", got)
+	}
+	if n := len(app.reminders.pending(42, 42, false)); n != 0 {
+		t.Errorf("digest listed among %d reminders", n)
+	}
+
+	first := app.reminders.pending(42, 42, true)[0].Due
+	app.fireDueReminders(first)
+	app.queue.drain(context.Background())
+	app.queue.resume()
+	calls := apis.callsTo(geminiHost, ":generateContent")
+	if len(calls) == 0 {
+		t.Fatal("digest was not answered")
+	}
+	body := string(calls[len(calls)-1].body)
+	if !strings.Contains(body, "Hacker News front page") || !strings.Contains(body, "googleSearch") || !strings.Contains(body, "scheduled digest") {
+		t.Errorf("digest request lacks prompt, search or instruction: %s", body)
+	}
+	if texts := apis.sentTexts(); !strings.Contains(texts[len(texts)-1], "Top stories today") {
+		t.Errorf("digest delivered %q", texts[len(texts)-1])
+	}
+
+	list := app.reminders.pending(42, 42, true)
+	if len(list) != 1 || !list[0].Due.Equal(first.Add(24*time.Hour)) {
+		t.Fatalf("digest after firing = %+v, want it moved to the next day", list)
+	}
+	if got := digest("cancel 1"); !strings.Contains(got, "1 cancelled") {
+		t.Errorf("cancel answered %q", got)
+	}
+}
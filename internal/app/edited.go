@@ -0,0 +1,41 @@
+package app
+
+import (
+	"errors"
+	"strings"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// handleEdited re-answers the latest prompt of a chat when its author edits it.
+// Edits of older messages and of commands are ignored: the answers after them
+// built on the original wording.
+func (a *App) handleEdited(c tele.Context) error {
+	msg := c.Message()
+	if msg == nil || msg.Chat == nil || strings.HasPrefix(msg.Text, "/") {
+		return nil
+	}
+	session := a.sessionOf(msg)
+	session.mu.Lock()
+	latest := session.lastTurn.promptID == msg.ID
+	session.mu.Unlock()
+	if !latest {
+		return nil
+	}
+	return a.enqueueTurn(msg, turnOptions{edited: true})
+}
+
+// replaceReply edits the earlier reply replyID to text, sending a new message
+// when Telegram no longer lets the bot edit it.
+func (a *App) replaceReply(chat *tele.Chat, replyID int, text string, opts *tele.SendOptions) (*tele.Message, error) {
+	previous := &tele.Message{ID: replyID, Chat: chat}
+	edited, err := a.editWithFallback(previous, text, opts)
+	switch {
+	case err == nil:
+		return edited, nil
+	case errors.Is(err, tele.ErrSameMessageContent), errors.Is(err, tele.ErrMessageNotModified):
+		return previous, nil
+	}
+	a.chatLog(chat.ID).Warn("editing the previous reply failed, sending a new one", "chat_id", chat.ID, "message_id", replyID, "err", err)
+	return a.sendWithFallback(chat, text, opts)
+}
@@ -0,0 +1,46 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestEditedPromptReplacesTheAnswer(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.reply = "Paris."
+	if err := app.processMessage(context.Background(), testMessage(42, "Capital of France?"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+
+	apis.reply = "Berlin."
+	edited := testMessage(42, "Capital of Germany?")
+	if err := app.processMessage(context.Background(), edited, turnOptions{edited: true}); err != nil {
+		t.Fatalf("processMessage of the edit: %v", err)
+	}
+	if sends := apis.callsTo(telegramHost, "sendMessage"); len(sends) != 1 {
+		t.Errorf("sent %d messages, want the first reply only", len(sends))
+	}
+	if edits := apis.callsTo(telegramHost, "editMessageText"); len(edits) != 1 {
+		t.Fatalf("edited %d messages, want the reply edited in place", len(edits))
+	}
+
+	session := app.sessions.get(42)
+	if len(session.history) != 2 || contentText(session.history[0]) != "Capital of Germany?" || contentText(session.history[1]) != "Berlin." {
+		var texts []string
+		for _, c := range session.history {
+			texts = append(texts, contentText(c))
+		}
+		t.Errorf("history = %q, want only the edited turn", texts)
+	}
+
+	older := testMessage(42, "Capital of Spain?")
+	older.ID = 0
+	if err := app.handleEdited(app.bot.NewContext(tele.Update{EditedMessage: older})); err != nil {
+		t.Fatalf("handleEdited: %v", err)
+	}
+	if busy, pending := app.queue.stats(); busy+pending != 0 {
+		t.Error("an edit of an older message was queued")
+	}
+}
@@ -0,0 +1,271 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	ephemeralMinTTL = time.Minute
+	// ephemeralMaxTTL stays well within the 48 hours in which Telegram lets
+	// bots delete messages.
+	ephemeralMaxTTL = 24 * time.Hour
+	// ephemeralBatch is how many messages one deleteMessages call takes.
+	ephemeralBatch = 100
+)
+
+// ephemeralSettings is the ephemeral mode of a chat: the bot deletes its
+// messages TTL after sending them and, with Prompts, the messages users
+// address to it as well.
+type ephemeralSettings struct {
+	TTL     time.Duration `json:"ttl"`
+	Prompts bool          `json:"prompts,omitempty"`
+}
+
+// ephemeralMessage is a message waiting for deletion.
+type ephemeralMessage struct {
+	ChatID    int64     `json:"chat_id"`
+	MessageID int       `json:"message_id"`
+	Due       time.Time `json:"due"`
+}
+
+type ephemeralData struct {
+	Chats   map[int64]ephemeralSettings `json:"chats"`
+	Pending []ephemeralMessage          `json:"pending,omitempty"`
+}
+
+// ephemeralStore keeps the ephemeral chats and the messages waiting for
+// deletion across restarts in a JSON file. Every change rewrites the file
+// atomically.
+type ephemeralStore struct {
+	mu   sync.Mutex
+	path string
+	data ephemeralData
+}
+
+func openEphemeralStore(path string) (*ephemeralStore, error) {
+	s := &ephemeralStore{path: path, data: ephemeralData{Chats: make(map[int64]ephemeralSettings)}}
+	if err := readJSONFile(path, &s.data); err != nil {
+		return nil, err
+	}
+	if s.data.Chats == nil {
+		s.data.Chats = make(map[int64]ephemeralSettings)
+	}
+	return s, nil
+}
+
+// save writes the store; the caller holds s.mu.
+func (s *ephemeralStore) save() error {
+	return writeJSONFile(s.path, s.data)
+}
+
+func (s *ephemeralStore) settings(chatID int64) (ephemeralSettings, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	settings, ok := s.data.Chats[chatID]
+	return settings, ok
+}
+
+// set turns ephemeral mode of chatID on with settings, or off when settings
+// is nil. Messages already waiting are deleted either way.
+func (s *ephemeralStore) set(chatID int64, settings *ephemeralSettings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if settings == nil {
+		delete(s.data.Chats, chatID)
+	} else {
+		s.data.Chats[chatID] = *settings
+	}
+	return s.save()
+}
+
+// schedule queues a message of chatID sent at for deletion, when the chat is
+// ephemeral; a prompt only when the chat deletes prompts too. It reports
+// whether it did.
+func (s *ephemeralStore) schedule(chatID int64, messageID int, at time.Time, prompt bool) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	settings, ok := s.data.Chats[chatID]
+	if !ok || (prompt && !settings.Prompts) {
+		return false, nil
+	}
+	s.data.Pending = append(s.data.Pending, ephemeralMessage{ChatID: chatID, MessageID: messageID, Due: at.Add(settings.TTL)})
+	return true, s.save()
+}
+
+// takeDue removes and returns the messages due for deletion at now.
+func (s *ephemeralStore) takeDue(now time.Time) ([]ephemeralMessage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due, kept []ephemeralMessage
+	for _, m := range s.data.Pending {
+		if m.Due.After(now) {
+			kept = append(kept, m)
+		} else {
+			due = append(due, m)
+		}
+	}
+	if len(due) == 0 {
+		return nil, nil
+	}
+	s.data.Pending = kept
+	return due, s.save()
+}
+
+// migrate moves the ephemeral mode and the waiting messages of chat from to
+// chat to. The messages keep their IDs in the supergroup.
+func (s *ephemeralStore) migrate(from, to int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := false
+	if settings, ok := s.data.Chats[from]; ok {
+		delete(s.data.Chats, from)
+		s.data.Chats[to] = settings
+		changed = true
+	}
+	for i := range s.data.Pending {
+		if s.data.Pending[i].ChatID == from {
+			s.data.Pending[i].ChatID = to
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+	return s.save()
+}
+
+// purgeChat forgets the ephemeral mode and the waiting messages of chatID.
+func (s *ephemeralStore) purgeChat(chatID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, changed := s.data.Chats[chatID]
+	delete(s.data.Chats, chatID)
+	kept := s.data.Pending[:0]
+	for _, m := range s.data.Pending {
+		if m.ChatID != chatID {
+			kept = append(kept, m)
+		}
+	}
+	changed = changed || len(kept) != len(s.data.Pending)
+	s.data.Pending = kept
+	if !changed {
+		return nil
+	}
+	return s.save()
+}
+
+// scheduleDeletion queues a message sent to or by the bot for deletion when
+// its chat is ephemeral.
+func (a *App) scheduleDeletion(msg *tele.Message, prompt bool) {
+	if msg == nil || msg.Chat == nil {
+		return
+	}
+	if _, err := a.ephemeral.schedule(msg.Chat.ID, msg.ID, time.Now(), prompt); err != nil {
+		a.chatLog(msg.Chat.ID).Warn("save ephemeral messages failed", "chat_id", msg.Chat.ID, "err", err)
+	}
+}
+
+// ephemeralMiddleware queues the messages users address to the bot for
+// deletion in chats that delete prompts.
+func (a *App) ephemeralMiddleware(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		if msg := c.Update().Message; msg != nil && msg.Chat != nil && a.addressedToBot(msg) {
+			a.scheduleDeletion(msg, true)
+		}
+		return next(c)
+	}
+}
+
+// deleteDueMessages deletes the messages of ephemeral chats whose time is up.
+func (a *App) deleteDueMessages(now time.Time) {
+	due, err := a.ephemeral.takeDue(now)
+	if err != nil {
+		slog.Warn("save ephemeral messages failed", "err", err)
+	}
+	byChat := make(map[int64][]tele.Editable)
+	for _, m := range due {
+		byChat[m.ChatID] = append(byChat[m.ChatID], tele.StoredMessage{MessageID: strconv.Itoa(m.MessageID), ChatID: m.ChatID})
+	}
+	for chatID, messages := range byChat {
+		if a.operator.isInactive(chatID) {
+			continue
+		}
+		for start := 0; start < len(messages); start += ephemeralBatch {
+			batch := messages[start:min(start+ephemeralBatch, len(messages))]
+			if err := a.bot.DeleteMany(batch); err != nil {
+				a.telegramFailed("delete messages", chatID, err)
+			}
+		}
+	}
+}
+
+// handleEphemeral shows or switches ephemeral mode of the chat:
+//
+//	/ephemeral <ttl> [prompts]   deletes the bot's messages after ttl, such as 10m
+//	                             or 2h, and with prompts the users' messages to it
+//	/ephemeral off               keeps messages again
+//
+// In groups only admins may switch it, and deleting prompts needs the bot to
+// be allowed to delete messages.
+func (a *App) handleEphemeral(c tele.Context) error {
+	chat := c.Chat()
+	lang := a.chatLanguage(chat, c.Sender())
+	reply := func(body string) error {
+		_, err := a.sendWithFallback(chat, body, &tele.SendOptions{ThreadID: messageTopic(c.Message()), DisableWebPagePreview: true})
+		return err
+	}
+	fields := strings.Fields(strings.ToLower(c.Message().Payload))
+	if len(fields) == 0 {
+		state := tr(lang, "ephemeral_is_off")
+		if settings, ok := a.ephemeral.settings(chat.ID); ok {
+			state = ephemeralDescription(lang, settings)
+		}
+		return reply(state + "\n\n" + tr(lang, "ephemeral_usage", formatTTL(ephemeralMaxTTL)))
+	}
+	var settings *ephemeralSettings
+	if fields[0] != "off" {
+		ttl, err := time.ParseDuration(fields[0])
+		if err != nil || ttl < ephemeralMinTTL || ttl > ephemeralMaxTTL || len(fields) > 2 || (len(fields) == 2 && fields[1] != "prompts") {
+			return reply(tr(lang, "ephemeral_usage", formatTTL(ephemeralMaxTTL)))
+		}
+		settings = &ephemeralSettings{TTL: ttl.Round(time.Second), Prompts: len(fields) == 2}
+	}
+	if isGroupChat(chat) && !a.isChatAdmin(c) {
+		return reply(tr(lang, "ephemeral_admins"))
+	}
+	if err := a.ephemeral.set(chat.ID, settings); err != nil {
+		a.chatLog(chat.ID).Warn("save ephemeral mode failed", "chat_id", chat.ID, "err", err)
+		return reply(tr(lang, "ephemeral_failed"))
+	}
+	if settings == nil {
+		return reply(tr(lang, "ephemeral_off"))
+	}
+	// The command itself goes too, now that the chat deletes prompts.
+	a.scheduleDeletion(c.Message(), true)
+	return reply(ephemeralDescription(lang, *settings))
+}
+
+func ephemeralDescription(lang language, settings ephemeralSettings) string {
+	if settings.Prompts {
+		return tr(lang, "ephemeral_prompts", formatTTL(settings.TTL))
+	}
+	return tr(lang, "ephemeral_on", formatTTL(settings.TTL))
+}
+
+// formatTTL renders d as "90m", "2h" or "45s", whichever unit divides it.
+func formatTTL(d time.Duration) string {
+	switch {
+	case d%time.Hour == 0:
+		return fmt.Sprintf("%dh", d/time.Hour)
+	case d%time.Minute == 0:
+		return fmt.Sprintf("%dm", d/time.Minute)
+	}
+	return fmt.Sprintf("%ds", d/time.Second)
+}
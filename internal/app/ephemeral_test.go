@@ -0,0 +1,91 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestEphemeralModeDeletesMessages(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.reply = "Gone soon."
+	ephemeral := func(payload string) string {
+		t.Helper()
+		msg := testMessage(42, "/ephemeral "+payload)
+		msg.ID = 5
+		msg.Payload = payload
+		if err := app.handleEphemeral(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("handleEphemeral(%q): %v", payload, err)
+		}
+		texts := apis.sentTexts()
+		return texts[len(texts)-1]
+	}
+
+	if got := ephemeral("3d"); !strings.Contains(got, "up to 24h") {
+		t.Errorf("/ephemeral 3d answered %q, want the usage", got)
+	}
+	if got := ephemeral("10m prompts"); !strings.Contains(got, "messages addressed to me 10m after") {
+		t.Errorf("/ephemeral 10m prompts answered %q", got)
+	}
+	prompt := testMessage(42, "Hi")
+	prompt.ID = 6
+	next := func(c tele.Context) error {
+		return app.processMessage(context.Background(), c.Message(), turnOptions{})
+	}
+	if err := app.ephemeralMiddleware(next)(app.bot.NewContext(tele.Update{Message: prompt})); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	if reopened, err := openEphemeralStore(filepath.Join(app.dataDir, "ephemeral.json")); err != nil {
+		t.Fatalf("openEphemeralStore: %v", err)
+	} else if settings, ok := reopened.settings(42); !ok || settings.TTL != 10*time.Minute || !settings.Prompts {
+		t.Errorf("saved settings = %+v, %v", settings, ok)
+	}
+
+	app.deleteDueMessages(time.Now())
+	if calls := apis.callsTo(telegramHost, "deleteMessages"); len(calls) != 0 {
+		t.Fatalf("deleted %d times before the time was up", len(calls))
+	}
+	app.deleteDueMessages(time.Now().Add(11 * time.Minute))
+	calls := apis.callsTo(telegramHost, "deleteMessages")
+	if len(calls) != 1 {
+		t.Fatalf("deleteMessages called %d times, want 1", len(calls))
+	}
+	var params struct {
+		MessageIDs string `json:"message_ids"`
+	}
+	var ids []string
+	if err := json.Unmarshal(calls[0].body, &params); err != nil || json.Unmarshal([]byte(params.MessageIDs), &ids) != nil {
+		t.Fatalf("decode %s: %v", calls[0].body, err)
+	}
+	slices.Sort(ids)
+	// The usage reply came before ephemeral mode was on; the command, its
+	// confirmation, the prompt and the answer go.
+	if want := []string{"1002", "1003", "5", "6"}; !slices.Equal(ids, want) {
+		t.Errorf("deleted messages %v, want %v", ids, want)
+	}
+	app.deleteDueMessages(time.Now().Add(time.Hour))
+	if calls := apis.callsTo(telegramHost, "deleteMessages"); len(calls) != 1 {
+		t.Errorf("deleted the same messages again: %d calls", len(calls))
+	}
+
+	ephemeral("off")
+	app.scheduleDeletion(prompt, true)
+	app.deleteDueMessages(time.Now().Add(time.Hour))
+	if calls := apis.callsTo(telegramHost, "deleteMessages"); len(calls) != 1 {
+		t.Errorf("deleted messages after /ephemeral off: %d calls", len(calls))
+	}
+}
+
+func TestFormatTTL(t *testing.T) {
+	for d, want := range map[time.Duration]string{2 * time.Hour: "2h", 90 * time.Minute: "90m", 75 * time.Second: "75s"} {
+		if got := formatTTL(d); got != want {
+			t.Errorf("formatTTL(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	exportObsidianUnique = "export_obsidian"
+	exportTitleLimit     = 60
+)
+
+// exportTitle derives a short human title for an exported answer from its prompt.
+func exportTitle(art *responseArtifacts) string {
+	title := strings.TrimSpace(strings.SplitN(art.Prompt, "\n", 2)[0])
+	if title == "" {
+		title = "Eteon answer"
+	}
+	if utf8.RuneCountInString(title) > exportTitleLimit {
+		title = string([]rune(title)[:exportTitleLimit-1]) + "…"
+	}
+	return title
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^\p{L}\p{N}]+`)
+
+// exportFileName turns a title into a portable, lowercase, dash-separated file name.
+func exportFileName(title, ext string) string {
+	name := strings.Trim(unsafeFileChars.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if name == "" {
+		name = "eteon-answer"
+	}
+	if utf8.RuneCountInString(name) > 80 {
+		name = strings.TrimRight(string([]rune(name)[:80]), "-")
+	}
+	return name + ext
+}
+
+// obsidianNote renders an answer as Markdown with YAML front matter and a sources section.
+func obsidianNote(art *responseArtifacts) string {
+	created := art.CreatedAt
+	if created.IsZero() {
+		created = time.Now()
+	}
+
+	var b strings.Builder
+	b.WriteString("---\n")
+	fmt.Fprintf(&b, "title: %q\n", exportTitle(art))
+	fmt.Fprintf(&b, "created: %s\n", created.Format(time.RFC3339))
+	b.WriteString("source: eteon\n")
+	b.WriteString("tags: [eteon]\n")
+	b.WriteString("---\n\n")
+
+	if prompt := strings.TrimSpace(art.Prompt); prompt != "" {
+		b.WriteString("## Question\n\n")
+		for _, line := range strings.Split(prompt, "\n") {
+			b.WriteString("> ")
+			b.WriteString(line)
+			b.WriteString("\n")
+		}
+		b.WriteString("\n## Answer\n\n")
+	}
+	b.WriteString(strings.TrimSpace(art.Reply))
+	b.WriteString("\n")
+
+	if len(art.Sources) > 0 {
+		b.WriteString("\n## Sources\n\n")
+		for _, src := range art.Sources {
+			title := src.Title
+			if title == "" {
+				title = src.URI
+			}
+			fmt.Fprintf(&b, "- [%s](%s)\n", strings.ReplaceAll(title, "]", "\\]"), src.URI)
+		}
+	}
+	return b.String()
+}
+
+// sendTextDocument delivers content as a file attachment; opts are passed on
+// to the send, such as the topic to post in.
+func (a *App) sendTextDocument(recipient tele.Recipient, fileName, mimeType, content, caption string, opts ...any) error {
+	doc := &tele.Document{
+		File:     tele.FromReader(strings.NewReader(content)),
+		FileName: fileName,
+		MIME:     mimeType,
+		Caption:  caption,
+	}
+	_, err := a.botSend(recipient, doc, opts...)
+	return err
+}
+
+func (a *App) handleExportObsidian(c tele.Context) error {
+	if err := c.Respond(); err != nil {
+		slog.Warn("callback acknowledge failed", "err", err)
+	}
+	art, ok := a.artifacts.get(c.Callback().Data)
+	if !ok || strings.TrimSpace(art.Reply) == "" {
+		_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), tr(a.chatLanguage(c.Chat(), c.Sender()), "export_gone"), &tele.SendOptions{DisableWebPagePreview: true})
+		return err
+	}
+	note := obsidianNote(art)
+	name := a.exportName(context.Background(), c.Chat().ID, exportTitle(art), ".md", note)
+	return a.sendTextDocument(inTopic(c.Chat(), c.Message()), name, "text/markdown", note, "")
+}
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExportTitleAndFileName(t *testing.T) {
+	tests := []struct {
+		prompt, title, file string
+	}{
+		{"How do I bake bread?\nWith rye, please.", "How do I bake bread?", "how-do-i-bake-bread.md"},
+		{"  ", "Eteon answer", "eteon-answer.md"},
+		{"Größe & Gewicht", "Größe & Gewicht", "größe-gewicht.md"},
+		{strings.Repeat("a", 100), strings.Repeat("a", exportTitleLimit-1) + "…", strings.Repeat("a", exportTitleLimit-1) + ".md"},
+	}
+	for _, tt := range tests {
+		title := exportTitle(&responseArtifacts{Prompt: tt.prompt})
+		if title != tt.title {
+			t.Errorf("exportTitle(%.20q) = %q, want %q", tt.prompt, title, tt.title)
+		}
+		if got := exportFileName(title, ".md"); got != tt.file {
+			t.Errorf("exportFileName(%q) = %q, want %q", title, got, tt.file)
+		}
+	}
+	if got := exportFileName("!!!", ".md"); got != "eteon-answer.md" {
+		t.Errorf("exportFileName of punctuation = %q", got)
+	}
+}
+
+func TestObsidianNote(t *testing.T) {
+	art := &responseArtifacts{
+		Prompt:    "Which tents?\nFor two people.",
+		Reply:     "  Take the light one.  ",
+		Sources:   []sourceRef{{Title: "Tents [2026]", URI: "https://example.com/tents"}, {URI: "https://example.com/raw"}},
+		CreatedAt: time.Date(2026, 5, 1, 8, 30, 0, 0, time.UTC),
+	}
+	want := `---
+title: "Which tents?"
+created: 2026-05-01T08:30:00Z
+source: eteon
+tags: [eteon]
+---
+
+## Question
+
+> Which tents?
+> For two people.
+
+## Answer
+
+Take the light one.
+
+## Sources
+
+- [Tents [2026\]](https://example.com/tents)
+- [https://example.com/raw](https://example.com/raw)
+`
+	if got := obsidianNote(art); got != want {
+		t.Errorf("note =\n%s\nwant\n%s", got, want)
+	}
+	if got := obsidianNote(&responseArtifacts{Reply: "Only this."}); strings.Contains(got, "## Question") || !strings.HasSuffix(got, "---\n\nOnly this.\n") {
+		t.Errorf("note without a prompt =\n%s", got)
+	}
+}
+
+func TestExportObsidianSendsTheNote(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.reply = "tent-buying-guide"
+	id := app.artifacts.put(&responseArtifacts{ChatID: 42, Prompt: "Which tents?", Reply: "Take the light one."})
+	if err := app.handleExportObsidian(actionCallback(app, 42, 7, id)); err != nil {
+		t.Fatalf("handleExportObsidian: %v", err)
+	}
+	docs := apis.callsTo(telegramHost, "sendDocument")
+	if len(docs) != 1 {
+		t.Fatalf("sent %d documents, want the note", len(docs))
+	}
+	if body := string(docs[0].body); !strings.Contains(body, "tent-buying-guide.md") || !strings.Contains(body, "Take the light one.") {
+		t.Errorf("document = %.300s", body)
+	}
+
+	if err := app.handleExportObsidian(actionCallback(app, 42, 7, "gone")); err != nil {
+		t.Fatalf("handleExportObsidian: %v", err)
+	}
+	if texts := apis.sentTexts(); !containsText(texts, "no longer available") {
+		t.Errorf("sent %q, want the expired notice", texts)
+	}
+}
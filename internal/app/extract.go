@@ -0,0 +1,235 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	extractChunkChars   = 30000
+	extractMaxChars     = 600000
+	extractMaxFileBytes = 20 << 20
+	extractCacheTTL     = time.Hour
+	extractCacheEntries = 32
+)
+
+// errNoTextLayer reports a document whose text cannot be recovered locally, such
+// as a scanned PDF. The caller falls back to sending the file itself.
+var errNoTextLayer = errors.New("no usable text layer")
+
+type textExtractor struct {
+	format  string
+	extract func(data []byte) (string, error)
+	// verbatim keeps whitespace that carries meaning, such as the context
+	// lines of a patch, and the full text for later use.
+	verbatim bool
+}
+
+var textExtractors = map[string]textExtractor{
+	".pdf":   {format: "PDF", extract: extractPDFText},
+	".docx":  {format: "DOCX", extract: extractDOCXText},
+	".xlsx":  {format: "XLSX", extract: extractXLSXText},
+	".epub":  {format: "EPUB", extract: extractEPUBText},
+	".diff":  {format: "patch", extract: extractPlainText, verbatim: true},
+	".patch": {format: "patch", extract: extractPlainText, verbatim: true},
+}
+
+var extractorExtByMIME = map[string]string{
+	"application/pdf": ".pdf",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": ".docx",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":       ".xlsx",
+	"application/epub+zip": ".epub",
+	"text/x-diff":          ".diff",
+	"text/x-patch":         ".patch",
+}
+
+func extractorFor(doc *tele.Document) (textExtractor, bool) {
+	ext := strings.ToLower(filepath.Ext(doc.FileName))
+	if _, ok := textExtractors[ext]; !ok {
+		ext = extractorExtByMIME[strings.ToLower(doc.MIME)]
+	}
+	ex, ok := textExtractors[ext]
+	return ex, ok
+}
+
+// extractCache remembers extracted chunks by Telegram's stable file ID, so the
+// same document shared again or replied to is not parsed twice.
+type extractCache struct {
+	mu      sync.Mutex
+	entries map[string]extractEntry
+}
+
+type extractEntry struct {
+	chunks    []string
+	truncated bool
+	stored    time.Time
+	// source is the extracted text as is, kept for verbatim formats.
+	source string
+}
+
+func newExtractCache() *extractCache {
+	return &extractCache{entries: make(map[string]extractEntry)}
+}
+
+func (c *extractCache) get(key string) (extractEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if ok && time.Since(entry.stored) > extractCacheTTL {
+		delete(c.entries, key)
+		return extractEntry{}, false
+	}
+	return entry, ok
+}
+
+func (c *extractCache) put(key string, entry extractEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.entries) >= extractCacheEntries {
+		var oldest string
+		for k, e := range c.entries {
+			if oldest == "" || e.stored.Before(c.entries[oldest].stored) {
+				oldest = k
+			}
+		}
+		delete(c.entries, oldest)
+	}
+	entry.stored = time.Now()
+	c.entries[key] = entry
+}
+
+// documentParts converts supported documents to text chunks and leaves every
+// other document, or one without extractable text, to Gemini's own parsing.
+func (a *App) documentParts(ctx context.Context, doc *tele.Document) ([]*genai.Part, error) {
+	ex, ok := extractorFor(doc)
+	file := doc.MediaFile()
+	if !ok || file.FileSize > extractMaxFileBytes {
+		part, err := a.partFromFile(ctx, file, doc.MIME)
+		if err != nil {
+			return nil, err
+		}
+		return []*genai.Part{part}, nil
+	}
+
+	key := file.UniqueID
+	if key == "" {
+		key = file.FileID
+	}
+	entry, cached := a.extracts.get(key)
+	if !cached {
+		// A PDF without a text layer went to Gemini as a file before.
+		if part, ok := a.media.get(mediaKey(file, doc.MIME)); ok {
+			return []*genai.Part{part}, nil
+		}
+		reader, err := a.bot.File(file)
+		if err != nil {
+			return nil, fmt.Errorf("get file: %w", err)
+		}
+		data, err := io.ReadAll(io.LimitReader(reader, extractMaxFileBytes+1))
+		reader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("read file: %w", err)
+		}
+		if len(data) > extractMaxFileBytes {
+			// Telegram did not tell the size, and the file is larger than
+			// the bot may download: its text would be cut off unnoticed.
+			return nil, errFileTooLarge
+		}
+
+		text, err := ex.extract(data)
+		if err != nil {
+			if ex.format != "PDF" {
+				return nil, fmt.Errorf("extract %s: %w", ex.format, err)
+			}
+			part, err := a.blobPart(ctx, data, file, doc.MIME)
+			if err != nil {
+				return nil, err
+			}
+			if !isPrivate(ctx) {
+				a.media.put(mediaKey(file, doc.MIME), part)
+			}
+			return []*genai.Part{part}, nil
+		}
+		if ex.verbatim {
+			entry = chunkText(text)
+			entry.source = text
+		} else {
+			entry = chunkDocument(text)
+		}
+		if !isPrivate(ctx) {
+			a.extracts.put(key, entry)
+		}
+	}
+
+	name := doc.FileName
+	if name == "" {
+		name = "document"
+	}
+	header := fmt.Sprintf("[Text extracted from the %s document %q in %d part(s).", ex.format, name, len(entry.chunks))
+	if entry.truncated {
+		header += fmt.Sprintf(" The document is long, so only its first %d characters are included.", extractMaxChars)
+	}
+	parts := []*genai.Part{genai.NewPartFromText(header + "]")}
+	for i, chunk := range entry.chunks {
+		parts = append(parts, genai.NewPartFromText(fmt.Sprintf("[%s, part %d of %d]\n%s", name, i+1, len(entry.chunks), chunk)))
+	}
+	return parts, nil
+}
+
+// blobPart sends already downloaded bytes, uploading them when they exceed the inline limit.
+func (a *App) blobPart(ctx context.Context, data []byte, file *tele.File, explicitMIME string) (*genai.Part, error) {
+	if len(data) == 0 {
+		return nil, errors.New("empty media payload")
+	}
+	if a.inlineMediaLimit > 0 && int64(len(data)) > a.inlineMediaLimit {
+		return a.uploadPart(ctx, bytes.NewReader(data), file, explicitMIME)
+	}
+	return &genai.Part{InlineData: &genai.Blob{Data: data, MIMEType: detectMIME(file, explicitMIME, data)}}, nil
+}
+
+var blankLinesPattern = regexp.MustCompile(`\n[ \t]*(?:\n[ \t]*)+`)
+
+// chunkDocument caps text at extractMaxChars and splits it into pieces of about
+// extractChunkChars, preferring paragraph and then line boundaries.
+func chunkDocument(text string) extractEntry {
+	return chunkText(strings.TrimSpace(blankLinesPattern.ReplaceAllString(text, "\n\n")))
+}
+
+// chunkText splits text as chunkDocument does without normalising blank lines.
+func chunkText(text string) extractEntry {
+	var entry extractEntry
+	if utf8.RuneCountInString(text) > extractMaxChars {
+		text = string([]rune(text)[:extractMaxChars])
+		entry.truncated = true
+	}
+
+	for text != "" {
+		if utf8.RuneCountInString(text) <= extractChunkChars {
+			entry.chunks = append(entry.chunks, text)
+			break
+		}
+		limit := len(string([]rune(text)[:extractChunkChars]))
+		cut := strings.LastIndex(text[:limit], "\n\n")
+		if cut < limit/2 {
+			cut = strings.LastIndex(text[:limit], "\n")
+		}
+		if cut < limit/2 {
+			cut = limit
+		}
+		entry.chunks = append(entry.chunks, strings.TrimSpace(text[:cut]))
+		text = strings.TrimSpace(text[cut:])
+	}
+	return entry
+}
@@ -0,0 +1,116 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestExtractorFor(t *testing.T) {
+	tests := []struct {
+		name, mime string
+		want       string
+	}{
+		{"report.PDF", "", "PDF"},
+		{"notes.docx", "application/octet-stream", "DOCX"},
+		{"data", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", "XLSX"},
+		{"book.bin", "application/epub+zip", "EPUB"},
+		{"fix.patch", "", "patch"},
+		{"change", "text/x-diff", "patch"},
+		{"photo.jpg", "image/jpeg", ""},
+	}
+	for _, tt := range tests {
+		ex, ok := extractorFor(&tele.Document{FileName: tt.name, MIME: tt.mime})
+		if got := ex.format; ok != (tt.want != "") || got != tt.want {
+			t.Errorf("extractorFor(%q, %q) = %q, %v; want %q", tt.name, tt.mime, got, ok, tt.want)
+		}
+	}
+}
+
+func TestChunkDocument(t *testing.T) {
+	// Two of these paragraphs fill a chunk.
+	paragraph := strings.Repeat("word ", 2000)
+	tests := []struct {
+		name      string
+		text      string
+		chunks    int
+		truncated bool
+	}{
+		{"empty", "  \n\n ", 0, false},
+		{"short", "one\n\n\n\ntwo", 1, false},
+		{"paragraphs", strings.Repeat(paragraph+"\n\n", 6), 3, false},
+		{"one long line", strings.Repeat("x", 2*extractChunkChars+10), 3, false},
+		{"over the cap", strings.Repeat(paragraph+"\n\n", extractMaxChars/len(paragraph)+5), extractMaxChars / (2 * len(paragraph)), true},
+	}
+	for _, tt := range tests {
+		entry := chunkDocument(tt.text)
+		if len(entry.chunks) != tt.chunks || entry.truncated != tt.truncated {
+			t.Errorf("%s: %d chunks, truncated %v; want %d, %v", tt.name, len(entry.chunks), entry.truncated, tt.chunks, tt.truncated)
+		}
+		for i, chunk := range entry.chunks {
+			if n := utf8.RuneCountInString(chunk); n > extractChunkChars || n == 0 {
+				t.Errorf("%s: chunk %d has %d characters", tt.name, i, n)
+			}
+		}
+	}
+	if got := chunkDocument("one\n \n\t\ntwo").chunks[0]; got != "one\n\ntwo" {
+		t.Errorf("blank lines = %q, want one empty line", got)
+	}
+	if got := chunkText("  keep\n\n\n\nthis").chunks[0]; got != "  keep\n\n\n\nthis" {
+		t.Errorf("verbatim text = %q, want it unchanged", got)
+	}
+}
+
+func TestDocumentPartsExtractsText(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.files = map[string]string{
+		"docx1":  string(zipFixture(t, map[string]string{"word/document.xml": docxDocument})),
+		"broken": "not a zip",
+	}
+	doc := &tele.Document{File: tele.File{FileID: "docx1", UniqueID: "u-docx1"}, FileName: "report.docx"}
+
+	for range 2 {
+		parts, err := app.documentParts(context.Background(), doc)
+		if err != nil {
+			t.Fatalf("documentParts: %v", err)
+		}
+		if len(parts) != 2 || !strings.Contains(parts[0].Text, "DOCX") || !strings.Contains(parts[1].Text, "Quarterly report") {
+			t.Fatalf("parts = %+v", parts)
+		}
+	}
+	if downloads := apis.callsTo(telegramHost, "file"); len(downloads) != 1 {
+		t.Errorf("downloaded %d times, want the cached text reused", len(downloads))
+	}
+
+	broken := &tele.Document{File: tele.File{FileID: "broken"}, FileName: "broken.docx"}
+	if _, err := app.documentParts(context.Background(), broken); err == nil || !strings.Contains(err.Error(), "extract DOCX") {
+		t.Errorf("malformed DOCX err = %v", err)
+	}
+}
+
+func TestDocumentPartsRefusesOversizedFileOfUnknownSize(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.files = map[string]string{
+		"big":  strings.Repeat("a", extractMaxFileBytes+1),
+		"fits": strings.Repeat("a", 100),
+	}
+	doc := &tele.Document{File: tele.File{FileID: "big"}, FileName: "big.patch"}
+	if _, err := app.documentParts(context.Background(), doc); !errors.Is(err, errFileTooLarge) {
+		t.Errorf("err = %v, want errFileTooLarge", err)
+	}
+	msg := testMessage(42, "")
+	msg.Document = doc
+	app.processMessage(context.Background(), msg, turnOptions{})
+	if texts := apis.sentTexts(); len(texts) != 1 || !strings.Contains(texts[0], "larger than 20 MB") {
+		t.Errorf("sent %q, want the file too large notice", texts)
+	}
+
+	doc = &tele.Document{File: tele.File{FileID: "fits"}, FileName: "fits.patch"}
+	if _, err := app.documentParts(context.Background(), doc); err != nil {
+		t.Errorf("file within the limit: %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	// defaultInlineMediaLimit keeps requests well under Gemini's 20 MB limit
+	// on inline data, which covers the whole request.
+	defaultInlineMediaLimit = 4 << 20
+	// telegramDownloadLimit is the largest file the cloud Bot API lets bots
+	// download. Larger files need a local Bot API server.
+	telegramDownloadLimit = 20 << 20
+	fileUploadTimeout     = 5 * time.Minute
+	filePollInterval      = 2 * time.Second
+)
+
+// errFileTooLarge is returned for files over telegramDownloadLimit, which
+// the Bot API refuses to serve.
+var errFileTooLarge = errors.New("file too large to download from Telegram")
+
+// uploadPart streams large media to the Gemini Files API and references it by URI
+// instead of inlining the bytes into the request.
+func (a *App) uploadPart(ctx context.Context, reader io.Reader, file *tele.File, explicitMIME string) (*genai.Part, error) {
+	ctx, cancel := context.WithTimeout(ctx, fileUploadTimeout)
+	defer cancel()
+
+	buffered := bufio.NewReaderSize(reader, 512)
+	head, _ := buffered.Peek(512)
+	if len(head) == 0 {
+		return nil, fmt.Errorf("empty media payload")
+	}
+	mimeType := detectMIME(file, explicitMIME, head)
+
+	uploaded, err := a.client.Files.Upload(ctx, buffered, &genai.UploadFileConfig{
+		MIMEType:    mimeType,
+		DisplayName: file.UniqueID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("upload file: %w", err)
+	}
+
+	for uploaded.State == genai.FileStateProcessing {
+		if err := sleepContext(ctx, filePollInterval); err != nil {
+			return nil, fmt.Errorf("wait for file %s: %w", uploaded.Name, err)
+		}
+		if uploaded, err = a.client.Files.Get(ctx, uploaded.Name, nil); err != nil {
+			return nil, fmt.Errorf("poll file: %w", err)
+		}
+	}
+	if uploaded.State == genai.FileStateFailed {
+		return nil, fmt.Errorf("file %s failed processing", uploaded.Name)
+	}
+	return genai.NewPartFromURI(uploaded.URI, uploaded.MIMEType), nil
+}
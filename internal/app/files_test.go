@@ -0,0 +1,25 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestFileOverDownloadLimitIsNotFetched(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	msg := testMessage(42, "")
+	msg.Caption = "What happens here?"
+	msg.Video = &tele.Video{File: tele.File{FileID: "big", UniqueID: "big", FileSize: telegramDownloadLimit + 1}, MIME: "video/mp4"}
+
+	if err := app.processMessage(context.Background(), msg, turnOptions{}); err == nil {
+		t.Fatal("processMessage succeeded")
+	}
+	if calls := apis.callsTo(telegramHost, "getFile"); len(calls) != 0 {
+		t.Errorf("asked Telegram for the file %d times", len(calls))
+	}
+	if texts := apis.sentTexts(); !containsText(texts, "larger than 20 MB") {
+		t.Errorf("sent %q, want the file size notice", texts)
+	}
+}
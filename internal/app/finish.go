@@ -0,0 +1,102 @@
+package app
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const continueReplyUnique = "continue_reply"
+
+// continuePrompt asks the model to resume an answer cut off at the token limit.
+const continuePrompt = "Continue your previous answer exactly where it stopped. Do not repeat what you already wrote and do not add a preamble."
+
+// promptBlocked reports whether Gemini refused the prompt itself.
+func promptBlocked(resp *genai.GenerateContentResponse) bool {
+	fb := resp.PromptFeedback
+	return fb != nil && fb.BlockReason != "" && fb.BlockReason != genai.BlockedReasonUnspecified
+}
+
+// blockedNotice explains why a prompt was refused and which safety categories
+// flagged it.
+func blockedNotice(lang language, fb *genai.GenerateContentResponsePromptFeedback) string {
+	notice := tr(lang, "blocked_reason", humanizeEnum(string(fb.BlockReason)))
+	if categories := flaggedCategories(fb.SafetyRatings); categories != "" {
+		notice += " " + tr(lang, "safety_categories", categories)
+	}
+	return notice + " " + tr(lang, "blocked_hint")
+}
+
+// finishNotice explains an answer that did not end normally; truncated reports
+// a cut at the token limit, which a Continue button can resume.
+func finishNotice(lang language, cand *genai.Candidate) (notice string, truncated bool) {
+	if cand == nil {
+		return "", false
+	}
+	switch cand.FinishReason {
+	case "", genai.FinishReasonUnspecified, genai.FinishReasonStop:
+		return "", false
+	case genai.FinishReasonMaxTokens:
+		return tr(lang, "finish_max_tokens"), true
+	case genai.FinishReasonSafety, genai.FinishReasonImageSafety:
+		notice = tr(lang, "finish_safety")
+		if categories := flaggedCategories(cand.SafetyRatings); categories != "" {
+			notice += " " + tr(lang, "safety_categories", categories)
+		}
+		return notice, false
+	case genai.FinishReasonRecitation:
+		return tr(lang, "finish_recitation"), false
+	default:
+		return tr(lang, "finish_other", humanizeEnum(string(cand.FinishReason))), false
+	}
+}
+
+// flaggedCategories lists the harm categories that blocked content or were
+// rated at least a medium probability.
+func flaggedCategories(ratings []*genai.SafetyRating) string {
+	var names []string
+	for _, r := range ratings {
+		if r == nil {
+			continue
+		}
+		if r.Blocked || r.Probability == genai.HarmProbabilityMedium || r.Probability == genai.HarmProbabilityHigh {
+			name := humanizeEnum(strings.TrimPrefix(string(r.Category), "HARM_CATEGORY_"))
+			if !strings.Contains(strings.Join(names, ","), name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// humanizeEnum turns an API enum such as SEXUALLY_EXPLICIT into "sexually explicit".
+func humanizeEnum(v string) string {
+	return strings.ReplaceAll(strings.ToLower(v), "_", " ")
+}
+
+// handleContinueReply resumes the latest answer after it hit the token limit.
+func (a *App) handleContinueReply(c tele.Context) error {
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	session := a.sessionOf(c.Message())
+	session.mu.Lock()
+	turn := session.lastTurn
+	session.mu.Unlock()
+	if c.Callback().Message == nil || c.Callback().Message.ID != turn.replyID {
+		return c.Respond(&tele.CallbackResponse{Text: tr(lang, "continue_stale")})
+	}
+	if err := c.Respond(); err != nil {
+		slog.Warn("callback acknowledge failed", "err", err)
+	}
+
+	msg := &tele.Message{
+		ID:       c.Callback().Message.ID,
+		Chat:     c.Chat(),
+		Sender:   c.Sender(),
+		Text:     continuePrompt,
+		Unixtime: time.Now().Unix(),
+	}
+	return a.enqueueTurn(msg, turnOptions{})
+}
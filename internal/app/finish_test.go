@@ -0,0 +1,54 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestFinishNotice(t *testing.T) {
+	tests := []struct {
+		name      string
+		cand      *genai.Candidate
+		want      string
+		truncated bool
+	}{
+		{"none", nil, "", false},
+		{"stop", &genai.Candidate{FinishReason: genai.FinishReasonStop}, "", false},
+		{"max tokens", &genai.Candidate{FinishReason: genai.FinishReasonMaxTokens}, "length limit", true},
+		{"recitation", &genai.Candidate{FinishReason: genai.FinishReasonRecitation}, "paraphrase", false},
+		{"other", &genai.Candidate{FinishReason: genai.FinishReasonMalformedFunctionCall}, "(malformed function call)", false},
+		{"safety", &genai.Candidate{
+			FinishReason: genai.FinishReasonSafety,
+			SafetyRatings: []*genai.SafetyRating{
+				{Category: genai.HarmCategoryDangerousContent, Probability: genai.HarmProbabilityHigh},
+				{Category: genai.HarmCategoryHarassment, Probability: genai.HarmProbabilityNegligible},
+			},
+		}, "Flagged categories: dangerous content.", false},
+	}
+	for _, tt := range tests {
+		notice, truncated := finishNotice(defaultLanguage, tt.cand)
+		if truncated != tt.truncated {
+			t.Errorf("%s: truncated = %v, want %v", tt.name, truncated, tt.truncated)
+		}
+		if tt.want == "" && notice != "" || !strings.Contains(notice, tt.want) {
+			t.Errorf("%s: notice = %q, want it to contain %q", tt.name, notice, tt.want)
+		}
+	}
+}
+
+func TestBlockedNotice(t *testing.T) {
+	fb := &genai.GenerateContentResponsePromptFeedback{
+		BlockReason: genai.BlockedReasonSafety,
+		SafetyRatings: []*genai.SafetyRating{
+			{Category: genai.HarmCategorySexuallyExplicit, Blocked: true},
+		},
+	}
+	got := blockedNotice(defaultLanguage, fb)
+	for _, want := range []string{"(safety)", "sexually explicit", "Rephrase"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("blockedNotice = %q, want it to contain %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+// forwardPart describes where a forwarded message came from, so the model can
+// weigh a news post by its source and date when asked whether it is true.
+func forwardPart(msg *tele.Message) *genai.Part {
+	origin := msg.Origin
+	if origin == nil {
+		return nil
+	}
+
+	source := originName(origin)
+	if source == "" {
+		source = "hidden by the original sender"
+	}
+	details := []string{"Source: " + source}
+	channel := origin.Chat
+	if channel == nil {
+		channel = origin.SenderChat
+	}
+	if channel != nil && channel.Username != "" {
+		details = append(details, "Username: @"+channel.Username)
+		if origin.MessageID != 0 {
+			details = append(details, fmt.Sprintf("Link: https://t.me/%s/%d", channel.Username, origin.MessageID))
+		}
+	}
+	if origin.Signature != "" {
+		details = append(details, "Author: "+origin.Signature)
+	}
+	if origin.DateUnixtime != 0 {
+		details = append(details, "Originally sent: "+origin.Time().UTC().Format(time.RFC1123))
+	}
+	if msg.Text == "" && msg.Caption == "" {
+		details = append(details, "The forwarded message has no text; its attachment follows")
+	}
+
+	text := "[The user forwarded the following message. " + strings.Join(details, ". ") + ".]"
+	return genai.NewPartFromText(text)
+}
@@ -0,0 +1,51 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestForwardPart(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  *tele.Message
+		want string
+	}{
+		{"not forwarded", &tele.Message{Text: "hi"}, ""},
+		{"channel post", &tele.Message{Text: "Rates rise", Origin: &tele.MessageOrigin{
+			Type: "channel", Chat: &tele.Chat{Title: "Daily News", Username: "dailynews"}, MessageID: 812, Signature: "Ana Lee", DateUnixtime: 1700000000,
+		}}, "[The user forwarded the following message. Source: channel Daily News. Username: @dailynews. Link: https://t.me/dailynews/812. Author: Ana Lee. Originally sent: Tue, 14 Nov 2023 22:13:20 UTC.]"},
+		{"user", &tele.Message{Caption: "look", Origin: &tele.MessageOrigin{Type: "user", Sender: &tele.User{FirstName: "Ana"}}},
+			"[The user forwarded the following message. Source: Ana.]"},
+		{"hidden user", &tele.Message{Text: "psst", Origin: &tele.MessageOrigin{Type: "hidden_user"}},
+			"[The user forwarded the following message. Source: hidden by the original sender.]"},
+		{"group sender chat without a link", &tele.Message{Origin: &tele.MessageOrigin{Type: "chat", SenderChat: &tele.Chat{Title: "Team", Username: "team"}}},
+			"[The user forwarded the following message. Source: Team. Username: @team. The forwarded message has no text; its attachment follows.]"},
+	}
+	for _, tt := range tests {
+		part := forwardPart(tt.msg)
+		got := ""
+		if part != nil {
+			got = part.Text
+		}
+		if got != tt.want {
+			t.Errorf("%s: forwardPart = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestForwardedMessageReachesTheModel(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	msg := testMessage(42, "Scientists confirm the moon is cheese")
+	msg.Origin = &tele.MessageOrigin{Type: "channel", Chat: &tele.Chat{Title: "Satire Daily", Username: "satire"}, MessageID: 5}
+	if err := app.processMessage(context.Background(), msg, turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	calls := apis.callsTo(geminiHost, ":generateContent")
+	if len(calls) == 0 || !strings.Contains(string(calls[len(calls)-1].body), "Link: https://t.me/satire/5") {
+		t.Error("the origin of the forward is missing from the request")
+	}
+}
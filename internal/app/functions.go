@@ -0,0 +1,387 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"google.golang.org/genai"
+)
+
+const (
+	maxFunctionRounds = 5
+	// toolRouterModel picks between the functions and the built-in tools
+	// when a turn could use both.
+	toolRouterModel = "gemini-2.5-flash-lite"
+	// toolRouterContents is how much of the conversation the router sees.
+	toolRouterContents    = 4
+	toolRouterInstruction = "Call one of the functions if the last user message needs it. Otherwise answer with the single word: none."
+)
+
+type functionHandler func(ctx context.Context, args map[string]any) (map[string]any, error)
+
+// functionFilter decides whether a function is offered to a chat; nil means every chat.
+type functionFilter func(chatID int64) bool
+
+type registeredFunction struct {
+	decl    *genai.FunctionDeclaration
+	handler functionHandler
+	allowed functionFilter
+}
+
+// functionRegistry holds Go-side tools the model can invoke through function calling.
+type functionRegistry struct {
+	mu    sync.RWMutex
+	order []string
+	funcs map[string]registeredFunction
+}
+
+func newFunctionRegistry() *functionRegistry {
+	return &functionRegistry{funcs: make(map[string]registeredFunction)}
+}
+
+func (r *functionRegistry) register(decl *genai.FunctionDeclaration, handler functionHandler) {
+	r.registerFor(decl, handler, nil)
+}
+
+// registerFor adds a function that is only offered to chats accepted by allowed.
+func (r *functionRegistry) registerFor(decl *genai.FunctionDeclaration, handler functionHandler, allowed functionFilter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.funcs[decl.Name]; !exists {
+		r.order = append(r.order, decl.Name)
+	}
+	r.funcs[decl.Name] = registeredFunction{decl: decl, handler: handler, allowed: allowed}
+}
+
+// unregister removes the named functions.
+func (r *functionRegistry) unregister(names ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, name := range names {
+		delete(r.funcs, name)
+	}
+	r.order = slices.DeleteFunc(r.order, func(name string) bool {
+		_, ok := r.funcs[name]
+		return !ok
+	})
+}
+
+// toolFor returns the declarations available to chatID as a single tool entry, or
+// nil when there are none.
+func (r *functionRegistry) toolFor(chatID int64) *genai.Tool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var decls []*genai.FunctionDeclaration
+	for _, name := range r.order {
+		fn := r.funcs[name]
+		if fn.allowed == nil || fn.allowed(chatID) {
+			decls = append(decls, fn.decl)
+		}
+	}
+	if len(decls) == 0 {
+		return nil
+	}
+	return &genai.Tool{FunctionDeclarations: decls}
+}
+
+func (r *functionRegistry) invoke(ctx context.Context, call *genai.FunctionCall) *genai.Part {
+	r.mu.RLock()
+	fn, ok := r.funcs[call.Name]
+	r.mu.RUnlock()
+	if ok && fn.allowed != nil {
+		chatID, known := chatIDFromContext(ctx)
+		ok = known && fn.allowed(chatID)
+	}
+
+	var result map[string]any
+	if !ok {
+		result = map[string]any{"error": fmt.Sprintf("unknown function %q", call.Name)}
+	} else if out, err := fn.handler(ctx, call.Args); err != nil {
+		logFrom(ctx).Warn("function call failed", "function", call.Name, "err", err)
+		result = map[string]any{"error": err.Error()}
+	} else {
+		result = out
+	}
+
+	part := genai.NewPartFromFunctionResponse(call.Name, result)
+	part.FunctionResponse.ID = call.ID
+	return part
+}
+
+// generateWithFunctions runs generate and resolves any function calls locally,
+// feeding the results back until the model produces a final answer. Token usage
+// of intermediate rounds is folded into the returned response.
+func (a *App) generateWithFunctions(ctx context.Context, contents []*genai.Content, cfg *genai.GenerateContentConfig) (*genai.GenerateContentResponse, string, error) {
+	cfg = a.routeTools(ctx, contents, cfg)
+	var spent []*genai.GenerateContentResponseUsageMetadata
+	working := contents
+	for round := 0; ; round++ {
+		resp, model, err := a.generate(ctx, working, cfg)
+		if err != nil {
+			return nil, "", err
+		}
+		calls := functionCalls(resp)
+		if len(calls) == 0 || round == maxFunctionRounds {
+			resp.UsageMetadata = mergeUsage(resp.UsageMetadata, spent)
+			return resp, model, nil
+		}
+		spent = append(spent, resp.UsageMetadata)
+
+		responses := &genai.Content{Role: genai.RoleUser}
+		for _, call := range calls {
+			responses.Parts = append(responses.Parts, a.functions.invoke(ctx, call))
+		}
+		next := make([]*genai.Content, 0, len(working)+2)
+		next = append(next, working...)
+		next = append(next, firstCandidate(resp).Content, responses)
+		working = next
+	}
+}
+
+// Ways of routing a turn between the functions and the built-in tools, as
+// set by Config.ToolRouting.
+const (
+	toolRoutingModel     = "model"
+	toolRoutingFunctions = "functions"
+	toolRoutingBuiltin   = "builtin"
+)
+
+// parseToolRouting reads Config.ToolRouting, where empty means model.
+func parseToolRouting(raw string) (string, error) {
+	switch routing := strings.ToLower(strings.TrimSpace(raw)); routing {
+	case "", toolRoutingModel:
+		return toolRoutingModel, nil
+	case toolRoutingFunctions, toolRoutingBuiltin:
+		return routing, nil
+	default:
+		return "", fmt.Errorf("unknown routing %q, want model, functions or builtin", raw)
+	}
+}
+
+// routeTools picks the tools of a turn whose config offers both functions and
+// built-in tools, which Gemini 2.5 rejects in one request. Unless
+// Config.ToolRouting fixes the choice, a small model is shown only the
+// functions first: when it calls one, the turn runs with the functions,
+// otherwise with the built-in tools. A turn whose last message has no text
+// has nothing to route and keeps the built-in tools without asking.
+func (a *App) routeTools(ctx context.Context, contents []*genai.Content, cfg *genai.GenerateContentConfig) *genai.GenerateContentConfig {
+	builtin, functions := splitTools(cfg.Tools)
+	if len(builtin) == 0 || len(functions) == 0 {
+		return cfg
+	}
+	routed := *cfg
+	routed.Tools = builtin
+	var useFunctions bool
+	switch {
+	case !a.isGeminiModel(a.primaryModel(ctx)):
+		// The built-in tools only exist on Gemini.
+		useFunctions = true
+	case a.toolRouting == toolRoutingModel:
+		useFunctions = lastUserText(contents) != "" && a.wantsFunctions(ctx, contents, functions)
+	default:
+		useFunctions = a.toolRouting == toolRoutingFunctions
+	}
+	if useFunctions {
+		routed.Tools = functions
+	}
+	return &routed
+}
+
+// lastUserText returns the text of the last user message in contents.
+func lastUserText(contents []*genai.Content) string {
+	for i := len(contents) - 1; i >= 0; i-- {
+		if contents[i].Role != genai.RoleUser {
+			continue
+		}
+		var b strings.Builder
+		for _, part := range contents[i].Parts {
+			if part != nil && !part.Thought {
+				b.WriteString(part.Text)
+			}
+		}
+		return strings.TrimSpace(b.String())
+	}
+	return ""
+}
+
+// wantsFunctions asks toolRouterModel whether the end of contents calls for
+// one of the functions. Failures fall back to the built-in tools.
+func (a *App) wantsFunctions(ctx context.Context, contents []*genai.Content, functions []*genai.Tool) bool {
+	start := max(0, len(contents)-toolRouterContents)
+	for start < len(contents)-1 && contents[start].Role != genai.RoleUser {
+		start++
+	}
+	cfg := &genai.GenerateContentConfig{
+		SystemInstruction: genai.NewContentFromText(toolRouterInstruction, genai.Role("system")),
+		Tools:             functions,
+		ThinkingConfig:    &genai.ThinkingConfig{ThinkingBudget: genai.Ptr[int32](0)},
+		MaxOutputTokens:   256,
+	}
+	resp, err := a.generateWithRetry(ctx, toolRouterModel, contents[start:], cfg)
+	if err != nil {
+		logFrom(ctx).Warn("tool routing failed, using the built-in tools", "err", err)
+		return false
+	}
+	if chatID, ok := chatIDFromContext(ctx); ok {
+		a.recordUsage(ctx, chatID, toolRouterModel, resp.UsageMetadata)
+	}
+	return len(functionCalls(resp)) > 0
+}
+
+// Names of the built-in Gemini tools for Config.DisabledTools.
+const (
+	googleSearchTool  = "google_search"
+	urlContextTool    = "url_context"
+	codeExecutionTool = "code_execution"
+)
+
+// builtinTools returns the built-in Gemini tools that are not disabled, as a
+// single tool entry, or nil when all of them are.
+func builtinTools(disabled []string) []*genai.Tool {
+	tool := &genai.Tool{}
+	if !slices.Contains(disabled, googleSearchTool) {
+		tool.GoogleSearch = &genai.GoogleSearch{}
+	}
+	if !slices.Contains(disabled, urlContextTool) {
+		tool.URLContext = &genai.URLContext{}
+	}
+	if !slices.Contains(disabled, codeExecutionTool) {
+		tool.CodeExecution = &genai.ToolCodeExecution{}
+	}
+	if tool.GoogleSearch == nil && tool.URLContext == nil && tool.CodeExecution == nil {
+		return nil
+	}
+	return []*genai.Tool{tool}
+}
+
+// has reports whether a function called name is registered.
+func (r *functionRegistry) has(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.funcs[name]
+	return ok
+}
+
+// disableTools removes the disabled functions once all are registered. A name
+// that matches no tool is an error, since a typo would leave the tool on.
+func (a *App) disableTools(names []string) error {
+	for _, name := range names {
+		switch {
+		case name == googleSearchTool, name == urlContextTool, name == codeExecutionTool:
+		case a.functions.has(name):
+			a.functions.unregister(name)
+		default:
+			return fmt.Errorf("DISABLED_TOOLS: no tool named %q", name)
+		}
+	}
+	if len(names) > 0 {
+		a.noteStartup("Disabled tools: %s", strings.Join(names, ", "))
+	}
+	return nil
+}
+
+// splitTools separates the function declarations from the built-in tools.
+func splitTools(tools []*genai.Tool) (builtin, functions []*genai.Tool) {
+	for _, tool := range tools {
+		if tool == nil {
+			continue
+		}
+		if len(tool.FunctionDeclarations) > 0 {
+			functions = append(functions, tool)
+		} else {
+			builtin = append(builtin, tool)
+		}
+	}
+	return builtin, functions
+}
+
+// withoutMixedTools drops the function declarations from a config that also
+// has built-in tools, for requests that skip routeTools.
+func withoutMixedTools(cfg *genai.GenerateContentConfig) *genai.GenerateContentConfig {
+	if cfg == nil {
+		return cfg
+	}
+	builtin, functions := splitTools(cfg.Tools)
+	if len(builtin) == 0 || len(functions) == 0 {
+		return cfg
+	}
+	stripped := *cfg
+	stripped.Tools = builtin
+	return &stripped
+}
+
+type chatIDKey struct{}
+
+// withChatID records the chat a request belongs to so function handlers can scope their work.
+func withChatID(ctx context.Context, chatID int64) context.Context {
+	return context.WithValue(ctx, chatIDKey{}, chatID)
+}
+
+func chatIDFromContext(ctx context.Context) (int64, bool) {
+	id, ok := ctx.Value(chatIDKey{}).(int64)
+	return id, ok
+}
+
+type threadKey struct{}
+
+// withThread records the forum topic a request came from, so what function
+// handlers send lands there too.
+func withThread(ctx context.Context, thread int) context.Context {
+	return context.WithValue(ctx, threadKey{}, thread)
+}
+
+func threadFromContext(ctx context.Context) int {
+	thread, _ := ctx.Value(threadKey{}).(int)
+	return thread
+}
+
+type userIDKey struct{}
+
+// withUserID records the user behind a request for tools that act on personal accounts.
+func withUserID(ctx context.Context, userID int64) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+func userIDFromContext(ctx context.Context) (int64, bool) {
+	id, ok := ctx.Value(userIDKey{}).(int64)
+	return id, ok
+}
+
+func functionCalls(resp *genai.GenerateContentResponse) []*genai.FunctionCall {
+	cand := firstCandidate(resp)
+	if cand == nil || cand.Content == nil {
+		return nil
+	}
+	var calls []*genai.FunctionCall
+	for _, part := range cand.Content.Parts {
+		if part != nil && part.FunctionCall != nil {
+			calls = append(calls, part.FunctionCall)
+		}
+	}
+	return calls
+}
+
+func mergeUsage(final *genai.GenerateContentResponseUsageMetadata, earlier []*genai.GenerateContentResponseUsageMetadata) *genai.GenerateContentResponseUsageMetadata {
+	if len(earlier) == 0 {
+		return final
+	}
+	merged := &genai.GenerateContentResponseUsageMetadata{}
+	if final != nil {
+		*merged = *final
+	}
+	for _, u := range earlier {
+		if u == nil {
+			continue
+		}
+		merged.PromptTokenCount += u.PromptTokenCount
+		merged.CachedContentTokenCount += u.CachedContentTokenCount
+		merged.ToolUsePromptTokenCount += u.ToolUsePromptTokenCount
+		merged.CandidatesTokenCount += u.CandidatesTokenCount
+		merged.ThoughtsTokenCount += u.ThoughtsTokenCount
+		merged.TotalTokenCount += u.TotalTokenCount
+	}
+	return merged
+}
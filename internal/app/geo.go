@@ -0,0 +1,209 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	nominatimURL          = "https://nominatim.openstreetmap.org"
+	openWeatherURL        = "https://api.openweathermap.org/data/2.5/weather"
+	defaultToolsUserAgent = "eteonbot/1.0"
+)
+
+var toolHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// getJSON fetches url and decodes a JSON body into out.
+func getJSON(ctx context.Context, rawURL, userAgent string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	if userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+	resp, err := toolHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+type geoClient struct {
+	userAgent      string
+	openWeatherKey string
+}
+
+func (g *geoClient) geocode(ctx context.Context, query string) ([]map[string]any, error) {
+	var results []struct {
+		DisplayName string `json:"display_name"`
+		Lat         string `json:"lat"`
+		Lon         string `json:"lon"`
+		Type        string `json:"type"`
+	}
+	q := url.Values{"q": {query}, "format": {"jsonv2"}, "limit": {"3"}}
+	if err := getJSON(ctx, nominatimURL+"/search?"+q.Encode(), g.userAgent, &results); err != nil {
+		return nil, fmt.Errorf("geocode: %w", err)
+	}
+	out := make([]map[string]any, 0, len(results))
+	for _, r := range results {
+		lat, _ := strconv.ParseFloat(r.Lat, 64)
+		lon, _ := strconv.ParseFloat(r.Lon, 64)
+		out = append(out, map[string]any{"name": r.DisplayName, "lat": lat, "lon": lon, "type": r.Type})
+	}
+	return out, nil
+}
+
+func (g *geoClient) reverseGeocode(ctx context.Context, lat, lon float64) (string, error) {
+	var result struct {
+		DisplayName string `json:"display_name"`
+	}
+	q := url.Values{
+		"lat":    {strconv.FormatFloat(lat, 'f', 6, 64)},
+		"lon":    {strconv.FormatFloat(lon, 'f', 6, 64)},
+		"format": {"jsonv2"},
+	}
+	if err := getJSON(ctx, nominatimURL+"/reverse?"+q.Encode(), g.userAgent, &result); err != nil {
+		return "", fmt.Errorf("reverse geocode: %w", err)
+	}
+	return result.DisplayName, nil
+}
+
+func (g *geoClient) weather(ctx context.Context, lat, lon float64) (map[string]any, error) {
+	var result struct {
+		Name    string `json:"name"`
+		Weather []struct {
+			Description string `json:"description"`
+		} `json:"weather"`
+		Main struct {
+			Temp      float64 `json:"temp"`
+			FeelsLike float64 `json:"feels_like"`
+			Humidity  float64 `json:"humidity"`
+			Pressure  float64 `json:"pressure"`
+		} `json:"main"`
+		Wind struct {
+			Speed float64 `json:"speed"`
+		} `json:"wind"`
+		Dt int64 `json:"dt"`
+	}
+	q := url.Values{
+		"lat":   {strconv.FormatFloat(lat, 'f', 6, 64)},
+		"lon":   {strconv.FormatFloat(lon, 'f', 6, 64)},
+		"units": {"metric"},
+		"appid": {g.openWeatherKey},
+	}
+	if err := getJSON(ctx, openWeatherURL+"?"+q.Encode(), g.userAgent, &result); err != nil {
+		return nil, fmt.Errorf("weather: %w", err)
+	}
+	conditions := ""
+	if len(result.Weather) > 0 {
+		conditions = result.Weather[0].Description
+	}
+	return map[string]any{
+		"place":          result.Name,
+		"conditions":     conditions,
+		"temperature_c":  result.Main.Temp,
+		"feels_like_c":   result.Main.FeelsLike,
+		"humidity_pct":   result.Main.Humidity,
+		"pressure_hpa":   result.Main.Pressure,
+		"wind_speed_m_s": result.Wind.Speed,
+		"observed_at":    time.Unix(result.Dt, 0).UTC().Format(time.RFC3339),
+	}, nil
+}
+
+func (a *App) registerGeoTools() {
+	latLonSchema := map[string]*genai.Schema{
+		"lat": {Type: genai.TypeNumber, Description: "Latitude in decimal degrees."},
+		"lon": {Type: genai.TypeNumber, Description: "Longitude in decimal degrees."},
+	}
+
+	a.functions.register(&genai.FunctionDeclaration{
+		Name:        "geocode",
+		Description: "Look up coordinates for a place name or address.",
+		Parameters: &genai.Schema{
+			Type:       genai.TypeObject,
+			Properties: map[string]*genai.Schema{"query": {Type: genai.TypeString, Description: "Place name or address."}},
+			Required:   []string{"query"},
+		},
+	}, func(ctx context.Context, args map[string]any) (map[string]any, error) {
+		query := argString(args, "query")
+		if query == "" {
+			return nil, errors.New("missing query")
+		}
+		matches, err := a.geo.geocode(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"matches": matches}, nil
+	})
+
+	a.functions.register(&genai.FunctionDeclaration{
+		Name:        "reverse_geocode",
+		Description: "Describe the address or place at the given coordinates.",
+		Parameters:  &genai.Schema{Type: genai.TypeObject, Properties: latLonSchema, Required: []string{"lat", "lon"}},
+	}, func(ctx context.Context, args map[string]any) (map[string]any, error) {
+		lat, lon, err := argLatLon(args)
+		if err != nil {
+			return nil, err
+		}
+		name, err := a.geo.reverseGeocode(ctx, lat, lon)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"address": name}, nil
+	})
+
+	if a.geo.openWeatherKey == "" {
+		return
+	}
+	a.functions.register(&genai.FunctionDeclaration{
+		Name:        "current_weather",
+		Description: "Get current weather conditions at the given coordinates. Geocode place names first.",
+		Parameters:  &genai.Schema{Type: genai.TypeObject, Properties: latLonSchema, Required: []string{"lat", "lon"}},
+	}, func(ctx context.Context, args map[string]any) (map[string]any, error) {
+		lat, lon, err := argLatLon(args)
+		if err != nil {
+			return nil, err
+		}
+		return a.geo.weather(ctx, lat, lon)
+	})
+}
+
+func argLatLon(args map[string]any) (float64, float64, error) {
+	lat, err := argNumber(args, "lat")
+	if err != nil {
+		return 0, 0, err
+	}
+	lon, err := argNumber(args, "lon")
+	if err != nil {
+		return 0, 0, err
+	}
+	return lat, lon, nil
+}
+
+// locationPart describes a shared location or venue so the model can pass the
+// coordinates to the geo tools.
+func locationPart(msg *tele.Message) *genai.Part {
+	switch {
+	case msg.Venue != nil:
+		return genai.NewPartFromText(fmt.Sprintf("The user shared a venue: %s, %s (latitude %.6f, longitude %.6f).",
+			msg.Venue.Title, msg.Venue.Address, msg.Venue.Location.Lat, msg.Venue.Location.Lng))
+	case msg.Location != nil:
+		return genai.NewPartFromText(fmt.Sprintf("The user shared their location: latitude %.6f, longitude %.6f.",
+			msg.Location.Lat, msg.Location.Lng))
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestGeoTools(t *testing.T) {
+	app, apis := newTestApp(t, Config{OpenWeatherAPIKey: "owm-key", ToolsUserAgent: "eteon-test"})
+	var agents []string
+	apis.handle("nominatim.openstreetmap.org", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents = append(agents, r.UserAgent())
+		q := r.URL.Query()
+		switch {
+		case r.URL.Path == "/search" && q.Get("q") == "Berlin":
+			w.Write([]byte(`[{"display_name":"Berlin, Germany","lat":"52.5170365","lon":"13.3888599","type":"city"}]`))
+		case r.URL.Path == "/search":
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+		case r.URL.Path == "/reverse" && q.Get("lat") == "52.517037" && q.Get("lon") == "13.388860":
+			w.Write([]byte(`{"display_name":"Unter den Linden, Berlin"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	apis.handle("api.openweathermap.org", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if q := r.URL.Query(); q.Get("appid") != "owm-key" || q.Get("units") != "metric" {
+			http.Error(w, "bad key", http.StatusUnauthorized)
+			return
+		}
+		w.Write([]byte(`{"name":"Mitte","weather":[{"description":"light rain"}],"main":{"temp":11.5,"feels_like":9,"humidity":80,"pressure":1012},"wind":{"speed":4.2},"dt":1700000000}`))
+	}))
+
+	tests := []struct {
+		name string
+		args map[string]any
+		want map[string]any
+	}{
+		{"geocode", map[string]any{"query": "Berlin"}, map[string]any{"matches": []map[string]any{
+			{"name": "Berlin, Germany", "lat": 52.5170365, "lon": 13.3888599, "type": "city"},
+		}}},
+		{"geocode", map[string]any{"query": "Atlantis"}, map[string]any{"error": "geocode: unexpected status 503 Service Unavailable"}},
+		{"geocode", map[string]any{}, map[string]any{"error": "missing query"}},
+		{"reverse_geocode", map[string]any{"lat": 52.5170365, "lon": 13.3888599}, map[string]any{"address": "Unter den Linden, Berlin"}},
+		{"current_weather", map[string]any{"lat": 52.52, "lon": 13.39}, map[string]any{
+			"place": "Mitte", "conditions": "light rain", "temperature_c": 11.5, "feels_like_c": 9.0,
+			"humidity_pct": 80.0, "pressure_hpa": 1012.0, "wind_speed_m_s": 4.2, "observed_at": "2023-11-14T22:13:20Z",
+		}},
+	}
+	for _, tt := range tests {
+		part := app.functions.invoke(context.Background(), &genai.FunctionCall{Name: tt.name, Args: tt.args})
+		got, _ := json.Marshal(part.FunctionResponse.Response)
+		if want, _ := json.Marshal(tt.want); string(got) != string(want) {
+			t.Errorf("%s(%v) = %s, want %s", tt.name, tt.args, got, want)
+		}
+	}
+	for _, agent := range agents {
+		if agent != "eteon-test" {
+			t.Errorf("Nominatim got user agent %q, want the configured one", agent)
+		}
+	}
+
+	without, _ := newTestApp(t, Config{})
+	if without.functions.has("current_weather") || !without.functions.has("geocode") {
+		t.Error("current_weather is offered without an OpenWeather key")
+	}
+}
+
+func TestLocationPart(t *testing.T) {
+	tests := []struct {
+		msg  *tele.Message
+		want string
+	}{
+		{&tele.Message{Location: &tele.Location{Lat: 52.52, Lng: 13.405}}, "shared their location: latitude 52.520000, longitude 13.405000."},
+		{&tele.Message{Venue: &tele.Venue{Location: tele.Location{Lat: 1, Lng: 2}, Title: "Café", Address: "Main St 1"}}, "shared a venue: Café, Main St 1 (latitude 1.000000, longitude 2.000000)."},
+	}
+	for _, tt := range tests {
+		if part := locationPart(tt.msg); part == nil || !strings.Contains(part.Text, tt.want) {
+			t.Errorf("locationPart = %+v, want %q", part, tt.want)
+		}
+	}
+	if part := locationPart(&tele.Message{Text: "hi"}); part != nil {
+		t.Errorf("locationPart of a text message = %+v", part)
+	}
+}
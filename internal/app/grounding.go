@@ -0,0 +1,215 @@
+package app
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/genai"
+)
+
+// urlDatePattern finds a publication date in a URL path such as /2025/05/12/
+// or /2025-05-12-title.
+var urlDatePattern = regexp.MustCompile(`/(20\d{2})[/-](0[1-9]|1[0-2])(?:[/-](0[1-9]|[12]\d|3[01]))?(?:[/-]|$)`)
+
+// groundingStats sums up what grounded an answer.
+type groundingStats struct {
+	sources int
+	// newest is the most recent publication date among the sources, zero when
+	// none carries one.
+	newest time.Time
+	// cited is the share of the answer text that grounding supports cover, or
+	// -1 when the response has no supports.
+	cited float64
+}
+
+// groundingStatsOf reads the grounding and citation metadata of a candidate;
+// ok is false when the answer was not grounded in any source.
+func groundingStatsOf(cand *genai.Candidate) (stats groundingStats, ok bool) {
+	stats.sources = len(collectSources(cand))
+	if stats.sources == 0 {
+		return stats, false
+	}
+	stats.cited = -1
+
+	consider := func(t time.Time) {
+		if t.After(stats.newest) && !t.After(time.Now()) {
+			stats.newest = t
+		}
+	}
+	if cand.CitationMetadata != nil {
+		for _, c := range cand.CitationMetadata.Citations {
+			if c == nil {
+				continue
+			}
+			if d := c.PublicationDate; d.Year > 0 && d.Month > 0 {
+				consider(time.Date(d.Year, d.Month, max(d.Day, 1), 0, 0, 0, 0, time.UTC))
+			}
+			consider(urlDate(c.URI))
+		}
+	}
+	if cand.URLContextMetadata != nil {
+		for _, m := range cand.URLContextMetadata.URLMetadata {
+			if m != nil && m.URLRetrievalStatus == genai.URLRetrievalStatusSuccess {
+				consider(urlDate(m.RetrievedURL))
+			}
+		}
+	}
+	if gm := cand.GroundingMetadata; gm != nil {
+		for _, chunk := range gm.GroundingChunks {
+			if chunk != nil && chunk.Web != nil {
+				consider(urlDate(chunk.Web.URI))
+			}
+		}
+		if len(gm.GroundingSupports) > 0 {
+			stats.cited = citedShare(cand.Content, gm.GroundingSupports)
+		}
+	}
+	return stats, true
+}
+
+// urlDate returns the date in the path of uri, or zero.
+func urlDate(uri string) time.Time {
+	m := urlDatePattern.FindStringSubmatch(uri)
+	if m == nil {
+		return time.Time{}
+	}
+	year, _ := strconv.Atoi(m[1])
+	month, _ := strconv.Atoi(m[2])
+	day := 1
+	if m[3] != "" {
+		day, _ = strconv.Atoi(m[3])
+	}
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+}
+
+// citedShare is the fraction of the visible answer text that supports cover,
+// merging overlapping segments of each part.
+func citedShare(content *genai.Content, supports []*genai.GroundingSupport) float64 {
+	if content == nil {
+		return 0
+	}
+	total := 0
+	covered := make(map[int32][]bool)
+	for i, part := range content.Parts {
+		if part == nil || part.Thought {
+			continue
+		}
+		total += len(part.Text)
+		covered[int32(i)] = make([]bool, len(part.Text))
+	}
+	if total == 0 {
+		return 0
+	}
+	cited := 0
+	for _, s := range supports {
+		if s == nil || s.Segment == nil {
+			continue
+		}
+		marks, ok := covered[s.Segment.PartIndex]
+		if !ok {
+			continue
+		}
+		for i := max(s.Segment.StartIndex, 0); i < min(s.Segment.EndIndex, int32(len(marks))); i++ {
+			if !marks[i] {
+				marks[i] = true
+				cited++
+			}
+		}
+	}
+	return float64(cited) / float64(total)
+}
+
+// groundingLabel is the line under a grounded answer saying how many sources
+// back it, how recent the newest is and how much of the answer they cover.
+func groundingLabel(lang language, cand *genai.Candidate) string {
+	stats, ok := groundingStatsOf(cand)
+	if !ok {
+		return ""
+	}
+	parts := []string{tr(lang, "grounding_sources", stats.sources)}
+	if !stats.newest.IsZero() {
+		parts = append(parts, tr(lang, "grounding_newest", stats.newest.Format("2006-01")))
+	}
+	if stats.cited >= 0 {
+		parts = append(parts, tr(lang, "grounding_cited", int(stats.cited*100+0.5)))
+	}
+	return fmt.Sprintf("_%s_", strings.Join(parts, " · "))
+}
+
+// footnotedText inserts numbered markers after the segments of text, part
+// partIndex of the answer, that grounding supports cover. Each marker links
+// to its source and carries the source's number in the "Show sources" list.
+// A segment whose offsets do not match the text is found by its text instead,
+// and markers never land inside a code block.
+func footnotedText(text string, partIndex int, gm *genai.GroundingMetadata, sources []sourceRef) string {
+	if gm == nil || len(gm.GroundingSupports) == 0 || len(sources) == 0 {
+		return text
+	}
+	numbers := make(map[string]int, len(sources))
+	for i, src := range sources {
+		numbers[src.URI] = i + 1
+	}
+	marks := make(map[int][]int)
+	for _, support := range gm.GroundingSupports {
+		if support == nil || support.Segment == nil || int(support.Segment.PartIndex) != partIndex {
+			continue
+		}
+		end := segmentEnd(text, support.Segment)
+		if end < 0 || strings.Count(text[:end], "```")%2 == 1 {
+			continue
+		}
+		for _, index := range support.GroundingChunkIndices {
+			if index < 0 || int(index) >= len(gm.GroundingChunks) {
+				continue
+			}
+			chunk := gm.GroundingChunks[index]
+			if chunk == nil || chunk.Web == nil {
+				continue
+			}
+			if n := numbers[strings.TrimSpace(chunk.Web.URI)]; n > 0 && !slices.Contains(marks[end], n) {
+				marks[end] = append(marks[end], n)
+			}
+		}
+	}
+	if len(marks) == 0 {
+		return text
+	}
+	var b strings.Builder
+	prev := 0
+	for _, end := range slices.Sorted(maps.Keys(marks)) {
+		b.WriteString(text[prev:end])
+		cited := marks[end]
+		slices.Sort(cited)
+		for _, n := range cited {
+			fmt.Fprintf(&b, "[[%d]](%s)", n, sources[n-1].URI)
+		}
+		prev = end
+	}
+	b.WriteString(text[prev:])
+	return b.String()
+}
+
+// segmentEnd returns the byte offset in text where seg ends, or -1 when the
+// segment cannot be found.
+func segmentEnd(text string, seg *genai.Segment) int {
+	start, end := int(seg.StartIndex), int(seg.EndIndex)
+	if start >= 0 && start < end && end <= len(text) && (end == len(text) || utf8.RuneStart(text[end])) {
+		if seg.Text == "" || text[start:end] == seg.Text {
+			return end
+		}
+	}
+	if seg.Text == "" {
+		return -1
+	}
+	i := strings.Index(text, seg.Text)
+	if i < 0 {
+		return -1
+	}
+	return i + len(seg.Text)
+}
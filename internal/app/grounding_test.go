@@ -0,0 +1,83 @@
+package app
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+func TestGroundingLabel(t *testing.T) {
+	answer := "Go 1.24 shipped in February. It adds generic type aliases."
+	cand := &genai.Candidate{
+		Content: genai.NewContentFromText(answer, genai.RoleModel),
+		GroundingMetadata: &genai.GroundingMetadata{
+			GroundingChunks: []*genai.GroundingChunk{
+				{Web: &genai.GroundingChunkWeb{URI: "https://go.dev/blog/2025/02/go1.24", Title: "go.dev"}},
+				{Web: &genai.GroundingChunkWeb{URI: "https://news.example.com/2024/08/12/go", Title: "example.com"}},
+				{Web: &genai.GroundingChunkWeb{URI: "https://vertexaisearch.cloud.google.com/redirect/abc", Title: "other"}},
+			},
+			GroundingSupports: []*genai.GroundingSupport{
+				{Segment: &genai.Segment{StartIndex: 0, EndIndex: 29}},
+				{Segment: &genai.Segment{StartIndex: 10, EndIndex: 29}},
+			},
+		},
+	}
+	want := "_Sources: 3 · newest from 2025-02 · 50% of the answer cited_"
+	if got := groundingLabel(defaultLanguage, cand); got != want {
+		t.Errorf("groundingLabel = %q, want %q", got, want)
+	}
+
+	cand.GroundingMetadata.GroundingSupports = nil
+	cand.GroundingMetadata.GroundingChunks = cand.GroundingMetadata.GroundingChunks[2:]
+	if got, want := groundingLabel(defaultLanguage, cand), "_Sources: 1_"; got != want {
+		t.Errorf("undated groundingLabel = %q, want %q", got, want)
+	}
+
+	if got := groundingLabel(defaultLanguage, &genai.Candidate{Content: cand.Content}); got != "" {
+		t.Errorf("ungrounded answer labelled %q", got)
+	}
+}
+
+func TestURLDate(t *testing.T) {
+	tests := map[string]string{
+		"https://example.com/2025/05/12/post":  "2025-05-12",
+		"https://example.com/news/2024-11-rel": "2024-11-01",
+		"https://example.com/v2025/13/x":       "",
+		"https://example.com/item/20250512":    "",
+	}
+	for uri, want := range tests {
+		got := ""
+		if d := urlDate(uri); !d.IsZero() {
+			got = d.Format(time.DateOnly)
+		}
+		if got != want {
+			t.Errorf("urlDate(%q) = %q, want %q", uri, got, want)
+		}
+	}
+}
+
+func TestFootnotedText(t *testing.T) {
+	answer := "Go 1.24 shipped in February. It adds generic type aliases.\n```go\ntype A = B\n```"
+	gm := &genai.GroundingMetadata{
+		GroundingChunks: []*genai.GroundingChunk{
+			{Web: &genai.GroundingChunkWeb{URI: "https://go.dev/blog/go1.24"}},
+			{Web: &genai.GroundingChunkWeb{URI: "https://example.com/go"}},
+		},
+		GroundingSupports: []*genai.GroundingSupport{
+			{Segment: &genai.Segment{StartIndex: 0, EndIndex: 28}, GroundingChunkIndices: []int32{1, 0}},
+			{Segment: &genai.Segment{StartIndex: 0, EndIndex: 5, Text: "It adds generic type aliases."}, GroundingChunkIndices: []int32{1}},
+			{Segment: &genai.Segment{StartIndex: 63, EndIndex: 68}, GroundingChunkIndices: []int32{0}},
+			{Segment: &genai.Segment{PartIndex: 1, StartIndex: 0, EndIndex: 28}, GroundingChunkIndices: []int32{0}},
+		},
+	}
+	sources := []sourceRef{{URI: "https://go.dev/blog/go1.24"}, {URI: "https://example.com/go"}}
+	want := "Go 1.24 shipped in February.[[1]](https://go.dev/blog/go1.24)[[2]](https://example.com/go)" +
+		" It adds generic type aliases.[[2]](https://example.com/go)\n```go\ntype A = B\n```"
+	if got := footnotedText(answer, 0, gm, sources); got != want {
+		t.Errorf("footnotedText =\n%s\nwant\n%s", got, want)
+	}
+	if got := footnotedText(answer, 0, nil, sources); got != answer {
+		t.Errorf("ungrounded text changed to %q", got)
+	}
+}
@@ -0,0 +1,85 @@
+package app
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const maxGroupContextLength = 2000
+
+func isGroupChat(chat *tele.Chat) bool {
+	return chat != nil && (chat.Type == tele.ChatGroup || chat.Type == tele.ChatSuperGroup)
+}
+
+// groupContextText describes a group from its getChat details, or returns "" when
+// the group has neither a description nor a pinned message.
+func groupContextText(chat *tele.Chat) string {
+	var sections []string
+	if desc := strings.TrimSpace(chat.Description); desc != "" {
+		sections = append(sections, "Description:\n"+desc)
+	}
+	if chat.PinnedMessage != nil {
+		if pinned := messagePrompt(chat.PinnedMessage); pinned != "" {
+			sections = append(sections, "Pinned message:\n"+pinned)
+		}
+	}
+	if len(sections) == 0 {
+		return ""
+	}
+	text := strings.Join(sections, "\n\n")
+	if utf8.RuneCountInString(text) > maxGroupContextLength {
+		text = string([]rune(text)[:maxGroupContextLength-1]) + "…"
+	}
+	return text
+}
+
+func groupContextInstruction(title, context string) string {
+	if context == "" {
+		return ""
+	}
+	name := "this Telegram group"
+	if title = strings.TrimSpace(title); title != "" {
+		name = "the Telegram group \"" + title + "\""
+	}
+	return "You are chatting in " + name + ". Use its description and pinned message below to understand the group's purpose and rules, and follow those rules:\n" + context
+}
+
+// ensureGroupContext loads the description and pinned message of a group into its
+// session the first time the bot answers there. A failed lookup is retried on the
+// next message.
+func (a *App) ensureGroupContext(chat *tele.Chat, session *sessionState) {
+	if !a.groupContext || !isGroupChat(chat) {
+		return
+	}
+	session.mu.Lock()
+	loaded := session.groupContextLoaded
+	session.mu.Unlock()
+	if loaded {
+		return
+	}
+
+	full, err := a.bot.ChatByID(chat.ID)
+	if err != nil {
+		a.chatLog(chat.ID).Warn("load group context failed", "chat_id", chat.ID, "err", err)
+		return
+	}
+	session.mu.Lock()
+	session.groupContext = groupContextInstruction(full.Title, groupContextText(full))
+	session.groupContextLoaded = true
+	session.mu.Unlock()
+}
+
+// handlePinned refreshes the group context on the next message after a new pin.
+func (a *App) handlePinned(c tele.Context) error {
+	if !a.groupContext || !isGroupChat(c.Chat()) {
+		return nil
+	}
+	for _, session := range a.sessions.ofChat(c.Chat().ID) {
+		session.mu.Lock()
+		session.groupContextLoaded = false
+		session.mu.Unlock()
+	}
+	return nil
+}
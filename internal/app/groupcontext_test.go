@@ -0,0 +1,106 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestGroupContextText(t *testing.T) {
+	pinned := &tele.Message{Text: "Rule 1: no spoilers."}
+	tests := []struct {
+		name string
+		chat *tele.Chat
+		want string
+	}{
+		{"nothing", &tele.Chat{Description: "  "}, ""},
+		{"description", &tele.Chat{Description: " Trail talk only. "}, "Description:\nTrail talk only."},
+		{"pinned message", &tele.Chat{PinnedMessage: pinned}, "Pinned message:\nRule 1: no spoilers."},
+		{"both", &tele.Chat{Description: "Books.", PinnedMessage: pinned}, "Description:\nBooks.\n\nPinned message:\nRule 1: no spoilers."},
+		{"empty pin", &tele.Chat{Description: "Books.", PinnedMessage: &tele.Message{}}, "Description:\nBooks."},
+	}
+	for _, tt := range tests {
+		if got := groupContextText(tt.chat); got != tt.want {
+			t.Errorf("%s: groupContextText = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	long := groupContextText(&tele.Chat{Description: strings.Repeat("é", 3*maxGroupContextLength)})
+	if n := utf8.RuneCountInString(long); n != maxGroupContextLength || !strings.HasSuffix(long, "…") {
+		t.Errorf("long context has %d characters, want %d ending in an ellipsis", n, maxGroupContextLength)
+	}
+
+	if got := groupContextInstruction("Hikers", ""); got != "" {
+		t.Errorf("instruction without context = %q", got)
+	}
+	if got := groupContextInstruction(" ", "Description:\nBooks."); !strings.HasPrefix(got, "You are chatting in this Telegram group.") {
+		t.Errorf("instruction without a title = %q", got)
+	}
+}
+
+func TestGroupContextLoadsOnceAndAfterAPin(t *testing.T) {
+	app, apis := newTestApp(t, Config{GroupContext: true})
+	apis.chats = map[string]map[string]any{
+		"-100": {"id": -100, "type": "supergroup", "title": "Hikers", "description": "Trail talk only."},
+	}
+	group := &tele.Chat{ID: -100, Type: tele.ChatSuperGroup}
+	ask := func(chat *tele.Chat) string {
+		t.Helper()
+		msg := testMessage(chat.ID, "Any tips?")
+		msg.Chat = chat
+		if err := app.processMessage(context.Background(), msg, turnOptions{}); err != nil {
+			t.Fatalf("processMessage: %v", err)
+		}
+		calls := apis.callsTo(geminiHost, ":generateContent")
+		return string(calls[len(calls)-1].body)
+	}
+	lookups := func() int { return len(apis.callsTo(telegramHost, "getChat")) }
+
+	for range 2 {
+		if body := ask(group); !strings.Contains(body, `the Telegram group \"Hikers\"`) || !strings.Contains(body, "Trail talk only.") {
+			t.Fatalf("request lacks the group context: %.400s", body)
+		}
+	}
+	if n := lookups(); n != 1 {
+		t.Errorf("looked the group up %d times, want once", n)
+	}
+
+	apis.chats["-100"]["pinned_message"] = map[string]any{"message_id": 9, "date": 1, "chat": map[string]any{"id": -100, "type": "supergroup"}, "text": "Meet at 8."}
+	pin := testMessage(-100, "")
+	pin.Chat = group
+	if err := app.handlePinned(app.bot.NewContext(tele.Update{Message: pin})); err != nil {
+		t.Fatalf("handlePinned: %v", err)
+	}
+	if body := ask(group); !strings.Contains(body, "Pinned message:\\nMeet at 8.") {
+		t.Errorf("request lacks the new pin: %.400s", body)
+	}
+	if n := lookups(); n != 2 {
+		t.Errorf("looked the group up %d times, want again after the pin", n)
+	}
+
+	unknown := &tele.Chat{ID: -200, Type: tele.ChatGroup}
+	ask(unknown)
+	ask(unknown)
+	if n := lookups(); n != 4 {
+		t.Errorf("looked the groups up %d times, want a failed lookup retried", n)
+	}
+	ask(&tele.Chat{ID: 42, Type: tele.ChatPrivate})
+	if n := lookups(); n != 4 {
+		t.Error("a private chat was looked up")
+	}
+}
+
+func TestGroupContextOff(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	msg := testMessage(-100, "Any tips?")
+	msg.Chat.Type = tele.ChatSuperGroup
+	if err := app.processMessage(context.Background(), msg, turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	if n := len(apis.callsTo(telegramHost, "getChat")); n != 0 {
+		t.Errorf("looked the group up %d times with GROUP_CONTEXT off", n)
+	}
+}
@@ -0,0 +1,2353 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// language is a two-letter code of a language the bot's own messages are translated to.
+type language string
+
+const defaultLanguage language = "en"
+
+var languageNames = map[language]string{
+	"en": "English",
+	"de": "Deutsch",
+	"es": "Español",
+	"ru": "Русский",
+	"uk": "Українська",
+}
+
+// catalogs holds the bot-authored messages per language. English is complete;
+// a key missing from another language falls back to it.
+var catalogs = map[language]map[string]string{
+	"en": {
+		"welcome":            "Hi, I am Eteon. Share a prompt, a link, or media and I will respond concisely.",
+		"thinking_current":   "Current thinking budget: %s",
+		"thinking_switched":  "Thinking budget switched to %s",
+		"sampling_temp":      "Temp",
+		"sampling_top_p":     "Top-p",
+		"sampling_tokens":    "Tokens",
+		"sampling_reset":     "Reset sampling",
+		"tool_search":        "Web search",
+		"tool_links":         "Links",
+		"tool_code":          "Code",
+		"tool_on":            "on",
+		"tool_off":           "off",
+		"tools_current":      "Tools: %s. Turn them all off with /settings tools off for answers from the model alone.",
+		"tools_usage":        "Usage: /settings tools on|off, or /settings google_search, url_context or code_execution with on or off.",
+		"json_usage":         "Usage: /json <JSON schema or description of the shape> turns JSON mode on; /json off turns it off.",
+		"json_on":            "JSON mode on. Replies are JSON shaped as: %s\nSend /json off for normal replies.",
+		"json_off":           "JSON mode off. Replies are normal text again.",
+		"json_bad_schema":    "That looks like a JSON schema but does not parse: %s",
+		"json_document":      "The JSON is in the document above.",
+		"sampling_default":   "default",
+		"sampling_current":   "Temperature: %s, top-p: %s, max reply tokens: %s",
+		"sampling_usage":     "Usage: /settings temperature <0..2>, /settings top_p <0..1> or /settings max_tokens <%d..%d>, each also with default; /settings reset clears them.",
+		"topic_not_forum":    "/topic works inside a topic of a forum group.",
+		"topic_admins_only":  "Only group admins can change the settings of this topic.",
+		"topic_current":      "Topic persona: %s\nTopic tools: %s",
+		"topic_default":      "chat default",
+		"topic_usage":        "Usage: /topic persona <instructions|default>, /topic tools <all|none|list of %s> or /topic reset.",
+		"topic_too_long":     "That persona is too long (%d characters). The limit is %d.",
+		"export_usage":       "Usage: /export [md|json|html]. Markdown is the default.",
+		"export_empty":       "There is no conversation to export yet.",
+		"import_usage":       "Send a JSON transcript from /export with /import as its caption, or reply /import to it. It replaces the conversation in this chat.",
+		"import_too_large":   "That transcript is too large. The limit is %d KB.",
+		"import_invalid":     "That file is not a JSON transcript from /export.",
+		"import_done":        "Conversation restored with %d messages.",
+		"checkpoint_usage":   "Usage: /checkpoint [name]. Names are one word of up to 32 letters, digits, dots, dashes or underscores.",
+		"checkpoint_empty":   "There is no conversation to save yet.",
+		"checkpoint_saved":   "Checkpoint %s saved with %d messages. Use /rollback %s to return to it.",
+		"rollback_none":      "No checkpoints yet. Save one with /checkpoint [name].",
+		"rollback_list":      "Checkpoints, newest first (name · saved · messages):\n%s\nUse /rollback <name> to return to one.",
+		"rollback_unknown":   "There is no checkpoint %s. Send /rollback to list them.",
+		"rollback_done":      "Back at checkpoint %s with %d messages.",
+		"rollback_saved":     "The conversation you left is saved as %s.",
+		"chat_usage":         "Usage: /chat lists your conversations, /chat new <name> starts one, /chat switch <name> returns to one and /chat delete <name> deletes one. Names are one word of up to 32 letters, digits, dots, dashes or underscores.",
+		"chat_list":          "Conversations (name · messages):\n%s\nUse /chat switch <name> to change.",
+		"chat_exists":        "There is already a conversation %s. Use /chat switch %s to return to it.",
+		"chat_too_many":      "A chat can hold %d conversations. Delete one with /chat delete <name> first.",
+		"chat_started":       "Started the conversation %s. The previous one is kept.",
+		"chat_unknown":       "There is no conversation %s. Send /chat to list them.",
+		"chat_switched":      "Switched to %s with %d messages.",
+		"chat_delete_active": "Switch to another conversation before deleting this one.",
+		"chat_deleted":       "Conversation %s deleted.",
+		"catchup_groups":     "/catchup works in groups.",
+		"catchup_admins":     "Only group admins can turn catch-up on or off.",
+		"catchup_on":         "Catch-up is on. I keep the messages of the last %d hours in memory only, at most %d, and never store them on disk. /catchup [hours] summarizes them; /catchup off deletes them. I can only see every message if privacy mode is off for me or I am an admin of the group.",
+		"catchup_off":        "Catch-up is off and the buffered messages are deleted.",
+		"catchup_disabled":   "Catch-up is off in this group. An admin can turn it on with /catchup on.",
+		"catchup_usage":      "Usage: /catchup [hours from 1 to %d], or /catchup on|off for admins.",
+		"catchup_empty":      "Nothing was said in the last %d hours.",
+		"catchup_failed":     "I could not summarize the discussion. Please try again later.",
+		"catchup_header":     "What you missed in the last %d hours:",
+		"sendto_usage":       "Usage: /sendto <target> <what to write>, /sendto remove <target>, or in the target chat /sendto allow <name> and /sendto revoke. Names use a-z, 0-9, - and _.",
+		"sendto_none":        "You have no saved targets. Run /sendto allow <name> in the chat that should receive your messages; in groups an admin has to run it.",
+		"sendto_list":        "Your targets: %s. Draft a message with /sendto <target> <what to write>.",
+		"sendto_allowed":     "This chat is saved as target %s. Draft messages for it from any chat with /sendto %[1]s <what to write>.",
+		"sendto_admins_only": "Only group admins can let this chat receive drafted messages.",
+		"sendto_revoked":     "Removed %d targets pointing to this chat.",
+		"sendto_removed":     "Target %s removed.",
+		"sendto_unknown":     "There is no target named %s. /sendto lists yours.",
+		"sendto_preview":     "Draft for %s. Send it as is, discard it, or run /sendto again with changes:",
+		"sendto_no_draft":    "I could not write the draft. Please try again.",
+		"btn_send_draft":     "Send",
+		"btn_discard_draft":  "Discard",
+		"sendto_sent":        "Sent to %s.",
+		"sendto_failed":      "I could not send to %s. Check that I am still a member allowed to post there.",
+		"sendto_expired":     "This draft has expired. Run /sendto again.",
+		"sendto_discarded":   "Draft discarded.",
+		"sendto_not_yours":   "Only the author of this draft can send or discard it.",
+		"remind_usage":       "Usage: /remind in 2h30m <text>, /remind at 18:00 <text>, /remind tomorrow 9:00 <text> or /remind 2025-06-01 9:00 <text>. Start the text with ask: to have me answer it as a prompt at that time. /remind lists your reminders, /remind cancel <id> cancels one and /remind tz <Area/City> sets the time zone.",
+		"remind_none":        "You have no reminders in this chat.",
+		"remind_list":        "Your reminders:\n%s",
+		"remind_unknown":     "There is no reminder #%d of yours here.",
+		"remind_cancelled":   "Reminder #%d cancelled.",
+		"remind_bad_tz":      "Unknown time zone. Use a name such as Europe/Berlin or America/New_York.",
+		"remind_tz":          "Time zone set to %s.",
+		"remind_range":       "Reminders have to be in the future and at most a year ahead.",
+		"remind_too_many":    "You already have %d reminders. Cancel one first.",
+		"remind_set":         "Reminder #%d set for %s.",
+		"remind_fire":        "⏰ Reminder: %s",
+		"digest_usage":       "Usage: /digest <daily|weekdays|weekends|mon,thu> <HH:MM> <prompt>, e.g. /digest daily 8:00 summarize the Hacker News front page. I answer the prompt with web search at that time. /digest lists your digests and /digest cancel <id> cancels one. Times use the chat's time zone from /remind tz.",
+		"digest_none":        "You have no digests in this chat.",
+		"digest_list":        "Your digests (%s):\n%s",
+		"digest_unknown":     "There is no digest #%d of yours here.",
+		"digest_cancelled":   "Digest #%d cancelled.",
+		"digest_too_many":    "You already have %d digests. Cancel one first.",
+		"digest_set":         "Digest #%d scheduled %s (%s). First one on %s.",
+		"input_failed":       "I could not process that input.",
+		"rate_limited":       "You are sending messages too fast. Try again in %d seconds.",
+		"file_too_large":     "That file is larger than 20 MB, the most Telegram lets bots download. Please send a smaller or compressed version.",
+		"input_empty":        "Please send text or supported media.",
+		"request_failed":     "Eteon could not complete that request.",
+		"request_cancelled":  "Request cancelled.",
+		"calc_no_code":       "I could not back this answer with executed code, so I will not give a number. Try rephrasing the calculation.",
+		"blocked_reason":     "The request was blocked by Gemini (%s).",
+		"blocked_hint":       "Rephrase it without the flagged content, or split it into smaller questions.",
+		"safety_categories":  "Flagged categories: %s.",
+		"finish_max_tokens":  "The answer hit the length limit. Tap Continue for the rest, or allow longer replies with /verbosity or /settings.",
+		"finish_safety":      "The answer was stopped by the safety filters.",
+		"finish_recitation":  "The answer was stopped because it closely reproduced existing text, such as a copyrighted source. Ask for a summary or a paraphrase instead.",
+		"finish_other":       "The answer ended early (%s). Try again or rephrase the request.",
+		"btn_continue":       "Continue",
+		"continue_stale":     "Only the latest answer can be continued.",
+		"no_content":         "No content received.",
+		"fallback_notice":    "Answered by %s because %s was unavailable.",
+		"light_notice":       "The bot is very busy right now, so this answer got less reasoning than usual.",
+		"budget_notice":      "Today's usage limit is reached, so this answer comes from a smaller model.",
+		"budget_refused":     "The bot has reached its usage limit for today. Please try again after %s.",
+		"budget_user_limit":  "You have reached your usage limit for today. Please try again after %s.",
+		"grounding_sources":  "Sources: %d",
+		"grounding_newest":   "newest from %s",
+		"grounding_cited":    "%d%% of the answer cited",
+		"queue_full":         "You already have several messages waiting. Please wait for the replies before sending more.",
+		"queue_draining":     "The bot is about to restart. Please send your message again in a minute.",
+		"queued_one":         "Queued, working on your previous message.",
+		"queued_many":        "Queued behind your previous messages. I will get to this one shortly.",
+		"calls_waiting":      "Busy right now: you are number %d in line, about %s to wait.",
+		"calls_saturated":    "Eteon is overloaded right now. Please try again in a minute.",
+		"btn_cancel_request": "Cancel current request",
+		"cancel_started":     "Cancelling the current request.",
+		"cancel_nothing":     "There is no request in progress.",
+		"cancel_not_yours":   "Only the person who sent the current request or a chat admin can cancel it.",
+		"btn_thoughts":       "Show thoughts",
+		"btn_sources":        "Show sources",
+		"btn_code":           "Show code",
+		"btn_obsidian":       "Export as Obsidian note",
+		"btn_notion":         "Save to Notion",
+		"btn_review_patch":   "Export review as .patch comments",
+		"btn_regenerate":     "Regenerate",
+		"btn_compare":        "Compare with previous",
+		"btn_bookmark":       "⭐ Bookmark",
+		"bookmark_expired":   "This reply is too old to bookmark.",
+		"bookmark_saved":     "Bookmarked as #%d. See /bookmarks.",
+		"bookmark_exists":    "Already bookmarked as #%d.",
+		"bookmark_too_many":  "You have %d bookmarks. Delete some with /bookmarks delete <id> first.",
+		"bookmark_failed":    "Could not save the bookmark. Try again later.",
+		"bookmarks_usage":    "Usage: /bookmarks lists your bookmarks, /bookmarks <words> searches them, /bookmarks <id> shows one and /bookmarks delete <id> deletes one.",
+		"bookmarks_none":     "You have no bookmarks yet. Press ⭐ Bookmark under a reply to save it.",
+		"bookmarks_no_match": "No bookmarks contain %q.",
+		"bookmarks_list":     "Bookmarks (%d):\n%s\nSend /bookmarks <id> to open one.",
+		"bookmark_unknown":   "There is no bookmark #%d of yours.",
+		"bookmark_deleted":   "Bookmark #%d deleted.",
+		"feedback_expired":   "This reply is too old to rate.",
+		"feedback_thanks":    "Thanks! I'll use this to fit my answers to you. See /remember.",
+		"feedback_same":      "You already rated this reply.",
+		"feedback_failed":    "Could not save your feedback. Try again later.",
+		"remember_usage":     "Usage: /remember <fact> remembers something about you in every chat, even after /reset (e.g. /remember I am vegetarian). /remember shows what I remember and what I learned from your ratings, /memories lists the facts, /forget <id> forgets one and /remember reset forgets everything including your 👍/👎 feedback.",
+		"remember_show":      "What I remember about you:\n%s\n\nLearned from %d ratings:\n%s\n\nAdd a fact with /remember <fact>, forget one with /forget <id>.",
+		"remember_no_notes":  "none yet",
+		"remember_unrated":   "nothing yet - rate at least %d replies with 👍 or 👎",
+		"remember_saved":     "Got it, I'll remember that in every chat as #%d. See /memories.",
+		"remember_exists":    "I already remember that as #%d.",
+		"remember_too_long":  "Keep a fact under %d characters.",
+		"remember_too_many":  "I remember %d facts about you. Forget one with /forget <id> first.",
+		"memory_unknown":     "There is no memory #%d.",
+		"memory_forgot":      "Memory #%d forgotten.",
+		"remember_reset":     "Your memories and ratings are forgotten.",
+		"remember_failed":    "Could not save your memories. Try again later.",
+		"memories_none":      "I remember nothing about you yet. Tell me something with /remember <fact>.",
+		"memories_list":      "What I remember about you (%d):\n%s\nForget one with /forget <id>.",
+		"memories_cleared":   "All your memories are forgotten.",
+		"forget_usage":       "Usage: /forget <id> forgets one memory from /memories, /forget all forgets every one.",
+		"memories_auto":      "Usage: /memories auto on lets me pick out facts about you from our private chat for you to keep or delete, /memories auto off stops it.",
+		"memories_auto_on":   "Every %d messages I'll look for facts worth remembering in our private chat and ask you before keeping them. Turn it off with /memories auto off.",
+		"memories_auto_off":  "I'll only remember what you tell me with /remember.",
+		"memories_pending":   "⏳ marks facts I picked up from our chats. Keep or delete them with the buttons.",
+		"memories_extracted": "I picked up these facts about you. Keep the ones I should remember:\n%s",
+		"memory_kept":        "Memory #%d kept.",
+		"kb_usage":           "Usage: reply /kb add to a document, or send one with /kb add as its caption, to add it to this chat's knowledge base. Questions here are then answered from it. /kb list lists the documents, /kb delete <id> removes one and /kb delete all removes them all. /kb share editor|reader invites other chats of your team, /kb join <token> uses a shared knowledge base and /kb leave goes back to this chat's own.",
+		"kb_added":           "Added %s to the knowledge base as #%d (%s). Ask away.",
+		"kb_passages":        "%d passages",
+		"kb_truncated":       "The document is long, so only its beginning is indexed.",
+		"kb_list":            "Knowledge base (%d documents):\n%s\nRemove one with /kb delete <id>.",
+		"kb_empty":           "This chat's knowledge base is empty. Reply /kb add to a document to add it.",
+		"kb_unsupported":     "I can't read the text of %s. Add PDF, Word, Excel, EPUB or plain-text documents.",
+		"kb_full":            "The knowledge base holds at most %d passages. Remove a document with /kb delete <id> first.",
+		"kb_unknown":         "There is no document #%d in the knowledge base.",
+		"kb_deleted":         "Document #%d removed from the knowledge base.",
+		"kb_cleared":         "The knowledge base is empty now.",
+		"kb_read_only":       "This chat reads a shared knowledge base and cannot change its documents.",
+		"kb_owner_only":      "Only the chat that owns this knowledge base can share it.",
+		"kb_admins_only":     "Only group admins can change how this chat shares knowledge bases.",
+		"kb_share_usage":     "Usage: /kb share editor lets other chats add and remove documents, /kb share reader only lets them ask.",
+		"kb_invite":          "Invite created. Send this in the other chat:\n`/kb join %s`\nIt joins as %s. The invite works for any number of chats for %d days.",
+		"kb_join_usage":      "Usage: /kb join <token>, with a token from /kb share.",
+		"kb_join_invalid":    "That invite is unknown or has expired. Ask for a new one from /kb share.",
+		"kb_join_own":        "This chat already owns that knowledge base.",
+		"kb_joined":          "This chat now uses the shared knowledge base as %s. Its own documents are kept aside until /kb leave.",
+		"kb_not_shared":      "This chat uses its own knowledge base.",
+		"kb_left":            "This chat uses its own knowledge base again.",
+		"kb_members_none":    "This knowledge base is not shared. Invite other chats with /kb share editor|reader.",
+		"kb_members":         "Shared with (chat · role):\n%s\nOpen invites: %d. Stop sharing with /kb revoke <chat id> or /kb revoke all.",
+		"kb_revoked":         "Chat %d no longer shares this knowledge base.",
+		"kb_revoked_all":     "This knowledge base is no longer shared, and its invites no longer work.",
+		"kb_not_member":      "Chat %d does not share this knowledge base.",
+		"kb_role_owner":      "owner",
+		"kb_role_editor":     "editor",
+		"kb_role_reader":     "reader",
+		"kb_failed":          "Could not update the knowledge base. Try again later.",
+		"recall_usage":       "Usage: /recall <query> answers from earlier exchanges of this chat that no longer fit in the conversation history. /recall on or /recall off turns their automatic retrieval for regular messages on or off, and /recall clear deletes them. This chat has %d archived exchanges.",
+		"recall_auto_on":     "Earlier exchanges that relate to a message are pulled back in automatically.",
+		"recall_auto_off":    "Earlier exchanges are only searched with /recall <query>.",
+		"recall_cleared":     "The archive of earlier exchanges is empty now.",
+		"recall_empty":       "Nothing has left this chat's conversation history yet, so there is nothing to recall.",
+		"recall_failed":      "Could not update the archive of earlier exchanges. Try again later.",
+		"privacy_usage":      "/privacy on answers every message on its own: the conversation is not kept, replies have no buttons because their contents are not stored, and the logs leave out what you send. Turning it on deletes the chat's history. /privacy off keeps conversations again. Settings, bookmarks, memories and the knowledge base stay either way.",
+		"privacy_state_on":   "Privacy mode is on in this chat.",
+		"privacy_state_off":  "Privacy mode is off in this chat.",
+		"privacy_on":         "Privacy mode is on. The chat's history is deleted, and each message is now answered on its own without being kept.",
+		"privacy_off":        "Privacy mode is off. New messages are kept as the conversation again.",
+		"privacy_admins":     "Only group admins can switch privacy mode.",
+		"privacy_failed":     "Could not switch privacy mode. Try again later.",
+		"catchup_private":    "Catch-up keeps the group's messages, so it cannot be turned on in privacy mode.",
+		"ephemeral_usage":    "/ephemeral <time> deletes my messages in this chat after the given time, such as 10m or 2h, up to %s. /ephemeral <time> prompts deletes the messages addressed to me as well; in groups I need the right to delete messages for that. /ephemeral off keeps messages again.",
+		"ephemeral_is_off":   "Ephemeral mode is off in this chat.",
+		"ephemeral_on":       "Ephemeral mode is on: I delete my messages %s after sending them.",
+		"ephemeral_prompts":  "Ephemeral mode is on: I delete my messages and the messages addressed to me %s after they were sent.",
+		"ephemeral_off":      "Ephemeral mode is off. Messages already sent are still deleted on time.",
+		"ephemeral_admins":   "Only group admins can switch ephemeral mode.",
+		"ephemeral_failed":   "Could not switch ephemeral mode. Try again later.",
+		"replyto_usage":      "/replyto on makes my answers replies to the message that asked, which keeps questions and answers together in busy chats. /replyto off sends them on their own. /replyto auto goes back to the default: replies in groups, plain answers in private chats.",
+		"replyto_state_on":   "My answers in this chat reply to the message that asked.",
+		"replyto_state_off":  "My answers in this chat are sent on their own.",
+		"replyto_on":         "My answers now reply to the message that asked.",
+		"replyto_off":        "My answers are now sent on their own.",
+		"replyto_auto_on":    "Back to the default: my answers here reply to the message that asked.",
+		"replyto_auto_off":   "Back to the default: my answers here are sent on their own.",
+		"replyto_admins":     "Only group admins can change how I answer.",
+		"replyto_failed":     "Could not change how I answer. Try again later.",
+		"regenerate_stale":   "Only the latest answer can be regenerated.",
+		"compare_none":       "The new answer does not differ from the previous one.",
+		"compare_header":     "Changes from the previous answer: %s",
+		"thoughts_working":   "Summarising thoughts...",
+		"thoughts_none":      "Reasoning summary is unavailable.",
+		"thoughts_header":    "Reasoning summary:",
+		"btn_thoughts_full":  "Full reasoning",
+		"thoughts_full":      "Full reasoning trace",
+		"sources_none":       "No sources available for this reply.",
+		"sources_header":     "Sources:",
+		"source_untitled":    "Untitled",
+		"code_none":          "No executable code was used for this reply.",
+		"verbosity_short":    "Short",
+		"verbosity_normal":   "Normal",
+		"verbosity_long":     "Long",
+		"verbosity_set":      "Reply length set to %s.",
+		"verbosity_current":  "Current reply length: %s",
+		"tone_professional":  "Professional",
+		"tone_neutral":       "Neutral",
+		"tone_playful":       "Playful 🎉",
+		"tone_set":           "Tone set to %s.",
+		"tone_current":       "Current tone: %s\nProfessional replies never contain emoji or profanity; playful ones use emoji freely.",
+		"comparedocs_usage":  "Send the two documents to compare, as an album or one after the other. You can also send them as an album captioned /compare_docs, or reply /compare_docs to one of them.",
+		"comparedocs_next":   "Got %s. Now send the document to compare it with.",
+		"comparedocs_failed": "I could not compare these documents.",
+		"comparedocs_title":  "Comparison: %s vs %s",
+		"comparedocs_aspect": "Aspect",
+		"comparedocs_common": "In common",
+		"comparedocs_newer":  "Newer: %s",
+		"comparedocs_done":   "More complete: %s",
+		"comparedocs_same":   "equal",
+		"comparedocs_unsure": "cannot tell",
+		"pipeline_step":      "%s: step %d/%d, %s…",
+		"pipeline_done":      "%s is ready.",
+		"pipeline_failed":    "%s failed. Please try again later.",
+		"pipeline_usage":     "Send %s with the text to work on, reply with it to a message or document, or send a document with it as the caption.",
+		"contract_title":     "Contract review",
+		"contract_summary":   "Summary",
+		"contract_duties":    "Obligations",
+		"contract_risks":     "Risks",
+		"contract_unusual":   "Unusual and missing clauses",
+		"contract_caveat":    "This review was generated automatically and is not legal advice.",
+		"contract_usage":     "/contract on reviews every document you send without a caption as a contract: a summary, the obligations, the risks and unusual clauses, returned as a report. Reply /contract to a document, or caption it /contract, to review just that one.",
+		"contract_on":        "Contract review enabled. Documents sent without a caption are reviewed as contracts.",
+		"contract_off":       "Contract review disabled.",
+		"transcribe_usage":   "Reply /transcribe to a voice note, audio or video, or send one with /transcribe as its caption. Add srt for subtitles.",
+		"ocr_usage":          "Reply /ocr to a photo, image or PDF scan, or send one with /ocr as its caption, to get its text verbatim.",
+		"ocr_failed":         "I could not read the text of this scan.",
+		"ocr_empty":          "I found no text in this scan.",
+		"ocr_caption":        "Extracted text",
+		"ocr_page":           "Page %d",
+		"summarize_usage":    "Send /summarize [short|medium|detailed] [quotes] with a text or link, reply with it to a message, link, document or voice note, or send a document with /summarize as its caption.",
+		"summarize_failed":   "I could not summarize this.",
+		"summarize_bad_link": "I could not read the linked page.",
+		"summarize_caption":  "Summary",
+		"summarize_quotes":   "Key quotes",
+		"translate_usage":    "Usage: /translate <language> <text>, or as a reply to a message, document or voice note. The language is a code such as de, a name, or a pair such as en-de.",
+		"translate_failed":   "I could not translate this.",
+		"translate_caption":  "Translation into %s",
+		"search_usage":       "Usage: /search <query>. The answer always comes from a fresh Google search, with its sources.",
+		"search_unsourced":   "The search found no sources for this answer, so treat it with care.",
+		"transcribe_failed":  "I could not transcribe that recording.",
+		"transcribe_empty":   "I could not hear anyone speaking in that recording.",
+		"transcribe_caption": "Full transcript",
+		"disclaimer_medical": "This is general information, not medical advice. Please ask a doctor or pharmacist about your situation.",
+		"disclaimer_finance": "This is general information, not financial advice. Please check important decisions with an adviser.",
+		"selfcheck_on":       "Self-check enabled. Factual answers will be verified against web sources before they are sent.",
+		"selfcheck_off":      "Self-check disabled.",
+		"deterministic_on":   "Deterministic mode enabled. Replies use temperature 0 and seed %d, so repeating a question in the same context gives the same answer.",
+		"deterministic_off":  "Deterministic mode disabled. Replies use the model's default sampling again.",
+		"devmode_on":         "Developer mode enabled. Pasted errors and stack traces get a probable cause, a fix and a minimal repro without a question.",
+		"devmode_off":        "Developer mode disabled.",
+		"language_current":   "Bot messages in this chat are in %s.",
+		"language_auto":      "Bot messages in this chat follow each user's Telegram language.",
+		"language_set":       "Bot messages in this chat are now in %s.",
+		"language_usage":     "Usage: /language <code> or /language auto. Available: %s",
+		"language_unknown":   "That language is not available. Available: %s",
+		"usage_title":        "Usage for this chat",
+		"usage_today":        "Today",
+		"usage_month":        "This month",
+		"usage_none":         "%s: no requests",
+		"usage_line":         "%s: %s requests, %s tokens (prompt %s / output %s / thinking %s), ~$%.4f",
+		"usage_estimate":     "Costs are estimates based on list prices.",
+		"calc_usage":         "Usage: /calc <question with numbers>",
+		"action_confirm_q":   "Run action %s?",
+		"action_confirm":     "Confirm",
+		"action_cancel":      "Cancel",
+		"action_not_yours":   "Only the person who asked for this action can confirm or cancel it.",
+		"action_expired":     "That action request has expired.",
+		"action_done":        "Action %s completed.",
+		"action_failed":      "Action %s failed.",
+		"action_cancelled":   "Action cancelled.",
+		"admin_only":         "This command is limited to bot administrators.",
+		"advanced_admins":    "Advanced settings are limited to bot administrators.",
+		"model_admins":       "Choosing the model is limited to bot administrators.",
+		"calendar_off":       "Calendar integration is not enabled on this bot.",
+		"calendar_private":   "Ask about your calendar in a private chat with the bot.",
+		"calendar_status":    "Connected to %s as %s.",
+		"calendar_none":      "No calendar connected. Use /calendar connect <caldav-url> <username> <app-password> in a private chat.",
+		"calendar_conn_use":  "Usage: /calendar connect <caldav-url> <username> <app-password>",
+		"calendar_unsafe":    "For your safety, connect calendars in a private chat with the bot. Consider rotating that password.",
+		"calendar_https":     "The CalDAV URL must start with https://.",
+		"calendar_failed":    "Could not reach that calendar. Check the CalDAV URL and credentials.",
+		"calendar_on":        "Calendar connected. Ask me about your schedule or to book time.",
+		"calendar_removed":   "Calendar disconnected and credentials removed.",
+		"calendar_usage":     "Usage: /calendar [status|connect|disconnect]",
+		"export_gone":        "This answer is no longer available for export.",
+		"notion_off":         "Notion integration is not enabled on this bot.",
+		"notion_status":      "Notion is connected. Answers are saved under page %s.",
+		"notion_none":        "Notion is not connected. Use /notion connect <integration-token> <parent-page-url-or-id> in a private chat.",
+		"notion_conn_use":    "Usage: /notion connect <integration-token> <parent-page-url-or-id>",
+		"notion_unsafe":      "For your safety, connect Notion in a private chat with the bot. Consider rotating that token.",
+		"notion_bad_page":    "Could not find a Notion page ID in that link.",
+		"notion_on":          "Notion connected. Share the parent page with your integration, then use Save to Notion under any answer.",
+		"notion_removed":     "Notion disconnected and token removed.",
+		"notion_usage":       "Usage: /notion [status|connect|disconnect]",
+		"notion_first":       "Connect Notion first with /notion connect in a private chat.",
+		"notion_saved":       "Saved to Notion: %s",
+		"notion_failed":      "Could not save to Notion. Check that the parent page is shared with your integration.",
+		"notion_timeout":     "Notion did not respond in time. Please try again.",
+		"prefs_off":          "Personal preferences are not enabled on this bot.",
+		"speech_current":     "Your spoken languages: %s. Voice notes are transcribed with this hint.",
+		"speech_none":        "No spoken languages set. Use /languages English, Ukrainian to help transcribe mixed-language voice notes, or /languages reset.",
+		"speech_cleared":     "Spoken languages cleared.",
+		"speech_invalid":     "Could not update spoken languages: %s.",
+		"speech_set":         "Spoken languages set to %s.",
+		"replay_empty":       "There is nothing to replay yet. Chat with me first, then run /replay <model>.",
+		"replay_failed":      "The replay on %s failed after %d of the turns.",
+		"replay_cancelled":   "Replay cancelled.",
+		"replay_caption":     "Replayed %d turns on %s.",
+		"review_gone":        "This review is no longer available for export.",
+		"verify_ok":          "Verified against web sources.",
+		"verify_fixed":       "Corrected after verification.",
+		"verify_fixed_note":  "Corrected after verification: %s",
+		"verify_unsure":      "Confidence note: some claims could not be verified.",
+		"verify_unsure_note": "Confidence note: %s",
+		"advanced_usage":     "Usage: /advanced [stop <seq> | <seq> ... | presence <-2..2> | frequency <-2..2> | seed <int> | <option> off | reset]",
+		"advanced_none":      "No advanced generation settings. Model defaults apply.",
+		"advanced_stops":     "Stop sequences: %s",
+		"advanced_presence":  "Presence penalty: %g",
+		"advanced_frequency": "Frequency penalty: %g",
+		"advanced_seed":      "Seed: %d",
+		"advanced_stop_num":  "Give between 1 and %d stop sequences separated by |.",
+		"advanced_penalty":   "The penalty must be a number between %g and %g.",
+		"advanced_seed_int":  "The seed must be a whole number.",
+		"repo_usage":         "Usage: /repo <https git URL> to index a public repository, /repo drop to remove it, or /repo to see the current one.",
+		"repo_status":        "Answering from %s: %d files in %d chunks, indexed %s ago.",
+		"repo_none":          "No repository is indexed in this chat.",
+		"repo_dropped":       "Removed the repository index.",
+		"repo_failed":        "Could not index the repository: %s",
+		"repo_indexed":       "Indexed %s: %d files in %d chunks. Ask about the code and I will cite files and lines; /repo drop removes it.",
+		"repo_partial":       "The repository is large, so only its first %d chunks are indexed.",
+		"thinking_low":       "Low - 4,096 tokens",
+		"thinking_medium":    "Medium - 16,384 tokens",
+		"thinking_high":      "High - 32,768 tokens",
+		"thinking_dynamic":   "Dynamic reasoning",
+		"diff_changed":       "changed: %s",
+		"diff_added":         "added: %s",
+		"diff_removed":       "removed: %s",
+		"diff_more":          "(+%d more)",
+		"code_snippet":       "Code snippet %d",
+		"code_outcome":       "Outcome",
+		"code_output":        "Output",
+		"code_file_outcome":  "Code snippet %d, outcome: %s",
+		"admin_usage":        "Usage: /admin stats | broadcast <text> | ban <user id> | unban <user id> | artifacts [<chat id> | purge <chat id>] | reload | drain | resume",
+		"admin_bcast_usage":  "Usage: /admin broadcast <text>",
+		"admin_broadcasting": "Broadcasting to %d chats.",
+		"admin_bcast_done":   "Broadcast delivered to %d of %d chats.",
+		"admin_ban_usage":    "Usage: /admin %s <user id>, or reply to one of their messages.",
+		"admin_ban_admin":    "Administrators cannot be banned.",
+		"admin_unbanned":     "User %d is no longer banned.",
+		"admin_banned":       "User %d is banned. The bot ignores their messages.",
+		"admin_drain_busy":   "New messages are turned away, but %d chats are still busy with %d queued messages. Run /admin drain again or /admin resume.",
+		"admin_drained":      "All requests have finished and new messages are turned away. It is safe to stop the bot. Use /admin resume to accept messages again.",
+		"admin_resumed":      "Accepting messages again.",
+		"admin_stats":        "Bot statistics",
+		"admin_unknown":      "unknown",
+		"admin_version":      "Version: %s, up %s",
+		"admin_chats":        "Known chats: %d (%d inactive), active sessions: %d",
+		"admin_busy":         "Busy chats: %d, queued messages: %d",
+		"admin_banned_num":   "Banned users: %d",
+		"admin_generations":  "Recent generations: %d, identical requests answered from them: %d",
+		"admin_today":        "Today",
+		"admin_month":        "This month",
+		"admin_shedding":     "Going light because of the %s: new requests use %s and think less",
+		"admin_budget_stop":  "Daily budget of $%.2f reached: new requests are refused until %s",
+		"admin_budget_lite":  "Daily budget of $%.2f reached: new requests use %s and think less until %s",
+		"admin_na":           "n/a",
+		"admin_art_stats":    "Stored replies: %d (%s), button hits %d / misses %d (%s hit rate), evicted %d",
+		"admin_art_usage":    "Usage: /admin artifacts [<chat id> | purge <chat id>]",
+		"admin_art_purged":   "Purged %d stored replies of chat %d.",
+		"admin_art_none":     "No stored replies for chat %d.",
+		"admin_art_list":     "Stored replies for chat %d: %d",
+		"admin_art_newest":   "(newest %d shown)",
+		"admin_art_item":     "#%s, %s ago, %s: %s",
+		"calls_unlimited":    "Model calls: no limit",
+		"calls_stats":        "Model calls: %d of %d running, %d waiting",
+		"reload_actions_err": "Webhook actions not reloaded: %s",
+		"reload_actions":     "Reloaded %d webhook actions.",
+		"reload_state_err":   "Chat and ban list not reloaded: %s",
+		"reload_state":       "Reloaded %d known chats and %d banned users.",
+		"reload_cfg_err":     "Configuration not reloaded: %s",
+		"reload_cfg_same":    "Configuration reloaded, no tunables changed.",
+		"reload_cfg":         "Configuration reloaded:",
+		"diff_counts":        "Compared with the previous answer: %d sentences changed, %d added, %d removed.",
+		"speech_hint":        "Speech in the attached audio may be in any of these languages, possibly mixed within one recording: %s. Transcribe and interpret each part in the language actually spoken instead of assuming one language for the whole recording.",
+		"speech_bad_name":    "%q is not a language name",
+		"speech_too_many":    "at most %d languages can be listed",
+	},
+	"de": {
+		"welcome":            "Hallo, ich bin Eteon. Schick mir eine Frage, einen Link oder Medien, und ich antworte kurz und präzise.",
+		"thinking_current":   "Aktuelles Denkbudget: %s",
+		"thinking_switched":  "Denkbudget auf %s umgestellt",
+		"sampling_temp":      "Temp",
+		"sampling_top_p":     "Top-p",
+		"sampling_tokens":    "Tokens",
+		"sampling_reset":     "Sampling zurücksetzen",
+		"tool_search":        "Websuche",
+		"tool_links":         "Links",
+		"tool_code":          "Code",
+		"tool_on":            "an",
+		"tool_off":           "aus",
+		"tools_current":      "Werkzeuge: %s. Mit /settings tools off schaltest du alle ab und bekommst Antworten nur vom Modell.",
+		"tools_usage":        "Verwendung: /settings tools on|off oder /settings google_search, url_context bzw. code_execution mit on oder off.",
+		"json_usage":         "Verwendung: /json <JSON-Schema oder Beschreibung der Form> schaltet den JSON-Modus ein; /json off schaltet ihn aus.",
+		"json_on":            "JSON-Modus an. Antworten sind JSON in dieser Form: %s\nMit /json off gibt es wieder normale Antworten.",
+		"json_off":           "JSON-Modus aus. Antworten sind wieder normaler Text.",
+		"json_bad_schema":    "Das sieht nach einem JSON-Schema aus, lässt sich aber nicht lesen: %s",
+		"json_document":      "Das JSON steht im Dokument oben.",
+		"sampling_default":   "Standard",
+		"sampling_current":   "Temperatur: %s, Top-p: %s, maximale Antwort-Tokens: %s",
+		"sampling_usage":     "Verwendung: /settings temperature <0..2>, /settings top_p <0..1> oder /settings max_tokens <%d..%d>, jeweils auch mit default; /settings reset setzt alles zurück.",
+		"topic_not_forum":    "/topic funktioniert innerhalb eines Themas einer Forumsgruppe.",
+		"topic_admins_only":  "Nur Gruppenadmins können die Einstellungen dieses Themas ändern.",
+		"topic_current":      "Persona des Themas: %s\nWerkzeuge des Themas: %s",
+		"topic_default":      "Chat-Standard",
+		"topic_usage":        "Verwendung: /topic persona <Anweisungen|default>, /topic tools <all|none|Liste aus %s> oder /topic reset.",
+		"topic_too_long":     "Diese Persona ist zu lang (%d Zeichen). Das Limit liegt bei %d.",
+		"export_usage":       "Verwendung: /export [md|json|html]. Standard ist Markdown.",
+		"export_empty":       "Es gibt noch kein Gespräch zum Exportieren.",
+		"import_usage":       "Sende ein JSON-Protokoll aus /export mit /import als Bildunterschrift oder antworte mit /import darauf. Es ersetzt das Gespräch in diesem Chat.",
+		"import_too_large":   "Dieses Protokoll ist zu groß. Das Limit liegt bei %d KB.",
+		"import_invalid":     "Diese Datei ist kein JSON-Protokoll aus /export.",
+		"import_done":        "Gespräch mit %d Nachrichten wiederhergestellt.",
+		"checkpoint_usage":   "Verwendung: /checkpoint [Name]. Namen sind ein Wort aus bis zu 32 Buchstaben, Ziffern, Punkten, Binde- oder Unterstrichen.",
+		"checkpoint_empty":   "Es gibt noch kein Gespräch zum Speichern.",
+		"checkpoint_saved":   "Checkpoint %s mit %d Nachrichten gespeichert. Mit /rollback %s kehrst du dorthin zurück.",
+		"rollback_none":      "Noch keine Checkpoints. Speichere einen mit /checkpoint [Name].",
+		"rollback_list":      "Checkpoints, neueste zuerst (Name · gespeichert · Nachrichten):\n%s\nMit /rollback <Name> kehrst du zu einem zurück.",
+		"rollback_unknown":   "Es gibt keinen Checkpoint %s. Sende /rollback, um sie aufzulisten.",
+		"rollback_done":      "Zurück bei Checkpoint %s mit %d Nachrichten.",
+		"rollback_saved":     "Das verlassene Gespräch ist als %s gespeichert.",
+		"chat_usage":         "Verwendung: /chat listet deine Gespräche, /chat new <Name> beginnt eines, /chat switch <Name> wechselt zu einem und /chat delete <Name> löscht eines. Namen sind ein Wort aus bis zu 32 Buchstaben, Ziffern, Punkten, Binde- oder Unterstrichen.",
+		"chat_list":          "Gespräche (Name · Nachrichten):\n%s\nMit /chat switch <Name> wechselst du.",
+		"chat_exists":        "Es gibt bereits ein Gespräch %s. Mit /chat switch %s kehrst du dorthin zurück.",
+		"chat_too_many":      "Ein Chat kann %d Gespräche enthalten. Lösche zuerst eines mit /chat delete <Name>.",
+		"chat_started":       "Gespräch %s begonnen. Das vorherige bleibt erhalten.",
+		"chat_unknown":       "Es gibt kein Gespräch %s. Sende /chat, um sie aufzulisten.",
+		"chat_switched":      "Zu %s mit %d Nachrichten gewechselt.",
+		"chat_delete_active": "Wechsle zu einem anderen Gespräch, bevor du dieses löschst.",
+		"chat_deleted":       "Gespräch %s gelöscht.",
+		"catchup_groups":     "/catchup funktioniert in Gruppen.",
+		"catchup_admins":     "Nur Gruppenadmins können die Zusammenfassung ein- oder ausschalten.",
+		"catchup_on":         "Die Zusammenfassung ist an. Ich behalte die Nachrichten der letzten %d Stunden, höchstens %d, nur im Arbeitsspeicher und speichere sie nie auf der Festplatte. /catchup [Stunden] fasst sie zusammen; /catchup off löscht sie. Alle Nachrichten sehe ich nur, wenn der Datenschutzmodus für mich aus ist oder ich Admin der Gruppe bin.",
+		"catchup_off":        "Die Zusammenfassung ist aus und die gepufferten Nachrichten sind gelöscht.",
+		"catchup_disabled":   "Die Zusammenfassung ist in dieser Gruppe aus. Ein Admin kann sie mit /catchup on einschalten.",
+		"catchup_usage":      "Verwendung: /catchup [Stunden von 1 bis %d] oder /catchup on|off für Admins.",
+		"catchup_empty":      "In den letzten %d Stunden wurde nichts geschrieben.",
+		"catchup_failed":     "Ich konnte die Unterhaltung nicht zusammenfassen. Bitte versuche es später erneut.",
+		"catchup_header":     "Das hast du in den letzten %d Stunden verpasst:",
+		"sendto_usage":       "Verwendung: /sendto <Ziel> <was geschrieben werden soll>, /sendto remove <Ziel> oder im Zielchat /sendto allow <Name> und /sendto revoke. Namen bestehen aus a-z, 0-9, - und _.",
+		"sendto_none":        "Du hast keine gespeicherten Ziele. Führe /sendto allow <Name> in dem Chat aus, der deine Nachrichten empfangen soll; in Gruppen muss das ein Admin tun.",
+		"sendto_list":        "Deine Ziele: %s. Entwirf eine Nachricht mit /sendto <Ziel> <was geschrieben werden soll>.",
+		"sendto_allowed":     "Dieser Chat ist als Ziel %s gespeichert. Entwirf Nachrichten dafür aus jedem Chat mit /sendto %[1]s <was geschrieben werden soll>.",
+		"sendto_admins_only": "Nur Gruppenadmins können diesem Chat erlauben, entworfene Nachrichten zu empfangen.",
+		"sendto_revoked":     "%d Ziele, die auf diesen Chat zeigen, wurden entfernt.",
+		"sendto_removed":     "Ziel %s entfernt.",
+		"sendto_unknown":     "Es gibt kein Ziel namens %s. /sendto listet deine auf.",
+		"sendto_preview":     "Entwurf für %s. Sende ihn so, verwirf ihn oder führe /sendto mit Änderungen erneut aus:",
+		"sendto_no_draft":    "Ich konnte den Entwurf nicht schreiben. Bitte versuche es erneut.",
+		"btn_send_draft":     "Senden",
+		"btn_discard_draft":  "Verwerfen",
+		"sendto_sent":        "An %s gesendet.",
+		"sendto_failed":      "Ich konnte nicht an %s senden. Prüfe, ob ich dort noch Mitglied bin und schreiben darf.",
+		"sendto_expired":     "Dieser Entwurf ist abgelaufen. Führe /sendto erneut aus.",
+		"sendto_discarded":   "Entwurf verworfen.",
+		"sendto_not_yours":   "Nur der Autor dieses Entwurfs kann ihn senden oder verwerfen.",
+		"remind_usage":       "Verwendung: /remind in 2h30m <Text>, /remind at 18:00 <Text>, /remind tomorrow 9:00 <Text> oder /remind 2025-06-01 9:00 <Text>. Beginne den Text mit ask:, damit ich ihn zu dieser Zeit als Frage beantworte. /remind listet deine Erinnerungen, /remind cancel <ID> löscht eine und /remind tz <Gebiet/Stadt> setzt die Zeitzone.",
+		"remind_none":        "Du hast keine Erinnerungen in diesem Chat.",
+		"remind_list":        "Deine Erinnerungen:\n%s",
+		"remind_unknown":     "Hier gibt es keine Erinnerung #%d von dir.",
+		"remind_cancelled":   "Erinnerung #%d gelöscht.",
+		"remind_bad_tz":      "Unbekannte Zeitzone. Verwende einen Namen wie Europe/Berlin oder America/New_York.",
+		"remind_tz":          "Zeitzone auf %s gesetzt.",
+		"remind_range":       "Erinnerungen müssen in der Zukunft und höchstens ein Jahr entfernt liegen.",
+		"remind_too_many":    "Du hast bereits %d Erinnerungen. Lösche zuerst eine.",
+		"remind_set":         "Erinnerung #%d für %s gesetzt.",
+		"remind_fire":        "⏰ Erinnerung: %s",
+		"digest_usage":       "Verwendung: /digest <daily|weekdays|weekends|mon,thu> <HH:MM> <Anfrage>, z. B. /digest daily 8:00 fasse die Startseite von Hacker News zusammen. Ich beantworte die Anfrage zu dieser Zeit mit Websuche. /digest listet deine Digests und /digest cancel <ID> löscht einen. Zeiten gelten in der Zeitzone des Chats aus /remind tz.",
+		"digest_none":        "Du hast keine Digests in diesem Chat.",
+		"digest_list":        "Deine Digests (%s):\n%s",
+		"digest_unknown":     "Hier gibt es keinen Digest #%d von dir.",
+		"digest_cancelled":   "Digest #%d gelöscht.",
+		"digest_too_many":    "Du hast bereits %d Digests. Lösche zuerst einen.",
+		"digest_set":         "Digest #%d geplant: %s (%s). Der erste kommt am %s.",
+		"input_failed":       "Diese Eingabe konnte ich nicht verarbeiten.",
+		"rate_limited":       "Du sendest zu schnell Nachrichten. Versuche es in %d Sekunden erneut.",
+		"file_too_large":     "Die Datei ist größer als 20 MB, mehr dürfen Bots bei Telegram nicht herunterladen. Bitte schick eine kleinere oder komprimierte Version.",
+		"input_empty":        "Bitte schick Text oder unterstützte Medien.",
+		"request_failed":     "Eteon konnte diese Anfrage nicht abschließen.",
+		"request_cancelled":  "Anfrage abgebrochen.",
+		"calc_no_code":       "Ich konnte diese Antwort nicht mit ausgeführtem Code belegen und nenne deshalb keine Zahl. Formuliere die Rechnung bitte anders.",
+		"blocked_reason":     "Die Anfrage wurde von Gemini blockiert (%s).",
+		"blocked_hint":       "Formuliere sie ohne die markierten Inhalte um oder teile sie in kleinere Fragen auf.",
+		"safety_categories":  "Markierte Kategorien: %s.",
+		"finish_max_tokens":  "Die Antwort hat die Längengrenze erreicht. Tippe auf Weiter für den Rest oder erlaube längere Antworten mit /verbosity oder /settings.",
+		"finish_safety":      "Die Antwort wurde von den Sicherheitsfiltern gestoppt.",
+		"finish_recitation":  "Die Antwort wurde gestoppt, weil sie vorhandenen Text, etwa eine urheberrechtlich geschützte Quelle, fast wörtlich wiedergab. Bitte stattdessen um eine Zusammenfassung oder Umschreibung.",
+		"finish_other":       "Die Antwort endete vorzeitig (%s). Versuche es erneut oder formuliere die Anfrage um.",
+		"btn_continue":       "Weiter",
+		"continue_stale":     "Nur die neueste Antwort kann fortgesetzt werden.",
+		"no_content":         "Keine Antwort erhalten.",
+		"fallback_notice":    "Beantwortet von %s, weil %s nicht verfügbar war.",
+		"light_notice":       "Der Bot ist gerade stark ausgelastet, daher wurde bei dieser Antwort weniger nachgedacht als sonst.",
+		"budget_notice":      "Das heutige Nutzungslimit ist erreicht, daher stammt diese Antwort von einem kleineren Modell.",
+		"budget_refused":     "Der Bot hat sein Nutzungslimit für heute erreicht. Bitte versuche es nach %s erneut.",
+		"budget_user_limit":  "Du hast dein Nutzungslimit für heute erreicht. Bitte versuche es nach %s erneut.",
+		"grounding_sources":  "Quellen: %d",
+		"grounding_newest":   "neueste von %s",
+		"grounding_cited":    "%d%% der Antwort belegt",
+		"queue_full":         "Es warten bereits mehrere Nachrichten von dir. Bitte warte auf die Antworten, bevor du weitere schickst.",
+		"queue_draining":     "Der Bot startet gleich neu. Bitte schick deine Nachricht in einer Minute noch einmal.",
+		"queued_one":         "In der Warteschlange, ich bearbeite noch deine vorige Nachricht.",
+		"queued_many":        "In der Warteschlange hinter deinen vorigen Nachrichten. Ich komme gleich dazu.",
+		"calls_waiting":      "Gerade viel los: Du bist Nummer %d in der Warteschlange, etwa %s Wartezeit.",
+		"calls_saturated":    "Eteon ist gerade überlastet. Bitte versuche es in einer Minute erneut.",
+		"btn_cancel_request": "Aktuelle Anfrage abbrechen",
+		"cancel_started":     "Die aktuelle Anfrage wird abgebrochen.",
+		"cancel_nothing":     "Es läuft gerade keine Anfrage.",
+		"cancel_not_yours":   "Nur wer die aktuelle Anfrage gestellt hat oder ein Chat-Admin kann sie abbrechen.",
+		"btn_thoughts":       "Gedanken zeigen",
+		"btn_sources":        "Quellen zeigen",
+		"btn_code":           "Code zeigen",
+		"btn_obsidian":       "Als Obsidian-Notiz exportieren",
+		"btn_notion":         "In Notion speichern",
+		"btn_review_patch":   "Review als .patch-Kommentare exportieren",
+		"btn_regenerate":     "Neu generieren",
+		"btn_compare":        "Mit vorheriger vergleichen",
+		"btn_bookmark":       "⭐ Merken",
+		"bookmark_expired":   "Diese Antwort ist zu alt zum Merken.",
+		"bookmark_saved":     "Als #%d gemerkt. Siehe /bookmarks.",
+		"bookmark_exists":    "Bereits als #%d gemerkt.",
+		"bookmark_too_many":  "Du hast %d Lesezeichen. Lösche zuerst welche mit /bookmarks delete <ID>.",
+		"bookmark_failed":    "Das Lesezeichen konnte nicht gespeichert werden. Versuche es später erneut.",
+		"bookmarks_usage":    "Verwendung: /bookmarks listet deine Lesezeichen, /bookmarks <Wörter> durchsucht sie, /bookmarks <ID> zeigt eines und /bookmarks delete <ID> löscht eines.",
+		"bookmarks_none":     "Du hast noch keine Lesezeichen. Tippe unter einer Antwort auf ⭐ Merken, um sie zu speichern.",
+		"bookmarks_no_match": "Keine Lesezeichen enthalten %q.",
+		"bookmarks_list":     "Lesezeichen (%d):\n%s\nSende /bookmarks <ID>, um eines zu öffnen.",
+		"bookmark_unknown":   "Es gibt kein Lesezeichen #%d von dir.",
+		"bookmark_deleted":   "Lesezeichen #%d gelöscht.",
+		"feedback_expired":   "Diese Antwort ist zu alt zum Bewerten.",
+		"feedback_thanks":    "Danke! Damit passe ich meine Antworten an dich an. Siehe /remember.",
+		"feedback_same":      "Du hast diese Antwort bereits bewertet.",
+		"feedback_failed":    "Dein Feedback konnte nicht gespeichert werden. Versuche es später erneut.",
+		"remember_usage":     "Verwendung: /remember <Fakt> merkt sich etwas über dich in jedem Chat, auch nach /reset (z. B. /remember ich bin Vegetarier). /remember zeigt, was ich mir gemerkt und aus deinen Bewertungen gelernt habe, /memories listet die Fakten, /forget <ID> vergisst einen und /remember reset vergisst alles einschließlich deines 👍/👎-Feedbacks.",
+		"remember_show":      "Was ich mir über dich merke:\n%s\n\nGelernt aus %d Bewertungen:\n%s\n\nFüge einen Fakt mit /remember <Fakt> hinzu, vergiss einen mit /forget <ID>.",
+		"remember_no_notes":  "noch keine",
+		"remember_unrated":   "noch nichts - bewerte mindestens %d Antworten mit 👍 oder 👎",
+		"remember_saved":     "Verstanden, das merke ich mir in jedem Chat als #%d. Siehe /memories.",
+		"remember_exists":    "Das merke ich mir schon als #%d.",
+		"remember_too_long":  "Halte einen Fakt unter %d Zeichen.",
+		"remember_too_many":  "Ich merke mir %d Fakten über dich. Vergiss zuerst einen mit /forget <ID>.",
+		"memory_unknown":     "Es gibt keine Erinnerung #%d.",
+		"memory_forgot":      "Erinnerung #%d vergessen.",
+		"remember_reset":     "Deine Erinnerungen und Bewertungen sind vergessen.",
+		"remember_failed":    "Deine Erinnerungen konnten nicht gespeichert werden. Versuche es später erneut.",
+		"memories_none":      "Ich merke mir noch nichts über dich. Erzähl mir etwas mit /remember <Fakt>.",
+		"memories_list":      "Was ich mir über dich merke (%d):\n%s\nVergiss einen mit /forget <ID>.",
+		"memories_cleared":   "Alle deine Erinnerungen sind vergessen.",
+		"forget_usage":       "Verwendung: /forget <ID> vergisst eine Erinnerung aus /memories, /forget all vergisst alle.",
+		"memories_auto":      "Verwendung: Mit /memories auto on suche ich in unserem privaten Chat nach Fakten über dich, die du behalten oder löschen kannst, /memories auto off beendet das.",
+		"memories_auto_on":   "Alle %d Nachrichten suche ich in unserem privaten Chat nach Fakten, die sich zu merken lohnen, und frage dich, bevor ich sie behalte. Ausschalten mit /memories auto off.",
+		"memories_auto_off":  "Ich merke mir nur noch, was du mir mit /remember sagst.",
+		"memories_pending":   "⏳ markiert Fakten, die ich aus unseren Chats aufgeschnappt habe. Behalte oder lösche sie mit den Tasten.",
+		"memories_extracted": "Diese Fakten über dich habe ich aufgeschnappt. Behalte die, die ich mir merken soll:\n%s",
+		"memory_kept":        "Erinnerung #%d behalten.",
+		"kb_usage":           "Verwendung: Antworte mit /kb add auf ein Dokument oder sende eines mit /kb add als Beschriftung, um es der Wissensbasis dieses Chats hinzuzufügen. Fragen hier werden dann daraus beantwortet. /kb list listet die Dokumente, /kb delete <ID> entfernt eines und /kb delete all entfernt alle. /kb share editor|reader lädt andere Chats deines Teams ein, /kb join <Token> nutzt eine geteilte Wissensbasis und /kb leave kehrt zur eigenen dieses Chats zurück.",
+		"kb_added":           "%s wurde der Wissensbasis als #%d hinzugefügt (%s). Frag los.",
+		"kb_passages":        "%d Abschnitte",
+		"kb_truncated":       "Das Dokument ist lang, daher ist nur sein Anfang indexiert.",
+		"kb_list":            "Wissensbasis (%d Dokumente):\n%s\nEntferne eines mit /kb delete <ID>.",
+		"kb_empty":           "Die Wissensbasis dieses Chats ist leer. Antworte mit /kb add auf ein Dokument, um es hinzuzufügen.",
+		"kb_unsupported":     "Ich kann den Text von %s nicht lesen. Füge PDF-, Word-, Excel-, EPUB- oder Textdokumente hinzu.",
+		"kb_full":            "Die Wissensbasis fasst höchstens %d Abschnitte. Entferne zuerst ein Dokument mit /kb delete <ID>.",
+		"kb_unknown":         "Es gibt kein Dokument #%d in der Wissensbasis.",
+		"kb_deleted":         "Dokument #%d wurde aus der Wissensbasis entfernt.",
+		"kb_cleared":         "Die Wissensbasis ist jetzt leer.",
+		"kb_read_only":       "Dieser Chat liest eine geteilte Wissensbasis und kann ihre Dokumente nicht ändern.",
+		"kb_owner_only":      "Nur der Chat, dem diese Wissensbasis gehört, kann sie teilen.",
+		"kb_admins_only":     "Nur Gruppenadmins können ändern, wie dieser Chat Wissensbasen teilt.",
+		"kb_share_usage":     "Verwendung: /kb share editor lässt andere Chats Dokumente hinzufügen und entfernen, /kb share reader lässt sie nur fragen.",
+		"kb_invite":          "Einladung erstellt. Sende dies im anderen Chat:\n`/kb join %s`\nEr tritt als %s bei. Die Einladung gilt %d Tage für beliebig viele Chats.",
+		"kb_join_usage":      "Verwendung: /kb join <Token>, mit einem Token aus /kb share.",
+		"kb_join_invalid":    "Diese Einladung ist unbekannt oder abgelaufen. Lass dir mit /kb share eine neue geben.",
+		"kb_join_own":        "Diesem Chat gehört diese Wissensbasis bereits.",
+		"kb_joined":          "Dieser Chat nutzt jetzt die geteilte Wissensbasis als %s. Seine eigenen Dokumente ruhen bis /kb leave.",
+		"kb_not_shared":      "Dieser Chat nutzt seine eigene Wissensbasis.",
+		"kb_left":            "Dieser Chat nutzt wieder seine eigene Wissensbasis.",
+		"kb_members_none":    "Diese Wissensbasis ist nicht geteilt. Lade andere Chats mit /kb share editor|reader ein.",
+		"kb_members":         "Geteilt mit (Chat · Rolle):\n%s\nOffene Einladungen: %d. Beende das Teilen mit /kb revoke <Chat-ID> oder /kb revoke all.",
+		"kb_revoked":         "Chat %d teilt diese Wissensbasis nicht mehr.",
+		"kb_revoked_all":     "Diese Wissensbasis ist nicht mehr geteilt, und ihre Einladungen gelten nicht mehr.",
+		"kb_not_member":      "Chat %d teilt diese Wissensbasis nicht.",
+		"kb_role_owner":      "Eigentümer",
+		"kb_role_editor":     "Bearbeiter",
+		"kb_role_reader":     "Leser",
+		"kb_failed":          "Die Wissensbasis konnte nicht aktualisiert werden. Versuche es später erneut.",
+		"recall_usage":       "Verwendung: /recall <Suchanfrage> antwortet aus früheren Unterhaltungen dieses Chats, die nicht mehr in den Gesprächsverlauf passen. /recall on oder /recall off schaltet ihr automatisches Abrufen für normale Nachrichten ein oder aus, und /recall clear löscht sie. Dieser Chat hat %d archivierte Unterhaltungen.",
+		"recall_auto_on":     "Frühere Unterhaltungen, die zu einer Nachricht passen, werden automatisch wieder einbezogen.",
+		"recall_auto_off":    "Frühere Unterhaltungen werden nur mit /recall <Suchanfrage> durchsucht.",
+		"recall_cleared":     "Das Archiv früherer Unterhaltungen ist jetzt leer.",
+		"recall_empty":       "Aus dem Gesprächsverlauf dieses Chats ist noch nichts herausgefallen, es gibt also nichts abzurufen.",
+		"recall_failed":      "Das Archiv früherer Unterhaltungen konnte nicht aktualisiert werden. Versuche es später erneut.",
+		"privacy_usage":      "/privacy on beantwortet jede Nachricht für sich: Das Gespräch wird nicht gespeichert, Antworten haben keine Schaltflächen, weil ihr Inhalt nicht gespeichert wird, und die Logs enthalten nicht, was du sendest. Beim Einschalten wird der Verlauf des Chats gelöscht. /privacy off speichert Gespräche wieder. Einstellungen, Lesezeichen, Erinnerungen und die Wissensbasis bleiben in jedem Fall erhalten.",
+		"privacy_state_on":   "Der Privatsphäre-Modus ist in diesem Chat an.",
+		"privacy_state_off":  "Der Privatsphäre-Modus ist in diesem Chat aus.",
+		"privacy_on":         "Der Privatsphäre-Modus ist an. Der Verlauf des Chats ist gelöscht, und jede Nachricht wird jetzt für sich beantwortet, ohne gespeichert zu werden.",
+		"privacy_off":        "Der Privatsphäre-Modus ist aus. Neue Nachrichten werden wieder als Gespräch gespeichert.",
+		"privacy_admins":     "Nur Gruppenadmins können den Privatsphäre-Modus umschalten.",
+		"privacy_failed":     "Der Privatsphäre-Modus konnte nicht umgeschaltet werden. Versuche es später erneut.",
+		"catchup_private":    "Die Zusammenfassung speichert die Nachrichten der Gruppe und lässt sich daher im Privatsphäre-Modus nicht einschalten.",
+		"ephemeral_usage":    "/ephemeral <Zeit> löscht meine Nachrichten in diesem Chat nach der angegebenen Zeit, etwa 10m oder 2h, höchstens %s. /ephemeral <Zeit> prompts löscht auch die Nachrichten an mich; in Gruppen brauche ich dafür das Recht, Nachrichten zu löschen. /ephemeral off behält Nachrichten wieder.",
+		"ephemeral_is_off":   "Der flüchtige Modus ist in diesem Chat aus.",
+		"ephemeral_on":       "Der flüchtige Modus ist an: Ich lösche meine Nachrichten %s nach dem Senden.",
+		"ephemeral_prompts":  "Der flüchtige Modus ist an: Ich lösche meine Nachrichten und die Nachrichten an mich %s nach dem Senden.",
+		"ephemeral_off":      "Der flüchtige Modus ist aus. Bereits gesendete Nachrichten werden trotzdem rechtzeitig gelöscht.",
+		"ephemeral_admins":   "Nur Gruppenadmins können den flüchtigen Modus umschalten.",
+		"ephemeral_failed":   "Der flüchtige Modus konnte nicht umgeschaltet werden. Versuche es später erneut.",
+		"replyto_usage":      "/replyto on macht meine Antworten zu Antworten auf die Nachricht, die gefragt hat, damit Fragen und Antworten in belebten Chats zusammenbleiben. /replyto off sendet sie für sich. /replyto auto kehrt zur Voreinstellung zurück: Antworten in Gruppen, einfache Nachrichten in privaten Chats.",
+		"replyto_state_on":   "Meine Antworten in diesem Chat antworten auf die Nachricht, die gefragt hat.",
+		"replyto_state_off":  "Meine Antworten in diesem Chat werden für sich gesendet.",
+		"replyto_on":         "Meine Antworten antworten jetzt auf die Nachricht, die gefragt hat.",
+		"replyto_off":        "Meine Antworten werden jetzt für sich gesendet.",
+		"replyto_auto_on":    "Zurück zur Voreinstellung: Meine Antworten hier antworten auf die Nachricht, die gefragt hat.",
+		"replyto_auto_off":   "Zurück zur Voreinstellung: Meine Antworten hier werden für sich gesendet.",
+		"replyto_admins":     "Nur Gruppenadmins können ändern, wie ich antworte.",
+		"replyto_failed":     "Konnte nicht ändern, wie ich antworte. Versuche es später erneut.",
+		"regenerate_stale":   "Nur die neueste Antwort kann neu generiert werden.",
+		"compare_none":       "Die neue Antwort unterscheidet sich nicht von der vorherigen.",
+		"compare_header":     "Änderungen gegenüber der vorherigen Antwort: %s",
+		"thoughts_working":   "Gedanken werden zusammengefasst...",
+		"thoughts_none":      "Keine Zusammenfassung der Überlegungen verfügbar.",
+		"thoughts_header":    "Zusammenfassung der Überlegungen:",
+		"btn_thoughts_full":  "Vollständige Überlegungen",
+		"thoughts_full":      "Vollständiger Gedankengang",
+		"sources_none":       "Für diese Antwort gibt es keine Quellen.",
+		"sources_header":     "Quellen:",
+		"source_untitled":    "Ohne Titel",
+		"code_none":          "Für diese Antwort wurde kein Code ausgeführt.",
+		"verbosity_short":    "Kurz",
+		"verbosity_normal":   "Normal",
+		"verbosity_long":     "Ausführlich",
+		"verbosity_set":      "Antwortlänge auf %s gesetzt.",
+		"verbosity_current":  "Aktuelle Antwortlänge: %s",
+		"tone_professional":  "Professionell",
+		"tone_neutral":       "Neutral",
+		"tone_playful":       "Verspielt 🎉",
+		"tone_set":           "Ton auf %s gesetzt.",
+		"tone_current":       "Aktueller Ton: %s\nProfessionelle Antworten enthalten nie Emojis oder Kraftausdrücke, verspielte nutzen Emojis großzügig.",
+		"comparedocs_usage":  "Sende die zwei zu vergleichenden Dokumente, als Album oder nacheinander. Du kannst sie auch als Album mit der Beschriftung /compare_docs senden oder mit /compare_docs auf eines davon antworten.",
+		"comparedocs_next":   "%s erhalten. Sende jetzt das Dokument, mit dem es verglichen werden soll.",
+		"comparedocs_failed": "Diese Dokumente konnte ich nicht vergleichen.",
+		"comparedocs_title":  "Vergleich: %s und %s",
+		"comparedocs_aspect": "Aspekt",
+		"comparedocs_common": "Gemeinsamkeiten",
+		"comparedocs_newer":  "Neuer: %s",
+		"comparedocs_done":   "Vollständiger: %s",
+		"comparedocs_same":   "gleich",
+		"comparedocs_unsure": "nicht erkennbar",
+		"pipeline_step":      "%s: Schritt %d/%d, %s…",
+		"pipeline_done":      "%s ist fertig.",
+		"pipeline_failed":    "%s ist fehlgeschlagen. Bitte versuche es später erneut.",
+		"pipeline_usage":     "Sende %s mit dem zu bearbeitenden Text, antworte damit auf eine Nachricht oder ein Dokument oder sende ein Dokument mit dem Befehl als Beschriftung.",
+		"contract_title":     "Vertragsprüfung",
+		"contract_summary":   "Zusammenfassung",
+		"contract_duties":    "Pflichten",
+		"contract_risks":     "Risiken",
+		"contract_unusual":   "Ungewöhnliche und fehlende Klauseln",
+		"contract_caveat":    "Diese Prüfung wurde automatisch erstellt und ist keine Rechtsberatung.",
+		"contract_usage":     "/contract on prüft jedes Dokument, das du ohne Beschriftung sendest, als Vertrag: Zusammenfassung, Pflichten, Risiken und ungewöhnliche Klauseln, als Bericht. Antworte mit /contract auf ein Dokument oder beschrifte es mit /contract, um nur dieses zu prüfen.",
+		"contract_on":        "Vertragsprüfung aktiviert. Dokumente ohne Beschriftung werden als Verträge geprüft.",
+		"contract_off":       "Vertragsprüfung deaktiviert.",
+		"transcribe_usage":   "Antworte mit /transcribe auf eine Sprachnachricht, Audiodatei oder ein Video, oder sende eines mit /transcribe als Beschriftung. Mit srt erhältst du Untertitel.",
+		"ocr_usage":          "Antworte mit /ocr auf ein Foto, Bild oder einen PDF-Scan, oder sende eines mit /ocr als Beschriftung, um den Text wörtlich zu erhalten.",
+		"ocr_failed":         "Ich konnte den Text dieses Scans nicht lesen.",
+		"ocr_empty":          "Ich habe in diesem Scan keinen Text gefunden.",
+		"ocr_caption":        "Extrahierter Text",
+		"ocr_page":           "Seite %d",
+		"summarize_usage":    "Sende /summarize [short|medium|detailed] [quotes] mit einem Text oder Link, antworte damit auf eine Nachricht, einen Link, ein Dokument oder eine Sprachnachricht, oder sende ein Dokument mit /summarize als Beschriftung.",
+		"summarize_failed":   "Ich konnte das nicht zusammenfassen.",
+		"summarize_bad_link": "Ich konnte die verlinkte Seite nicht lesen.",
+		"summarize_caption":  "Zusammenfassung",
+		"summarize_quotes":   "Wichtige Zitate",
+		"translate_usage":    "Verwendung: /translate <Sprache> <Text> oder als Antwort auf eine Nachricht, ein Dokument oder eine Sprachnachricht. Die Sprache ist ein Code wie de, ein Name oder ein Paar wie en-de.",
+		"translate_failed":   "Das konnte ich nicht übersetzen.",
+		"translate_caption":  "Übersetzung: %s",
+		"search_usage":       "Verwendung: /search <Anfrage>. Die Antwort stammt immer aus einer aktuellen Google-Suche, mit Quellen.",
+		"search_unsourced":   "Die Suche hat keine Quellen für diese Antwort gefunden, sei also vorsichtig damit.",
+		"transcribe_failed":  "Ich konnte diese Aufnahme nicht transkribieren.",
+		"transcribe_empty":   "In dieser Aufnahme konnte ich niemanden sprechen hören.",
+		"transcribe_caption": "Vollständiges Transkript",
+		"disclaimer_medical": "Dies sind allgemeine Informationen und keine medizinische Beratung. Bitte frage einen Arzt oder Apotheker zu deiner Situation.",
+		"disclaimer_finance": "Dies sind allgemeine Informationen und keine Finanzberatung. Bitte prüfe wichtige Entscheidungen mit einem Berater.",
+		"selfcheck_on":       "Selbstprüfung aktiviert. Sachantworten werden vor dem Senden mit Webquellen abgeglichen.",
+		"selfcheck_off":      "Selbstprüfung deaktiviert.",
+		"deterministic_on":   "Deterministischer Modus aktiviert. Antworten nutzen Temperatur 0 und Seed %d, dieselbe Frage im selben Kontext ergibt also dieselbe Antwort.",
+		"deterministic_off":  "Deterministischer Modus deaktiviert. Antworten nutzen wieder das Standard-Sampling des Modells.",
+		"devmode_on":         "Entwicklermodus aktiviert. Eingefügte Fehlermeldungen und Stacktraces erhalten ohne weitere Frage eine wahrscheinliche Ursache, eine Lösung und ein minimales Beispiel zum Nachstellen.",
+		"devmode_off":        "Entwicklermodus deaktiviert.",
+		"language_current":   "Bot-Nachrichten in diesem Chat sind auf %s.",
+		"language_auto":      "Bot-Nachrichten in diesem Chat folgen der Telegram-Sprache der jeweiligen Person.",
+		"language_set":       "Bot-Nachrichten in diesem Chat sind jetzt auf %s.",
+		"language_usage":     "Verwendung: /language <Code> oder /language auto. Verfügbar: %s",
+		"language_unknown":   "Diese Sprache ist nicht verfügbar. Verfügbar: %s",
+		"usage_title":        "Verbrauch in diesem Chat",
+		"usage_today":        "Heute",
+		"usage_month":        "Diesen Monat",
+		"usage_none":         "%s: keine Anfragen",
+		"usage_line":         "%s: %s Anfragen, %s Tokens (Eingabe %s / Ausgabe %s / Denken %s), ~$%.4f",
+		"usage_estimate":     "Die Kosten sind Schätzungen auf Basis der Listenpreise.",
+		"calc_usage":         "Verwendung: /calc <Frage mit Zahlen>",
+		"action_confirm_q":   "Aktion %s ausführen?",
+		"action_confirm":     "Bestätigen",
+		"action_cancel":      "Abbrechen",
+		"action_not_yours":   "Nur wer diese Aktion angefordert hat, kann sie bestätigen oder abbrechen.",
+		"action_expired":     "Diese Aktionsanfrage ist abgelaufen.",
+		"action_done":        "Aktion %s ausgeführt.",
+		"action_failed":      "Aktion %s fehlgeschlagen.",
+		"action_cancelled":   "Aktion abgebrochen.",
+		"admin_only":         "Dieser Befehl ist den Administratoren des Bots vorbehalten.",
+		"advanced_admins":    "Erweiterte Einstellungen sind den Administratoren des Bots vorbehalten.",
+		"model_admins":       "Das Modell können nur die Administratoren des Bots wählen.",
+		"calendar_off":       "Die Kalenderanbindung ist bei diesem Bot nicht aktiviert.",
+		"calendar_private":   "Frag nach deinem Kalender im privaten Chat mit dem Bot.",
+		"calendar_status":    "Verbunden mit %s als %s.",
+		"calendar_none":      "Kein Kalender verbunden. Nutze /calendar connect <caldav-url> <username> <app-password> im privaten Chat.",
+		"calendar_conn_use":  "Verwendung: /calendar connect <caldav-url> <username> <app-password>",
+		"calendar_unsafe":    "Verbinde Kalender zu deiner Sicherheit im privaten Chat mit dem Bot. Ändere das Passwort am besten.",
+		"calendar_https":     "Die CalDAV-URL muss mit https:// beginnen.",
+		"calendar_failed":    "Der Kalender ist nicht erreichbar. Prüfe die CalDAV-URL und die Zugangsdaten.",
+		"calendar_on":        "Kalender verbunden. Frag mich nach deinen Terminen oder lass mich Zeit eintragen.",
+		"calendar_removed":   "Kalender getrennt und Zugangsdaten gelöscht.",
+		"calendar_usage":     "Verwendung: /calendar [status|connect|disconnect]",
+		"export_gone":        "Diese Antwort kann nicht mehr exportiert werden.",
+		"notion_off":         "Die Notion-Anbindung ist bei diesem Bot nicht aktiviert.",
+		"notion_status":      "Notion ist verbunden. Antworten werden unter der Seite %s gespeichert.",
+		"notion_none":        "Notion ist nicht verbunden. Nutze /notion connect <integration-token> <parent-page-url-or-id> im privaten Chat.",
+		"notion_conn_use":    "Verwendung: /notion connect <integration-token> <parent-page-url-or-id>",
+		"notion_unsafe":      "Verbinde Notion zu deiner Sicherheit im privaten Chat mit dem Bot. Erneuere das Token am besten.",
+		"notion_bad_page":    "In diesem Link steht keine Notion-Seiten-ID.",
+		"notion_on":          "Notion verbunden. Teile die übergeordnete Seite mit deiner Integration und nutze dann „Save to Notion“ unter einer Antwort.",
+		"notion_removed":     "Notion getrennt und Token gelöscht.",
+		"notion_usage":       "Verwendung: /notion [status|connect|disconnect]",
+		"notion_first":       "Verbinde zuerst Notion mit /notion connect im privaten Chat.",
+		"notion_saved":       "In Notion gespeichert: %s",
+		"notion_failed":      "Speichern in Notion fehlgeschlagen. Prüfe, ob die übergeordnete Seite mit deiner Integration geteilt ist.",
+		"notion_timeout":     "Notion hat nicht rechtzeitig geantwortet. Bitte versuch es erneut.",
+		"prefs_off":          "Persönliche Einstellungen sind bei diesem Bot nicht aktiviert.",
+		"speech_current":     "Deine gesprochenen Sprachen: %s. Sprachnachrichten werden mit diesem Hinweis transkribiert.",
+		"speech_none":        "Keine gesprochenen Sprachen festgelegt. Nutze /languages English, Ukrainian, damit gemischtsprachige Sprachnachrichten besser transkribiert werden, oder /languages reset.",
+		"speech_cleared":     "Gesprochene Sprachen gelöscht.",
+		"speech_invalid":     "Gesprochene Sprachen nicht geändert: %s.",
+		"speech_set":         "Gesprochene Sprachen: %s.",
+		"replay_empty":       "Es gibt noch nichts zum Wiederholen. Schreib erst mit mir und starte dann /replay <model>.",
+		"replay_failed":      "Die Wiederholung mit %s ist nach %d der Runden fehlgeschlagen.",
+		"replay_cancelled":   "Wiederholung abgebrochen.",
+		"replay_caption":     "%d Runden mit %s wiederholt.",
+		"review_gone":        "Dieses Review kann nicht mehr exportiert werden.",
+		"verify_ok":          "Mit Webquellen überprüft.",
+		"verify_fixed":       "Nach der Überprüfung korrigiert.",
+		"verify_fixed_note":  "Nach der Überprüfung korrigiert: %s",
+		"verify_unsure":      "Hinweis zur Verlässlichkeit: Einige Aussagen ließen sich nicht überprüfen.",
+		"verify_unsure_note": "Hinweis zur Verlässlichkeit: %s",
+		"advanced_usage":     "Verwendung: /advanced [stop <seq> | <seq> ... | presence <-2..2> | frequency <-2..2> | seed <int> | <option> off | reset]",
+		"advanced_none":      "Keine erweiterten Generierungseinstellungen. Es gelten die Standardwerte des Modells.",
+		"advanced_stops":     "Stoppsequenzen: %s",
+		"advanced_presence":  "Presence-Penalty: %g",
+		"advanced_frequency": "Frequency-Penalty: %g",
+		"advanced_seed":      "Seed: %d",
+		"advanced_stop_num":  "Gib zwischen 1 und %d Stoppsequenzen an, getrennt durch |.",
+		"advanced_penalty":   "Die Penalty muss eine Zahl zwischen %g und %g sein.",
+		"advanced_seed_int":  "Der Seed muss eine ganze Zahl sein.",
+		"repo_usage":         "Verwendung: /repo <https-Git-URL> indexiert ein öffentliches Repository, /repo drop entfernt es, /repo zeigt das aktuelle.",
+		"repo_status":        "Antworten aus %s: %d Dateien in %d Abschnitten, indexiert vor %s.",
+		"repo_none":          "In diesem Chat ist kein Repository indexiert.",
+		"repo_dropped":       "Der Repository-Index wurde entfernt.",
+		"repo_failed":        "Das Repository konnte nicht indexiert werden: %s",
+		"repo_indexed":       "%s indexiert: %d Dateien in %d Abschnitten. Frag nach dem Code, und ich nenne Dateien und Zeilen; /repo drop entfernt den Index.",
+		"repo_partial":       "Das Repository ist groß, daher sind nur die ersten %d Abschnitte indexiert.",
+		"thinking_low":       "Niedrig - 4.096 Tokens",
+		"thinking_medium":    "Mittel - 16.384 Tokens",
+		"thinking_high":      "Hoch - 32.768 Tokens",
+		"thinking_dynamic":   "Dynamisches Denken",
+		"diff_changed":       "geändert: %s",
+		"diff_added":         "hinzugefügt: %s",
+		"diff_removed":       "entfernt: %s",
+		"diff_more":          "(+%d weitere)",
+		"code_snippet":       "Codeausschnitt %d",
+		"code_outcome":       "Ergebnis",
+		"code_output":        "Ausgabe",
+		"code_file_outcome":  "Codeausschnitt %d, Ergebnis: %s",
+		"admin_usage":        "Verwendung: /admin stats | broadcast <text> | ban <user id> | unban <user id> | artifacts [<chat id> | purge <chat id>] | reload | drain | resume",
+		"admin_bcast_usage":  "Verwendung: /admin broadcast <text>",
+		"admin_broadcasting": "Sende an %d Chats.",
+		"admin_bcast_done":   "Rundsendung an %d von %d Chats zugestellt.",
+		"admin_ban_usage":    "Verwendung: /admin %s <user id>, oder antworte auf eine Nachricht der Person.",
+		"admin_ban_admin":    "Administratoren können nicht gesperrt werden.",
+		"admin_unbanned":     "Nutzer %d ist nicht mehr gesperrt.",
+		"admin_banned":       "Nutzer %d ist gesperrt. Der Bot ignoriert seine Nachrichten.",
+		"admin_drain_busy":   "Neue Nachrichten werden abgewiesen, aber %d Chats arbeiten noch %d wartende Nachrichten ab. Führe /admin drain erneut oder /admin resume aus.",
+		"admin_drained":      "Alle Anfragen sind abgeschlossen, neue Nachrichten werden abgewiesen. Der Bot kann jetzt gefahrlos gestoppt werden. Mit /admin resume werden wieder Nachrichten angenommen.",
+		"admin_resumed":      "Nachrichten werden wieder angenommen.",
+		"admin_stats":        "Bot-Statistik",
+		"admin_unknown":      "unbekannt",
+		"admin_version":      "Version: %s, läuft seit %s",
+		"admin_chats":        "Bekannte Chats: %d (%d inaktiv), aktive Sitzungen: %d",
+		"admin_busy":         "Beschäftigte Chats: %d, wartende Nachrichten: %d",
+		"admin_banned_num":   "Gesperrte Nutzer: %d",
+		"admin_generations":  "Letzte Generierungen: %d, daraus beantwortete identische Anfragen: %d",
+		"admin_today":        "Heute",
+		"admin_month":        "Dieser Monat",
+		"admin_shedding":     "Sparbetrieb wegen %s: neue Anfragen nutzen %s und denken weniger",
+		"admin_budget_stop":  "Tagesbudget von $%.2f erreicht: neue Anfragen werden bis %s abgelehnt",
+		"admin_budget_lite":  "Tagesbudget von $%.2f erreicht: neue Anfragen nutzen %s und denken weniger bis %s",
+		"admin_na":           "k. A.",
+		"admin_art_stats":    "Gespeicherte Antworten: %d (%s), Button-Treffer %d / Fehlschläge %d (%s Trefferquote), verdrängt %d",
+		"admin_art_usage":    "Verwendung: /admin artifacts [<chat id> | purge <chat id>]",
+		"admin_art_purged":   "%d gespeicherte Antworten von Chat %d gelöscht.",
+		"admin_art_none":     "Keine gespeicherten Antworten für Chat %d.",
+		"admin_art_list":     "Gespeicherte Antworten für Chat %d: %d",
+		"admin_art_newest":   "(die neuesten %d angezeigt)",
+		"admin_art_item":     "#%s, vor %s, %s: %s",
+		"calls_unlimited":    "Modellaufrufe: unbegrenzt",
+		"calls_stats":        "Modellaufrufe: %d von %d laufen, %d warten",
+		"reload_actions_err": "Webhook-Aktionen nicht neu geladen: %s",
+		"reload_actions":     "%d Webhook-Aktionen neu geladen.",
+		"reload_state_err":   "Chat- und Sperrliste nicht neu geladen: %s",
+		"reload_state":       "%d bekannte Chats und %d gesperrte Nutzer neu geladen.",
+		"reload_cfg_err":     "Konfiguration nicht neu geladen: %s",
+		"reload_cfg_same":    "Konfiguration neu geladen, keine Einstellungen geändert.",
+		"reload_cfg":         "Konfiguration neu geladen:",
+		"diff_counts":        "Im Vergleich zur vorherigen Antwort: %d Sätze geändert, %d hinzugefügt, %d entfernt.",
+		"speech_hint":        "Das angehängte Audio kann in jeder dieser Sprachen gesprochen sein, auch gemischt in einer Aufnahme: %s. Transkribiere und deute jeden Teil in der tatsächlich gesprochenen Sprache, statt eine Sprache für die ganze Aufnahme anzunehmen.",
+		"speech_bad_name":    "%q ist kein Sprachname",
+		"speech_too_many":    "höchstens %d Sprachen sind möglich",
+	},
+	"es": {
+		"welcome":            "Hola, soy Eteon. Envíame una pregunta, un enlace o un archivo multimedia y responderé de forma concisa.",
+		"thinking_current":   "Presupuesto de razonamiento actual: %s",
+		"thinking_switched":  "Presupuesto de razonamiento cambiado a %s",
+		"sampling_temp":      "Temp",
+		"sampling_top_p":     "Top-p",
+		"sampling_tokens":    "Tokens",
+		"sampling_reset":     "Restablecer muestreo",
+		"tool_search":        "Búsqueda web",
+		"tool_links":         "Enlaces",
+		"tool_code":          "Código",
+		"tool_on":            "sí",
+		"tool_off":           "no",
+		"tools_current":      "Herramientas: %s. Desactívalas todas con /settings tools off para respuestas solo del modelo.",
+		"tools_usage":        "Uso: /settings tools on|off, o /settings google_search, url_context o code_execution con on u off.",
+		"json_usage":         "Uso: /json <esquema JSON o descripción de la forma> activa el modo JSON; /json off lo desactiva.",
+		"json_on":            "Modo JSON activado. Las respuestas son JSON con esta forma: %s\nEnvía /json off para respuestas normales.",
+		"json_off":           "Modo JSON desactivado. Las respuestas vuelven a ser texto normal.",
+		"json_bad_schema":    "Parece un esquema JSON, pero no se puede leer: %s",
+		"json_document":      "El JSON está en el documento de arriba.",
+		"sampling_default":   "predeterminado",
+		"sampling_current":   "Temperatura: %s, top-p: %s, tokens máximos de respuesta: %s",
+		"sampling_usage":     "Uso: /settings temperature <0..2>, /settings top_p <0..1> o /settings max_tokens <%d..%d>, cada uno también con default; /settings reset los borra.",
+		"topic_not_forum":    "/topic funciona dentro de un tema de un grupo con foro.",
+		"topic_admins_only":  "Solo los administradores del grupo pueden cambiar los ajustes de este tema.",
+		"topic_current":      "Persona del tema: %s\nHerramientas del tema: %s",
+		"topic_default":      "predeterminado del chat",
+		"topic_usage":        "Uso: /topic persona <instrucciones|default>, /topic tools <all|none|lista de %s> o /topic reset.",
+		"topic_too_long":     "Esa persona es demasiado larga (%d caracteres). El límite es %d.",
+		"export_usage":       "Uso: /export [md|json|html]. Markdown es el formato predeterminado.",
+		"export_empty":       "Todavía no hay ninguna conversación para exportar.",
+		"import_usage":       "Envía una transcripción JSON de /export con /import como pie, o responde /import a ella. Sustituye la conversación de este chat.",
+		"import_too_large":   "Esa transcripción es demasiado grande. El límite es %d KB.",
+		"import_invalid":     "Ese archivo no es una transcripción JSON de /export.",
+		"import_done":        "Conversación restaurada con %d mensajes.",
+		"checkpoint_usage":   "Uso: /checkpoint [nombre]. Los nombres son una palabra de hasta 32 letras, dígitos, puntos, guiones o guiones bajos.",
+		"checkpoint_empty":   "Todavía no hay conversación que guardar.",
+		"checkpoint_saved":   "Punto de control %s guardado con %d mensajes. Usa /rollback %s para volver a él.",
+		"rollback_none":      "Aún no hay puntos de control. Guarda uno con /checkpoint [nombre].",
+		"rollback_list":      "Puntos de control, el más reciente primero (nombre · guardado · mensajes):\n%s\nUsa /rollback <nombre> para volver a uno.",
+		"rollback_unknown":   "No hay ningún punto de control %s. Envía /rollback para verlos.",
+		"rollback_done":      "De vuelta en el punto de control %s con %d mensajes.",
+		"rollback_saved":     "La conversación que dejaste se guardó como %s.",
+		"chat_usage":         "Uso: /chat lista tus conversaciones, /chat new <nombre> empieza una, /chat switch <nombre> vuelve a una y /chat delete <nombre> borra una. Los nombres son una palabra de hasta 32 letras, dígitos, puntos, guiones o guiones bajos.",
+		"chat_list":          "Conversaciones (nombre · mensajes):\n%s\nUsa /chat switch <nombre> para cambiar.",
+		"chat_exists":        "Ya hay una conversación %s. Usa /chat switch %s para volver a ella.",
+		"chat_too_many":      "Un chat puede tener %d conversaciones. Borra una con /chat delete <nombre> primero.",
+		"chat_started":       "Conversación %s iniciada. La anterior se conserva.",
+		"chat_unknown":       "No hay ninguna conversación %s. Envía /chat para verlas.",
+		"chat_switched":      "Cambiado a %s con %d mensajes.",
+		"chat_delete_active": "Cambia a otra conversación antes de borrar esta.",
+		"chat_deleted":       "Conversación %s borrada.",
+		"catchup_groups":     "/catchup funciona en grupos.",
+		"catchup_admins":     "Solo los administradores del grupo pueden activar o desactivar el resumen.",
+		"catchup_on":         "El resumen está activado. Guardo los mensajes de las últimas %d horas, como máximo %d, solo en memoria y nunca en disco. /catchup [horas] los resume; /catchup off los borra. Solo veo todos los mensajes si el modo de privacidad está desactivado para mí o soy administrador del grupo.",
+		"catchup_off":        "El resumen está desactivado y los mensajes guardados se han borrado.",
+		"catchup_disabled":   "El resumen está desactivado en este grupo. Un administrador puede activarlo con /catchup on.",
+		"catchup_usage":      "Uso: /catchup [horas de 1 a %d], o /catchup on|off para administradores.",
+		"catchup_empty":      "No se dijo nada en las últimas %d horas.",
+		"catchup_failed":     "No pude resumir la conversación. Inténtalo de nuevo más tarde.",
+		"catchup_header":     "Lo que te perdiste en las últimas %d horas:",
+		"sendto_usage":       "Uso: /sendto <destino> <qué escribir>, /sendto remove <destino>, o en el chat de destino /sendto allow <nombre> y /sendto revoke. Los nombres usan a-z, 0-9, - y _.",
+		"sendto_none":        "No tienes destinos guardados. Ejecuta /sendto allow <nombre> en el chat que debe recibir tus mensajes; en grupos debe hacerlo un administrador.",
+		"sendto_list":        "Tus destinos: %s. Redacta un mensaje con /sendto <destino> <qué escribir>.",
+		"sendto_allowed":     "Este chat está guardado como destino %s. Redacta mensajes para él desde cualquier chat con /sendto %[1]s <qué escribir>.",
+		"sendto_admins_only": "Solo los administradores del grupo pueden permitir que este chat reciba mensajes redactados.",
+		"sendto_revoked":     "Se eliminaron %d destinos que apuntaban a este chat.",
+		"sendto_removed":     "Destino %s eliminado.",
+		"sendto_unknown":     "No hay ningún destino llamado %s. /sendto muestra los tuyos.",
+		"sendto_preview":     "Borrador para %s. Envíalo tal cual, descártalo o ejecuta /sendto de nuevo con cambios:",
+		"sendto_no_draft":    "No pude redactar el borrador. Inténtalo de nuevo.",
+		"btn_send_draft":     "Enviar",
+		"btn_discard_draft":  "Descartar",
+		"sendto_sent":        "Enviado a %s.",
+		"sendto_failed":      "No pude enviar a %s. Comprueba que sigo siendo miembro y puedo publicar allí.",
+		"sendto_expired":     "Este borrador ha caducado. Ejecuta /sendto de nuevo.",
+		"sendto_discarded":   "Borrador descartado.",
+		"sendto_not_yours":   "Solo el autor de este borrador puede enviarlo o descartarlo.",
+		"remind_usage":       "Uso: /remind in 2h30m <texto>, /remind at 18:00 <texto>, /remind tomorrow 9:00 <texto> o /remind 2025-06-01 9:00 <texto>. Empieza el texto con ask: para que lo responda como pregunta a esa hora. /remind muestra tus recordatorios, /remind cancel <id> cancela uno y /remind tz <Área/Ciudad> fija la zona horaria.",
+		"remind_none":        "No tienes recordatorios en este chat.",
+		"remind_list":        "Tus recordatorios:\n%s",
+		"remind_unknown":     "Aquí no tienes ningún recordatorio #%d.",
+		"remind_cancelled":   "Recordatorio #%d cancelado.",
+		"remind_bad_tz":      "Zona horaria desconocida. Usa un nombre como Europe/Madrid o America/Mexico_City.",
+		"remind_tz":          "Zona horaria fijada en %s.",
+		"remind_range":       "Los recordatorios deben ser futuros y como mucho a un año vista.",
+		"remind_too_many":    "Ya tienes %d recordatorios. Cancela uno primero.",
+		"remind_set":         "Recordatorio #%d fijado para %s.",
+		"remind_fire":        "⏰ Recordatorio: %s",
+		"digest_usage":       "Uso: /digest <daily|weekdays|weekends|mon,thu> <HH:MM> <petición>, p. ej. /digest daily 8:00 resume la portada de Hacker News. Respondo la petición con búsqueda web a esa hora. /digest lista tus resúmenes y /digest cancel <id> cancela uno. Las horas usan la zona horaria del chat de /remind tz.",
+		"digest_none":        "No tienes resúmenes en este chat.",
+		"digest_list":        "Tus resúmenes (%s):\n%s",
+		"digest_unknown":     "Aquí no hay ningún resumen #%d tuyo.",
+		"digest_cancelled":   "Resumen #%d cancelado.",
+		"digest_too_many":    "Ya tienes %d resúmenes. Cancela uno primero.",
+		"digest_set":         "Resumen #%d programado %s (%s). El primero llega el %s.",
+		"input_failed":       "No pude procesar ese mensaje.",
+		"rate_limited":       "Estás enviando mensajes demasiado rápido. Vuelve a intentarlo en %d segundos.",
+		"file_too_large":     "Ese archivo supera los 20 MB, el máximo que Telegram permite descargar a los bots. Envía una versión más pequeña o comprimida.",
+		"input_empty":        "Envía texto o un archivo multimedia compatible.",
+		"request_failed":     "Eteon no pudo completar esa solicitud.",
+		"request_cancelled":  "Solicitud cancelada.",
+		"calc_no_code":       "No pude respaldar esta respuesta con código ejecutado, así que no daré una cifra. Prueba a reformular el cálculo.",
+		"blocked_reason":     "Gemini bloqueó la solicitud (%s).",
+		"blocked_hint":       "Reformúlala sin el contenido señalado o divídela en preguntas más pequeñas.",
+		"safety_categories":  "Categorías señaladas: %s.",
+		"finish_max_tokens":  "La respuesta alcanzó el límite de longitud. Pulsa Continuar para el resto o permite respuestas más largas con /verbosity o /settings.",
+		"finish_safety":      "Los filtros de seguridad detuvieron la respuesta.",
+		"finish_recitation":  "La respuesta se detuvo porque reproducía casi literalmente un texto existente, como una fuente con derechos de autor. Pide un resumen o una paráfrasis.",
+		"finish_other":       "La respuesta terminó antes de tiempo (%s). Inténtalo de nuevo o reformula la solicitud.",
+		"btn_continue":       "Continuar",
+		"continue_stale":     "Solo se puede continuar la última respuesta.",
+		"no_content":         "No se recibió contenido.",
+		"fallback_notice":    "Respondido por %s porque %s no estaba disponible.",
+		"light_notice":       "El bot está muy ocupado ahora mismo, así que esta respuesta se razonó menos de lo habitual.",
+		"budget_notice":      "Se alcanzó el límite de uso de hoy, así que esta respuesta viene de un modelo más pequeño.",
+		"budget_refused":     "El bot alcanzó su límite de uso de hoy. Vuelve a intentarlo después de las %s.",
+		"budget_user_limit":  "Alcanzaste tu límite de uso de hoy. Vuelve a intentarlo después de las %s.",
+		"grounding_sources":  "Fuentes: %d",
+		"grounding_newest":   "la más reciente de %s",
+		"grounding_cited":    "%d%% de la respuesta citado",
+		"queue_full":         "Ya tienes varios mensajes en espera. Espera las respuestas antes de enviar más.",
+		"queue_draining":     "El bot se reiniciará en breve. Vuelve a enviar tu mensaje en un minuto.",
+		"queued_one":         "En cola, estoy con tu mensaje anterior.",
+		"queued_many":        "En cola detrás de tus mensajes anteriores. Llegaré a este en breve.",
+		"calls_waiting":      "Hay mucha actividad: eres el número %d en la cola, unos %s de espera.",
+		"calls_saturated":    "Eteon está sobrecargado ahora mismo. Inténtalo de nuevo en un minuto.",
+		"btn_cancel_request": "Cancelar la solicitud actual",
+		"cancel_started":     "Cancelando la solicitud actual.",
+		"cancel_nothing":     "No hay ninguna solicitud en curso.",
+		"cancel_not_yours":   "Solo quien envió la solicitud actual o un administrador del chat puede cancelarla.",
+		"btn_thoughts":       "Ver razonamiento",
+		"btn_sources":        "Ver fuentes",
+		"btn_code":           "Ver código",
+		"btn_obsidian":       "Exportar como nota de Obsidian",
+		"btn_notion":         "Guardar en Notion",
+		"btn_review_patch":   "Exportar revisión como comentarios .patch",
+		"btn_regenerate":     "Regenerar",
+		"btn_compare":        "Comparar con la anterior",
+		"btn_bookmark":       "⭐ Guardar",
+		"bookmark_expired":   "Esta respuesta es demasiado antigua para guardarla.",
+		"bookmark_saved":     "Guardada como #%d. Mira /bookmarks.",
+		"bookmark_exists":    "Ya está guardada como #%d.",
+		"bookmark_too_many":  "Tienes %d marcadores. Borra alguno con /bookmarks delete <id> primero.",
+		"bookmark_failed":    "No se pudo guardar el marcador. Inténtalo más tarde.",
+		"bookmarks_usage":    "Uso: /bookmarks lista tus marcadores, /bookmarks <palabras> los busca, /bookmarks <id> muestra uno y /bookmarks delete <id> borra uno.",
+		"bookmarks_none":     "Aún no tienes marcadores. Pulsa ⭐ Guardar bajo una respuesta para guardarla.",
+		"bookmarks_no_match": "Ningún marcador contiene %q.",
+		"bookmarks_list":     "Marcadores (%d):\n%s\nEnvía /bookmarks <id> para abrir uno.",
+		"bookmark_unknown":   "No tienes ningún marcador #%d.",
+		"bookmark_deleted":   "Marcador #%d borrado.",
+		"feedback_expired":   "Esta respuesta es demasiado antigua para valorarla.",
+		"feedback_thanks":    "¡Gracias! Lo usaré para adaptar mis respuestas a ti. Consulta /remember.",
+		"feedback_same":      "Ya valoraste esta respuesta.",
+		"feedback_failed":    "No se pudo guardar tu valoración. Inténtalo más tarde.",
+		"remember_usage":     "Uso: /remember <dato> recuerda algo sobre ti en todos los chats, incluso tras /reset (p. ej. /remember soy vegetariano). /remember muestra lo que recuerdo y lo aprendido de tus valoraciones, /memories lista los datos, /forget <id> olvida uno y /remember reset lo olvida todo, incluidas tus valoraciones 👍/👎.",
+		"remember_show":      "Lo que recuerdo de ti:\n%s\n\nAprendido de %d valoraciones:\n%s\n\nAñade un dato con /remember <dato> y olvida uno con /forget <id>.",
+		"remember_no_notes":  "ninguna todavía",
+		"remember_unrated":   "nada todavía: valora al menos %d respuestas con 👍 o 👎",
+		"remember_saved":     "Entendido, lo recordaré en todos los chats como #%d. Consulta /memories.",
+		"remember_exists":    "Ya lo recuerdo como #%d.",
+		"remember_too_long":  "Mantén cada dato por debajo de %d caracteres.",
+		"remember_too_many":  "Recuerdo %d datos sobre ti. Olvida uno primero con /forget <id>.",
+		"memory_unknown":     "No existe el recuerdo #%d.",
+		"memory_forgot":      "Recuerdo #%d olvidado.",
+		"remember_reset":     "Tus recuerdos y valoraciones se han olvidado.",
+		"remember_failed":    "No se pudieron guardar tus recuerdos. Inténtalo más tarde.",
+		"memories_none":      "Aún no recuerdo nada sobre ti. Cuéntame algo con /remember <dato>.",
+		"memories_list":      "Lo que recuerdo de ti (%d):\n%s\nOlvida uno con /forget <id>.",
+		"memories_cleared":   "Todos tus recuerdos se han olvidado.",
+		"forget_usage":       "Uso: /forget <id> olvida un recuerdo de /memories, /forget all los olvida todos.",
+		"memories_auto":      "Uso: /memories auto on me deja extraer datos sobre ti de nuestro chat privado para que los conserves o borres, /memories auto off lo detiene.",
+		"memories_auto_on":   "Cada %d mensajes buscaré en nuestro chat privado datos que valga la pena recordar y te preguntaré antes de guardarlos. Desactívalo con /memories auto off.",
+		"memories_auto_off":  "Solo recordaré lo que me digas con /remember.",
+		"memories_pending":   "⏳ marca los datos que saqué de nuestros chats. Consérvalos o bórralos con los botones.",
+		"memories_extracted": "Saqué estos datos sobre ti. Conserva los que deba recordar:\n%s",
+		"memory_kept":        "Recuerdo #%d conservado.",
+		"kb_usage":           "Uso: responde /kb add a un documento, o envíalo con /kb add como pie de foto, para añadirlo a la base de conocimiento de este chat. Las preguntas aquí se responderán a partir de ella. /kb list lista los documentos, /kb delete <id> elimina uno y /kb delete all los elimina todos. /kb share editor|reader invita a otros chats de tu equipo, /kb join <token> usa una base de conocimiento compartida y /kb leave vuelve a la propia de este chat.",
+		"kb_added":           "%s añadido a la base de conocimiento como #%d (%s). Pregunta lo que quieras.",
+		"kb_passages":        "%d fragmentos",
+		"kb_truncated":       "El documento es largo, así que solo se indexó su comienzo.",
+		"kb_list":            "Base de conocimiento (%d documentos):\n%s\nElimina uno con /kb delete <id>.",
+		"kb_empty":           "La base de conocimiento de este chat está vacía. Responde /kb add a un documento para añadirlo.",
+		"kb_unsupported":     "No puedo leer el texto de %s. Añade documentos PDF, Word, Excel, EPUB o de texto.",
+		"kb_full":            "La base de conocimiento admite como máximo %d fragmentos. Elimina primero un documento con /kb delete <id>.",
+		"kb_unknown":         "No hay ningún documento #%d en la base de conocimiento.",
+		"kb_deleted":         "Documento #%d eliminado de la base de conocimiento.",
+		"kb_cleared":         "La base de conocimiento está vacía ahora.",
+		"kb_read_only":       "Este chat lee una base de conocimiento compartida y no puede cambiar sus documentos.",
+		"kb_owner_only":      "Solo el chat propietario de esta base de conocimiento puede compartirla.",
+		"kb_admins_only":     "Solo los administradores del grupo pueden cambiar cómo este chat comparte bases de conocimiento.",
+		"kb_share_usage":     "Uso: /kb share editor permite a otros chats añadir y eliminar documentos, /kb share reader solo les permite preguntar.",
+		"kb_invite":          "Invitación creada. Envía esto en el otro chat:\n`/kb join %s`\nSe une como %s. La invitación sirve para cualquier número de chats durante %d días.",
+		"kb_join_usage":      "Uso: /kb join <token>, con un token de /kb share.",
+		"kb_join_invalid":    "Esa invitación no existe o ha caducado. Pide una nueva con /kb share.",
+		"kb_join_own":        "Este chat ya es el propietario de esa base de conocimiento.",
+		"kb_joined":          "Este chat usa ahora la base de conocimiento compartida como %s. Sus propios documentos quedan apartados hasta /kb leave.",
+		"kb_not_shared":      "Este chat usa su propia base de conocimiento.",
+		"kb_left":            "Este chat vuelve a usar su propia base de conocimiento.",
+		"kb_members_none":    "Esta base de conocimiento no está compartida. Invita a otros chats con /kb share editor|reader.",
+		"kb_members":         "Compartida con (chat · rol):\n%s\nInvitaciones abiertas: %d. Deja de compartir con /kb revoke <id del chat> o /kb revoke all.",
+		"kb_revoked":         "El chat %d ya no comparte esta base de conocimiento.",
+		"kb_revoked_all":     "Esta base de conocimiento ya no está compartida y sus invitaciones ya no sirven.",
+		"kb_not_member":      "El chat %d no comparte esta base de conocimiento.",
+		"kb_role_owner":      "propietario",
+		"kb_role_editor":     "editor",
+		"kb_role_reader":     "lector",
+		"kb_failed":          "No se pudo actualizar la base de conocimiento. Inténtalo más tarde.",
+		"recall_usage":       "Uso: /recall <consulta> responde a partir de intercambios anteriores de este chat que ya no caben en el historial de la conversación. /recall on o /recall off activa o desactiva su recuperación automática para los mensajes normales, y /recall clear los borra. Este chat tiene %d intercambios archivados.",
+		"recall_auto_on":     "Los intercambios anteriores relacionados con un mensaje se recuperan automáticamente.",
+		"recall_auto_off":    "Los intercambios anteriores solo se buscan con /recall <consulta>.",
+		"recall_cleared":     "El archivo de intercambios anteriores está vacío ahora.",
+		"recall_empty":       "Todavía no ha salido nada del historial de este chat, así que no hay nada que recordar.",
+		"recall_failed":      "No se pudo actualizar el archivo de intercambios anteriores. Inténtalo más tarde.",
+		"privacy_usage":      "/privacy on responde cada mensaje por separado: la conversación no se guarda, las respuestas no tienen botones porque su contenido no se almacena y los registros no incluyen lo que envías. Al activarlo se borra el historial del chat. /privacy off vuelve a guardar las conversaciones. Los ajustes, marcadores, recuerdos y la base de conocimiento se mantienen en ambos casos.",
+		"privacy_state_on":   "El modo privado está activado en este chat.",
+		"privacy_state_off":  "El modo privado está desactivado en este chat.",
+		"privacy_on":         "El modo privado está activado. El historial del chat se borró y ahora cada mensaje se responde por separado sin guardarse.",
+		"privacy_off":        "El modo privado está desactivado. Los mensajes nuevos vuelven a guardarse como conversación.",
+		"privacy_admins":     "Solo los administradores del grupo pueden cambiar el modo privado.",
+		"privacy_failed":     "No se pudo cambiar el modo privado. Inténtalo más tarde.",
+		"catchup_private":    "El resumen guarda los mensajes del grupo, así que no se puede activar en modo privado.",
+		"ephemeral_usage":    "/ephemeral <tiempo> borra mis mensajes en este chat pasado el tiempo indicado, como 10m o 2h, hasta %s. /ephemeral <tiempo> prompts borra también los mensajes dirigidos a mí; en grupos necesito permiso para borrar mensajes. /ephemeral off vuelve a conservar los mensajes.",
+		"ephemeral_is_off":   "El modo efímero está desactivado en este chat.",
+		"ephemeral_on":       "El modo efímero está activado: borro mis mensajes %s después de enviarlos.",
+		"ephemeral_prompts":  "El modo efímero está activado: borro mis mensajes y los mensajes dirigidos a mí %s después de enviarse.",
+		"ephemeral_off":      "El modo efímero está desactivado. Los mensajes ya enviados se borran igualmente a su hora.",
+		"ephemeral_admins":   "Solo los administradores del grupo pueden cambiar el modo efímero.",
+		"ephemeral_failed":   "No se pudo cambiar el modo efímero. Inténtalo más tarde.",
+		"replyto_usage":      "/replyto on hace que mis respuestas respondan al mensaje que preguntó, para que preguntas y respuestas sigan juntas en chats concurridos. /replyto off las envía por separado. /replyto auto vuelve al valor predeterminado: respuestas en grupos, mensajes sueltos en chats privados.",
+		"replyto_state_on":   "Mis respuestas en este chat responden al mensaje que preguntó.",
+		"replyto_state_off":  "Mis respuestas en este chat se envían por separado.",
+		"replyto_on":         "Ahora mis respuestas responden al mensaje que preguntó.",
+		"replyto_off":        "Ahora mis respuestas se envían por separado.",
+		"replyto_auto_on":    "De vuelta al valor predeterminado: aquí mis respuestas responden al mensaje que preguntó.",
+		"replyto_auto_off":   "De vuelta al valor predeterminado: aquí mis respuestas se envían por separado.",
+		"replyto_admins":     "Solo los administradores del grupo pueden cambiar cómo respondo.",
+		"replyto_failed":     "No se pudo cambiar cómo respondo. Inténtalo más tarde.",
+		"regenerate_stale":   "Solo se puede regenerar la última respuesta.",
+		"compare_none":       "La nueva respuesta no difiere de la anterior.",
+		"compare_header":     "Cambios respecto a la respuesta anterior: %s",
+		"thoughts_working":   "Resumiendo el razonamiento...",
+		"thoughts_none":      "El resumen del razonamiento no está disponible.",
+		"thoughts_header":    "Resumen del razonamiento:",
+		"btn_thoughts_full":  "Razonamiento completo",
+		"thoughts_full":      "Razonamiento completo",
+		"sources_none":       "No hay fuentes para esta respuesta.",
+		"sources_header":     "Fuentes:",
+		"source_untitled":    "Sin título",
+		"code_none":          "No se ejecutó código para esta respuesta.",
+		"verbosity_short":    "Corta",
+		"verbosity_normal":   "Normal",
+		"verbosity_long":     "Larga",
+		"verbosity_set":      "Longitud de respuesta: %s.",
+		"verbosity_current":  "Longitud de respuesta actual: %s",
+		"tone_professional":  "Profesional",
+		"tone_neutral":       "Neutral",
+		"tone_playful":       "Desenfadado 🎉",
+		"tone_set":           "Tono establecido: %s.",
+		"tone_current":       "Tono actual: %s\nLas respuestas profesionales nunca llevan emojis ni palabrotas; las desenfadadas usan emojis con libertad.",
+		"comparedocs_usage":  "Envía los dos documentos que quieres comparar, como álbum o uno tras otro. También puedes enviarlos como álbum con el pie /compare_docs o responder /compare_docs a uno de ellos.",
+		"comparedocs_next":   "Recibido %s. Ahora envía el documento con el que compararlo.",
+		"comparedocs_failed": "No pude comparar estos documentos.",
+		"comparedocs_title":  "Comparación: %s y %s",
+		"comparedocs_aspect": "Aspecto",
+		"comparedocs_common": "En común",
+		"comparedocs_newer":  "Más reciente: %s",
+		"comparedocs_done":   "Más completo: %s",
+		"comparedocs_same":   "iguales",
+		"comparedocs_unsure": "no se puede saber",
+		"pipeline_step":      "%s: paso %d/%d, %s…",
+		"pipeline_done":      "%s: listo.",
+		"pipeline_failed":    "%s: ha fallado. Inténtalo de nuevo más tarde.",
+		"pipeline_usage":     "Envía %s con el texto a procesar, respóndelo a un mensaje o documento, o envía un documento con el comando como pie.",
+		"contract_title":     "Revisión de contrato",
+		"contract_summary":   "Resumen",
+		"contract_duties":    "Obligaciones",
+		"contract_risks":     "Riesgos",
+		"contract_unusual":   "Cláusulas inusuales y ausentes",
+		"contract_caveat":    "Esta revisión se generó automáticamente y no constituye asesoramiento jurídico.",
+		"contract_usage":     "/contract on revisa como contrato cada documento que envíes sin pie: resumen, obligaciones, riesgos y cláusulas inusuales, en un informe. Responde /contract a un documento o ponle el pie /contract para revisar solo ese.",
+		"contract_on":        "Revisión de contratos activada. Los documentos enviados sin pie se revisan como contratos.",
+		"contract_off":       "Revisión de contratos desactivada.",
+		"transcribe_usage":   "Responde /transcribe a una nota de voz, audio o vídeo, o envíalo con /transcribe como pie de foto. Añade srt para subtítulos.",
+		"ocr_usage":          "Responde /ocr a una foto, imagen o PDF escaneado, o envíalo con /ocr como pie de foto, para obtener su texto literal.",
+		"ocr_failed":         "No pude leer el texto de este escaneo.",
+		"ocr_empty":          "No encontré texto en este escaneo.",
+		"ocr_caption":        "Texto extraído",
+		"ocr_page":           "Página %d",
+		"summarize_usage":    "Envía /summarize [short|medium|detailed] [quotes] con un texto o enlace, respóndelo a un mensaje, enlace, documento o nota de voz, o envía un documento con /summarize como pie de foto.",
+		"summarize_failed":   "No pude resumir esto.",
+		"summarize_bad_link": "No pude leer la página enlazada.",
+		"summarize_caption":  "Resumen",
+		"summarize_quotes":   "Citas clave",
+		"translate_usage":    "Uso: /translate <idioma> <texto>, o como respuesta a un mensaje, documento o nota de voz. El idioma es un código como de, un nombre o un par como en-de.",
+		"translate_failed":   "No pude traducir esto.",
+		"translate_caption":  "Traducción: %s",
+		"search_usage":       "Uso: /search <consulta>. La respuesta siempre sale de una búsqueda reciente en Google, con sus fuentes.",
+		"search_unsourced":   "La búsqueda no encontró fuentes para esta respuesta, tómala con cautela.",
+		"transcribe_failed":  "No pude transcribir esa grabación.",
+		"transcribe_empty":   "No oí a nadie hablar en esa grabación.",
+		"transcribe_caption": "Transcripción completa",
+		"disclaimer_medical": "Esto es información general, no consejo médico. Consulta tu caso con un médico o farmacéutico.",
+		"disclaimer_finance": "Esto es información general, no asesoramiento financiero. Consulta las decisiones importantes con un asesor.",
+		"selfcheck_on":       "Autoverificación activada. Las respuestas factuales se contrastarán con fuentes web antes de enviarse.",
+		"selfcheck_off":      "Autoverificación desactivada.",
+		"deterministic_on":   "Modo determinista activado. Las respuestas usan temperatura 0 y semilla %d, así que repetir una pregunta en el mismo contexto da la misma respuesta.",
+		"deterministic_off":  "Modo determinista desactivado. Las respuestas vuelven a usar el muestreo predeterminado del modelo.",
+		"devmode_on":         "Modo desarrollador activado. Los errores y trazas de pila pegados reciben una causa probable, una solución y una reproducción mínima sin necesidad de preguntar.",
+		"devmode_off":        "Modo desarrollador desactivado.",
+		"language_current":   "Los mensajes del bot en este chat están en %s.",
+		"language_auto":      "Los mensajes del bot en este chat siguen el idioma de Telegram de cada persona.",
+		"language_set":       "Los mensajes del bot en este chat ahora están en %s.",
+		"language_usage":     "Uso: /language <código> o /language auto. Disponibles: %s",
+		"language_unknown":   "Ese idioma no está disponible. Disponibles: %s",
+		"usage_title":        "Uso de este chat",
+		"usage_today":        "Hoy",
+		"usage_month":        "Este mes",
+		"usage_none":         "%s: sin solicitudes",
+		"usage_line":         "%s: %s solicitudes, %s tokens (entrada %s / salida %s / razonamiento %s), ~$%.4f",
+		"usage_estimate":     "Los costes son estimaciones según los precios de lista.",
+		"calc_usage":         "Uso: /calc <pregunta con números>",
+		"action_confirm_q":   "¿Ejecutar la acción %s?",
+		"action_confirm":     "Confirmar",
+		"action_cancel":      "Cancelar",
+		"action_not_yours":   "Solo quien pidió esta acción puede confirmarla o cancelarla.",
+		"action_expired":     "Esa solicitud de acción ha caducado.",
+		"action_done":        "Acción %s completada.",
+		"action_failed":      "La acción %s falló.",
+		"action_cancelled":   "Acción cancelada.",
+		"admin_only":         "Este comando está reservado a los administradores del bot.",
+		"advanced_admins":    "Los ajustes avanzados están reservados a los administradores del bot.",
+		"model_admins":       "Solo los administradores del bot pueden elegir el modelo.",
+		"calendar_off":       "La integración con el calendario no está activada en este bot.",
+		"calendar_private":   "Pregunta por tu calendario en un chat privado con el bot.",
+		"calendar_status":    "Conectado a %s como %s.",
+		"calendar_none":      "No hay ningún calendario conectado. Usa /calendar connect <caldav-url> <username> <app-password> en un chat privado.",
+		"calendar_conn_use":  "Uso: /calendar connect <caldav-url> <username> <app-password>",
+		"calendar_unsafe":    "Por tu seguridad, conecta los calendarios en un chat privado con el bot. Conviene cambiar esa contraseña.",
+		"calendar_https":     "La URL de CalDAV debe empezar por https://.",
+		"calendar_failed":    "No se pudo acceder a ese calendario. Revisa la URL de CalDAV y las credenciales.",
+		"calendar_on":        "Calendario conectado. Pregúntame por tu agenda o pídeme que reserve tiempo.",
+		"calendar_removed":   "Calendario desconectado y credenciales eliminadas.",
+		"calendar_usage":     "Uso: /calendar [status|connect|disconnect]",
+		"export_gone":        "Esta respuesta ya no se puede exportar.",
+		"notion_off":         "La integración con Notion no está activada en este bot.",
+		"notion_status":      "Notion está conectado. Las respuestas se guardan en la página %s.",
+		"notion_none":        "Notion no está conectado. Usa /notion connect <integration-token> <parent-page-url-or-id> en un chat privado.",
+		"notion_conn_use":    "Uso: /notion connect <integration-token> <parent-page-url-or-id>",
+		"notion_unsafe":      "Por tu seguridad, conecta Notion en un chat privado con el bot. Conviene renovar ese token.",
+		"notion_bad_page":    "No encontré el ID de una página de Notion en ese enlace.",
+		"notion_on":          "Notion conectado. Comparte la página principal con tu integración y usa Save to Notion debajo de cualquier respuesta.",
+		"notion_removed":     "Notion desconectado y token eliminado.",
+		"notion_usage":       "Uso: /notion [status|connect|disconnect]",
+		"notion_first":       "Primero conecta Notion con /notion connect en un chat privado.",
+		"notion_saved":       "Guardado en Notion: %s",
+		"notion_failed":      "No se pudo guardar en Notion. Comprueba que la página principal está compartida con tu integración.",
+		"notion_timeout":     "Notion no respondió a tiempo. Inténtalo de nuevo.",
+		"prefs_off":          "Las preferencias personales no están activadas en este bot.",
+		"speech_current":     "Tus idiomas hablados: %s. Las notas de voz se transcriben con esta pista.",
+		"speech_none":        "No hay idiomas hablados definidos. Usa /languages English, Ukrainian para transcribir mejor las notas de voz en varios idiomas, o /languages reset.",
+		"speech_cleared":     "Idiomas hablados borrados.",
+		"speech_invalid":     "No se pudieron actualizar los idiomas hablados: %s.",
+		"speech_set":         "Idiomas hablados: %s.",
+		"replay_empty":       "Aún no hay nada que repetir. Primero chatea conmigo y luego ejecuta /replay <model>.",
+		"replay_failed":      "La repetición con %s falló tras %d de los turnos.",
+		"replay_cancelled":   "Repetición cancelada.",
+		"replay_caption":     "%d turnos repetidos con %s.",
+		"review_gone":        "Esta revisión ya no se puede exportar.",
+		"verify_ok":          "Verificado con fuentes web.",
+		"verify_fixed":       "Corregido tras la verificación.",
+		"verify_fixed_note":  "Corregido tras la verificación: %s",
+		"verify_unsure":      "Nota de fiabilidad: algunas afirmaciones no se pudieron verificar.",
+		"verify_unsure_note": "Nota de fiabilidad: %s",
+		"advanced_usage":     "Uso: /advanced [stop <seq> | <seq> ... | presence <-2..2> | frequency <-2..2> | seed <int> | <option> off | reset]",
+		"advanced_none":      "No hay ajustes avanzados de generación. Se usan los valores predeterminados del modelo.",
+		"advanced_stops":     "Secuencias de parada: %s",
+		"advanced_presence":  "Penalización por presencia: %g",
+		"advanced_frequency": "Penalización por frecuencia: %g",
+		"advanced_seed":      "Semilla: %d",
+		"advanced_stop_num":  "Indica entre 1 y %d secuencias de parada separadas por |.",
+		"advanced_penalty":   "La penalización debe ser un número entre %g y %g.",
+		"advanced_seed_int":  "La semilla debe ser un número entero.",
+		"repo_usage":         "Uso: /repo <URL git https> para indexar un repositorio público, /repo drop para quitarlo o /repo para ver el actual.",
+		"repo_status":        "Respondo a partir de %s: %d archivos en %d fragmentos, indexado hace %s.",
+		"repo_none":          "No hay ningún repositorio indexado en este chat.",
+		"repo_dropped":       "Se ha eliminado el índice del repositorio.",
+		"repo_failed":        "No se pudo indexar el repositorio: %s",
+		"repo_indexed":       "%s indexado: %d archivos en %d fragmentos. Pregunta por el código y citaré archivos y líneas; /repo drop lo elimina.",
+		"repo_partial":       "El repositorio es grande, así que solo se indexan sus primeros %d fragmentos.",
+		"thinking_low":       "Bajo - 4.096 tokens",
+		"thinking_medium":    "Medio - 16.384 tokens",
+		"thinking_high":      "Alto - 32.768 tokens",
+		"thinking_dynamic":   "Razonamiento dinámico",
+		"diff_changed":       "cambiado: %s",
+		"diff_added":         "añadido: %s",
+		"diff_removed":       "eliminado: %s",
+		"diff_more":          "(+%d más)",
+		"code_snippet":       "Fragmento de código %d",
+		"code_outcome":       "Resultado",
+		"code_output":        "Salida",
+		"code_file_outcome":  "Fragmento de código %d, resultado: %s",
+		"admin_usage":        "Uso: /admin stats | broadcast <text> | ban <user id> | unban <user id> | artifacts [<chat id> | purge <chat id>] | reload | drain | resume",
+		"admin_bcast_usage":  "Uso: /admin broadcast <text>",
+		"admin_broadcasting": "Enviando a %d chats.",
+		"admin_bcast_done":   "Difusión entregada en %d de %d chats.",
+		"admin_ban_usage":    "Uso: /admin %s <user id>, o responde a uno de sus mensajes.",
+		"admin_ban_admin":    "Los administradores no pueden ser bloqueados.",
+		"admin_unbanned":     "El usuario %d ya no está bloqueado.",
+		"admin_banned":       "El usuario %d está bloqueado. El bot ignora sus mensajes.",
+		"admin_drain_busy":   "Los mensajes nuevos se rechazan, pero %d chats siguen ocupados con %d mensajes en cola. Ejecuta /admin drain de nuevo o /admin resume.",
+		"admin_drained":      "Todas las solicitudes han terminado y los mensajes nuevos se rechazan. Ya se puede detener el bot. Usa /admin resume para volver a aceptar mensajes.",
+		"admin_resumed":      "Se vuelven a aceptar mensajes.",
+		"admin_stats":        "Estadísticas del bot",
+		"admin_unknown":      "desconocida",
+		"admin_version":      "Versión: %s, en marcha desde hace %s",
+		"admin_chats":        "Chats conocidos: %d (%d inactivos), sesiones activas: %d",
+		"admin_busy":         "Chats ocupados: %d, mensajes en cola: %d",
+		"admin_banned_num":   "Usuarios bloqueados: %d",
+		"admin_generations":  "Generaciones recientes: %d, solicitudes idénticas respondidas con ellas: %d",
+		"admin_today":        "Hoy",
+		"admin_month":        "Este mes",
+		"admin_shedding":     "Modo ligero por %s: las solicitudes nuevas usan %s y razonan menos",
+		"admin_budget_stop":  "Presupuesto diario de $%.2f alcanzado: las solicitudes nuevas se rechazan hasta las %s",
+		"admin_budget_lite":  "Presupuesto diario de $%.2f alcanzado: las solicitudes nuevas usan %s y razonan menos hasta las %s",
+		"admin_na":           "n/d",
+		"admin_art_stats":    "Respuestas guardadas: %d (%s), aciertos de botones %d / fallos %d (%s de aciertos), descartadas %d",
+		"admin_art_usage":    "Uso: /admin artifacts [<chat id> | purge <chat id>]",
+		"admin_art_purged":   "Se eliminaron %d respuestas guardadas del chat %d.",
+		"admin_art_none":     "No hay respuestas guardadas del chat %d.",
+		"admin_art_list":     "Respuestas guardadas del chat %d: %d",
+		"admin_art_newest":   "(se muestran las %d más recientes)",
+		"admin_art_item":     "#%s, hace %s, %s: %s",
+		"calls_unlimited":    "Llamadas al modelo: sin límite",
+		"calls_stats":        "Llamadas al modelo: %d de %d en curso, %d en espera",
+		"reload_actions_err": "Acciones de webhook no recargadas: %s",
+		"reload_actions":     "Se recargaron %d acciones de webhook.",
+		"reload_state_err":   "Lista de chats y bloqueos no recargada: %s",
+		"reload_state":       "Se recargaron %d chats conocidos y %d usuarios bloqueados.",
+		"reload_cfg_err":     "Configuración no recargada: %s",
+		"reload_cfg_same":    "Configuración recargada, ningún ajuste ha cambiado.",
+		"reload_cfg":         "Configuración recargada:",
+		"diff_counts":        "Frente a la respuesta anterior: %d frases cambiadas, %d añadidas, %d eliminadas.",
+		"speech_hint":        "El audio adjunto puede estar hablado en cualquiera de estos idiomas, incluso mezclados en una misma grabación: %s. Transcribe e interpreta cada parte en el idioma realmente hablado en lugar de suponer un solo idioma para toda la grabación.",
+		"speech_bad_name":    "%q no es un nombre de idioma",
+		"speech_too_many":    "se pueden indicar como máximo %d idiomas",
+	},
+	"ru": {
+		"welcome":            "Привет, я Eteon. Пришлите вопрос, ссылку или медиафайл, и я отвечу кратко.",
+		"thinking_current":   "Текущий бюджет размышлений: %s",
+		"thinking_switched":  "Бюджет размышлений изменён на %s",
+		"sampling_temp":      "Темп.",
+		"sampling_top_p":     "Top-p",
+		"sampling_tokens":    "Токены",
+		"sampling_reset":     "Сбросить выборку",
+		"tool_search":        "Веб-поиск",
+		"tool_links":         "Ссылки",
+		"tool_code":          "Код",
+		"tool_on":            "вкл",
+		"tool_off":           "выкл",
+		"tools_current":      "Инструменты: %s. Выключите все через /settings tools off, чтобы отвечала только модель.",
+		"tools_usage":        "Использование: /settings tools on|off или /settings google_search, url_context либо code_execution с on или off.",
+		"json_usage":         "Использование: /json <JSON-схема или описание формы> включает режим JSON; /json off выключает его.",
+		"json_on":            "Режим JSON включён. Ответы приходят в JSON такой формы: %s\nОтправьте /json off для обычных ответов.",
+		"json_off":           "Режим JSON выключен. Ответы снова обычным текстом.",
+		"json_bad_schema":    "Похоже на JSON-схему, но её не удалось разобрать: %s",
+		"json_document":      "JSON — в документе выше.",
+		"sampling_default":   "по умолчанию",
+		"sampling_current":   "Температура: %s, top-p: %s, максимум токенов ответа: %s",
+		"sampling_usage":     "Использование: /settings temperature <0..2>, /settings top_p <0..1> или /settings max_tokens <%d..%d>, также со значением default; /settings reset сбрасывает всё.",
+		"topic_not_forum":    "/topic работает внутри темы группы с форумом.",
+		"topic_admins_only":  "Менять настройки этой темы могут только администраторы группы.",
+		"topic_current":      "Персона темы: %s\nИнструменты темы: %s",
+		"topic_default":      "как в чате",
+		"topic_usage":        "Использование: /topic persona <инструкции|default>, /topic tools <all|none|список из %s> или /topic reset.",
+		"topic_too_long":     "Персона слишком длинная (%d символов). Ограничение — %d.",
+		"export_usage":       "Использование: /export [md|json|html]. По умолчанию — Markdown.",
+		"export_empty":       "Пока нечего экспортировать: разговор пуст.",
+		"import_usage":       "Отправьте JSON-расшифровку из /export с подписью /import или ответьте на неё командой /import. Она заменит разговор в этом чате.",
+		"import_too_large":   "Расшифровка слишком большая. Ограничение — %d КБ.",
+		"import_invalid":     "Этот файл не является JSON-расшифровкой из /export.",
+		"import_done":        "Разговор восстановлен, сообщений: %d.",
+		"checkpoint_usage":   "Использование: /checkpoint [имя]. Имя — одно слово до 32 символов из букв, цифр, точек, дефисов или подчёркиваний.",
+		"checkpoint_empty":   "Пока нечего сохранять.",
+		"checkpoint_saved":   "Контрольная точка %s сохранена, сообщений: %d. Чтобы вернуться к ней, используйте /rollback %s.",
+		"rollback_none":      "Контрольных точек пока нет. Сохраните одну командой /checkpoint [имя].",
+		"rollback_list":      "Контрольные точки, сначала новые (имя · сохранена · сообщений):\n%s\nЧтобы вернуться к одной, используйте /rollback <имя>.",
+		"rollback_unknown":   "Контрольной точки %s нет. Отправьте /rollback, чтобы увидеть список.",
+		"rollback_done":      "Возврат к контрольной точке %s, сообщений: %d.",
+		"rollback_saved":     "Покинутый разговор сохранён как %s.",
+		"chat_usage":         "Использование: /chat показывает ваши разговоры, /chat new <имя> начинает новый, /chat switch <имя> переключает на другой, /chat delete <имя> удаляет. Имя — одно слово до 32 символов из букв, цифр, точек, дефисов или подчёркиваний.",
+		"chat_list":          "Разговоры (имя · сообщений):\n%s\nЧтобы переключиться, используйте /chat switch <имя>.",
+		"chat_exists":        "Разговор %s уже есть. Чтобы вернуться к нему, используйте /chat switch %s.",
+		"chat_too_many":      "В чате может быть не больше %d разговоров. Сначала удалите один командой /chat delete <имя>.",
+		"chat_started":       "Начат разговор %s. Предыдущий сохранён.",
+		"chat_unknown":       "Разговора %s нет. Отправьте /chat, чтобы увидеть список.",
+		"chat_switched":      "Переключено на %s, сообщений: %d.",
+		"chat_delete_active": "Переключитесь на другой разговор, прежде чем удалять этот.",
+		"chat_deleted":       "Разговор %s удалён.",
+		"catchup_groups":     "/catchup работает в группах.",
+		"catchup_admins":     "Включать и выключать сводку могут только администраторы группы.",
+		"catchup_on":         "Сводка включена. Я храню сообщения за последние %d ч, не больше %d, только в памяти и никогда не записываю их на диск. /catchup [часы] подводит итог; /catchup off удаляет их. Я вижу все сообщения, только если для меня выключен режим приватности или я администратор группы.",
+		"catchup_off":        "Сводка выключена, сохранённые сообщения удалены.",
+		"catchup_disabled":   "Сводка в этой группе выключена. Администратор может включить её командой /catchup on.",
+		"catchup_usage":      "Использование: /catchup [часы от 1 до %d] или /catchup on|off для администраторов.",
+		"catchup_empty":      "За последние %d ч ничего не писали.",
+		"catchup_failed":     "Не удалось подвести итог обсуждения. Попробуйте позже.",
+		"catchup_header":     "Что вы пропустили за последние %d ч:",
+		"sendto_usage":       "Использование: /sendto <цель> <что написать>, /sendto remove <цель>, а в целевом чате — /sendto allow <имя> и /sendto revoke. Имена состоят из a-z, 0-9, - и _.",
+		"sendto_none":        "У вас нет сохранённых целей. Выполните /sendto allow <имя> в чате, который должен получать ваши сообщения; в группах это должен сделать администратор.",
+		"sendto_list":        "Ваши цели: %s. Составьте сообщение командой /sendto <цель> <что написать>.",
+		"sendto_allowed":     "Этот чат сохранён как цель %s. Составляйте для него сообщения из любого чата командой /sendto %[1]s <что написать>.",
+		"sendto_admins_only": "Разрешить этому чату получать составленные сообщения могут только администраторы группы.",
+		"sendto_revoked":     "Удалено целей, указывающих на этот чат: %d.",
+		"sendto_removed":     "Цель %s удалена.",
+		"sendto_unknown":     "Цели с именем %s нет. /sendto покажет ваши цели.",
+		"sendto_preview":     "Черновик для %s. Отправьте как есть, отмените или выполните /sendto ещё раз с правками:",
+		"sendto_no_draft":    "Не удалось составить черновик. Попробуйте ещё раз.",
+		"btn_send_draft":     "Отправить",
+		"btn_discard_draft":  "Отменить",
+		"sendto_sent":        "Отправлено в %s.",
+		"sendto_failed":      "Не удалось отправить в %s. Проверьте, что я всё ещё участник и могу там писать.",
+		"sendto_expired":     "Срок действия черновика истёк. Выполните /sendto ещё раз.",
+		"sendto_discarded":   "Черновик отменён.",
+		"sendto_not_yours":   "Отправить или отменить черновик может только его автор.",
+		"remind_usage":       "Использование: /remind in 2h30m <текст>, /remind at 18:00 <текст>, /remind tomorrow 9:00 <текст> или /remind 2025-06-01 9:00 <текст>. Начните текст с ask:, чтобы я ответил на него как на вопрос в это время. /remind показывает ваши напоминания, /remind cancel <id> отменяет одно, а /remind tz <Регион/Город> задаёт часовой пояс.",
+		"remind_none":        "У вас нет напоминаний в этом чате.",
+		"remind_list":        "Ваши напоминания:\n%s",
+		"remind_unknown":     "Здесь нет вашего напоминания #%d.",
+		"remind_cancelled":   "Напоминание #%d отменено.",
+		"remind_bad_tz":      "Неизвестный часовой пояс. Используйте название вроде Europe/Moscow или Asia/Almaty.",
+		"remind_tz":          "Часовой пояс: %s.",
+		"remind_range":       "Напоминание должно быть в будущем и не дальше чем через год.",
+		"remind_too_many":    "У вас уже %d напоминаний. Сначала отмените одно.",
+		"remind_set":         "Напоминание #%d установлено на %s.",
+		"remind_fire":        "⏰ Напоминание: %s",
+		"digest_usage":       "Использование: /digest <daily|weekdays|weekends|mon,thu> <ЧЧ:ММ> <запрос>, например /digest daily 8:00 кратко перескажи главную Hacker News. В это время я отвечу на запрос с поиском в интернете. /digest показывает ваши дайджесты, /digest cancel <id> отменяет один. Время указывается в часовом поясе чата из /remind tz.",
+		"digest_none":        "У вас нет дайджестов в этом чате.",
+		"digest_list":        "Ваши дайджесты (%s):\n%s",
+		"digest_unknown":     "Здесь нет вашего дайджеста #%d.",
+		"digest_cancelled":   "Дайджест #%d отменён.",
+		"digest_too_many":    "У вас уже %d дайджестов. Сначала отмените один.",
+		"digest_set":         "Дайджест #%d запланирован: %s (%s). Первый придёт %s.",
+		"input_failed":       "Не удалось обработать это сообщение.",
+		"rate_limited":       "Вы отправляете сообщения слишком часто. Попробуйте снова через %d с.",
+		"file_too_large":     "Файл больше 20 МБ — это максимум, который Telegram позволяет скачивать ботам. Пришлите файл поменьше или сожмите его.",
+		"input_empty":        "Пришлите текст или поддерживаемый медиафайл.",
+		"request_failed":     "Eteon не смог выполнить этот запрос.",
+		"request_cancelled":  "Запрос отменён.",
+		"calc_no_code":       "Не удалось подтвердить ответ выполненным кодом, поэтому я не назову число. Попробуйте переформулировать расчёт.",
+		"blocked_reason":     "Gemini заблокировал запрос (%s).",
+		"blocked_hint":       "Переформулируйте его без отмеченного содержимого или разбейте на вопросы поменьше.",
+		"safety_categories":  "Отмеченные категории: %s.",
+		"finish_max_tokens":  "Ответ упёрся в ограничение длины. Нажмите «Продолжить», чтобы получить остальное, или разрешите более длинные ответы через /verbosity или /settings.",
+		"finish_safety":      "Ответ остановлен фильтрами безопасности.",
+		"finish_recitation":  "Ответ остановлен, потому что почти дословно воспроизводил существующий текст, например источник, защищённый авторским правом. Попросите краткое изложение или пересказ.",
+		"finish_other":       "Ответ оборвался раньше времени (%s). Попробуйте ещё раз или переформулируйте запрос.",
+		"btn_continue":       "Продолжить",
+		"continue_stale":     "Продолжить можно только последний ответ.",
+		"no_content":         "Ответ не получен.",
+		"fallback_notice":    "Ответила модель %s, потому что %s была недоступна.",
+		"light_notice":       "Бот сейчас сильно загружен, поэтому над этим ответом он думал меньше обычного.",
+		"budget_notice":      "Дневной лимит использования исчерпан, поэтому этот ответ дала модель поменьше.",
+		"budget_refused":     "Бот исчерпал свой лимит использования на сегодня. Попробуйте снова после %s.",
+		"budget_user_limit":  "Вы исчерпали свой лимит использования на сегодня. Попробуйте снова после %s.",
+		"grounding_sources":  "Источников: %d",
+		"grounding_newest":   "самый свежий от %s",
+		"grounding_cited":    "подтверждено %d%% ответа",
+		"queue_full":         "У вас уже несколько сообщений в очереди. Дождитесь ответов, прежде чем отправлять новые.",
+		"queue_draining":     "Бот скоро перезапустится. Отправьте сообщение ещё раз через минуту.",
+		"queued_one":         "В очереди, обрабатываю ваше предыдущее сообщение.",
+		"queued_many":        "В очереди после ваших предыдущих сообщений. Скоро дойду и до этого.",
+		"calls_waiting":      "Сейчас много запросов: вы %d-й в очереди, ждать примерно %s.",
+		"calls_saturated":    "Eteon сейчас перегружен. Попробуйте снова через минуту.",
+		"btn_cancel_request": "Отменить текущий запрос",
+		"cancel_started":     "Отменяю текущий запрос.",
+		"cancel_nothing":     "Сейчас нет выполняющихся запросов.",
+		"cancel_not_yours":   "Отменить текущий запрос может только его автор или администратор чата.",
+		"btn_thoughts":       "Показать рассуждения",
+		"btn_sources":        "Показать источники",
+		"btn_code":           "Показать код",
+		"btn_obsidian":       "Экспорт в заметку Obsidian",
+		"btn_notion":         "Сохранить в Notion",
+		"btn_review_patch":   "Экспорт ревью как комментарии в .patch",
+		"btn_regenerate":     "Сгенерировать заново",
+		"btn_compare":        "Сравнить с предыдущим",
+		"btn_bookmark":       "⭐ В закладки",
+		"bookmark_expired":   "Этот ответ слишком старый, чтобы добавить его в закладки.",
+		"bookmark_saved":     "Добавлено в закладки как #%d. См. /bookmarks.",
+		"bookmark_exists":    "Уже в закладках как #%d.",
+		"bookmark_too_many":  "У вас %d закладок. Сначала удалите лишние командой /bookmarks delete <id>.",
+		"bookmark_failed":    "Не удалось сохранить закладку. Попробуйте позже.",
+		"bookmarks_usage":    "Использование: /bookmarks показывает ваши закладки, /bookmarks <слова> ищет в них, /bookmarks <id> открывает одну, /bookmarks delete <id> удаляет.",
+		"bookmarks_none":     "Закладок пока нет. Нажмите ⭐ В закладки под ответом, чтобы сохранить его.",
+		"bookmarks_no_match": "Нет закладок, содержащих %q.",
+		"bookmarks_list":     "Закладки (%d):\n%s\nОтправьте /bookmarks <id>, чтобы открыть одну.",
+		"bookmark_unknown":   "У вас нет закладки #%d.",
+		"bookmark_deleted":   "Закладка #%d удалена.",
+		"feedback_expired":   "Этот ответ слишком старый, чтобы его оценить.",
+		"feedback_thanks":    "Спасибо! Учту это, чтобы отвечать так, как вам удобнее. См. /remember.",
+		"feedback_same":      "Вы уже оценили этот ответ.",
+		"feedback_failed":    "Не удалось сохранить оценку. Попробуйте позже.",
+		"remember_usage":     "Использование: /remember <факт> запоминает что-то о вас во всех чатах, даже после /reset (например, /remember я вегетарианец). /remember показывает, что я помню и чему научился по вашим оценкам, /memories выводит список фактов, /forget <id> забывает один, а /remember reset забывает всё, включая оценки 👍/👎.",
+		"remember_show":      "Что я помню о вас:\n%s\n\nВыведено из оценок (%d):\n%s\n\nДобавить факт: /remember <факт>, забыть: /forget <id>.",
+		"remember_no_notes":  "пока нет",
+		"remember_unrated":   "пока ничего — оцените хотя бы %d ответа кнопками 👍 или 👎",
+		"remember_saved":     "Понял, буду помнить это во всех чатах как #%d. См. /memories.",
+		"remember_exists":    "Я уже помню это как #%d.",
+		"remember_too_long":  "Факт должен быть короче %d символов.",
+		"remember_too_many":  "Я помню о вас уже %d фактов. Сначала забудьте один через /forget <id>.",
+		"memory_unknown":     "Воспоминания #%d нет.",
+		"memory_forgot":      "Воспоминание #%d забыто.",
+		"remember_reset":     "Ваши воспоминания и оценки забыты.",
+		"remember_failed":    "Не удалось сохранить воспоминания. Попробуйте позже.",
+		"memories_none":      "Я пока ничего о вас не помню. Расскажите что-нибудь через /remember <факт>.",
+		"memories_list":      "Что я помню о вас (%d):\n%s\nЗабыть: /forget <id>.",
+		"memories_cleared":   "Все ваши воспоминания забыты.",
+		"forget_usage":       "Использование: /forget <id> забывает одно воспоминание из /memories, /forget all забывает все.",
+		"memories_auto":      "Использование: /memories auto on позволяет мне находить факты о вас в нашем личном чате, чтобы вы их сохранили или удалили, /memories auto off отключает это.",
+		"memories_auto_on":   "Каждые %d сообщений я буду искать в нашем личном чате факты, которые стоит запомнить, и спрашивать вас, прежде чем сохранить. Отключить: /memories auto off.",
+		"memories_auto_off":  "Буду помнить только то, что вы скажете через /remember.",
+		"memories_pending":   "⏳ отмечает факты, которые я заметил в наших чатах. Сохраните или удалите их кнопками.",
+		"memories_extracted": "Я заметил о вас такие факты. Сохраните те, что мне стоит запомнить:\n%s",
+		"memory_kept":        "Воспоминание #%d сохранено.",
+		"kb_usage":           "Использование: ответьте /kb add на документ или отправьте его с подписью /kb add, чтобы добавить в базу знаний этого чата. Вопросы здесь будут получать ответы из неё. /kb list выводит документы, /kb delete <id> удаляет один, /kb delete all удаляет все. /kb share editor|reader приглашает другие чаты вашей команды, /kb join <токен> подключает общую базу знаний, /kb leave возвращает собственную базу этого чата.",
+		"kb_added":           "%s добавлен в базу знаний как #%d (%s). Спрашивайте.",
+		"kb_passages":        "фрагментов: %d",
+		"kb_truncated":       "Документ длинный, поэтому проиндексировано только его начало.",
+		"kb_list":            "База знаний (документов: %d):\n%s\nУдалить: /kb delete <id>.",
+		"kb_empty":           "База знаний этого чата пуста. Ответьте /kb add на документ, чтобы добавить его.",
+		"kb_unsupported":     "Не удаётся прочитать текст %s. Добавляйте документы PDF, Word, Excel, EPUB или текстовые файлы.",
+		"kb_full":            "В базе знаний не больше %d фрагментов. Сначала удалите документ через /kb delete <id>.",
+		"kb_unknown":         "В базе знаний нет документа #%d.",
+		"kb_deleted":         "Документ #%d удалён из базы знаний.",
+		"kb_cleared":         "База знаний теперь пуста.",
+		"kb_read_only":       "Этот чат читает общую базу знаний и не может менять её документы.",
+		"kb_owner_only":      "Делиться этой базой знаний может только чат, которому она принадлежит.",
+		"kb_admins_only":     "Только администраторы группы могут менять, как этот чат делится базами знаний.",
+		"kb_share_usage":     "Использование: /kb share editor позволяет другим чатам добавлять и удалять документы, /kb share reader — только задавать вопросы.",
+		"kb_invite":          "Приглашение создано. Отправьте в другом чате:\n`/kb join %s`\nРоль: %s. Приглашение действует для любого числа чатов %d дн.",
+		"kb_join_usage":      "Использование: /kb join <токен>, токен берётся из /kb share.",
+		"kb_join_invalid":    "Такого приглашения нет или оно истекло. Попросите новое через /kb share.",
+		"kb_join_own":        "Эта база знаний уже принадлежит этому чату.",
+		"kb_joined":          "Теперь этот чат использует общую базу знаний, роль: %s. Собственные документы отложены до /kb leave.",
+		"kb_not_shared":      "Этот чат использует собственную базу знаний.",
+		"kb_left":            "Этот чат снова использует собственную базу знаний.",
+		"kb_members_none":    "Этой базой знаний никто не пользуется совместно. Пригласите другие чаты через /kb share editor|reader.",
+		"kb_members":         "Общий доступ (чат · роль):\n%s\nОткрытых приглашений: %d. Закрыть доступ: /kb revoke <id чата> или /kb revoke all.",
+		"kb_revoked":         "Чат %d больше не пользуется этой базой знаний.",
+		"kb_revoked_all":     "Общий доступ к этой базе знаний закрыт, приглашения больше не действуют.",
+		"kb_not_member":      "Чат %d не пользуется этой базой знаний.",
+		"kb_role_owner":      "владелец",
+		"kb_role_editor":     "редактор",
+		"kb_role_reader":     "читатель",
+		"kb_failed":          "Не удалось обновить базу знаний. Попробуйте позже.",
+		"recall_usage":       "Использование: /recall <запрос> отвечает по прежним обменам сообщениями в этом чате, которые уже не помещаются в историю разговора. /recall on или /recall off включает или выключает их автоматический поиск для обычных сообщений, а /recall clear удаляет их. В архиве этого чата обменов: %d.",
+		"recall_auto_on":     "Прежние обмены, связанные с сообщением, подтягиваются автоматически.",
+		"recall_auto_off":    "Прежние обмены ищутся только через /recall <запрос>.",
+		"recall_cleared":     "Архив прежних обменов теперь пуст.",
+		"recall_empty":       "Из истории этого чата пока ничего не выпало, так что вспоминать нечего.",
+		"recall_failed":      "Не удалось обновить архив прежних обменов. Попробуйте позже.",
+		"privacy_usage":      "/privacy on отвечает на каждое сообщение отдельно: разговор не сохраняется, у ответов нет кнопок, потому что их содержимое не хранится, а в журналы не попадает то, что вы отправляете. При включении история чата удаляется. /privacy off снова сохраняет разговоры. Настройки, закладки, воспоминания и база знаний остаются в любом случае.",
+		"privacy_state_on":   "Режим приватности в этом чате включён.",
+		"privacy_state_off":  "Режим приватности в этом чате выключен.",
+		"privacy_on":         "Режим приватности включён. История чата удалена, и теперь каждое сообщение получает отдельный ответ и не сохраняется.",
+		"privacy_off":        "Режим приватности выключен. Новые сообщения снова сохраняются как разговор.",
+		"privacy_admins":     "Переключать режим приватности могут только администраторы группы.",
+		"privacy_failed":     "Не удалось переключить режим приватности. Попробуйте позже.",
+		"catchup_private":    "Сводка хранит сообщения группы, поэтому её нельзя включить в режиме приватности.",
+		"ephemeral_usage":    "/ephemeral <время> удаляет мои сообщения в этом чате через указанное время, например 10m или 2h, не больше %s. /ephemeral <время> prompts удаляет и сообщения, адресованные мне; в группах для этого мне нужно право удалять сообщения. /ephemeral off снова сохраняет сообщения.",
+		"ephemeral_is_off":   "Временный режим в этом чате выключен.",
+		"ephemeral_on":       "Временный режим включён: я удаляю свои сообщения через %s после отправки.",
+		"ephemeral_prompts":  "Временный режим включён: я удаляю свои сообщения и сообщения, адресованные мне, через %s после отправки.",
+		"ephemeral_off":      "Временный режим выключен. Уже отправленные сообщения всё равно будут удалены вовремя.",
+		"ephemeral_admins":   "Только администраторы группы могут переключать временный режим.",
+		"ephemeral_failed":   "Не удалось переключить временный режим. Попробуйте позже.",
+		"replyto_usage":      "/replyto on делает мои ответы ответами на сообщение с вопросом, чтобы вопросы и ответы не терялись в оживлённых чатах. /replyto off отправляет их отдельно. /replyto auto возвращает настройку по умолчанию: ответы в группах, обычные сообщения в личных чатах.",
+		"replyto_state_on":   "Мои ответы в этом чате отвечают на сообщение с вопросом.",
+		"replyto_state_off":  "Мои ответы в этом чате отправляются отдельно.",
+		"replyto_on":         "Теперь мои ответы отвечают на сообщение с вопросом.",
+		"replyto_off":        "Теперь мои ответы отправляются отдельно.",
+		"replyto_auto_on":    "Вернулась настройка по умолчанию: здесь мои ответы отвечают на сообщение с вопросом.",
+		"replyto_auto_off":   "Вернулась настройка по умолчанию: здесь мои ответы отправляются отдельно.",
+		"replyto_admins":     "Только администраторы группы могут менять, как я отвечаю.",
+		"replyto_failed":     "Не удалось изменить, как я отвечаю. Попробуйте позже.",
+		"regenerate_stale":   "Заново сгенерировать можно только последний ответ.",
+		"compare_none":       "Новый ответ не отличается от предыдущего.",
+		"compare_header":     "Изменения по сравнению с предыдущим ответом: %s",
+		"thoughts_working":   "Составляю краткое изложение рассуждений...",
+		"thoughts_none":      "Краткое изложение рассуждений недоступно.",
+		"thoughts_header":    "Ход рассуждений:",
+		"btn_thoughts_full":  "Полный ход рассуждений",
+		"thoughts_full":      "Полный ход рассуждений",
+		"sources_none":       "Для этого ответа нет источников.",
+		"sources_header":     "Источники:",
+		"source_untitled":    "Без названия",
+		"code_none":          "Для этого ответа код не выполнялся.",
+		"verbosity_short":    "Короткие",
+		"verbosity_normal":   "Обычные",
+		"verbosity_long":     "Подробные",
+		"verbosity_set":      "Длина ответов: %s.",
+		"verbosity_current":  "Текущая длина ответов: %s",
+		"tone_professional":  "Деловой",
+		"tone_neutral":       "Нейтральный",
+		"tone_playful":       "Игривый 🎉",
+		"tone_set":           "Тон: %s.",
+		"tone_current":       "Текущий тон: %s\nВ деловом тоне ответы без эмодзи и грубостей, в игривом — с эмодзи.",
+		"comparedocs_usage":  "Отправьте два документа для сравнения — альбомом или по одному. Можно также отправить альбом с подписью /compare_docs или ответить /compare_docs на один из них.",
+		"comparedocs_next":   "Получил %s. Теперь отправьте документ, с которым его сравнить.",
+		"comparedocs_failed": "Не удалось сравнить эти документы.",
+		"comparedocs_title":  "Сравнение: %s и %s",
+		"comparedocs_aspect": "Аспект",
+		"comparedocs_common": "Общее",
+		"comparedocs_newer":  "Новее: %s",
+		"comparedocs_done":   "Полнее: %s",
+		"comparedocs_same":   "одинаково",
+		"comparedocs_unsure": "не определить",
+		"pipeline_step":      "%s: шаг %d/%d, %s…",
+		"pipeline_done":      "%s: готово.",
+		"pipeline_failed":    "%s: не удалось. Попробуйте позже.",
+		"pipeline_usage":     "Отправьте %s с текстом для обработки, ответьте им на сообщение или документ или отправьте документ с этой командой в подписи.",
+		"contract_title":     "Проверка договора",
+		"contract_summary":   "Краткое содержание",
+		"contract_duties":    "Обязательства",
+		"contract_risks":     "Риски",
+		"contract_unusual":   "Необычные и отсутствующие положения",
+		"contract_caveat":    "Эта проверка создана автоматически и не является юридической консультацией.",
+		"contract_usage":     "/contract on проверяет как договор каждый документ, отправленный без подписи: краткое содержание, обязательства, риски и необычные положения — в виде отчёта. Ответьте /contract на документ или подпишите его /contract, чтобы проверить только его.",
+		"contract_on":        "Проверка договоров включена. Документы без подписи проверяются как договоры.",
+		"contract_off":       "Проверка договоров выключена.",
+		"transcribe_usage":   "Ответьте /transcribe на голосовое сообщение, аудио или видео или отправьте его с подписью /transcribe. Добавьте srt для субтитров.",
+		"ocr_usage":          "Ответьте /ocr на фото, изображение или PDF-скан или отправьте его с подписью /ocr, чтобы получить текст дословно.",
+		"ocr_failed":         "Не удалось прочитать текст этого скана.",
+		"ocr_empty":          "В этом скане нет текста.",
+		"ocr_caption":        "Извлечённый текст",
+		"ocr_page":           "Страница %d",
+		"summarize_usage":    "Отправьте /summarize [short|medium|detailed] [quotes] с текстом или ссылкой, ответьте им на сообщение, ссылку, документ или голосовое сообщение, или отправьте документ с подписью /summarize.",
+		"summarize_failed":   "Не удалось составить краткое содержание.",
+		"summarize_bad_link": "Не удалось прочитать страницу по ссылке.",
+		"summarize_caption":  "Краткое содержание",
+		"summarize_quotes":   "Ключевые цитаты",
+		"translate_usage":    "Использование: /translate <язык> <текст> или ответом на сообщение, документ или голосовое. Язык — код вроде de, название или пара вроде en-de.",
+		"translate_failed":   "Не удалось это перевести.",
+		"translate_caption":  "Перевод: %s",
+		"search_usage":       "Использование: /search <запрос>. Ответ всегда основан на свежем поиске Google и содержит источники.",
+		"search_unsourced":   "Поиск не нашёл источников для этого ответа, относитесь к нему осторожно.",
+		"transcribe_failed":  "Не удалось расшифровать эту запись.",
+		"transcribe_empty":   "В этой записи я не услышал речи.",
+		"transcribe_caption": "Полная расшифровка",
+		"disclaimer_medical": "Это общая информация, а не медицинская консультация. Обсудите свою ситуацию с врачом или фармацевтом.",
+		"disclaimer_finance": "Это общая информация, а не финансовая консультация. Важные решения обсудите с консультантом.",
+		"selfcheck_on":       "Самопроверка включена. Фактические ответы будут сверяться с веб-источниками перед отправкой.",
+		"selfcheck_off":      "Самопроверка выключена.",
+		"deterministic_on":   "Детерминированный режим включён. Ответы используют температуру 0 и seed %d, поэтому один и тот же вопрос в том же контексте даёт тот же ответ.",
+		"deterministic_off":  "Детерминированный режим выключен. Ответы снова используют стандартную выборку модели.",
+		"devmode_on":         "Режим разработчика включён. Для вставленных ошибок и трассировок стека бот без вопроса назовёт вероятную причину, исправление и минимальный пример воспроизведения.",
+		"devmode_off":        "Режим разработчика выключен.",
+		"language_current":   "Сообщения бота в этом чате на языке: %s.",
+		"language_auto":      "Сообщения бота в этом чате следуют языку Telegram каждого пользователя.",
+		"language_set":       "Теперь сообщения бота в этом чате на языке: %s.",
+		"language_usage":     "Использование: /language <код> или /language auto. Доступны: %s",
+		"language_unknown":   "Этот язык недоступен. Доступны: %s",
+		"usage_title":        "Расход в этом чате",
+		"usage_today":        "Сегодня",
+		"usage_month":        "В этом месяце",
+		"usage_none":         "%s: запросов нет",
+		"usage_line":         "%s: запросов %s, токенов %s (запрос %s / ответ %s / размышления %s), ~$%.4f",
+		"usage_estimate":     "Стоимость оценена по прейскурантным ценам.",
+		"calc_usage":         "Использование: /calc <вопрос с числами>",
+		"action_confirm_q":   "Выполнить действие %s?",
+		"action_confirm":     "Подтвердить",
+		"action_cancel":      "Отменить",
+		"action_not_yours":   "Подтвердить или отменить это действие может только тот, кто его запросил.",
+		"action_expired":     "Срок этого запроса на действие истёк.",
+		"action_done":        "Действие %s выполнено.",
+		"action_failed":      "Действие %s не удалось.",
+		"action_cancelled":   "Действие отменено.",
+		"admin_only":         "Эта команда доступна только администраторам бота.",
+		"advanced_admins":    "Расширенные настройки доступны только администраторам бота.",
+		"model_admins":       "Выбирать модель могут только администраторы бота.",
+		"calendar_off":       "Интеграция с календарём в этом боте не включена.",
+		"calendar_private":   "Спрашивайте о своём календаре в личном чате с ботом.",
+		"calendar_status":    "Подключено к %s как %s.",
+		"calendar_none":      "Календарь не подключён. Используйте /calendar connect <caldav-url> <username> <app-password> в личном чате.",
+		"calendar_conn_use":  "Использование: /calendar connect <caldav-url> <username> <app-password>",
+		"calendar_unsafe":    "В целях безопасности подключайте календари в личном чате с ботом. Лучше смените этот пароль.",
+		"calendar_https":     "Адрес CalDAV должен начинаться с https://.",
+		"calendar_failed":    "Не удалось подключиться к календарю. Проверьте адрес CalDAV и учётные данные.",
+		"calendar_on":        "Календарь подключён. Спрашивайте о расписании или просите запланировать время.",
+		"calendar_removed":   "Календарь отключён, учётные данные удалены.",
+		"calendar_usage":     "Использование: /calendar [status|connect|disconnect]",
+		"export_gone":        "Этот ответ больше нельзя экспортировать.",
+		"notion_off":         "Интеграция с Notion в этом боте не включена.",
+		"notion_status":      "Notion подключён. Ответы сохраняются на странице %s.",
+		"notion_none":        "Notion не подключён. Используйте /notion connect <integration-token> <parent-page-url-or-id> в личном чате.",
+		"notion_conn_use":    "Использование: /notion connect <integration-token> <parent-page-url-or-id>",
+		"notion_unsafe":      "В целях безопасности подключайте Notion в личном чате с ботом. Лучше замените этот токен.",
+		"notion_bad_page":    "В этой ссылке не найден ID страницы Notion.",
+		"notion_on":          "Notion подключён. Откройте родительскую страницу для своей интеграции и нажимайте Save to Notion под любым ответом.",
+		"notion_removed":     "Notion отключён, токен удалён.",
+		"notion_usage":       "Использование: /notion [status|connect|disconnect]",
+		"notion_first":       "Сначала подключите Notion командой /notion connect в личном чате.",
+		"notion_saved":       "Сохранено в Notion: %s",
+		"notion_failed":      "Не удалось сохранить в Notion. Проверьте, что родительская страница открыта для вашей интеграции.",
+		"notion_timeout":     "Notion не ответил вовремя. Попробуйте ещё раз.",
+		"prefs_off":          "Личные настройки в этом боте не включены.",
+		"speech_current":     "Ваши разговорные языки: %s. Голосовые сообщения расшифровываются с этой подсказкой.",
+		"speech_none":        "Разговорные языки не заданы. Используйте /languages English, Ukrainian, чтобы лучше расшифровывать голосовые сообщения на нескольких языках, или /languages reset.",
+		"speech_cleared":     "Разговорные языки сброшены.",
+		"speech_invalid":     "Не удалось обновить разговорные языки: %s.",
+		"speech_set":         "Разговорные языки: %s.",
+		"replay_empty":       "Пока нечего повторять. Сначала пообщайтесь со мной, затем запустите /replay <model>.",
+		"replay_failed":      "Повтор на %s прервался после %d ходов.",
+		"replay_cancelled":   "Повтор отменён.",
+		"replay_caption":     "Повторено ходов: %d на %s.",
+		"review_gone":        "Этот разбор больше нельзя экспортировать.",
+		"verify_ok":          "Проверено по веб-источникам.",
+		"verify_fixed":       "Исправлено после проверки.",
+		"verify_fixed_note":  "Исправлено после проверки: %s",
+		"verify_unsure":      "Примечание о достоверности: некоторые утверждения проверить не удалось.",
+		"verify_unsure_note": "Примечание о достоверности: %s",
+		"advanced_usage":     "Использование: /advanced [stop <seq> | <seq> ... | presence <-2..2> | frequency <-2..2> | seed <int> | <option> off | reset]",
+		"advanced_none":      "Расширенных настроек генерации нет. Действуют значения модели по умолчанию.",
+		"advanced_stops":     "Стоп-последовательности: %s",
+		"advanced_presence":  "Штраф за присутствие: %g",
+		"advanced_frequency": "Штраф за частоту: %g",
+		"advanced_seed":      "Seed: %d",
+		"advanced_stop_num":  "Укажите от 1 до %d стоп-последовательностей через |.",
+		"advanced_penalty":   "Штраф должен быть числом от %g до %g.",
+		"advanced_seed_int":  "Seed должен быть целым числом.",
+		"repo_usage":         "Использование: /repo <https git URL> индексирует публичный репозиторий, /repo drop удаляет его, /repo показывает текущий.",
+		"repo_status":        "Отвечаю по %s: %d файлов в %d фрагментах, проиндексировано %s назад.",
+		"repo_none":          "В этом чате нет проиндексированного репозитория.",
+		"repo_dropped":       "Индекс репозитория удалён.",
+		"repo_failed":        "Не удалось проиндексировать репозиторий: %s",
+		"repo_indexed":       "%s проиндексирован: %d файлов в %d фрагментах. Спрашивайте о коде, я буду ссылаться на файлы и строки; /repo drop удаляет индекс.",
+		"repo_partial":       "Репозиторий большой, поэтому проиндексированы только первые %d фрагментов.",
+		"thinking_low":       "Низкий - 4 096 токенов",
+		"thinking_medium":    "Средний - 16 384 токена",
+		"thinking_high":      "Высокий - 32 768 токенов",
+		"thinking_dynamic":   "Динамические размышления",
+		"diff_changed":       "изменено: %s",
+		"diff_added":         "добавлено: %s",
+		"diff_removed":       "удалено: %s",
+		"diff_more":          "(ещё %d)",
+		"code_snippet":       "Фрагмент кода %d",
+		"code_outcome":       "Результат",
+		"code_output":        "Вывод",
+		"code_file_outcome":  "Фрагмент кода %d, результат: %s",
+		"admin_usage":        "Использование: /admin stats | broadcast <text> | ban <user id> | unban <user id> | artifacts [<chat id> | purge <chat id>] | reload | drain | resume",
+		"admin_bcast_usage":  "Использование: /admin broadcast <text>",
+		"admin_broadcasting": "Рассылка в %d чатов.",
+		"admin_bcast_done":   "Рассылка доставлена в %d из %d чатов.",
+		"admin_ban_usage":    "Использование: /admin %s <user id> или ответьте на одно из сообщений пользователя.",
+		"admin_ban_admin":    "Администраторов нельзя заблокировать.",
+		"admin_unbanned":     "Пользователь %d больше не заблокирован.",
+		"admin_banned":       "Пользователь %d заблокирован. Бот игнорирует его сообщения.",
+		"admin_drain_busy":   "Новые сообщения отклоняются, но %d чатов ещё заняты %d сообщениями в очереди. Выполните /admin drain снова или /admin resume.",
+		"admin_drained":      "Все запросы завершены, новые сообщения отклоняются. Бота можно безопасно остановить. /admin resume снова включает приём сообщений.",
+		"admin_resumed":      "Приём сообщений возобновлён.",
+		"admin_stats":        "Статистика бота",
+		"admin_unknown":      "неизвестна",
+		"admin_version":      "Версия: %s, работает %s",
+		"admin_chats":        "Известные чаты: %d (%d неактивны), активные сессии: %d",
+		"admin_busy":         "Занятые чаты: %d, сообщений в очереди: %d",
+		"admin_banned_num":   "Заблокированные пользователи: %d",
+		"admin_generations":  "Недавние генерации: %d, одинаковых запросов отвечено из них: %d",
+		"admin_today":        "Сегодня",
+		"admin_month":        "Этот месяц",
+		"admin_shedding":     "Облегчённый режим из-за %s: новые запросы используют %s и меньше размышляют",
+		"admin_budget_stop":  "Дневной бюджет $%.2f исчерпан: новые запросы отклоняются до %s",
+		"admin_budget_lite":  "Дневной бюджет $%.2f исчерпан: новые запросы используют %s и меньше размышляют до %s",
+		"admin_na":           "н/д",
+		"admin_art_stats":    "Сохранённые ответы: %d (%s), попаданий кнопок %d / промахов %d (%s попаданий), вытеснено %d",
+		"admin_art_usage":    "Использование: /admin artifacts [<chat id> | purge <chat id>]",
+		"admin_art_purged":   "Удалено %d сохранённых ответов чата %d.",
+		"admin_art_none":     "Нет сохранённых ответов для чата %d.",
+		"admin_art_list":     "Сохранённые ответы чата %d: %d",
+		"admin_art_newest":   "(показаны последние %d)",
+		"admin_art_item":     "#%s, %s назад, %s: %s",
+		"calls_unlimited":    "Вызовы модели: без ограничений",
+		"calls_stats":        "Вызовы модели: выполняется %d из %d, ожидают %d",
+		"reload_actions_err": "Действия вебхуков не перезагружены: %s",
+		"reload_actions":     "Перезагружено действий вебхуков: %d.",
+		"reload_state_err":   "Список чатов и блокировок не перезагружен: %s",
+		"reload_state":       "Перезагружено известных чатов: %d, заблокированных пользователей: %d.",
+		"reload_cfg_err":     "Конфигурация не перезагружена: %s",
+		"reload_cfg_same":    "Конфигурация перезагружена, настройки не изменились.",
+		"reload_cfg":         "Конфигурация перезагружена:",
+		"diff_counts":        "По сравнению с предыдущим ответом: изменено предложений: %d, добавлено: %d, удалено: %d.",
+		"speech_hint":        "Речь во вложенном аудио может быть на любом из этих языков, в том числе вперемешку в одной записи: %s. Расшифровывай и понимай каждую часть на том языке, на котором она действительно звучит, а не предполагай один язык для всей записи.",
+		"speech_bad_name":    "%q — не название языка",
+		"speech_too_many":    "можно указать не более %d языков",
+	},
+	"uk": {
+		"welcome":            "Привіт, я Eteon. Надішліть запитання, посилання або медіафайл, і я відповім стисло.",
+		"thinking_current":   "Поточний бюджет міркувань: %s",
+		"thinking_switched":  "Бюджет міркувань змінено на %s",
+		"sampling_temp":      "Темп.",
+		"sampling_top_p":     "Top-p",
+		"sampling_tokens":    "Токени",
+		"sampling_reset":     "Скинути вибірку",
+		"tool_search":        "Вебпошук",
+		"tool_links":         "Посилання",
+		"tool_code":          "Код",
+		"tool_on":            "увімк",
+		"tool_off":           "вимк",
+		"tools_current":      "Інструменти: %s. Вимкніть усі через /settings tools off, щоб відповідала лише модель.",
+		"tools_usage":        "Використання: /settings tools on|off або /settings google_search, url_context чи code_execution з on або off.",
+		"json_usage":         "Використання: /json <JSON-схема або опис форми> вмикає режим JSON; /json off вимикає його.",
+		"json_on":            "Режим JSON увімкнено. Відповіді надходять у JSON такої форми: %s\nНадішліть /json off для звичайних відповідей.",
+		"json_off":           "Режим JSON вимкнено. Відповіді знову звичайним текстом.",
+		"json_bad_schema":    "Схоже на JSON-схему, але її не вдалося розібрати: %s",
+		"json_document":      "JSON — у документі вище.",
+		"sampling_default":   "за замовчуванням",
+		"sampling_current":   "Температура: %s, top-p: %s, максимум токенів відповіді: %s",
+		"sampling_usage":     "Використання: /settings temperature <0..2>, /settings top_p <0..1> або /settings max_tokens <%d..%d>, також зі значенням default; /settings reset скидає все.",
+		"topic_not_forum":    "/topic працює всередині теми групи з форумом.",
+		"topic_admins_only":  "Змінювати налаштування цієї теми можуть лише адміністратори групи.",
+		"topic_current":      "Персона теми: %s\nІнструменти теми: %s",
+		"topic_default":      "як у чаті",
+		"topic_usage":        "Використання: /topic persona <інструкції|default>, /topic tools <all|none|список із %s> або /topic reset.",
+		"topic_too_long":     "Персона задовга (%d символів). Обмеження — %d.",
+		"export_usage":       "Використання: /export [md|json|html]. За замовчуванням — Markdown.",
+		"export_empty":       "Поки немає розмови для експорту.",
+		"import_usage":       "Надішліть JSON-розшифровку з /export з підписом /import або дайте відповідь на неї командою /import. Вона замінить розмову в цьому чаті.",
+		"import_too_large":   "Розшифровка завелика. Обмеження — %d КБ.",
+		"import_invalid":     "Цей файл не є JSON-розшифровкою з /export.",
+		"import_done":        "Розмову відновлено, повідомлень: %d.",
+		"checkpoint_usage":   "Використання: /checkpoint [назва]. Назва — одне слово до 32 символів із літер, цифр, крапок, дефісів або підкреслень.",
+		"checkpoint_empty":   "Поки що немає розмови для збереження.",
+		"checkpoint_saved":   "Контрольну точку %s збережено, повідомлень: %d. Щоб повернутися до неї, використайте /rollback %s.",
+		"rollback_none":      "Контрольних точок ще немає. Збережіть одну командою /checkpoint [назва].",
+		"rollback_list":      "Контрольні точки, спершу нові (назва · збережено · повідомлень):\n%s\nЩоб повернутися до однієї, використайте /rollback <назва>.",
+		"rollback_unknown":   "Контрольної точки %s немає. Надішліть /rollback, щоб побачити список.",
+		"rollback_done":      "Повернення до контрольної точки %s, повідомлень: %d.",
+		"rollback_saved":     "Покинуту розмову збережено як %s.",
+		"chat_usage":         "Використання: /chat показує ваші розмови, /chat new <назва> починає нову, /chat switch <назва> перемикає на іншу, /chat delete <назва> видаляє. Назва — одне слово до 32 символів із літер, цифр, крапок, дефісів або підкреслень.",
+		"chat_list":          "Розмови (назва · повідомлень):\n%s\nЩоб перемкнутися, використайте /chat switch <назва>.",
+		"chat_exists":        "Розмова %s вже є. Щоб повернутися до неї, використайте /chat switch %s.",
+		"chat_too_many":      "У чаті може бути не більше %d розмов. Спершу видаліть одну командою /chat delete <назва>.",
+		"chat_started":       "Розпочато розмову %s. Попередню збережено.",
+		"chat_unknown":       "Розмови %s немає. Надішліть /chat, щоб побачити список.",
+		"chat_switched":      "Перемкнуто на %s, повідомлень: %d.",
+		"chat_delete_active": "Перемкніться на іншу розмову, перш ніж видаляти цю.",
+		"chat_deleted":       "Розмову %s видалено.",
+		"catchup_groups":     "/catchup працює в групах.",
+		"catchup_admins":     "Вмикати й вимикати зведення можуть лише адміністратори групи.",
+		"catchup_on":         "Зведення ввімкнено. Я зберігаю повідомлення за останні %d год, не більше %d, лише в пам'яті й ніколи не записую їх на диск. /catchup [години] підсумовує їх; /catchup off видаляє їх. Я бачу всі повідомлення, лише якщо для мене вимкнено режим приватності або я адміністратор групи.",
+		"catchup_off":        "Зведення вимкнено, збережені повідомлення видалено.",
+		"catchup_disabled":   "Зведення в цій групі вимкнено. Адміністратор може ввімкнути його командою /catchup on.",
+		"catchup_usage":      "Використання: /catchup [години від 1 до %d] або /catchup on|off для адміністраторів.",
+		"catchup_empty":      "За останні %d год нічого не писали.",
+		"catchup_failed":     "Не вдалося підсумувати обговорення. Спробуйте пізніше.",
+		"catchup_header":     "Що ви пропустили за останні %d год:",
+		"sendto_usage":       "Використання: /sendto <ціль> <що написати>, /sendto remove <ціль>, а в цільовому чаті — /sendto allow <назва> і /sendto revoke. Назви складаються з a-z, 0-9, - і _.",
+		"sendto_none":        "У вас немає збережених цілей. Виконайте /sendto allow <назва> в чаті, який має отримувати ваші повідомлення; у групах це має зробити адміністратор.",
+		"sendto_list":        "Ваші цілі: %s. Складіть повідомлення командою /sendto <ціль> <що написати>.",
+		"sendto_allowed":     "Цей чат збережено як ціль %s. Складайте для нього повідомлення з будь-якого чату командою /sendto %[1]s <що написати>.",
+		"sendto_admins_only": "Дозволити цьому чату отримувати складені повідомлення можуть лише адміністратори групи.",
+		"sendto_revoked":     "Видалено цілей, що вказують на цей чат: %d.",
+		"sendto_removed":     "Ціль %s видалено.",
+		"sendto_unknown":     "Цілі з назвою %s немає. /sendto покаже ваші цілі.",
+		"sendto_preview":     "Чернетка для %s. Надішліть як є, скасуйте або виконайте /sendto ще раз зі змінами:",
+		"sendto_no_draft":    "Не вдалося скласти чернетку. Спробуйте ще раз.",
+		"btn_send_draft":     "Надіслати",
+		"btn_discard_draft":  "Скасувати",
+		"sendto_sent":        "Надіслано в %s.",
+		"sendto_failed":      "Не вдалося надіслати в %s. Перевірте, що я досі учасник і можу там писати.",
+		"sendto_expired":     "Термін дії чернетки минув. Виконайте /sendto ще раз.",
+		"sendto_discarded":   "Чернетку скасовано.",
+		"sendto_not_yours":   "Надіслати або скасувати чернетку може лише її автор.",
+		"remind_usage":       "Використання: /remind in 2h30m <текст>, /remind at 18:00 <текст>, /remind tomorrow 9:00 <текст> або /remind 2025-06-01 9:00 <текст>. Почніть текст з ask:, щоб я відповів на нього як на запитання в цей час. /remind показує ваші нагадування, /remind cancel <id> скасовує одне, а /remind tz <Регіон/Місто> задає часовий пояс.",
+		"remind_none":        "У вас немає нагадувань у цьому чаті.",
+		"remind_list":        "Ваші нагадування:\n%s",
+		"remind_unknown":     "Тут немає вашого нагадування #%d.",
+		"remind_cancelled":   "Нагадування #%d скасовано.",
+		"remind_bad_tz":      "Невідомий часовий пояс. Використовуйте назву на кшталт Europe/Kyiv або Europe/Warsaw.",
+		"remind_tz":          "Часовий пояс: %s.",
+		"remind_range":       "Нагадування має бути в майбутньому й не далі ніж за рік.",
+		"remind_too_many":    "У вас уже %d нагадувань. Спершу скасуйте одне.",
+		"remind_set":         "Нагадування #%d встановлено на %s.",
+		"remind_fire":        "⏰ Нагадування: %s",
+		"digest_usage":       "Використання: /digest <daily|weekdays|weekends|mon,thu> <ГГ:ХХ> <запит>, наприклад /digest daily 8:00 коротко перекажи головну Hacker News. У цей час я відповім на запит із пошуком в інтернеті. /digest показує ваші дайджести, /digest cancel <id> скасовує один. Час вказується в часовому поясі чату з /remind tz.",
+		"digest_none":        "У вас немає дайджестів у цьому чаті.",
+		"digest_list":        "Ваші дайджести (%s):\n%s",
+		"digest_unknown":     "Тут немає вашого дайджесту #%d.",
+		"digest_cancelled":   "Дайджест #%d скасовано.",
+		"digest_too_many":    "У вас уже %d дайджестів. Спершу скасуйте один.",
+		"digest_set":         "Дайджест #%d заплановано: %s (%s). Перший надійде %s.",
+		"input_failed":       "Не вдалося обробити це повідомлення.",
+		"rate_limited":       "Ви надсилаєте повідомлення надто часто. Спробуйте знову через %d с.",
+		"file_too_large":     "Файл більший за 20 МБ — це максимум, який Telegram дозволяє завантажувати ботам. Надішліть менший або стиснений файл.",
+		"input_empty":        "Надішліть текст або підтримуваний медіафайл.",
+		"request_failed":     "Eteon не зміг виконати цей запит.",
+		"request_cancelled":  "Запит скасовано.",
+		"calc_no_code":       "Не вдалося підтвердити відповідь виконаним кодом, тому я не назву число. Спробуйте переформулювати обчислення.",
+		"blocked_reason":     "Gemini заблокував запит (%s).",
+		"blocked_hint":       "Переформулюйте його без позначеного вмісту або розбийте на менші запитання.",
+		"safety_categories":  "Позначені категорії: %s.",
+		"finish_max_tokens":  "Відповідь досягла обмеження довжини. Натисніть «Продовжити», щоб отримати решту, або дозвольте довші відповіді через /verbosity чи /settings.",
+		"finish_safety":      "Відповідь зупинено фільтрами безпеки.",
+		"finish_recitation":  "Відповідь зупинено, бо вона майже дослівно відтворювала наявний текст, наприклад джерело, захищене авторським правом. Попросіть короткий виклад або переказ.",
+		"finish_other":       "Відповідь обірвалася передчасно (%s). Спробуйте ще раз або переформулюйте запит.",
+		"btn_continue":       "Продовжити",
+		"continue_stale":     "Продовжити можна лише останню відповідь.",
+		"no_content":         "Відповідь не отримано.",
+		"fallback_notice":    "Відповіла модель %s, бо %s була недоступна.",
+		"light_notice":       "Бот зараз дуже завантажений, тому над цією відповіддю він думав менше, ніж зазвичай.",
+		"budget_notice":      "Денний ліміт використання вичерпано, тому цю відповідь дала менша модель.",
+		"budget_refused":     "Бот вичерпав свій ліміт використання на сьогодні. Спробуйте знову після %s.",
+		"budget_user_limit":  "Ви вичерпали свій ліміт використання на сьогодні. Спробуйте знову після %s.",
+		"grounding_sources":  "Джерел: %d",
+		"grounding_newest":   "найсвіжіше від %s",
+		"grounding_cited":    "підтверджено %d%% відповіді",
+		"queue_full":         "У вас уже кілька повідомлень у черзі. Дочекайтеся відповідей, перш ніж надсилати нові.",
+		"queue_draining":     "Бот незабаром перезапуститься. Надішліть повідомлення ще раз за хвилину.",
+		"queued_one":         "У черзі, обробляю ваше попереднє повідомлення.",
+		"queued_many":        "У черзі після ваших попередніх повідомлень. Незабаром дійду й до цього.",
+		"calls_waiting":      "Зараз багато запитів: ви %d-й у черзі, чекати приблизно %s.",
+		"calls_saturated":    "Eteon зараз перевантажений. Спробуйте ще раз за хвилину.",
+		"btn_cancel_request": "Скасувати поточний запит",
+		"cancel_started":     "Скасовую поточний запит.",
+		"cancel_nothing":     "Зараз немає запитів, що виконуються.",
+		"cancel_not_yours":   "Скасувати поточний запит може лише його автор або адміністратор чату.",
+		"btn_thoughts":       "Показати міркування",
+		"btn_sources":        "Показати джерела",
+		"btn_code":           "Показати код",
+		"btn_obsidian":       "Експорт у нотатку Obsidian",
+		"btn_notion":         "Зберегти в Notion",
+		"btn_review_patch":   "Експорт рев’ю як коментарі в .patch",
+		"btn_regenerate":     "Згенерувати знову",
+		"btn_compare":        "Порівняти з попередньою",
+		"btn_bookmark":       "⭐ У закладки",
+		"bookmark_expired":   "Ця відповідь застара, щоб додати її в закладки.",
+		"bookmark_saved":     "Додано в закладки як #%d. Див. /bookmarks.",
+		"bookmark_exists":    "Уже в закладках як #%d.",
+		"bookmark_too_many":  "У вас %d закладок. Спершу видаліть зайві командою /bookmarks delete <id>.",
+		"bookmark_failed":    "Не вдалося зберегти закладку. Спробуйте пізніше.",
+		"bookmarks_usage":    "Використання: /bookmarks показує ваші закладки, /bookmarks <слова> шукає в них, /bookmarks <id> відкриває одну, /bookmarks delete <id> видаляє.",
+		"bookmarks_none":     "Закладок ще немає. Натисніть ⭐ У закладки під відповіддю, щоб зберегти її.",
+		"bookmarks_no_match": "Немає закладок, що містять %q.",
+		"bookmarks_list":     "Закладки (%d):\n%s\nНадішліть /bookmarks <id>, щоб відкрити одну.",
+		"bookmark_unknown":   "У вас немає закладки #%d.",
+		"bookmark_deleted":   "Закладку #%d видалено.",
+		"feedback_expired":   "Ця відповідь надто стара, щоб її оцінити.",
+		"feedback_thanks":    "Дякую! Врахую це, щоб відповідати так, як вам зручніше. Див. /remember.",
+		"feedback_same":      "Ви вже оцінили цю відповідь.",
+		"feedback_failed":    "Не вдалося зберегти оцінку. Спробуйте пізніше.",
+		"remember_usage":     "Використання: /remember <факт> запам'ятовує щось про вас в усіх чатах, навіть після /reset (наприклад, /remember я вегетаріанець). /remember показує, що я пам'ятаю і чого навчився з ваших оцінок, /memories виводить список фактів, /forget <id> забуває один, а /remember reset забуває все, зокрема оцінки 👍/👎.",
+		"remember_show":      "Що я пам'ятаю про вас:\n%s\n\nВиведено з оцінок (%d):\n%s\n\nДодати факт: /remember <факт>, забути: /forget <id>.",
+		"remember_no_notes":  "поки немає",
+		"remember_unrated":   "поки нічого — оцініть щонайменше %d відповіді кнопками 👍 або 👎",
+		"remember_saved":     "Зрозумів, пам'ятатиму це в усіх чатах як #%d. Див. /memories.",
+		"remember_exists":    "Я вже пам'ятаю це як #%d.",
+		"remember_too_long":  "Факт має бути коротшим за %d символів.",
+		"remember_too_many":  "Я пам'ятаю про вас уже %d фактів. Спершу забудьте один через /forget <id>.",
+		"memory_unknown":     "Спогаду #%d немає.",
+		"memory_forgot":      "Спогад #%d забуто.",
+		"remember_reset":     "Ваші спогади та оцінки забуто.",
+		"remember_failed":    "Не вдалося зберегти спогади. Спробуйте пізніше.",
+		"memories_none":      "Я поки нічого про вас не пам'ятаю. Розкажіть щось через /remember <факт>.",
+		"memories_list":      "Що я пам'ятаю про вас (%d):\n%s\nЗабути: /forget <id>.",
+		"memories_cleared":   "Усі ваші спогади забуто.",
+		"forget_usage":       "Використання: /forget <id> забуває один спогад із /memories, /forget all забуває всі.",
+		"memories_auto":      "Використання: /memories auto on дозволяє мені знаходити факти про вас у нашому особистому чаті, щоб ви їх зберегли або видалили, /memories auto off вимикає це.",
+		"memories_auto_on":   "Кожні %d повідомлень я шукатиму в нашому особистому чаті факти, які варто запам'ятати, і питатиму вас, перш ніж зберегти. Вимкнути: /memories auto off.",
+		"memories_auto_off":  "Пам'ятатиму лише те, що ви скажете через /remember.",
+		"memories_pending":   "⏳ позначає факти, які я помітив у наших чатах. Збережіть або видаліть їх кнопками.",
+		"memories_extracted": "Я помітив про вас такі факти. Збережіть ті, які мені варто запам'ятати:\n%s",
+		"memory_kept":        "Спогад #%d збережено.",
+		"kb_usage":           "Використання: дайте відповідь /kb add на документ або надішліть його з підписом /kb add, щоб додати до бази знань цього чату. Питання тут отримуватимуть відповіді з неї. /kb list показує документи, /kb delete <id> видаляє один, /kb delete all видаляє всі. /kb share editor|reader запрошує інші чати вашої команди, /kb join <токен> підключає спільну базу знань, /kb leave повертає власну базу цього чату.",
+		"kb_added":           "%s додано до бази знань як #%d (%s). Питайте.",
+		"kb_passages":        "фрагментів: %d",
+		"kb_truncated":       "Документ довгий, тому проіндексовано лише його початок.",
+		"kb_list":            "База знань (документів: %d):\n%s\nВидалити: /kb delete <id>.",
+		"kb_empty":           "База знань цього чату порожня. Дайте відповідь /kb add на документ, щоб додати його.",
+		"kb_unsupported":     "Не вдається прочитати текст %s. Додавайте документи PDF, Word, Excel, EPUB або текстові файли.",
+		"kb_full":            "У базі знань не більше %d фрагментів. Спершу видаліть документ через /kb delete <id>.",
+		"kb_unknown":         "У базі знань немає документа #%d.",
+		"kb_deleted":         "Документ #%d видалено з бази знань.",
+		"kb_cleared":         "База знань тепер порожня.",
+		"kb_read_only":       "Цей чат читає спільну базу знань і не може змінювати її документи.",
+		"kb_owner_only":      "Ділитися цією базою знань може лише чат, якому вона належить.",
+		"kb_admins_only":     "Лише адміністратори групи можуть змінювати, як цей чат ділиться базами знань.",
+		"kb_share_usage":     "Використання: /kb share editor дозволяє іншим чатам додавати й видаляти документи, /kb share reader — лише ставити запитання.",
+		"kb_invite":          "Запрошення створено. Надішліть в іншому чаті:\n`/kb join %s`\nРоль: %s. Запрошення діє для будь-якої кількості чатів %d дн.",
+		"kb_join_usage":      "Використання: /kb join <токен>, токен береться з /kb share.",
+		"kb_join_invalid":    "Такого запрошення немає або воно минуло. Попросіть нове через /kb share.",
+		"kb_join_own":        "Ця база знань уже належить цьому чату.",
+		"kb_joined":          "Тепер цей чат використовує спільну базу знань, роль: %s. Власні документи відкладено до /kb leave.",
+		"kb_not_shared":      "Цей чат використовує власну базу знань.",
+		"kb_left":            "Цей чат знову використовує власну базу знань.",
+		"kb_members_none":    "Цією базою знань ніхто не користується спільно. Запросіть інші чати через /kb share editor|reader.",
+		"kb_members":         "Спільний доступ (чат · роль):\n%s\nВідкритих запрошень: %d. Закрити доступ: /kb revoke <id чату> або /kb revoke all.",
+		"kb_revoked":         "Чат %d більше не користується цією базою знань.",
+		"kb_revoked_all":     "Спільний доступ до цієї бази знань закрито, запрошення більше не діють.",
+		"kb_not_member":      "Чат %d не користується цією базою знань.",
+		"kb_role_owner":      "власник",
+		"kb_role_editor":     "редактор",
+		"kb_role_reader":     "читач",
+		"kb_failed":          "Не вдалося оновити базу знань. Спробуйте пізніше.",
+		"recall_usage":       "Використання: /recall <запит> відповідає за попередніми обмінами повідомленнями в цьому чаті, які вже не вміщуються в історію розмови. /recall on або /recall off вмикає чи вимикає їхній автоматичний пошук для звичайних повідомлень, а /recall clear видаляє їх. В архіві цього чату обмінів: %d.",
+		"recall_auto_on":     "Попередні обміни, пов'язані з повідомленням, підтягуються автоматично.",
+		"recall_auto_off":    "Попередні обміни шукаються лише через /recall <запит>.",
+		"recall_cleared":     "Архів попередніх обмінів тепер порожній.",
+		"recall_empty":       "З історії цього чату поки нічого не випало, тож згадувати нічого.",
+		"recall_failed":      "Не вдалося оновити архів попередніх обмінів. Спробуйте пізніше.",
+		"privacy_usage":      "/privacy on відповідає на кожне повідомлення окремо: розмова не зберігається, відповіді не мають кнопок, бо їхній вміст не зберігається, а до журналів не потрапляє те, що ви надсилаєте. Під час увімкнення історія чату видаляється. /privacy off знову зберігає розмови. Налаштування, закладки, спогади й база знань залишаються в будь-якому разі.",
+		"privacy_state_on":   "Режим приватності в цьому чаті увімкнено.",
+		"privacy_state_off":  "Режим приватності в цьому чаті вимкнено.",
+		"privacy_on":         "Режим приватності увімкнено. Історію чату видалено, і тепер кожне повідомлення отримує окрему відповідь і не зберігається.",
+		"privacy_off":        "Режим приватності вимкнено. Нові повідомлення знову зберігаються як розмова.",
+		"privacy_admins":     "Перемикати режим приватності можуть лише адміністратори групи.",
+		"privacy_failed":     "Не вдалося перемкнути режим приватності. Спробуйте пізніше.",
+		"catchup_private":    "Підсумок зберігає повідомлення групи, тому його не можна увімкнути в режимі приватності.",
+		"ephemeral_usage":    "/ephemeral <час> видаляє мої повідомлення в цьому чаті через вказаний час, наприклад 10m або 2h, не більше %s. /ephemeral <час> prompts видаляє й повідомлення, адресовані мені; у групах для цього мені потрібне право видаляти повідомлення. /ephemeral off знову зберігає повідомлення.",
+		"ephemeral_is_off":   "Тимчасовий режим у цьому чаті вимкнено.",
+		"ephemeral_on":       "Тимчасовий режим увімкнено: я видаляю свої повідомлення через %s після надсилання.",
+		"ephemeral_prompts":  "Тимчасовий режим увімкнено: я видаляю свої повідомлення та повідомлення, адресовані мені, через %s після надсилання.",
+		"ephemeral_off":      "Тимчасовий режим вимкнено. Уже надіслані повідомлення все одно буде видалено вчасно.",
+		"ephemeral_admins":   "Лише адміністратори групи можуть перемикати тимчасовий режим.",
+		"ephemeral_failed":   "Не вдалося перемкнути тимчасовий режим. Спробуйте пізніше.",
+		"replyto_usage":      "/replyto on робить мої відповіді відповідями на повідомлення з питанням, щоб питання й відповіді не губилися в жвавих чатах. /replyto off надсилає їх окремо. /replyto auto повертає типове налаштування: відповіді в групах, звичайні повідомлення в особистих чатах.",
+		"replyto_state_on":   "Мої відповіді в цьому чаті відповідають на повідомлення з питанням.",
+		"replyto_state_off":  "Мої відповіді в цьому чаті надсилаються окремо.",
+		"replyto_on":         "Тепер мої відповіді відповідають на повідомлення з питанням.",
+		"replyto_off":        "Тепер мої відповіді надсилаються окремо.",
+		"replyto_auto_on":    "Повернуто типове налаштування: тут мої відповіді відповідають на повідомлення з питанням.",
+		"replyto_auto_off":   "Повернуто типове налаштування: тут мої відповіді надсилаються окремо.",
+		"replyto_admins":     "Лише адміністратори групи можуть змінювати, як я відповідаю.",
+		"replyto_failed":     "Не вдалося змінити, як я відповідаю. Спробуйте пізніше.",
+		"regenerate_stale":   "Знову згенерувати можна лише останню відповідь.",
+		"compare_none":       "Нова відповідь не відрізняється від попередньої.",
+		"compare_header":     "Зміни порівняно з попередньою відповіддю: %s",
+		"thoughts_working":   "Складаю підсумок міркувань...",
+		"thoughts_none":      "Підсумок міркувань недоступний.",
+		"thoughts_header":    "Хід міркувань:",
+		"btn_thoughts_full":  "Повний хід міркувань",
+		"thoughts_full":      "Повний хід міркувань",
+		"sources_none":       "Для цієї відповіді немає джерел.",
+		"sources_header":     "Джерела:",
+		"source_untitled":    "Без назви",
+		"code_none":          "Для цієї відповіді код не виконувався.",
+		"verbosity_short":    "Короткі",
+		"verbosity_normal":   "Звичайні",
+		"verbosity_long":     "Докладні",
+		"verbosity_set":      "Довжина відповідей: %s.",
+		"verbosity_current":  "Поточна довжина відповідей: %s",
+		"tone_professional":  "Діловий",
+		"tone_neutral":       "Нейтральний",
+		"tone_playful":       "Грайливий 🎉",
+		"tone_set":           "Тон: %s.",
+		"tone_current":       "Поточний тон: %s\nУ діловому тоні відповіді без емодзі та лайки, у грайливому — з емодзі.",
+		"comparedocs_usage":  "Надішліть два документи для порівняння — альбомом або по одному. Можна також надіслати альбом з підписом /compare_docs або відповісти /compare_docs на один із них.",
+		"comparedocs_next":   "Отримав %s. Тепер надішліть документ, з яким його порівняти.",
+		"comparedocs_failed": "Не вдалося порівняти ці документи.",
+		"comparedocs_title":  "Порівняння: %s і %s",
+		"comparedocs_aspect": "Аспект",
+		"comparedocs_common": "Спільне",
+		"comparedocs_newer":  "Новіший: %s",
+		"comparedocs_done":   "Повніший: %s",
+		"comparedocs_same":   "однаково",
+		"comparedocs_unsure": "не визначити",
+		"pipeline_step":      "%s: крок %d/%d, %s…",
+		"pipeline_done":      "%s: готово.",
+		"pipeline_failed":    "%s: не вдалося. Спробуйте пізніше.",
+		"pipeline_usage":     "Надішліть %s з текстом для обробки, дайте ним відповідь на повідомлення чи документ або надішліть документ із цією командою в підписі.",
+		"contract_title":     "Перевірка договору",
+		"contract_summary":   "Стислий зміст",
+		"contract_duties":    "Зобов'язання",
+		"contract_risks":     "Ризики",
+		"contract_unusual":   "Незвичні та відсутні положення",
+		"contract_caveat":    "Цю перевірку створено автоматично, вона не є юридичною консультацією.",
+		"contract_usage":     "/contract on перевіряє як договір кожен документ, надісланий без підпису: стислий зміст, зобов'язання, ризики та незвичні положення — у вигляді звіту. Дайте відповідь /contract на документ або підпишіть його /contract, щоб перевірити лише його.",
+		"contract_on":        "Перевірку договорів увімкнено. Документи без підпису перевіряються як договори.",
+		"contract_off":       "Перевірку договорів вимкнено.",
+		"transcribe_usage":   "Відповідайте /transcribe на голосове повідомлення, аудіо чи відео або надішліть його з підписом /transcribe. Додайте srt для субтитрів.",
+		"ocr_usage":          "Відповідайте /ocr на фото, зображення чи PDF-скан або надішліть його з підписом /ocr, щоб отримати текст дослівно.",
+		"ocr_failed":         "Не вдалося прочитати текст цього скану.",
+		"ocr_empty":          "У цьому скані немає тексту.",
+		"ocr_caption":        "Витягнутий текст",
+		"ocr_page":           "Сторінка %d",
+		"summarize_usage":    "Надішліть /summarize [short|medium|detailed] [quotes] з текстом або посиланням, дайте ним відповідь на повідомлення, посилання, документ чи голосове повідомлення, або надішліть документ з підписом /summarize.",
+		"summarize_failed":   "Не вдалося скласти стислий виклад.",
+		"summarize_bad_link": "Не вдалося прочитати сторінку за посиланням.",
+		"summarize_caption":  "Стислий виклад",
+		"summarize_quotes":   "Ключові цитати",
+		"translate_usage":    "Використання: /translate <мова> <текст> або відповіддю на повідомлення, документ чи голосове. Мова — код на кшталт de, назва або пара на кшталт en-de.",
+		"translate_failed":   "Не вдалося це перекласти.",
+		"translate_caption":  "Переклад: %s",
+		"search_usage":       "Використання: /search <запит>. Відповідь завжди ґрунтується на свіжому пошуку Google і містить джерела.",
+		"search_unsourced":   "Пошук не знайшов джерел для цієї відповіді, тож ставтеся до неї обережно.",
+		"transcribe_failed":  "Не вдалося розшифрувати цей запис.",
+		"transcribe_empty":   "У цьому записі я не почув мовлення.",
+		"transcribe_caption": "Повна розшифровка",
+		"disclaimer_medical": "Це загальна інформація, а не медична консультація. Обговоріть свою ситуацію з лікарем або фармацевтом.",
+		"disclaimer_finance": "Це загальна інформація, а не фінансова консультація. Важливі рішення обговоріть з консультантом.",
+		"selfcheck_on":       "Самоперевірку ввімкнено. Фактичні відповіді звірятимуться з веб-джерелами перед надсиланням.",
+		"selfcheck_off":      "Самоперевірку вимкнено.",
+		"deterministic_on":   "Детермінований режим увімкнено. Відповіді використовують температуру 0 і seed %d, тож те саме запитання в тому самому контексті дає ту саму відповідь.",
+		"deterministic_off":  "Детермінований режим вимкнено. Відповіді знову використовують стандартну вибірку моделі.",
+		"devmode_on":         "Режим розробника увімкнено. Для вставлених помилок і трасувань стека бот без запитання назве ймовірну причину, виправлення та мінімальний приклад відтворення.",
+		"devmode_off":        "Режим розробника вимкнено.",
+		"language_current":   "Повідомлення бота в цьому чаті мовою: %s.",
+		"language_auto":      "Повідомлення бота в цьому чаті відповідають мові Telegram кожного користувача.",
+		"language_set":       "Тепер повідомлення бота в цьому чаті мовою: %s.",
+		"language_usage":     "Використання: /language <код> або /language auto. Доступні: %s",
+		"language_unknown":   "Ця мова недоступна. Доступні: %s",
+		"usage_title":        "Витрати в цьому чаті",
+		"usage_today":        "Сьогодні",
+		"usage_month":        "Цього місяця",
+		"usage_none":         "%s: запитів немає",
+		"usage_line":         "%s: запитів %s, токенів %s (запит %s / відповідь %s / міркування %s), ~$%.4f",
+		"usage_estimate":     "Вартість оцінено за прейскурантними цінами.",
+		"calc_usage":         "Використання: /calc <питання з числами>",
+		"action_confirm_q":   "Виконати дію %s?",
+		"action_confirm":     "Підтвердити",
+		"action_cancel":      "Скасувати",
+		"action_not_yours":   "Підтвердити або скасувати цю дію може лише той, хто її запросив.",
+		"action_expired":     "Термін цього запиту на дію минув.",
+		"action_done":        "Дію %s виконано.",
+		"action_failed":      "Дія %s не вдалася.",
+		"action_cancelled":   "Дію скасовано.",
+		"admin_only":         "Ця команда доступна лише адміністраторам бота.",
+		"advanced_admins":    "Розширені налаштування доступні лише адміністраторам бота.",
+		"model_admins":       "Обирати модель можуть лише адміністратори бота.",
+		"calendar_off":       "Інтеграцію з календарем у цьому боті не ввімкнено.",
+		"calendar_private":   "Питайте про свій календар в особистому чаті з ботом.",
+		"calendar_status":    "Підключено до %s як %s.",
+		"calendar_none":      "Календар не підключено. Використайте /calendar connect <caldav-url> <username> <app-password> в особистому чаті.",
+		"calendar_conn_use":  "Використання: /calendar connect <caldav-url> <username> <app-password>",
+		"calendar_unsafe":    "З міркувань безпеки підключайте календарі в особистому чаті з ботом. Краще змініть цей пароль.",
+		"calendar_https":     "Адреса CalDAV має починатися з https://.",
+		"calendar_failed":    "Не вдалося підключитися до календаря. Перевірте адресу CalDAV і облікові дані.",
+		"calendar_on":        "Календар підключено. Питайте про розклад або просіть запланувати час.",
+		"calendar_removed":   "Календар відключено, облікові дані видалено.",
+		"calendar_usage":     "Використання: /calendar [status|connect|disconnect]",
+		"export_gone":        "Цю відповідь більше не можна експортувати.",
+		"notion_off":         "Інтеграцію з Notion у цьому боті не ввімкнено.",
+		"notion_status":      "Notion підключено. Відповіді зберігаються на сторінці %s.",
+		"notion_none":        "Notion не підключено. Використайте /notion connect <integration-token> <parent-page-url-or-id> в особистому чаті.",
+		"notion_conn_use":    "Використання: /notion connect <integration-token> <parent-page-url-or-id>",
+		"notion_unsafe":      "З міркувань безпеки підключайте Notion в особистому чаті з ботом. Краще замініть цей токен.",
+		"notion_bad_page":    "У цьому посиланні не знайдено ID сторінки Notion.",
+		"notion_on":          "Notion підключено. Надайте доступ до батьківської сторінки своїй інтеграції й натискайте Save to Notion під будь-якою відповіддю.",
+		"notion_removed":     "Notion відключено, токен видалено.",
+		"notion_usage":       "Використання: /notion [status|connect|disconnect]",
+		"notion_first":       "Спершу підключіть Notion командою /notion connect в особистому чаті.",
+		"notion_saved":       "Збережено в Notion: %s",
+		"notion_failed":      "Не вдалося зберегти в Notion. Перевірте, що батьківська сторінка доступна вашій інтеграції.",
+		"notion_timeout":     "Notion не відповів вчасно. Спробуйте ще раз.",
+		"prefs_off":          "Особисті налаштування в цьому боті не ввімкнено.",
+		"speech_current":     "Ваші розмовні мови: %s. Голосові повідомлення розшифровуються з цією підказкою.",
+		"speech_none":        "Розмовні мови не задано. Використайте /languages English, Ukrainian, щоб краще розшифровувати голосові повідомлення кількома мовами, або /languages reset.",
+		"speech_cleared":     "Розмовні мови скинуто.",
+		"speech_invalid":     "Не вдалося оновити розмовні мови: %s.",
+		"speech_set":         "Розмовні мови: %s.",
+		"replay_empty":       "Поки що нічого повторювати. Спершу поспілкуйтеся зі мною, потім запустіть /replay <model>.",
+		"replay_failed":      "Повтор на %s перервався після %d ходів.",
+		"replay_cancelled":   "Повтор скасовано.",
+		"replay_caption":     "Повторено ходів: %d на %s.",
+		"review_gone":        "Цей розбір більше не можна експортувати.",
+		"verify_ok":          "Перевірено за веб-джерелами.",
+		"verify_fixed":       "Виправлено після перевірки.",
+		"verify_fixed_note":  "Виправлено після перевірки: %s",
+		"verify_unsure":      "Примітка щодо достовірності: деякі твердження перевірити не вдалося.",
+		"verify_unsure_note": "Примітка щодо достовірності: %s",
+		"advanced_usage":     "Використання: /advanced [stop <seq> | <seq> ... | presence <-2..2> | frequency <-2..2> | seed <int> | <option> off | reset]",
+		"advanced_none":      "Розширених налаштувань генерації немає. Діють типові значення моделі.",
+		"advanced_stops":     "Стоп-послідовності: %s",
+		"advanced_presence":  "Штраф за присутність: %g",
+		"advanced_frequency": "Штраф за частоту: %g",
+		"advanced_seed":      "Seed: %d",
+		"advanced_stop_num":  "Вкажіть від 1 до %d стоп-послідовностей через |.",
+		"advanced_penalty":   "Штраф має бути числом від %g до %g.",
+		"advanced_seed_int":  "Seed має бути цілим числом.",
+		"repo_usage":         "Використання: /repo <https git URL> індексує публічний репозиторій, /repo drop видаляє його, /repo показує поточний.",
+		"repo_status":        "Відповідаю за %s: %d файлів у %d фрагментах, проіндексовано %s тому.",
+		"repo_none":          "У цьому чаті немає проіндексованого репозиторію.",
+		"repo_dropped":       "Індекс репозиторію видалено.",
+		"repo_failed":        "Не вдалося проіндексувати репозиторій: %s",
+		"repo_indexed":       "%s проіндексовано: %d файлів у %d фрагментах. Питайте про код, я посилатимусь на файли й рядки; /repo drop видаляє індекс.",
+		"repo_partial":       "Репозиторій великий, тому проіндексовано лише перші %d фрагментів.",
+		"thinking_low":       "Низький - 4 096 токенів",
+		"thinking_medium":    "Середній - 16 384 токени",
+		"thinking_high":      "Високий - 32 768 токенів",
+		"thinking_dynamic":   "Динамічні міркування",
+		"diff_changed":       "змінено: %s",
+		"diff_added":         "додано: %s",
+		"diff_removed":       "вилучено: %s",
+		"diff_more":          "(ще %d)",
+		"code_snippet":       "Фрагмент коду %d",
+		"code_outcome":       "Результат",
+		"code_output":        "Вивід",
+		"code_file_outcome":  "Фрагмент коду %d, результат: %s",
+		"admin_usage":        "Використання: /admin stats | broadcast <text> | ban <user id> | unban <user id> | artifacts [<chat id> | purge <chat id>] | reload | drain | resume",
+		"admin_bcast_usage":  "Використання: /admin broadcast <text>",
+		"admin_broadcasting": "Розсилка в %d чатів.",
+		"admin_bcast_done":   "Розсилку доставлено в %d з %d чатів.",
+		"admin_ban_usage":    "Використання: /admin %s <user id> або дайте відповідь на одне з повідомлень користувача.",
+		"admin_ban_admin":    "Адміністраторів не можна заблокувати.",
+		"admin_unbanned":     "Користувача %d розблоковано.",
+		"admin_banned":       "Користувача %d заблоковано. Бот ігнорує його повідомлення.",
+		"admin_drain_busy":   "Нові повідомлення відхиляються, але %d чатів ще зайняті %d повідомленнями в черзі. Виконайте /admin drain знову або /admin resume.",
+		"admin_drained":      "Усі запити завершено, нові повідомлення відхиляються. Бота можна безпечно зупинити. /admin resume знову вмикає прийом повідомлень.",
+		"admin_resumed":      "Прийом повідомлень відновлено.",
+		"admin_stats":        "Статистика бота",
+		"admin_unknown":      "невідома",
+		"admin_version":      "Версія: %s, працює %s",
+		"admin_chats":        "Відомі чати: %d (%d неактивні), активні сесії: %d",
+		"admin_busy":         "Зайняті чати: %d, повідомлень у черзі: %d",
+		"admin_banned_num":   "Заблоковані користувачі: %d",
+		"admin_generations":  "Нещодавні генерації: %d, однакових запитів відповіли з них: %d",
+		"admin_today":        "Сьогодні",
+		"admin_month":        "Цей місяць",
+		"admin_shedding":     "Полегшений режим через %s: нові запити використовують %s і менше міркують",
+		"admin_budget_stop":  "Денний бюджет $%.2f вичерпано: нові запити відхиляються до %s",
+		"admin_budget_lite":  "Денний бюджет $%.2f вичерпано: нові запити використовують %s і менше міркують до %s",
+		"admin_na":           "н/д",
+		"admin_art_stats":    "Збережені відповіді: %d (%s), влучань кнопок %d / промахів %d (%s влучань), витіснено %d",
+		"admin_art_usage":    "Використання: /admin artifacts [<chat id> | purge <chat id>]",
+		"admin_art_purged":   "Видалено %d збережених відповідей чату %d.",
+		"admin_art_none":     "Немає збережених відповідей для чату %d.",
+		"admin_art_list":     "Збережені відповіді чату %d: %d",
+		"admin_art_newest":   "(показано останні %d)",
+		"admin_art_item":     "#%s, %s тому, %s: %s",
+		"calls_unlimited":    "Виклики моделі: без обмежень",
+		"calls_stats":        "Виклики моделі: виконується %d з %d, очікують %d",
+		"reload_actions_err": "Дії вебхуків не перезавантажено: %s",
+		"reload_actions":     "Перезавантажено дій вебхуків: %d.",
+		"reload_state_err":   "Список чатів і блокувань не перезавантажено: %s",
+		"reload_state":       "Перезавантажено відомих чатів: %d, заблокованих користувачів: %d.",
+		"reload_cfg_err":     "Конфігурацію не перезавантажено: %s",
+		"reload_cfg_same":    "Конфігурацію перезавантажено, налаштування не змінилися.",
+		"reload_cfg":         "Конфігурацію перезавантажено:",
+		"diff_counts":        "Порівняно з попередньою відповіддю: змінено речень: %d, додано: %d, вилучено: %d.",
+		"speech_hint":        "Мовлення у вкладеному аудіо може бути будь-якою з цих мов, зокрема впереміш в одному записі: %s. Розшифровуй і розумій кожну частину тією мовою, якою вона справді звучить, а не припускай одну мову для всього запису.",
+		"speech_bad_name":    "%q — не назва мови",
+		"speech_too_many":    "можна вказати не більше %d мов",
+	},
+}
+
+// tr returns the message key in lang formatted with args, falling back to English.
+func tr(lang language, key string, args ...any) string {
+	msg, ok := catalogs[lang][key]
+	if !ok {
+		msg = catalogs[defaultLanguage][key]
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+// parseLanguage maps a code such as "uk" or Telegram's "pt-br" to a translated language.
+func parseLanguage(code string) (language, bool) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if base, _, ok := strings.Cut(code, "-"); ok {
+		code = base
+	}
+	lang := language(code)
+	_, ok := catalogs[lang]
+	return lang, ok
+}
+
+// availableLanguages lists the translated languages as "code (name)".
+func availableLanguages() string {
+	codes := make([]string, 0, len(languageNames))
+	for lang := range languageNames {
+		codes = append(codes, string(lang))
+	}
+	slices.Sort(codes)
+	for i, code := range codes {
+		codes[i] = fmt.Sprintf("%s (%s)", code, languageNames[language(code)])
+	}
+	return strings.Join(codes, ", ")
+}
+
+// chatLanguage picks the language of bot messages: the one chosen for the chat
+// with /language, else the Telegram language of user, else English.
+func (a *App) chatLanguage(chat *tele.Chat, user *tele.User) language {
+	if chat != nil {
+		session := a.sessions.get(chat.ID)
+		session.mu.Lock()
+		lang := session.language
+		session.mu.Unlock()
+		if lang != "" {
+			return lang
+		}
+	}
+	if user != nil {
+		if lang, ok := parseLanguage(user.LanguageCode); ok {
+			return lang
+		}
+	}
+	return defaultLanguage
+}
+
+func (a *App) handleLanguage(c tele.Context) error {
+	session := a.sessions.get(c.Chat().ID)
+	payload := strings.TrimSpace(c.Message().Payload)
+
+	var body string
+	switch {
+	case payload == "":
+		session.mu.Lock()
+		lang := session.language
+		session.mu.Unlock()
+		current := tr(a.chatLanguage(c.Chat(), c.Sender()), "language_auto")
+		if lang != "" {
+			current = tr(lang, "language_current", languageNames[lang])
+		}
+		body = current + "\n\n" + tr(a.chatLanguage(c.Chat(), c.Sender()), "language_usage", availableLanguages())
+	case strings.EqualFold(payload, "auto"):
+		session.mu.Lock()
+		session.language = ""
+		session.mu.Unlock()
+		body = tr(a.chatLanguage(c.Chat(), c.Sender()), "language_auto")
+	default:
+		lang, ok := parseLanguage(payload)
+		if !ok {
+			body = tr(a.chatLanguage(c.Chat(), c.Sender()), "language_unknown", availableLanguages())
+			break
+		}
+		session.mu.Lock()
+		session.language = lang
+		session.mu.Unlock()
+		body = tr(lang, "language_set", languageNames[lang])
+	}
+	_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), body, &tele.SendOptions{DisableWebPagePreview: true})
+	return err
+}
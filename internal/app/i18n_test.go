@@ -0,0 +1,86 @@
+package app
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+var formatVerb = regexp.MustCompile(`%[a-z]`)
+
+func TestCatalogsMatchEnglish(t *testing.T) {
+	english := catalogs[defaultLanguage]
+	for lang, catalog := range catalogs {
+		if _, ok := languageNames[lang]; !ok {
+			t.Errorf("%s: catalog has no entry in languageNames", lang)
+		}
+		for key, msg := range catalog {
+			want, ok := english[key]
+			if !ok {
+				t.Errorf("%s: key %q is not in the English catalog", lang, key)
+				continue
+			}
+			if got, want := formatVerb.FindAllString(msg, -1), formatVerb.FindAllString(want, -1); !slices.Equal(got, want) {
+				t.Errorf("%s: %q has verbs %v, English has %v", lang, key, got, want)
+			}
+		}
+		for key := range english {
+			if _, ok := catalog[key]; !ok {
+				t.Errorf("%s: missing translation for %q", lang, key)
+			}
+		}
+	}
+}
+
+func TestParseLanguage(t *testing.T) {
+	tests := []struct {
+		in   string
+		want language
+		ok   bool
+	}{
+		{"uk", "uk", true},
+		{" DE ", "de", true},
+		{"es-419", "es", true},
+		{"pt-br", "pt", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := parseLanguage(tt.in)
+		if ok != tt.ok || (ok && got != tt.want) {
+			t.Errorf("parseLanguage(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+	if got := tr("de", "no_such_key"); got != "" {
+		t.Errorf("tr of unknown key = %q", got)
+	}
+}
+
+func TestCommandsAnswerInTheUsersLanguage(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	tests := []struct {
+		command string
+		handler tele.HandlerFunc
+		want    string
+	}{
+		{"/usage", app.handleUsage, "Heute: keine Anfragen"},
+		{"/calc", app.handleCalc, "Verwendung: /calc"},
+		{"/calendar", app.handleCalendar, "Kalenderanbindung"},
+		{"/notion", app.handleNotion, "Notion-Anbindung"},
+		{"/languages", app.handleLanguages, "Persönliche Einstellungen"},
+		{"/admin", app.handleAdmin, "Administratoren des Bots"},
+	}
+	for _, tt := range tests {
+		msg := testMessage(42, tt.command)
+		msg.Sender.LanguageCode = "de"
+		if err := tt.handler(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("%s: %v", tt.command, err)
+		}
+		texts := apis.sentTexts()
+		if got := strings.ReplaceAll(texts[len(texts)-1], "\\", ""); !strings.Contains(got, tt.want) {
+			t.Errorf("%s answered %q, want German", tt.command, got)
+		}
+	}
+}
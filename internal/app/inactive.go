@@ -0,0 +1,58 @@
+package app
+
+import (
+	"log/slog"
+	"time"
+)
+
+// defaultInactiveGrace is how long the bot keeps the data of a chat that
+// blocked or removed it, in case the chat comes back.
+const defaultInactiveGrace = 30 * 24 * time.Hour
+
+// purgeInactiveChats deletes the data of the chats that have been inactive for
+// longer than the grace period.
+func (a *App) purgeInactiveChats(now time.Time) {
+	if a.inactiveGrace < 0 {
+		return
+	}
+	for _, chatID := range a.operator.inactiveBefore(now.Add(-a.inactiveGrace)) {
+		a.purgeChat(chatID)
+	}
+}
+
+// purgeChat deletes everything the bot stores about a chat. For a private chat,
+// whose ID is the user's, that includes the user's preferences, send targets,
+// bookmarks, style profile and memories.
+func (a *App) purgeChat(chatID int64) {
+	warn := func(what string, err error) {
+		if err != nil {
+			slog.Warn("purge chat failed", "chat_id", chatID, "store", what, "err", err)
+		}
+	}
+	a.sessions.remove(chatID)
+	a.catchup.disable(chatID)
+	a.repos.set(chatID, nil)
+	warn("knowledge base", a.kb.clear(chatID))
+	warn("recall", a.recall.purge(chatID))
+	warn("privacy", a.privacy.set(chatID, false))
+	warn("ephemeral", a.ephemeral.purgeChat(chatID))
+	warn("reply mode", a.replyTo.set(chatID, nil))
+	replies := a.artifacts.purgeChat(chatID)
+	_, err := a.sampling.update(chatID, func(s *samplingSettings) { *s = samplingSettings{} })
+	warn("sampling", err)
+	warn("topics", a.topics.purgeChat(chatID))
+	_, err = a.sendTargets.revoke(chatID)
+	warn("send targets", err)
+	warn("reminders", a.reminders.purgeChat(chatID))
+	if chatID > 0 {
+		warn("send targets", a.sendTargets.forgetUser(chatID))
+		warn("bookmarks", a.bookmarks.forgetUser(chatID))
+		warn("styles", a.styles.forgetUser(chatID))
+		warn("memories", a.memories.forgetUser(chatID))
+		if a.prefs != nil {
+			warn("preferences", a.prefs.purgeUser(chatID))
+		}
+	}
+	warn("admin state", a.operator.forgetChat(chatID))
+	slog.Info("purged inactive chat", "chat_id", chatID, "stored_replies", replies)
+}
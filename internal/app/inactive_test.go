@@ -0,0 +1,58 @@
+package app
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestInactiveChatIsPurgedAfterGrace(t *testing.T) {
+	app, apis := newTestApp(t, Config{InactiveChatGrace: time.Hour})
+	apis.telegramError = func(method string, body []byte) (int, string) {
+		if method == "sendMessage" && strings.Contains(string(body), `"chat_id":"42"`) {
+			return 403, "Forbidden: bot was blocked by the user"
+		}
+		return 0, ""
+	}
+	for _, id := range []int64{42, 43} {
+		if err := app.operator.seeChat(id); err != nil {
+			t.Fatalf("seeChat: %v", err)
+		}
+		if _, err := app.reminders.add(reminder{ChatID: id, UserID: id, Due: time.Now().Add(time.Hour), Text: "stretch"}); err != nil {
+			t.Fatalf("add reminder: %v", err)
+		}
+		if _, err := app.sampling.update(id, func(s *samplingSettings) { s.Temperature = genai.Ptr[float32](0.2) }); err != nil {
+			t.Fatalf("update sampling: %v", err)
+		}
+		app.sessions.get(id)
+	}
+
+	app.sendWithFallback(&tele.Chat{ID: 42}, "Hello", nil)
+	if !app.operator.isInactive(42) {
+		t.Fatal("blocked chat not marked inactive")
+	}
+	app.purgeInactiveChats(time.Now())
+	if len(app.reminders.pending(42, 42, false)) != 1 {
+		t.Fatal("chat purged before its grace period ended")
+	}
+
+	app.purgeInactiveChats(time.Now().Add(2 * time.Hour))
+	if n := len(app.reminders.pending(42, 42, false)); n != 0 {
+		t.Errorf("%d reminders left for the purged chat", n)
+	}
+	if !app.sampling.get(42).isZero() {
+		t.Error("sampling settings left for the purged chat")
+	}
+	if got := app.operator.knownChats(); len(got) != 1 || got[0] != 43 {
+		t.Errorf("known chats after purge = %v, want [43]", got)
+	}
+	if app.operator.isInactive(42) || app.sessions.count() != 1 {
+		t.Errorf("purged chat still tracked: inactive=%v sessions=%d", app.operator.isInactive(42), app.sessions.count())
+	}
+	if len(app.reminders.pending(43, 43, false)) != 1 || app.sampling.get(43).isZero() {
+		t.Error("purge touched an active chat")
+	}
+}
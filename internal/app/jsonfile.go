@@ -0,0 +1,51 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// The small stores in the data directory keep their state in JSON files
+// that are read once on open and rewritten on every change.
+
+// readJSONFile decodes the file at path into v. A missing file leaves v as it
+// is, so a store starts out empty.
+func readJSONFile(path string, v any) error {
+	raw, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil
+	case err != nil:
+		return err
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("decode %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeJSONFile replaces the file at path with v encoded as JSON.
+func writeJSONFile(path string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, raw)
+}
+
+// writeFileAtomic replaces the file at path with data, readable by the bot
+// only. It writes a temporary file and renames it over the old one, so a
+// crash leaves either the old or the new contents.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
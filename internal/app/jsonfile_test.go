@@ -0,0 +1,37 @@
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestJSONFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "store.json")
+	got := map[int64]string{7: "kept"}
+	if err := readJSONFile(path, &got); err != nil || len(got) != 1 {
+		t.Fatalf("missing file: %v, %v; want the value unchanged", got, err)
+	}
+
+	if err := writeJSONFile(path, map[int64]string{1: "one", 2: "two"}); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	var back map[int64]string
+	if err := readJSONFile(path, &back); err != nil || back[1] != "one" || back[2] != "two" {
+		t.Errorf("read back %v, %v", back, err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("file mode = %v, %v; want 0600", info.Mode(), err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte("{broken"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := readJSONFile(path, &back); err == nil || !strings.Contains(err.Error(), "decode "+path) {
+		t.Errorf("corrupt file err = %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package app
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const jsonMIMEType = "application/json"
+
+// handleJSON turns JSON mode on for the chat with a JSON schema or a plain
+// description of the shape replies should have, or off with "off". Replies
+// are then machine-parseable JSON for piping into other tools.
+func (a *App) handleJSON(c tele.Context) error {
+	msg := c.Message()
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	payload := strings.TrimSpace(msg.Payload)
+	session := a.sessionOf(msg)
+	body := tr(lang, "json_usage")
+	switch {
+	case payload == "":
+		session.mu.Lock()
+		mode := session.jsonMode
+		session.mu.Unlock()
+		if mode != "" {
+			body = tr(lang, "json_on", mode)
+		}
+	case strings.EqualFold(payload, "off"):
+		session.mu.Lock()
+		session.jsonMode = ""
+		session.mu.Unlock()
+		body = tr(lang, "json_off")
+	default:
+		if looksLikeSchema(payload) {
+			var schema map[string]any
+			if err := json.Unmarshal([]byte(payload), &schema); err != nil {
+				body = tr(lang, "json_bad_schema", err)
+				break
+			}
+		}
+		session.mu.Lock()
+		session.jsonMode = payload
+		session.mu.Unlock()
+		body = tr(lang, "json_on", payload)
+	}
+	_, err := a.sendWithFallback(c.Chat(), body, &tele.SendOptions{ThreadID: messageTopic(msg), DisableWebPagePreview: true})
+	return err
+}
+
+func looksLikeSchema(mode string) bool {
+	return strings.HasPrefix(strings.TrimSpace(mode), "{")
+}
+
+// applyJSONMode asks for a JSON reply: following mode as the response JSON
+// schema when it is one, shaped as it describes otherwise. Gemini rejects
+// tools together with a JSON response, so the turn has none.
+func applyJSONMode(cfg *genai.GenerateContentConfig, mode string) {
+	cfg.ResponseMIMEType = jsonMIMEType
+	cfg.Tools = nil
+	var schema map[string]any
+	if looksLikeSchema(mode) && json.Unmarshal([]byte(mode), &schema) == nil {
+		cfg.ResponseJsonSchema = schema
+		return
+	}
+	cfg.SystemInstruction = appendInstruction(cfg.SystemInstruction,
+		"Reply with JSON only, shaped as follows: "+mode)
+}
+
+// jsonReply turns a JSON answer into the text of the reply: an indented code
+// block, or a note pointing to the reply.json document sent to chat when the
+// block would not fit in a message.
+func (a *App) jsonReply(chat *tele.Chat, thread int, lang language, answer string) (string, error) {
+	answer = strings.TrimSpace(answer)
+	var indented bytes.Buffer
+	if json.Indent(&indented, []byte(answer), "", "  ") == nil {
+		answer = indented.String()
+	}
+	if len(answer) <= longMessagePart {
+		return "```json\n" + answer + "\n```", nil
+	}
+	if err := a.sendTextDocument(chat, "reply.json", jsonMIMEType, answer+"\n", "", &tele.SendOptions{ThreadID: thread}); err != nil {
+		return "", err
+	}
+	return tr(lang, "json_document"), nil
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestJSONMode(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	jsonCommand := func(payload string) {
+		t.Helper()
+		msg := testMessage(42, "/json "+payload)
+		msg.Payload = payload
+		if err := app.handleJSON(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("handleJSON(%q): %v", payload, err)
+		}
+	}
+
+	jsonCommand(`{"type": "object", "properties": {"city": {"type": "string"}}`)
+	if texts := apis.sentTexts(); !strings.Contains(texts[len(texts)-1], "does not parse") {
+		t.Fatalf("broken schema answered %q", texts[len(texts)-1])
+	}
+	jsonCommand(`{"type": "object", "properties": {"city": {"type": "string"}}}`)
+
+	apis.reply = `{"city":"Paris"}`
+	if err := app.processMessage(context.Background(), testMessage(42, "Capital of France?"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	calls := apis.callsTo(geminiHost, "gemini-2.5-pro:generateContent")
+	body := string(calls[len(calls)-1].body)
+	if !strings.Contains(body, `"responseMimeType":"application/json"`) || !strings.Contains(body, `"responseJsonSchema":{"properties"`) || strings.Contains(body, `"tools"`) {
+		t.Errorf("request is not a JSON request without tools: %s", body)
+	}
+	texts := apis.sentTexts()
+	if reply := texts[len(texts)-1]; !strings.Contains(reply, "```json\n{\n  \"city\": \"Paris\"\n}\n```") {
+		t.Errorf("reply = %q, want an indented JSON block", reply)
+	}
+
+	apis.reply = `{"city":"` + strings.Repeat("x", longMessagePart) + `"}`
+	if err := app.processMessage(context.Background(), testMessage(42, "A long one"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	docs := apis.callsTo(telegramHost, "sendDocument")
+	if len(docs) != 1 || !strings.Contains(string(docs[0].body), `filename="reply.json"`) {
+		t.Fatalf("sent %d documents, want reply.json", len(docs))
+	}
+
+	jsonCommand("off")
+	if mode := app.sessions.get(42).prefs().jsonMode; mode != "" {
+		t.Errorf("JSON mode %q after /json off", mode)
+	}
+}
+
+func TestJSONModeDescription(t *testing.T) {
+	app, _ := newTestApp(t, Config{})
+	session := app.sessions.get(42)
+	session.jsonMode = "a list of cities with their population"
+	cfg := app.buildGenerateConfig(42, session.prefs())
+	if cfg.ResponseMIMEType != jsonMIMEType || cfg.ResponseJsonSchema != nil || cfg.Tools != nil {
+		t.Errorf("config = %q, schema %v, tools %v", cfg.ResponseMIMEType, cfg.ResponseJsonSchema, cfg.Tools)
+	}
+	last := cfg.SystemInstruction.Parts[len(cfg.SystemInstruction.Parts)-1].Text
+	if !strings.Contains(last, "a list of cities with their population") {
+		t.Errorf("instruction misses the shape: %q", last)
+	}
+}
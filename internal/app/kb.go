@@ -0,0 +1,867 @@
+package app
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	kbCommand = "/kb"
+	// kbChunkRunes is the size of the passages a document is split into.
+	kbChunkRunes = 1500
+	// kbMaxChunks bounds the passages of a chat's knowledge base.
+	kbMaxChunks    = 3000
+	kbIndexTimeout = 5 * time.Minute
+	// kbTopChunks is how many passages each question is answered from.
+	kbTopChunks = 6
+	// kbInviteTTL is how long a /kb share invite lets chats join.
+	kbInviteTTL = 7 * 24 * time.Hour
+	// kbMaxInvites bounds the open invites of a knowledge base.
+	kbMaxInvites = 20
+)
+
+// kbRole is what a chat may do with a knowledge base: the owner shares it,
+// editors add and remove documents, readers only ask questions.
+type kbRole string
+
+const (
+	kbOwner  kbRole = "owner"
+	kbEditor kbRole = "editor"
+	kbReader kbRole = "reader"
+)
+
+func (r kbRole) canEdit() bool {
+	return r == kbOwner || r == kbEditor
+}
+
+// kbTextExtensions are the plain-text documents /kb add reads as they are;
+// PDF, DOCX, XLSX and EPUB go through the text extractors.
+var kbTextExtensions = map[string]bool{
+	".txt": true, ".md": true, ".markdown": true, ".csv": true, ".tsv": true, ".json": true,
+	".yaml": true, ".yml": true, ".xml": true, ".html": true, ".htm": true, ".rst": true, ".org": true,
+}
+
+// kbDocument is a document indexed into a chat's knowledge base.
+type kbDocument struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	Added     time.Time `json:"added"`
+	Chunks    int       `json:"chunks"`
+	Truncated bool      `json:"truncated,omitempty"`
+}
+
+// kbChunk is a passage of a document with its embedding.
+type kbChunk struct {
+	Doc    int64     `json:"doc"`
+	Text   string    `json:"text"`
+	Vector []float32 `json:"vector"`
+}
+
+// knowledgeBase holds the documents a chat indexed with /kb add. The owner
+// of a shared one also keeps the chats it is shared with and the open
+// invites; a chat that joined it keeps the owner in SharedFrom.
+type knowledgeBase struct {
+	NextID     int64               `json:"next_id"`
+	Docs       []kbDocument        `json:"docs"`
+	Chunks     []kbChunk           `json:"chunks"`
+	Members    map[int64]kbRole    `json:"members,omitempty"`
+	Invites    map[string]kbInvite `json:"invites,omitempty"`
+	SharedFrom int64               `json:"shared_from,omitempty"`
+}
+
+// kbInvite lets any chat holding its token join a knowledge base with Role
+// until it expires.
+type kbInvite struct {
+	Role    kbRole    `json:"role"`
+	Expires time.Time `json:"expires"`
+}
+
+// unused reports whether the knowledge base holds nothing worth a file.
+func (kb *knowledgeBase) unused() bool {
+	return len(kb.Docs) == 0 && len(kb.Members) == 0 && len(kb.Invites) == 0 && kb.SharedFrom == 0
+}
+
+func (kb *knowledgeBase) document(id int64) (kbDocument, bool) {
+	i := slices.IndexFunc(kb.Docs, func(d kbDocument) bool { return d.ID == id })
+	if i < 0 {
+		return kbDocument{}, false
+	}
+	return kb.Docs[i], true
+}
+
+// kbStore keeps the knowledge base of each chat in a JSON file of its own
+// under dir, loaded on first use. Every change rewrites the file atomically.
+type kbStore struct {
+	mu    sync.Mutex
+	dir   string
+	chats map[int64]*knowledgeBase
+}
+
+func newKBStore(dir string) *kbStore {
+	return &kbStore{dir: dir, chats: make(map[int64]*knowledgeBase)}
+}
+
+func (s *kbStore) path(chatID int64) string {
+	return filepath.Join(s.dir, strconv.FormatInt(chatID, 10)+".json")
+}
+
+// load returns the knowledge base of chatID; the caller holds s.mu.
+func (s *kbStore) load(chatID int64) (*knowledgeBase, error) {
+	if kb, ok := s.chats[chatID]; ok {
+		return kb, nil
+	}
+	kb := &knowledgeBase{}
+	if err := readJSONFile(s.path(chatID), kb); err != nil {
+		return nil, err
+	}
+	s.chats[chatID] = kb
+	return kb, nil
+}
+
+// save writes the knowledge base of chatID; the caller holds s.mu.
+func (s *kbStore) save(chatID int64, kb *knowledgeBase) error {
+	if kb.unused() {
+		if err := os.Remove(s.path(chatID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	return writeJSONFile(s.path(chatID), kb)
+}
+
+// known reports whether chatID has a knowledge base, without reading it;
+// the caller holds s.mu.
+func (s *kbStore) known(chatID int64) bool {
+	if _, ok := s.chats[chatID]; ok {
+		return true
+	}
+	_, err := os.Stat(s.path(chatID))
+	return err == nil
+}
+
+// access returns the chat whose knowledge base chatID uses, chatID itself
+// unless it joined a shared one, and the role chatID has there. A chat the
+// owner removed falls back to its own.
+func (s *kbStore) access(chatID int64) (int64, kbRole, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.known(chatID) {
+		return chatID, kbOwner, nil
+	}
+	kb, err := s.load(chatID)
+	if err != nil || kb.SharedFrom == 0 {
+		return chatID, kbOwner, err
+	}
+	owner, err := s.load(kb.SharedFrom)
+	if err != nil {
+		return chatID, kbOwner, err
+	}
+	if role, ok := owner.Members[chatID]; ok {
+		return kb.SharedFrom, role, nil
+	}
+	return chatID, kbOwner, nil
+}
+
+var (
+	errKBFull      = errors.New("knowledge base is full")
+	errKBInvite    = errors.New("unknown or expired knowledge base invite")
+	errKBOwnInvite = errors.New("invite to the chat's own knowledge base")
+)
+
+// add stores doc with its passages and returns it with its ID.
+func (s *kbStore) add(chatID int64, doc kbDocument, chunks []kbChunk) (kbDocument, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kb, err := s.load(chatID)
+	if err != nil {
+		return doc, err
+	}
+	if len(kb.Chunks)+len(chunks) > kbMaxChunks {
+		return doc, errKBFull
+	}
+	kb.NextID++
+	doc.ID = kb.NextID
+	doc.Chunks = len(chunks)
+	for i := range chunks {
+		chunks[i].Doc = doc.ID
+	}
+	kb.Docs = append(kb.Docs, doc)
+	kb.Chunks = append(kb.Chunks, chunks...)
+	return doc, s.save(chatID, kb)
+}
+
+// documents returns the documents of chatID, oldest first.
+func (s *kbStore) documents(chatID int64) ([]kbDocument, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kb, err := s.load(chatID)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Clone(kb.Docs), nil
+}
+
+func (s *kbStore) remove(chatID, id int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kb, err := s.load(chatID)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := kb.document(id); !ok {
+		return false, nil
+	}
+	kb.Docs = slices.DeleteFunc(kb.Docs, func(d kbDocument) bool { return d.ID == id })
+	kb.Chunks = slices.DeleteFunc(kb.Chunks, func(c kbChunk) bool { return c.Doc == id })
+	return true, s.save(chatID, kb)
+}
+
+// clearDocuments removes the documents of chatID and keeps how the
+// knowledge base is shared.
+func (s *kbStore) clearDocuments(chatID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kb, err := s.load(chatID)
+	if err != nil {
+		return err
+	}
+	kb.Docs, kb.Chunks = nil, nil
+	return s.save(chatID, kb)
+}
+
+// clear removes the knowledge base of chatID, leaving the one it shared.
+func (s *kbStore) clear(chatID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.known(chatID) {
+		kb, err := s.load(chatID)
+		if err != nil {
+			return err
+		}
+		if _, err := s.detach(chatID, kb); err != nil {
+			return err
+		}
+	}
+	delete(s.chats, chatID)
+	if err := os.Remove(s.path(chatID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// migrate moves the knowledge base of chat from to chat to, along with the
+// places other chats refer to it. Open invites name the old chat and are
+// dropped.
+func (s *kbStore) migrate(from, to int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kb, err := s.load(from)
+	if err != nil || kb.unused() {
+		return err
+	}
+	kb.Invites = nil
+	delete(s.chats, from)
+	s.chats[to] = kb
+	if err := s.save(to, kb); err != nil {
+		return err
+	}
+	if err := os.Remove(s.path(from)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if kb.SharedFrom != 0 {
+		owner, err := s.load(kb.SharedFrom)
+		if err != nil {
+			return err
+		}
+		if role, ok := owner.Members[from]; ok {
+			delete(owner.Members, from)
+			owner.Members[to] = role
+			if err := s.save(kb.SharedFrom, owner); err != nil {
+				return err
+			}
+		}
+	}
+	for member := range kb.Members {
+		joined, err := s.load(member)
+		if err != nil {
+			return err
+		}
+		if joined.SharedFrom == from {
+			joined.SharedFrom = to
+			if err := s.save(member, joined); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// invite opens the knowledge base of chatID to chats that send the returned
+// token, with role. The token starts with the owner's chat ID.
+func (s *kbStore) invite(chatID int64, role kbRole, now time.Time) (string, error) {
+	random := make([]byte, 9)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	token := strconv.FormatInt(chatID, 36) + "_" + hex.EncodeToString(random)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kb, err := s.load(chatID)
+	if err != nil {
+		return "", err
+	}
+	maps.DeleteFunc(kb.Invites, func(_ string, inv kbInvite) bool { return now.After(inv.Expires) })
+	if len(kb.Invites) >= kbMaxInvites {
+		oldest := slices.MinFunc(slices.Collect(maps.Keys(kb.Invites)), func(x, y string) int {
+			return kb.Invites[x].Expires.Compare(kb.Invites[y].Expires)
+		})
+		delete(kb.Invites, oldest)
+	}
+	if kb.Invites == nil {
+		kb.Invites = make(map[string]kbInvite)
+	}
+	kb.Invites[token] = kbInvite{Role: role, Expires: now.Add(kbInviteTTL)}
+	return token, s.save(chatID, kb)
+}
+
+// join makes chatID use the knowledge base token opens, leaving any other
+// it used, and returns the role it got.
+func (s *kbStore) join(chatID int64, token string, now time.Time) (kbRole, error) {
+	prefix, _, _ := strings.Cut(token, "_")
+	ownerID, err := strconv.ParseInt(prefix, 36, 64)
+	if err != nil {
+		return "", errKBInvite
+	}
+	if ownerID == chatID {
+		return "", errKBOwnInvite
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.known(ownerID) {
+		return "", errKBInvite
+	}
+	owner, err := s.load(ownerID)
+	if err != nil {
+		return "", err
+	}
+	invite, ok := owner.Invites[token]
+	if !ok || now.After(invite.Expires) {
+		return "", errKBInvite
+	}
+	kb, err := s.load(chatID)
+	if err != nil {
+		return "", err
+	}
+	if _, err := s.detach(chatID, kb); err != nil {
+		return "", err
+	}
+	if owner.Members == nil {
+		owner.Members = make(map[int64]kbRole)
+	}
+	owner.Members[chatID] = invite.Role
+	if err := s.save(ownerID, owner); err != nil {
+		return "", err
+	}
+	kb.SharedFrom = ownerID
+	return invite.Role, s.save(chatID, kb)
+}
+
+// leave makes chatID use its own knowledge base again. It reports false when
+// chatID did not use a shared one.
+func (s *kbStore) leave(chatID int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.known(chatID) {
+		return false, nil
+	}
+	kb, err := s.load(chatID)
+	if err != nil || kb.SharedFrom == 0 {
+		return false, err
+	}
+	left, err := s.detach(chatID, kb)
+	if err != nil {
+		return false, err
+	}
+	return left, s.save(chatID, kb)
+}
+
+// detach takes chatID off the knowledge base it joined and reports whether
+// it was still a member there; the caller holds s.mu and saves kb.
+func (s *kbStore) detach(chatID int64, kb *knowledgeBase) (bool, error) {
+	if kb.SharedFrom == 0 {
+		return false, nil
+	}
+	ownerID := kb.SharedFrom
+	kb.SharedFrom = 0
+	owner, err := s.load(ownerID)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := owner.Members[chatID]; !ok {
+		return false, nil
+	}
+	delete(owner.Members, chatID)
+	return true, s.save(ownerID, owner)
+}
+
+// sharing returns the chats the knowledge base of chatID is shared with and
+// the number of its invites still open.
+func (s *kbStore) sharing(chatID int64, now time.Time) (map[int64]kbRole, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kb, err := s.load(chatID)
+	if err != nil {
+		return nil, 0, err
+	}
+	open := 0
+	for _, inv := range kb.Invites {
+		if !now.After(inv.Expires) {
+			open++
+		}
+	}
+	return maps.Clone(kb.Members), open, nil
+}
+
+// revoke stops sharing the knowledge base of chatID with member, or with
+// every member and invite when member is zero. It reports whether there was
+// anything to revoke.
+func (s *kbStore) revoke(chatID, member int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kb, err := s.load(chatID)
+	if err != nil {
+		return false, err
+	}
+	if member == 0 {
+		if len(kb.Members) == 0 && len(kb.Invites) == 0 {
+			return false, nil
+		}
+		kb.Members, kb.Invites = nil, nil
+		return true, s.save(chatID, kb)
+	}
+	if _, ok := kb.Members[member]; !ok {
+		return false, nil
+	}
+	delete(kb.Members, member)
+	return true, s.save(chatID, kb)
+}
+
+// kbHit is a passage found for a question.
+type kbHit struct {
+	Name string
+	Text string
+}
+
+// search returns the passages of chatID closest to query, best first.
+func (s *kbStore) search(chatID int64, query []float32, k int) []kbHit {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	kb, err := s.load(chatID)
+	if err != nil || len(kb.Chunks) == 0 {
+		return nil
+	}
+	type scored struct {
+		chunk kbChunk
+		score float64
+	}
+	ranked := make([]scored, len(kb.Chunks))
+	for i, c := range kb.Chunks {
+		ranked[i] = scored{c, cosine(query, c.Vector)}
+	}
+	slices.SortStableFunc(ranked, func(x, y scored) int {
+		switch {
+		case x.score > y.score:
+			return -1
+		case x.score < y.score:
+			return 1
+		}
+		return 0
+	})
+	hits := make([]kbHit, 0, k)
+	for _, r := range ranked[:min(k, len(ranked))] {
+		doc, _ := kb.document(r.chunk.Doc)
+		hits = append(hits, kbHit{Name: doc.Name, Text: r.chunk.Text})
+	}
+	return hits
+}
+
+// hasDocuments reports whether chatID has documents, without reading its
+// knowledge base when it was never loaded and has no file.
+func (s *kbStore) hasDocuments(chatID int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.known(chatID) {
+		return false
+	}
+	kb, err := s.load(chatID)
+	return err == nil && len(kb.Docs) > 0
+}
+
+// kbPassages splits text into passages of about kbChunkRunes, preferring
+// paragraph and then line boundaries.
+func kbPassages(text string) []string {
+	var passages []string
+	var current strings.Builder
+	flush := func() {
+		if p := strings.TrimSpace(current.String()); p != "" {
+			passages = append(passages, p)
+		}
+		current.Reset()
+	}
+	for _, paragraph := range strings.Split(blankLinesPattern.ReplaceAllString(text, "\n\n"), "\n\n") {
+		for _, piece := range splitRunes(paragraph, kbChunkRunes) {
+			if current.Len() > 0 && utf8.RuneCountInString(current.String())+utf8.RuneCountInString(piece) > kbChunkRunes {
+				flush()
+			}
+			if current.Len() > 0 {
+				current.WriteString("\n\n")
+			}
+			current.WriteString(piece)
+		}
+	}
+	flush()
+	return passages
+}
+
+// splitRunes cuts text into pieces of at most n runes, at line breaks where
+// it can.
+func splitRunes(text string, n int) []string {
+	var pieces []string
+	for utf8.RuneCountInString(text) > n {
+		limit := len(string([]rune(text)[:n]))
+		cut := strings.LastIndex(text[:limit], "\n")
+		if cut < limit/2 {
+			cut = limit
+		}
+		pieces = append(pieces, text[:cut])
+		text = strings.TrimLeft(text[cut:], "\n")
+	}
+	return append(pieces, text)
+}
+
+// kbDocumentText downloads doc and returns its text.
+func (a *App) kbDocumentText(doc *tele.Document) (string, error) {
+	ex, extractable := extractorFor(doc)
+	ext := strings.ToLower(filepath.Ext(doc.FileName))
+	if !extractable && !kbTextExtensions[ext] && !strings.HasPrefix(doc.MIME, "text/") {
+		return "", errNoTextLayer
+	}
+	if doc.FileSize > extractMaxFileBytes {
+		return "", errFileTooLarge
+	}
+	reader, err := a.bot.File(doc.MediaFile())
+	if err != nil {
+		return "", fmt.Errorf("get file: %w", err)
+	}
+	data, err := io.ReadAll(io.LimitReader(reader, extractMaxFileBytes+1))
+	reader.Close()
+	if err != nil {
+		return "", fmt.Errorf("read file: %w", err)
+	}
+	if len(data) > extractMaxFileBytes {
+		return "", errFileTooLarge
+	}
+	if extractable {
+		text, err := ex.extract(data)
+		if err != nil && ex.format == "PDF" {
+			return "", errNoTextLayer
+		}
+		return text, err
+	}
+	if !utf8.Valid(data) {
+		return "", errNoTextLayer
+	}
+	if ext == ".html" || ext == ".htm" || doc.MIME == "text/html" {
+		var b strings.Builder
+		if err := htmlText(&b, strings.NewReader(string(data))); err != nil {
+			return "", err
+		}
+		return b.String(), nil
+	}
+	return string(data), nil
+}
+
+// kbInstruction retrieves the passages of the chat's knowledge base that
+// relate to prompt and asks for answers naming the documents they come from.
+// It is empty when the chat has no knowledge base or the lookup fails.
+func (a *App) kbInstruction(ctx context.Context, chatID int64, prompt string) string {
+	if strings.TrimSpace(prompt) == "" {
+		return ""
+	}
+	asking := chatID
+	chatID, _, err := a.kb.access(chatID)
+	if err != nil {
+		logFrom(ctx).Warn("knowledge base lookup failed", "err", err)
+		return ""
+	}
+	if !a.kb.hasDocuments(chatID) {
+		return ""
+	}
+	vectors, err := a.embedTexts(ctx, asking, []string{prompt}, "RETRIEVAL_QUERY")
+	if err != nil {
+		logFrom(ctx).Warn("knowledge base lookup failed", "err", err)
+		return ""
+	}
+	hits := a.kb.search(chatID, vectors[0], kbTopChunks)
+	if len(hits) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("The chat keeps a knowledge base of documents. Passages from it that may relate to the user's message follow. ")
+	b.WriteString("Answer from them where they apply and name the document you used, such as (handbook.pdf). ")
+	b.WriteString("Ignore passages that do not relate to the message, and say so when the knowledge base does not cover a question about it.\n")
+	for _, hit := range hits {
+		fmt.Fprintf(&b, "\n--- %s ---\n%s\n", hit.Name, hit.Text)
+	}
+	return b.String()
+}
+
+// handleKB manages the chat's knowledge base:
+//
+//	/kb add                     as a reply to a document, or as its caption
+//	/kb list                    lists the documents
+//	/kb delete <id|all>         removes one document or all of them
+//	/kb share <editor|reader>   creates an invite for other chats of the team
+//	/kb join <token>            uses the knowledge base the invite opens
+//	/kb leave                   goes back to the chat's own knowledge base
+//	/kb members                 lists the chats it is shared with
+//	/kb revoke <chat id|all>    stops sharing with one chat or with all
+//
+// Questions in the chat are then answered with the passages of the indexed
+// documents that relate to them. Readers of a shared knowledge base cannot
+// change its documents, and in groups only admins change how it is shared.
+func (a *App) handleKB(c tele.Context) error {
+	msg := c.Message()
+	chat := c.Chat()
+	lang := a.chatLanguage(chat, c.Sender())
+	reply := func(body string) error {
+		_, err := a.sendWithFallback(chat, body, &tele.SendOptions{ThreadID: messageTopic(msg), DisableWebPagePreview: true})
+		return err
+	}
+	payload := msg.Payload
+	if msg.Document != nil {
+		_, payload = captionCommand(msg)
+	}
+	command, arg, _ := strings.Cut(strings.TrimSpace(payload), " ")
+	command, arg = strings.ToLower(command), strings.TrimSpace(arg)
+	target, role, err := a.kb.access(chat.ID)
+	if err != nil {
+		a.chatLog(chat.ID).Warn("load knowledge base failed", "chat_id", chat.ID, "err", err)
+		return reply(tr(lang, "kb_failed"))
+	}
+	switch command {
+	case "add", "delete":
+		if !role.canEdit() {
+			return reply(tr(lang, "kb_read_only"))
+		}
+	case "share", "members", "revoke":
+		if role != kbOwner {
+			return reply(tr(lang, "kb_owner_only"))
+		}
+	}
+	switch command {
+	case "share", "join", "leave", "revoke":
+		if isGroupChat(chat) && !a.isChatAdmin(c) {
+			return reply(tr(lang, "kb_admins_only"))
+		}
+	}
+
+	switch command {
+	case "add":
+		source := msg
+		if source.Document == nil {
+			source = msg.ReplyTo
+		}
+		if source == nil || source.Document == nil {
+			return reply(tr(lang, "kb_usage"))
+		}
+		return a.enqueueJob(c.Message(), func(ctx context.Context) error {
+			return a.addToKB(ctx, msg, source.Document)
+		})
+	case "list":
+		docs, err := a.kb.documents(target)
+		if err != nil {
+			a.chatLog(chat.ID).Warn("load knowledge base failed", "chat_id", chat.ID, "err", err)
+			return reply(tr(lang, "kb_failed"))
+		}
+		if len(docs) == 0 {
+			return reply(tr(lang, "kb_empty"))
+		}
+		lines := make([]string, len(docs))
+		for i, d := range docs {
+			lines[i] = fmt.Sprintf("#%d · %s · %s", d.ID, d.Name, tr(lang, "kb_passages", d.Chunks))
+		}
+		return reply(tr(lang, "kb_list", len(docs), strings.Join(lines, "\n")))
+	case "delete":
+		if strings.EqualFold(arg, "all") {
+			if err := a.kb.clearDocuments(target); err != nil {
+				a.chatLog(chat.ID).Warn("clear knowledge base failed", "chat_id", chat.ID, "err", err)
+				return reply(tr(lang, "kb_failed"))
+			}
+			return reply(tr(lang, "kb_cleared"))
+		}
+		id, err := strconv.ParseInt(strings.TrimPrefix(arg, "#"), 10, 64)
+		if err != nil {
+			return reply(tr(lang, "kb_usage"))
+		}
+		removed, err := a.kb.remove(target, id)
+		if err != nil {
+			a.chatLog(chat.ID).Warn("save knowledge base failed", "chat_id", chat.ID, "err", err)
+			return reply(tr(lang, "kb_failed"))
+		}
+		if !removed {
+			return reply(tr(lang, "kb_unknown", id))
+		}
+		return reply(tr(lang, "kb_deleted", id))
+	case "share":
+		invited := kbRole(strings.ToLower(arg))
+		if invited != kbEditor && invited != kbReader {
+			return reply(tr(lang, "kb_share_usage"))
+		}
+		token, err := a.kb.invite(chat.ID, invited, time.Now())
+		if err != nil {
+			a.chatLog(chat.ID).Warn("save knowledge base failed", "chat_id", chat.ID, "err", err)
+			return reply(tr(lang, "kb_failed"))
+		}
+		return reply(tr(lang, "kb_invite", token, tr(lang, "kb_role_"+string(invited)), int(kbInviteTTL/(24*time.Hour))))
+	case "join":
+		if arg == "" {
+			return reply(tr(lang, "kb_join_usage"))
+		}
+		joined, err := a.kb.join(chat.ID, arg, time.Now())
+		switch {
+		case errors.Is(err, errKBInvite):
+			return reply(tr(lang, "kb_join_invalid"))
+		case errors.Is(err, errKBOwnInvite):
+			return reply(tr(lang, "kb_join_own"))
+		case err != nil:
+			a.chatLog(chat.ID).Warn("join knowledge base failed", "chat_id", chat.ID, "err", err)
+			return reply(tr(lang, "kb_failed"))
+		}
+		return reply(tr(lang, "kb_joined", tr(lang, "kb_role_"+string(joined))))
+	case "leave":
+		left, err := a.kb.leave(chat.ID)
+		switch {
+		case err != nil:
+			a.chatLog(chat.ID).Warn("leave knowledge base failed", "chat_id", chat.ID, "err", err)
+			return reply(tr(lang, "kb_failed"))
+		case !left:
+			return reply(tr(lang, "kb_not_shared"))
+		}
+		return reply(tr(lang, "kb_left"))
+	case "members":
+		members, invites, err := a.kb.sharing(chat.ID, time.Now())
+		if err != nil {
+			a.chatLog(chat.ID).Warn("load knowledge base failed", "chat_id", chat.ID, "err", err)
+			return reply(tr(lang, "kb_failed"))
+		}
+		if len(members) == 0 && invites == 0 {
+			return reply(tr(lang, "kb_members_none"))
+		}
+		var lines []string
+		for _, id := range slices.Sorted(maps.Keys(members)) {
+			lines = append(lines, fmt.Sprintf("%d · %s", id, tr(lang, "kb_role_"+string(members[id]))))
+		}
+		return reply(tr(lang, "kb_members", strings.Join(lines, "\n"), invites))
+	case "revoke":
+		var member int64
+		if !strings.EqualFold(arg, "all") {
+			if member, err = strconv.ParseInt(arg, 10, 64); err != nil || member == 0 {
+				return reply(tr(lang, "kb_usage"))
+			}
+		}
+		revoked, err := a.kb.revoke(chat.ID, member)
+		switch {
+		case err != nil:
+			a.chatLog(chat.ID).Warn("save knowledge base failed", "chat_id", chat.ID, "err", err)
+			return reply(tr(lang, "kb_failed"))
+		case member == 0:
+			return reply(tr(lang, "kb_revoked_all"))
+		case !revoked:
+			return reply(tr(lang, "kb_not_member", member))
+		}
+		return reply(tr(lang, "kb_revoked", member))
+	}
+	return reply(tr(lang, "kb_usage"))
+}
+
+// addToKB indexes doc into the knowledge base of the chat of msg.
+func (a *App) addToKB(ctx context.Context, msg *tele.Message, doc *tele.Document) error {
+	lang := a.chatLanguage(msg.Chat, msg.Sender)
+	reply := func(body string) error {
+		_, err := a.sendWithFallback(msg.Chat, body, &tele.SendOptions{ThreadID: messageTopic(msg), DisableWebPagePreview: true})
+		return err
+	}
+	ctx, cancel := context.WithTimeout(withChatID(ctx, msg.Chat.ID), kbIndexTimeout)
+	defer cancel()
+
+	name := doc.FileName
+	if name == "" {
+		name = "document"
+	}
+	text, err := a.kbDocumentText(doc)
+	switch {
+	case errors.Is(err, errNoTextLayer):
+		return reply(tr(lang, "kb_unsupported", name))
+	case errors.Is(err, errFileTooLarge):
+		return reply(tr(lang, "file_too_large"))
+	case err != nil:
+		logFrom(ctx).Warn("read knowledge base document failed", "err", err)
+		return reply(tr(lang, "kb_failed"))
+	}
+	passages := kbPassages(text)
+	if len(passages) == 0 {
+		return reply(tr(lang, "kb_unsupported", name))
+	}
+	entry := kbDocument{Name: name, Added: time.Now().UTC()}
+	if len(passages) > kbMaxChunks {
+		passages, entry.Truncated = passages[:kbMaxChunks], true
+	}
+	texts := make([]string, len(passages))
+	for i, p := range passages {
+		texts[i] = name + "\n" + p
+	}
+	vectors, err := a.embedTexts(ctx, msg.Chat.ID, texts, "RETRIEVAL_DOCUMENT")
+	if err != nil {
+		logFrom(ctx).Warn("embed knowledge base document failed", "err", err)
+		return reply(tr(lang, "kb_failed"))
+	}
+	chunks := make([]kbChunk, len(passages))
+	for i, p := range passages {
+		chunks[i] = kbChunk{Text: p, Vector: vectors[i]}
+	}
+	target, role, err := a.kb.access(msg.Chat.ID)
+	if err == nil && !role.canEdit() {
+		return reply(tr(lang, "kb_read_only"))
+	}
+	if err == nil {
+		entry, err = a.kb.add(target, entry, chunks)
+	}
+	switch {
+	case errors.Is(err, errKBFull):
+		return reply(tr(lang, "kb_full", kbMaxChunks))
+	case err != nil:
+		logFrom(ctx).Warn("save knowledge base failed", "err", err)
+		return reply(tr(lang, "kb_failed"))
+	}
+	body := tr(lang, "kb_added", name, entry.ID, tr(lang, "kb_passages", entry.Chunks))
+	if entry.Truncated {
+		body += " " + tr(lang, "kb_truncated")
+	}
+	return reply(body)
+}
@@ -0,0 +1,195 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestKnowledgeBaseAnswersFromDocuments(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.files = map[string]string{
+		"handbook": "# Handbook\n\nVacation requests go to your manager two weeks ahead.\n\nThe office opens at eight.",
+		"menu":     "Lunch is served from noon. Soup on Mondays, pasta on Fridays.",
+		"logo":     "\x89PNG\x00",
+	}
+	document := func(id, name, mime string) *tele.Message {
+		msg := testMessage(42, "")
+		msg.Document = &tele.Document{File: tele.File{FileID: id, UniqueID: id}, FileName: name, MIME: mime}
+		return msg
+	}
+	command := func(payload string, replyTo *tele.Message) *tele.Message {
+		msg := testMessage(42, "/kb "+payload)
+		msg.Payload = payload
+		msg.ReplyTo = replyTo
+		return msg
+	}
+	kb := func(payload string) string {
+		t.Helper()
+		if err := app.handleKB(app.bot.NewContext(tele.Update{Message: command(payload, nil)})); err != nil {
+			t.Fatalf("handleKB(%q): %v", payload, err)
+		}
+		texts := apis.sentTexts()
+		return texts[len(texts)-1]
+	}
+
+	handbook := document("handbook", "handbook.md", "text/markdown")
+	handbook.Caption = "/kb add"
+	if err := app.handleUserMessage(app.bot.NewContext(tele.Update{Message: handbook})); err != nil {
+		t.Fatalf("handleUserMessage: %v", err)
+	}
+	for _, doc := range []*tele.Message{document("menu", "menu.txt", "text/plain"), document("logo", "logo.png", "image/png")} {
+		if err := app.handleKB(app.bot.NewContext(tele.Update{Message: command("add", doc)})); err != nil {
+			t.Fatalf("handleKB: %v", err)
+		}
+	}
+	app.queue.drain(context.Background())
+	var texts []string
+	for _, text := range apis.sentTexts() {
+		if !strings.HasPrefix(text, "Queued") {
+			texts = append(texts, text)
+		}
+	}
+	if len(texts) != 3 || !strings.Contains(texts[0], "handbook\\.md to the knowledge base as \\#1") ||
+		!strings.Contains(texts[1], "menu\\.txt") || !strings.Contains(texts[2], "read the text of logo") {
+		t.Errorf("/kb add answered %q", texts)
+	}
+	if got := kb("list"); !strings.Contains(got, "\\#1 · handbook\\.md") || !strings.Contains(got, "\\#2 · menu\\.txt") {
+		t.Errorf("/kb list answered %q", got)
+	}
+
+	if err := app.processMessage(context.Background(), testMessage(42, "When is pasta served for lunch?"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	calls := apis.callsTo(geminiHost, "gemini-2.5-pro:generateContent")
+	body := string(calls[len(calls)-1].body)
+	menu, vacation := strings.Index(body, "pasta on Fridays"), strings.Index(body, "Vacation requests")
+	if menu < 0 || !strings.Contains(body, "--- menu.txt ---") || (vacation >= 0 && vacation < menu) {
+		t.Errorf("the menu is not the best passage in the request: %s", body)
+	}
+
+	reopened := newKBStore(filepath.Join(app.dataDir, "kb"))
+	if docs, err := reopened.documents(42); err != nil || len(docs) != 2 {
+		t.Errorf("persisted documents = %+v, %v", docs, err)
+	}
+	if got := kb("delete 2"); !strings.Contains(got, "\\#2 removed") {
+		t.Errorf("/kb delete answered %q", got)
+	}
+	if app.kbInstruction(context.Background(), 42, "pasta") == "" {
+		t.Error("the handbook left no passages")
+	}
+	kb("delete all")
+	if app.kbInstruction(context.Background(), 42, "pasta") != "" || app.kb.hasDocuments(42) {
+		t.Error("the knowledge base still answers after /kb delete all")
+	}
+}
+
+func TestKnowledgeBaseSharedAcrossChats(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	kb := func(chatID int64, payload string) string {
+		t.Helper()
+		msg := testMessage(chatID, "/kb "+payload)
+		msg.Payload = payload
+		if err := app.handleKB(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("handleKB(%q): %v", payload, err)
+		}
+		texts := apis.sentTexts()
+		return texts[len(texts)-1]
+	}
+	invite := regexp.MustCompile("/kb join ([0-9a-z_-]+)")
+	share := func(role string) string {
+		t.Helper()
+		m := invite.FindStringSubmatch(kb(42, "share "+role))
+		if m == nil {
+			t.Fatalf("/kb share %s created no invite", role)
+		}
+		return m[1]
+	}
+	if _, err := app.kb.add(42, kbDocument{Name: "handbook.md"}, []kbChunk{{Text: "The office opens at eight.", Vector: []float32{1, 0}}}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	reader, editor := share("reader"), share("editor")
+	if got := kb(42, "share owner"); !strings.Contains(got, "Usage: /kb share") {
+		t.Errorf("/kb share owner answered %q", got)
+	}
+	if got := kb(42, "join "+reader); !strings.Contains(got, "already owns") {
+		t.Errorf("joining its own knowledge base answered %q", got)
+	}
+	if got := kb(43, "join 16_deadbeef"); !strings.Contains(got, "unknown or has expired") {
+		t.Errorf("an unknown invite answered %q", got)
+	}
+	if got := kb(43, "join "+reader); !strings.Contains(got, "as reader") {
+		t.Errorf("/kb join answered %q", got)
+	}
+	kb(44, "join "+editor)
+
+	if got := kb(43, "list"); !strings.Contains(got, "handbook\\.md") {
+		t.Errorf("a reader's /kb list answered %q", got)
+	}
+	if app.kbInstruction(context.Background(), 43, "When does the office open?") == "" {
+		t.Error("a reader is not answered from the shared documents")
+	}
+	if got := kb(43, "delete 1"); !strings.Contains(got, "cannot change its documents") {
+		t.Errorf("a reader deleted a document: %q", got)
+	}
+	if got := kb(43, "share reader"); !strings.Contains(got, "Only the chat that owns") {
+		t.Errorf("a reader shared the knowledge base: %q", got)
+	}
+	if got := kb(42, "members"); !strings.Contains(got, "43 · reader") || !strings.Contains(got, "44 · editor") || !strings.Contains(got, "Open invites: 2") {
+		t.Errorf("/kb members answered %q", got)
+	}
+
+	// The sharing survives a restart and follows the owner into a supergroup.
+	reopened := newKBStore(filepath.Join(app.dataDir, "kb"))
+	if err := reopened.migrate(42, -1042); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if owner, role, err := reopened.access(44); err != nil || owner != -1042 || role != kbEditor {
+		t.Errorf("after the move chat 44 uses %d as %s (%v)", owner, role, err)
+	}
+	app.kb = reopened
+
+	if got := kb(44, "delete 1"); !strings.Contains(got, "\\#1 removed") {
+		t.Errorf("an editor's /kb delete answered %q", got)
+	}
+	if reopened.hasDocuments(-1042) {
+		t.Error("the editor's delete left the owner's document")
+	}
+	if got := kb(-1042, "revoke 43"); !strings.Contains(got, "43 no longer shares") {
+		t.Errorf("/kb revoke answered %q", got)
+	}
+	if owner, _, _ := reopened.access(43); owner != 43 {
+		t.Errorf("a revoked chat still uses %d", owner)
+	}
+	if got := kb(44, "leave"); !strings.Contains(got, "own knowledge base again") {
+		t.Errorf("/kb leave answered %q", got)
+	}
+	if members, invites, _ := reopened.sharing(-1042, time.Now()); len(members) != 0 || invites != 0 {
+		t.Errorf("after leaving the owner shares with %v and %d invites", members, invites)
+	}
+	if got := kb(43, "leave"); strings.Contains(got, "again") {
+		t.Errorf("leaving after the revoke answered %q", got)
+	}
+}
+
+func TestKBPassages(t *testing.T) {
+	text := strings.Repeat("word ", 200) + "\n\n" + strings.Repeat("line of text\n", 300) + "\n\nshort"
+	passages := kbPassages(text)
+	if len(passages) < 3 {
+		t.Fatalf("got %d passages, want the long text split", len(passages))
+	}
+	for i, p := range passages {
+		if n := len([]rune(p)); n > kbChunkRunes {
+			t.Errorf("passage %d has %d runes, over %d", i, n, kbChunkRunes)
+		}
+	}
+	if !strings.HasSuffix(passages[len(passages)-1], "short") {
+		t.Errorf("last passage = %q", passages[len(passages)-1])
+	}
+}
@@ -0,0 +1,169 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultConcurrentCalls is how many model calls run at once across all
+	// chats unless Config.MaxConcurrentCalls says otherwise.
+	defaultConcurrentCalls = 8
+	// waitingCallsPerSlot bounds the calls waiting for a slot: beyond this
+	// many per slot, new calls fail at once.
+	waitingCallsPerSlot = 4
+	// defaultCallEstimate is how long a call is expected to take before any
+	// has finished.
+	defaultCallEstimate = 10 * time.Second
+)
+
+// errCallsSaturated fails a model call when the line of waiting calls is full.
+var errCallsSaturated = errors.New("too many model calls waiting")
+
+// callLimiter caps the model calls running at once so a busy bot does not
+// pile dozens of requests onto the API together. Calls beyond the cap wait
+// in line for a slot, first come first served, and the line is bounded.
+type callLimiter struct {
+	mu      sync.Mutex
+	slots   int
+	running int
+	waiting []chan struct{}
+	// average is the moving average of how long calls hold a slot.
+	average time.Duration
+}
+
+// newCallLimiter reads Config.MaxConcurrentCalls: zero means the default and
+// a negative value no limit, returned as nil.
+func newCallLimiter(configured int) *callLimiter {
+	switch {
+	case configured == 0:
+		configured = defaultConcurrentCalls
+	case configured < 0:
+		return nil
+	}
+	return &callLimiter{slots: configured, average: defaultCallEstimate}
+}
+
+// acquire takes a slot for a call made with ctx, waiting in line while all
+// are taken. The first wait of a request is announced through the notice of
+// withCallWaitNotice. The returned func gives the slot back.
+func (l *callLimiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+	l.mu.Lock()
+	if l.running < l.slots && len(l.waiting) == 0 {
+		l.running++
+		l.mu.Unlock()
+		return l.releaser(), nil
+	}
+	if len(l.waiting) >= l.slots*waitingCallsPerSlot {
+		l.mu.Unlock()
+		return nil, errCallsSaturated
+	}
+	ready := make(chan struct{})
+	l.waiting = append(l.waiting, ready)
+	position := len(l.waiting)
+	wait := l.estimate(position)
+	l.mu.Unlock()
+
+	if notice, ok := ctx.Value(callWaitKey{}).(*callWaitNotice); ok {
+		notice.announce(position, wait)
+	}
+	select {
+	case <-ready:
+		return l.releaser(), nil
+	case <-ctx.Done():
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for i, ch := range l.waiting {
+		if ch == ready {
+			l.waiting = append(l.waiting[:i], l.waiting[i+1:]...)
+			return nil, ctx.Err()
+		}
+	}
+	// The slot arrived as ctx ended: pass it on.
+	l.handOff()
+	return nil, ctx.Err()
+}
+
+// releaser returns the func that gives back a slot taken now.
+func (l *callLimiter) releaser() func() {
+	start := time.Now()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			l.average += (time.Since(start) - l.average) / 5
+			l.handOff()
+		})
+	}
+}
+
+// handOff gives a freed slot to the first call in line, or frees it; the
+// caller holds l.mu.
+func (l *callLimiter) handOff() {
+	if len(l.waiting) == 0 {
+		l.running--
+		return
+	}
+	close(l.waiting[0])
+	l.waiting = l.waiting[1:]
+}
+
+// estimate is how long the call at position in line should wait; the caller
+// holds l.mu.
+func (l *callLimiter) estimate(position int) time.Duration {
+	rounds := (position + l.slots - 1) / l.slots
+	return time.Duration(rounds) * l.average
+}
+
+// stats reports the slots, the calls running and those waiting.
+func (l *callLimiter) stats() (slots, running, waiting int) {
+	if l == nil {
+		return 0, 0, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.slots, l.running, len(l.waiting)
+}
+
+// formatCallStats renders the limiter's stats for /admin stats.
+func formatCallStats(lang language, slots, running, waiting int) string {
+	if slots == 0 {
+		return tr(lang, "calls_unlimited")
+	}
+	return tr(lang, "calls_stats", running, slots, waiting)
+}
+
+type callWaitKey struct{}
+
+// callWaitNotice tells the user of a request once that its calls wait for a
+// slot.
+type callWaitNotice struct {
+	once   sync.Once
+	notify func(position int, wait time.Duration)
+}
+
+func (n *callWaitNotice) announce(position int, wait time.Duration) {
+	n.once.Do(func() { n.notify(position, wait) })
+}
+
+// withCallWaitNotice makes the first wait for a slot of the calls made with
+// ctx call notify with the place in line and the expected wait.
+func withCallWaitNotice(ctx context.Context, notify func(position int, wait time.Duration)) context.Context {
+	return context.WithValue(ctx, callWaitKey{}, &callWaitNotice{notify: notify})
+}
+
+// formatWait renders an expected wait in whole seconds under a minute and
+// whole minutes from there.
+func formatWait(d time.Duration) string {
+	if d < time.Minute {
+		return formatTTL(max(d.Round(time.Second), time.Second))
+	}
+	return formatTTL(d.Round(time.Minute))
+}
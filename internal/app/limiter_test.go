@@ -0,0 +1,85 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCallLimiterQueuesAndBounds(t *testing.T) {
+	l := newCallLimiter(1)
+	release, err := l.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+
+	type waiter struct {
+		position int
+		wait     time.Duration
+		done     chan func()
+	}
+	var waiters []*waiter
+	for range waitingCallsPerSlot {
+		w := &waiter{done: make(chan func(), 1)}
+		notified := make(chan struct{})
+		ctx := withCallWaitNotice(context.Background(), func(position int, wait time.Duration) {
+			w.position, w.wait = position, wait
+			close(notified)
+		})
+		go func() {
+			release, err := l.acquire(ctx)
+			if err != nil {
+				t.Errorf("acquire: %v", err)
+			}
+			w.done <- release
+		}()
+		<-notified
+		waiters = append(waiters, w)
+	}
+	for i, w := range waiters {
+		if w.position != i+1 || w.wait != time.Duration(i+1)*defaultCallEstimate {
+			t.Errorf("waiter %d was told place %d and %v", i, w.position, w.wait)
+		}
+	}
+	if _, err := l.acquire(context.Background()); !errors.Is(err, errCallsSaturated) {
+		t.Fatalf("acquire with a full line = %v, want errCallsSaturated", err)
+	}
+
+	release()
+	for i, w := range waiters {
+		next := <-w.done
+		if slots, running, waiting := l.stats(); slots != 1 || running != 1 || waiting != len(waiters)-i-1 {
+			t.Errorf("stats after %d handoffs = %d, %d, %d", i+1, slots, running, waiting)
+		}
+		next()
+	}
+	if _, running, waiting := l.stats(); running != 0 || waiting != 0 {
+		t.Errorf("%d calls running and %d waiting after all released", running, waiting)
+	}
+}
+
+func TestCallLimiterLeavesLineOnCancel(t *testing.T) {
+	l := newCallLimiter(1)
+	release, _ := l.acquire(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("acquire = %v, want the deadline", err)
+	}
+	release()
+	if _, running, waiting := l.stats(); running != 0 || waiting != 0 {
+		t.Errorf("%d calls running and %d waiting", running, waiting)
+	}
+	if newCallLimiter(-1) != nil {
+		t.Error("a negative limit still limits")
+	}
+}
+
+func TestFormatWait(t *testing.T) {
+	for d, want := range map[time.Duration]string{200 * time.Millisecond: "1s", 42 * time.Second: "42s", 100 * time.Second: "2m"} {
+		if got := formatWait(d); got != want {
+			t.Errorf("formatWait(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package app
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+const (
+	// defaultLoadSheddingDepth is how many requests, running or waiting
+	// across all chats, make new requests go light.
+	defaultLoadSheddingDepth = 20
+	// loadSheddingModel answers in place of a larger Gemini model under load.
+	loadSheddingModel = "gemini-2.5-flash"
+	// loadSheddingThinking caps the thinking budget under load.
+	loadSheddingThinking = 1024
+	// budgetSheddingShare is the part of MonthlyBudgetUSD from which
+	// requests go light.
+	budgetSheddingShare = 0.9
+)
+
+// sheddingDepth reads Config.LoadSheddingDepth: zero means the default and
+// a negative value never, returned as zero.
+func sheddingDepth(configured int) int {
+	switch {
+	case configured == 0:
+		return defaultLoadSheddingDepth
+	case configured < 0:
+		return 0
+	}
+	return configured
+}
+
+// Reasons for going light, as logged and shown in /admin stats.
+const (
+	shedQueue  = "queue"
+	shedBudget = "budget"
+)
+
+// shedding reports why new requests should go light, or "" while the bot
+// has room: the queue is deep or the month's spend nears the budget.
+func (a *App) shedding() string {
+	if !a.features.enabled(featureLoadShedding) {
+		return ""
+	}
+	t := a.tuned()
+	if t.shedDepth > 0 {
+		if busy, pending := a.queue.stats(); busy+pending >= t.shedDepth {
+			return shedQueue
+		}
+	}
+	if t.monthlyBudget > 0 {
+		if _, month := a.usage.globalSnapshot(); month.CostUSD >= t.monthlyBudget*budgetSheddingShare {
+			return shedBudget
+		}
+	}
+	return ""
+}
+
+// goLight moves a request made with ctx to loadSheddingModel, unless it
+// already runs on a Flash or non-Gemini model, and caps its thinking. It
+// reports whether anything changed and copies cfg only when it changes it.
+func (a *App) goLight(ctx context.Context, cfg *genai.GenerateContentConfig) (context.Context, *genai.GenerateContentConfig, bool) {
+	changed := false
+	if model := a.primaryModel(ctx); a.isGeminiModel(model) && !strings.Contains(model, "flash") {
+		ctx = withModel(ctx, loadSheddingModel)
+		changed = true
+	}
+	if thinking := cfg.ThinkingConfig; thinking != nil && (thinking.ThinkingBudget == nil || *thinking.ThinkingBudget < 0 || *thinking.ThinkingBudget > loadSheddingThinking) {
+		capped := *thinking
+		capped.ThinkingBudget = genai.Ptr[int32](loadSheddingThinking)
+		light := *cfg
+		light.ThinkingConfig = &capped
+		cfg = &light
+		changed = true
+	}
+	return ctx, cfg, changed
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestGoLightNearBudget(t *testing.T) {
+	app, apis := newTestApp(t, Config{MonthlyBudgetUSD: 1})
+	apis.reply = "Short answer."
+	if err := app.processMessage(context.Background(), testMessage(42, "Hi"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	if calls := apis.callsTo(geminiHost, loadSheddingModel+":generateContent"); len(calls) != 0 {
+		t.Fatalf("went light below the budget")
+	}
+
+	// 1M output tokens of gemini-2.5-pro cost $10.
+	app.usage.record(42, "gemini-2.5-pro", &genai.GenerateContentResponseUsageMetadata{CandidatesTokenCount: 100_000})
+	if err := app.processMessage(context.Background(), testMessage(42, "Hi again"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	calls := apis.callsTo(geminiHost, loadSheddingModel+":generateContent")
+	if len(calls) != 1 {
+		t.Fatalf("%s answered %d times, want the request moved to it", loadSheddingModel, len(calls))
+	}
+	if !strings.Contains(string(calls[0].body), `"thinkingBudget":1024`) {
+		t.Errorf("request %s, want a capped thinking budget", calls[0].body)
+	}
+	texts := apis.sentTexts()
+	if reply := texts[len(texts)-1]; !strings.Contains(reply, "less reasoning") || strings.Contains(reply, "unavailable") {
+		t.Errorf("reply = %q, want the light notice only", reply)
+	}
+	if stats := app.adminStats(defaultLanguage); !strings.Contains(stats, "Going light because of the budget") {
+		t.Errorf("stats = %q", stats)
+	}
+}
+
+func TestSheddingOnDeepQueue(t *testing.T) {
+	app, _ := newTestApp(t, Config{LoadSheddingDepth: 2})
+	release := make(chan struct{})
+	defer close(release)
+	for chatID := int64(1); chatID <= 2; chatID++ {
+		if reason := app.shedding(); reason != "" {
+			t.Fatalf("shedding with %d busy chats: %s", chatID-1, reason)
+		}
+		if _, err := app.queue.enqueue(chatID, 0, func(context.Context) error { <-release; return nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if reason := app.shedding(); reason != shedQueue {
+		t.Errorf("shedding() = %q, want %q", reason, shedQueue)
+	}
+}
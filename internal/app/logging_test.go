@@ -0,0 +1,94 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// lockedBuffer collects log output written from the queue workers.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// records decodes the JSON log lines written so far.
+func (b *lockedBuffer) records(t *testing.T) []map[string]any {
+	t.Helper()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(b.buf.String()), "\n") {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatalf("log line %q: %v", line, err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestRequestLogsCarryTheRequest(t *testing.T) {
+	app, _ := newTestApp(t, Config{})
+	var out lockedBuffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&out, nil)))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+
+	for _, text := range []string{"Hi", "Hi again"} {
+		msg := testMessage(42, text)
+		if err := app.enqueueJob(msg, func(ctx context.Context) error {
+			return app.processMessage(ctx, msg, turnOptions{})
+		}); err != nil {
+			t.Fatalf("enqueueJob: %v", err)
+		}
+	}
+	app.queue.drain(context.Background())
+
+	requestID := regexp.MustCompile(`^[0-9a-f]{16}$`)
+	ids := map[any]bool{}
+	for _, record := range out.records(t) {
+		if record["msg"] != "reply sent" {
+			continue
+		}
+		for _, key := range []string{"request_id", "chat_id", "user_id", "message_id", "model", "prompt_tokens", "output_tokens", "latency_ms"} {
+			if _, ok := record[key]; !ok {
+				t.Errorf("reply log lacks %s: %v", key, record)
+			}
+		}
+		if id, _ := record["request_id"].(string); !requestID.MatchString(id) {
+			t.Errorf("request_id = %q", id)
+		}
+		if record["chat_id"] != 42.0 || record["model"] != geminiModel {
+			t.Errorf("reply log = %v", record)
+		}
+		ids[record["request_id"]] = true
+	}
+	if len(ids) != 2 {
+		t.Errorf("%d distinct request IDs, want one per request", len(ids))
+	}
+}
+
+func TestLogFrom(t *testing.T) {
+	if logFrom(context.Background()) != slog.Default() {
+		t.Error("logFrom outside a request is not the default logger")
+	}
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	if logFrom(withLogger(context.Background(), logger)) != logger {
+		t.Error("logFrom lost the request logger")
+	}
+	if a, b := newRequestID(), newRequestID(); a == b {
+		t.Errorf("two requests share the ID %s", a)
+	}
+}
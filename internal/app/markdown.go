@@ -0,0 +1,370 @@
+package app
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// markdownToV2 converts standard Markdown, as produced by Gemini, into Telegram
+// MarkdownV2. Constructs without a MarkdownV2 equivalent are approximated:
+// headings become bold lines, list markers become bullets, and tables are
+// rendered as aligned monospace blocks.
+func markdownToV2(src string) string {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+	out := make([]string, 0, len(lines))
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		if fence, lang, ok := openingFence(trimmed); ok {
+			var code []string
+			j := i + 1
+			for ; j < len(lines); j++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[j]), fence) {
+					break
+				}
+				code = append(code, lines[j])
+			}
+			out = append(out, "```"+lang+"\n"+escapeCode(strings.Join(code, "\n"))+"\n```")
+			i = j
+			continue
+		}
+
+		if isTableRow(trimmed) && i+1 < len(lines) && isTableSeparator(strings.TrimSpace(lines[i+1])) {
+			rows := [][]string{splitTableRow(trimmed)}
+			j := i + 2
+			for ; j < len(lines) && isTableRow(strings.TrimSpace(lines[j])); j++ {
+				rows = append(rows, splitTableRow(strings.TrimSpace(lines[j])))
+			}
+			out = append(out, "```\n"+escapeCode(renderTable(rows))+"\n```")
+			i = j - 1
+			continue
+		}
+
+		switch {
+		case trimmed == "":
+			out = append(out, "")
+		case horizontalRulePattern.MatchString(line):
+			out = append(out, "──────────")
+		default:
+			if m := headingPattern.FindStringSubmatch(line); m != nil {
+				out = append(out, "*"+convertInline(m[2], styleBold)+"*")
+			} else if strings.HasPrefix(trimmed, ">") {
+				out = append(out, ">"+convertInline(strings.TrimSpace(strings.TrimLeft(trimmed, ">")), 0))
+			} else if m := bulletPattern.FindStringSubmatch(line); m != nil {
+				out = append(out, m[1]+"• "+convertInline(m[3], 0))
+			} else if m := orderedPattern.FindStringSubmatch(line); m != nil {
+				out = append(out, m[1]+m[2]+"\\. "+convertInline(m[3], 0))
+			} else {
+				out = append(out, convertInline(line, 0))
+			}
+		}
+	}
+	return strings.Join(out, "\n")
+}
+
+var (
+	headingPattern        = regexp.MustCompile(`^\s{0,3}(#{1,6})\s+(.*?)\s*#*\s*$`)
+	horizontalRulePattern = regexp.MustCompile(`^\s{0,3}(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	bulletPattern         = regexp.MustCompile(`^(\s*)([-*+])\s+(.*)$`)
+	orderedPattern        = regexp.MustCompile(`^(\s*)(\d{1,9})[.)]\s+(.*)$`)
+	tableSeparatorPattern = regexp.MustCompile(`^\|?\s*:?-{2,}:?\s*(\|\s*:?-{2,}:?\s*)*\|?$`)
+	fenceLangPattern      = regexp.MustCompile(`^[A-Za-z0-9_+\-#.]*$`)
+)
+
+func openingFence(line string) (fence, lang string, ok bool) {
+	for _, f := range []string{"```", "~~~"} {
+		if strings.HasPrefix(line, f) {
+			lang = strings.TrimSpace(strings.TrimLeft(line, f[:1]))
+			if !fenceLangPattern.MatchString(lang) {
+				lang = ""
+			}
+			return f, lang, true
+		}
+	}
+	return "", "", false
+}
+
+func isTableRow(line string) bool {
+	return strings.HasPrefix(line, "|") && strings.Count(line, "|") >= 2
+}
+
+func isTableSeparator(line string) bool {
+	return strings.Contains(line, "-") && tableSeparatorPattern.MatchString(line)
+}
+
+func splitTableRow(line string) []string {
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "|"), "|")
+	cells := strings.Split(line, "|")
+	for i, cell := range cells {
+		cells[i] = stripInlineMarkers(strings.TrimSpace(cell))
+	}
+	return cells
+}
+
+var inlineMarkerReplacer = strings.NewReplacer("**", "", "__", "", "`", "", "~~", "")
+
+func stripInlineMarkers(s string) string {
+	return inlineMarkerReplacer.Replace(s)
+}
+
+func renderTable(rows [][]string) string {
+	var widths []int
+	for _, row := range rows {
+		for i, cell := range row {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			if n := utf8.RuneCountInString(cell); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	var b strings.Builder
+	for r, row := range rows {
+		for i := range widths {
+			cell := ""
+			if i < len(row) {
+				cell = row[i]
+			}
+			if i > 0 {
+				b.WriteString(" | ")
+			}
+			b.WriteString(cell)
+			b.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)))
+		}
+		b.WriteString("\n")
+		if r == 0 {
+			for i, w := range widths {
+				if i > 0 {
+					b.WriteString("-+-")
+				}
+				b.WriteString(strings.Repeat("-", w))
+			}
+			b.WriteString("\n")
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+type inlineStyle uint8
+
+const (
+	styleBold inlineStyle = 1 << iota
+	styleItalic
+	styleStrike
+	styleLink
+)
+
+const markdownV2Special = "_*[]()~`>#+-=|{}.!\\"
+
+func escapeV2Rune(r rune) string {
+	if strings.ContainsRune(markdownV2Special, r) {
+		return "\\" + string(r)
+	}
+	return string(r)
+}
+
+// convertInline rewrites inline Markdown spans. active tracks enclosing styles,
+// since MarkdownV2 forbids nesting an entity inside one of the same kind.
+func convertInline(s string, active inlineStyle) string {
+	r := []rune(s)
+	var b strings.Builder
+	for i := 0; i < len(r); {
+		c := r[i]
+		switch {
+		case c == '\\' && i+1 < len(r) && strings.ContainsRune(markdownV2Special, r[i+1]):
+			b.WriteString(escapeV2Rune(r[i+1]))
+			i += 2
+			continue
+
+		case c == '`':
+			n := runLength(r, i, '`')
+			if end := findRun(r, i+n, '`', n); end >= 0 {
+				code := string(r[i+n : end])
+				if len(code) > 1 && strings.HasPrefix(code, " ") && strings.HasSuffix(code, " ") {
+					code = code[1 : len(code)-1]
+				}
+				b.WriteString("`" + escapeCode(code) + "`")
+				i = end + n
+				continue
+			}
+
+		case hasRunes(r, i, "***"):
+			if end := findDelimiter(r, i+3, "***"); end > i+3 {
+				inner := wrapStyle(string(r[i+3:end]), styleItalic, active|styleBold)
+				if active&styleBold == 0 {
+					inner = "*" + inner + "*"
+				}
+				b.WriteString(inner)
+				i = end + 3
+				continue
+			}
+			fallthrough
+
+		case hasRunes(r, i, "**") || hasRunes(r, i, "__"):
+			delim := string(r[i : i+2])
+			if end := findDelimiter(r, i+2, delim); end > i+2 {
+				b.WriteString(wrapStyle(string(r[i+2:end]), styleBold, active))
+				i = end + 2
+				continue
+			}
+
+		case hasRunes(r, i, "~~"):
+			if end := findDelimiter(r, i+2, "~~"); end > i+2 {
+				b.WriteString(wrapStyle(string(r[i+2:end]), styleStrike, active))
+				i = end + 2
+				continue
+			}
+
+		case (c == '*' || c == '_') && canOpenEmphasis(r, i):
+			if end := findClosingEmphasis(r, i+1, c); end > i+1 {
+				b.WriteString(wrapStyle(string(r[i+1:end]), styleItalic, active))
+				i = end + 1
+				continue
+			}
+
+		case c == '[':
+			if text, url, next, ok := parseLink(r, i); ok {
+				if active&styleLink == 0 {
+					b.WriteString("[" + convertInline(text, active|styleLink) + "](" + escapeLinkURL(url) + ")")
+				} else {
+					b.WriteString(convertInline(text, active))
+				}
+				i = next
+				continue
+			}
+		}
+
+		b.WriteString(escapeV2Rune(c))
+		i++
+	}
+	return b.String()
+}
+
+func wrapStyle(inner string, style, active inlineStyle) string {
+	if active&style != 0 {
+		return convertInline(inner, active)
+	}
+	marker := map[inlineStyle]string{styleBold: "*", styleItalic: "_", styleStrike: "~"}[style]
+	return marker + convertInline(inner, active|style) + marker
+}
+
+func hasRunes(r []rune, i int, prefix string) bool {
+	p := []rune(prefix)
+	if i+len(p) > len(r) {
+		return false
+	}
+	for k, pr := range p {
+		if r[i+k] != pr {
+			return false
+		}
+	}
+	return true
+}
+
+func runLength(r []rune, i int, c rune) int {
+	n := 0
+	for i+n < len(r) && r[i+n] == c {
+		n++
+	}
+	return n
+}
+
+// findRun locates the next run of exactly n copies of c at or after start.
+func findRun(r []rune, start int, c rune, n int) int {
+	for j := start; j < len(r); {
+		if r[j] != c {
+			j++
+			continue
+		}
+		if m := runLength(r, j, c); m == n {
+			return j
+		} else {
+			j += m
+		}
+	}
+	return -1
+}
+
+func findDelimiter(r []rune, start int, delim string) int {
+	for j := start; j < len(r); j++ {
+		if hasRunes(r, j, delim) && j > start && !unicode.IsSpace(r[j-1]) {
+			return j
+		}
+	}
+	return -1
+}
+
+func canOpenEmphasis(r []rune, i int) bool {
+	if i+1 >= len(r) || unicode.IsSpace(r[i+1]) || r[i+1] == r[i] {
+		return false
+	}
+	if r[i] == '_' && i > 0 && (unicode.IsLetter(r[i-1]) || unicode.IsDigit(r[i-1])) {
+		return false
+	}
+	return true
+}
+
+func findClosingEmphasis(r []rune, start int, c rune) int {
+	for j := start; j < len(r); j++ {
+		if r[j] != c || unicode.IsSpace(r[j-1]) {
+			continue
+		}
+		if j+1 < len(r) && r[j+1] == c {
+			j++
+			continue
+		}
+		if c == '_' && j+1 < len(r) && (unicode.IsLetter(r[j+1]) || unicode.IsDigit(r[j+1])) {
+			continue
+		}
+		return j
+	}
+	return -1
+}
+
+// parseLink recognises [text](url) starting at i and returns the index after it.
+func parseLink(r []rune, i int) (text, url string, next int, ok bool) {
+	depth := 0
+	closeText := -1
+	for j := i; j < len(r); j++ {
+		if r[j] == '[' {
+			depth++
+		} else if r[j] == ']' {
+			depth--
+			if depth == 0 {
+				closeText = j
+				break
+			}
+		}
+	}
+	if closeText < 0 || closeText+1 >= len(r) || r[closeText+1] != '(' {
+		return "", "", 0, false
+	}
+	depth = 0
+	for j := closeText + 1; j < len(r); j++ {
+		switch r[j] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				url = strings.TrimSpace(string(r[closeText+2 : j]))
+				if url == "" || strings.ContainsAny(url, " \n") {
+					return "", "", 0, false
+				}
+				return string(r[i+1 : closeText]), url, j + 1, true
+			}
+		}
+	}
+	return "", "", 0, false
+}
+
+func escapeLinkURL(url string) string {
+	url = strings.ReplaceAll(url, "\\", "\\\\")
+	return strings.ReplaceAll(url, ")", "\\)")
+}
@@ -0,0 +1,46 @@
+package app
+
+import "testing"
+
+func TestMarkdownToV2(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"plain text is escaped", "Costs 3.50 (approx.)!", `Costs 3\.50 \(approx\.\)\!`},
+		{"bold and italic", "**bold** and *italic* and _also_", `*bold* and _italic_ and _also_`},
+		{"bold italic", "***both***", `*_both_*`},
+		{"strikethrough", "~~gone~~", `~gone~`},
+		{"nested bold is flattened", "**a __b__ c**", `*a b c*`},
+		{"italic inside bold", "**bold _and italic_**", `*bold _and italic_*`},
+		{"snake_case stays text", "use snake_case_names", `use snake\_case\_names`},
+		{"inline code", "run `a_b(1)`", "run `a_b(1)`"},
+		{"link", "[the docs](https://example.com/a_(b))", `[the docs](https://example.com/a_(b\))`},
+		{"heading", "## Title.", `*Title\.*`},
+		{"bullets", "- one\n  * two", "• one\n  • two"},
+		{"ordered list", "1. first\n2) second", "1\\. first\n2\\. second"},
+		{"quote", "> said so.", `>said so\.`},
+		{"horizontal rule", "---", "──────────"},
+		{"code fence", "```go\nx := a_b * 2\n```", "```go\nx := a_b * 2\n```"},
+		{"code fence escapes", "```\n`x` \\ y\n```", "```\n\\`x\\` \\\\ y\n```"},
+		{"unterminated fence", "~~~\ncode", "```\ncode\n```"},
+		{"crlf", "a.\r\nb", "a\\.\nb"},
+	}
+	for _, tt := range tests {
+		if got := markdownToV2(tt.in); got != tt.want {
+			t.Errorf("%s: markdownToV2(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdownToV2Table(t *testing.T) {
+	in := "| Name | **Qty** |\n|---|:--:|\n| apple | 3 |\n| kiwi fruit | 12 |\nafter"
+	want := "```\n" +
+		"Name       | Qty\n" +
+		"-----------+----\n" +
+		"apple      | 3  \n" +
+		"kiwi fruit | 12 \n" +
+		"```\nafter"
+	if got := markdownToV2(in); got != want {
+		t.Errorf("markdownToV2 table =\n%s\nwant\n%s", got, want)
+	}
+}
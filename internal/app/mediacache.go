@@ -0,0 +1,115 @@
+package app
+
+import (
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	// mediaCacheTTL keeps downloaded media for the follow-up questions and
+	// replies of a conversation.
+	mediaCacheTTL = time.Hour
+	// uploadedFileTTL stays under the 48 hours the Files API keeps uploads.
+	uploadedFileTTL = 46 * time.Hour
+	// mediaCacheBytes bounds the downloaded media held in memory.
+	mediaCacheBytes   = 64 << 20
+	mediaCacheEntries = 512
+)
+
+// mediaCache remembers the parts made from Telegram files by their unique ID,
+// which stays the same when a file is sent again, forwarded or replied to:
+// downloaded bytes for a while, and Files API uploads until shortly before
+// Gemini deletes them.
+type mediaCache struct {
+	mu      sync.Mutex
+	entries map[string]mediaEntry
+	size    int64
+}
+
+type mediaEntry struct {
+	part    *genai.Part
+	size    int64
+	expires time.Time
+}
+
+func newMediaCache() *mediaCache {
+	return &mediaCache{entries: make(map[string]mediaEntry)}
+}
+
+// mediaKey keys file read as explicitMIME, or returns "" for a file without
+// an ID.
+func mediaKey(file *tele.File, explicitMIME string) string {
+	id := file.UniqueID
+	if id == "" {
+		id = file.FileID
+	}
+	if id == "" {
+		return ""
+	}
+	return id + "|" + explicitMIME
+}
+
+// get returns a copy of the cached part, so callers may change it.
+func (c *mediaCache) get(key string) (*genai.Part, bool) {
+	if key == "" {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		c.remove(key)
+		return nil, false
+	}
+	part := *entry.part
+	return &part, true
+}
+
+// put caches part, an inline blob or a file URI, evicting the entries closest
+// to expiry when the cache is full. Blobs over a quarter of mediaCacheBytes
+// are not kept.
+func (c *mediaCache) put(key string, part *genai.Part) {
+	if key == "" || part == nil {
+		return
+	}
+	entry := mediaEntry{part: part, expires: time.Now().Add(uploadedFileTTL)}
+	switch {
+	case part.InlineData != nil:
+		entry.size = int64(len(part.InlineData.Data))
+		entry.expires = time.Now().Add(mediaCacheTTL)
+		if entry.size > mediaCacheBytes/4 {
+			return
+		}
+	case part.FileData == nil:
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.remove(key)
+	for len(c.entries) > 0 && (len(c.entries) >= mediaCacheEntries || c.size+entry.size > mediaCacheBytes) {
+		var first string
+		for k, e := range c.entries {
+			if first == "" || e.expires.Before(c.entries[first].expires) {
+				first = k
+			}
+		}
+		c.remove(first)
+	}
+	c.entries[key] = entry
+	c.size += entry.size
+}
+
+// remove drops key; the caller holds c.mu.
+func (c *mediaCache) remove(key string) {
+	if entry, ok := c.entries[key]; ok {
+		c.size -= entry.size
+		delete(c.entries, key)
+	}
+}
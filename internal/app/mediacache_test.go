@@ -0,0 +1,63 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestResentPhotoIsNotDownloadedAgain(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.reply = "A cat."
+	apis.files = map[string]string{"first": "\xff\xd8\xff\xe0 jpeg bytes"}
+	for _, fileID := range []string{"first", "second"} {
+		msg := testMessage(42, "")
+		msg.Caption = "What is this?"
+		// Telegram gives the same file a new file ID when it is sent again,
+		// but keeps its unique ID.
+		msg.Photo = &tele.Photo{File: tele.File{FileID: fileID, UniqueID: "cat"}}
+		if err := app.processMessage(context.Background(), msg, turnOptions{}); err != nil {
+			t.Fatalf("processMessage: %v", err)
+		}
+	}
+
+	if downloads := apis.callsTo(telegramHost, "file"); len(downloads) != 1 {
+		t.Errorf("downloaded the photo %d times, want once", len(downloads))
+	}
+	calls := apis.callsTo(geminiHost, "gemini-2.5-pro:generateContent")
+	if last := string(calls[len(calls)-1].body); strings.Count(last, `"inlineData"`) != 2 {
+		t.Errorf("second request has %d images, want the history's and the new one", strings.Count(last, `"inlineData"`))
+	}
+}
+
+func TestMediaCacheEvicts(t *testing.T) {
+	c := newMediaCache()
+	blob := func(size int) *genai.Part {
+		return &genai.Part{InlineData: &genai.Blob{Data: make([]byte, size), MIMEType: "image/png"}}
+	}
+	c.put("big", blob(mediaCacheBytes/4+1))
+	if _, ok := c.get("big"); ok {
+		t.Error("cached a blob over a quarter of the cache")
+	}
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		c.put(key, blob(mediaCacheBytes/4))
+	}
+	if _, ok := c.get("a"); ok {
+		t.Error("kept the oldest blob past the size limit")
+	}
+	if c.size > mediaCacheBytes {
+		t.Errorf("size = %d, over the limit", c.size)
+	}
+	c.put("upload", genai.NewPartFromURI("https://generativelanguage.googleapis.com/v1beta/files/x", "video/mp4"))
+	part, ok := c.get("upload")
+	if !ok || part.FileData == nil {
+		t.Fatal("lost the upload")
+	}
+	part.FileData = nil
+	if again, _ := c.get("upload"); again.FileData == nil {
+		t.Error("get returned the cached part itself")
+	}
+}
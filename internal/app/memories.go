@@ -0,0 +1,369 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	// maxMemoriesPerUser and maxMemoryLen bound what /remember keeps.
+	maxMemoriesPerUser = 50
+	maxMemoryLen       = 500
+)
+
+// memory is a fact a user asked the bot to remember across chats and resets.
+// Pending memories were extracted from a conversation and wait for the user
+// to keep them; until then they do not reach the model.
+type memory struct {
+	ID      int64     `json:"id"`
+	SavedAt time.Time `json:"saved_at"`
+	Text    string    `json:"text"`
+	Pending bool      `json:"pending,omitempty"`
+}
+
+// memoryStore keeps the memories of every user across restarts in a JSON
+// file. Every change rewrites the file atomically.
+type memoryStore struct {
+	mu   sync.Mutex
+	path string
+	data memoryData
+	// since counts the messages of each user since the last extraction.
+	since map[int64]int
+}
+
+type memoryData struct {
+	NextID int64              `json:"next_id"`
+	Users  map[int64][]memory `json:"users"`
+	// Auto holds the users who opted in to memory extraction.
+	Auto map[int64]bool `json:"auto,omitempty"`
+}
+
+func openMemoryStore(path string) (*memoryStore, error) {
+	s := &memoryStore{path: path, data: memoryData{Users: make(map[int64][]memory), Auto: make(map[int64]bool)}, since: make(map[int64]int)}
+	if err := readJSONFile(path, &s.data); err != nil {
+		return nil, err
+	}
+	if s.data.Users == nil {
+		s.data.Users = make(map[int64][]memory)
+	}
+	if s.data.Auto == nil {
+		s.data.Auto = make(map[int64]bool)
+	}
+	return s, nil
+}
+
+// save writes the store; the caller holds s.mu.
+func (s *memoryStore) save() error {
+	return writeJSONFile(s.path, s.data)
+}
+
+var (
+	errTooManyMemories = errors.New("too many memories")
+	errMemoryExists    = errors.New("already remembered")
+)
+
+// add remembers text for userID and returns the memory with its ID. Adding a
+// pending memory again keeps it.
+func (s *memoryStore) add(userID int64, text string) (memory, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := s.data.Users[userID]
+	if i := slices.IndexFunc(list, func(m memory) bool { return strings.EqualFold(m.Text, text) }); i >= 0 {
+		if !list[i].Pending {
+			return list[i], errMemoryExists
+		}
+		list[i].Pending = false
+		return list[i], s.save()
+	}
+	if len(list) >= maxMemoriesPerUser {
+		return memory{}, errTooManyMemories
+	}
+	s.data.NextID++
+	m := memory{ID: s.data.NextID, SavedAt: time.Now().UTC(), Text: text}
+	s.data.Users[userID] = append(list, m)
+	return m, s.save()
+}
+
+// propose adds the facts userID has no memory of yet as pending memories and
+// returns them, within the limit of memories per user.
+func (s *memoryStore) propose(userID int64, facts []string) ([]memory, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var added []memory
+	for _, text := range facts {
+		text = strings.Join(strings.Fields(text), " ")
+		list := s.data.Users[userID]
+		if len(list) >= maxMemoriesPerUser {
+			break
+		}
+		if text == "" || utf8.RuneCountInString(text) > maxMemoryLen ||
+			slices.ContainsFunc(list, func(m memory) bool { return strings.EqualFold(m.Text, text) }) {
+			continue
+		}
+		s.data.NextID++
+		m := memory{ID: s.data.NextID, SavedAt: time.Now().UTC(), Text: text, Pending: true}
+		s.data.Users[userID] = append(list, m)
+		added = append(added, m)
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+	return added, s.save()
+}
+
+// confirm keeps the pending memory id of userID. It reports whether userID
+// has that memory.
+func (s *memoryStore) confirm(userID, id int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := s.data.Users[userID]
+	i := slices.IndexFunc(list, func(m memory) bool { return m.ID == id })
+	if i < 0 {
+		return false, nil
+	}
+	if !list[i].Pending {
+		return true, nil
+	}
+	list[i].Pending = false
+	return true, s.save()
+}
+
+// auto reports whether userID opted in to memory extraction.
+func (s *memoryStore) auto(userID int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data.Auto[userID]
+}
+
+func (s *memoryStore) setAuto(userID int64, on bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.since, userID)
+	if s.data.Auto[userID] == on {
+		return nil
+	}
+	if on {
+		s.data.Auto[userID] = true
+	} else {
+		delete(s.data.Auto, userID)
+	}
+	return s.save()
+}
+
+// due counts a message of userID and reports whether enough messages passed
+// since the last extraction to run another.
+func (s *memoryStore) due(userID int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.since[userID]++
+	if s.since[userID] < memoryExtractEvery {
+		return false
+	}
+	delete(s.since, userID)
+	return true
+}
+
+// list returns the memories of userID, oldest first.
+func (s *memoryStore) list(userID int64) []memory {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.data.Users[userID])
+}
+
+func (s *memoryStore) remove(userID, id int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := s.data.Users[userID]
+	i := slices.IndexFunc(list, func(m memory) bool { return m.ID == id })
+	if i < 0 {
+		return false, nil
+	}
+	s.data.Users[userID] = slices.Delete(list, i, i+1)
+	if len(s.data.Users[userID]) == 0 {
+		delete(s.data.Users, userID)
+	}
+	return true, s.save()
+}
+
+// clear removes every memory of userID.
+func (s *memoryStore) clear(userID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data.Users[userID]; !ok {
+		return nil
+	}
+	delete(s.data.Users, userID)
+	return s.save()
+}
+
+// forgetUser removes every memory of userID and the opt-in to extraction.
+func (s *memoryStore) forgetUser(userID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, remembers := s.data.Users[userID]
+	if !remembers && !s.data.Auto[userID] {
+		return nil
+	}
+	delete(s.data.Users, userID)
+	delete(s.data.Auto, userID)
+	return s.save()
+}
+
+// adoptStyleNotes moves the preferences /remember kept in the style profiles
+// before there were memories into the memory store, so they keep reaching
+// the model.
+func (s *memoryStore) adoptStyleNotes(styles *styleStore) error {
+	styles.mu.Lock()
+	defer styles.mu.Unlock()
+	moved := false
+	for userID, p := range styles.users {
+		if len(p.Notes) == 0 {
+			continue
+		}
+		for _, note := range p.Notes {
+			if _, err := s.add(userID, note); err != nil && !errors.Is(err, errMemoryExists) && !errors.Is(err, errTooManyMemories) {
+				return err
+			}
+		}
+		p.Notes = nil
+		if len(p.Votes) == 0 {
+			delete(styles.users, userID)
+		}
+		moved = true
+	}
+	if !moved {
+		return nil
+	}
+	return styles.save()
+}
+
+// memoryInstruction is the system prompt addition with the memories of the
+// sender of msg, empty when there are none.
+func (a *App) memoryInstruction(msg *tele.Message) string {
+	if msg.Sender == nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, m := range a.memories.list(msg.Sender.ID) {
+		if !m.Pending {
+			b.WriteString("\n- " + m.Text)
+		}
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "The user asked you to remember these facts across conversations. Take them into account " +
+		"where they matter, and follow the current request when it says otherwise:" + b.String()
+}
+
+// rememberFact saves a memory for the sender of c from a /remember payload.
+func (a *App) rememberFact(c tele.Context, fact string) error {
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	fact = strings.Join(strings.Fields(fact), " ")
+	if utf8.RuneCountInString(fact) > maxMemoryLen {
+		return a.replyMemories(c, tr(lang, "remember_too_long", maxMemoryLen))
+	}
+	m, err := a.memories.add(userIDOf(c.Sender()), fact)
+	switch {
+	case errors.Is(err, errMemoryExists):
+		return a.replyMemories(c, tr(lang, "remember_exists", m.ID))
+	case errors.Is(err, errTooManyMemories):
+		return a.replyMemories(c, tr(lang, "remember_too_many", maxMemoriesPerUser))
+	case err != nil:
+		a.chatLog(c.Chat().ID).Warn("save memories failed", "chat_id", c.Chat().ID, "err", err)
+		return a.replyMemories(c, tr(lang, "remember_failed"))
+	}
+	return a.replyMemories(c, tr(lang, "remember_saved", m.ID))
+}
+
+// handleMemories lists what the bot remembers of the sender, with buttons to
+// review the memories extracted from conversations:
+//
+//	/memories              lists the memories
+//	/memories auto on|off  switches extraction from private chats
+func (a *App) handleMemories(c tele.Context) error {
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	userID := userIDOf(c.Sender())
+	if command, arg, _ := strings.Cut(strings.TrimSpace(c.Message().Payload), " "); strings.EqualFold(command, "auto") {
+		arg = strings.ToLower(strings.TrimSpace(arg))
+		on := arg == "on"
+		switch {
+		case (arg != "on" && arg != "off") || userID == 0:
+			return a.replyMemories(c, tr(lang, "memories_auto"))
+		case a.memories.setAuto(userID, on) != nil:
+			return a.replyMemories(c, tr(lang, "remember_failed"))
+		case on:
+			return a.replyMemories(c, tr(lang, "memories_auto_on", memoryExtractEvery))
+		}
+		return a.replyMemories(c, tr(lang, "memories_auto_off"))
+	}
+
+	memories := a.memories.list(userID)
+	if len(memories) == 0 {
+		return a.replyMemories(c, tr(lang, "memories_none"))
+	}
+	lines := make([]string, len(memories))
+	var pending []memory
+	for i, m := range memories {
+		lines[i] = fmt.Sprintf("#%d · %s · %s", m.ID, m.SavedAt.Format("2006-01-02"), m.Text)
+		if m.Pending {
+			lines[i] = "⏳ " + lines[i]
+			pending = append(pending, m)
+		}
+	}
+	body := tr(lang, "memories_list", len(memories), strings.Join(lines, "\n"))
+	if len(pending) == 0 {
+		return a.replyMemories(c, body)
+	}
+	_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), body+"\n\n"+tr(lang, "memories_pending"), &tele.SendOptions{
+		ThreadID:              messageTopic(c.Message()),
+		DisableWebPagePreview: true,
+		ReplyMarkup:           memoryReviewMarkup(pending),
+	})
+	return err
+}
+
+// handleForget forgets memories of the sender:
+//
+//	/forget <id>   forgets one memory
+//	/forget all    forgets every memory
+func (a *App) handleForget(c tele.Context) error {
+	return a.forgetMemory(c, strings.TrimSpace(c.Message().Payload))
+}
+
+func (a *App) forgetMemory(c tele.Context, arg string) error {
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	userID := userIDOf(c.Sender())
+	if strings.EqualFold(arg, "all") {
+		if err := a.memories.clear(userID); err != nil {
+			a.chatLog(c.Chat().ID).Warn("save memories failed", "chat_id", c.Chat().ID, "err", err)
+			return a.replyMemories(c, tr(lang, "remember_failed"))
+		}
+		return a.replyMemories(c, tr(lang, "memories_cleared"))
+	}
+	id, err := strconv.ParseInt(strings.TrimPrefix(arg, "#"), 10, 64)
+	if err != nil {
+		return a.replyMemories(c, tr(lang, "forget_usage"))
+	}
+	removed, err := a.memories.remove(userID, id)
+	if err != nil {
+		a.chatLog(c.Chat().ID).Warn("save memories failed", "chat_id", c.Chat().ID, "err", err)
+	}
+	if !removed {
+		return a.replyMemories(c, tr(lang, "memory_unknown", id))
+	}
+	return a.replyMemories(c, tr(lang, "memory_forgot", id))
+}
+
+func (a *App) replyMemories(c tele.Context, body string) error {
+	_, err := a.sendWithFallback(c.Chat(), body, &tele.SendOptions{ThreadID: messageTopic(c.Message()), DisableWebPagePreview: true})
+	return err
+}
@@ -0,0 +1,92 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestMemoriesReachEveryChatOfTheUser(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	command := func(handler func(tele.Context) error, text, payload string) string {
+		t.Helper()
+		msg := testMessage(42, text)
+		msg.Payload = payload
+		if err := handler(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("%s: %v", text, err)
+		}
+		texts := apis.sentTexts()
+		return texts[len(texts)-1]
+	}
+
+	if got := command(app.handleMemories, "/memories", ""); !strings.Contains(got, "nothing about you") {
+		t.Errorf("/memories without memories answered %q", got)
+	}
+	command(app.handleRemember, "/remember I am vegetarian", "I am vegetarian")
+	if got := command(app.handleRemember, "/remember I am  vegetarian", "I am  vegetarian"); !strings.Contains(got, "already remember that as \\#1") {
+		t.Errorf("repeated /remember answered %q", got)
+	}
+	command(app.handleRemember, "/remember My cat is called Miso", "My cat is called Miso")
+	if got := command(app.handleMemories, "/memories", ""); !strings.Contains(got, "\\#1 · ") || !strings.Contains(got, "My cat is called Miso") {
+		t.Errorf("/memories answered %q", got)
+	}
+
+	app.sessions.remove(42)
+	if err := app.processMessage(context.Background(), testMessage(42, "Suggest a dinner."), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	calls := apis.callsTo(geminiHost, "gemini-2.5-pro:generateContent")
+	body := string(calls[len(calls)-1].body)
+	if !strings.Contains(body, "I am vegetarian") || !strings.Contains(body, "called Miso") {
+		t.Errorf("memories missing from the request after a reset: %s", body)
+	}
+
+	if got := command(app.handleForget, "/forget 1", "1"); !strings.Contains(got, "\\#1 forgotten") {
+		t.Errorf("/forget answered %q", got)
+	}
+	if got := command(app.handleForget, "/forget 1", "1"); !strings.Contains(got, "no memory \\#1") {
+		t.Errorf("second /forget answered %q", got)
+	}
+	reopened, err := openMemoryStore(filepath.Join(app.dataDir, "memories.json"))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if got := reopened.list(42); len(got) != 1 || got[0].ID != 2 {
+		t.Errorf("persisted memories = %+v", got)
+	}
+	command(app.handleForget, "/forget all", "all")
+	if got := app.memories.list(42); len(got) != 0 {
+		t.Errorf("memories after /forget all = %+v", got)
+	}
+}
+
+func TestStyleNotesMoveToMemories(t *testing.T) {
+	dir := t.TempDir()
+	raw, _ := json.Marshal(map[int64]styleProfile{
+		42: {Notes: []string{"answer in German"}},
+		7:  {Notes: []string{"no emoji"}, Votes: []styleVote{{Reply: "r", Liked: true}}},
+	})
+	if err := os.WriteFile(filepath.Join(dir, "styles.json"), raw, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	app, _ := newTestApp(t, Config{DataDir: dir})
+	if got := app.memories.list(42); len(got) != 1 || got[0].Text != "answer in German" {
+		t.Errorf("memories of 42 = %+v", got)
+	}
+	if got := app.memories.list(7); len(got) != 1 || got[0].Text != "no emoji" {
+		t.Errorf("memories of 7 = %+v", got)
+	}
+	if p := app.styles.get(7); len(p.Votes) != 1 || len(app.styles.users[7].Notes) != 0 {
+		t.Errorf("style profile of 7 = %+v", app.styles.users[7])
+	}
+
+	restarted, _ := newTestApp(t, Config{DataDir: dir, SQLitePath: "none"})
+	if got := restarted.memories.list(42); len(got) != 1 {
+		t.Errorf("notes moved twice: %+v", got)
+	}
+}
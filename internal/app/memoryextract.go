@@ -0,0 +1,161 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	memoryExtractModel   = "gemini-2.5-flash-lite"
+	memoryExtractTimeout = 20 * time.Second
+	// memoryExtractEvery is how many messages of an opted-in user pass
+	// between extractions; memoryExtractTurns how many history entries
+	// one reads.
+	memoryExtractEvery = 6
+	memoryExtractTurns = 12
+	// memoryExtractMax bounds the facts proposed at once.
+	memoryExtractMax = 3
+
+	memoryKeepUnique = "memory_keep"
+	memoryDropUnique = "memory_drop"
+)
+
+const memoryExtractInstruction = "You pick out what is worth remembering about the user from a conversation with an assistant. " +
+	"Keep only durable facts about the user and lasting preferences that would help in future, unrelated conversations: " +
+	"who they are, their work, family, pets, home, diet, tools, languages, and how they like to be answered. " +
+	"Skip one-off requests, the topic of the conversation, passing moods, what the assistant said, facts about other people, " +
+	"and secrets such as passwords, card or account numbers. Skip anything the known facts already say. " +
+	"Write each fact as one short sentence in the first person, as the user would say it, in the user's language. " +
+	"Return at most %d facts, and none when nothing qualifies; most conversations have none."
+
+func memoryExtractSchema() *genai.Schema {
+	return &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"facts": {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeString}},
+		},
+		Required: []string{"facts"},
+	}
+}
+
+// extractMemories runs after a reply to a user who opted in with /memories
+// auto on. Every few messages in their private chat, a small model reads the
+// latest turns for durable facts, which become pending memories the user
+// keeps or deletes with the buttons of a review message.
+func (a *App) extractMemories(ctx context.Context, msg *tele.Message, session *sessionState) {
+	if msg.Sender == nil || msg.Chat.Type != tele.ChatPrivate || !a.memories.auto(msg.Sender.ID) || !a.memories.due(msg.Sender.ID) {
+		return
+	}
+	session.mu.Lock()
+	turns := slices.Clone(session.history[max(0, len(session.history)-memoryExtractTurns):])
+	session.mu.Unlock()
+	conversation := transcript(turns)
+	if conversation == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, memoryExtractTimeout)
+	defer cancel()
+	var prompt strings.Builder
+	if known := a.memories.list(msg.Sender.ID); len(known) > 0 {
+		prompt.WriteString("Known facts:\n")
+		for _, m := range known {
+			prompt.WriteString("- " + m.Text + "\n")
+		}
+		prompt.WriteString("\n")
+	}
+	prompt.WriteString("Conversation:\n" + conversation)
+	cfg := &genai.GenerateContentConfig{
+		SystemInstruction: genai.NewContentFromText(fmt.Sprintf(memoryExtractInstruction, memoryExtractMax), genai.Role("system")),
+		Temperature:       genai.Ptr[float32](0),
+		ThinkingConfig:    &genai.ThinkingConfig{ThinkingBudget: genai.Ptr[int32](0)},
+		MaxOutputTokens:   256,
+		ResponseMIMEType:  "application/json",
+		ResponseSchema:    memoryExtractSchema(),
+	}
+	resp, err := a.generateWithRetry(ctx, memoryExtractModel, []*genai.Content{genai.NewContentFromText(prompt.String(), genai.RoleUser)}, cfg)
+	if err != nil {
+		logFrom(ctx).Warn("extract memories failed", "err", err)
+		return
+	}
+	a.recordUsage(ctx, msg.Chat.ID, memoryExtractModel, resp.UsageMetadata)
+	var result struct {
+		Facts []string `json:"facts"`
+	}
+	if err := json.Unmarshal([]byte(resp.Text()), &result); err != nil {
+		logFrom(ctx).Warn("decode memories failed", "err", err)
+		return
+	}
+	proposed, err := a.memories.propose(msg.Sender.ID, result.Facts[:min(len(result.Facts), memoryExtractMax)])
+	if err != nil {
+		logFrom(ctx).Warn("save memories failed", "err", err)
+	}
+	if len(proposed) == 0 {
+		return
+	}
+	logFrom(ctx).Info("extracted memories", "count", len(proposed))
+	lang := a.chatLanguage(msg.Chat, msg.Sender)
+	lines := make([]string, len(proposed))
+	for i, m := range proposed {
+		lines[i] = fmt.Sprintf("#%d · %s", m.ID, m.Text)
+	}
+	opts := &tele.SendOptions{ThreadID: messageTopic(msg), DisableWebPagePreview: true, ReplyMarkup: memoryReviewMarkup(proposed)}
+	if _, err := a.sendWithFallback(msg.Chat, tr(lang, "memories_extracted", strings.Join(lines, "\n")), opts); err != nil {
+		logFrom(ctx).Warn("send memory review failed", "err", err)
+	}
+}
+
+// memoryReviewMarkup has a keep and a delete button for each memory.
+func memoryReviewMarkup(memories []memory) *tele.ReplyMarkup {
+	markup := &tele.ReplyMarkup{}
+	rows := make([]tele.Row, len(memories))
+	for i, m := range memories {
+		id := strconv.FormatInt(m.ID, 10)
+		rows[i] = markup.Row(
+			markup.Data("✅ #"+id, memoryKeepUnique, id),
+			markup.Data("🗑 #"+id, memoryDropUnique, id),
+		)
+	}
+	markup.Inline(rows...)
+	return markup
+}
+
+// handleMemoryKeep and handleMemoryDrop review a memory extracted from a
+// conversation. Memories belong to the presser, so nobody else can review
+// them.
+func (a *App) handleMemoryKeep(c tele.Context) error { return a.reviewMemory(c, true) }
+func (a *App) handleMemoryDrop(c tele.Context) error { return a.reviewMemory(c, false) }
+
+func (a *App) reviewMemory(c tele.Context, keep bool) error {
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	id, err := strconv.ParseInt(c.Callback().Data, 10, 64)
+	if err != nil {
+		return c.Respond()
+	}
+	userID := userIDOf(c.Sender())
+	var found bool
+	if keep {
+		found, err = a.memories.confirm(userID, id)
+	} else {
+		found, err = a.memories.remove(userID, id)
+	}
+	notice := tr(lang, "memory_kept", id)
+	switch {
+	case err != nil:
+		a.chatLog(c.Chat().ID).Warn("save memories failed", "chat_id", c.Chat().ID, "err", err)
+		notice = tr(lang, "remember_failed")
+	case !found:
+		notice = tr(lang, "memory_unknown", id)
+	case !keep:
+		notice = tr(lang, "memory_forgot", id)
+	}
+	return c.Respond(&tele.CallbackResponse{Text: notice})
+}
@@ -0,0 +1,86 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestExtractedMemoriesWaitForReview(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.answer = func(_ string, body []byte) []any {
+		if strings.Contains(string(body), "worth remembering") {
+			return []any{map[string]any{"text": `{"facts":["I live in Lyon.","I have two kids.","I live in Lyon."]}`}}
+		}
+		return []any{map[string]any{"text": "Sure."}}
+	}
+	extractions := func() int {
+		n := 0
+		for _, call := range apis.callsTo(geminiHost, memoryExtractModel) {
+			if strings.Contains(string(call.body), "worth remembering") {
+				n++
+			}
+		}
+		return n
+	}
+	chat := func(n int) {
+		t.Helper()
+		for range n {
+			if err := app.processMessage(context.Background(), testMessage(42, "We moved to Lyon with our two kids."), turnOptions{}); err != nil {
+				t.Fatalf("processMessage: %v", err)
+			}
+		}
+	}
+
+	chat(memoryExtractEvery)
+	if got := extractions(); got != 0 {
+		t.Fatalf("extracted %d times without the opt-in", got)
+	}
+	msg := testMessage(42, "/memories auto on")
+	msg.Payload = "auto on"
+	if err := app.handleMemories(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+		t.Fatalf("handleMemories: %v", err)
+	}
+	chat(memoryExtractEvery - 1)
+	if got := extractions(); got != 0 {
+		t.Fatalf("extracted %d times before %d messages", got, memoryExtractEvery)
+	}
+	chat(1)
+	if got := extractions(); got != 1 {
+		t.Fatalf("extracted %d times, want once", got)
+	}
+	pending := app.memories.list(42)
+	if len(pending) != 2 || !pending[0].Pending || !pending[1].Pending {
+		t.Fatalf("memories = %+v, want two pending", pending)
+	}
+	texts := apis.sentTexts()
+	if review := texts[len(texts)-1]; !strings.Contains(review, "picked up these facts") || !strings.Contains(review, "I live in Lyon") {
+		t.Errorf("review message = %q", review)
+	}
+	if got := app.memoryInstruction(testMessage(42, "")); got != "" {
+		t.Errorf("pending memories reach the model: %q", got)
+	}
+
+	keep := actionCallback(app, 42, 42, "1")
+	if err := app.handleMemoryKeep(keep); err != nil {
+		t.Fatalf("handleMemoryKeep: %v", err)
+	}
+	if err := app.handleMemoryDrop(actionCallback(app, 42, 7, "2")); err != nil {
+		t.Fatalf("handleMemoryDrop: %v", err)
+	}
+	calls := apis.callsTo(telegramHost, "answerCallbackQuery")
+	if got := string(calls[len(calls)-1].body); !strings.Contains(got, "no memory") {
+		t.Errorf("another user's delete answered %s", got)
+	}
+	if err := app.handleMemoryDrop(actionCallback(app, 42, 42, "2")); err != nil {
+		t.Fatalf("handleMemoryDrop: %v", err)
+	}
+	if got := app.memories.list(42); len(got) != 1 || got[0].Pending || got[0].Text != "I live in Lyon." {
+		t.Errorf("memories after review = %+v", got)
+	}
+	if got := app.memoryInstruction(testMessage(42, "")); !strings.Contains(got, "I live in Lyon.") {
+		t.Errorf("kept memory missing from the instruction: %q", got)
+	}
+}
@@ -0,0 +1,57 @@
+package app
+
+import (
+	"log/slog"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// moveKey moves the value of from to to, unless to already has one.
+func moveKey[V any](m map[int64]V, from, to int64) {
+	v, ok := m[from]
+	if !ok {
+		return
+	}
+	delete(m, from)
+	if _, taken := m[to]; !taken {
+		m[to] = v
+	}
+}
+
+// handleMigration follows a group that Telegram upgraded to a supergroup,
+// which gives it a new chat ID.
+func (a *App) handleMigration(c tele.Context) error {
+	from, to := c.Migration()
+	if from == 0 || to == 0 || from == to {
+		return nil
+	}
+	a.migrateChat(from, to)
+	return nil
+}
+
+// migrateChat moves everything the bot keeps about chat from to chat to:
+// the session, settings, usage, reminders, send targets, indexed repository
+// and knowledge base.
+func (a *App) migrateChat(from, to int64) {
+	warn := func(what string, err error) {
+		if err != nil {
+			slog.Warn("migrate chat failed", "from", from, "to", to, "store", what, "err", err)
+		}
+	}
+	a.sessions.migrate(from, to)
+	a.catchup.migrate(from, to)
+	a.usage.migrate(from, to)
+	a.repos.migrate(from, to)
+	a.artifacts.migrate(from, to)
+	warn("sampling", a.sampling.migrate(from, to))
+	warn("topics", a.topics.migrate(from, to))
+	warn("reminders", a.reminders.migrate(from, to))
+	warn("send targets", a.sendTargets.migrate(from, to))
+	warn("admin state", a.operator.migrate(from, to))
+	warn("knowledge base", a.kb.migrate(from, to))
+	warn("recall", a.recall.migrate(from, to))
+	warn("privacy", a.privacy.migrate(from, to))
+	warn("ephemeral", a.ephemeral.migrate(from, to))
+	warn("reply mode", a.replyTo.migrate(from, to))
+	slog.Info("chat migrated to a supergroup", "from", from, "to", to)
+}
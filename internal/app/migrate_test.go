@@ -0,0 +1,77 @@
+package app
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestMigrationKeepsChatState(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.reply = "Noted."
+	const group, supergroup = -100, -1001234
+
+	msg := testMessage(group, "Our sprint ends Friday.")
+	msg.Chat.Type = tele.ChatGroup
+	if err := app.processMessage(context.Background(), msg, turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	if _, err := app.sampling.update(group, func(s *samplingSettings) { s.TopP = genai.Ptr[float32](0.5) }); err != nil {
+		t.Fatalf("update sampling: %v", err)
+	}
+	if _, err := app.reminders.add(reminder{ChatID: group, ChatType: string(tele.ChatGroup), UserID: 42, Due: time.Now().Add(time.Hour), Text: "demo"}); err != nil {
+		t.Fatalf("add reminder: %v", err)
+	}
+	if err := app.sendTargets.save(42, "team", sendTarget{ChatID: group, Title: "Team"}); err != nil {
+		t.Fatalf("save target: %v", err)
+	}
+	if err := app.operator.seeChat(group); err != nil {
+		t.Fatalf("seeChat: %v", err)
+	}
+
+	migration := &tele.Message{Chat: &tele.Chat{ID: group, Type: tele.ChatGroup}, MigrateFrom: group, MigrateTo: supergroup}
+	if err := app.handleMigration(app.bot.NewContext(tele.Update{Message: migration})); err != nil {
+		t.Fatalf("handleMigration: %v", err)
+	}
+
+	session := app.sessions.get(supergroup)
+	session.mu.Lock()
+	history := len(session.history)
+	session.mu.Unlock()
+	if history != 2 {
+		t.Errorf("supergroup session has %d entries, want the group's 2", history)
+	}
+	if app.sampling.get(supergroup).TopP == nil || !app.sampling.get(group).isZero() {
+		t.Error("sampling settings not moved")
+	}
+	if list := app.reminders.pending(supergroup, 42, false); len(list) != 1 || list[0].ChatType != string(tele.ChatSuperGroup) {
+		t.Errorf("reminders after migration = %+v", list)
+	}
+	if target, ok := app.sendTargets.get(42, "team"); !ok || target.ChatID != supergroup {
+		t.Errorf("send target after migration = %+v", target)
+	}
+	if _, month := app.usage.chatSnapshot(supergroup); month.Requests == 0 {
+		t.Error("usage not moved")
+	}
+	if chats := app.operator.knownChats(); len(chats) != 1 || chats[0] != supergroup {
+		t.Errorf("known chats = %v, want the supergroup", chats)
+	}
+}
+
+func TestMigratedSendErrorMovesChat(t *testing.T) {
+	app, _ := newTestApp(t, Config{})
+	if _, err := app.sampling.update(-100, func(s *samplingSettings) { s.TopP = genai.Ptr[float32](0.5) }); err != nil {
+		t.Fatalf("update sampling: %v", err)
+	}
+	err := tele.GroupError{MigratedTo: -1005}
+	if fault, _ := classifyTelegramError(err); fault != faultMigrated {
+		t.Fatalf("classified as %q", fault)
+	}
+	app.telegramFailed("send", -100, err)
+	if app.sampling.get(-1005).TopP == nil {
+		t.Error("a migration reported by a send did not move the chat")
+	}
+}
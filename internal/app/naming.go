@@ -0,0 +1,61 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/genai"
+)
+
+const (
+	namingModel   = "gemini-2.5-flash-lite"
+	namingTimeout = 10 * time.Second
+	// namingSampleRunes is how much of a document the naming model reads.
+	namingSampleRunes = 4000
+)
+
+// exportName asks a small model for a file name that describes content, such as
+// "q3-sales-analysis.md", and falls back to fallbackTitle when it cannot.
+func (a *App) exportName(ctx context.Context, chatID int64, fallbackTitle, ext, content string) string {
+	ctx, cancel := context.WithTimeout(ctx, namingTimeout)
+	defer cancel()
+
+	sample := strings.TrimSpace(content)
+	if utf8.RuneCountInString(sample) > namingSampleRunes {
+		sample = string([]rune(sample)[:namingSampleRunes])
+	}
+	if sample == "" {
+		return exportFileName(fallbackTitle, ext)
+	}
+
+	instruction := strings.Join([]string{
+		"Name the file that holds the document below.",
+		"Reply with 2 to 6 lowercase words joined by dashes that say what it is about, such as q3-sales-analysis or berlin-trip-packing-list.",
+		"Use the language of the document, no extension, no dates unless the document is about one, and nothing else.",
+	}, " ")
+	contents := []*genai.Content{genai.NewContentFromText(sample, genai.RoleUser)}
+	cfg := &genai.GenerateContentConfig{
+		SystemInstruction: genai.NewContentFromText(instruction, genai.Role("system")),
+		Temperature:       genai.Ptr[float32](0),
+		MaxOutputTokens:   32,
+	}
+	resp, err := a.generateWithRetry(ctx, namingModel, contents, cfg)
+	if err != nil {
+		logFrom(ctx).Warn("export naming failed", "err", err)
+		return exportFileName(fallbackTitle, ext)
+	}
+	a.recordUsage(ctx, chatID, namingModel, resp.UsageMetadata)
+
+	name := strings.TrimSpace(resp.Text())
+	if line, _, _ := strings.Cut(name, "\n"); line != "" {
+		name = line
+	}
+	name = strings.TrimSuffix(strings.Trim(name, "`\"' ."), ext)
+	if strings.Count(exportFileName(name, ""), "-") > 7 {
+		// A sentence rather than a name; the title reads better.
+		return exportFileName(fallbackTitle, ext)
+	}
+	return exportFileName(name, ext)
+}
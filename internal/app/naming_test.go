@@ -0,0 +1,28 @@
+package app
+
+import (
+	"context"
+	"testing"
+)
+
+func TestExportNameFromContent(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	tests := []struct {
+		content, answer, want string
+	}{
+		{"Revenue grew 12% in Q3...", "`Q3-Sales-Analysis.md`\n", "q3-sales-analysis.md"},
+		{"Notes about the weekend", "This document is a collection of notes the user wrote about their weekend plans", "weekend-notes.md"},
+		{"", "unused", "weekend-notes.md"},
+	}
+	for _, tt := range tests {
+		apis.answer = func(model string, body []byte) []any {
+			if model != namingModel {
+				t.Errorf("naming used %s, want %s", model, namingModel)
+			}
+			return []any{map[string]any{"text": tt.answer}}
+		}
+		if got := app.exportName(context.Background(), 42, "Weekend notes", ".md", tt.content); got != tt.want {
+			t.Errorf("exportName(%q) with answer %q = %q, want %q", tt.content, tt.answer, got, tt.want)
+		}
+	}
+}
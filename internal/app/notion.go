@@ -0,0 +1,273 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	saveNotionUnique   = "save_notion"
+	notionPrefKey      = "notion"
+	notionPagesURL     = "https://api.notion.com/v1/pages"
+	notionVersion      = "2022-06-28"
+	notionTextLimit    = 2000
+	notionMaxBlocks    = 100
+	notionCodeLanguage = "plain text"
+)
+
+// notionAccount holds a user's integration token and the page new notes go under.
+type notionAccount struct {
+	Token    string `json:"token"`
+	ParentID string `json:"parent_id"`
+}
+
+var notionIDPattern = regexp.MustCompile(`[0-9a-fA-F]{32}|[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+
+func notionRichText(text string) []map[string]any {
+	var out []map[string]any
+	runes := []rune(text)
+	for len(runes) > 0 {
+		n := min(len(runes), notionTextLimit)
+		out = append(out, map[string]any{"type": "text", "text": map[string]any{"content": string(runes[:n])}})
+		runes = runes[n:]
+	}
+	return out
+}
+
+func notionLink(title, url string) []map[string]any {
+	return []map[string]any{{
+		"type": "text",
+		"text": map[string]any{"content": title, "link": map[string]any{"url": url}},
+	}}
+}
+
+// notionLanguages maps common fence tags onto Notion's fixed language list;
+// Notion rejects the whole page if a code block names an unknown language.
+var notionLanguages = map[string]string{
+	"bash": "bash", "sh": "shell", "shell": "shell", "c": "c", "cpp": "c++", "c++": "c++",
+	"csharp": "c#", "cs": "c#", "css": "css", "go": "go", "golang": "go", "html": "html",
+	"java": "java", "js": "javascript", "javascript": "javascript", "json": "json",
+	"kotlin": "kotlin", "markdown": "markdown", "md": "markdown", "php": "php",
+	"py": "python", "python": "python", "rb": "ruby", "ruby": "ruby", "rust": "rust",
+	"rs": "rust", "sql": "sql", "swift": "swift", "ts": "typescript",
+	"typescript": "typescript", "yaml": "yaml", "yml": "yaml", "xml": "xml",
+}
+
+func notionLanguage(tag string) string {
+	if lang, ok := notionLanguages[strings.ToLower(tag)]; ok {
+		return lang
+	}
+	return notionCodeLanguage
+}
+
+func notionBlock(kind string, body map[string]any) map[string]any {
+	return map[string]any{"object": "block", "type": kind, kind: body}
+}
+
+// notionBlocks maps the reply's Markdown structure onto Notion block types.
+func notionBlocks(art *responseArtifacts) []map[string]any {
+	var blocks []map[string]any
+	lines := strings.Split(strings.ReplaceAll(art.Reply, "\r\n", "\n"), "\n")
+	var paragraph []string
+	flush := func() {
+		if text := strings.TrimSpace(strings.Join(paragraph, "\n")); text != "" {
+			blocks = append(blocks, notionBlock("paragraph", map[string]any{"rich_text": notionRichText(stripInlineMarkers(text))}))
+		}
+		paragraph = nil
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+		if fence, lang, ok := openingFence(trimmed); ok {
+			flush()
+			var code []string
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), fence); i++ {
+				code = append(code, lines[i])
+			}
+			blocks = append(blocks, notionBlock("code", map[string]any{
+				"rich_text": notionRichText(strings.Join(code, "\n")),
+				"language":  notionLanguage(lang),
+			}))
+			continue
+		}
+		switch {
+		case trimmed == "":
+			flush()
+		case headingPattern.MatchString(line):
+			flush()
+			m := headingPattern.FindStringSubmatch(line)
+			level := min(len(m[1]), 3)
+			kind := fmt.Sprintf("heading_%d", level)
+			blocks = append(blocks, notionBlock(kind, map[string]any{"rich_text": notionRichText(stripInlineMarkers(m[2]))}))
+		case bulletPattern.MatchString(line):
+			flush()
+			m := bulletPattern.FindStringSubmatch(line)
+			blocks = append(blocks, notionBlock("bulleted_list_item", map[string]any{"rich_text": notionRichText(stripInlineMarkers(m[3]))}))
+		case orderedPattern.MatchString(line):
+			flush()
+			m := orderedPattern.FindStringSubmatch(line)
+			blocks = append(blocks, notionBlock("numbered_list_item", map[string]any{"rich_text": notionRichText(stripInlineMarkers(m[3]))}))
+		default:
+			paragraph = append(paragraph, line)
+		}
+	}
+	flush()
+
+	if len(art.Sources) > 0 {
+		blocks = append(blocks, notionBlock("heading_2", map[string]any{"rich_text": notionRichText("Sources")}))
+		for _, src := range art.Sources {
+			title := src.Title
+			if title == "" {
+				title = src.URI
+			}
+			blocks = append(blocks, notionBlock("bulleted_list_item", map[string]any{"rich_text": notionLink(title, src.URI)}))
+		}
+	}
+	if len(blocks) > notionMaxBlocks {
+		blocks = blocks[:notionMaxBlocks]
+	}
+	return blocks
+}
+
+func (acct notionAccount) createPage(ctx context.Context, art *responseArtifacts) (string, error) {
+	payload := map[string]any{
+		"parent": map[string]any{"page_id": acct.ParentID},
+		"properties": map[string]any{
+			"title": map[string]any{"title": notionRichText(exportTitle(art))},
+		},
+		"children": notionBlocks(art),
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, notionPagesURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+acct.Token)
+	req.Header.Set("Notion-Version", notionVersion)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := toolHTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("notion: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("notion: status %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var created struct {
+		URL string `json:"url"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", fmt.Errorf("notion: decode response: %w", err)
+	}
+	return created.URL, nil
+}
+
+func (a *App) handleNotion(c tele.Context) error {
+	msg := c.Message()
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	if a.prefs == nil {
+		_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), tr(lang, "notion_off"), &tele.SendOptions{DisableWebPagePreview: true})
+		return err
+	}
+	if msg.Sender == nil {
+		return nil
+	}
+
+	args := strings.Fields(msg.Payload)
+	var body string
+	switch {
+	case len(args) == 0 || args[0] == "status":
+		var acct notionAccount
+		if ok, err := a.prefs.get(msg.Sender.ID, notionPrefKey, &acct); err == nil && ok {
+			body = tr(lang, "notion_status", acct.ParentID)
+		} else {
+			body = tr(lang, "notion_none")
+		}
+	case args[0] == "connect":
+		if len(args) != 3 {
+			body = tr(lang, "notion_conn_use")
+			break
+		}
+		if err := a.bot.Delete(msg); err != nil {
+			a.telegramFailed("delete credentials message", c.Chat().ID, err)
+		}
+		if c.Chat().Type != tele.ChatPrivate {
+			body = tr(lang, "notion_unsafe")
+			break
+		}
+		parent := notionIDPattern.FindString(args[2])
+		if parent == "" {
+			body = tr(lang, "notion_bad_page")
+			break
+		}
+		if err := a.prefs.set(msg.Sender.ID, notionPrefKey, notionAccount{Token: args[1], ParentID: parent}); err != nil {
+			return err
+		}
+		body = tr(lang, "notion_on")
+	case args[0] == "disconnect":
+		if err := a.prefs.delete(msg.Sender.ID, notionPrefKey); err != nil {
+			return err
+		}
+		body = tr(lang, "notion_removed")
+	default:
+		body = tr(lang, "notion_usage")
+	}
+	_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), body, &tele.SendOptions{DisableWebPagePreview: true})
+	return err
+}
+
+func (a *App) handleSaveNotion(c tele.Context) error {
+	if err := c.Respond(); err != nil {
+		slog.Warn("callback acknowledge failed", "err", err)
+	}
+	if a.prefs == nil || c.Sender() == nil {
+		return nil
+	}
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	art, ok := a.artifacts.get(c.Callback().Data)
+	if !ok || strings.TrimSpace(art.Reply) == "" {
+		_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), tr(lang, "export_gone"), &tele.SendOptions{DisableWebPagePreview: true})
+		return err
+	}
+
+	var acct notionAccount
+	found, err := a.prefs.get(c.Sender().ID, notionPrefKey, &acct)
+	if err != nil {
+		return err
+	}
+	if !found {
+		_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), tr(lang, "notion_first"), &tele.SendOptions{DisableWebPagePreview: true})
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	url, err := acct.createPage(ctx, art)
+	body := tr(lang, "notion_saved", url)
+	if err != nil {
+		a.chatLog(c.Chat().ID).Warn("save to notion failed", "chat_id", c.Chat().ID, "err", err)
+		body = tr(lang, "notion_failed")
+		if errors.Is(err, context.DeadlineExceeded) {
+			body = tr(lang, "notion_timeout")
+		}
+	}
+	_, sendErr := a.sendWithFallback(inTopic(c.Chat(), c.Message()), body, &tele.SendOptions{DisableWebPagePreview: true})
+	return sendErr
+}
@@ -0,0 +1,129 @@
+package app
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestNotionBlocks(t *testing.T) {
+	art := &responseArtifacts{
+		Reply:   "# Plan\nFirst **bold** line\ncontinues here.\n\n- one\n2. two\n```golang\nfmt.Println()\n```\n```brainfuck\n+.\n```",
+		Sources: []sourceRef{{Title: "Go", URI: "https://go.dev"}, {URI: "https://example.com"}},
+	}
+	blocks := notionBlocks(art)
+	var kinds []string
+	for _, b := range blocks {
+		kinds = append(kinds, b["type"].(string))
+	}
+	want := "heading_1 paragraph bulleted_list_item numbered_list_item code code heading_2 bulleted_list_item bulleted_list_item"
+	if got := strings.Join(kinds, " "); got != want {
+		t.Fatalf("blocks = %s, want %s", got, want)
+	}
+	text := func(i int) string {
+		rich := blocks[i][kinds[i]].(map[string]any)["rich_text"].([]map[string]any)
+		return rich[0]["text"].(map[string]any)["content"].(string)
+	}
+	if got := text(1); got != "First bold line\ncontinues here." {
+		t.Errorf("paragraph = %q", got)
+	}
+	for i, lang := range map[int]string{4: "go", 5: notionCodeLanguage} {
+		if got := blocks[i]["code"].(map[string]any)["language"]; got != lang {
+			t.Errorf("code block %d language = %v, want %q", i, got, lang)
+		}
+	}
+	if got := text(8); got != "https://example.com" {
+		t.Errorf("untitled source = %q, want its URL", got)
+	}
+
+	long := notionBlocks(&responseArtifacts{Reply: strings.Repeat("x", 2*notionTextLimit+1)})
+	if rich := long[0]["paragraph"].(map[string]any)["rich_text"].([]map[string]any); len(rich) != 3 {
+		t.Errorf("a long paragraph has %d rich text parts, want 3", len(rich))
+	}
+	many := notionBlocks(&responseArtifacts{Reply: strings.Repeat("- item\n", notionMaxBlocks+10)})
+	if len(many) != notionMaxBlocks {
+		t.Errorf("%d blocks, want the cap of %d", len(many), notionMaxBlocks)
+	}
+}
+
+func TestSaveToNotion(t *testing.T) {
+	app, apis := newTestApp(t, Config{PrefsEncryptionKey: "test-key"})
+	const userID = 7
+	status := http.StatusOK
+	var page map[string]any
+	apis.handle("api.notion.com", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret_token" || r.Header.Get("Notion-Version") != notionVersion {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &page)
+		if status != http.StatusOK {
+			http.Error(w, `{"message":"page not shared"}`, status)
+			return
+		}
+		w.Write([]byte(`{"url":"https://www.notion.so/new-page"}`))
+	}))
+	notion := func(chat *tele.Chat, payload string) {
+		t.Helper()
+		msg := &tele.Message{ID: 5, Chat: chat, Sender: &tele.User{ID: userID}, Text: "/notion " + payload, Payload: payload}
+		if err := app.handleNotion(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("handleNotion(%q): %v", payload, err)
+		}
+	}
+	id := app.artifacts.put(&responseArtifacts{ChatID: userID, Prompt: "Weekend plan", Reply: "- hike\n- read"})
+	save := func() string {
+		t.Helper()
+		if err := app.handleSaveNotion(actionCallback(app, userID, userID, id)); err != nil {
+			t.Fatalf("handleSaveNotion: %v", err)
+		}
+		texts := apis.sentTexts()
+		return strings.ReplaceAll(texts[len(texts)-1], `\`, "")
+	}
+	private := &tele.Chat{ID: userID, Type: tele.ChatPrivate}
+	const parent = "0123456789abcdef0123456789abcdef"
+
+	if got := save(); !strings.Contains(got, "Connect Notion first") {
+		t.Errorf("save before connecting sent %q", got)
+	}
+	notion(&tele.Chat{ID: -100, Type: tele.ChatSuperGroup}, "connect secret_token https://notion.so/Page-"+parent)
+	if texts := apis.sentTexts(); !containsText(texts, "connect Notion in a private chat") {
+		t.Errorf("connecting in a group sent %q, want the safety notice", texts)
+	}
+	if deleted := apis.callsTo(telegramHost, "deleteMessage"); len(deleted) != 1 {
+		t.Errorf("deleted %d messages, want the one with the token", len(deleted))
+	}
+	notion(private, "connect secret_token https://notion.so/no-id-here")
+	notion(private, "connect secret_token https://notion.so/Page-"+parent)
+	if texts := apis.sentTexts(); !containsText(texts, "Could not find a Notion page ID") || !containsText(texts, "Notion connected") {
+		t.Errorf("connecting sent %q", texts)
+	}
+
+	if got := save(); !strings.Contains(got, "Saved to Notion: https://www.notion.so/new-page") {
+		t.Errorf("save sent %q", got)
+	}
+	if got := page["parent"].(map[string]any)["page_id"]; got != parent {
+		t.Errorf("page created under %v, want %s", got, parent)
+	}
+	if children := page["children"].([]any); len(children) != 2 {
+		t.Errorf("page has %d blocks, want the two bullets", len(children))
+	}
+	status = http.StatusBadRequest
+	if got := save(); !strings.Contains(got, "Could not save to Notion") {
+		t.Errorf("failed save sent %q", got)
+	}
+
+	notion(private, "disconnect")
+	notion(private, "status")
+	if texts := apis.sentTexts(); !containsText(texts, "Notion is not connected") {
+		t.Errorf("status after disconnecting sent %q", texts)
+	}
+	id = "gone"
+	if got := save(); !strings.Contains(got, "no longer available") {
+		t.Errorf("saving an expired answer sent %q", got)
+	}
+}
@@ -0,0 +1,162 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const ocrCommand = "/ocr"
+
+const ocrInstruction = "Extract the text of every page verbatim, in reading order and in its original language. " +
+	"Do not describe, summarize, translate or correct anything. Keep line breaks, paragraphs, lists and table rows " +
+	"as they appear, with table cells separated by \" | \". Mark illegible words as [illegible]. " +
+	"Return an empty text for a page without text."
+
+func ocrSchema() *genai.Schema {
+	return &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"pages": {
+				Type: genai.TypeArray,
+				Items: &genai.Schema{
+					Type: genai.TypeObject,
+					Properties: map[string]*genai.Schema{
+						"page": {Type: genai.TypeInteger},
+						"text": {Type: genai.TypeString},
+					},
+					Required:         []string{"page", "text"},
+					PropertyOrdering: []string{"page", "text"},
+				},
+			},
+		},
+		Required: []string{"pages"},
+	}
+}
+
+type ocrPage struct {
+	Page int    `json:"page"`
+	Text string `json:"text"`
+}
+
+// scan returns the photo, image document or PDF of msg with its MIME type.
+func scan(msg *tele.Message) (*tele.File, string, bool) {
+	switch {
+	case msg == nil:
+	case msg.Photo != nil:
+		return msg.Photo.MediaFile(), "image/jpeg", true
+	case msg.Document != nil && (strings.HasPrefix(msg.Document.MIME, "image/") || msg.Document.MIME == "application/pdf"):
+		return msg.Document.MediaFile(), msg.Document.MIME, true
+	}
+	return nil, "", false
+}
+
+// handleOCR returns the verbatim text of a photo or scan, page by page for
+// PDFs, rather than the description a plain message would get:
+//
+//	/ocr as a reply to a photo, image or PDF
+//	a photo, image or PDF captioned /ocr
+func (a *App) handleOCR(c tele.Context) error {
+	msg := c.Message()
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	source := msg
+	if _, _, ok := scan(source); !ok {
+		source = msg.ReplyTo
+	}
+	if _, _, ok := scan(source); !ok {
+		_, err := a.sendWithFallback(c.Chat(), tr(lang, "ocr_usage"), &tele.SendOptions{ThreadID: messageTopic(msg), DisableWebPagePreview: true})
+		return err
+	}
+	return a.enqueueJob(msg, func(ctx context.Context) error {
+		return a.ocr(ctx, msg, source)
+	})
+}
+
+// ocr sends the text of the scan in source to the chat of msg: as a message
+// when it is short, else as a .txt document.
+func (a *App) ocr(ctx context.Context, msg, source *tele.Message) error {
+	lang := a.chatLanguage(msg.Chat, msg.Sender)
+	opts := &tele.SendOptions{ThreadID: messageTopic(msg), ReplyTo: source, DisableWebPagePreview: true}
+	fail := func(key string) error {
+		_, err := a.sendWithFallback(msg.Chat, tr(lang, key), opts)
+		return err
+	}
+
+	file, mimeType, _ := scan(source)
+	ctx, cancel := context.WithTimeout(withChatID(ctx, msg.Chat.ID), 5*time.Minute)
+	defer cancel()
+	part, err := a.partFromFile(ctx, file, mimeType)
+	if err != nil {
+		logFrom(ctx).Warn("read scan failed", "err", err)
+		if errors.Is(err, errFileTooLarge) {
+			return fail("file_too_large")
+		}
+		return fail("input_failed")
+	}
+	cfg := &genai.GenerateContentConfig{
+		SystemInstruction: genai.NewContentFromText(ocrInstruction, genai.Role("system")),
+		ResponseMIMEType:  "application/json",
+		ResponseSchema:    ocrSchema(),
+		Temperature:       genai.Ptr[float32](0),
+	}
+	contents := []*genai.Content{genai.NewContentFromParts([]*genai.Part{part, genai.NewPartFromText("Extract the text.")}, genai.RoleUser)}
+	resp, model, err := a.generate(ctx, contents, cfg)
+	if err != nil {
+		logFrom(ctx).Warn("ocr failed", "err", err)
+		return fail("ocr_failed")
+	}
+	a.recordUsage(ctx, msg.Chat.ID, model, resp.UsageMetadata)
+	var result struct {
+		Pages []ocrPage `json:"pages"`
+	}
+	if err := json.Unmarshal([]byte(resp.Text()), &result); err != nil {
+		logFrom(ctx).Warn("decode ocr failed", "err", err)
+		return fail("ocr_failed")
+	}
+	text := ocrText(lang, result.Pages)
+	if text == "" {
+		return fail("ocr_empty")
+	}
+	if len(text) <= longMessagePart {
+		_, err := a.sendWithFallback(msg.Chat, text, opts)
+		return err
+	}
+	return a.sendTextDocument(msg.Chat, ocrName(source), "text/plain", text+"\n", tr(lang, "ocr_caption"), opts)
+}
+
+// ocrText joins the pages, headed by their numbers when there are several.
+func ocrText(lang language, pages []ocrPage) string {
+	var kept []ocrPage
+	for _, p := range pages {
+		if p.Text = strings.TrimSpace(p.Text); p.Text != "" {
+			kept = append(kept, p)
+		}
+	}
+	if len(kept) == 1 && len(pages) == 1 {
+		return kept[0].Text
+	}
+	var sections []string
+	for i, p := range kept {
+		page := p.Page
+		if page <= 0 {
+			page = i + 1
+		}
+		sections = append(sections, fmt.Sprintf("— %s —\n%s", tr(lang, "ocr_page", page), p.Text))
+	}
+	return strings.Join(sections, "\n\n")
+}
+
+// ocrName names the text file after the scanned file.
+func ocrName(msg *tele.Message) string {
+	if msg.Document != nil && msg.Document.FileName != "" {
+		return strings.TrimSuffix(msg.Document.FileName, filepath.Ext(msg.Document.FileName)) + ".txt"
+	}
+	return "scan.txt"
+}
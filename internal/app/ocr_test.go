@@ -0,0 +1,58 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestOCRPagesOfPDFCaption(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.files = map[string]string{"scan": "%PDF-1.4"}
+	apis.answer = func(string, []byte) []any {
+		return []any{map[string]any{"text": `{"pages":[{"page":1,"text":"INVOICE 42\nTotal | 10 EUR"},{"page":2,"text":""},{"page":3,"text":"Thank you."}]}`}}
+	}
+
+	msg := testMessage(42, "")
+	msg.Caption = "/ocr"
+	msg.Document = &tele.Document{File: tele.File{FileID: "scan", UniqueID: "scan"}, MIME: "application/pdf", FileName: "invoice.pdf"}
+	if err := app.handleUserMessage(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+		t.Fatalf("handleUserMessage: %v", err)
+	}
+	app.queue.drain(context.Background())
+
+	calls := apis.callsTo(geminiHost, ":generateContent")
+	if len(calls) != 1 || !strings.Contains(string(calls[0].body), "verbatim") || !strings.Contains(string(calls[0].body), "application/pdf") {
+		t.Fatalf("generateContent calls = %d, want one OCR request with the PDF", len(calls))
+	}
+	want := escapeMarkdownV2("— Page 1 —\nINVOICE 42\nTotal | 10 EUR\n\n— Page 3 —\nThank you.")
+	if texts := apis.sentTexts(); len(texts) != 1 || texts[0] != want {
+		t.Errorf("sent %q, want %q", texts, want)
+	}
+}
+
+func TestOCRSinglePhotoAndUsage(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.files = map[string]string{"photo": "\xff\xd8\xff"}
+	apis.answer = func(string, []byte) []any {
+		return []any{map[string]any{"text": `{"pages":[{"page":1,"text":"No parking"}]}`}}
+	}
+	if err := app.handleOCR(app.bot.NewContext(tele.Update{Message: testMessage(42, "/ocr")})); err != nil {
+		t.Fatalf("handleOCR: %v", err)
+	}
+	photo := testMessage(42, "")
+	photo.Photo = &tele.Photo{File: tele.File{FileID: "photo", UniqueID: "photo"}}
+	msg := testMessage(42, "/ocr")
+	msg.ReplyTo = photo
+	if err := app.handleOCR(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+		t.Fatalf("handleOCR: %v", err)
+	}
+	app.queue.drain(context.Background())
+
+	texts := apis.sentTexts()
+	if len(texts) != 2 || !strings.Contains(texts[0], "Reply /ocr") || texts[1] != "No parking" {
+		t.Errorf("sent %q, want the usage and the text", texts)
+	}
+}
@@ -0,0 +1,325 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// zipFiles indexes an archive's entries by their slash separated name.
+func zipFiles(data []byte) (map[string]*zip.File, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	return files, nil
+}
+
+func openZipEntry(files map[string]*zip.File, name string) (io.ReadCloser, error) {
+	f, ok := files[name]
+	if !ok {
+		return nil, fmt.Errorf("missing %s", name)
+	}
+	return f.Open()
+}
+
+func decodeZipXML(files map[string]*zip.File, name string, out any) error {
+	rc, err := openZipEntry(files, name)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(out)
+}
+
+func extractDOCXText(data []byte) (string, error) {
+	files, err := zipFiles(data)
+	if err != nil {
+		return "", err
+	}
+	rc, err := openZipEntry(files, "word/document.xml")
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+
+	var b strings.Builder
+	dec := xml.NewDecoder(rc)
+	inText := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				b.WriteString("\t")
+			case "br", "cr":
+				b.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				b.WriteString("\n")
+			case "tc":
+				b.WriteString("\t")
+			}
+		case xml.CharData:
+			if inText {
+				b.Write(t)
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+type xlsxRels struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRichText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (r xlsxRichText) String() string {
+	if len(r.Runs) == 0 {
+		return r.Text
+	}
+	var b strings.Builder
+	for _, run := range r.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string        `xml:"r,attr"`
+			Type   string        `xml:"t,attr"`
+			Value  string        `xml:"v"`
+			Inline *xlsxRichText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// extractXLSXText renders every sheet as tab separated rows under its name.
+func extractXLSXText(data []byte) (string, error) {
+	files, err := zipFiles(data)
+	if err != nil {
+		return "", err
+	}
+
+	var shared struct {
+		Items []xlsxRichText `xml:"si"`
+	}
+	if _, ok := files["xl/sharedStrings.xml"]; ok {
+		if err := decodeZipXML(files, "xl/sharedStrings.xml", &shared); err != nil {
+			return "", fmt.Errorf("shared strings: %w", err)
+		}
+	}
+	var workbook xlsxWorkbook
+	if err := decodeZipXML(files, "xl/workbook.xml", &workbook); err != nil {
+		return "", fmt.Errorf("workbook: %w", err)
+	}
+	var rels xlsxRels
+	if err := decodeZipXML(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", fmt.Errorf("workbook relationships: %w", err)
+	}
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		target := strings.TrimPrefix(rel.Target, "/")
+		if !strings.HasPrefix(target, "xl/") {
+			target = path.Join("xl", target)
+		}
+		targets[rel.ID] = target
+	}
+
+	var b strings.Builder
+	for _, sheet := range workbook.Sheets {
+		var ws xlsxSheet
+		if err := decodeZipXML(files, targets[sheet.RID], &ws); err != nil {
+			return "", fmt.Errorf("sheet %q: %w", sheet.Name, err)
+		}
+		fmt.Fprintf(&b, "## Sheet: %s\n", sheet.Name)
+		for _, row := range ws.Rows {
+			var cells []string
+			for _, cell := range row.Cells {
+				if col := xlsxColumn(cell.Ref); col > len(cells) {
+					cells = append(cells, make([]string, col-len(cells))...)
+				}
+				value := cell.Value
+				switch cell.Type {
+				case "s":
+					if i, err := strconv.Atoi(value); err == nil && i >= 0 && i < len(shared.Items) {
+						value = shared.Items[i].String()
+					}
+				case "inlineStr":
+					if cell.Inline != nil {
+						value = cell.Inline.String()
+					}
+				case "b":
+					value = map[string]string{"0": "FALSE", "1": "TRUE"}[value]
+				}
+				cells = append(cells, strings.ReplaceAll(value, "\n", " "))
+			}
+			if line := strings.TrimRight(strings.Join(cells, "\t"), "\t"); line != "" {
+				b.WriteString(line)
+				b.WriteString("\n")
+			}
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+// xlsxColumn returns the zero based column of a cell reference such as "C7".
+func xlsxColumn(ref string) int {
+	col := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		col = col*26 + int(r-'A'+1)
+	}
+	return max(col-1, 0)
+}
+
+// extractEPUBText follows the package spine and flattens each chapter's XHTML.
+func extractEPUBText(data []byte) (string, error) {
+	files, err := zipFiles(data)
+	if err != nil {
+		return "", err
+	}
+	var container struct {
+		Rootfiles []struct {
+			Path string `xml:"full-path,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	if err := decodeZipXML(files, "META-INF/container.xml", &container); err != nil {
+		return "", fmt.Errorf("container: %w", err)
+	}
+	if len(container.Rootfiles) == 0 {
+		return "", errors.New("container lists no package")
+	}
+	opfPath := container.Rootfiles[0].Path
+
+	var pkg struct {
+		Items []struct {
+			ID   string `xml:"id,attr"`
+			Href string `xml:"href,attr"`
+		} `xml:"manifest>item"`
+		Spine []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"spine>itemref"`
+	}
+	if err := decodeZipXML(files, opfPath, &pkg); err != nil {
+		return "", fmt.Errorf("package: %w", err)
+	}
+	hrefs := make(map[string]string, len(pkg.Items))
+	for _, item := range pkg.Items {
+		hrefs[item.ID] = path.Join(path.Dir(opfPath), item.Href)
+	}
+
+	var b strings.Builder
+	for _, ref := range pkg.Spine {
+		rc, err := openZipEntry(files, hrefs[ref.IDRef])
+		if err != nil {
+			continue
+		}
+		err = htmlText(&b, rc)
+		rc.Close()
+		if err != nil {
+			return "", fmt.Errorf("chapter %s: %w", ref.IDRef, err)
+		}
+		b.WriteString("\n\n")
+	}
+	return b.String(), nil
+}
+
+var htmlBlockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "section": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "blockquote": true,
+}
+
+// htmlText writes the readable text of an (X)HTML document, one block per line.
+func htmlText(w *strings.Builder, r io.Reader) error {
+	dec := xml.NewDecoder(r)
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+
+	skip := 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch name := strings.ToLower(t.Name.Local); {
+			case name == "script" || name == "style" || name == "head":
+				skip++
+			case htmlBlockElements[name]:
+				w.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch name := strings.ToLower(t.Name.Local); {
+			case name == "script" || name == "style" || name == "head":
+				skip = max(skip-1, 0)
+			case htmlBlockElements[name]:
+				w.WriteString("\n")
+			}
+		case xml.CharData:
+			if skip > 0 || len(bytes.TrimSpace(t)) == 0 {
+				continue
+			}
+			if isSpaceByte(t[0]) {
+				w.WriteString(" ")
+			}
+			w.WriteString(strings.Join(strings.Fields(string(t)), " "))
+			if isSpaceByte(t[len(t)-1]) {
+				w.WriteString(" ")
+			}
+		}
+	}
+}
+
+func isSpaceByte(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -0,0 +1,156 @@
+package app
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// zipFixture builds an archive of the given files in memory.
+func zipFixture(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close archive: %v", err)
+	}
+	return b.Bytes()
+}
+
+const docxDocument = `<?xml version="1.0" encoding="UTF-8"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:r><w:t>Quarterly</w:t></w:r><w:r><w:t xml:space="preserve"> report</w:t></w:r></w:p>
+<w:p><w:r><w:t>Name</w:t><w:tab/><w:t>Value</w:t><w:br/><w:t>next line</w:t></w:r></w:p>
+<w:tbl><w:tr><w:tc><w:p><w:r><w:t>A1</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>B1</w:t></w:r></w:p></w:tc></w:tr></w:tbl>
+<w:p><w:r><w:instrText>PAGE</w:instrText></w:r></w:p>
+</w:body></w:document>`
+
+func TestExtractDOCXText(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr string
+	}{
+		{"paragraphs, tabs and breaks", zipFixture(t, map[string]string{"word/document.xml": docxDocument}), "Quarterly report\nName\tValue\nnext line\nA1\n\tB1\n\t\n", ""},
+		{"not an archive", []byte("PK but not really"), "", "zip"},
+		{"no document part", zipFixture(t, map[string]string{"word/styles.xml": "<styles/>"}), "", "missing word/document.xml"},
+		{"malformed XML", zipFixture(t, map[string]string{"word/document.xml": "<w:document><w:p>"}), "", "EOF"},
+	}
+	for _, tt := range tests {
+		got, err := extractDOCXText(tt.data)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: text = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func xlsxFixture(t *testing.T, sheet string) []byte {
+	t.Helper()
+	return zipFixture(t, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Sales" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`,
+		"xl/sharedStrings.xml":       `<sst><si><t>Region</t></si><si><r><t>To</t></r><r><t>tal</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml":   sheet,
+	})
+}
+
+func TestExtractXLSXText(t *testing.T) {
+	sheet := `<worksheet><sheetData>
+<row><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+<row><c r="A2" t="inlineStr"><is><t>North
+East</t></is></c><c r="C2"><v>42</v></c><c r="D2" t="b"><v>1</v></c></row>
+<row><c r="A3" t="s"><v>9</v></c></row>
+</sheetData></worksheet>`
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr string
+	}{
+		{"shared, inline, sparse and boolean cells", xlsxFixture(t, sheet), "## Sheet: Sales\nRegion\tTotal\nNorth East\t\t42\tTRUE\n9\n\n", ""},
+		{"missing sheet", zipFixture(t, map[string]string{
+			"xl/workbook.xml":            `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Gone" r:id="rId1"/></sheets></workbook>`,
+			"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId1" Target="/xl/worksheets/gone.xml"/></Relationships>`,
+		}), "", `sheet "Gone": missing xl/worksheets/gone.xml`},
+		{"malformed sheet", xlsxFixture(t, "<worksheet><sheetData><row>"), "", `sheet "Sales"`},
+		{"no workbook", zipFixture(t, map[string]string{"xl/styles.xml": "<styleSheet/>"}), "", "workbook: missing xl/workbook.xml"},
+	}
+	for _, tt := range tests {
+		got, err := extractXLSXText(tt.data)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: err = %v, want %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s: text = %q, %v; want %q", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestXLSXColumn(t *testing.T) {
+	for ref, want := range map[string]int{"A1": 0, "C7": 2, "Z9": 25, "AA10": 26, "": 0, "7": 0} {
+		if got := xlsxColumn(ref); got != want {
+			t.Errorf("xlsxColumn(%q) = %d, want %d", ref, got, want)
+		}
+	}
+}
+
+func TestExtractEPUBText(t *testing.T) {
+	container := `<container><rootfiles><rootfile full-path="OEBPS/content.opf"/></rootfiles></container>`
+	opf := `<package><manifest>
+<item id="c1" href="one.xhtml"/><item id="c2" href="text/two.xhtml"/><item id="gone" href="missing.xhtml"/>
+</manifest><spine><itemref idref="c1"/><itemref idref="gone"/><itemref idref="c2"/></spine></package>`
+	book := zipFixture(t, map[string]string{
+		"META-INF/container.xml": container,
+		"OEBPS/content.opf":      opf,
+		"OEBPS/one.xhtml":        `<html><head><title>skip</title></head><body><h1>Chapter  One</h1><p>It was a <em>dark</em> night&nbsp;&amp; cold.</p><script>var x;</script></body></html>`,
+		"OEBPS/text/two.xhtml":   `<html><body><p>Chapter two<br>begins</p></body></html>`,
+	})
+	got, err := extractEPUBText(book)
+	if err != nil {
+		t.Fatalf("extractEPUBText: %v", err)
+	}
+	for _, want := range []string{"Chapter One", "It was a dark night & cold.", "Chapter two", "begins"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("text %q lacks %q", got, want)
+		}
+	}
+	for _, unwanted := range []string{"skip", "var x"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("text %q contains %q", got, unwanted)
+		}
+	}
+	if strings.Index(got, "Chapter One") > strings.Index(got, "Chapter two") {
+		t.Errorf("chapters out of spine order: %q", got)
+	}
+
+	for name, files := range map[string]map[string]string{
+		"no container":  {"OEBPS/content.opf": opf},
+		"empty package": {"META-INF/container.xml": `<container><rootfiles/></container>`},
+		"no package":    {"META-INF/container.xml": container},
+	} {
+		if _, err := extractEPUBText(zipFixture(t, files)); err == nil {
+			t.Errorf("%s: extracted without error", name)
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// openAIProvider talks to the chat completions API of OpenAI, which Ollama
+// and many self-hosted servers also offer.
+type openAIProvider struct {
+	baseURL string
+	apiKey  string
+	caps    capabilities
+}
+
+type openAIMessage struct {
+	Role       string           `json:"role"`
+	Content    any              `json:"content"`
+	ToolCalls  []openAIToolCall `json:"tool_calls,omitempty"`
+	ToolCallID string           `json:"tool_call_id,omitempty"`
+}
+
+type openAIToolCall struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Function struct {
+		Name      string `json:"name"`
+		Arguments string `json:"arguments"`
+	} `json:"function"`
+}
+
+type openAIRequest struct {
+	Model            string          `json:"model"`
+	Messages         []openAIMessage `json:"messages"`
+	Tools            []any           `json:"tools,omitempty"`
+	Temperature      *float32        `json:"temperature,omitempty"`
+	TopP             *float32        `json:"top_p,omitempty"`
+	MaxTokens        int32           `json:"max_completion_tokens,omitempty"`
+	Stop             []string        `json:"stop,omitempty"`
+	Seed             *int32          `json:"seed,omitempty"`
+	PresencePenalty  *float32        `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float32        `json:"frequency_penalty,omitempty"`
+	ReasoningEffort  string          `json:"reasoning_effort,omitempty"`
+	ResponseFormat   any             `json:"response_format,omitempty"`
+}
+
+type openAIResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message struct {
+			Content   string           `json:"content"`
+			Reasoning string           `json:"reasoning_content"`
+			ToolCalls []openAIToolCall `json:"tool_calls"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int32 `json:"prompt_tokens"`
+		CompletionTokens int32 `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+func (p *openAIProvider) capabilities() capabilities {
+	return p.caps
+}
+
+func (p *openAIProvider) generateContent(ctx context.Context, model string, contents []*genai.Content, cfg *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	if cfg == nil {
+		cfg = &genai.GenerateContentConfig{}
+	}
+	req := openAIRequest{
+		Model:            model,
+		Temperature:      cfg.Temperature,
+		TopP:             cfg.TopP,
+		MaxTokens:        cfg.MaxOutputTokens,
+		Stop:             cfg.StopSequences,
+		Seed:             cfg.Seed,
+		PresencePenalty:  cfg.PresencePenalty,
+		FrequencyPenalty: cfg.FrequencyPenalty,
+	}
+	system := systemText(cfg.SystemInstruction)
+	if cfg.ResponseMIMEType == "application/json" {
+		req.ResponseFormat = map[string]string{"type": "json_object"}
+		system = strings.TrimSpace(system + "\n\n" + jsonAnswerInstruction(cfg.ResponseSchema))
+	}
+	if system != "" {
+		req.Messages = append(req.Messages, openAIMessage{Role: "system", Content: system})
+	}
+	req.Messages = append(req.Messages, openAIMessages(contents)...)
+	for _, decl := range functionDeclarations(cfg.Tools) {
+		req.Tools = append(req.Tools, map[string]any{
+			"type": "function",
+			"function": map[string]any{
+				"name":        decl.Name,
+				"description": decl.Description,
+				"parameters":  declarationSchema(decl),
+			},
+		})
+	}
+	if cfg.ThinkingConfig != nil && cfg.ThinkingConfig.ThinkingBudget != nil {
+		req.ReasoningEffort = reasoningEffort(*cfg.ThinkingConfig.ThinkingBudget)
+	}
+
+	header := http.Header{}
+	if p.apiKey != "" {
+		header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	var resp openAIResponse
+	if err := postJSON(ctx, p.baseURL+"/chat/completions", header, req, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("%s answered without a choice", model)
+	}
+	choice := resp.Choices[0]
+	var parts []*genai.Part
+	if choice.Message.Reasoning != "" {
+		parts = append(parts, &genai.Part{Text: choice.Message.Reasoning, Thought: true})
+	}
+	if choice.Message.Content != "" {
+		parts = append(parts, genai.NewPartFromText(choice.Message.Content))
+	}
+	for _, call := range choice.Message.ToolCalls {
+		var args map[string]any
+		if err := json.Unmarshal([]byte(call.Function.Arguments), &args); err != nil {
+			args = map[string]any{}
+		}
+		part := genai.NewPartFromFunctionCall(call.Function.Name, args)
+		part.FunctionCall.ID = call.ID
+		parts = append(parts, part)
+	}
+	finish := genai.FinishReasonStop
+	switch choice.FinishReason {
+	case "length":
+		finish = genai.FinishReasonMaxTokens
+	case "content_filter":
+		finish = genai.FinishReasonSafety
+	}
+	return modelResponse(resp.Model, parts, finish, resp.Usage.PromptTokens, resp.Usage.CompletionTokens), nil
+}
+
+// openAIMessages converts a conversation to chat messages. Function results
+// become tool messages of their own.
+func openAIMessages(contents []*genai.Content) []openAIMessage {
+	var messages []openAIMessage
+	for _, content := range contents {
+		if content == nil {
+			continue
+		}
+		msg := openAIMessage{Role: "user"}
+		if content.Role == genai.RoleModel {
+			msg.Role = "assistant"
+		}
+		var blocks []map[string]any
+		var results []openAIMessage
+		for _, part := range content.Parts {
+			switch {
+			case part == nil || part.Thought:
+			case part.Text != "":
+				blocks = append(blocks, map[string]any{"type": "text", "text": part.Text})
+			case part.InlineData != nil:
+				blocks = append(blocks, openAIMedia(part.InlineData))
+			case part.FileData != nil:
+				blocks = append(blocks, map[string]any{"type": "text", "text": "[An attachment stored with Gemini was left out.]"})
+			case part.FunctionCall != nil:
+				args, _ := json.Marshal(part.FunctionCall.Args)
+				call := openAIToolCall{ID: toolCallID(part.FunctionCall.ID, part.FunctionCall.Name), Type: "function"}
+				call.Function.Name, call.Function.Arguments = part.FunctionCall.Name, string(args)
+				msg.ToolCalls = append(msg.ToolCalls, call)
+			case part.FunctionResponse != nil:
+				result, _ := json.Marshal(part.FunctionResponse.Response)
+				results = append(results, openAIMessage{
+					Role:       "tool",
+					Content:    string(result),
+					ToolCallID: toolCallID(part.FunctionResponse.ID, part.FunctionResponse.Name),
+				})
+			}
+		}
+		messages = append(messages, results...)
+		if len(blocks) == 0 && len(msg.ToolCalls) == 0 {
+			continue
+		}
+		switch {
+		case len(blocks) == 1 && blocks[0]["type"] == "text":
+			msg.Content = blocks[0]["text"]
+		case len(blocks) > 0:
+			msg.Content = blocks
+		}
+		messages = append(messages, msg)
+	}
+	return messages
+}
+
+// openAIMedia converts an attachment to a content block: images and PDFs as
+// data URLs, anything else as a note.
+func openAIMedia(blob *genai.Blob) map[string]any {
+	dataURL := "data:" + blob.MIMEType + ";base64," + base64.StdEncoding.EncodeToString(blob.Data)
+	switch {
+	case strings.HasPrefix(blob.MIMEType, "image/"):
+		return map[string]any{"type": "image_url", "image_url": map[string]string{"url": dataURL}}
+	case blob.MIMEType == "application/pdf":
+		return map[string]any{"type": "file", "file": map[string]string{"filename": blob.DisplayName, "file_data": dataURL}}
+	}
+	return map[string]any{"type": "text", "text": fmt.Sprintf("[A %s attachment was left out: this model cannot read it.]", blob.MIMEType)}
+}
+
+// toolCallID returns id, or one derived from the function name for calls
+// Gemini made without an ID.
+func toolCallID(id, name string) string {
+	if id != "" {
+		return id
+	}
+	return "call_" + name
+}
+
+// reasoningEffort maps a thinking budget to the effort levels of OpenAI.
+func reasoningEffort(budget int32) string {
+	switch {
+	case budget == 0:
+		return "minimal"
+	case budget > 0 && budget <= 4096:
+		return "low"
+	case budget > 16384:
+		return "high"
+	}
+	return "medium"
+}
+
+// jsonAnswerInstruction asks for JSON, matching schema when there is one, for
+// providers that cannot enforce a schema.
+func jsonAnswerInstruction(schema *genai.Schema) string {
+	if schema == nil {
+		return "Answer with a JSON object only."
+	}
+	encoded, err := json.Marshal(jsonSchema(schema))
+	if err != nil {
+		return "Answer with a JSON object only."
+	}
+	return "Answer with a JSON object only, matching this JSON Schema: " + string(encoded)
+}
@@ -0,0 +1,182 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const exportReviewUnique = "export_review"
+
+// reviewInstruction turns the answer to a diff into comments that can be placed
+// on its lines.
+const reviewInstruction = "The user's message contains a diff or patch. Review it as a careful senior engineer: " +
+	"correctness bugs first, then security, error handling, concurrency, naming and tests. " +
+	"Put each comment on its own line in the form `path/to/file:LINE: comment`, where LINE is the line number " +
+	"in the new version of the file, as counted from the @@ hunk headers. " +
+	"After the comments, give a one-sentence verdict. Skip praise and comments on unchanged code."
+
+var (
+	patchHunkHeader = regexp.MustCompile(`(?m)^@@ -\d+(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+	patchFileHeader = regexp.MustCompile(`(?m)^(?:diff --git |--- \S.*\n\+\+\+ \S)`)
+	reviewComment   = regexp.MustCompile("(?m)^\\s*(?:[-*]\\s+)?`?([^\\s:`]+):(\\d+)`?:?\\s+(.+)$")
+)
+
+// patchReview is a review of a patch whose comments can be exported onto it.
+type patchReview struct {
+	Patch    string
+	Comments []reviewNote
+}
+
+type reviewNote struct {
+	File string
+	Line int
+	Body string
+}
+
+func (r *patchReview) size() int {
+	n := len(r.Patch)
+	for _, c := range r.Comments {
+		n += len(c.File) + len(c.Body)
+	}
+	return n
+}
+
+// looksLikePatch reports whether text holds a unified diff.
+func looksLikePatch(text string) bool {
+	return patchFileHeader.MatchString(text) && patchHunkHeader.MatchString(text)
+}
+
+// extractPlainText accepts UTF-8 text files as they are.
+func extractPlainText(data []byte) (string, error) {
+	if !utf8.Valid(data) {
+		return "", errors.New("not UTF-8 text")
+	}
+	return string(data), nil
+}
+
+// messagePatch returns the diff a message carries, pasted or as a .diff or
+// .patch document already read by collectParts, or "".
+func (a *App) messagePatch(msg *tele.Message) string {
+	if text := messagePrompt(msg); looksLikePatch(text) {
+		return text
+	}
+	if msg.Document == nil {
+		return ""
+	}
+	if ex, ok := extractorFor(msg.Document); !ok || !ex.verbatim {
+		return ""
+	}
+	file := msg.Document.MediaFile()
+	key := file.UniqueID
+	if key == "" {
+		key = file.FileID
+	}
+	if entry, ok := a.extracts.get(key); ok && looksLikePatch(entry.source) {
+		return entry.source
+	}
+	return ""
+}
+
+// parseReviewComments collects the file:line comments of a review reply.
+func parseReviewComments(reply string) []reviewNote {
+	var notes []reviewNote
+	for _, m := range reviewComment.FindAllStringSubmatch(reply, -1) {
+		line, err := strconv.Atoi(m[2])
+		if err != nil || line <= 0 {
+			continue
+		}
+		notes = append(notes, reviewNote{File: m[1], Line: line, Body: strings.TrimSpace(m[3])})
+	}
+	return notes
+}
+
+// samePatchPath compares paths with or without the a/ and b/ prefixes of git
+// diffs, letting a comment name a file by its trailing components.
+func samePatchPath(patchPath, commentPath string) bool {
+	trim := func(p string) string {
+		p = strings.TrimPrefix(strings.TrimPrefix(p, "a/"), "b/")
+		return strings.TrimPrefix(p, "./")
+	}
+	p, c := trim(patchPath), trim(commentPath)
+	return p == c || strings.HasSuffix(p, "/"+c)
+}
+
+// annotatePatch inserts each comment as a "# review:" line after the line it
+// refers to. Comments that match no line are listed before the first file,
+// where patch tools ignore text.
+func annotatePatch(review *patchReview) string {
+	placed := make([]bool, len(review.Comments))
+	var body strings.Builder
+	var file string
+	newLine := 0
+	inHunk := false
+	for _, line := range strings.SplitAfter(review.Patch, "\n") {
+		if line == "" {
+			continue
+		}
+		body.WriteString(line)
+		trimmed := strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(trimmed, "+++ "):
+			file = strings.TrimSpace(strings.TrimPrefix(trimmed, "+++ "))
+			if tab := strings.IndexByte(file, '\t'); tab >= 0 {
+				file = file[:tab]
+			}
+			inHunk = false
+			continue
+		case strings.HasPrefix(trimmed, "diff --git "), strings.HasPrefix(trimmed, "--- "):
+			inHunk = false
+			continue
+		}
+		if m := patchHunkHeader.FindStringSubmatch(trimmed); m != nil {
+			newLine, _ = strconv.Atoi(m[1])
+			inHunk = true
+			continue
+		}
+		if !inHunk || strings.HasPrefix(trimmed, "-") || strings.HasPrefix(trimmed, "\\") {
+			continue
+		}
+		if !strings.HasSuffix(line, "\n") {
+			body.WriteString("\n")
+		}
+		for i, c := range review.Comments {
+			if !placed[i] && c.Line == newLine && samePatchPath(file, c.File) {
+				fmt.Fprintf(&body, "# review: %s\n", c.Body)
+				placed[i] = true
+			}
+		}
+		newLine++
+	}
+
+	var head strings.Builder
+	head.WriteString("Review comments are marked with \"# review:\" after the line they refer to.\n")
+	for i, c := range review.Comments {
+		if !placed[i] {
+			fmt.Fprintf(&head, "%s:%d: %s\n", c.File, c.Line, c.Body)
+		}
+	}
+	head.WriteString("\n")
+	return head.String() + body.String()
+}
+
+func (a *App) handleExportReview(c tele.Context) error {
+	if err := c.Respond(); err != nil {
+		slog.Warn("callback acknowledge failed", "err", err)
+	}
+	art, ok := a.artifacts.get(c.Callback().Data)
+	if !ok || art.Review == nil {
+		_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), tr(a.chatLanguage(c.Chat(), c.Sender()), "review_gone"), &tele.SendOptions{DisableWebPagePreview: true})
+		return err
+	}
+	annotated := annotatePatch(art.Review)
+	name := a.exportName(context.Background(), c.Chat().ID, "code review", ".patch", art.Review.Patch)
+	return a.sendTextDocument(inTopic(c.Chat(), c.Message()), name, "text/x-diff", annotated, "")
+}
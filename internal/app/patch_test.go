@@ -0,0 +1,63 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const samplePatch = `diff --git a/server/handler.go b/server/handler.go
+--- a/server/handler.go
++++ b/server/handler.go
+@@ -10,4 +10,5 @@ func handle(w http.ResponseWriter, r *http.Request) {
+ 	id := r.URL.Query().Get("id")
+-	user := load(id)
++	user, _ := load(id)
++	log.Println(user.Name)
+ 	render(w, user)
+`
+
+func TestReviewCommentsAnnotateThePatch(t *testing.T) {
+	if !looksLikePatch(samplePatch) {
+		t.Fatal("sample patch not detected")
+	}
+	if looksLikePatch("Use --- to separate sections and +++ for emphasis.") {
+		t.Error("prose detected as a patch")
+	}
+
+	reply := "- `server/handler.go:11`: the error from load is dropped, so user may be nil.\n" +
+		"handler.go:12: logging the name leaks personal data.\n" +
+		"server/other.go:3: unrelated file.\n\n" +
+		"Verdict: needs changes."
+	comments := parseReviewComments(reply)
+	if len(comments) != 3 {
+		t.Fatalf("parsed %d comments, want 3: %+v", len(comments), comments)
+	}
+
+	got := annotatePatch(&patchReview{Patch: samplePatch, Comments: comments})
+	for _, want := range []string{
+		"+\tuser, _ := load(id)\n# review: the error from load is dropped, so user may be nil.\n",
+		"+\tlog.Println(user.Name)\n# review: logging the name leaks personal data.\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("annotated patch lacks %q:\n%s", want, got)
+		}
+	}
+	if head, _, _ := strings.Cut(got, "diff --git"); !strings.Contains(head, "server/other.go:3: unrelated file.") {
+		t.Errorf("unplaced comment not listed before the patch:\n%s", head)
+	}
+}
+
+func TestExportReviewExpiredInUsersLanguage(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	reply := testMessage(42, "")
+	reply.Sender = &tele.User{ID: 1, IsBot: true}
+	callback := &tele.Callback{Sender: &tele.User{ID: 42, LanguageCode: "es"}, Message: reply, Data: "42_9"}
+	if err := app.handleExportReview(app.bot.NewContext(tele.Update{Callback: callback})); err != nil {
+		t.Fatalf("handleExportReview: %v", err)
+	}
+	if texts := apis.sentTexts(); len(texts) != 1 || !strings.Contains(texts[0], "ya no se puede exportar") {
+		t.Errorf("sent %q, want the Spanish notice", texts)
+	}
+}
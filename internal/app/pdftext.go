@@ -0,0 +1,299 @@
+package app
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+const (
+	pdfMinTextRunes     = 100
+	pdfMinPrintableRate = 0.9
+	pdfMaxStreamBytes   = 16 << 20
+)
+
+// extractPDFText reads the text layer of simple PDFs: it inflates content
+// streams and collects the strings shown by text operators. Fonts with custom
+// encodings and scanned pages produce unusable output, which is detected and
+// reported as errNoTextLayer so the file goes to Gemini unchanged.
+func extractPDFText(data []byte) (string, error) {
+	var b strings.Builder
+	rest := data
+	for {
+		start := bytes.Index(rest, []byte("stream"))
+		if start < 0 {
+			break
+		}
+		dict := rest[:start]
+		if i := bytes.LastIndex(dict, []byte("obj")); i >= 0 {
+			dict = dict[i:]
+		}
+		body := rest[start+len("stream"):]
+		body = bytes.TrimPrefix(body, []byte("\r"))
+		body = bytes.TrimPrefix(body, []byte("\n"))
+		end := bytes.Index(body, []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		stream := body[:end]
+		rest = body[end+len("endstream"):]
+
+		if bytes.Contains(dict, []byte("/Image")) || bytes.Contains(dict, []byte("/Length1")) {
+			continue
+		}
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			inflated, err := inflate(stream)
+			if err != nil {
+				continue
+			}
+			stream = inflated
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			continue
+		}
+		if bytes.Contains(stream, []byte("BT")) && bytes.Contains(stream, []byte("ET")) {
+			pdfContentText(&b, stream)
+		}
+	}
+
+	text := b.String()
+	if !usableText(text) {
+		return "", errNoTextLayer
+	}
+	return text, nil
+}
+
+func inflate(stream []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(stream))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	out, err := io.ReadAll(io.LimitReader(zr, pdfMaxStreamBytes))
+	if len(out) > 0 {
+		// Truncated streams are common; keep whatever inflated cleanly.
+		return out, nil
+	}
+	return nil, err
+}
+
+// usableText rejects output that is too short or mostly glyph codes.
+func usableText(text string) bool {
+	var total, printable int
+	for _, r := range text {
+		if unicode.IsSpace(r) {
+			continue
+		}
+		total++
+		if unicode.IsPrint(r) && r != unicode.ReplacementChar && r < 0xE000 {
+			printable++
+		}
+	}
+	return total >= pdfMinTextRunes && float64(printable)/float64(total) >= pdfMinPrintableRate
+}
+
+// pdfContentText interprets the text showing and positioning operators of a
+// content stream. Other operators and their operands are ignored.
+func pdfContentText(b *strings.Builder, content []byte) {
+	var operands []string
+	lastY := ""
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			s, next := pdfLiteralString(content, i)
+			operands = append(operands, s)
+			i = next
+		case c == '<' && i+1 < len(content) && content[i+1] != '<':
+			s, next := pdfHexString(content, i)
+			operands = append(operands, s)
+			i = next
+		case c == '[':
+			// TJ arrays: strings separated by kerning adjustments; large negative
+			// adjustments stand in for word spaces.
+			var parts strings.Builder
+			i++
+			for i < len(content) && content[i] != ']' {
+				switch content[i] {
+				case '(':
+					s, next := pdfLiteralString(content, i)
+					parts.WriteString(s)
+					i = next
+				case '<':
+					s, next := pdfHexString(content, i)
+					parts.WriteString(s)
+					i = next
+				default:
+					j := i
+					for j < len(content) && strings.IndexByte("-+.0123456789", content[j]) >= 0 {
+						j++
+					}
+					if j > i {
+						if n, err := strconv.ParseFloat(string(content[i:j]), 64); err == nil && n < -200 {
+							parts.WriteString(" ")
+						}
+						i = j
+					} else {
+						i++
+					}
+				}
+			}
+			operands = append(operands, parts.String())
+			i++
+		case isPDFDelimiter(c):
+			i++
+		default:
+			j := i
+			for j < len(content) && !isPDFDelimiter(content[j]) && content[j] != '(' && content[j] != '<' && content[j] != '[' {
+				j++
+			}
+			if j == i {
+				j++
+			}
+			token := string(content[i:j])
+			i = j
+			switch token {
+			case "Tj", "TJ":
+				if len(operands) > 0 {
+					b.WriteString(operands[len(operands)-1])
+				}
+			case "'", "\"":
+				b.WriteString("\n")
+				if len(operands) > 0 {
+					b.WriteString(operands[len(operands)-1])
+				}
+			case "T*":
+				b.WriteString("\n")
+			case "Td", "TD":
+				if len(operands) >= 2 && operands[len(operands)-1] != "0" {
+					b.WriteString("\n")
+				} else {
+					b.WriteString(" ")
+				}
+			case "Tm":
+				if len(operands) >= 6 && operands[len(operands)-1] != lastY {
+					lastY = operands[len(operands)-1]
+					b.WriteString("\n")
+				}
+			case "ET":
+				b.WriteString("\n")
+			default:
+				if token[0] == '/' || token[0] == '-' || token[0] == '.' || (token[0] >= '0' && token[0] <= '9') {
+					operands = append(operands, token)
+					continue
+				}
+			}
+			if !isPDFOperandToken(token) {
+				operands = operands[:0]
+			}
+		}
+	}
+}
+
+func isPDFOperandToken(token string) bool {
+	return token == "true" || token == "false" || token == "null"
+}
+
+func isPDFDelimiter(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0 || c == ']' || c == '>' || c == ')' || c == '{' || c == '}'
+}
+
+// pdfLiteralString decodes a (...) string starting at i, handling escapes and
+// balanced parentheses, and returns the index after the closing parenthesis.
+func pdfLiteralString(content []byte, i int) (string, int) {
+	var raw []byte
+	depth := 0
+	for i < len(content) {
+		c := content[i]
+		switch {
+		case c == '(':
+			if depth > 0 {
+				raw = append(raw, c)
+			}
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return decodePDFBytes(raw), i + 1
+			}
+			raw = append(raw, c)
+		case c == '\\' && i+1 < len(content):
+			i++
+			switch e := content[i]; e {
+			case 'n':
+				raw = append(raw, '\n')
+			case 'r':
+				raw = append(raw, '\r')
+			case 't':
+				raw = append(raw, '\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				if e == '\r' && i+1 < len(content) && content[i+1] == '\n' {
+					i++
+				}
+			default:
+				if e >= '0' && e <= '7' {
+					n := 0
+					for k := 0; k < 3 && i < len(content) && content[i] >= '0' && content[i] <= '7'; k++ {
+						n = n*8 + int(content[i]-'0')
+						i++
+					}
+					raw = append(raw, byte(n))
+					continue
+				}
+				raw = append(raw, e)
+			}
+		default:
+			raw = append(raw, c)
+		}
+		i++
+	}
+	return decodePDFBytes(raw), i
+}
+
+func pdfHexString(content []byte, i int) (string, int) {
+	end := bytes.IndexByte(content[i:], '>')
+	if end < 0 {
+		return "", len(content)
+	}
+	var digits []byte
+	for _, c := range content[i+1 : i+end] {
+		if strings.IndexByte("0123456789abcdefABCDEF", c) >= 0 {
+			digits = append(digits, c)
+		}
+	}
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	raw := make([]byte, len(digits)/2)
+	for k := range raw {
+		n, _ := strconv.ParseUint(string(digits[2*k:2*k+2]), 16, 8)
+		raw[k] = byte(n)
+	}
+	return decodePDFBytes(raw), i + end + 1
+}
+
+// decodePDFBytes treats strings with a UTF-16 byte order mark as UTF-16 and
+// everything else as Latin-1, which covers the standard single byte encodings
+// closely enough for the text layer.
+func decodePDFBytes(raw []byte) string {
+	if len(raw) >= 2 && raw[0] == 0xFE && raw[1] == 0xFF {
+		units := make([]uint16, 0, len(raw)/2)
+		for k := 2; k+1 < len(raw); k += 2 {
+			units = append(units, uint16(raw[k])<<8|uint16(raw[k+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, len(raw))
+	for k, c := range raw {
+		runes[k] = rune(c)
+	}
+	return string(runes)
+}
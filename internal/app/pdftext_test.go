@@ -0,0 +1,107 @@
+package app
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// pdfFixture is a minimal PDF with one stream object per content, each with
+// the given dictionary entries.
+func pdfFixture(dict string, streams ...[]byte) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	for i, stream := range streams {
+		fmt.Fprintf(&b, "%d 0 obj\n<< %s >>\nstream\n", i+1, dict)
+		b.Write(stream)
+		b.WriteString("\nendstream\nendobj\n")
+	}
+	b.WriteString("%%EOF\n")
+	return b.Bytes()
+}
+
+func deflate(t *testing.T, data string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	zw := zlib.NewWriter(&b)
+	if _, err := zw.Write([]byte(data)); err != nil {
+		t.Fatalf("deflate: %v", err)
+	}
+	zw.Close()
+	return b.Bytes()
+}
+
+// pdfLines is a content stream showing each line on its own.
+func pdfLines(lines ...string) string {
+	var b strings.Builder
+	b.WriteString("BT /F1 12 Tf 72 720 Td\n")
+	for _, line := range lines {
+		b.WriteString("(" + line + ") Tj 0 -14 Td\n")
+	}
+	b.WriteString("ET")
+	return b.String()
+}
+
+const pdfSentence = "The quick brown fox jumps over the lazy dog."
+
+func TestExtractPDFText(t *testing.T) {
+	plain := pdfLines(pdfSentence, pdfSentence, pdfSentence)
+	compressed := deflate(t, plain)
+	tests := []struct {
+		name    string
+		data    []byte
+		want    string
+		wantErr error
+	}{
+		{"uncompressed stream", pdfFixture("/Length 200", []byte(plain)), pdfSentence + "\n" + pdfSentence, nil},
+		{"compressed stream", pdfFixture("/Filter /FlateDecode", compressed), pdfSentence, nil},
+		{"truncated compressed stream keeps what inflated", pdfFixture("/Filter /FlateDecode", compressed[:len(compressed)-6]), pdfSentence, nil},
+		{"corrupt compressed stream", pdfFixture("/Filter /FlateDecode", []byte("not zlib at all")), "", errNoTextLayer},
+		{"other filters are skipped", pdfFixture("/Filter /DCTDecode", []byte(plain)), "", errNoTextLayer},
+		{"images are skipped", pdfFixture("/Subtype /Image", []byte(plain)), "", errNoTextLayer},
+		{"embedded fonts are skipped", pdfFixture("/Length1 300", []byte(plain)), "", errNoTextLayer},
+		{"missing endstream", []byte("1 0 obj\n<< >>\nstream\n" + plain), "", errNoTextLayer},
+		{"too little text", pdfFixture("", []byte(pdfLines("Page 1"))), "", errNoTextLayer},
+		{"glyph codes", pdfFixture("", []byte(pdfLines(strings.Repeat(`\001\002\003`, 60)))), "", errNoTextLayer},
+		{"not a PDF", []byte("hello"), "", errNoTextLayer},
+	}
+	for _, tt := range tests {
+		got, err := extractPDFText(tt.data)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: err = %v, want %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("%s: text = %q, want it to contain %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPDFContentText(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{"show and move", "BT (Hello) Tj 0 -14 Td (world) Tj ET", "Hello\nworld\n"},
+		{"same line move", "BT (Hello) Tj 40 0 Td (world) Tj ET", "Hello world\n"},
+		{"kerning as word space", "BT [(Hel) 20 (lo) -300 (world)] TJ ET", "Hello world\n"},
+		{"next line operators", "BT (one) Tj T* (two) Tj (three) ' ET", "one\ntwo\nthree\n"},
+		{"text matrix rows", "BT 1 0 0 1 72 700 Tm (a) Tj 1 0 0 1 90 700 Tm (b) Tj 1 0 0 1 72 680 Tm (c) Tj ET", "\nab\nc\n"},
+		{"escapes", `BT (a \(b\) c\\d \101\n) Tj ET`, "a (b) c\\d A\n\n"},
+		{"balanced parentheses", "BT (f(x) = 1) Tj ET", "f(x) = 1\n"},
+		{"hex string", "BT <48656C6C6F> Tj ET", "Hello\n"},
+		{"odd hex string", "BT <4869 3> Tj ET", "Hi0\n"},
+		{"UTF-16 hex string", "BT <FEFF041F04400438> Tj ET", "При\n"},
+		{"comments", "BT % (hidden) Tj\n(shown) Tj ET", "shown\n"},
+		{"unterminated string", "BT (cut off", ""},
+	}
+	for _, tt := range tests {
+		var b strings.Builder
+		pdfContentText(&b, []byte(tt.content))
+		if got := b.String(); got != tt.want {
+			t.Errorf("%s: text = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
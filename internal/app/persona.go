@@ -0,0 +1,48 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const maxPersonaLength = 1000
+
+func personaInstruction(persona string) string {
+	return "The chat configured the following persona. Follow it unless it conflicts with the rules above:\n" + persona
+}
+
+func (a *App) handlePersona(c tele.Context) error {
+	session := a.sessionOf(c.Message())
+	payload := strings.TrimSpace(c.Message().Payload)
+
+	var body string
+	switch {
+	case payload == "":
+		session.mu.Lock()
+		current := session.persona
+		session.mu.Unlock()
+		if current == "" {
+			body = fmt.Sprintf("No custom persona set. Use /persona <instructions> (up to %d characters) or /persona reset.", maxPersonaLength)
+		} else {
+			body = "Current persona:\n" + current
+		}
+	case strings.EqualFold(payload, "reset"):
+		session.mu.Lock()
+		session.persona = ""
+		session.mu.Unlock()
+		body = "Persona reset to the default."
+	case utf8.RuneCountInString(payload) > maxPersonaLength:
+		body = fmt.Sprintf("That persona is too long (%d characters). The limit is %d.", utf8.RuneCountInString(payload), maxPersonaLength)
+	default:
+		session.mu.Lock()
+		session.persona = payload
+		session.mu.Unlock()
+		body = "Persona updated. It applies to the following messages in this chat."
+	}
+
+	_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), body, &tele.SendOptions{DisableWebPagePreview: true})
+	return err
+}
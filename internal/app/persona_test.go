@@ -0,0 +1,56 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestPersonaCommand(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	persona := func(payload string) string {
+		t.Helper()
+		msg := testMessage(42, "/persona "+payload)
+		msg.Payload = payload
+		if err := app.handlePersona(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("handlePersona(%q): %v", payload, err)
+		}
+		texts := apis.sentTexts()
+		return strings.ReplaceAll(texts[len(texts)-1], `\`, "")
+	}
+
+	tests := []struct {
+		payload string
+		reply   string
+		persona string
+	}{
+		{"", "No custom persona set.", ""},
+		{"Answer like a pirate.", "Persona updated.", "Answer like a pirate."},
+		{"", "Current persona:\nAnswer like a pirate.", "Answer like a pirate."},
+		{strings.Repeat("ä", maxPersonaLength+1), "That persona is too long (1001 characters).", "Answer like a pirate."},
+		{"RESET", "Persona reset to the default.", ""},
+	}
+	for _, tt := range tests {
+		if got := persona(tt.payload); !strings.Contains(got, tt.reply) {
+			t.Errorf("/persona %.20q replied %q, want %q", tt.payload, got, tt.reply)
+		}
+		session := app.sessionOf(testMessage(42, ""))
+		session.mu.Lock()
+		got := session.persona
+		session.mu.Unlock()
+		if got != tt.persona {
+			t.Errorf("after /persona %.20q the persona is %q, want %q", tt.payload, got, tt.persona)
+		}
+	}
+
+	persona("Answer like a pirate.")
+	if err := app.processMessage(context.Background(), testMessage(42, "Hi"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	calls := apis.callsTo(geminiHost, ":generateContent")
+	if len(calls) == 0 || !strings.Contains(string(calls[len(calls)-1].body), "configured the following persona") {
+		t.Error("the persona is missing from the system instruction")
+	}
+}
@@ -0,0 +1,117 @@
+package app
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+const (
+	piiEmail   = "email"
+	piiPhone   = "phone"
+	piiAddress = "address"
+)
+
+var defaultPIIDetectors = []string{piiEmail, piiPhone, piiAddress}
+
+type piiDetector struct {
+	name    string
+	pattern *regexp.Regexp
+	accept  func(match string) bool
+}
+
+var piiDetectors = map[string]piiDetector{
+	piiEmail: {
+		name:    piiEmail,
+		pattern: regexp.MustCompile(`(?i)[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,}`),
+	},
+	// International phone numbers start with +; national ones need the 3-3-4
+	// grouping, so dates, decimals and plain quantities are left alone.
+	piiPhone: {
+		name:    piiPhone,
+		pattern: regexp.MustCompile(`\B\+\d{1,3}(?:[\s.\-]?\(?\d{1,4}\)?){2,5}\b|(?:\B\(\d{3}\)\s?|\b\d{3}[\s.\-])\d{3}[\s.\-]\d{4}\b`),
+		accept: func(match string) bool {
+			digits := 0
+			for _, r := range match {
+				if r >= '0' && r <= '9' {
+					digits++
+				}
+			}
+			return digits >= 8 && digits <= 15
+		},
+	},
+	piiAddress: {
+		name:    piiAddress,
+		pattern: regexp.MustCompile(`(?i)\b\d{1,5}\s+(?:[a-z0-9.']+\s+){1,4}(?:street|st|avenue|ave|road|rd|boulevard|blvd|lane|ln|drive|dr|court|ct|way|place|pl|square|sq|terrace|highway|hwy)\b`),
+	},
+}
+
+// piiMasker replaces personal data in stored history with typed placeholders.
+type piiMasker struct {
+	detectors []piiDetector
+}
+
+func validatePIIDetectors(names []string) error {
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if _, ok := piiDetectors[name]; !ok {
+			return fmt.Errorf("unknown PII detector %q", name)
+		}
+	}
+	return nil
+}
+
+func newPIIMasker(names []string) *piiMasker {
+	if names == nil {
+		names = defaultPIIDetectors
+	}
+	m := &piiMasker{}
+	for _, name := range names {
+		if det, ok := piiDetectors[strings.ToLower(strings.TrimSpace(name))]; ok {
+			m.detectors = append(m.detectors, det)
+		}
+	}
+	if len(m.detectors) == 0 {
+		return nil
+	}
+	return m
+}
+
+func (m *piiMasker) maskText(text string) string {
+	if m == nil || text == "" {
+		return text
+	}
+	for _, det := range m.detectors {
+		placeholder := "[" + det.name + "]"
+		text = det.pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if det.accept != nil && !det.accept(match) {
+				return match
+			}
+			return placeholder
+		})
+	}
+	return text
+}
+
+// maskContent returns a copy of content with text parts masked; media parts are kept as-is.
+func (m *piiMasker) maskContent(content *genai.Content) *genai.Content {
+	if m == nil || content == nil {
+		return content
+	}
+	masked := &genai.Content{Role: content.Role, Parts: make([]*genai.Part, 0, len(content.Parts))}
+	for _, part := range content.Parts {
+		if part == nil || part.Text == "" {
+			masked.Parts = append(masked.Parts, part)
+			continue
+		}
+		cp := *part
+		cp.Text = m.maskText(part.Text)
+		masked.Parts = append(masked.Parts, &cp)
+	}
+	return masked
+}
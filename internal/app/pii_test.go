@@ -0,0 +1,64 @@
+package app
+
+import (
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestPIIMaskText(t *testing.T) {
+	m := newPIIMasker(nil)
+	tests := []struct {
+		in, want string
+	}{
+		{"Mail jane.doe+work@example.co.uk today", "Mail [email] today"},
+		{"Call +1 (555) 123-4567 or +44 20 7946 0958", "Call [phone] or [phone]"},
+		{"Office: (555) 123-4567, cell 555.987.6543", "Office: [phone], cell [phone]"},
+		{"Ship to 221 Baker Street, London", "Ship to [address], London"},
+		{"Order 12345 costs 99.50", "Order 12345 costs 99.50"},
+		{"Room 4021, ext. 1234-567", "Room 4021, ext. 1234-567"},
+		{"Due 2024-05-14, not 14.05.2024", "Due 2024-05-14, not 14.05.2024"},
+		{"pi is 3.14159265", "pi is 3.14159265"},
+		{"1 234 567 units, or 12345678 in total", "1 234 567 units, or 12345678 in total"},
+		{"x+12345678", "x+12345678"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := m.maskText(tt.in); got != tt.want {
+			t.Errorf("maskText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPIIMaskerDetectors(t *testing.T) {
+	m := newPIIMasker([]string{" Email "})
+	const text = "a@b.io, +44 20 7946 0958"
+	if got := m.maskText(text); got != "[email], +44 20 7946 0958" {
+		t.Errorf("email only: maskText = %q", got)
+	}
+	if m := newPIIMasker([]string{}); m != nil {
+		t.Error("masker with no detectors is not nil")
+	}
+	if got := (*piiMasker)(nil).maskText(text); got != text {
+		t.Errorf("nil masker changed the text to %q", got)
+	}
+	if err := validatePIIDetectors([]string{"email", " Phone", ""}); err != nil {
+		t.Errorf("validatePIIDetectors: %v", err)
+	}
+	if err := validatePIIDetectors([]string{"ssn"}); err == nil {
+		t.Error("validatePIIDetectors accepted an unknown detector")
+	}
+}
+
+func TestPIIMaskContent(t *testing.T) {
+	image := &genai.Part{InlineData: &genai.Blob{MIMEType: "image/png", Data: []byte{1}}}
+	content := &genai.Content{Role: "user", Parts: []*genai.Part{genai.NewPartFromText("I am a@b.io"), image}}
+
+	masked := newPIIMasker(nil).maskContent(content)
+	if masked.Parts[0].Text != "I am [email]" || masked.Parts[1] != image || masked.Role != "user" {
+		t.Errorf("maskContent = %+v", masked.Parts)
+	}
+	if content.Parts[0].Text != "I am a@b.io" {
+		t.Error("maskContent changed the original content")
+	}
+}
@@ -0,0 +1,322 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	// pipelineStepTimeout bounds a single step of a pipeline.
+	pipelineStepTimeout = 3 * time.Minute
+	// pipelineInlineLimit is the longest report a pipeline with message
+	// output sends as a message; longer ones arrive as a file.
+	pipelineInlineLimit = 3500
+)
+
+// Output formats of a pipeline.
+const (
+	pipelineDocument = "document"
+	pipelineMessage  = "message"
+)
+
+// PipelineConfig defines a chain of prompts operators run with a command,
+// over a document, the message replied to or the text after the command.
+type PipelineConfig struct {
+	// Command runs the pipeline, without the leading slash. Telegram allows
+	// lowercase letters, digits and underscores. Built-in commands win.
+	Command string
+	// Title heads the report; empty uses the command.
+	Title string
+	// Instruction is the system instruction every step shares.
+	Instruction string
+	// Output is document for a Markdown file, the default, or message for a
+	// reply, which still becomes a file when it is too long.
+	Output string
+	Steps  []PipelineStepConfig
+}
+
+// PipelineStepConfig is one prompt of a pipeline.
+type PipelineStepConfig struct {
+	// Title heads the section of the report the step writes.
+	Title  string
+	Prompt string
+	// Model runs the step; empty selects the configured model and its
+	// fallback.
+	Model string
+	// Tools are the built-in tools the step may use: google_search,
+	// url_context and code_execution. Empty allows none.
+	Tools []string
+}
+
+var pipelineCommandPattern = regexp.MustCompile(`^[a-z0-9_]{1,32}$`)
+
+// validate checks p against the tools that are turned off.
+func (p PipelineConfig) validate(disabledTools []string) error {
+	if len(p.Steps) == 0 {
+		return errors.New("no steps")
+	}
+	switch p.Output {
+	case "", pipelineDocument, pipelineMessage:
+	default:
+		return fmt.Errorf("unknown output %q, want document or message", p.Output)
+	}
+	for i, step := range p.Steps {
+		if strings.TrimSpace(step.Prompt) == "" {
+			return fmt.Errorf("step %d: empty prompt", i+1)
+		}
+		for _, name := range step.Tools {
+			switch {
+			case name != googleSearchTool && name != urlContextTool && name != codeExecutionTool:
+				return fmt.Errorf("step %d: unknown tool %q, want google_search, url_context or code_execution", i+1, name)
+			case slices.Contains(disabledTools, name):
+				return fmt.Errorf("step %d: tool %s is disabled", i+1, name)
+			}
+		}
+	}
+	return nil
+}
+
+// validatePipelines checks the pipelines of the configuration.
+func validatePipelines(pipelines []PipelineConfig, disabledTools []string) error {
+	seen := make(map[string]bool)
+	for i, p := range pipelines {
+		command := strings.TrimPrefix(strings.TrimSpace(p.Command), "/")
+		if !pipelineCommandPattern.MatchString(command) {
+			return fmt.Errorf("pipeline %d: command %q must be 1 to 32 lowercase letters, digits or underscores", i+1, p.Command)
+		}
+		if seen[command] {
+			return fmt.Errorf("pipeline %d: command /%s is defined twice", i+1, command)
+		}
+		seen[command] = true
+		if err := p.validate(disabledTools); err != nil {
+			return fmt.Errorf("pipeline /%s: %w", command, err)
+		}
+	}
+	return nil
+}
+
+// pipeline is a fixed chain of prompts run over a document or text. Each step
+// answers one question about the input and becomes a section of the report;
+// later steps also see the sections before them.
+type pipeline struct {
+	// name names the command and the report file.
+	name  string
+	title string
+	// instruction is the system instruction every step shares.
+	instruction string
+	steps       []pipelineStep
+	// footer closes the report, for example with a disclaimer.
+	footer string
+	output string
+}
+
+type pipelineStep struct {
+	title  string
+	prompt string
+	// model runs the step; empty selects the primary model with its fallback.
+	model string
+	tools []*genai.Tool
+}
+
+// newPipeline turns a validated configuration into a pipeline.
+func newPipeline(cfg PipelineConfig) pipeline {
+	name := strings.TrimPrefix(strings.TrimSpace(cfg.Command), "/")
+	p := pipeline{
+		name:        name,
+		title:       strings.TrimSpace(cfg.Title),
+		instruction: strings.TrimSpace(cfg.Instruction),
+		output:      cfg.Output,
+	}
+	if p.title == "" {
+		p.title = "/" + name
+	}
+	for i, step := range cfg.Steps {
+		title := strings.TrimSpace(step.Title)
+		if title == "" {
+			title = fmt.Sprintf("Step %d", i+1)
+		}
+		p.steps = append(p.steps, pipelineStep{
+			title:  title,
+			prompt: strings.TrimSpace(step.Prompt),
+			model:  strings.TrimSpace(step.Model),
+			tools:  stepTools(step.Tools),
+		})
+	}
+	return p
+}
+
+// stepTools returns the built-in tools named in names.
+func stepTools(names []string) []*genai.Tool {
+	if len(names) == 0 {
+		return nil
+	}
+	var disabled []string
+	for _, name := range []string{googleSearchTool, urlContextTool, codeExecutionTool} {
+		if !slices.Contains(names, name) {
+			disabled = append(disabled, name)
+		}
+	}
+	return builtinTools(disabled)
+}
+
+// registerPipelines adds a command for each configured pipeline. It runs
+// before the built-in commands are registered, so those take precedence.
+func (a *App) registerPipelines() {
+	for _, p := range a.pipelines {
+		a.bot.Handle("/"+p.name, func(c tele.Context) error {
+			return a.startPipeline(c.Message(), p, c.Message().Payload)
+		})
+	}
+}
+
+// configuredPipeline returns the pipeline command runs, if any.
+func (a *App) configuredPipeline(command string) (pipeline, bool) {
+	for _, p := range a.pipelines {
+		if "/"+p.name == command {
+			return p, true
+		}
+	}
+	return pipeline{}, false
+}
+
+// startPipeline queues p for the document of msg, the message msg replies to,
+// or the text after the command.
+func (a *App) startPipeline(msg *tele.Message, p pipeline, payload string) error {
+	source, text := msg, strings.TrimSpace(payload)
+	if msg.Document == nil && msg.ReplyTo != nil {
+		source = msg.ReplyTo
+		if quoted := messagePrompt(source); quoted != "" {
+			text = strings.TrimSpace(quoted + "\n\n" + text)
+		}
+	}
+	if source.Document == nil && text == "" {
+		lang := a.chatLanguage(msg.Chat, msg.Sender)
+		_, err := a.sendWithFallback(msg.Chat, tr(lang, "pipeline_usage", "/"+p.name), &tele.SendOptions{ThreadID: messageTopic(msg), DisableWebPagePreview: true})
+		return err
+	}
+	return a.enqueueJob(msg, func(ctx context.Context) error {
+		return a.runPipeline(ctx, source, p, text)
+	})
+}
+
+// runPipeline runs p over the document of msg and text and sends the report,
+// keeping a status message up to date while the steps run.
+func (a *App) runPipeline(ctx context.Context, msg *tele.Message, p pipeline, text string) error {
+	chat, doc := msg.Chat, msg.Document
+	lang := a.chatLanguage(chat, msg.Sender)
+	opts := &tele.SendOptions{ThreadID: messageTopic(msg), DisableWebPagePreview: true}
+	subject := p.title
+	if doc != nil {
+		subject += ": " + doc.FileName
+	}
+
+	status, err := a.sendWithFallback(chat, tr(lang, "pipeline_step", subject, 1, len(p.steps), p.steps[0].title), opts)
+	if err != nil {
+		return err
+	}
+	setStatus := func(text string) {
+		if _, err := a.editWithFallback(status, text, opts); err != nil {
+			logFrom(ctx).Warn("update pipeline status failed", "err", err)
+		}
+	}
+
+	var input []*genai.Part
+	if doc != nil {
+		docParts, err := a.documentParts(ctx, doc)
+		if err != nil {
+			logFrom(ctx).Warn("read document failed", "file", doc.FileName, "err", err)
+			if errors.Is(err, errFileTooLarge) {
+				setStatus(tr(lang, "file_too_large"))
+			} else {
+				setStatus(tr(lang, "input_failed"))
+			}
+			return nil
+		}
+		input = append([]*genai.Part{genai.NewPartFromText("Document: " + doc.FileName)}, docParts...)
+	}
+	if text != "" {
+		input = append(input, genai.NewPartFromText("Input:\n"+text))
+	}
+
+	instruction := strings.TrimSpace(p.instruction + fmt.Sprintf(" Format the answer with Markdown and write it in %s.", languageNames[lang]))
+	var sections []string
+	for i, step := range p.steps {
+		if i > 0 {
+			setStatus(tr(lang, "pipeline_step", subject, i+1, len(p.steps), step.title))
+		}
+		parts := slices.Clone(input)
+		if len(sections) > 0 {
+			parts = append(parts, genai.NewPartFromText("Findings so far:\n\n"+strings.Join(sections, "\n\n")))
+		}
+		parts = append(parts, genai.NewPartFromText(step.prompt))
+		cfg := &genai.GenerateContentConfig{
+			SystemInstruction: genai.NewContentFromText(instruction, genai.Role("system")),
+			Tools:             step.tools,
+		}
+
+		answer, err := a.runPipelineStep(ctx, chat.ID, step, genai.NewContentFromParts(parts, genai.RoleUser), cfg)
+		if err != nil {
+			logFrom(ctx).Warn("pipeline step failed", "pipeline", p.name, "step", step.title, "err", err)
+			setStatus(tr(lang, "pipeline_failed", subject))
+			return nil
+		}
+		sections = append(sections, "## "+step.title+"\n\n"+answer)
+	}
+
+	report := fmt.Sprintf("# %s\n\n%s\n", subject, strings.Join(sections, "\n\n"))
+	if p.footer != "" {
+		report += "\n---\n\n_" + p.footer + "_\n"
+	}
+	if p.output == pipelineMessage && len(report) <= pipelineInlineLimit {
+		_, err = a.sendWithFallback(chat, report, opts)
+	} else {
+		fileName := p.name + ".md"
+		if doc != nil {
+			fileName = p.name + "-" + strings.TrimSuffix(doc.FileName, filepath.Ext(doc.FileName)) + ".md"
+		}
+		err = a.sendTextDocument(chat, fileName, "text/markdown", report, subject, opts)
+	}
+	if err != nil {
+		setStatus(tr(lang, "pipeline_failed", subject))
+		return err
+	}
+	setStatus(tr(lang, "pipeline_done", subject))
+	return nil
+}
+
+// runPipelineStep asks the model of step and returns the text of its answer.
+func (a *App) runPipelineStep(ctx context.Context, chatID int64, step pipelineStep, content *genai.Content, cfg *genai.GenerateContentConfig) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, pipelineStepTimeout)
+	defer cancel()
+	contents := []*genai.Content{content}
+	var (
+		resp  *genai.GenerateContentResponse
+		model = step.model
+		err   error
+	)
+	if model == "" {
+		resp, model, err = a.generate(ctx, contents, cfg)
+	} else {
+		resp, err = a.generateWithRetry(ctx, model, contents, configForModel(model, cfg))
+	}
+	if err != nil {
+		return "", err
+	}
+	a.recordUsage(ctx, chatID, model, resp.UsageMetadata)
+	answer := strings.TrimSpace(resp.Text())
+	if answer == "" {
+		return "", fmt.Errorf("empty answer from %s", model)
+	}
+	slog.Debug("pipeline step done", "chat_id", chatID, "step", step.title, "model", model)
+	return answer, nil
+}
@@ -0,0 +1,94 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestConfiguredPipelineOverText(t *testing.T) {
+	app, apis := newTestApp(t, Config{Pipelines: []PipelineConfig{{
+		Command: "release_notes",
+		Title:   "Release notes",
+		Output:  "message",
+		Steps: []PipelineStepConfig{
+			{Title: "Highlights", Prompt: "Name the highlights."},
+			{Title: "Sources", Prompt: "Link the announcements.", Model: "gemini-2.5-flash", Tools: []string{googleSearchTool}},
+		},
+	}}})
+	apis.reply = "Faster sync."
+
+	p, ok := app.configuredPipeline("/release_notes")
+	if !ok {
+		t.Fatal("pipeline not configured")
+	}
+	cmd := testMessage(42, "/release_notes")
+	cmd.ReplyTo = testMessage(42, "sync is 2x faster; fixed login crash")
+	if err := app.startPipeline(cmd, p, ""); err != nil {
+		t.Fatalf("startPipeline: %v", err)
+	}
+	app.queue.drain(context.Background())
+
+	calls := apis.callsTo(geminiHost, ":generateContent")
+	if len(calls) != 2 {
+		t.Fatalf("generateContent called %d times, want one per step", len(calls))
+	}
+	first, second := string(calls[0].body), string(calls[1].body)
+	if !strings.Contains(first, "fixed login crash") || strings.Contains(first, "googleSearch") {
+		t.Errorf("first step request %q misses the replied text or has tools", first)
+	}
+	if !strings.Contains(second, "googleSearch") || !strings.Contains(calls[1].method, "gemini-2.5-flash") {
+		t.Errorf("second step went to %s without its tools", calls[1].method)
+	}
+	if docs := apis.callsTo(telegramHost, "sendDocument"); len(docs) != 0 {
+		t.Errorf("sent %d documents, want the report as a message", len(docs))
+	}
+	texts := apis.sentTexts()
+	found := false
+	for _, text := range texts {
+		found = found || strings.Contains(text, "Highlights") && strings.Contains(text, "Faster sync")
+	}
+	if !found {
+		t.Errorf("report not sent, sent %q", texts)
+	}
+}
+
+func TestPipelineWithoutInputShowsUsage(t *testing.T) {
+	app, apis := newTestApp(t, Config{Pipelines: []PipelineConfig{{Command: "brief", Steps: []PipelineStepConfig{{Prompt: "Brief it."}}}}})
+	p, _ := app.configuredPipeline("/brief")
+	if err := app.startPipeline(testMessage(42, "/brief"), p, ""); err != nil {
+		t.Fatalf("startPipeline: %v", err)
+	}
+	app.queue.drain(context.Background())
+	if calls := apis.callsTo(geminiHost, ":generateContent"); len(calls) != 0 {
+		t.Errorf("generateContent called %d times without input", len(calls))
+	}
+	if texts := apis.sentTexts(); len(texts) != 1 || !strings.Contains(texts[0], "/brief") {
+		t.Errorf("sent %q, want the usage", texts)
+	}
+}
+
+func TestValidatePipelines(t *testing.T) {
+	step := []PipelineStepConfig{{Prompt: "x"}}
+	cases := []struct {
+		pipelines []PipelineConfig
+		want      string
+	}{
+		{[]PipelineConfig{{Command: "Release-Notes", Steps: step}}, "must be"},
+		{[]PipelineConfig{{Command: "a", Steps: step}, {Command: "/a", Steps: step}}, "defined twice"},
+		{[]PipelineConfig{{Command: "a"}}, "no steps"},
+		{[]PipelineConfig{{Command: "a", Output: "pdf", Steps: step}}, "unknown output"},
+		{[]PipelineConfig{{Command: "a", Steps: []PipelineStepConfig{{Title: "x"}}}}, "step 1: empty prompt"},
+		{[]PipelineConfig{{Command: "a", Steps: []PipelineStepConfig{{Prompt: "x", Tools: []string{"browser"}}}}}, "unknown tool"},
+		{[]PipelineConfig{{Command: "a", Steps: []PipelineStepConfig{{Prompt: "x", Tools: []string{urlContextTool}}}}}, "tool url_context is disabled"},
+	}
+	for _, tc := range cases {
+		err := validatePipelines(tc.pipelines, []string{urlContextTool})
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("validatePipelines(%+v) = %v, want %q", tc.pipelines, err, tc.want)
+		}
+	}
+	if err := validatePipelines([]PipelineConfig{{Command: "/brief", Steps: step}}, nil); err != nil {
+		t.Errorf("valid pipeline: %v", err)
+	}
+}
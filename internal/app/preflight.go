@@ -0,0 +1,71 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const preflightTimeout = 20 * time.Second
+
+// preflight warms up the Gemini and Telegram connections and checks the
+// configuration before the first update arrives, so a wrong key or model name
+// fails at startup instead of on the first user's message. Transient failures
+// are logged and startup continues.
+func (a *App) preflight(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, preflightTimeout)
+	defer cancel()
+
+	tuned := a.tuned()
+	if a.isGeminiModel(tuned.model) {
+		if err := a.checkModel(ctx, tuned.model); err != nil {
+			return err
+		}
+	} else {
+		slog.Info("model served by another provider, skipping the model check", "model", tuned.model)
+	}
+
+	if tuned.fallbackModel != "" && a.isGeminiModel(tuned.fallbackModel) {
+		if _, err := a.client.Models.Get(ctx, tuned.fallbackModel, nil); err != nil && !isRetryableGenAIError(err) {
+			slog.Warn("fallback model unavailable, disabling it", "model", tuned.fallbackModel, "err", err)
+			a.noteStartup("Disabled the fallback model %s because it is unavailable", tuned.fallbackModel)
+			a.updateTunables(func(t *tunables) { t.fallbackModel = "" })
+		}
+	}
+
+	me := a.bot.Me
+	slog.Info("telegram bot ready", "username", me.Username, "test_environment", a.testEnvironment, "can_join_groups", me.CanJoinGroups, "can_read_all_group_messages", me.CanReadMessages)
+	if hook, err := a.bot.Webhook(); err != nil {
+		slog.Warn("telegram webhook check failed", "err", err)
+	} else if _, polling := a.bot.Poller.(*tele.LongPoller); polling && hook.Listen != "" {
+		slog.Warn("a webhook is set for this bot; long polling will not receive updates until it is removed", "url", hook.Listen)
+		a.noteStartup("A webhook is set for this bot, which blocks long polling")
+	}
+	slog.Info("media limits", "download_limit_bytes", telegramDownloadLimit, "inline_limit_bytes", a.inlineMediaLimit)
+	return nil
+}
+
+// checkModel fails when Gemini does not know model and opens the connection
+// the first reply will use.
+func (a *App) checkModel(ctx context.Context, model string) error {
+	info, err := a.client.Models.Get(ctx, model, nil)
+	switch {
+	case err == nil:
+		slog.Info("model available", "model", model, "input_token_limit", info.InputTokenLimit, "output_token_limit", info.OutputTokenLimit)
+	case isRetryableGenAIError(err):
+		slog.Warn("model check failed, continuing", "model", model, "err", err)
+	default:
+		return fmt.Errorf("check model %s: %w", model, err)
+	}
+
+	// CountTokens is free and is served next to GenerateContent, so it opens the
+	// connection the first reply will use without billing a request.
+	if _, err := a.client.Models.CountTokens(ctx, model, genai.Text("ping"), nil); err != nil {
+		slog.Warn("gemini warmup failed", "err", err)
+	}
+	return nil
+}
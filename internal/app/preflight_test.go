@@ -0,0 +1,20 @@
+package app
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPreflightDisablesUnknownFallback(t *testing.T) {
+	app, apis := newTestApp(t, Config{FallbackModel: "gemini-0.1-missing"})
+
+	if err := app.preflight(context.Background()); err != nil {
+		t.Fatalf("preflight: %v", err)
+	}
+	if fallback := app.tuned().fallbackModel; fallback != "" {
+		t.Fatalf("fallback model %q is still enabled", fallback)
+	}
+	if len(apis.callsTo(geminiHost, ":countTokens")) != 1 {
+		t.Fatal("the Gemini connection was not warmed up")
+	}
+}
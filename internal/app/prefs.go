@@ -0,0 +1,118 @@
+package app
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// prefsStore keeps per-user preferences and credentials in a single AES-GCM
+// encrypted file. Every write rewrites the file atomically.
+type prefsStore struct {
+	mu   sync.Mutex
+	path string
+	aead cipher.AEAD
+	data map[int64]map[string]json.RawMessage
+}
+
+func openPrefsStore(path, secret string) (*prefsStore, error) {
+	if secret == "" {
+		return nil, errors.New("encryption key is empty")
+	}
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &prefsStore{path: path, aead: aead, data: make(map[int64]map[string]json.RawMessage)}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) < aead.NonceSize() {
+		return nil, fmt.Errorf("%s is truncated", path)
+	}
+	plain, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("decrypt %s: %w", path, err)
+	}
+	if err := json.Unmarshal(plain, &s.data); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// get decodes the value stored under name for userID into out.
+func (s *prefsStore) get(userID int64, name string, out any) (bool, error) {
+	s.mu.Lock()
+	raw, ok := s.data[userID][name]
+	s.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	return true, json.Unmarshal(raw, out)
+}
+
+func (s *prefsStore) set(userID int64, name string, value any) error {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.data[userID] == nil {
+		s.data[userID] = make(map[string]json.RawMessage)
+	}
+	s.data[userID][name] = raw
+	return s.flushLocked()
+}
+
+func (s *prefsStore) delete(userID int64, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data[userID][name]; !ok {
+		return nil
+	}
+	delete(s.data[userID], name)
+	if len(s.data[userID]) == 0 {
+		delete(s.data, userID)
+	}
+	return s.flushLocked()
+}
+
+// purgeUser removes everything stored for userID.
+func (s *prefsStore) purgeUser(userID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data[userID]; !ok {
+		return nil
+	}
+	delete(s.data, userID)
+	return s.flushLocked()
+}
+
+func (s *prefsStore) flushLocked() error {
+	plain, err := json.Marshal(s.data)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, s.aead.Seal(nonce, nonce, plain, nil))
+}
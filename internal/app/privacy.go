@@ -0,0 +1,310 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// privacyBackend keeps the chats in privacy mode next to the sessions, so
+// every instance honours a switch at once and it survives restarts.
+type privacyBackend interface {
+	// privacyChats returns the chats in privacy mode with the time each
+	// turned it on.
+	privacyChats(ctx context.Context) (map[int64]time.Time, error)
+	// privacySince returns when chatID turned privacy mode on, and false when
+	// it is off.
+	privacySince(ctx context.Context, chatID int64) (time.Time, bool, error)
+	// enablePrivacy turns privacy mode of chatID on, keeping the time of a
+	// chat that already has it.
+	enablePrivacy(ctx context.Context, chatID int64, since time.Time) error
+	disablePrivacy(ctx context.Context, chatID int64) error
+	// migratePrivacy moves privacy mode of chat from to chat to.
+	migratePrivacy(ctx context.Context, from, to int64) error
+}
+
+// privacyStore keeps the chats in privacy mode with the time each turned it
+// on, in the shared backend when there is one and in a JSON file otherwise.
+type privacyStore struct {
+	mu    sync.Mutex
+	path  string
+	chats map[int64]time.Time
+	// shared, when set, holds the flags; chats is the copy that answers
+	// while it cannot be reached.
+	shared privacyBackend
+}
+
+// openPrivacyStore reads the chats in privacy mode from shared, or from the
+// file at path without a backend. Chats a file from before the backend lists
+// move into it, and the file is removed.
+func openPrivacyStore(ctx context.Context, path string, shared privacyBackend) (*privacyStore, error) {
+	s := &privacyStore{path: path, chats: make(map[int64]time.Time), shared: shared}
+	if err := readJSONFile(path, &s.chats); err != nil {
+		return nil, err
+	}
+	if shared == nil {
+		return s, nil
+	}
+	for chatID, since := range s.chats {
+		if err := shared.enablePrivacy(ctx, chatID, since); err != nil {
+			return nil, fmt.Errorf("move %s: %w", path, err)
+		}
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	chats, err := shared.privacyChats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.chats = chats
+	return s, nil
+}
+
+// enabled reports whether chatID is in privacy mode. With a backend it asks
+// it every time, so a switch on another instance applies to the next
+// message; when the backend fails, the last known state answers.
+func (s *privacyStore) enabled(chatID int64) bool {
+	if s.shared != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+		since, on, err := s.shared.privacySince(ctx, chatID)
+		cancel()
+		if err == nil {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if on {
+				s.chats[chatID] = since
+			} else {
+				delete(s.chats, chatID)
+			}
+			return on
+		}
+		slog.Warn("read privacy mode failed", "chat_id", chatID, "err", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.chats[chatID]
+	return ok
+}
+
+// set turns privacy mode of chatID on or off and saves the change.
+func (s *privacyStore) set(chatID int64, on bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shared != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+		defer cancel()
+		var err error
+		if on {
+			err = s.shared.enablePrivacy(ctx, chatID, time.Now().UTC())
+		} else {
+			err = s.shared.disablePrivacy(ctx, chatID)
+		}
+		if err != nil {
+			return err
+		}
+	} else if _, ok := s.chats[chatID]; ok == on {
+		return nil
+	}
+	if !on {
+		delete(s.chats, chatID)
+	} else if _, ok := s.chats[chatID]; !ok {
+		s.chats[chatID] = time.Now().UTC()
+	}
+	return s.saveLocked()
+}
+
+// migrate moves privacy mode of chat from to chat to.
+func (s *privacyStore) migrate(from, to int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shared != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+		defer cancel()
+		if err := s.shared.migratePrivacy(ctx, from, to); err != nil {
+			return err
+		}
+	}
+	since, ok := s.chats[from]
+	if !ok {
+		return nil
+	}
+	delete(s.chats, from)
+	s.chats[to] = since
+	return s.saveLocked()
+}
+
+// saveLocked writes the file when there is no backend; the caller holds s.mu.
+func (s *privacyStore) saveLocked() error {
+	if s.shared != nil {
+		return nil
+	}
+	return writeJSONFile(s.path, s.chats)
+}
+
+// forgetConversations drops the history, summary, checkpoints and parked
+// threads of a session, keeping its settings; the caller holds s.mu.
+func (s *sessionState) forgetConversations() {
+	s.history = nil
+	s.historyTokens = nil
+	s.historyMeta = nil
+	s.summary = ""
+	s.lastTurn = lastTurn{}
+	s.checkpoints = nil
+	s.checkpointSeq = 0
+	s.thread = ""
+	s.threads = nil
+}
+
+// handlePrivacy shows or switches privacy mode of the chat. In privacy mode
+// every message is answered on its own: nothing of the conversation is kept,
+// replies carry no buttons since their contents are not stored, the
+// background features that read the chat stay off, and the request logs leave
+// out what users sent. Turning it on forgets what the chat kept so far. In
+// groups only admins may switch it.
+func (a *App) handlePrivacy(c tele.Context) error {
+	chat := c.Chat()
+	lang := a.chatLanguage(chat, c.Sender())
+	reply := func(body string) error {
+		_, err := a.sendWithFallback(chat, body, &tele.SendOptions{ThreadID: messageTopic(c.Message()), DisableWebPagePreview: true})
+		return err
+	}
+	payload := strings.ToLower(strings.TrimSpace(c.Message().Payload))
+	if payload != "on" && payload != "off" {
+		state := tr(lang, "privacy_state_off")
+		if a.privacy.enabled(chat.ID) {
+			state = tr(lang, "privacy_state_on")
+		}
+		return reply(state + "\n\n" + tr(lang, "privacy_usage"))
+	}
+	if isGroupChat(chat) && !a.isChatAdmin(c) {
+		return reply(tr(lang, "privacy_admins"))
+	}
+	on := payload == "on"
+	if err := a.privacy.set(chat.ID, on); err != nil {
+		a.chatLog(chat.ID).Warn("save privacy mode failed", "chat_id", chat.ID, "err", err)
+		return reply(tr(lang, "privacy_failed"))
+	}
+	if !on {
+		return reply(tr(lang, "privacy_off"))
+	}
+	a.forgetChatContents(chat.ID)
+	return reply(tr(lang, "privacy_on"))
+}
+
+// forgetChatContents drops what a chat kept of its conversations when it
+// turns privacy mode on: the histories of its sessions, the stored replies
+// behind their buttons, the archive of /recall and the catch-up buffer.
+// Settings, bookmarks, memories and the knowledge base stay, since users
+// keep those on purpose.
+func (a *App) forgetChatContents(chatID int64) {
+	for _, session := range a.sessions.ofChat(chatID) {
+		session.mu.Lock()
+		session.forgetConversations()
+		session.mu.Unlock()
+	}
+	a.sessions.persist(chatID)
+	a.artifacts.purgeChat(chatID)
+	a.catchup.disable(chatID)
+	if err := a.recall.purge(chatID); err != nil {
+		a.chatLog(chatID).Warn("purge recall archive failed", "chat_id", chatID, "err", err)
+	}
+}
+
+// privateLogValue replaces the attributes of log lines that may quote what
+// users sent.
+const privateLogValue = "[private]"
+
+// privateLogKeys are the string attributes request logs keep in privacy mode;
+// they describe the request, not its contents.
+var privateLogKeys = map[string]bool{
+	"request_id": true, "model": true, "reason": true, "finish_reason": true, "store": true,
+	"op": true, "fault": true,
+}
+
+// privateLogHandler keeps the request logs of a chat in privacy mode free of
+// what users sent. Numbers, flags, durations and times pass, as do the
+// strings of privateLogKeys; other strings and errors, which may quote a
+// link, a file name or a prompt, become privateLogValue.
+type privateLogHandler struct {
+	slog.Handler
+}
+
+func (h privateLogHandler) Handle(ctx context.Context, r slog.Record) error {
+	clean := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(attr slog.Attr) bool {
+		clean.AddAttrs(redactLogAttr(attr))
+		return true
+	})
+	return h.Handler.Handle(ctx, clean)
+}
+
+func (h privateLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = redactLogAttr(attr)
+	}
+	return privateLogHandler{h.Handler.WithAttrs(redacted)}
+}
+
+func (h privateLogHandler) WithGroup(name string) slog.Handler {
+	return privateLogHandler{h.Handler.WithGroup(name)}
+}
+
+func redactLogAttr(attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+	switch value.Kind() {
+	case slog.KindGroup:
+		group := value.Group()
+		redacted := make([]any, len(group))
+		for i, a := range group {
+			redacted[i] = redactLogAttr(a)
+		}
+		return slog.Group(attr.Key, redacted...)
+	case slog.KindString, slog.KindAny:
+		if !privateLogKeys[attr.Key] {
+			return slog.String(attr.Key, privateLogValue)
+		}
+	}
+	return slog.Attr{Key: attr.Key, Value: value}
+}
+
+type privateKey struct{}
+
+// withPrivacy marks ctx as a request of a chat in privacy mode: its logger
+// redacts what users sent, and the media and document caches keep nothing of
+// it.
+func withPrivacy(ctx context.Context) context.Context {
+	return context.WithValue(withLogger(ctx, privateLogger(logFrom(ctx))), privateKey{}, true)
+}
+
+// isPrivate reports whether ctx is a request of a chat in privacy mode.
+func isPrivate(ctx context.Context) bool {
+	private, _ := ctx.Value(privateKey{}).(bool)
+	return private
+}
+
+// chatLog is the logger for lines about chatID logged outside of a request,
+// by handlers and background jobs; in privacy mode it redacts them as a
+// request's logger does.
+func (a *App) chatLog(chatID int64) *slog.Logger {
+	if a.privacy.enabled(chatID) {
+		return privateLogger(slog.Default())
+	}
+	return slog.Default()
+}
+
+// privateLogger wraps logger for a chat in privacy mode.
+func privateLogger(logger *slog.Logger) *slog.Logger {
+	if _, ok := logger.Handler().(privateLogHandler); ok {
+		return logger
+	}
+	return slog.New(privateLogHandler{logger.Handler()})
+}
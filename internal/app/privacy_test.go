@@ -0,0 +1,189 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestPrivacyModeAnswersStatelessly(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.reply = "Noted."
+	privacy := func(payload string) string {
+		t.Helper()
+		msg := testMessage(42, "/privacy "+payload)
+		msg.Payload = payload
+		if err := app.handlePrivacy(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("handlePrivacy(%q): %v", payload, err)
+		}
+		texts := apis.sentTexts()
+		return texts[len(texts)-1]
+	}
+	ask := func(text string) (request, reply string) {
+		t.Helper()
+		if err := app.processMessage(context.Background(), testMessage(42, text), turnOptions{}); err != nil {
+			t.Fatalf("processMessage: %v", err)
+		}
+		calls := apis.callsTo(geminiHost, geminiModel+":generateContent")
+		sent := apis.callsTo(telegramHost, "sendMessage")
+		return string(calls[len(calls)-1].body), string(sent[len(sent)-1].body)
+	}
+	session := app.sessions.get(42)
+
+	if _, reply := ask("My name is Ada."); !strings.Contains(reply, "reply_markup") {
+		t.Fatalf("a regular reply has no buttons: %s", reply)
+	}
+	if len(session.history) != 2 || len(app.artifacts.forChat(42)) == 0 {
+		t.Fatalf("the regular turn kept %d history entries and %d replies", len(session.history), len(app.artifacts.forChat(42)))
+	}
+
+	if got := privacy("on"); !strings.Contains(got, "Privacy mode is on") {
+		t.Errorf("/privacy on answered %q", got)
+	}
+	if len(session.history) != 0 || session.summary != "" || len(app.artifacts.forChat(42)) != 0 {
+		t.Fatalf("privacy mode kept %d history entries and %d replies", len(session.history), len(app.artifacts.forChat(42)))
+	}
+	ask("My account number is 1234.")
+	request, reply := ask("What is my name?")
+	if strings.Contains(request, "Ada") || strings.Contains(request, "1234") {
+		t.Errorf("a private turn was sent earlier messages: %s", request)
+	}
+	if strings.Contains(reply, "reply_markup") {
+		t.Errorf("a private reply has buttons: %s", reply)
+	}
+	if len(session.history) != 0 || len(app.artifacts.forChat(42)) != 0 {
+		t.Errorf("private turns kept %d history entries and %d replies", len(session.history), len(app.artifacts.forChat(42)))
+	}
+	if reopened, err := openPrivacyStore(context.Background(), filepath.Join(app.dataDir, "privacy.json"), app.sqlite); err != nil || !reopened.enabled(42) {
+		t.Errorf("privacy mode was not saved: %v", err)
+	}
+
+	if got := privacy(""); !strings.Contains(got, "Privacy mode is on in this chat") {
+		t.Errorf("/privacy answered %q", got)
+	}
+	privacy("off")
+	ask("Remember this.")
+	if len(session.history) != 2 {
+		t.Errorf("history after /privacy off has %d entries, want 2", len(session.history))
+	}
+}
+
+func TestPrivacyModeMovesIntoTheDatabase(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "privacy.json")
+	if err := writeJSONFile(path, map[int64]time.Time{42: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	app, _ := newTestApp(t, Config{DataDir: dir})
+	if !app.privacy.enabled(42) || app.privacy.enabled(43) {
+		t.Error("privacy mode from the file was not kept")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("the file was kept after the move: %v", err)
+	}
+	if err := app.privacy.migrate(42, -10042); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	app.sqlite.Close()
+
+	restarted, _ := newTestApp(t, Config{DataDir: dir})
+	if restarted.privacy.enabled(42) || !restarted.privacy.enabled(-10042) {
+		t.Error("the migrated chat lost privacy mode across the restart")
+	}
+}
+
+func TestPrivacyModeWithoutDatabase(t *testing.T) {
+	dir := t.TempDir()
+	app, _ := newTestApp(t, Config{DataDir: dir, SQLitePath: "none"})
+	if err := app.privacy.set(42, true); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	reopened, err := openPrivacyStore(context.Background(), filepath.Join(dir, "privacy.json"), nil)
+	if err != nil || !reopened.enabled(42) {
+		t.Errorf("privacy mode was not saved to the file: %v", err)
+	}
+}
+
+func TestPrivateLogHandler(t *testing.T) {
+	var out bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&out, nil)).With("chat_id", 42)
+	logger = privateLogger(logger).With("message_id", 7, "file", "diagnosis.pdf")
+	logger.Warn("fetch page failed", "url", "https://clinic.example/ada", "err", errors.New("get https://clinic.example/ada: timeout"),
+		"model", "gemini-2.5-pro", "pages", 2, slog.Group("call", "query", "ada lovelace"))
+
+	line := out.String()
+	for _, secret := range []string{"clinic.example", "diagnosis", "lovelace"} {
+		if strings.Contains(line, secret) {
+			t.Errorf("log line leaks %q: %s", secret, line)
+		}
+	}
+	for _, kept := range []string{"chat_id=42", "message_id=7", "model=gemini-2.5-pro", "pages=2", "url=[private]", "call.query=[private]"} {
+		if !strings.Contains(line, kept) {
+			t.Errorf("log line lacks %q: %s", kept, line)
+		}
+	}
+}
+
+func TestPrivateTurnKeepsNothing(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.reply = "Noted."
+	apis.files = map[string]string{
+		"photo1": "jpeg bytes of the rash",
+		"docx1":  string(zipFixture(t, map[string]string{"word/document.xml": docxDocument})),
+	}
+	var out bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(defaultLogger) })
+	if err := app.privacy.set(42, true); err != nil {
+		t.Fatalf("enable privacy mode: %v", err)
+	}
+
+	photo := testMessage(42, "")
+	photo.Caption, photo.Photo = "Is this rash serious?", &tele.Photo{File: tele.File{FileID: "photo1", UniqueID: "u-photo1"}}
+	doc := testMessage(42, "")
+	doc.ID++
+	doc.Caption, doc.Document = "Summarize my lab results", &tele.Document{File: tele.File{FileID: "docx1", UniqueID: "u-docx1"}, FileName: "labs.docx"}
+	for _, msg := range []*tele.Message{photo, doc} {
+		if err := app.processMessage(context.Background(), msg, turnOptions{}); err != nil {
+			t.Fatalf("processMessage: %v", err)
+		}
+	}
+	app.telegramFailed("sendMessage", 42, errors.New("Bad Request: can't parse entities in \"Is this rash serious?\""))
+
+	session := app.sessions.get(42)
+	session.mu.Lock()
+	turn, history := session.lastTurn, len(session.history)
+	session.mu.Unlock()
+	if turn.prompt != nil || turn.opts.previous != nil || history != 0 {
+		t.Errorf("session kept prompt %v and %d history entries", turn.prompt, history)
+	}
+	if turn.promptID != doc.ID {
+		t.Errorf("last prompt ID = %d, want %d so edits still find the latest reply", turn.promptID, doc.ID)
+	}
+	app.media.mu.Lock()
+	media := len(app.media.entries)
+	app.media.mu.Unlock()
+	app.extracts.mu.Lock()
+	extracts := len(app.extracts.entries)
+	app.extracts.mu.Unlock()
+	if media != 0 || extracts != 0 || len(app.artifacts.forChat(42)) != 0 {
+		t.Errorf("kept %d media, %d documents and %d replies", media, extracts, len(app.artifacts.forChat(42)))
+	}
+	if !strings.Contains(out.String(), "err=[private]") {
+		t.Errorf("the failed Telegram call was not logged redacted:\n%s", out.String())
+	}
+	for _, secret := range []string{"rash", "lab results", "labs.docx", "Quarterly"} {
+		if strings.Contains(out.String(), secret) {
+			t.Errorf("logs quote %q:\n%s", secret, out.String())
+		}
+	}
+}
@@ -0,0 +1,422 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+// Kinds of ProviderConfig.
+const (
+	providerOpenAI    = "openai"
+	providerAnthropic = "anthropic"
+	providerOllama    = "ollama"
+)
+
+// ProviderConfig adds a model backend besides Gemini. Its models are
+// addressed as name:model, such as openai:gpt-4o or ollama:llama3.1,
+// wherever a model is set: Config.Model, a pipeline step or /model.
+type ProviderConfig struct {
+	// Name prefixes the models of the provider; empty uses the kind.
+	Name string
+	// Kind is openai, for OpenAI and servers with a compatible API,
+	// anthropic or ollama.
+	Kind string
+	// BaseURL replaces the endpoint of the kind, such as a self-hosted
+	// server or a proxy.
+	BaseURL string
+	APIKey  string
+	// Thinking, Tools and Multimodal override what the kind supports: a
+	// thinking budget, function calls, and images and PDFs. Requests drop
+	// what the provider does not support.
+	Thinking   *bool
+	Tools      *bool
+	Multimodal *bool
+}
+
+// capabilities are the request features a provider supports.
+type capabilities struct {
+	thinking   bool
+	tools      bool
+	multimodal bool
+}
+
+// providerDefaults are the endpoint and capabilities of each kind.
+var providerDefaults = map[string]struct {
+	baseURL string
+	caps    capabilities
+}{
+	providerOpenAI:    {"https://api.openai.com/v1", capabilities{tools: true, multimodal: true}},
+	providerAnthropic: {"https://api.anthropic.com/v1", capabilities{thinking: true, tools: true, multimodal: true}},
+	providerOllama:    {"http://localhost:11434/v1", capabilities{tools: true}},
+}
+
+// provider generates content on a model backend. Requests and responses use
+// the genai types whatever the backend, so only the providers know the
+// difference.
+type provider interface {
+	generateContent(ctx context.Context, model string, contents []*genai.Content, cfg *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error)
+	capabilities() capabilities
+}
+
+// geminiProvider serves the models without a provider prefix.
+type geminiProvider struct {
+	client *genai.Client
+}
+
+func (p geminiProvider) generateContent(ctx context.Context, model string, contents []*genai.Content, cfg *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	return p.client.Models.GenerateContent(ctx, model, contents, cfg)
+}
+
+func (geminiProvider) capabilities() capabilities {
+	return capabilities{thinking: true, tools: true, multimodal: true}
+}
+
+// newProvider builds the provider cfg describes.
+func newProvider(cfg ProviderConfig) provider {
+	defaults := providerDefaults[cfg.Kind]
+	base := strings.TrimRight(strings.TrimSpace(cfg.BaseURL), "/")
+	if base == "" {
+		base = defaults.baseURL
+	}
+	caps := defaults.caps
+	for _, o := range []struct {
+		set  *bool
+		flag *bool
+	}{{cfg.Thinking, &caps.thinking}, {cfg.Tools, &caps.tools}, {cfg.Multimodal, &caps.multimodal}} {
+		if o.set != nil {
+			*o.flag = *o.set
+		}
+	}
+	key := strings.TrimSpace(cfg.APIKey)
+	if cfg.Kind == providerAnthropic {
+		return &anthropicProvider{baseURL: base, apiKey: key, caps: caps}
+	}
+	return &openAIProvider{baseURL: base, apiKey: key, caps: caps}
+}
+
+// providerName is the prefix of the models of cfg.
+func providerName(cfg ProviderConfig) string {
+	if name := strings.TrimSpace(cfg.Name); name != "" {
+		return name
+	}
+	return cfg.Kind
+}
+
+// validateProviders checks the providers and that the models refer to
+// configured ones.
+func validateProviders(providers []ProviderConfig, models ...string) error {
+	names := make(map[string]bool)
+	for i, p := range providers {
+		if _, ok := providerDefaults[p.Kind]; !ok {
+			return fmt.Errorf("provider %d: unknown kind %q, want openai, anthropic or ollama", i+1, p.Kind)
+		}
+		name := providerName(p)
+		if strings.ContainsAny(name, ": ") || name == "gemini" {
+			return fmt.Errorf("provider %d: invalid name %q", i+1, name)
+		}
+		if names[name] {
+			return fmt.Errorf("provider %d: name %s is used twice", i+1, name)
+		}
+		names[name] = true
+		if base := strings.TrimSpace(p.BaseURL); base != "" {
+			if u, err := url.Parse(base); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+				return fmt.Errorf("provider %s: %q is not an http or https URL", name, base)
+			}
+		}
+		if strings.TrimSpace(p.APIKey) == "" && p.Kind != providerOllama {
+			return fmt.Errorf("provider %s: an API key is required", name)
+		}
+	}
+	for _, model := range models {
+		if prefix, _, ok := strings.Cut(model, ":"); ok && !names[prefix] {
+			return fmt.Errorf("model %s: no provider named %s", model, prefix)
+		}
+	}
+	return nil
+}
+
+// providerFor returns the provider that serves model and the name of the
+// model there. Models without a known prefix go to Gemini.
+func (a *App) providerFor(model string) (provider, string) {
+	if prefix, name, ok := strings.Cut(model, ":"); ok {
+		if p, ok := a.providers[prefix]; ok {
+			return p, name
+		}
+	}
+	return geminiProvider{client: a.client}, model
+}
+
+// isGeminiModel reports whether Gemini serves model.
+func (a *App) isGeminiModel(model string) bool {
+	p, _ := a.providerFor(model)
+	_, ok := p.(geminiProvider)
+	return ok
+}
+
+type modelKey struct{}
+
+// withModel makes model answer the requests made with ctx in place of the
+// configured model; an empty model keeps the configured one.
+func withModel(ctx context.Context, model string) context.Context {
+	if model == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, modelKey{}, model)
+}
+
+// primaryModel returns the model that answers the requests made with ctx.
+func (a *App) primaryModel(ctx context.Context) string {
+	if model, ok := ctx.Value(modelKey{}).(string); ok {
+		return model
+	}
+	return a.tuned().model
+}
+
+// forCapabilities drops from a request what caps does not support. Without
+// multimodal input, attachments become a note so the model knows one was
+// left out. It copies only what it changes.
+func forCapabilities(caps capabilities, contents []*genai.Content, cfg *genai.GenerateContentConfig) ([]*genai.Content, *genai.GenerateContentConfig) {
+	if cfg != nil && ((!caps.thinking && cfg.ThinkingConfig != nil) || (!caps.tools && (cfg.Tools != nil || cfg.ToolConfig != nil))) {
+		cloned := *cfg
+		if !caps.thinking {
+			cloned.ThinkingConfig = nil
+		}
+		if !caps.tools {
+			cloned.Tools, cloned.ToolConfig = nil, nil
+		}
+		cfg = &cloned
+	}
+	if caps.multimodal {
+		return contents, cfg
+	}
+	isMedia := func(part *genai.Part) bool {
+		return part != nil && (part.InlineData != nil || part.FileData != nil)
+	}
+	out, copied := contents, false
+	for i, content := range contents {
+		if content == nil || !slices.ContainsFunc(content.Parts, isMedia) {
+			continue
+		}
+		if !copied {
+			out, copied = slices.Clone(contents), true
+		}
+		parts := make([]*genai.Part, 0, len(content.Parts))
+		for _, part := range content.Parts {
+			if isMedia(part) {
+				part = genai.NewPartFromText("[An attachment was left out: this model reads text only.]")
+			}
+			parts = append(parts, part)
+		}
+		out[i] = &genai.Content{Role: content.Role, Parts: parts}
+	}
+	return out, cfg
+}
+
+// providerHTTPClient reaches the providers other than Gemini. The attempt
+// context bounds each request.
+var providerHTTPClient = &http.Client{}
+
+// postJSON sends body as JSON to rawURL and decodes the answer into out.
+// Failures answered by the server become a genai.APIError, so the retries
+// treat them like those of Gemini.
+func postJSON(ctx context.Context, rawURL string, header http.Header, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rawURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header = header.Clone()
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := providerHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		var problem struct {
+			Error struct {
+				Message string `json:"message"`
+				Type    string `json:"type"`
+			} `json:"error"`
+		}
+		message := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &problem) == nil && problem.Error.Message != "" {
+			message = problem.Error.Message
+		}
+		return genai.APIError{Code: resp.StatusCode, Status: http.StatusText(resp.StatusCode), Message: message}
+	}
+	return json.Unmarshal(data, out)
+}
+
+// systemText joins the text parts of a system instruction.
+func systemText(instruction *genai.Content) string {
+	if instruction == nil {
+		return ""
+	}
+	var texts []string
+	for _, part := range instruction.Parts {
+		if part != nil && part.Text != "" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n\n")
+}
+
+// functionDeclarations collects the function declarations of tools; the
+// built-in Gemini tools have no counterpart elsewhere.
+func functionDeclarations(tools []*genai.Tool) []*genai.FunctionDeclaration {
+	var decls []*genai.FunctionDeclaration
+	for _, tool := range tools {
+		if tool != nil {
+			decls = append(decls, tool.FunctionDeclarations...)
+		}
+	}
+	return decls
+}
+
+// jsonSchema converts a genai schema to JSON Schema.
+func jsonSchema(s *genai.Schema) map[string]any {
+	if s == nil {
+		return map[string]any{"type": "object", "properties": map[string]any{}}
+	}
+	out := map[string]any{}
+	if s.Type != "" {
+		out["type"] = strings.ToLower(string(s.Type))
+	}
+	if s.Description != "" {
+		out["description"] = s.Description
+	}
+	if len(s.Enum) > 0 {
+		out["enum"] = s.Enum
+	}
+	if s.Format != "" {
+		out["format"] = s.Format
+	}
+	if s.Items != nil {
+		out["items"] = jsonSchema(s.Items)
+	}
+	if s.Type == genai.TypeObject || len(s.Properties) > 0 {
+		props := make(map[string]any, len(s.Properties))
+		for name, prop := range s.Properties {
+			props[name] = jsonSchema(prop)
+		}
+		out["properties"] = props
+	}
+	if len(s.Required) > 0 {
+		out["required"] = s.Required
+	}
+	return out
+}
+
+// declarationSchema returns the parameters of decl as JSON Schema.
+func declarationSchema(decl *genai.FunctionDeclaration) any {
+	if decl.ParametersJsonSchema != nil {
+		return decl.ParametersJsonSchema
+	}
+	return jsonSchema(decl.Parameters)
+}
+
+// modelResponse wraps the parts of an answer from another provider as a
+// Gemini response.
+func modelResponse(model string, parts []*genai.Part, finish genai.FinishReason, prompt, output int32) *genai.GenerateContentResponse {
+	return &genai.GenerateContentResponse{
+		ModelVersion: model,
+		Candidates: []*genai.Candidate{{
+			Content:      &genai.Content{Role: genai.RoleModel, Parts: parts},
+			FinishReason: finish,
+		}},
+		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{
+			PromptTokenCount:     prompt,
+			CandidatesTokenCount: output,
+			TotalTokenCount:      prompt + output,
+		},
+	}
+}
+
+const modelUsageText = "Usage: /model <model> | default. Models of other providers go as name:model, such as openai:gpt-4o."
+
+// handleModel shows or changes the model that answers the chat. Like
+// /advanced it is limited to admins, since models differ in price.
+func (a *App) handleModel(c tele.Context) error {
+	reply := func(body string) error {
+		_, err := a.sendWithFallback(c.Chat(), body, &tele.SendOptions{ThreadID: messageTopic(c.Message()), DisableWebPagePreview: true})
+		return err
+	}
+	if c.Sender() == nil || !a.isAdmin(c.Sender().ID) {
+		return reply(tr(a.chatLanguage(c.Chat(), c.Sender()), "model_admins"))
+	}
+	session := a.sessionOf(c.Message())
+	choice := strings.TrimSpace(c.Message().Payload)
+	if prefix, _, ok := strings.Cut(choice, ":"); ok && a.providers[prefix] == nil {
+		return reply(fmt.Sprintf("No provider named %s. %s", prefix, a.describeProviders()))
+	}
+
+	session.mu.Lock()
+	switch {
+	case choice == "":
+	case strings.EqualFold(choice, "default"):
+		session.model = ""
+	default:
+		session.model = choice
+	}
+	model := session.model
+	session.mu.Unlock()
+
+	var b strings.Builder
+	if model == "" {
+		fmt.Fprintf(&b, "Model: %s (default)\n", a.tuned().model)
+		model = a.tuned().model
+	} else {
+		fmt.Fprintf(&b, "Model: %s (this chat)\n", model)
+	}
+	backend, _ := a.providerFor(model)
+	fmt.Fprintf(&b, "Supports: %s\n%s", backend.capabilities(), a.describeProviders())
+	if choice == "" {
+		b.WriteString("\n\n" + modelUsageText)
+	}
+	return reply(b.String())
+}
+
+// describeProviders lists the providers besides Gemini.
+func (a *App) describeProviders() string {
+	if len(a.providers) == 0 {
+		return "Only Gemini models are configured."
+	}
+	names := slices.Sorted(maps.Keys(a.providers))
+	return "Providers: gemini, " + strings.Join(names, ", ") + "."
+}
+
+func (c capabilities) String() string {
+	var features []string
+	if c.thinking {
+		features = append(features, "thinking")
+	}
+	if c.tools {
+		features = append(features, "tools")
+	}
+	if c.multimodal {
+		features = append(features, "images and PDFs")
+	}
+	if len(features) == 0 {
+		return "text only"
+	}
+	return strings.Join(features, ", ")
+}
@@ -0,0 +1,154 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestChatModelFromOpenAIProvider(t *testing.T) {
+	app, apis := newTestApp(t, Config{Providers: []ProviderConfig{{Kind: providerOllama, BaseURL: "http://ollama.test/v1"}}})
+	var request map[string]any
+	apis.handle("ollama.test", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_ = json.NewDecoder(req.Body).Decode(&request)
+		writeJSON(w, map[string]any{
+			"model":   "llama3.1",
+			"choices": []any{map[string]any{"message": map[string]any{"content": "Hello from Llama."}, "finish_reason": "stop"}},
+			"usage":   map[string]any{"prompt_tokens": 12, "completion_tokens": 4},
+		})
+	}))
+
+	cmd := testMessage(42, "/model ollama:llama3.1")
+	cmd.Payload = "ollama:llama3.1"
+	if err := app.handleModel(app.bot.NewContext(tele.Update{Message: cmd})); err != nil {
+		t.Fatalf("handleModel: %v", err)
+	}
+	if texts := apis.sentTexts(); !strings.Contains(texts[len(texts)-1], "this chat") {
+		t.Errorf("sent %q, want the chosen model", texts[len(texts)-1])
+	}
+	if err := app.processMessage(context.Background(), testMessage(42, "Hi"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+
+	if calls := apis.callsTo(geminiHost, ":generateContent"); len(calls) != 0 {
+		t.Errorf("Gemini answered %d times, want the chat's model", len(calls))
+	}
+	if request["model"] != "llama3.1" || request["reasoning_effort"] != nil {
+		t.Errorf("request = %v, want llama3.1 without thinking", request)
+	}
+	messages, _ := request["messages"].([]any)
+	if len(messages) < 2 || messages[0].(map[string]any)["role"] != "system" {
+		t.Errorf("messages = %v, want the system instruction first", messages)
+	}
+	texts := apis.sentTexts()
+	if reply := texts[len(texts)-1]; !strings.Contains(reply, "Hello from Llama") || strings.Contains(reply, "instead of") {
+		t.Errorf("reply = %q, want the answer without a fallback notice", reply)
+	}
+}
+
+func TestAnthropicProviderCallsFunctions(t *testing.T) {
+	app, apis := newTestApp(t, Config{
+		Model:     "claude:claude-sonnet-4-5",
+		Providers: []ProviderConfig{{Name: "claude", Kind: providerAnthropic, APIKey: "sk-ant", BaseURL: "https://anthropic.test/v1"}},
+	})
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	apis.handle("anthropic.test", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		mu.Lock()
+		requests = append(requests, string(body))
+		first := len(requests) == 1
+		mu.Unlock()
+		if req.Header.Get("x-api-key") != "sk-ant" {
+			http.Error(w, `{"error":{"type":"authentication_error","message":"bad key"}}`, http.StatusUnauthorized)
+			return
+		}
+		content := []any{map[string]any{"type": "text", "text": "That is 10 km."}}
+		if first {
+			content = []any{
+				map[string]any{"type": "thinking", "thinking": "Convert miles.", "signature": "sig"},
+				map[string]any{"type": "tool_use", "id": "toolu_1", "name": "convert_units", "input": map[string]any{"value": 6.2137, "from": "mi", "to": "km"}},
+			}
+		}
+		writeJSON(w, map[string]any{"model": "claude-sonnet-4-5", "content": content, "stop_reason": "end_turn", "usage": map[string]any{"input_tokens": 20, "output_tokens": 8}})
+	}))
+
+	if err := app.processMessage(context.Background(), testMessage(42, "6.2137 miles in km?"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(requests) != 2 {
+		t.Fatalf("Anthropic called %d times, want the call and the answer", len(requests))
+	}
+	if !strings.Contains(requests[0], `"name":"convert_units"`) || !strings.Contains(requests[0], `"budget_tokens"`) {
+		t.Errorf("first request %s misses the functions or the thinking budget", requests[0])
+	}
+	for _, want := range []string{`"tool_use_id":"toolu_1"`, `"signature":"sig"`} {
+		if !strings.Contains(requests[1], want) {
+			t.Errorf("second request misses %s", want)
+		}
+	}
+	if texts := apis.sentTexts(); !strings.Contains(texts[len(texts)-1], "10 km") {
+		t.Errorf("reply = %q", texts[len(texts)-1])
+	}
+}
+
+func TestForCapabilities(t *testing.T) {
+	contents := []*genai.Content{genai.NewContentFromParts([]*genai.Part{
+		genai.NewPartFromText("What is this?"),
+		genai.NewPartFromBytes([]byte{0x89}, "image/png"),
+	}, genai.RoleUser)}
+	cfg := &genai.GenerateContentConfig{
+		ThinkingConfig: &genai.ThinkingConfig{ThinkingBudget: genai.Ptr[int32](1024)},
+		Tools:          builtinTools(nil),
+	}
+
+	out, adapted := forCapabilities(capabilities{}, contents, cfg)
+	if adapted.ThinkingConfig != nil || adapted.Tools != nil {
+		t.Errorf("config kept unsupported features: %+v", adapted)
+	}
+	if cfg.ThinkingConfig == nil || contents[0].Parts[1].InlineData == nil {
+		t.Error("forCapabilities changed its arguments")
+	}
+	if part := out[0].Parts[1]; part.InlineData != nil || !strings.Contains(part.Text, "left out") {
+		t.Errorf("image part = %+v, want a note", part)
+	}
+
+	full := capabilities{thinking: true, tools: true, multimodal: true}
+	if out, adapted := forCapabilities(full, contents, cfg); adapted != cfg || &out[0] != &contents[0] {
+		t.Error("copied a request the provider fully supports")
+	}
+}
+
+func TestValidateProviders(t *testing.T) {
+	cases := []struct {
+		providers []ProviderConfig
+		models    []string
+		want      string
+	}{
+		{[]ProviderConfig{{Kind: "mistral"}}, nil, "unknown kind"},
+		{[]ProviderConfig{{Kind: providerOpenAI}}, nil, "API key is required"},
+		{[]ProviderConfig{{Kind: providerOllama}, {Kind: providerOllama}}, nil, "used twice"},
+		{[]ProviderConfig{{Kind: providerOllama, BaseURL: "localhost:11434"}}, nil, "not an http or https URL"},
+		{nil, []string{"openai:gpt-4o"}, "no provider named openai"},
+	}
+	for _, tc := range cases {
+		if err := validateProviders(tc.providers, tc.models...); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("validateProviders(%+v, %v) = %v, want %q", tc.providers, tc.models, err, tc.want)
+		}
+	}
+	if err := validateProviders([]ProviderConfig{{Name: "local", Kind: providerOllama}}, "local:qwen3", "gemini-2.5-pro", ""); err != nil {
+		t.Errorf("valid providers: %v", err)
+	}
+}
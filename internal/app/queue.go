@@ -0,0 +1,245 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	maxQueuedPerChat    = 5
+	cancelRequestUnique = "cancel_request"
+)
+
+type chatJob func(ctx context.Context) error
+
+var (
+	errNothingToCancel = errors.New("no request in progress")
+	errNotYourRequest  = errors.New("request started by another user")
+	errQueueFull       = errors.New("too many queued messages")
+	errDraining        = errors.New("queue is draining")
+)
+
+// chatQueue runs jobs for a chat one at a time so concurrent messages never
+// interleave their history updates. Each chat gets a worker goroutine only while
+// it has work.
+type chatQueue struct {
+	mu      sync.Mutex
+	workers map[int64]*chatWorker
+	// draining turns new jobs away while the bot prepares to shut down.
+	draining bool
+}
+
+type chatWorker struct {
+	pending []queuedJob
+	cancel  context.CancelFunc
+	// userID started the in-flight job.
+	userID int64
+}
+
+// queuedJob is a job with the user it runs for, zero for jobs the bot starts
+// on its own such as reminders, and the logger carrying its request ID.
+type queuedJob struct {
+	job    chatJob
+	userID int64
+	logger *slog.Logger
+}
+
+func newChatQueue() *chatQueue {
+	return &chatQueue{workers: make(map[int64]*chatWorker)}
+}
+
+// enqueue schedules job for chatID on behalf of userID. It reports how many
+// jobs are ahead of it (including the in-flight one), errQueueFull when the
+// chat's queue is full and errDraining while the queue is draining.
+func (q *chatQueue) enqueue(chatID, userID int64, job chatJob) (int, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.draining {
+		return 0, errDraining
+	}
+
+	logger := slog.Default().With("request_id", newRequestID(), "chat_id", chatID, "user_id", userID)
+	next := queuedJob{job: job, userID: userID, logger: logger}
+	w, busy := q.workers[chatID]
+	if !busy {
+		w = &chatWorker{}
+		q.workers[chatID] = w
+		go q.run(chatID, w, next)
+		return 0, nil
+	}
+	if len(w.pending) >= maxQueuedPerChat {
+		return len(w.pending) + 1, errQueueFull
+	}
+	w.pending = append(w.pending, next)
+	return len(w.pending), nil
+}
+
+// drain stops accepting jobs and waits until the running and queued ones have
+// finished or ctx ends. The queue keeps rejecting jobs until resume.
+func (q *chatQueue) drain(ctx context.Context) error {
+	q.mu.Lock()
+	q.draining = true
+	q.mu.Unlock()
+
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if busy, _ := q.stats(); busy == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// resume accepts jobs again after drain.
+func (q *chatQueue) resume() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.draining = false
+}
+
+// isDraining reports whether the queue turns new jobs away.
+func (q *chatQueue) isDraining() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.draining
+}
+
+// stats reports how many chats have a job running and how many jobs wait behind them.
+func (q *chatQueue) stats() (busy, pending int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for _, w := range q.workers {
+		pending += len(w.pending)
+	}
+	return len(q.workers), pending
+}
+
+// busy reports whether chatID has a job running or waiting.
+func (q *chatQueue) busy(chatID int64) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	_, busy := q.workers[chatID]
+	return busy
+}
+
+func (q *chatQueue) run(chatID int64, w *chatWorker, next queuedJob) {
+	for next.job != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		q.mu.Lock()
+		w.cancel = cancel
+		w.userID = next.userID
+		q.mu.Unlock()
+
+		if err := next.job(withLogger(ctx, next.logger)); err != nil {
+			next.logger.Warn("request failed", "err", err)
+		}
+		cancel()
+
+		q.mu.Lock()
+		w.cancel = nil
+		if len(w.pending) == 0 {
+			delete(q.workers, chatID)
+			next = queuedJob{}
+		} else {
+			next = w.pending[0]
+			w.pending = w.pending[1:]
+		}
+		q.mu.Unlock()
+	}
+}
+
+// cancelCurrent aborts the in-flight job for chatID when userID started it,
+// or regardless of who started it when force is set.
+func (q *chatQueue) cancelCurrent(chatID, userID int64, force bool) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	w, ok := q.workers[chatID]
+	switch {
+	case !ok || w.cancel == nil:
+		return errNothingToCancel
+	case !force && w.userID != userID:
+		return errNotYourRequest
+	}
+	w.cancel()
+	return nil
+}
+
+// handleCancelRequest cancels the chat's in-flight request for the user who
+// started it or a chat admin.
+func (a *App) handleCancelRequest(c tele.Context) error {
+	chatID, userID := c.Chat().ID, userIDOf(c.Sender())
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	// In a private chat every request is the user's own.
+	err := a.queue.cancelCurrent(chatID, userID, c.Chat().Type == tele.ChatPrivate)
+	if errors.Is(err, errNotYourRequest) && a.isChatAdmin(c) {
+		err = a.queue.cancelCurrent(chatID, userID, true)
+	}
+	if c.Callback() != nil {
+		if errors.Is(err, errNotYourRequest) {
+			if err := c.Respond(&tele.CallbackResponse{Text: tr(lang, "cancel_not_yours")}); err != nil {
+				slog.Warn("callback acknowledge failed", "err", err)
+			}
+			return nil
+		}
+		if err := c.Respond(); err != nil {
+			slog.Warn("callback acknowledge failed", "err", err)
+		}
+	}
+
+	body := tr(lang, "cancel_started")
+	switch {
+	case errors.Is(err, errNothingToCancel):
+		body = tr(lang, "cancel_nothing")
+	case errors.Is(err, errNotYourRequest):
+		body = tr(lang, "cancel_not_yours")
+	}
+	_, err = a.sendWithFallback(inTopic(c.Chat(), c.Message()), body, &tele.SendOptions{DisableWebPagePreview: true})
+	return err
+}
+
+// isChatAdmin reports whether the sender administers the chat, including
+// admins posting anonymously as the group.
+func (a *App) isChatAdmin(c tele.Context) bool {
+	sender := c.Sender()
+	if sender == nil {
+		return false
+	}
+	if msg := c.Message(); msg != nil && msg.SenderChat != nil && msg.SenderChat.ID == c.Chat().ID {
+		return true
+	}
+	member, err := a.bot.ChatMemberOf(c.Chat(), sender)
+	if err != nil {
+		a.telegramFailed("check chat admin", c.Chat().ID, err)
+		return false
+	}
+	return member.Role == tele.Administrator || member.Role == tele.Creator
+}
+
+// userIDOf returns the ID of user, or zero when there is none.
+func userIDOf(user *tele.User) int64 {
+	if user == nil {
+		return 0
+	}
+	return user.ID
+}
+
+func (a *App) notifyQueued(to tele.Recipient, lang language, ahead int) error {
+	body := tr(lang, "queued_one")
+	if ahead > 1 {
+		body = tr(lang, "queued_many")
+	}
+	markup := &tele.ReplyMarkup{}
+	markup.Inline(markup.Row(markup.Data(tr(lang, "btn_cancel_request"), cancelRequestUnique)))
+	_, err := a.sendWithFallback(to, body, &tele.SendOptions{ReplyMarkup: markup, DisableWebPagePreview: true})
+	return err
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// startBlockingJob runs a job for userID in chatID that lasts until it is
+// cancelled, and returns a channel closed when it ends.
+func startBlockingJob(t *testing.T, q *chatQueue, chatID, userID int64) <-chan struct{} {
+	t.Helper()
+	started, done := make(chan struct{}), make(chan struct{})
+	q.enqueue(chatID, userID, func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		close(done)
+		return nil
+	})
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("job did not start")
+	}
+	return done
+}
+
+func TestCancelCurrentChecksTheRequester(t *testing.T) {
+	q := newChatQueue()
+	const chatID = -100
+	if err := q.cancelCurrent(chatID, 1, false); !errors.Is(err, errNothingToCancel) {
+		t.Fatalf("cancel with nothing running: err = %v", err)
+	}
+	done := startBlockingJob(t, q, chatID, 1)
+	if err := q.cancelCurrent(chatID, 2, false); !errors.Is(err, errNotYourRequest) {
+		t.Fatalf("cancel by another user: err = %v", err)
+	}
+	if err := q.cancelCurrent(chatID, 1, false); err != nil {
+		t.Fatalf("cancel by the requester: %v", err)
+	}
+	<-done
+
+	done = startBlockingJob(t, q, chatID, 1)
+	if err := q.cancelCurrent(chatID, 2, true); err != nil {
+		t.Fatalf("forced cancel: %v", err)
+	}
+	<-done
+}
+
+func TestCancelCommandInGroup(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	const chatID, owner, member = -100, 1, 2
+	done := startBlockingJob(t, app.queue, chatID, owner)
+
+	cancel := func(userID int64) {
+		msg := &tele.Message{
+			ID:     5,
+			Chat:   &tele.Chat{ID: chatID, Type: tele.ChatSuperGroup},
+			Sender: &tele.User{ID: userID},
+			Text:   "/cancel",
+		}
+		if err := app.handleCancelRequest(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("handleCancelRequest: %v", err)
+		}
+	}
+	cancel(member)
+	select {
+	case <-done:
+		t.Fatal("another member cancelled the request")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if texts := apis.sentTexts(); !containsText(texts, "Only the person who sent the current request") {
+		t.Errorf("sent %q, want the member told it is not theirs", texts)
+	}
+	cancel(owner)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("the requester could not cancel")
+	}
+}
@@ -0,0 +1,344 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	recallCommand = "/recall"
+	// recallMaxExchanges bounds the archive of a chat; the oldest exchanges
+	// go first.
+	recallMaxExchanges = 2000
+	// recallExchangeRunes caps the text kept of one exchange.
+	recallExchangeRunes = 3000
+	recallIndexTimeout  = time.Minute
+	// recallTopExchanges is how many exchanges /recall answers from, and
+	// recallAutoExchanges how many a regular message may pull back when they
+	// score at least recallMinScore.
+	recallTopExchanges  = 4
+	recallAutoExchanges = 2
+	recallMinScore      = 0.75
+)
+
+// recallExchange is a user message with the replies to it, archived with its
+// embedding when it left the history of a chat.
+type recallExchange struct {
+	At     time.Time `json:"at"`
+	Text   string    `json:"text"`
+	Vector []float32 `json:"vector"`
+}
+
+// recallArchive holds the trimmed exchanges of a chat, oldest first.
+type recallArchive struct {
+	// Manual turns off the automatic retrieval; /recall still searches.
+	Manual    bool             `json:"manual,omitempty"`
+	Exchanges []recallExchange `json:"exchanges"`
+}
+
+// recallStore keeps the archive of each chat in a JSON file of its own under
+// dir, loaded on first use. Every change rewrites the file atomically.
+type recallStore struct {
+	mu    sync.Mutex
+	dir   string
+	chats map[int64]*recallArchive
+}
+
+func newRecallStore(dir string) *recallStore {
+	return &recallStore{dir: dir, chats: make(map[int64]*recallArchive)}
+}
+
+func (s *recallStore) path(chatID int64) string {
+	return filepath.Join(s.dir, strconv.FormatInt(chatID, 10)+".json")
+}
+
+// load returns the archive of chatID; the caller holds s.mu.
+func (s *recallStore) load(chatID int64) (*recallArchive, error) {
+	if archive, ok := s.chats[chatID]; ok {
+		return archive, nil
+	}
+	archive := &recallArchive{}
+	if err := readJSONFile(s.path(chatID), archive); err != nil {
+		return nil, err
+	}
+	s.chats[chatID] = archive
+	return archive, nil
+}
+
+// save writes the archive of chatID; the caller holds s.mu.
+func (s *recallStore) save(chatID int64, archive *recallArchive) error {
+	if len(archive.Exchanges) == 0 && !archive.Manual {
+		if err := os.Remove(s.path(chatID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	return writeJSONFile(s.path(chatID), archive)
+}
+
+// add archives exchanges, dropping the oldest beyond recallMaxExchanges.
+func (s *recallStore) add(chatID int64, exchanges []recallExchange) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	archive, err := s.load(chatID)
+	if err != nil {
+		return err
+	}
+	archive.Exchanges = append(archive.Exchanges, exchanges...)
+	if extra := len(archive.Exchanges) - recallMaxExchanges; extra > 0 {
+		archive.Exchanges = slices.Delete(archive.Exchanges, 0, extra)
+	}
+	return s.save(chatID, archive)
+}
+
+// count returns how many exchanges chatID archived and whether they are
+// retrieved automatically.
+func (s *recallStore) count(chatID int64) (int, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	archive, err := s.load(chatID)
+	if err != nil {
+		return 0, false, err
+	}
+	return len(archive.Exchanges), !archive.Manual, nil
+}
+
+func (s *recallStore) setAuto(chatID int64, on bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	archive, err := s.load(chatID)
+	if err != nil {
+		return err
+	}
+	archive.Manual = !on
+	return s.save(chatID, archive)
+}
+
+// clear removes the archive of chatID, keeping its retrieval setting.
+func (s *recallStore) clear(chatID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	archive, err := s.load(chatID)
+	if err != nil {
+		return err
+	}
+	archive.Exchanges = nil
+	return s.save(chatID, archive)
+}
+
+// purge forgets everything about chatID.
+func (s *recallStore) purge(chatID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.chats, chatID)
+	if err := os.Remove(s.path(chatID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// migrate moves the archive of chat from to chat to.
+func (s *recallStore) migrate(from, to int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	archive, err := s.load(from)
+	if err != nil || (len(archive.Exchanges) == 0 && !archive.Manual) {
+		return err
+	}
+	delete(s.chats, from)
+	s.chats[to] = archive
+	if err := s.save(to, archive); err != nil {
+		return err
+	}
+	return os.Remove(s.path(from))
+}
+
+// search returns up to k exchanges of chatID that score at least minScore
+// against query, best first. With auto set, it returns nothing when the chat
+// turned the automatic retrieval off.
+func (s *recallStore) search(chatID int64, query []float32, k int, minScore float64, auto bool) []recallExchange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	archive, err := s.load(chatID)
+	if err != nil || len(archive.Exchanges) == 0 || (auto && archive.Manual) {
+		return nil
+	}
+	type scored struct {
+		exchange recallExchange
+		score    float64
+	}
+	var ranked []scored
+	for _, e := range archive.Exchanges {
+		if score := cosine(query, e.Vector); score >= minScore {
+			ranked = append(ranked, scored{e, score})
+		}
+	}
+	slices.SortStableFunc(ranked, func(x, y scored) int {
+		switch {
+		case x.score > y.score:
+			return -1
+		case x.score < y.score:
+			return 1
+		}
+		return 0
+	})
+	hits := make([]recallExchange, 0, k)
+	for _, r := range ranked[:min(k, len(ranked))] {
+		hits = append(hits, r.exchange)
+	}
+	return hits
+}
+
+// searchable reports whether chatID has archived exchanges, without reading
+// the archive when it was never loaded and has no file.
+func (s *recallStore) searchable(chatID int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if archive, ok := s.chats[chatID]; ok {
+		return len(archive.Exchanges) > 0
+	}
+	_, err := os.Stat(s.path(chatID))
+	return err == nil
+}
+
+// recallExchanges groups turns into exchanges, each a user message with the
+// replies that follow it, rendered as a transcript.
+func recallExchanges(turns []*genai.Content) []string {
+	var exchanges []string
+	var group []*genai.Content
+	flush := func() {
+		if text := transcript(group); text != "" {
+			if runes := []rune(text); len(runes) > recallExchangeRunes {
+				text = string(runes[:recallExchangeRunes]) + "…"
+			}
+			exchanges = append(exchanges, text)
+		}
+		group = nil
+	}
+	for _, turn := range turns {
+		if turn != nil && turn.Role == genai.RoleUser && len(group) > 0 {
+			flush()
+		}
+		group = append(group, turn)
+	}
+	flush()
+	return exchanges
+}
+
+// archiveTurns embeds turns that left the history of a chat so /recall and
+// the automatic retrieval can still find them.
+func (a *App) archiveTurns(ctx context.Context, chatID int64, turns []*genai.Content) {
+	texts := recallExchanges(turns)
+	if len(texts) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, recallIndexTimeout)
+	defer cancel()
+	vectors, err := a.embedTexts(ctx, chatID, texts, "RETRIEVAL_DOCUMENT")
+	if err != nil {
+		logFrom(ctx).Warn("embed trimmed turns failed", "err", err)
+		return
+	}
+	now := time.Now().UTC()
+	exchanges := make([]recallExchange, len(texts))
+	for i, text := range texts {
+		exchanges[i] = recallExchange{At: now, Text: text, Vector: vectors[i]}
+	}
+	if err := a.recall.add(chatID, exchanges); err != nil {
+		logFrom(ctx).Warn("save recall archive failed", "err", err)
+	}
+}
+
+// recallInstruction retrieves earlier exchanges of the chat that left its
+// history and relate to prompt. A regular message pulls back only close
+// matches, and none when the chat turned the automatic retrieval off; an
+// explicit /recall takes the best ones whatever their score. It is empty when
+// nothing was found or the lookup fails.
+func (a *App) recallInstruction(ctx context.Context, chatID int64, prompt string, explicit bool) string {
+	if strings.TrimSpace(prompt) == "" || !a.recall.searchable(chatID) {
+		return ""
+	}
+	vectors, err := a.embedTexts(ctx, chatID, []string{prompt}, "RETRIEVAL_QUERY")
+	if err != nil {
+		logFrom(ctx).Warn("recall lookup failed", "err", err)
+		return ""
+	}
+	var hits []recallExchange
+	if explicit {
+		hits = a.recall.search(chatID, vectors[0], recallTopExchanges, -1, false)
+	} else {
+		hits = a.recall.search(chatID, vectors[0], recallAutoExchanges, recallMinScore, true)
+	}
+	if len(hits) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Earlier exchanges of this chat that no longer fit in the conversation history, and may relate to the user's message, follow with the date they left it. ")
+	b.WriteString("Use them where they help, as something said before rather than just now, and ignore those that do not relate to the message.\n")
+	for _, hit := range hits {
+		fmt.Fprintf(&b, "\n--- %s ---\n%s\n", hit.At.Format(time.DateOnly), hit.Text)
+	}
+	return b.String()
+}
+
+// handleRecall searches the chat's archive of trimmed exchanges:
+//
+//	/recall <query>   answers the query from the closest earlier exchanges
+//	/recall on|off    turns the automatic retrieval for regular messages on or off
+//	/recall clear     deletes the archive
+//	/recall           shows the archive's size and setting
+func (a *App) handleRecall(c tele.Context) error {
+	msg := c.Message()
+	chat := c.Chat()
+	lang := a.chatLanguage(chat, c.Sender())
+	reply := func(body string) error {
+		_, err := a.sendWithFallback(chat, body, &tele.SendOptions{ThreadID: messageTopic(msg), DisableWebPagePreview: true})
+		return err
+	}
+	payload := strings.TrimSpace(msg.Payload)
+
+	var err error
+	switch strings.ToLower(payload) {
+	case "":
+		n, auto, err := a.recall.count(chat.ID)
+		if err != nil {
+			a.chatLog(chat.ID).Warn("load recall archive failed", "chat_id", chat.ID, "err", err)
+			return reply(tr(lang, "recall_failed"))
+		}
+		state := tr(lang, "recall_auto_off")
+		if auto {
+			state = tr(lang, "recall_auto_on")
+		}
+		return reply(tr(lang, "recall_usage", n) + "\n\n" + state)
+	case "on", "off":
+		on := strings.EqualFold(payload, "on")
+		if err = a.recall.setAuto(chat.ID, on); err == nil {
+			if on {
+				return reply(tr(lang, "recall_auto_on"))
+			}
+			return reply(tr(lang, "recall_auto_off"))
+		}
+	case "clear":
+		if err = a.recall.clear(chat.ID); err == nil {
+			return reply(tr(lang, "recall_cleared"))
+		}
+	default:
+		if !a.recall.searchable(chat.ID) {
+			return reply(tr(lang, "recall_empty"))
+		}
+		return a.enqueueTurn(withText(msg, payload), turnOptions{recall: true})
+	}
+	a.chatLog(chat.ID).Warn("save recall archive failed", "chat_id", chat.ID, "err", err)
+	return reply(tr(lang, "recall_failed"))
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestTrimmedTurnsCanBeRecalled(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.answer = func(model string, body []byte) []any {
+		if model == summaryModel {
+			return []any{map[string]any{"text": "- The user is planning a wedding."}}
+		}
+		return []any{map[string]any{"text": "Sure."}}
+	}
+	apis.tokens = func(body []byte) int {
+		if strings.Contains(string(body), "sounds lovely") || strings.Contains(string(body), "great name") {
+			return 30000
+		}
+		return 10
+	}
+	recall := func(payload string) string {
+		t.Helper()
+		msg := testMessage(42, "/recall "+payload)
+		msg.Payload = payload
+		if err := app.handleRecall(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("handleRecall(%q): %v", payload, err)
+		}
+		texts := apis.sentTexts()
+		return texts[len(texts)-1]
+	}
+	ask := func(text string, opts turnOptions) string {
+		t.Helper()
+		if err := app.processMessage(context.Background(), testMessage(42, text), opts); err != nil {
+			t.Fatalf("processMessage: %v", err)
+		}
+		calls := apis.callsTo(geminiHost, geminiModel+":generateContent")
+		return string(calls[len(calls)-1].body)
+	}
+
+	if got := recall("wedding venue"); !strings.Contains(got, "nothing to recall") {
+		t.Errorf("/recall before anything was trimmed answered %q", got)
+	}
+	session := app.sessions.get(42)
+	session.appendTurn(genai.NewContentFromText("Our wedding venue is the old lighthouse in Brest.", genai.RoleUser),
+		genai.NewContentFromText("A lighthouse wedding in Brest sounds lovely.", genai.RoleModel), "")
+	session.appendTurn(genai.NewContentFromText("My cat is called Pixel.", genai.RoleUser),
+		genai.NewContentFromText("Pixel is a great name for a cat.", genai.RoleModel), "")
+	ask("Hi", turnOptions{})
+	if n, auto, err := app.recall.count(42); err != nil || n != 2 || !auto {
+		t.Fatalf("archive = %d exchanges (auto %v), %v; want the two trimmed exchanges", n, auto, err)
+	}
+
+	body := ask("Our wedding venue is the old lighthouse in Brest, right?", turnOptions{})
+	if !strings.Contains(body, "Earlier exchanges") || !strings.Contains(body, "A lighthouse wedding in Brest") || strings.Contains(body, "Pixel") {
+		t.Errorf("the wedding exchange was not the one pulled back: %s", body)
+	}
+	if body := ask("How do I bake bread?", turnOptions{}); strings.Contains(body, "Earlier exchanges") {
+		t.Errorf("an unrelated message pulled back exchanges: %s", body)
+	}
+
+	if got := recall("off"); !strings.Contains(got, "only searched with /recall") {
+		t.Errorf("/recall off answered %q", got)
+	}
+	if body := ask("Our wedding venue is the old lighthouse in Brest, right?", turnOptions{}); strings.Contains(body, "Earlier exchanges") {
+		t.Errorf("exchanges were pulled back with the automatic retrieval off: %s", body)
+	}
+	if body := ask("What is the cat called?", turnOptions{recall: true}); !strings.Contains(body, "Pixel is a great name") {
+		t.Errorf("an explicit recall missed the cat: %s", body)
+	}
+
+	reopened := newRecallStore(filepath.Join(app.dataDir, "recall"))
+	if n, auto, err := reopened.count(42); err != nil || n != 2 || auto {
+		t.Errorf("persisted archive = %d exchanges (auto %v), %v", n, auto, err)
+	}
+	recall("clear")
+	if app.recall.searchable(42) {
+		t.Error("the archive is still searchable after /recall clear")
+	}
+}
+
+func TestRecallExchanges(t *testing.T) {
+	turns := []*genai.Content{
+		genai.NewContentFromText("first", genai.RoleModel),
+		genai.NewContentFromText("question", genai.RoleUser),
+		genai.NewContentFromText("answer", genai.RoleModel),
+		genai.NewContentFromText("more", genai.RoleModel),
+		genai.NewContentFromText(strings.Repeat("long ", recallExchangeRunes), genai.RoleUser),
+	}
+	got := recallExchanges(turns)
+	if len(got) != 3 || got[1] != "User: question\n\nAssistant: answer\n\nAssistant: more" {
+		t.Fatalf("exchanges = %q", got)
+	}
+	if n := len([]rune(got[2])); n != recallExchangeRunes+1 {
+		t.Errorf("long exchange kept %d runes, want %d", n, recallExchangeRunes+1)
+	}
+}
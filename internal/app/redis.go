@@ -0,0 +1,436 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisKeyPrefix namespaces the keys of the bot in a shared Redis.
+	redisKeyPrefix = "eteon:"
+	// redisTimeout bounds a single call to Redis; the bot falls back to what it
+	// holds in memory when Redis does not answer in time.
+	redisTimeout = 2 * time.Second
+)
+
+// redisState keeps the sessions, reply artifacts, usage, privacy and reply
+// modes in Redis, so several bot instances serve the same chats with the
+// same state:
+//
+//	eteon:session:<chat>:<thread>   hash of the session JSON and its revision
+//	eteon:artifact:<id>             artifact JSON, expiring with the buttons
+//	eteon:artifacts:<chat>          set of the artifact IDs of a chat
+//	eteon:usage:<ledger>:<period>   hash of the usage totals of a day or month
+//	eteon:privacy                   hash of the chats in privacy mode and since when
+//	eteon:replyto                   hash of the reply modes chats chose, 1 or 0
+type redisState struct {
+	client *redis.Client
+}
+
+// openRedisState connects to the Redis at url, a redis:// or rediss:// URL.
+func openRedisState(ctx context.Context, url string) (*redisState, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("parse redis url: %w", err)
+	}
+	client := redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(ctx, redisTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("ping redis at %s: %w", opts.Addr, err)
+	}
+	return &redisState{client: client}, nil
+}
+
+func (r *redisState) addr() string {
+	return r.client.Options().Addr
+}
+
+func (r *redisState) Close() error {
+	return r.client.Close()
+}
+
+func sessionRedisKey(key sessionKey) string {
+	return fmt.Sprintf("%ssession:%d:%d", redisKeyPrefix, key.chatID, key.thread)
+}
+
+// chatSessionKeys lists the session keys of a chat and its topics.
+func (r *redisState) chatSessionKeys(ctx context.Context, chatID int64) ([]string, error) {
+	pattern := fmt.Sprintf("%ssession:%d:*", redisKeyPrefix, chatID)
+	var keys []string
+	iter := r.client.Scan(ctx, 0, pattern, 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
+// loadSession returns the stored session and its revision, or nil data when
+// there is none.
+func (r *redisState) loadSession(ctx context.Context, key sessionKey) ([]byte, int64, error) {
+	values, err := r.client.HMGet(ctx, sessionRedisKey(key), "data", "rev").Result()
+	if err != nil || values[0] == nil {
+		return nil, 0, err
+	}
+	data, _ := values[0].(string)
+	rev, _ := values[1].(string)
+	n, _ := strconv.ParseInt(rev, 10, 64)
+	return []byte(data), n, nil
+}
+
+// sessionRevision returns the revision of the stored session, zero when there
+// is none.
+func (r *redisState) sessionRevision(ctx context.Context, key sessionKey) (int64, error) {
+	rev, err := r.client.HGet(ctx, sessionRedisKey(key), "rev").Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return rev, err
+}
+
+// saveSession stores data as the session and returns its new revision.
+func (r *redisState) saveSession(ctx context.Context, key sessionKey, data []byte) (int64, error) {
+	k := sessionRedisKey(key)
+	var rev *redis.IntCmd
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, k, "data", data)
+		rev = pipe.HIncrBy(ctx, k, "rev", 1)
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return rev.Val(), nil
+}
+
+// deleteSessions drops the sessions of a chat and its topics.
+func (r *redisState) deleteSessions(ctx context.Context, chatID int64) error {
+	keys, err := r.chatSessionKeys(ctx, chatID)
+	if err != nil || len(keys) == 0 {
+		return err
+	}
+	return r.client.Del(ctx, keys...).Err()
+}
+
+// migrateSessions renames the sessions of chat from to chat to, keeping any
+// session to already has.
+func (r *redisState) migrateSessions(ctx context.Context, from, to int64) error {
+	keys, err := r.chatSessionKeys(ctx, from)
+	if err != nil {
+		return err
+	}
+	fromPrefix := fmt.Sprintf("%ssession:%d:", redisKeyPrefix, from)
+	toPrefix := fmt.Sprintf("%ssession:%d:", redisKeyPrefix, to)
+	for _, k := range keys {
+		renamed, err := r.client.RenameNX(ctx, k, toPrefix+strings.TrimPrefix(k, fromPrefix)).Result()
+		if err != nil {
+			return err
+		}
+		if !renamed {
+			if err := r.client.Del(ctx, k).Err(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func artifactRedisKey(id string) string {
+	return redisKeyPrefix + "artifact:" + id
+}
+
+func chatArtifactsRedisKey(chatID int64) string {
+	return fmt.Sprintf("%sartifacts:%d", redisKeyPrefix, chatID)
+}
+
+// saveArtifact stores art under id until ttl passes.
+func (r *redisState) saveArtifact(ctx context.Context, id string, art *responseArtifacts, ttl time.Duration) error {
+	raw, err := json.Marshal(art)
+	if err != nil {
+		return err
+	}
+	index := chatArtifactsRedisKey(art.ChatID)
+	_, err = r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, artifactRedisKey(id), raw, ttl)
+		pipe.SAdd(ctx, index, id)
+		if ttl > 0 {
+			pipe.Expire(ctx, index, ttl)
+		}
+		return nil
+	})
+	return err
+}
+
+// loadArtifact returns the artifact stored under id, or nil when there is none.
+func (r *redisState) loadArtifact(ctx context.Context, id string) (*responseArtifacts, error) {
+	raw, err := r.client.Get(ctx, artifactRedisKey(id)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	art := &responseArtifacts{}
+	if err := json.Unmarshal(raw, art); err != nil {
+		return nil, fmt.Errorf("decode artifact %s: %w", id, err)
+	}
+	return art, nil
+}
+
+// purgeArtifacts drops the artifacts of a chat and returns how many were
+// still stored.
+func (r *redisState) purgeArtifacts(ctx context.Context, chatID int64) (int, error) {
+	index := chatArtifactsRedisKey(chatID)
+	ids, err := r.client.SMembers(ctx, index).Result()
+	if err != nil {
+		return 0, err
+	}
+	keys := []string{index}
+	for _, id := range ids {
+		keys = append(keys, artifactRedisKey(id))
+	}
+	n, err := r.client.Del(ctx, keys...).Result()
+	if n > 0 {
+		n-- // the index
+	}
+	return int(n), err
+}
+
+// migrateArtifacts moves the artifacts of chat from to chat to; ttl is how
+// long the index of to lives.
+func (r *redisState) migrateArtifacts(ctx context.Context, from, to int64, ttl time.Duration) error {
+	index := chatArtifactsRedisKey(from)
+	ids, err := r.client.SMembers(ctx, index).Result()
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+	for _, id := range ids {
+		art, err := r.loadArtifact(ctx, id)
+		if err != nil {
+			return err
+		}
+		if art == nil {
+			continue
+		}
+		art.ChatID = to
+		raw, err := json.Marshal(art)
+		if err != nil {
+			return err
+		}
+		if err := r.client.SetArgs(ctx, artifactRedisKey(id), raw, redis.SetArgs{KeepTTL: true}).Err(); err != nil {
+			return err
+		}
+	}
+	target := chatArtifactsRedisKey(to)
+	if err := r.client.SUnionStore(ctx, target, target, index).Err(); err != nil {
+		return err
+	}
+	if ttl > 0 {
+		if err := r.client.Expire(ctx, target, ttl).Err(); err != nil {
+			return err
+		}
+	}
+	return r.client.Del(ctx, index).Err()
+}
+
+func usageRedisKey(ledger, period string) string {
+	return redisKeyPrefix + "usage:" + ledger + ":" + period
+}
+
+// addUsage adds u to the totals of ledger for day and month, which expire
+// once they are no longer kept.
+func (r *redisState) addUsage(ctx context.Context, ledger, day, month string, u tokenUsage) error {
+	_, err := r.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, p := range []struct {
+			period string
+			keep   time.Duration
+		}{
+			{day, (usageKeepDays + 1) * 24 * time.Hour},
+			{month, (usageKeepMonths + 1) * 31 * 24 * time.Hour},
+		} {
+			k := usageRedisKey(ledger, p.period)
+			pipe.HIncrBy(ctx, k, "requests", u.Requests)
+			pipe.HIncrBy(ctx, k, "prompt", u.Prompt)
+			pipe.HIncrBy(ctx, k, "candidates", u.Candidates)
+			pipe.HIncrBy(ctx, k, "thoughts", u.Thoughts)
+			pipe.HIncrByFloat(ctx, k, "cost_usd", u.CostUSD)
+			pipe.Expire(ctx, k, p.keep)
+		}
+		return nil
+	})
+	return err
+}
+
+// loadUsage returns the stored totals by ledger and period. Periods that are
+// no longer kept are skipped; they expire on their own.
+func (r *redisState) loadUsage(ctx context.Context, dayCutoff, monthCutoff string) (map[string]map[string]tokenUsage, error) {
+	stored := make(map[string]map[string]tokenUsage)
+	prefix := redisKeyPrefix + "usage:"
+	iter := r.client.Scan(ctx, 0, prefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		k := iter.Val()
+		name := strings.TrimPrefix(k, prefix)
+		i := strings.LastIndexByte(name, ':')
+		if i < 0 {
+			continue
+		}
+		ledger, period := name[:i], name[i+1:]
+		if (len(period) == len(usageDayLayout) && period < dayCutoff) || (len(period) == len(usageMonthLayout) && period < monthCutoff) {
+			continue
+		}
+		fields, err := r.client.HGetAll(ctx, k).Result()
+		if err != nil {
+			return nil, err
+		}
+		var u tokenUsage
+		u.Requests, _ = strconv.ParseInt(fields["requests"], 10, 64)
+		u.Prompt, _ = strconv.ParseInt(fields["prompt"], 10, 64)
+		u.Candidates, _ = strconv.ParseInt(fields["candidates"], 10, 64)
+		u.Thoughts, _ = strconv.ParseInt(fields["thoughts"], 10, 64)
+		u.CostUSD, _ = strconv.ParseFloat(fields["cost_usd"], 64)
+		if stored[ledger] == nil {
+			stored[ledger] = make(map[string]tokenUsage)
+		}
+		stored[ledger][period] = u
+	}
+	return stored, iter.Err()
+}
+
+// migrateUsage renames the totals of ledger from to ledger to, keeping the
+// periods to already has.
+func (r *redisState) migrateUsage(ctx context.Context, from, to string) error {
+	fromPrefix := redisKeyPrefix + "usage:" + from + ":"
+	var keys []string
+	iter := r.client.Scan(ctx, 0, fromPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+	for _, k := range keys {
+		renamed, err := r.client.RenameNX(ctx, k, usageRedisKey(to, strings.TrimPrefix(k, fromPrefix))).Result()
+		if err != nil {
+			return err
+		}
+		if !renamed {
+			if err := r.client.Del(ctx, k).Err(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// privacyRedisKey is the hash of the chats in privacy mode, mapping each to
+// the time it turned privacy mode on.
+const privacyRedisKey = redisKeyPrefix + "privacy"
+
+func (r *redisState) privacyChats(ctx context.Context) (map[int64]time.Time, error) {
+	fields, err := r.client.HGetAll(ctx, privacyRedisKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	chats := make(map[int64]time.Time, len(fields))
+	for field, value := range fields {
+		chatID, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			continue
+		}
+		since, _ := time.Parse(time.RFC3339Nano, value)
+		chats[chatID] = since
+	}
+	return chats, nil
+}
+
+func (r *redisState) privacySince(ctx context.Context, chatID int64) (time.Time, bool, error) {
+	value, err := r.client.HGet(ctx, privacyRedisKey, strconv.FormatInt(chatID, 10)).Result()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	since, _ := time.Parse(time.RFC3339Nano, value)
+	return since, true, nil
+}
+
+func (r *redisState) enablePrivacy(ctx context.Context, chatID int64, since time.Time) error {
+	return r.client.HSetNX(ctx, privacyRedisKey, strconv.FormatInt(chatID, 10), since.UTC().Format(time.RFC3339Nano)).Err()
+}
+
+func (r *redisState) disablePrivacy(ctx context.Context, chatID int64) error {
+	return r.client.HDel(ctx, privacyRedisKey, strconv.FormatInt(chatID, 10)).Err()
+}
+
+func (r *redisState) migratePrivacy(ctx context.Context, from, to int64) error {
+	since, on, err := r.privacySince(ctx, from)
+	if err != nil || !on {
+		return err
+	}
+	if err := r.enablePrivacy(ctx, to, since); err != nil {
+		return err
+	}
+	return r.disablePrivacy(ctx, from)
+}
+
+// replyToRedisKey is the hash of the chats that chose a reply mode, mapping
+// each to 1 when answers reply to their prompt and 0 when they do not.
+const replyToRedisKey = redisKeyPrefix + "replyto"
+
+func (r *redisState) replyToChoices(ctx context.Context) (map[int64]bool, error) {
+	fields, err := r.client.HGetAll(ctx, replyToRedisKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	chats := make(map[int64]bool, len(fields))
+	for field, value := range fields {
+		if chatID, err := strconv.ParseInt(field, 10, 64); err == nil {
+			chats[chatID] = value == "1"
+		}
+	}
+	return chats, nil
+}
+
+func (r *redisState) replyToChoice(ctx context.Context, chatID int64) (bool, bool, error) {
+	value, err := r.client.HGet(ctx, replyToRedisKey, strconv.FormatInt(chatID, 10)).Result()
+	if errors.Is(err, redis.Nil) {
+		return false, false, nil
+	}
+	return value == "1", err == nil, err
+}
+
+func (r *redisState) setReplyTo(ctx context.Context, chatID int64, on *bool) error {
+	field := strconv.FormatInt(chatID, 10)
+	switch {
+	case on == nil:
+		return r.client.HDel(ctx, replyToRedisKey, field).Err()
+	case *on:
+		return r.client.HSet(ctx, replyToRedisKey, field, "1").Err()
+	default:
+		return r.client.HSet(ctx, replyToRedisKey, field, "0").Err()
+	}
+}
+
+func (r *redisState) migrateReplyTo(ctx context.Context, from, to int64) error {
+	on, chosen, err := r.replyToChoice(ctx, from)
+	if err != nil || !chosen {
+		return err
+	}
+	if _, taken, err := r.replyToChoice(ctx, to); err != nil {
+		return err
+	} else if !taken {
+		if err := r.setReplyTo(ctx, to, &on); err != nil {
+			return err
+		}
+	}
+	return r.setReplyTo(ctx, from, nil)
+}
@@ -0,0 +1,204 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestRedisSharesSessionsAndArtifacts(t *testing.T) {
+	server := miniredis.RunT(t)
+	cfg := Config{RedisURL: "redis://" + server.Addr()}
+	first, apis := newTestApp(t, cfg)
+	cfg.TelegramToken, cfg.GeminiAPIKey, cfg.DataDir = "123:test-token", "test-key", t.TempDir()
+	second, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	apis.reply = "Noted, walrus."
+	if err := first.processMessage(context.Background(), testMessage(42, "Remember the word walrus."), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	first.sessions.persist(42)
+
+	session := second.sessions.get(42)
+	session.mu.Lock()
+	entries := len(session.history)
+	session.mu.Unlock()
+	if entries != 2 {
+		t.Fatalf("second instance sees %d history entries, want 2", entries)
+	}
+
+	var id string
+	for key := range first.artifacts.items {
+		id = key
+	}
+	if art, ok := second.artifacts.get(id); !ok || art.Reply != "Noted, walrus." {
+		t.Errorf("second instance artifact %s = %+v, %v", id, art, ok)
+	}
+
+	mine := first.sessions.get(42)
+	mine.mu.Lock()
+	mine.persona = "Answer like a pirate."
+	mine.mu.Unlock()
+	first.sessions.persist(42)
+	second.sessions.refresh(42)
+	session.mu.Lock()
+	persona := session.persona
+	session.mu.Unlock()
+	if persona != "Answer like a pirate." {
+		t.Errorf("refreshed persona = %q", persona)
+	}
+
+	followUp := testMessage(42, "Which word?")
+	followUp.ID = 2
+	if err := second.processMessage(context.Background(), followUp, turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	calls := apis.callsTo(geminiHost, ":generateContent")
+	if body := string(calls[len(calls)-1].body); !strings.Contains(body, "walrus") || !strings.Contains(body, "pirate") {
+		t.Errorf("second instance request lacks the shared session: %s", body)
+	}
+	second.sessions.persist(42)
+	first.sessions.refresh(42)
+	mine.mu.Lock()
+	entries = len(mine.history)
+	mine.mu.Unlock()
+	if entries != 4 {
+		t.Errorf("first instance sees %d history entries after refresh, want 4", entries)
+	}
+
+	first.sessions.migrate(42, 43)
+	if !server.Exists("eteon:session:43:0") || server.Exists("eteon:session:42:0") {
+		t.Errorf("migrated keys = %v", server.Keys())
+	}
+	first.sessions.remove(43)
+	if first.artifacts.purgeChat(42) != 2 {
+		t.Error("purge did not count the shared artifacts")
+	}
+	for _, key := range server.Keys() {
+		if strings.HasPrefix(key, "eteon:session:") || strings.HasPrefix(key, "eteon:artifacts:") {
+			t.Errorf("key %s survived the purge", key)
+		}
+	}
+}
+
+func TestRedisKeepsUsage(t *testing.T) {
+	server := miniredis.RunT(t)
+	cfg := Config{RedisURL: "redis://" + server.Addr()}
+	first, _ := newTestApp(t, cfg)
+	first.recordUsage(withUserID(context.Background(), 7), 42, "gemini-2.5-pro", &genai.GenerateContentResponseUsageMetadata{CandidatesTokenCount: 1000})
+	first.usage.migrate(42, -10042)
+
+	restarted, _ := newTestApp(t, cfg)
+	if day, month := restarted.usage.chatSnapshot(-10042); day.Requests != 1 || month.Candidates != 1000 {
+		t.Errorf("migrated chat usage after restart = %+v / %+v", day, month)
+	}
+	if day, _ := restarted.usage.chatSnapshot(42); day.Requests != 0 {
+		t.Errorf("old chat kept usage %+v", day)
+	}
+	if spent := restarted.usage.userSpentToday(7); spent != 0.01 {
+		t.Errorf("user spend after restart = $%g, want $0.01", spent)
+	}
+	if day, _ := restarted.usage.globalSnapshot(); day.Requests != 1 {
+		t.Errorf("global usage after restart = %+v", day)
+	}
+}
+
+func TestSessionSnapshotRoundTrip(t *testing.T) {
+	app, _ := newTestApp(t, Config{})
+	session := app.sessions.get(42)
+	session.mu.Lock()
+	session.appendTurn(genai.NewContentFromText("first question", genai.RoleUser), genai.NewContentFromText("first answer", genai.RoleModel), "gemini-2.5-pro")
+	session.saveCheckpoint("start")
+	session.switchThread("side")
+	session.appendTurn(genai.NewContentFromText("side question", genai.RoleUser), genai.NewContentFromText("side answer", genai.RoleModel), "gemini-2.5-pro")
+	session.verbosity = verbosityShort
+	raw, err := json.Marshal(session.snapshot())
+	session.mu.Unlock()
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	var snap sessionSnapshot
+	if err := json.Unmarshal(raw, &snap); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	restored := &sessionState{}
+	restored.restoreSnapshot(snap)
+	if restored.thread != "side" || len(restored.history) != 2 || restored.verbosity != verbosityShort {
+		t.Errorf("restored thread=%q history=%d verbosity=%q", restored.thread, len(restored.history), restored.verbosity)
+	}
+	main := restored.threads[defaultThread]
+	if main == nil || len(main.history) != 2 || len(main.checkpoints) != 1 || main.checkpoints[0].name != "start" {
+		t.Errorf("restored main thread = %+v", main)
+	}
+}
+
+func TestRedisSharesPrivacyMode(t *testing.T) {
+	server := miniredis.RunT(t)
+	cfg := Config{RedisURL: "redis://" + server.Addr()}
+	first, _ := newTestApp(t, cfg)
+	second, _ := newTestApp(t, cfg)
+
+	if err := first.privacy.set(42, true); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if !second.privacy.enabled(42) {
+		t.Error("the other instance does not see privacy mode")
+	}
+	if err := second.privacy.migrate(42, -10042); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if first.privacy.enabled(42) || !first.privacy.enabled(-10042) {
+		t.Error("the migration did not reach the first instance")
+	}
+	if err := first.privacy.set(-10042, false); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if second.privacy.enabled(-10042) || server.Exists("eteon:privacy") {
+		t.Errorf("privacy mode still on, keys %v", server.Keys())
+	}
+
+	// An unreachable Redis keeps the last known state rather than leaking
+	// a private chat.
+	first.privacy.set(7, true)
+	server.Close()
+	if !first.privacy.enabled(7) {
+		t.Error("privacy mode lost while Redis is down")
+	}
+}
+
+func TestRedisSharesReplyMode(t *testing.T) {
+	server := miniredis.RunT(t)
+	cfg := Config{RedisURL: "redis://" + server.Addr()}
+	first, _ := newTestApp(t, cfg)
+	second, _ := newTestApp(t, cfg)
+	group := &tele.Chat{ID: -100, Type: tele.ChatGroup}
+
+	off := false
+	if err := first.replyTo.set(-100, &off); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if second.replyTo.enabled(group) {
+		t.Error("the other instance does not see the choice")
+	}
+	if err := second.replyTo.migrate(-100, -10100); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if first.replyTo.enabled(&tele.Chat{ID: -10100, Type: tele.ChatSuperGroup}) || !first.replyTo.enabled(group) {
+		t.Error("the migration did not reach the first instance")
+	}
+	if err := first.replyTo.set(-10100, nil); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if server.Exists("eteon:replyto") {
+		t.Errorf("the choice survived /replyto auto: %v", server.Keys())
+	}
+}
@@ -0,0 +1,70 @@
+package app
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	regenerateUnique    = "regenerate"
+	compareReplyUnique  = "compare_reply"
+	regenerateTempBoost = 0.3
+	defaultTemperature  = 1.0
+	maxRegenerateTemp   = 2.0
+)
+
+// applyRegenerate raises the temperature and draws a fresh seed, so another
+// take on the same turn reads differently and is not answered from the
+// generation cache.
+func applyRegenerate(cfg *genai.GenerateContentConfig) {
+	temperature := float32(defaultTemperature)
+	if cfg.Temperature != nil {
+		temperature = *cfg.Temperature
+	}
+	temperature = min(temperature+regenerateTempBoost, maxRegenerateTemp)
+	seed := rand.Int32()
+	cfg.Temperature = &temperature
+	cfg.Seed = &seed
+}
+
+// handleRegenerate re-runs the latest turn and edits its reply in place. The
+// replaced text travels on to the new reply's artifacts for comparison.
+func (a *App) handleRegenerate(c tele.Context) error {
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	art, ok := a.artifacts.get(c.Callback().Data)
+	session := a.sessionOf(c.Message())
+	session.mu.Lock()
+	turn := session.lastTurn
+	session.mu.Unlock()
+	if !ok || turn.prompt == nil || c.Callback().Message == nil || c.Callback().Message.ID != turn.replyID {
+		return c.Respond(&tele.CallbackResponse{Text: tr(lang, "regenerate_stale")})
+	}
+	if err := c.Respond(); err != nil {
+		slog.Warn("callback acknowledge failed", "err", err)
+	}
+
+	opts := turn.opts
+	opts.edited, opts.regenerate = true, true
+	opts.previous = append(append([]string{}, art.Previous...), art.Reply)
+	return a.enqueueTurn(turn.prompt, opts)
+}
+
+// handleCompareReply shows how a regenerated reply differs from the one it replaced.
+func (a *App) handleCompareReply(c tele.Context) error {
+	if err := c.Respond(); err != nil {
+		slog.Warn("callback acknowledge failed", "err", err)
+	}
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	body := tr(lang, "compare_none")
+	if art, ok := a.artifacts.get(c.Callback().Data); ok && len(art.Previous) > 0 {
+		if diff := summarizeReplyDiff(lang, art.Previous[len(art.Previous)-1], art.Reply); strings.TrimSpace(diff) != "" {
+			body = tr(lang, "compare_header", diff)
+		}
+	}
+	_, err := a.sendWithFallback(c.Chat(), body, &tele.SendOptions{ThreadID: messageTopic(c.Message()), DisableWebPagePreview: true})
+	return err
+}
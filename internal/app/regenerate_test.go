@@ -0,0 +1,85 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestRegenerateEditsTheLatestReply(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.reply = "Lisbon is sunny."
+	if err := app.processMessage(context.Background(), testMessage(42, "Weather in Lisbon?"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	session := app.sessions.get(42)
+	replyID := session.lastTurn.replyID
+	var id string
+	for key := range app.artifacts.items {
+		id = key
+	}
+
+	press := func(messageID int) {
+		t.Helper()
+		c := app.bot.NewContext(tele.Update{Callback: &tele.Callback{
+			ID:      "callback",
+			Data:    id,
+			Sender:  &tele.User{ID: 42},
+			Message: &tele.Message{ID: messageID, Chat: &tele.Chat{ID: 42, Type: tele.ChatPrivate}},
+		}})
+		if err := app.handleRegenerate(c); err != nil {
+			t.Fatalf("handleRegenerate: %v", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := app.queue.drain(ctx); err != nil {
+			t.Fatalf("drain: %v", err)
+		}
+		app.queue.resume()
+	}
+
+	press(replyID + 100)
+	if edits := apis.callsTo(telegramHost, "editMessageText"); len(edits) != 0 {
+		t.Fatal("regenerated a reply that is not the latest")
+	}
+
+	apis.reply = "Lisbon is cloudy."
+	press(replyID)
+	edits := apis.callsTo(telegramHost, "editMessageText")
+	if len(edits) != 1 {
+		t.Fatalf("edited %d messages, want the reply edited in place", len(edits))
+	}
+	if text := string(edits[0].body); !strings.Contains(text, "Compared with the previous answer: 1 sentences changed, 0 added, 0 removed") {
+		t.Errorf("regenerated reply %s misses the change summary", text)
+	}
+	gens := apis.callsTo(geminiHost, ":generateContent")
+	var body struct {
+		GenerationConfig struct {
+			Temperature float32 `json:"temperature"`
+			Seed        *int32  `json:"seed"`
+		} `json:"generationConfig"`
+	}
+	if err := json.Unmarshal(gens[len(gens)-1].body, &body); err != nil {
+		t.Fatalf("decode request: %v", err)
+	}
+	if body.GenerationConfig.Temperature <= defaultTemperature || body.GenerationConfig.Seed == nil {
+		t.Errorf("regenerated with temperature %v and seed %v, want a raised temperature and a seed", body.GenerationConfig.Temperature, body.GenerationConfig.Seed)
+	}
+
+	var latest *responseArtifacts
+	for _, art := range app.artifacts.items {
+		if len(art.Previous) > 0 {
+			latest = art
+		}
+	}
+	if latest == nil || latest.Previous[0] != "Lisbon is sunny." || latest.Reply != "Lisbon is cloudy." {
+		t.Fatalf("regenerated artifacts = %+v, want the prior text kept", latest)
+	}
+	if len(session.history) != 2 {
+		t.Errorf("history has %d entries, want the regenerated turn only", len(session.history))
+	}
+}
@@ -0,0 +1,455 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	maxRemindersPerUser = 50
+	maxReminderHorizon  = 366 * 24 * time.Hour
+	// reminderAskPrefix makes a reminder run its text as a prompt when it fires.
+	reminderAskPrefix = "ask:"
+	// defaultReminderHour is the time of day of reminders given only a date.
+	defaultReminderHour = 9
+)
+
+// schedulerInterval is how often the scheduler looks for due reminders.
+var schedulerInterval = 30 * time.Second
+
+var (
+	reminderDurationPart = regexp.MustCompile(`^(\d+)(w|d|h|m|min|mins|s)`)
+	reminderClock        = regexp.MustCompile(`^([01]?\d|2[0-3])[:.]([0-5]\d)$`)
+)
+
+// reminder is a message the bot sends to a chat at Due. With Ask set, Text
+// is answered as a prompt at that time instead of being repeated.
+type reminder struct {
+	ID       int64     `json:"id"`
+	ChatID   int64     `json:"chat_id"`
+	ChatType string    `json:"chat_type"`
+	ThreadID int       `json:"thread_id,omitempty"`
+	UserID   int64     `json:"user_id"`
+	Due      time.Time `json:"due"`
+	Text     string    `json:"text"`
+	Ask      bool      `json:"ask,omitempty"`
+	// Repeat, when set, makes the reminder a recurring digest that is moved to
+	// its next occurrence instead of being removed when it fires.
+	Repeat *reminderRepeat `json:"repeat,omitempty"`
+}
+
+// reminderRepeat is the schedule of a recurring reminder: a time of day in the
+// chat's time zone on the given weekdays, or every day when Days is empty.
+type reminderRepeat struct {
+	Days   []time.Weekday `json:"days,omitempty"`
+	Hour   int            `json:"hour"`
+	Minute int            `json:"minute"`
+}
+
+// next returns the first occurrence of the schedule after now.
+func (r *reminderRepeat) next(now time.Time, loc *time.Location) time.Time {
+	now = now.In(loc)
+	for i := 0; i <= 7; i++ {
+		day := now.AddDate(0, 0, i)
+		at := time.Date(day.Year(), day.Month(), day.Day(), r.Hour, r.Minute, 0, 0, loc)
+		if at.After(now) && (len(r.Days) == 0 || slices.Contains(r.Days, at.Weekday())) {
+			return at
+		}
+	}
+	return now.AddDate(0, 0, 7)
+}
+
+// reminderStore keeps pending reminders and the time zone of each chat across
+// restarts in a JSON file. Every change rewrites the file atomically.
+type reminderStore struct {
+	mu   sync.Mutex
+	path string
+	data reminderData
+}
+
+type reminderData struct {
+	NextID    int64            `json:"next_id"`
+	Reminders []reminder       `json:"reminders"`
+	Zones     map[int64]string `json:"zones,omitempty"`
+}
+
+func openReminderStore(path string) (*reminderStore, error) {
+	s := &reminderStore{path: path, data: reminderData{Zones: make(map[int64]string)}}
+	if err := readJSONFile(path, &s.data); err != nil {
+		return nil, err
+	}
+	if s.data.Zones == nil {
+		s.data.Zones = make(map[int64]string)
+	}
+	return s, nil
+}
+
+// save writes the store; the caller holds s.mu.
+func (s *reminderStore) save() error {
+	return writeJSONFile(s.path, s.data)
+}
+
+// zone returns the time zone of a chat, UTC unless set with /remind tz.
+func (s *reminderStore) zone(chatID int64) *time.Location {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.zoneLocked(chatID)
+}
+
+func (s *reminderStore) zoneLocked(chatID int64) *time.Location {
+	name := s.data.Zones[chatID]
+	if loc, err := time.LoadLocation(name); err == nil && name != "" {
+		return loc
+	}
+	return time.UTC
+}
+
+func (s *reminderStore) setZone(chatID int64, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if name == "UTC" {
+		delete(s.data.Zones, chatID)
+	} else {
+		s.data.Zones[chatID] = name
+	}
+	return s.save()
+}
+
+var errTooManyReminders = errors.New("too many reminders")
+
+// add stores r and returns it with its ID.
+func (s *reminderStore) add(r reminder) (reminder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending, digests := 0, 0
+	for _, other := range s.data.Reminders {
+		if other.UserID != r.UserID {
+			continue
+		}
+		if other.Repeat != nil {
+			digests++
+		} else {
+			pending++
+		}
+	}
+	if r.Repeat == nil && pending >= maxRemindersPerUser || r.Repeat != nil && digests >= maxDigestsPerUser {
+		return r, errTooManyReminders
+	}
+	s.data.NextID++
+	r.ID = s.data.NextID
+	s.data.Reminders = append(s.data.Reminders, r)
+	return r, s.save()
+}
+
+// pending returns the one-off reminders, or the digests when recurring is set,
+// that userID set in chatID, soonest first.
+func (s *reminderStore) pending(chatID, userID int64, recurring bool) []reminder {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var list []reminder
+	for _, r := range s.data.Reminders {
+		if r.ChatID == chatID && r.UserID == userID && (r.Repeat != nil) == recurring {
+			list = append(list, r)
+		}
+	}
+	slices.SortFunc(list, func(a, b reminder) int { return a.Due.Compare(b.Due) })
+	return list
+}
+
+// cancel removes reminder id when userID set it in chatID.
+func (s *reminderStore) cancel(chatID, userID, id int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	i := slices.IndexFunc(s.data.Reminders, func(r reminder) bool {
+		return r.ID == id && r.ChatID == chatID && r.UserID == userID
+	})
+	if i < 0 {
+		return false, nil
+	}
+	s.data.Reminders = slices.Delete(s.data.Reminders, i, i+1)
+	return true, s.save()
+}
+
+// migrate moves the reminders and the time zone of chat from to chat to.
+func (s *reminderStore) migrate(from, to int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, r := range s.data.Reminders {
+		if r.ChatID == from {
+			s.data.Reminders[i].ChatID = to
+			s.data.Reminders[i].ChatType = string(tele.ChatSuperGroup)
+		}
+	}
+	moveKey(s.data.Zones, from, to)
+	return s.save()
+}
+
+// purgeChat removes the reminders and the time zone of chatID.
+func (s *reminderStore) purgeChat(chatID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Reminders = slices.DeleteFunc(s.data.Reminders, func(r reminder) bool { return r.ChatID == chatID })
+	delete(s.data.Zones, chatID)
+	return s.save()
+}
+
+// takeDue returns the reminders due at now. One-off reminders are removed and
+// recurring ones move to their next occurrence.
+func (s *reminderStore) takeDue(now time.Time) ([]reminder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var due, kept []reminder
+	for _, r := range s.data.Reminders {
+		if r.Due.After(now) {
+			kept = append(kept, r)
+			continue
+		}
+		due = append(due, r)
+		if r.Repeat != nil {
+			r.Due = r.Repeat.next(now, s.zoneLocked(r.ChatID))
+			kept = append(kept, r)
+		}
+	}
+	if len(due) == 0 {
+		return nil, nil
+	}
+	s.data.Reminders = kept
+	return due, s.save()
+}
+
+// parseReminderTime reads when a reminder is due from the start of payload
+// and returns the rest as its text. It understands "in 1h30m", "at 18:00",
+// "tomorrow [9:00]" and "2025-06-01 [9:00]", in loc.
+func parseReminderTime(payload string, now time.Time, loc *time.Location) (time.Time, string, error) {
+	fields := strings.Fields(payload)
+	if len(fields) < 2 {
+		return time.Time{}, "", errors.New("missing time or text")
+	}
+	now = now.In(loc)
+	rest := func(n int) string { return strings.Join(fields[n:], " ") }
+	clock := func(i int) (hour, minute int, ok bool) {
+		if i >= len(fields) {
+			return 0, 0, false
+		}
+		m := reminderClock.FindStringSubmatch(fields[i])
+		if m == nil {
+			return 0, 0, false
+		}
+		hour, _ = strconv.Atoi(m[1])
+		minute, _ = strconv.Atoi(m[2])
+		return hour, minute, true
+	}
+
+	switch first := strings.ToLower(fields[0]); {
+	case first == "in":
+		d, err := parseReminderDuration(fields[1])
+		if err != nil {
+			return time.Time{}, "", err
+		}
+		return now.Add(d), rest(2), nil
+	case first == "at":
+		hour, minute, ok := clock(1)
+		if !ok {
+			return time.Time{}, "", errors.New("bad clock time")
+		}
+		due := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, loc)
+		if !due.After(now) {
+			due = due.AddDate(0, 0, 1)
+		}
+		return due, rest(2), nil
+	case first == "tomorrow":
+		day := now.AddDate(0, 0, 1)
+		hour, minute, ok := clock(1)
+		n := 2
+		if !ok {
+			hour, minute, n = defaultReminderHour, 0, 1
+		}
+		return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, loc), rest(n), nil
+	default:
+		day, err := time.ParseInLocation(time.DateOnly, fields[0], loc)
+		if err != nil {
+			return time.Time{}, "", errors.New("unknown time")
+		}
+		hour, minute, ok := clock(1)
+		n := 2
+		if !ok {
+			hour, minute, n = defaultReminderHour, 0, 1
+		}
+		return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, loc), rest(n), nil
+	}
+}
+
+// parseReminderDuration reads durations such as 90m, 2h30m, 3d or 1w.
+func parseReminderDuration(s string) (time.Duration, error) {
+	s = strings.ToLower(s)
+	var total time.Duration
+	for s != "" {
+		m := reminderDurationPart.FindStringSubmatch(s)
+		if m == nil {
+			return 0, fmt.Errorf("bad duration %q", s)
+		}
+		n, _ := strconv.Atoi(m[1])
+		unit := map[string]time.Duration{
+			"w": 7 * 24 * time.Hour, "d": 24 * time.Hour, "h": time.Hour,
+			"m": time.Minute, "min": time.Minute, "mins": time.Minute, "s": time.Second,
+		}[m[2]]
+		total += time.Duration(n) * unit
+		s = s[len(m[0]):]
+	}
+	if total <= 0 {
+		return 0, errors.New("empty duration")
+	}
+	return total, nil
+}
+
+// handleRemind sets, lists and cancels reminders:
+//
+//	/remind <when> <text>        reminds the chat of text
+//	/remind <when> ask: <prompt> answers prompt at that time
+//	/remind                      lists the sender's reminders in this chat
+//	/remind cancel <id>          cancels one
+//	/remind tz <Area/City>       sets the time zone of the chat
+func (a *App) handleRemind(c tele.Context) error {
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	thread := messageTopic(c.Message())
+	reply := func(body string) error {
+		_, err := a.sendWithFallback(c.Chat(), body, &tele.SendOptions{ThreadID: thread, DisableWebPagePreview: true})
+		return err
+	}
+	userID := userIDOf(c.Sender())
+	chatID := c.Chat().ID
+	loc := a.reminders.zone(chatID)
+	payload := strings.TrimSpace(c.Message().Payload)
+	command, arg, _ := strings.Cut(payload, " ")
+	arg = strings.TrimSpace(arg)
+
+	switch strings.ToLower(command) {
+	case "":
+		list := a.reminders.pending(chatID, userID, false)
+		if len(list) == 0 {
+			return reply(tr(lang, "remind_none"))
+		}
+		lines := make([]string, 0, len(list))
+		for _, r := range list {
+			text := r.Text
+			if r.Ask {
+				text = reminderAskPrefix + " " + text
+			}
+			lines = append(lines, fmt.Sprintf("#%d · %s · %s", r.ID, r.Due.In(loc).Format("2006-01-02 15:04 MST"), text))
+		}
+		return reply(tr(lang, "remind_list", strings.Join(lines, "\n")))
+	case "cancel":
+		id, err := strconv.ParseInt(strings.TrimPrefix(arg, "#"), 10, 64)
+		if err != nil {
+			return reply(tr(lang, "remind_usage"))
+		}
+		ok, err := a.reminders.cancel(chatID, userID, id)
+		if err != nil {
+			a.chatLog(chatID).Warn("save reminders failed", "chat_id", chatID, "err", err)
+		}
+		if !ok {
+			return reply(tr(lang, "remind_unknown", id))
+		}
+		return reply(tr(lang, "remind_cancelled", id))
+	case "tz":
+		zone, err := time.LoadLocation(arg)
+		if err != nil || arg == "" || strings.EqualFold(arg, "local") {
+			return reply(tr(lang, "remind_bad_tz"))
+		}
+		if err := a.reminders.setZone(chatID, zone.String()); err != nil {
+			a.chatLog(chatID).Warn("save reminders failed", "chat_id", chatID, "err", err)
+		}
+		return reply(tr(lang, "remind_tz", zone.String()))
+	}
+
+	now := time.Now()
+	due, text, err := parseReminderTime(payload, now, loc)
+	if err != nil || strings.TrimSpace(text) == "" {
+		return reply(tr(lang, "remind_usage"))
+	}
+	if !due.After(now) || due.Sub(now) > maxReminderHorizon {
+		return reply(tr(lang, "remind_range"))
+	}
+	r := reminder{ChatID: chatID, ChatType: string(c.Chat().Type), ThreadID: thread, UserID: userID, Due: due, Text: text}
+	if rest, ok := strings.CutPrefix(text, reminderAskPrefix); ok {
+		r.Ask, r.Text = true, strings.TrimSpace(rest)
+		if r.Text == "" {
+			return reply(tr(lang, "remind_usage"))
+		}
+	}
+	r, err = a.reminders.add(r)
+	switch {
+	case errors.Is(err, errTooManyReminders):
+		return reply(tr(lang, "remind_too_many", maxRemindersPerUser))
+	case err != nil:
+		a.chatLog(chatID).Warn("save reminders failed", "chat_id", chatID, "err", err)
+	}
+	return reply(tr(lang, "remind_set", r.ID, due.In(loc).Format("2006-01-02 15:04 MST")))
+}
+
+// runScheduler fires due reminders, purges the chats inactive for longer
+// than the grace period and deletes the due messages of ephemeral chats until
+// ctx ends.
+func (a *App) runScheduler(ctx context.Context) {
+	ticker := time.NewTicker(schedulerInterval)
+	defer ticker.Stop()
+	for {
+		a.fireDueReminders(time.Now())
+		a.purgeInactiveChats(time.Now())
+		a.deleteDueMessages(time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// fireDueReminders sends the reminders due at now, or queues their prompts.
+func (a *App) fireDueReminders(now time.Time) {
+	due, err := a.reminders.takeDue(now)
+	if err != nil {
+		slog.Warn("save reminders failed", "err", err)
+	}
+	for _, r := range due {
+		if a.operator.isInactive(r.ChatID) {
+			continue
+		}
+		chat := &tele.Chat{ID: r.ChatID, Type: tele.ChatType(r.ChatType)}
+		user := &tele.User{ID: r.UserID}
+		lang := a.chatLanguage(chat, user)
+		if !r.Ask {
+			body := tr(lang, "remind_fire", r.Text)
+			if _, err := a.sendWithFallback(chat, body, &tele.SendOptions{ThreadID: r.ThreadID, DisableWebPagePreview: true}); err != nil {
+				a.chatLog(r.ChatID).Warn("send reminder failed", "chat_id", r.ChatID, "reminder_id", r.ID, "err", err)
+			}
+			continue
+		}
+		msg := &tele.Message{
+			Chat:         chat,
+			Sender:       user,
+			Text:         r.Text,
+			ThreadID:     r.ThreadID,
+			TopicMessage: r.ThreadID != 0,
+			Unixtime:     now.Unix(),
+		}
+		opts := turnOptions{}
+		if r.Repeat != nil {
+			opts = turnOptions{instruction: digestInstruction, tools: []*genai.Tool{{GoogleSearch: &genai.GoogleSearch{}}}}
+		}
+		if err := a.enqueueTurn(msg, opts); err != nil {
+			a.chatLog(r.ChatID).Warn("queue reminder prompt failed", "chat_id", r.ChatID, "reminder_id", r.ID, "err", err)
+		}
+	}
+}
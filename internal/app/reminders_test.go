@@ -0,0 +1,110 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestParseReminderTime(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no time zone data")
+	}
+	now := time.Date(2025, 6, 1, 20, 30, 0, 0, berlin)
+	tests := []struct {
+		payload string
+		due     string
+		text    string
+	}{
+		{"in 2h30m call mom", "2025-06-01 23:00", "call mom"},
+		{"in 3d water plants", "2025-06-04 20:30", "water plants"},
+		{"at 21:15 stretch", "2025-06-01 21:15", "stretch"},
+		{"at 8:00 standup", "2025-06-02 08:00", "standup"},
+		{"tomorrow pay rent", "2025-06-02 09:00", "pay rent"},
+		{"tomorrow 7.45 gym", "2025-06-02 07:45", "gym"},
+		{"2025-07-01 17:00 ask: summarize the news", "2025-07-01 17:00", "ask: summarize the news"},
+	}
+	for _, tt := range tests {
+		due, text, err := parseReminderTime(tt.payload, now, berlin)
+		if err != nil {
+			t.Errorf("%q: %v", tt.payload, err)
+			continue
+		}
+		if got := due.In(berlin).Format("2006-01-02 15:04"); got != tt.due || text != tt.text {
+			t.Errorf("%q = %s %q, want %s %q", tt.payload, got, text, tt.due, tt.text)
+		}
+	}
+	for _, bad := range []string{"in soon call", "at 25:00 x", "whenever x", "in 2h"} {
+		if _, text, err := parseReminderTime(bad, now, berlin); err == nil && text != "" {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+}
+
+func TestRemindersFire(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.reply = "Markets were calm today."
+	remind := func(payload string) string {
+		t.Helper()
+		msg := testMessage(42, "/remind "+payload)
+		msg.Payload = payload
+		if err := app.handleRemind(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("handleRemind(%q): %v", payload, err)
+		}
+		texts := apis.sentTexts()
+		return texts[len(texts)-1]
+	}
+
+	if got := remind("in 1h stand up and stretch"); !strings.Contains(got, "set for") {
+		t.Fatalf("set answered %q", got)
+	}
+	remind("in 2h ask: what happened on the markets today?")
+	remind("in 3h drink water")
+	if got := remind("in 2y too late"); !strings.Contains(got, "Usage") {
+		t.Errorf("unknown unit answered %q", got)
+	}
+	if got := remind("in 400d too late"); !strings.Contains(got, "at most a year") {
+		t.Errorf("far reminder answered %q", got)
+	}
+	if got := remind("cancel 3"); !strings.Contains(got, "3 cancelled") {
+		t.Errorf("cancel answered %q", got)
+	}
+	if got := remind(""); !strings.Contains(got, "stand up and stretch") || !strings.Contains(got, "ask: what happened") || strings.Contains(got, "drink water") {
+		t.Errorf("list answered %q", got)
+	}
+
+	reopened, err := openReminderStore(filepath.Join(app.dataDir, "reminders.json"))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if n := len(reopened.pending(42, 42, false)); n != 2 {
+		t.Errorf("persisted %d reminders, want 2", n)
+	}
+
+	app.fireDueReminders(time.Now().Add(90 * time.Minute))
+	if texts := apis.sentTexts(); !strings.Contains(texts[len(texts)-1], "stand up and stretch") {
+		t.Errorf("fired %q, want the first reminder", texts[len(texts)-1])
+	}
+	if calls := apis.callsTo(geminiHost, ":generateContent"); len(calls) != 0 {
+		t.Fatalf("plain reminder reached Gemini")
+	}
+
+	app.fireDueReminders(time.Now().Add(150 * time.Minute))
+	app.queue.drain(context.Background())
+	app.queue.resume()
+	calls := apis.callsTo(geminiHost, ":generateContent")
+	if len(calls) == 0 || !strings.Contains(string(calls[len(calls)-1].body), "what happened on the markets") {
+		t.Fatal("ask reminder was not answered as a prompt")
+	}
+	if texts := apis.sentTexts(); !strings.Contains(texts[len(texts)-1], "Markets were calm") {
+		t.Errorf("ask reminder answered %q", texts[len(texts)-1])
+	}
+	if n := len(app.reminders.pending(42, 42, false)); n != 0 {
+		t.Errorf("%d reminders left after firing", n)
+	}
+}
@@ -0,0 +1,161 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	// maxReplayTurns limits how many of the latest user turns /replay re-runs;
+	// earlier history is passed along unchanged as context.
+	maxReplayTurns = 10
+	replayTimeout  = 5 * time.Minute
+)
+
+// replayTurn pairs a replayed prompt with the stored answer and the new one.
+type replayTurn struct {
+	Prompt   string
+	Original string
+	Replay   string
+}
+
+// replayableModels lists the models /replay accepts, in a stable order.
+func replayableModels() []string {
+	models := make([]string, 0, len(modelPricing))
+	for model := range modelPricing {
+		models = append(models, model)
+	}
+	slices.Sort(models)
+	return models
+}
+
+func (a *App) handleReplay(c tele.Context) error {
+	model := strings.ToLower(strings.TrimSpace(c.Message().Payload))
+	if _, ok := modelPricing[model]; !ok {
+		body := "Usage: /replay <model>\nAvailable models: " + strings.Join(replayableModels(), ", ")
+		_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), body, &tele.SendOptions{DisableWebPagePreview: true})
+		return err
+	}
+	chat, session := c.Chat(), a.sessionOf(c.Message())
+	return a.enqueueJob(c.Message(), func(ctx context.Context) error {
+		return a.runReplay(ctx, chat, session, model)
+	})
+}
+
+// runReplay re-runs the latest user turns of the conversation against model, feeding the
+// model its own earlier replies, and sends both sides as an HTML document.
+func (a *App) runReplay(ctx context.Context, chat *tele.Chat, session *sessionState, model string) error {
+	session.mu.Lock()
+	history := slices.Clone(session.history)
+	summary := session.summary
+	prefs := session.prefs()
+	session.mu.Unlock()
+
+	start := len(history)
+	for users := 0; start > 0 && users < maxReplayTurns; {
+		start--
+		if history[start].Role == genai.RoleUser {
+			users++
+		}
+	}
+	to := chatTopic(chat, threadFromContext(ctx))
+	lang := a.chatLanguage(chat, nil)
+	if start == len(history) {
+		_, err := a.sendWithFallback(to, tr(lang, "replay_empty"), &tele.SendOptions{DisableWebPagePreview: true})
+		return err
+	}
+
+	var convo []*genai.Content
+	if summary != "" {
+		convo = append(convo, genai.NewContentFromText(summaryPrefix+summary, genai.RoleUser))
+	}
+	convo = append(convo, history[:start]...)
+
+	// Function tools need the bridge in generateWithFunctions; the replay keeps
+	// to the built-in tools so both models see the same capabilities.
+	cfg := configForModel(model, a.buildGenerateConfig(chat.ID, prefs))
+	cfg.Tools = a.tools
+
+	ctx = withChatID(ctx, chat.ID)
+	ctx, cancel := context.WithTimeout(ctx, replayTimeout)
+	defer cancel()
+
+	var turns []replayTurn
+	for i := start; i < len(history); i++ {
+		user := history[i]
+		if user.Role != genai.RoleUser {
+			continue
+		}
+		turn := replayTurn{Prompt: contentText(user)}
+		if i+1 < len(history) && history[i+1].Role == genai.RoleModel {
+			turn.Original = contentText(history[i+1])
+		}
+
+		convo = append(convo, user)
+		resp, err := a.generateWithRetry(ctx, model, convo, cfg)
+		if err != nil {
+			logFrom(ctx).Error("replay request failed", "model", model, "err", err)
+			notice := tr(lang, "replay_failed", model, len(turns))
+			if ctx.Err() != nil {
+				notice = tr(lang, "replay_cancelled")
+			}
+			_, sendErr := a.sendWithFallback(to, notice, &tele.SendOptions{DisableWebPagePreview: true})
+			if sendErr != nil {
+				logFrom(ctx).Warn("notify failed", "err", sendErr)
+			}
+			return err
+		}
+		a.recordUsage(ctx, chat.ID, model, resp.UsageMetadata)
+
+		turn.Replay, _ = a.renderResponse(resp)
+		turns = append(turns, turn)
+		if cand := firstCandidate(resp); cand != nil && cand.Content != nil {
+			convo = append(convo, filterModelContent(cand.Content))
+		}
+	}
+
+	caption := tr(lang, "replay_caption", len(turns), model)
+	var prompts strings.Builder
+	for _, turn := range turns {
+		prompts.WriteString(turn.Prompt)
+		prompts.WriteString("\n")
+	}
+	name := a.exportName(ctx, chat.ID, "replay "+model, ".html", "Replay on "+model+" of a conversation with these questions:\n"+prompts.String())
+	return a.sendTextDocument(to, name, "text/html", replayReport(model, turns), caption)
+}
+
+// replayReport renders the original and replayed answers in two columns.
+func replayReport(model string, turns []replayTurn) string {
+	cell := func(text string) string {
+		if strings.TrimSpace(text) == "" {
+			return "<td class=\"empty\">No reply</td>"
+		}
+		return "<td>" + html.EscapeString(text) + "</td>"
+	}
+
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html><head><meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&b, "<title>Eteon replay on %s</title>\n", html.EscapeString(model))
+	b.WriteString("<style>body{font-family:sans-serif;margin:2em}table{border-collapse:collapse;width:100%;table-layout:fixed}" +
+		"th,td{border:1px solid #ccc;padding:.6em;vertical-align:top;white-space:pre-wrap;word-wrap:break-word}" +
+		"th{background:#f3f3f3}td.prompt{background:#fafafa;font-weight:bold}td.empty{color:#888;font-style:italic}</style>\n")
+	b.WriteString("</head><body>\n")
+	fmt.Fprintf(&b, "<h1>Replay on %s</h1>\n", html.EscapeString(model))
+	fmt.Fprintf(&b, "<p>Generated %s. Each prompt was answered by %s with its own earlier replies as context.</p>\n",
+		time.Now().UTC().Format(time.RFC1123), html.EscapeString(model))
+	b.WriteString("<table>\n")
+	fmt.Fprintf(&b, "<tr><th>Original</th><th>%s</th></tr>\n", html.EscapeString(model))
+	for i, turn := range turns {
+		fmt.Fprintf(&b, "<tr><td class=\"prompt\" colspan=\"2\">%d. %s</td></tr>\n", i+1, html.EscapeString(turn.Prompt))
+		b.WriteString("<tr>" + cell(turn.Original) + cell(turn.Replay) + "</tr>\n")
+	}
+	b.WriteString("</table>\n</body></html>\n")
+	return b.String()
+}
@@ -0,0 +1,95 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestReplayComparesTheConversation(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	const replayModel = "gemini-2.5-flash"
+	apis.answer = func(model string, body []byte) []any {
+		switch model {
+		case replayModel:
+			return []any{map[string]any{"text": "Flash says <hi>"}}
+		case namingModel:
+			return []any{map[string]any{"text": "capital-replay"}}
+		}
+		return []any{map[string]any{"text": "Pro says hi"}}
+	}
+	for _, prompt := range []string{"Capital of France?", "And of Spain?"} {
+		if err := app.processMessage(context.Background(), testMessage(42, prompt), turnOptions{}); err != nil {
+			t.Fatalf("processMessage: %v", err)
+		}
+	}
+	msg := testMessage(42, "")
+	if err := app.runReplay(context.Background(), msg.Chat, app.sessionOf(msg), replayModel); err != nil {
+		t.Fatalf("runReplay: %v", err)
+	}
+
+	calls := apis.callsTo(geminiHost, replayModel+":generateContent")
+	if len(calls) != 2 {
+		t.Fatalf("%s got %d requests, want one per turn", replayModel, len(calls))
+	}
+	if second := string(calls[1].body); !strings.Contains(second, "Flash says") || strings.Contains(second, "Pro says") {
+		t.Errorf("the second turn does not see the replayed answer: %.400s", second)
+	}
+	docs := apis.callsTo(telegramHost, "sendDocument")
+	if len(docs) != 1 {
+		t.Fatalf("sent %d documents, want the comparison", len(docs))
+	}
+	body := string(docs[0].body)
+	for _, want := range []string{"capital-replay.html", "Replayed 2 turns on " + replayModel, "2. And of Spain?", "<td>Pro says hi</td><td>Flash says &lt;hi&gt;</td>"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("document lacks %q", want)
+		}
+	}
+	if day, _ := app.usage.chatSnapshot(42); day.Requests < 4 {
+		t.Errorf("recorded %d requests, want the replayed turns counted", day.Requests)
+	}
+}
+
+func TestReplayFailures(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	msg := testMessage(42, "")
+	if err := app.runReplay(context.Background(), msg.Chat, app.sessionOf(msg), "gemini-2.5-flash"); err != nil {
+		t.Fatalf("runReplay: %v", err)
+	}
+	if texts := apis.sentTexts(); !containsText(texts, "nothing to replay yet") {
+		t.Errorf("sent %q, want the empty notice", texts)
+	}
+
+	for _, prompt := range []string{"One?", "Two?"} {
+		if err := app.processMessage(context.Background(), testMessage(42, prompt), turnOptions{}); err != nil {
+			t.Fatalf("processMessage: %v", err)
+		}
+	}
+	replays := 0
+	apis.geminiError = func(path string, body []byte) int {
+		if strings.Contains(path, "gemini-2.5-flash:") {
+			if replays++; replays > 1 {
+				return http.StatusBadRequest
+			}
+		}
+		return 0
+	}
+	if err := app.runReplay(context.Background(), msg.Chat, app.sessionOf(msg), "gemini-2.5-flash"); err == nil {
+		t.Fatal("runReplay succeeded with a failing model")
+	}
+	if texts := apis.sentTexts(); !containsText(texts, "failed after 1 of the turns") {
+		t.Errorf("sent %q, want the failure notice", texts)
+	}
+
+	usage := testMessage(42, "/replay gpt")
+	usage.Payload = "gpt"
+	if err := app.handleReplay(app.bot.NewContext(tele.Update{Message: usage})); err != nil {
+		t.Fatalf("handleReplay: %v", err)
+	}
+	if texts := apis.sentTexts(); !containsText(texts, `gemini\-2\.5\-flash\-lite, gemini\-2\.5\-pro`) {
+		t.Errorf("sent %q, want the models listed", texts)
+	}
+}
@@ -0,0 +1,108 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+// replyPromptLimit caps the prompt quoted for a reply the user answers.
+const replyPromptLimit = 300
+
+// replyContextParts describes the message msg replies to, so requests like
+// "translate this" have the quoted text or media in front of the model. Media
+// from the quoted message is best effort: a failure is logged and skipped.
+func (a *App) replyContextParts(ctx context.Context, msg *tele.Message) []*genai.Part {
+	quoted := ""
+	if msg.Quote != nil {
+		quoted = strings.TrimSpace(msg.Quote.Text)
+	}
+
+	var (
+		author string
+		media  []*genai.Part
+	)
+	switch {
+	case msg.ReplyTo != nil:
+		orig := msg.ReplyTo
+		author = a.describeAuthor(orig)
+		if quoted == "" {
+			quoted = messagePrompt(orig)
+		}
+		parts, err := a.mediaParts(ctx, orig)
+		if err != nil {
+			logFrom(ctx).Warn("replied-to media failed", "err", err)
+		}
+		media = parts
+	case msg.ExternalReply != nil:
+		author = describeOrigin(msg.ExternalReply.Origin)
+	default:
+		return nil
+	}
+	if quoted == "" && len(media) == 0 {
+		return nil
+	}
+
+	header := fmt.Sprintf("[The user is replying to %s.", author)
+	if quoted != "" {
+		header += " Quoted text:]\n" + quoted + "\n[End of quoted text.]"
+	} else {
+		header += " Its attachment follows.]"
+	}
+	return append([]*genai.Part{genai.NewPartFromText(header)}, media...)
+}
+
+func (a *App) describeAuthor(msg *tele.Message) string {
+	if msg.Origin != nil {
+		return describeOrigin(msg.Origin)
+	}
+	if msg.Sender == nil {
+		return "an earlier message"
+	}
+	if a.bot.Me != nil && msg.Sender.ID == a.bot.Me.ID {
+		// Naming the prompt keeps follow-ups on the right turn when several
+		// conversations interleave in a busy chat.
+		if msg.Chat != nil {
+			if art, ok := a.artifacts.forReply(msg.Chat.ID, msg.ID); ok && art.Prompt != "" {
+				return fmt.Sprintf("your earlier reply to %q", truncateText(art.Prompt, replyPromptLimit))
+			}
+		}
+		return "one of your earlier replies"
+	}
+	if name := displayName(msg.Sender); name != "" {
+		return "a message from " + name
+	}
+	return "an earlier message"
+}
+
+func describeOrigin(origin *tele.MessageOrigin) string {
+	if origin == nil {
+		return "a message from another chat"
+	}
+	if name := originName(origin); name != "" {
+		return "a forwarded message from " + name
+	}
+	return "a forwarded message"
+}
+
+// originName names the original author of a forwarded message, if Telegram reveals it.
+func originName(origin *tele.MessageOrigin) string {
+	switch {
+	case origin.Sender != nil:
+		return displayName(origin.Sender)
+	case origin.SenderUsername != "":
+		return origin.SenderUsername
+	case origin.Chat != nil && origin.Chat.Title != "":
+		return "channel " + origin.Chat.Title
+	case origin.SenderChat != nil && origin.SenderChat.Title != "":
+		return origin.SenderChat.Title
+	}
+	return ""
+}
+
+func displayName(user *tele.User) string {
+	return strings.TrimSpace(user.FirstName + " " + user.LastName)
+}
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestReplyContextParts(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.files = map[string]string{"photo1": "\xff\xd8\xff\xe0jpeg"}
+	chat := &tele.Chat{ID: 42, Type: tele.ChatPrivate}
+	id := app.artifacts.put(&responseArtifacts{ChatID: 42, MessageID: 3, Prompt: "Why is the sky blue?", Reply: "Rayleigh scattering."})
+	app.artifacts.setReply(id, 4)
+	channel := &tele.MessageOrigin{Type: "channel", Chat: &tele.Chat{Title: "News"}}
+
+	tests := []struct {
+		name  string
+		msg   *tele.Message
+		want  string
+		parts int
+	}{
+		{"no reply", &tele.Message{Chat: chat}, "", 0},
+		{"a user's message", &tele.Message{Chat: chat, ReplyTo: &tele.Message{ID: 2, Chat: chat, Sender: &tele.User{ID: 9, FirstName: "Ana", LastName: "Lee"}, Text: "Meet at six"}},
+			"[The user is replying to a message from Ana Lee. Quoted text:]\nMeet at six\n[End of quoted text.]", 1},
+		{"a quote of it", &tele.Message{Chat: chat, Quote: &tele.TextQuote{Text: " six "}, ReplyTo: &tele.Message{ID: 2, Chat: chat, Sender: &tele.User{ID: 9, FirstName: "Ana"}, Text: "Meet at six"}},
+			"[The user is replying to a message from Ana. Quoted text:]\nsix\n[End of quoted text.]", 1},
+		{"a reply of the bot", &tele.Message{Chat: chat, ReplyTo: &tele.Message{ID: 4, Chat: chat, Sender: app.bot.Me, Text: "Rayleigh scattering."}},
+			`[The user is replying to your earlier reply to "Why is the sky blue?".`, 1},
+		{"an older reply of the bot", &tele.Message{Chat: chat, ReplyTo: &tele.Message{ID: 8, Chat: chat, Sender: app.bot.Me, Text: "Hello."}},
+			"[The user is replying to one of your earlier replies.", 1},
+		{"a forwarded message", &tele.Message{Chat: chat, ReplyTo: &tele.Message{ID: 2, Chat: chat, Origin: channel, Text: "Breaking"}},
+			"[The user is replying to a forwarded message from channel News.", 1},
+		{"a photo", &tele.Message{Chat: chat, ReplyTo: &tele.Message{ID: 2, Chat: chat, Sender: &tele.User{ID: 9, FirstName: "Ana"}, Photo: &tele.Photo{File: tele.File{FileID: "photo1"}}}},
+			"[The user is replying to a message from Ana. Its attachment follows.]", 2},
+		{"a message of another chat", &tele.Message{Chat: chat, Quote: &tele.TextQuote{Text: "Rates rise"}, ExternalReply: &tele.ExternalReply{Origin: channel}},
+			"[The user is replying to a forwarded message from channel News. Quoted text:]\nRates rise", 1},
+		{"another chat without a quote", &tele.Message{Chat: chat, ExternalReply: &tele.ExternalReply{Origin: channel}}, "", 0},
+	}
+	for _, tt := range tests {
+		parts := app.replyContextParts(context.Background(), tt.msg)
+		if len(parts) != tt.parts {
+			t.Errorf("%s: %d parts, want %d", tt.name, len(parts), tt.parts)
+			continue
+		}
+		if tt.parts > 0 && !strings.HasPrefix(parts[0].Text, tt.want) {
+			t.Errorf("%s: header = %q, want %q", tt.name, parts[0].Text, tt.want)
+		}
+	}
+}
+
+func TestReplyContextReachesTheModel(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	msg := testMessage(42, "translate this to German")
+	msg.ReplyTo = &tele.Message{ID: 2, Chat: msg.Chat, Sender: &tele.User{ID: 9, FirstName: "Ana"}, Text: "Good morning"}
+	if err := app.processMessage(context.Background(), msg, turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	calls := apis.callsTo(geminiHost, ":generateContent")
+	if len(calls) == 0 || !strings.Contains(string(calls[len(calls)-1].body), `Quoted text:]\nGood morning`) {
+		t.Error("the quoted message is missing from the request")
+	}
+}
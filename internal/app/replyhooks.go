@@ -0,0 +1,231 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Names of the built-in reply hooks for Config.ReplyHooks.
+const (
+	hookDisclaimers     = "disclaimers"
+	hookNoSelfReference = "no_self_reference"
+	hookShortenLinks    = "shorten_links"
+)
+
+// ReplyHook rewrites a rendered reply before it is sent. lang is the code of
+// the chat language, such as en, and prompt the message the reply answers. A
+// hook that fails returns reply unchanged.
+type ReplyHook func(ctx context.Context, lang, prompt, reply string) string
+
+// replyHookFactories build the built-in hooks from the configuration.
+var replyHookFactories = map[string]func(cfg Config) ReplyHook{
+	hookDisclaimers:     func(Config) ReplyHook { return appendDisclaimers },
+	hookNoSelfReference: func(Config) ReplyHook { return stripSelfReferences },
+	hookShortenLinks: func(cfg Config) ReplyHook {
+		return newLinkShortener(strings.TrimSpace(cfg.LinkShortenerURL)).shorten
+	},
+}
+
+func validateReplyHooks(names []string, shortenerURL string) error {
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := replyHookFactories[name]; !ok {
+			return fmt.Errorf("unknown reply hook %q, want disclaimers, no_self_reference or shorten_links", name)
+		}
+		if name == hookShortenLinks {
+			if !strings.Contains(shortenerURL, "{url}") {
+				return fmt.Errorf("shorten_links needs LINK_SHORTENER_URL with a {url} placeholder")
+			}
+			if u, err := url.Parse(strings.ReplaceAll(shortenerURL, "{url}", "x")); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+				return fmt.Errorf("LINK_SHORTENER_URL: %q is not an http or https URL", shortenerURL)
+			}
+		}
+	}
+	return nil
+}
+
+// replyHooks runs the hooks on every reply, in the order they were added.
+type replyHooks struct {
+	mu    sync.RWMutex
+	names []string
+	hooks []ReplyHook
+}
+
+func newReplyHooks(cfg Config) *replyHooks {
+	h := &replyHooks{}
+	for _, name := range cfg.ReplyHooks {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if factory, ok := replyHookFactories[name]; ok {
+			h.add(name, factory(cfg))
+		}
+	}
+	return h
+}
+
+func (h *replyHooks) add(name string, hook ReplyHook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.names = append(h.names, name)
+	h.hooks = append(h.hooks, hook)
+}
+
+func (h *replyHooks) apply(ctx context.Context, lang language, prompt, reply string) string {
+	h.mu.RLock()
+	hooks := h.hooks
+	h.mu.RUnlock()
+	for _, hook := range hooks {
+		reply = hook(ctx, string(lang), prompt, reply)
+	}
+	return reply
+}
+
+// AddReplyHook adds a hook that runs on every reply after the configured
+// ones, for code that embeds the bot. name shows up in the startup notes.
+func (a *App) AddReplyHook(name string, hook ReplyHook) {
+	a.replyHooks.add(name, hook)
+	a.noteStartup("Reply hook: %s", name)
+}
+
+var (
+	medicalTopic   = regexp.MustCompile(`(?i)\b(symptom|diagnos|medication|medicine|dosage|dose|prescri|side effect|antibiotic|ibuprofen|paracetamol|pregnan|blood pressure|vaccin|infection|surgery)`)
+	financialTopic = regexp.MustCompile(`(?i)\b(invest|stocks?\b|shares\b|portfolio|crypto|bitcoin|etfs?\b|dividend|retirement|pension|mortgage|loan|trading|interest rate|tax(es)?\b)`)
+)
+
+// topicThreshold is how many mentions in the prompt and reply together make
+// a topic, so a passing mention gets no disclaimer.
+const topicThreshold = 2
+
+// appendDisclaimers closes replies about health or money with a note that
+// they are no professional advice.
+func appendDisclaimers(_ context.Context, lang, prompt, reply string) string {
+	text := prompt + "\n" + reply
+	if len(medicalTopic.FindAllStringIndex(text, topicThreshold)) >= topicThreshold {
+		reply += "\n\n_" + tr(language(lang), "disclaimer_medical") + "_"
+	}
+	if len(financialTopic.FindAllStringIndex(text, topicThreshold)) >= topicThreshold {
+		reply += "\n\n_" + tr(language(lang), "disclaimer_finance") + "_"
+	}
+	return reply
+}
+
+var (
+	// selfReferenceLead is a clause such as "As an AI language model, "
+	// that opens a sentence.
+	selfReferenceLead = regexp.MustCompile(`(?i)\bas an? (?:ai|artificial intelligence|large language model|language model|ai (?:language )?model|ai assistant)(?: developed by [^,.]+| trained by [^,.]+)?,\s*`)
+	// selfReferenceSentence is a sentence that only talks about being an AI.
+	selfReferenceSentence = regexp.MustCompile(`(?i)(?:^|\s)(?:I(?:'m| am) (?:just |only )?an? (?:ai|language model|large language model|ai (?:language )?model)(?: developed by [^,.]+)?(?:,? (?:so|and) I (?:don't|do not|can't|cannot) have (?:personal )?(?:opinions|feelings|experiences)(?: or (?:opinions|feelings|experiences))?)?\.)`)
+)
+
+// stripSelfReferences removes the phrases in which the model talks about
+// being a model, keeping the rest of the sentence.
+func stripSelfReferences(_ context.Context, _, _, reply string) string {
+	reply = selfReferenceSentence.ReplaceAllString(reply, "")
+	for {
+		loc := selfReferenceLead.FindStringIndex(reply)
+		if loc == nil {
+			break
+		}
+		before, rest := reply[:loc[0]], reply[loc[1]:]
+		if r, size := utf8.DecodeRuneInString(rest); size > 0 && startsSentence(before) {
+			rest = string(unicode.ToUpper(r)) + rest[size:]
+		}
+		reply = before + rest
+	}
+	return strings.TrimSpace(reply)
+}
+
+// startsSentence reports whether text after before begins a sentence.
+func startsSentence(before string) bool {
+	trimmed := strings.TrimRight(before, " ")
+	return trimmed == "" || strings.HasSuffix(before, "\n") || strings.ContainsAny(trimmed[len(trimmed)-1:], ".!?")
+}
+
+const (
+	// shortLinkMinLength is the shortest link worth shortening.
+	shortLinkMinLength = 60
+	// shortLinkMax bounds the links shortened in one reply.
+	shortLinkMax       = 10
+	shortLinkTimeout   = 5 * time.Second
+	shortLinkCacheSize = 1000
+)
+
+var replyLinkPattern = regexp.MustCompile(`https?://[^\s<>()\[\]"']+`)
+
+// linkShortener replaces long links with short ones from a service that
+// answers GET template, with {url} replaced by the escaped link, with the
+// short link as plain text.
+type linkShortener struct {
+	template string
+	mu       sync.Mutex
+	cache    map[string]string
+}
+
+func newLinkShortener(template string) *linkShortener {
+	return &linkShortener{template: template, cache: make(map[string]string)}
+}
+
+func (s *linkShortener) shorten(ctx context.Context, _, _, reply string) string {
+	links := replyLinkPattern.FindAllString(reply, -1)
+	done := 0
+	for _, link := range links {
+		link = strings.TrimRight(link, ".,;:!?")
+		if len(link) < shortLinkMinLength || done == shortLinkMax {
+			continue
+		}
+		short, err := s.lookup(ctx, link)
+		if err != nil {
+			logFrom(ctx).Warn("shorten link failed", "err", err)
+			continue
+		}
+		reply = strings.ReplaceAll(reply, link, short)
+		done++
+	}
+	return reply
+}
+
+func (s *linkShortener) lookup(ctx context.Context, link string) (string, error) {
+	s.mu.Lock()
+	short, ok := s.cache[link]
+	s.mu.Unlock()
+	if ok {
+		return short, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, shortLinkTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.ReplaceAll(s.template, "{url}", url.QueryEscape(link)), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := toolHTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 2048))
+	if err != nil {
+		return "", err
+	}
+	short = strings.TrimSpace(string(body))
+	if u, err := url.Parse(short); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || len(short) >= len(link) {
+		return "", fmt.Errorf("shortener answered %q", short)
+	}
+	s.mu.Lock()
+	if len(s.cache) >= shortLinkCacheSize {
+		clear(s.cache)
+	}
+	s.cache[link] = short
+	s.mu.Unlock()
+	return short, nil
+}
@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestReplyHooksRewriteReplies(t *testing.T) {
+	app, apis := newTestApp(t, Config{ReplyHooks: []string{hookNoSelfReference}})
+	apis.reply = "As an AI language model, I suggest tea."
+	var prompt string
+	app.AddReplyHook("signature", func(_ context.Context, lang, p, reply string) string {
+		prompt = p
+		return reply + " (" + lang + ")"
+	})
+
+	if err := app.processMessage(context.Background(), testMessage(42, "Tea or coffee?"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	texts := apis.sentTexts()
+	if reply := texts[len(texts)-1]; !strings.HasPrefix(reply, "I suggest tea") || !strings.Contains(reply, `\(en\)`) {
+		t.Errorf("reply = %q, want it rewritten by both hooks in order", reply)
+	}
+	if prompt != "Tea or coffee?" {
+		t.Errorf("hook saw prompt %q", prompt)
+	}
+}
+
+func TestAppendDisclaimers(t *testing.T) {
+	ctx := context.Background()
+	reply := appendDisclaimers(ctx, "en", "Which dose of ibuprofen is safe?", "The usual dose for adults is 200 to 400 mg.")
+	if !strings.HasSuffix(reply, "_"+tr("en", "disclaimer_medical")+"_") {
+		t.Errorf("reply = %q, want the medical disclaimer", reply)
+	}
+	if strings.Contains(reply, tr("en", "disclaimer_finance")) {
+		t.Errorf("reply = %q, want no financial disclaimer", reply)
+	}
+	if got := appendDisclaimers(ctx, "en", "What is a good name for a dog?", "Rex. A vaccination is due at eight weeks."); strings.Contains(got, "_") {
+		t.Errorf("passing mention got a disclaimer: %q", got)
+	}
+}
+
+func TestStripSelfReferences(t *testing.T) {
+	cases := map[string]string{
+		"As an AI language model, I think both options work.":                         "I think both options work.",
+		"Both work. As an AI, I would pick the first.":                                "Both work. I would pick the first.",
+		"I'm an AI, so I don't have personal opinions. Most people prefer the first.": "Most people prefer the first.",
+		"Ask an AI, she said.": "Ask an AI, she said.",
+	}
+	for in, want := range cases {
+		if got := stripSelfReferences(context.Background(), "en", "", in); got != want {
+			t.Errorf("stripSelfReferences(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestShortenLinks(t *testing.T) {
+	app, apis := newTestApp(t, Config{ReplyHooks: []string{hookShortenLinks}, LinkShortenerURL: "https://short.test/create?url={url}"})
+	long := "https://example.com/articles/2024/05/a-very-long-article-title-that-goes-on-and-on"
+	requests := 0
+	apis.handle("short.test", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+		if req.URL.Query().Get("url") != long {
+			http.Error(w, "bad url", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte("https://short.test/x1\n"))
+	}))
+
+	reply := "Read " + long + ". Or https://go.dev."
+	for range 2 {
+		got := app.replyHooks.apply(context.Background(), "en", "", reply)
+		if want := "Read https://short.test/x1. Or https://go.dev."; got != want {
+			t.Errorf("apply = %q, want %q", got, want)
+		}
+	}
+	if requests != 1 {
+		t.Errorf("shortener asked %d times, want the cached link the second time", requests)
+	}
+}
+
+func TestValidateReplyHooks(t *testing.T) {
+	cases := []struct {
+		names []string
+		url   string
+		want  string
+	}{
+		{[]string{"emoji"}, "", "unknown reply hook"},
+		{[]string{hookShortenLinks}, "", "{url} placeholder"},
+		{[]string{hookShortenLinks}, "short.test/?u={url}", "not an http or https URL"},
+	}
+	for _, tc := range cases {
+		if err := validateReplyHooks(tc.names, tc.url); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("validateReplyHooks(%v, %q) = %v, want %q", tc.names, tc.url, err, tc.want)
+		}
+	}
+	if err := validateReplyHooks([]string{" Disclaimers", hookNoSelfReference}, ""); err != nil {
+		t.Errorf("valid hooks: %v", err)
+	}
+}
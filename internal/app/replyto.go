@@ -0,0 +1,204 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// replyToBackend keeps the reply mode chats chose next to the sessions and
+// the replies they link to.
+type replyToBackend interface {
+	// replyToChoices returns the chats that chose a reply mode.
+	replyToChoices(ctx context.Context) (map[int64]bool, error)
+	// replyToChoice returns the mode chatID chose, and false when it follows
+	// the default.
+	replyToChoice(ctx context.Context, chatID int64) (on, chosen bool, err error)
+	// setReplyTo stores the choice of chatID, or drops it when on is nil.
+	setReplyTo(ctx context.Context, chatID int64, on *bool) error
+	// migrateReplyTo moves the choice of chat from to chat to.
+	migrateReplyTo(ctx context.Context, from, to int64) error
+}
+
+// replyToStore keeps the chats that chose whether answers reply to their
+// prompt, in the shared backend when there is one and in a JSON file
+// otherwise; the others follow the default, replying in groups only.
+type replyToStore struct {
+	mu    sync.Mutex
+	path  string
+	chats map[int64]bool
+	// shared, when set, holds the choices; chats is the copy that answers
+	// while it cannot be reached.
+	shared replyToBackend
+}
+
+// openReplyToStore reads the choices from shared, or from the file at path
+// without a backend. Choices a file from before the backend lists move into
+// it, and the file is removed.
+func openReplyToStore(ctx context.Context, path string, shared replyToBackend) (*replyToStore, error) {
+	s := &replyToStore{path: path, chats: make(map[int64]bool), shared: shared}
+	if err := readJSONFile(path, &s.chats); err != nil {
+		return nil, err
+	}
+	if shared == nil {
+		return s, nil
+	}
+	for chatID, on := range s.chats {
+		if err := shared.setReplyTo(ctx, chatID, &on); err != nil {
+			return nil, fmt.Errorf("move %s: %w", path, err)
+		}
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	chats, err := shared.replyToChoices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.chats = chats
+	return s, nil
+}
+
+// enabled reports whether answers in chat reply to their prompt. With a
+// backend it asks it every time, so a switch on another instance applies to
+// the next answer; when the backend fails, the last known choice answers.
+func (s *replyToStore) enabled(chat *tele.Chat) bool {
+	if s.shared != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+		on, chosen, err := s.shared.replyToChoice(ctx, chat.ID)
+		cancel()
+		if err == nil {
+			s.mu.Lock()
+			if chosen {
+				s.chats[chat.ID] = on
+			} else {
+				delete(s.chats, chat.ID)
+			}
+			s.mu.Unlock()
+		} else {
+			slog.Warn("read reply mode failed", "chat_id", chat.ID, "err", err)
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if on, ok := s.chats[chat.ID]; ok {
+		return on
+	}
+	return isGroupChat(chat)
+}
+
+// set makes answers in chatID reply to their prompt or not, or follow the
+// default again when on is nil, and saves the change.
+func (s *replyToStore) set(chatID int64, on *bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shared != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+		defer cancel()
+		if err := s.shared.setReplyTo(ctx, chatID, on); err != nil {
+			return err
+		}
+	}
+	if on == nil {
+		if _, ok := s.chats[chatID]; !ok {
+			return nil
+		}
+		delete(s.chats, chatID)
+	} else {
+		s.chats[chatID] = *on
+	}
+	return s.saveLocked()
+}
+
+// migrate moves the choice of chat from to chat to.
+func (s *replyToStore) migrate(from, to int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shared != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+		defer cancel()
+		if err := s.shared.migrateReplyTo(ctx, from, to); err != nil {
+			return err
+		}
+	}
+	on, ok := s.chats[from]
+	if !ok {
+		return nil
+	}
+	delete(s.chats, from)
+	s.chats[to] = on
+	return s.saveLocked()
+}
+
+// saveLocked writes the file when there is no backend; the caller holds s.mu.
+func (s *replyToStore) saveLocked() error {
+	if s.shared != nil {
+		return nil
+	}
+	return writeJSONFile(s.path, s.chats)
+}
+
+// replyToPrompt returns the message an answer to msg replies to, or nil when
+// answers in its chat stand on their own. Prompts the bot made up itself
+// have no message to reply to.
+func (a *App) replyToPrompt(msg *tele.Message) *tele.Message {
+	if msg.ID == 0 {
+		return nil
+	}
+	if !a.replyTo.enabled(msg.Chat) {
+		return nil
+	}
+	return msg
+}
+
+// handleReplyTo shows or switches whether answers reply to the message that
+// asked, which keeps questions and answers together in busy chats:
+//
+//	/replyto on     answers reply to their prompt
+//	/replyto off    answers stand on their own
+//	/replyto auto   the default, replying in groups only
+//
+// In groups only admins may switch it.
+func (a *App) handleReplyTo(c tele.Context) error {
+	chat := c.Chat()
+	lang := a.chatLanguage(chat, c.Sender())
+	reply := func(body string) error {
+		_, err := a.sendWithFallback(chat, body, &tele.SendOptions{ThreadID: messageTopic(c.Message()), DisableWebPagePreview: true})
+		return err
+	}
+	var on *bool
+	switch payload := strings.ToLower(strings.TrimSpace(c.Message().Payload)); payload {
+	case "on", "off":
+		on = new(bool)
+		*on = payload == "on"
+	case "auto":
+	default:
+		state := tr(lang, "replyto_state_off")
+		if a.replyTo.enabled(chat) {
+			state = tr(lang, "replyto_state_on")
+		}
+		return reply(state + "\n\n" + tr(lang, "replyto_usage"))
+	}
+	if isGroupChat(chat) && !a.isChatAdmin(c) {
+		return reply(tr(lang, "replyto_admins"))
+	}
+	if err := a.replyTo.set(chat.ID, on); err != nil {
+		a.chatLog(chat.ID).Warn("save reply mode failed", "chat_id", chat.ID, "err", err)
+		return reply(tr(lang, "replyto_failed"))
+	}
+	switch {
+	case on == nil && a.replyTo.enabled(chat):
+		return reply(tr(lang, "replyto_auto_on"))
+	case on == nil:
+		return reply(tr(lang, "replyto_auto_off"))
+	case *on:
+		return reply(tr(lang, "replyto_on"))
+	}
+	return reply(tr(lang, "replyto_off"))
+}
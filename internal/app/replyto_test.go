@@ -0,0 +1,97 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestAnswersReplyToPromptInGroups(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.reply = "Paris."
+	ask := func(msg *tele.Message) string {
+		t.Helper()
+		if err := app.processMessage(context.Background(), msg, turnOptions{}); err != nil {
+			t.Fatalf("processMessage: %v", err)
+		}
+		sent := apis.callsTo(telegramHost, "sendMessage")
+		return string(sent[len(sent)-1].body)
+	}
+	group := func(id int, text string) *tele.Message {
+		msg := testMessage(-100, text)
+		msg.ID = id
+		msg.Chat.Type = tele.ChatGroup
+		return msg
+	}
+
+	if body := ask(testMessage(42, "Capital of France?")); strings.Contains(body, "reply_to_message_id") {
+		t.Errorf("a private answer replies to its prompt: %s", body)
+	}
+	if body := ask(group(7, "Capital of France?")); !strings.Contains(body, `"reply_to_message_id":"7"`) || !strings.Contains(body, "allow_sending_without_reply") {
+		t.Fatalf("a group answer does not reply to its prompt: %s", body)
+	}
+	answer, ok := app.artifacts.get("-100_7")
+	if !ok || answer.ReplyID == 0 {
+		t.Fatalf("the answer's message was not recorded: %+v", answer)
+	}
+
+	// Another conversation interleaves; a follow-up on the first answer
+	// still names the question it answered.
+	ask(group(8, "Capital of Spain?"))
+	followUp := group(9, "And its population?")
+	followUp.ReplyTo = &tele.Message{ID: answer.ReplyID, Chat: followUp.Chat, Sender: app.bot.Me, Text: "Paris."}
+	ask(followUp)
+	calls := apis.callsTo(geminiHost, ":generateContent")
+	if body := string(calls[len(calls)-1].body); !strings.Contains(body, `your earlier reply to \"Capital of France?\"`) {
+		t.Errorf("the follow-up lacks the turn it refers to: %s", body)
+	}
+
+	msg := testMessage(42, "/replyto on")
+	msg.Payload = "on"
+	if err := app.handleReplyTo(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+		t.Fatalf("handleReplyTo: %v", err)
+	}
+	prompt := testMessage(42, "Capital of Italy?")
+	prompt.ID = 3
+	if body := ask(prompt); !strings.Contains(body, `"reply_to_message_id":"3"`) {
+		t.Errorf("/replyto on did not thread a private answer: %s", body)
+	}
+	if reopened, err := openReplyToStore(context.Background(), filepath.Join(app.dataDir, "replyto.json"), app.sqlite); err != nil || !reopened.enabled(prompt.Chat) {
+		t.Errorf("reply mode was not saved: %v", err)
+	}
+}
+
+func TestReplyModeMovesIntoTheDatabase(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "replyto.json")
+	if err := writeJSONFile(path, map[int64]bool{42: true, -100: false}); err != nil {
+		t.Fatal(err)
+	}
+	app, _ := newTestApp(t, Config{DataDir: dir})
+	group := &tele.Chat{ID: -100, Type: tele.ChatGroup}
+	if !app.replyTo.enabled(&tele.Chat{ID: 42, Type: tele.ChatPrivate}) || app.replyTo.enabled(group) {
+		t.Error("the choices from the file were not kept")
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("the file was kept after the move: %v", err)
+	}
+	if err := app.replyTo.migrate(-100, -10100); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	app.sqlite.Close()
+
+	restarted, _ := newTestApp(t, Config{DataDir: dir})
+	if !restarted.replyTo.enabled(group) || restarted.replyTo.enabled(&tele.Chat{ID: -10100, Type: tele.ChatSuperGroup}) {
+		t.Error("the migrated choice was not kept across the restart")
+	}
+	if err := restarted.replyTo.set(42, nil); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if restarted.replyTo.enabled(&tele.Chat{ID: 42, Type: tele.ChatPrivate}) {
+		t.Error("/replyto auto did not return to the default")
+	}
+}
@@ -0,0 +1,477 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	repoEmbeddingModel = "gemini-embedding-001"
+	repoEmbeddingDims  = 768
+	repoEmbedBatch     = 100
+	repoIndexTimeout   = 5 * time.Minute
+	repoCloneTimeout   = 90 * time.Second
+	// repoMaxBytes caps the checked-out size of a repository.
+	repoMaxBytes = 64 << 20
+	// repoCloneMaxBytes caps what a clone may write, the objects under .git
+	// included; the clone is stopped once the directory grows past it.
+	repoCloneMaxBytes = 2 * repoMaxBytes
+	repoMaxFileBytes  = 256 << 10
+	repoMaxChunks     = 2000
+	repoChunkLines    = 60
+	// repoTopChunks is how many excerpts each question is answered from.
+	repoTopChunks = 8
+)
+
+// repoSkipDirs hold generated or vendored code that would crowd out the
+// repository's own sources.
+var repoSkipDirs = map[string]bool{
+	".git": true, "node_modules": true, "vendor": true, "dist": true, "build": true,
+	"target": true, "third_party": true, ".venv": true, "__pycache__": true,
+}
+
+var repoSkipFiles = map[string]bool{
+	"package-lock.json": true, "yarn.lock": true, "pnpm-lock.yaml": true, "go.sum": true,
+	"Cargo.lock": true, "poetry.lock": true, "composer.lock": true,
+}
+
+// repoChunk is a run of lines of one file with its embedding.
+type repoChunk struct {
+	Path   string
+	Start  int
+	End    int
+	Text   string
+	vector []float32
+}
+
+// repoIndex is a repository a chat asks questions about.
+type repoIndex struct {
+	URL     string
+	Files   int
+	Chunks  []repoChunk
+	Partial bool
+	Indexed time.Time
+}
+
+// repoStore holds the repository index of each chat in memory.
+type repoStore struct {
+	mu    sync.Mutex
+	chats map[int64]*repoIndex
+}
+
+func newRepoStore() *repoStore {
+	return &repoStore{chats: make(map[int64]*repoIndex)}
+}
+
+func (s *repoStore) get(chatID int64) *repoIndex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.chats[chatID]
+}
+
+// migrate moves the indexed repository of chat from to chat to.
+func (s *repoStore) migrate(from, to int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	moveKey(s.chats, from, to)
+}
+
+func (s *repoStore) set(chatID int64, idx *repoIndex) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if idx == nil {
+		delete(s.chats, chatID)
+		return
+	}
+	s.chats[chatID] = idx
+}
+
+// validateRepoURL accepts https URLs of hosts on the public internet, so /repo
+// cannot be pointed at the bot's own network. It returns the vetted address
+// the clone must connect to.
+func validateRepoURL(ctx context.Context, raw string) (*url.URL, net.IP, error) {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.Host == "" || u.User != nil {
+		return nil, nil, errors.New("give an https URL without credentials")
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return nil, nil, errors.New("the URL must not have a query or fragment")
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return nil, nil, fmt.Errorf("resolve %s: %w", u.Hostname(), err)
+	}
+	if len(addrs) == 0 {
+		return nil, nil, fmt.Errorf("%s has no address", u.Hostname())
+	}
+	for _, addr := range addrs {
+		ip := addr.IP
+		if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() || ip.IsMulticast() {
+			return nil, nil, fmt.Errorf("%s is not a public host", u.Hostname())
+		}
+	}
+	return u, addrs[0].IP, nil
+}
+
+// pinnedResolve is the curl resolve entry that makes git connect to the
+// address validateRepoURL vetted. Without it git would look the host up
+// again, and a name server answering with a public address first and an
+// internal one second could point the clone at the bot's own network.
+func pinnedResolve(u *url.URL, ip net.IP) string {
+	port := u.Port()
+	if port == "" {
+		port = "443"
+	}
+	addr := ip.String()
+	if ip.To4() == nil {
+		addr = "[" + addr + "]"
+	}
+	return u.Hostname() + ":" + port + ":" + addr
+}
+
+// cloneRepo makes a shallow clone of u, connecting to ip, into a new
+// temporary directory, which the caller removes. The clone is stopped after
+// repoCloneTimeout or once it writes more than repoCloneMaxBytes.
+func cloneRepo(ctx context.Context, u *url.URL, ip net.IP) (string, error) {
+	dir, err := os.MkdirTemp("", "eteon-repo-")
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, repoCloneTimeout)
+	defer cancel()
+
+	// Redirects are refused, since validateRepoURL only vetted the host of
+	// repoURL. On servers with partial clones, blobs over repoMaxFileBytes
+	// stay out of the pack and are fetched only for the checkout, where
+	// watchDirSize bounds them with everything else.
+	cmd := exec.CommandContext(ctx, "git",
+		"-c", "protocol.allow=never", "-c", "protocol.https.allow=always",
+		"-c", "http.followRedirects=false", "-c", "http.curloptResolve="+pinnedResolve(u, ip),
+		"-c", "credential.helper=", "-c", "core.symlinks=false",
+		"clone", "--depth=1", "--single-branch", "--no-tags", "--quiet",
+		fmt.Sprintf("--filter=blob:limit=%d", repoMaxFileBytes),
+		"--", u.String(), dir)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GIT_LFS_SKIP_SMUDGE=1")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stopWatch := watchDirSize(dir, repoCloneMaxBytes, time.Second/4, cancel)
+	err = cmd.Run()
+	if tooLarge := stopWatch(); err != nil || tooLarge {
+		os.RemoveAll(dir)
+		if tooLarge {
+			return "", fmt.Errorf("the repository is larger than %d MB", repoCloneMaxBytes>>20)
+		}
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("clone took longer than %s", repoCloneTimeout)
+		}
+		return "", fmt.Errorf("git clone: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return dir, nil
+}
+
+// watchDirSize measures dir every interval and calls cancel once it holds
+// more than limit bytes. The returned function stops watching and reports
+// whether the limit was passed.
+func watchDirSize(dir string, limit int64, every time.Duration, cancel context.CancelFunc) (stop func() bool) {
+	done := make(chan struct{})
+	result := make(chan bool, 1)
+	go func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				result <- dirSize(dir) > limit
+				return
+			case <-ticker.C:
+				if dirSize(dir) > limit {
+					cancel()
+					<-done
+					result <- true
+					return
+				}
+			}
+		}
+	}()
+	return func() bool {
+		close(done)
+		return <-result
+	}
+}
+
+// dirSize adds up the sizes of the regular files under dir, skipping what it
+// cannot read, since a running clone renames files as it goes.
+func dirSize(dir string) int64 {
+	var total int64
+	filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			total += info.Size()
+		}
+		return nil
+	})
+	return total
+}
+
+// readRepoChunks walks a checkout and splits its text files into chunks of
+// repoChunkLines lines. Binary, oversized, generated and vendored files are
+// skipped; partial reports that the chunk limit cut the walk short.
+func readRepoChunks(dir string) (chunks []repoChunk, files int, partial bool, err error) {
+	var total int64
+	err = filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != dir && (repoSkipDirs[d.Name()] || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// Symlinks could point outside the checkout.
+		if !d.Type().IsRegular() || repoSkipFiles[d.Name()] {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if total += info.Size(); total > repoMaxBytes {
+			return fmt.Errorf("the repository is larger than %d MB", repoMaxBytes>>20)
+		}
+		if info.Size() == 0 || info.Size() > repoMaxFileBytes {
+			return nil
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if bytes.IndexByte(data, 0) >= 0 || !utf8.Valid(data) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files++
+		lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+		for start := 0; start < len(lines); start += repoChunkLines {
+			if len(chunks) == repoMaxChunks {
+				partial = true
+				return filepath.SkipAll
+			}
+			end := min(start+repoChunkLines, len(lines))
+			chunks = append(chunks, repoChunk{
+				Path:  filepath.ToSlash(rel),
+				Start: start + 1,
+				End:   end,
+				Text:  strings.Join(lines[start:end], "\n"),
+			})
+		}
+		return nil
+	})
+	return chunks, files, partial, err
+}
+
+// embedTexts returns one embedding per text, batching the requests, and
+// charges them to chatID. The embeddings API reports no token counts, so the
+// texts are sized with estimateTokens.
+func (a *App) embedTexts(ctx context.Context, chatID int64, texts []string, taskType string) ([][]float32, error) {
+	vectors := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += repoEmbedBatch {
+		batch := texts[start:min(start+repoEmbedBatch, len(texts))]
+		contents := make([]*genai.Content, len(batch))
+		for i, text := range batch {
+			contents[i] = genai.NewContentFromText(text, genai.RoleUser)
+		}
+		resp, err := a.client.Models.EmbedContent(ctx, repoEmbeddingModel, contents, &genai.EmbedContentConfig{
+			TaskType:             taskType,
+			OutputDimensionality: genai.Ptr[int32](repoEmbeddingDims),
+		})
+		if err != nil {
+			return nil, err
+		}
+		a.recordUsage(ctx, chatID, repoEmbeddingModel, &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: int32(estimateTokens(contents...))})
+		if len(resp.Embeddings) != len(batch) {
+			return nil, fmt.Errorf("got %d embeddings for %d texts", len(resp.Embeddings), len(batch))
+		}
+		for _, e := range resp.Embeddings {
+			vectors = append(vectors, e.Values)
+		}
+	}
+	return vectors, nil
+}
+
+// indexRepo embeds the chunks of a checkout for chatID.
+func (a *App) indexRepo(ctx context.Context, chatID int64, repoURL, dir string) (*repoIndex, error) {
+	chunks, files, partial, err := readRepoChunks(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 {
+		return nil, errors.New("the repository has no text files to index")
+	}
+	texts := make([]string, len(chunks))
+	for i, c := range chunks {
+		texts[i] = c.Path + "\n" + c.Text
+	}
+	vectors, err := a.embedTexts(ctx, chatID, texts, "RETRIEVAL_DOCUMENT")
+	if err != nil {
+		return nil, fmt.Errorf("embed: %w", err)
+	}
+	for i := range chunks {
+		chunks[i].vector = vectors[i]
+	}
+	return &repoIndex{URL: repoURL, Files: files, Chunks: chunks, Partial: partial, Indexed: time.Now()}, nil
+}
+
+func cosine(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range min(len(a), len(b)) {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// search returns the chunks closest to query, best first.
+func (idx *repoIndex) search(query []float32, k int) []repoChunk {
+	type scored struct {
+		chunk repoChunk
+		score float64
+	}
+	ranked := make([]scored, len(idx.Chunks))
+	for i, c := range idx.Chunks {
+		ranked[i] = scored{c, cosine(query, c.vector)}
+	}
+	slices.SortFunc(ranked, func(x, y scored) int {
+		switch {
+		case x.score > y.score:
+			return -1
+		case x.score < y.score:
+			return 1
+		}
+		return 0
+	})
+	out := make([]repoChunk, 0, k)
+	for _, r := range ranked[:min(k, len(ranked))] {
+		out = append(out, r.chunk)
+	}
+	return out
+}
+
+// repoInstruction retrieves the excerpts of the chat's repository that relate
+// to prompt and asks for answers citing them by file and line. It is empty
+// when the chat has no repository or the lookup fails.
+func (a *App) repoInstruction(ctx context.Context, chatID int64, prompt string) string {
+	idx := a.repos.get(chatID)
+	if idx == nil || strings.TrimSpace(prompt) == "" {
+		return ""
+	}
+	vectors, err := a.embedTexts(ctx, chatID, []string{prompt}, "CODE_RETRIEVAL_QUERY")
+	if err != nil {
+		logFrom(ctx).Warn("repository lookup failed", "err", err)
+		return ""
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "The chat has indexed the repository %s. ", idx.URL)
+	b.WriteString("Excerpts related to the user's message follow, each line prefixed with its number. ")
+	b.WriteString("Answer questions about the codebase from them and cite code as path:line or path:start-end. ")
+	b.WriteString("Say so when the excerpts do not cover the question instead of guessing.\n")
+	for _, c := range idx.search(vectors[0], repoTopChunks) {
+		fmt.Fprintf(&b, "\n--- %s:%d-%d ---\n", c.Path, c.Start, c.End)
+		for i, line := range strings.Split(c.Text, "\n") {
+			fmt.Fprintf(&b, "%d| %s\n", c.Start+i, line)
+		}
+	}
+	return b.String()
+}
+
+func (a *App) handleRepo(c tele.Context) error {
+	chat := c.Chat()
+	lang := a.chatLanguage(chat, c.Sender())
+	payload := strings.TrimSpace(c.Message().Payload)
+	send := func(body string) error {
+		_, err := a.sendWithFallback(inTopic(chat, c.Message()), body, &tele.SendOptions{DisableWebPagePreview: true})
+		return err
+	}
+
+	switch {
+	case payload == "":
+		idx := a.repos.get(chat.ID)
+		if idx == nil {
+			return send(tr(lang, "repo_usage"))
+		}
+		return send(tr(lang, "repo_status", idx.URL, idx.Files, len(idx.Chunks), time.Since(idx.Indexed).Round(time.Minute)) +
+			"\n\n" + tr(lang, "repo_usage"))
+	case strings.EqualFold(payload, "drop"):
+		if a.repos.get(chat.ID) == nil {
+			return send(tr(lang, "repo_none"))
+		}
+		a.repos.set(chat.ID, nil)
+		return send(tr(lang, "repo_dropped"))
+	}
+
+	return a.enqueueJob(c.Message(), func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, repoIndexTimeout)
+		defer cancel()
+		idx, err := a.loadRepo(ctx, chat.ID, payload)
+		if err != nil {
+			logFrom(ctx).Warn("repository indexing failed", "url", payload, "err", err)
+			return send(tr(lang, "repo_failed", err.Error()))
+		}
+		a.repos.set(chat.ID, idx)
+		body := tr(lang, "repo_indexed", idx.URL, idx.Files, len(idx.Chunks))
+		if idx.Partial {
+			body += " " + tr(lang, "repo_partial", repoMaxChunks)
+		}
+		return send(body)
+	})
+}
+
+// loadRepo clones, indexes and removes the checkout of repoURL for chatID.
+func (a *App) loadRepo(ctx context.Context, chatID int64, repoURL string) (*repoIndex, error) {
+	u, ip, err := validateRepoURL(ctx, repoURL)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := exec.LookPath("git"); err != nil {
+		return nil, errors.New("git is not installed on the server")
+	}
+	dir, err := cloneRepo(ctx, u, ip)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	idx, err := a.indexRepo(ctx, chatID, u.String(), dir)
+	if err != nil {
+		return nil, err
+	}
+	idx.URL = strings.TrimSuffix(u.Host+path.Clean(u.Path), ".git")
+	return idx, nil
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/url"
+	"strings"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	// responseMediaLimit bounds the images and files sent with one answer.
+	responseMediaLimit = 10
+	// photoMaxBytes is the largest image Telegram takes as a photo; larger
+	// ones go out as documents.
+	photoMaxBytes = 10 << 20
+)
+
+// responseMedia is an image or file in an answer: generated inline or linked
+// by URL.
+type responseMedia struct {
+	MIMEType string
+	Data     []byte
+	URL      string
+	Name     string
+}
+
+// mediaOfPart returns the media a part of the answer carries.
+func mediaOfPart(part *genai.Part) (responseMedia, bool) {
+	switch {
+	case part.InlineData != nil && len(part.InlineData.Data) > 0:
+		return responseMedia{MIMEType: part.InlineData.MIMEType, Data: part.InlineData.Data, Name: part.InlineData.DisplayName}, true
+	case part.FileData != nil:
+		// Files API URIs need the API key, so only public links are passed
+		// on for Telegram to fetch.
+		u, err := url.Parse(part.FileData.FileURI)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || strings.HasSuffix(u.Hostname(), "googleapis.com") {
+			return responseMedia{}, false
+		}
+		return responseMedia{MIMEType: part.FileData.MIMEType, URL: part.FileData.FileURI, Name: part.FileData.DisplayName}, true
+	}
+	return responseMedia{}, false
+}
+
+// sendable returns the Telegram media for m: a photo, audio or video by its
+// type, a document otherwise. index numbers the files without a name.
+func (m responseMedia) sendable(index int) tele.Sendable {
+	file := tele.FromURL(m.URL)
+	if m.URL == "" {
+		file = tele.FromReader(bytes.NewReader(m.Data))
+	}
+	mediaType, _, _ := mime.ParseMediaType(m.MIMEType)
+	name := m.Name
+	if name == "" {
+		ext := ".bin"
+		if exts, _ := mime.ExtensionsByType(mediaType); len(exts) > 0 {
+			ext = exts[0]
+		}
+		name = fmt.Sprintf("file-%d%s", index, ext)
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "image/") && mediaType != "image/svg+xml" && len(m.Data) <= photoMaxBytes:
+		return &tele.Photo{File: file, Caption: m.Name}
+	case strings.HasPrefix(mediaType, "audio/"):
+		return &tele.Audio{File: file, MIME: mediaType, FileName: name, Caption: m.Name}
+	case strings.HasPrefix(mediaType, "video/"):
+		return &tele.Video{File: file, MIME: mediaType, FileName: name, Caption: m.Name}
+	}
+	return &tele.Document{File: file, MIME: mediaType, FileName: name, Caption: m.Name}
+}
+
+// sendResponseMedia sends the images and files of an answer after its text,
+// each as the Telegram media that fits its type, captioned with its name.
+func (a *App) sendResponseMedia(chat *tele.Chat, thread int, media []responseMedia) error {
+	for i, m := range media {
+		if _, err := a.botSend(chat, m.sendable(i+1), &tele.SendOptions{ThreadID: thread}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package app
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestCodeExecutionChartsSentAsPhotos(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	chart := base64.StdEncoding.EncodeToString([]byte("\x89PNG\r\n\x1a\nchart"))
+	apis.answer = func(model string, body []byte) []any {
+		return []any{
+			map[string]any{"executableCode": map[string]any{"language": "PYTHON", "code": "plt.plot([1, 2])"}},
+			map[string]any{"codeExecutionResult": map[string]any{"outcome": "OUTCOME_OK"}},
+			map[string]any{"inlineData": map[string]any{"mimeType": "image/png", "data": chart}},
+			map[string]any{"text": "Here is the chart."},
+		}
+	}
+
+	if err := app.processMessage(context.Background(), testMessage(42, "Plot 1 and 2"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	if texts := apis.sentTexts(); len(texts) != 1 || !strings.Contains(texts[0], "Here is the chart") {
+		t.Errorf("sent %q, want the answer", texts)
+	}
+	photos := apis.callsTo(telegramHost, "sendPhoto")
+	if len(photos) != 1 || !strings.Contains(string(photos[0].body), "chart") {
+		t.Fatalf("sent %d photos, want the chart", len(photos))
+	}
+}
+
+func TestResponseMediaKinds(t *testing.T) {
+	for _, tc := range []struct {
+		media responseMedia
+		want  string
+	}{
+		{responseMedia{MIMEType: "image/png", Data: []byte("png")}, "*telebot.Photo"},
+		{responseMedia{MIMEType: "image/svg+xml", Data: []byte("<svg/>")}, "*telebot.Document"},
+		{responseMedia{MIMEType: "audio/wav", Data: []byte("RIFF")}, "*telebot.Audio"},
+		{responseMedia{MIMEType: "video/mp4", URL: "https://example.com/clip.mp4"}, "*telebot.Video"},
+		{responseMedia{MIMEType: "text/csv", Data: []byte("a,b")}, "*telebot.Document"},
+	} {
+		if got := fmt.Sprintf("%T", tc.media.sendable(1)); got != tc.want {
+			t.Errorf("%s is sent as %s, want %s", tc.media.MIMEType, got, tc.want)
+		}
+	}
+	doc := responseMedia{MIMEType: "text/csv", Data: []byte("a,b")}.sendable(2).(*tele.Document)
+	if doc.FileName != "file-2.csv" {
+		t.Errorf("unnamed document is called %q", doc.FileName)
+	}
+}
+
+func TestGeneratedFilesSentAsDocuments(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.answer = func(model string, body []byte) []any {
+		return []any{
+			map[string]any{"text": "Here is the table."},
+			map[string]any{"inlineData": map[string]any{"mimeType": "text/csv", "data": base64.StdEncoding.EncodeToString([]byte("a,b\n1,2\n"))}},
+			map[string]any{"fileData": map[string]any{"mimeType": "application/pdf", "fileUri": "https://generativelanguage.googleapis.com/v1beta/files/abc"}},
+		}
+	}
+	if err := app.processMessage(context.Background(), testMessage(42, "Make a table"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	docs := apis.callsTo(telegramHost, "sendDocument")
+	if len(docs) != 1 || !strings.Contains(string(docs[0].body), `filename="file-1.csv"`) || !strings.Contains(string(docs[0].body), "1,2") {
+		t.Fatalf("sent %d documents, want the table", len(docs))
+	}
+}
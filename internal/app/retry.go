@@ -0,0 +1,171 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+const (
+	defaultFallbackModel = "gemini-2.5-flash"
+	retryMaxAttempts     = 3
+	retryMaxDelay        = 8 * time.Second
+	retryAttemptLimit    = 90 * time.Second
+)
+
+// retryBaseDelay is the ceiling of the first backoff, doubled on every
+// further attempt.
+var retryBaseDelay = time.Second
+
+// maxThinkingBudget caps the thinking budget each model accepts.
+var maxThinkingBudget = map[string]int32{
+	"gemini-2.5-pro":        32768,
+	"gemini-2.5-flash":      24576,
+	"gemini-2.5-flash-lite": 24576,
+}
+
+// generate calls GenerateContent with jittered exponential backoff on transient
+// failures, then repeats the attempts against the fallback model. It reports the
+// model that produced the response.
+func (a *App) generate(ctx context.Context, contents []*genai.Content, cfg *genai.GenerateContentConfig) (*genai.GenerateContentResponse, string, error) {
+	primary, fallback := a.primaryModel(ctx), a.tuned().fallbackModel
+	models := []string{primary}
+	if fallback != "" && fallback != primary {
+		models = append(models, fallback)
+	}
+
+	var lastErr error
+	for _, model := range models {
+		resp, err := a.generateCached(ctx, model, contents, configForModel(model, cfg))
+		if err == nil {
+			return resp, model, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil || !isRetryableGenAIError(err) {
+			break
+		}
+		logFrom(ctx).Warn("model unavailable, falling back", "model", model, "err", err)
+	}
+	return nil, "", lastErr
+}
+
+// generateWithRetry calls model with backoff on transient failures. An identical
+// request of the same chat made shortly before, or still running, answers
+// instead of a new call; in privacy mode every request makes its own call, so
+// nothing of the chat stays in the cache.
+func (a *App) generateWithRetry(ctx context.Context, model string, contents []*genai.Content, cfg *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	cfg = withoutMixedTools(cfg)
+	chatID, _ := chatIDFromContext(ctx)
+	if chatID != 0 && a.privacy.enabled(chatID) {
+		return a.retryGenerate(ctx, model, contents, cfg)
+	}
+	key, err := generationKey(chatID, model, contents, cfg)
+	if err != nil {
+		logFrom(ctx).Warn("cannot key generation for dedupe", "err", err)
+		return a.retryGenerate(ctx, model, contents, cfg)
+	}
+	return a.generations.do(ctx, key, func() (*genai.GenerateContentResponse, error) {
+		return a.retryGenerate(ctx, model, contents, cfg)
+	})
+}
+
+func (a *App) retryGenerate(ctx context.Context, model string, contents []*genai.Content, cfg *genai.GenerateContentConfig) (*genai.GenerateContentResponse, error) {
+	backend, name := a.providerFor(model)
+	contents, cfg = forCapabilities(backend.capabilities(), contents, cfg)
+	var err error
+	for attempt := 0; attempt < retryMaxAttempts; attempt++ {
+		if attempt > 0 {
+			if waitErr := sleepContext(ctx, backoffDelay(attempt)); waitErr != nil {
+				return nil, err
+			}
+		}
+
+		release, waitErr := a.calls.acquire(ctx)
+		if waitErr != nil {
+			return nil, waitErr
+		}
+		attemptCtx, cancel := context.WithTimeout(ctx, retryAttemptLimit)
+		var resp *genai.GenerateContentResponse
+		resp, err = backend.generateContent(attemptCtx, name, contents, cfg)
+		cancel()
+		release()
+		if err == nil {
+			return resp, nil
+		}
+		if ctx.Err() != nil || !isRetryableGenAIError(err) {
+			return nil, err
+		}
+		logFrom(ctx).Warn("genai attempt failed", "attempt", attempt+1, "max_attempts", retryMaxAttempts, "model", model, "err", err)
+	}
+	return nil, err
+}
+
+// configForModel adapts cfg to the limits of model, copying it only when a change is needed.
+func configForModel(model string, cfg *genai.GenerateContentConfig) *genai.GenerateContentConfig {
+	if cfg == nil || cfg.ThinkingConfig == nil || cfg.ThinkingConfig.ThinkingBudget == nil {
+		return cfg
+	}
+	limit, ok := maxThinkingBudget[model]
+	if !ok || *cfg.ThinkingConfig.ThinkingBudget <= limit {
+		return cfg
+	}
+	cloned := *cfg
+	thinking := *cfg.ThinkingConfig
+	thinking.ThinkingBudget = &limit
+	cloned.ThinkingConfig = &thinking
+	return &cloned
+}
+
+// fallbackNotice annotates replies produced by a model other than the primary one.
+func (a *App) fallbackNotice(ctx context.Context, lang language, model string) string {
+	primary := a.primaryModel(ctx)
+	if model == "" || model == primary {
+		return ""
+	}
+	return tr(lang, "fallback_notice", model, primary)
+}
+
+// backoffDelay returns a full-jitter delay for the given retry attempt.
+func backoffDelay(attempt int) time.Duration {
+	ceiling := retryBaseDelay << (attempt - 1)
+	if ceiling > retryMaxDelay || ceiling <= 0 {
+		ceiling = retryMaxDelay
+	}
+	return time.Duration(rand.Int64N(int64(ceiling))) + ceiling/4
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func isRetryableGenAIError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.Code {
+		case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+			http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+			return true
+		}
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
@@ -0,0 +1,123 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	for attempt := 1; attempt <= 6; attempt++ {
+		ceiling := min(retryBaseDelay<<(attempt-1), retryMaxDelay)
+		for range 20 {
+			if d := backoffDelay(attempt); d < ceiling/4 || d >= ceiling+ceiling/4 {
+				t.Fatalf("backoffDelay(%d) = %s, want within [%s, %s)", attempt, d, ceiling/4, ceiling+ceiling/4)
+			}
+		}
+	}
+}
+
+func TestIsRetryableGenAIError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{genai.APIError{Code: http.StatusTooManyRequests}, true},
+		{genai.APIError{Code: http.StatusServiceUnavailable}, true},
+		{genai.APIError{Code: http.StatusBadRequest}, false},
+		{genai.APIError{Code: http.StatusForbidden}, false},
+		{context.DeadlineExceeded, true},
+		{context.Canceled, false},
+		{errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		if got := isRetryableGenAIError(tt.err); got != tt.want {
+			t.Errorf("isRetryableGenAIError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateRetriesAndFallsBack(t *testing.T) {
+	base := retryBaseDelay
+	retryBaseDelay = time.Millisecond
+	t.Cleanup(func() { retryBaseDelay = base })
+
+	primary, fallback := "/"+geminiModel+":", "/"+defaultFallbackModel+":"
+	tests := []struct {
+		name  string
+		fail  func(path string, n int) int
+		model string
+		// calls counts the requests to the primary and the fallback model.
+		calls   [2]int
+		wantErr bool
+	}{
+		{"succeeds after a transient failure", func(path string, n int) int {
+			if n == 1 {
+				return http.StatusServiceUnavailable
+			}
+			return 0
+		}, geminiModel, [2]int{2, 0}, false},
+		{"falls back once the primary is exhausted", func(path string, n int) int {
+			if strings.Contains(path, primary) {
+				return http.StatusServiceUnavailable
+			}
+			return 0
+		}, defaultFallbackModel, [2]int{retryMaxAttempts, 1}, false},
+		{"gives up on a request error", func(path string, n int) int {
+			return http.StatusBadRequest
+		}, "", [2]int{1, 0}, true},
+		{"fails when both models are down", func(path string, n int) int {
+			return http.StatusInternalServerError
+		}, "", [2]int{retryMaxAttempts, retryMaxAttempts}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, apis := newTestApp(t, Config{})
+			n := 0
+			apis.geminiError = func(path string, body []byte) int {
+				n++
+				return tt.fail(path, n)
+			}
+			contents := []*genai.Content{genai.NewContentFromText("Hi", genai.RoleUser)}
+			resp, model, err := app.generate(context.Background(), contents, nil)
+			if (err != nil) != tt.wantErr || model != tt.model {
+				t.Fatalf("generate = %q, %v; want model %q, error %v", model, err, tt.model, tt.wantErr)
+			}
+			if err == nil && resp.Text() != "Sure." {
+				t.Errorf("text = %q", resp.Text())
+			}
+			var calls [2]int
+			for _, call := range apis.callsTo(geminiHost, ":generateContent") {
+				switch {
+				case strings.Contains(call.method, primary):
+					calls[0]++
+				case strings.Contains(call.method, fallback):
+					calls[1]++
+				}
+			}
+			if calls != tt.calls {
+				t.Errorf("requests to primary and fallback = %v, want %v", calls, tt.calls)
+			}
+		})
+	}
+}
+
+func TestConfigForModelCapsThinkingBudget(t *testing.T) {
+	cfg := &genai.GenerateContentConfig{ThinkingConfig: &genai.ThinkingConfig{ThinkingBudget: genai.Ptr[int32](30000)}}
+	capped := configForModel("gemini-2.5-flash", cfg)
+	if got := *capped.ThinkingConfig.ThinkingBudget; got != 24576 {
+		t.Errorf("flash budget = %d, want 24576", got)
+	}
+	if *cfg.ThinkingConfig.ThinkingBudget != 30000 {
+		t.Error("configForModel changed the caller's config")
+	}
+	if configForModel("gemini-2.5-pro", cfg) != cfg {
+		t.Error("a budget within the limit was copied")
+	}
+}
@@ -0,0 +1,210 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	selectSamplingUnique = "set_sampling"
+	maxTemperature       = 2.0
+	minReplyTokens       = 64
+	maxReplyTokens       = 32768
+)
+
+// samplingSettings are the sampling controls a chat picked in /settings; nil
+// fields keep the model default.
+type samplingSettings struct {
+	Temperature *float32 `json:"temperature,omitempty"`
+	TopP        *float32 `json:"top_p,omitempty"`
+	// MaxReplyTokens caps the visible reply; the thinking budget comes on top.
+	MaxReplyTokens *int32 `json:"max_reply_tokens,omitempty"`
+}
+
+func (s samplingSettings) isZero() bool {
+	return s.Temperature == nil && s.TopP == nil && s.MaxReplyTokens == nil
+}
+
+// apply sets the chosen controls on cfg. The reply cap replaces the one of
+// /verbosity and keeps the reserve for thinking.
+func (s samplingSettings) apply(cfg *genai.GenerateContentConfig, thinking thinkingMode) {
+	if s.Temperature != nil {
+		cfg.Temperature = genai.Ptr(*s.Temperature)
+	}
+	if s.TopP != nil {
+		cfg.TopP = genai.Ptr(*s.TopP)
+	}
+	if s.MaxReplyTokens != nil {
+		cfg.MaxOutputTokens = *s.MaxReplyTokens + thinkingReserve(thinking)
+	}
+}
+
+// samplingStore keeps the sampling settings of each chat across restarts in a
+// JSON file. Every change rewrites the file atomically.
+type samplingStore struct {
+	mu    sync.Mutex
+	path  string
+	chats map[int64]samplingSettings
+}
+
+func openSamplingStore(path string) (*samplingStore, error) {
+	s := &samplingStore{path: path, chats: make(map[int64]samplingSettings)}
+	if err := readJSONFile(path, &s.chats); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *samplingStore) get(chatID int64) samplingSettings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.chats[chatID]
+}
+
+// migrate moves the settings of chat from to chat to, unless to has its own.
+func (s *samplingStore) migrate(from, to int64) error {
+	settings := s.get(from)
+	if settings.isZero() {
+		return nil
+	}
+	if _, err := s.update(to, func(cur *samplingSettings) {
+		if cur.isZero() {
+			*cur = settings
+		}
+	}); err != nil {
+		return err
+	}
+	_, err := s.update(from, func(cur *samplingSettings) { *cur = samplingSettings{} })
+	return err
+}
+
+// update changes the settings of chatID with fn and saves them.
+func (s *samplingStore) update(chatID int64, fn func(*samplingSettings)) (samplingSettings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	settings := s.chats[chatID]
+	fn(&settings)
+	if settings.isZero() {
+		delete(s.chats, chatID)
+	} else {
+		s.chats[chatID] = settings
+	}
+
+	return settings, writeJSONFile(s.path, s.chats)
+}
+
+// setSampling parses "<control> <value>", where value may be "default", and
+// applies it to settings.
+func setSampling(settings *samplingSettings, control, value string) error {
+	reset := strings.EqualFold(value, "default") || strings.EqualFold(value, "off")
+	switch strings.ToLower(control) {
+	case "temperature", "temp":
+		if reset {
+			settings.Temperature = nil
+			return nil
+		}
+		f, err := strconv.ParseFloat(value, 32)
+		if err != nil || f < 0 || f > maxTemperature {
+			return fmt.Errorf("temperature must be between 0 and %g", maxTemperature)
+		}
+		settings.Temperature = genai.Ptr(float32(f))
+	case "top_p", "topp", "top-p":
+		if reset {
+			settings.TopP = nil
+			return nil
+		}
+		f, err := strconv.ParseFloat(value, 32)
+		if err != nil || f <= 0 || f > 1 {
+			return errors.New("top-p must be above 0 and at most 1")
+		}
+		settings.TopP = genai.Ptr(float32(f))
+	case "max_tokens", "tokens":
+		if reset {
+			settings.MaxReplyTokens = nil
+			return nil
+		}
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || n < minReplyTokens || n > maxReplyTokens {
+			return fmt.Errorf("max tokens must be between %d and %d", minReplyTokens, maxReplyTokens)
+		}
+		settings.MaxReplyTokens = genai.Ptr(int32(n))
+	case "reset":
+		*settings = samplingSettings{}
+	default:
+		return errors.New("unknown control")
+	}
+	return nil
+}
+
+func (s samplingSettings) describe(lang language) string {
+	value := func(set bool, v string) string {
+		if !set {
+			return tr(lang, "sampling_default")
+		}
+		return v
+	}
+	var temperature, topP, tokens string
+	if s.Temperature != nil {
+		temperature = strconv.FormatFloat(float64(*s.Temperature), 'g', -1, 32)
+	}
+	if s.TopP != nil {
+		topP = strconv.FormatFloat(float64(*s.TopP), 'g', -1, 32)
+	}
+	if s.MaxReplyTokens != nil {
+		tokens = strconv.Itoa(int(*s.MaxReplyTokens))
+	}
+	return tr(lang, "sampling_current",
+		value(s.Temperature != nil, temperature),
+		value(s.TopP != nil, topP),
+		value(s.MaxReplyTokens != nil, tokens))
+}
+
+// samplingRows are the /settings buttons for the sampling controls.
+func samplingRows(menu *tele.ReplyMarkup, lang language) []tele.Row {
+	row := func(control, label string, values ...string) tele.Row {
+		var btns []tele.Btn
+		for _, v := range values {
+			btns = append(btns, menu.Data(label+" "+v, selectSamplingUnique, control+" "+v))
+		}
+		return menu.Row(btns...)
+	}
+	return []tele.Row{
+		row("temperature", tr(lang, "sampling_temp"), "0.2", "0.7", "1.0", "1.5"),
+		row("top_p", tr(lang, "sampling_top_p"), "0.5", "0.8", "0.95"),
+		row("max_tokens", tr(lang, "sampling_tokens"), "512", "2048", "8192"),
+		menu.Row(menu.Data(tr(lang, "sampling_reset"), selectSamplingUnique, "reset")),
+	}
+}
+
+// changeSampling applies a control change for the chat and reports the result.
+func (a *App) changeSampling(c tele.Context, control, value string) error {
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	var problem error
+	settings, err := a.sampling.update(c.Chat().ID, func(s *samplingSettings) {
+		problem = setSampling(s, control, value)
+	})
+	body := settings.describe(lang)
+	switch {
+	case problem != nil:
+		body = tr(lang, "sampling_usage", minReplyTokens, maxReplyTokens)
+	case err != nil:
+		a.chatLog(c.Chat().ID).Warn("save sampling settings failed", "chat_id", c.Chat().ID, "err", err)
+	}
+	_, sendErr := a.sendWithFallback(inTopic(c.Chat(), c.Message()), body, &tele.SendOptions{DisableWebPagePreview: true})
+	return sendErr
+}
+
+func (a *App) handleSamplingSelection(c tele.Context) error {
+	if err := c.Respond(); err != nil {
+		slog.Warn("callback acknowledge failed", "err", err)
+	}
+	control, value, _ := strings.Cut(c.Callback().Data, " ")
+	return a.changeSampling(c, control, value)
+}
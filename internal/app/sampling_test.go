@@ -0,0 +1,51 @@
+package app
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestSettingsSamplingControls(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	settings := func(payload string) {
+		t.Helper()
+		msg := testMessage(42, "/settings "+payload)
+		msg.Payload = payload
+		if err := app.handleSettings(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("handleSettings(%q): %v", payload, err)
+		}
+	}
+
+	settings("temperature 0.3")
+	settings("top_p 0.9")
+	settings("max_tokens 1000")
+	settings("temperature 7")
+	if texts := apis.sentTexts(); !strings.Contains(texts[len(texts)-1], "Usage") {
+		t.Errorf("out of range temperature answered %q, want the usage", texts[len(texts)-1])
+	}
+
+	session := app.sessions.get(42)
+	cfg := app.buildGenerateConfig(42, session.prefs())
+	if cfg.Temperature == nil || *cfg.Temperature != 0.3 || cfg.TopP == nil || *cfg.TopP != 0.9 {
+		t.Errorf("config temperature %v, top-p %v; want 0.3 and 0.9", cfg.Temperature, cfg.TopP)
+	}
+	if want := 1000 + thinkingReserve(session.prefs().thinking); cfg.MaxOutputTokens != want {
+		t.Errorf("max output tokens = %d, want %d", cfg.MaxOutputTokens, want)
+	}
+
+	reopened, err := openSamplingStore(filepath.Join(app.dataDir, "sampling.json"))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if got := reopened.get(42); got.Temperature == nil || *got.Temperature != 0.3 {
+		t.Errorf("persisted settings = %+v", got)
+	}
+
+	settings("reset")
+	if cfg := app.buildGenerateConfig(42, session.prefs()); cfg.Temperature != nil || cfg.TopP != nil {
+		t.Errorf("after reset temperature %v, top-p %v; want defaults", cfg.Temperature, cfg.TopP)
+	}
+}
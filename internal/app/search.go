@@ -0,0 +1,63 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const searchInstruction = "Search mode: answer only from Google Search results fetched for this question, even if you think you know the answer. " +
+	"Prefer the most recent sources and mention dates when they matter."
+
+// handleSearch answers /search <query> from a Google search it forces, for
+// users who want fresh, sourced results rather than the model's choice of
+// tool. The sources follow the answer as links.
+func (a *App) handleSearch(c tele.Context) error {
+	msg := c.Message()
+	payload := strings.TrimSpace(msg.Payload)
+	if payload == "" {
+		_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), tr(a.chatLanguage(c.Chat(), c.Sender()), "search_usage"), &tele.SendOptions{DisableWebPagePreview: true})
+		return err
+	}
+	opts := turnOptions{
+		instruction:   searchInstruction,
+		tools:         []*genai.Tool{{GoogleSearch: &genai.GoogleSearch{}}},
+		requireSearch: true,
+	}
+	return a.enqueueTurn(withText(msg, payload), opts)
+}
+
+// generateWithSearch retries once with firmer guidance when the model
+// answered without searching, returning ok=false if it still did not.
+func (a *App) generateWithSearch(ctx context.Context, chatID int64, conversation []*genai.Content, cfg *genai.GenerateContentConfig) (*genai.GenerateContentResponse, string, bool, error) {
+	resp, model, err := a.generate(ctx, conversation, cfg)
+	if err != nil || len(collectSources(firstCandidate(resp))) > 0 {
+		return resp, model, err == nil, err
+	}
+	a.recordUsage(ctx, chatID, model, resp.UsageMetadata)
+
+	retryCfg := *cfg
+	retryCfg.SystemInstruction = appendInstruction(cfg.SystemInstruction, "Your previous attempt did not search. You must run a Google search before answering.")
+	resp, model, err = a.generate(ctx, conversation, &retryCfg)
+	if err != nil {
+		return nil, "", false, err
+	}
+	return resp, model, len(collectSources(firstCandidate(resp))) > 0, nil
+}
+
+// sourcesList numbers the sources of an answer with their links.
+func sourcesList(lang language, sources []sourceRef) string {
+	var b strings.Builder
+	b.WriteString(tr(lang, "sources_header"))
+	for i, src := range sources {
+		title := src.Title
+		if title == "" {
+			title = tr(lang, "source_untitled")
+		}
+		fmt.Fprintf(&b, "\n%d. %s - %s", i+1, title, src.URI)
+	}
+	return b.String()
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestSearchForcesGroundingAndListsSources(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.reply = "Go 1.25 is the latest release."
+	apis.metadata = func(body []byte) map[string]any {
+		if !strings.Contains(string(body), "did not search") {
+			return nil
+		}
+		return map[string]any{"groundingMetadata": map[string]any{"groundingChunks": []any{
+			map[string]any{"web": map[string]any{"uri": "https://go.dev/doc/devel/release", "title": "go.dev"}},
+		}}}
+	}
+
+	msg := testMessage(42, "/search latest Go release")
+	msg.Payload = "latest Go release"
+	if err := app.handleSearch(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+		t.Fatalf("handleSearch: %v", err)
+	}
+	app.queue.drain(context.Background())
+
+	calls := apis.callsTo(geminiHost, "gemini-2.5-pro:generateContent")
+	if len(calls) != 2 {
+		t.Fatalf("generateContent called %d times, want a retry after the unsourced answer", len(calls))
+	}
+	if body := string(calls[0].body); !strings.Contains(body, `"googleSearch"`) || strings.Contains(body, `"functionDeclarations"`) {
+		t.Errorf("search request does not force Google Search: %s", body)
+	}
+	texts := apis.sentTexts()
+	if len(texts) != 1 || !strings.Contains(texts[0], "go\\.dev \\- https://go\\.dev/doc/devel/release") {
+		t.Errorf("sent %q, want the answer with its sources", texts)
+	}
+}
+
+func TestSearchUsage(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	msg := testMessage(42, "/search")
+	if err := app.handleSearch(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+		t.Fatalf("handleSearch: %v", err)
+	}
+	if texts := apis.sentTexts(); len(texts) != 1 || !strings.Contains(texts[0], "Usage: /search") {
+		t.Errorf("sent %q, want the usage", texts)
+	}
+}
@@ -0,0 +1,355 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	sendDraftUnique    = "send_draft"
+	discardDraftUnique = "discard_draft"
+	sendDraftTTL       = 30 * time.Minute
+)
+
+var sendTargetName = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// draftInstruction asks for a message the user will post in another chat.
+const draftInstruction = "The user is composing a message that the bot will post on their behalf in the Telegram chat %q. " +
+	"Write that message from their instructions and the conversation so far. " +
+	"Reply with the message text only, ready to post, without a preamble, quotes or alternatives. " +
+	"Use the language the user writes in unless they ask for another."
+
+// sendTarget is a chat that opted in to receive drafted messages from a user.
+type sendTarget struct {
+	ChatID int64  `json:"chat_id"`
+	Title  string `json:"title"`
+}
+
+// sendTargetStore keeps the saved targets of each user across restarts in a
+// JSON file. Every change rewrites the file atomically.
+type sendTargetStore struct {
+	mu    sync.Mutex
+	path  string
+	users map[int64]map[string]sendTarget
+}
+
+func openSendTargetStore(path string) (*sendTargetStore, error) {
+	s := &sendTargetStore{path: path, users: make(map[int64]map[string]sendTarget)}
+	if err := readJSONFile(path, &s.users); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *sendTargetStore) get(userID int64, name string) (sendTarget, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	target, ok := s.users[userID][name]
+	return target, ok
+}
+
+// list returns the names of the targets of userID, sorted.
+func (s *sendTargetStore) list(userID int64) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var names []string
+	for name := range s.users[userID] {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (s *sendTargetStore) save(userID int64, name string, target sendTarget) error {
+	return s.update(func() {
+		if s.users[userID] == nil {
+			s.users[userID] = make(map[string]sendTarget)
+		}
+		s.users[userID][name] = target
+	})
+}
+
+func (s *sendTargetStore) remove(userID int64, name string) (bool, error) {
+	s.mu.Lock()
+	_, ok := s.users[userID][name]
+	s.mu.Unlock()
+	if !ok {
+		return false, nil
+	}
+	return true, s.update(func() {
+		delete(s.users[userID], name)
+		if len(s.users[userID]) == 0 {
+			delete(s.users, userID)
+		}
+	})
+}
+
+// revoke removes every target pointing at chatID and returns how many there were.
+func (s *sendTargetStore) revoke(chatID int64) (int, error) {
+	removed := 0
+	err := s.update(func() {
+		for userID, targets := range s.users {
+			for name, target := range targets {
+				if target.ChatID == chatID {
+					delete(targets, name)
+					removed++
+				}
+			}
+			if len(targets) == 0 {
+				delete(s.users, userID)
+			}
+		}
+	})
+	return removed, err
+}
+
+// migrate points the targets at chat from to chat to.
+func (s *sendTargetStore) migrate(from, to int64) error {
+	return s.update(func() {
+		for _, targets := range s.users {
+			for name, target := range targets {
+				if target.ChatID == from {
+					target.ChatID = to
+					targets[name] = target
+				}
+			}
+		}
+	})
+}
+
+// forgetUser removes every target userID saved.
+func (s *sendTargetStore) forgetUser(userID int64) error {
+	return s.update(func() { delete(s.users, userID) })
+}
+
+// update applies fn under the lock and saves the targets.
+func (s *sendTargetStore) update(fn func()) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn()
+	return writeJSONFile(s.path, s.users)
+}
+
+// sendDraft is a drafted message waiting for its author to send or discard it.
+type sendDraft struct {
+	name    string
+	target  sendTarget
+	text    string
+	chatID  int64
+	userID  int64
+	created time.Time
+}
+
+// sendDrafts parks drafts until their author presses Send or Discard.
+type sendDrafts struct {
+	mu      sync.Mutex
+	pending map[string]*sendDraft
+	counter uint64
+}
+
+func newSendDrafts() *sendDrafts {
+	return &sendDrafts{pending: make(map[string]*sendDraft)}
+}
+
+func (d *sendDrafts) put(draft *sendDraft) string {
+	id := strconv.FormatUint(atomic.AddUint64(&d.counter, 1), 10)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for key, item := range d.pending {
+		if time.Since(item.created) > sendDraftTTL {
+			delete(d.pending, key)
+		}
+	}
+	d.pending[id] = draft
+	return id
+}
+
+// take removes and returns draft id of chatID when userID wrote it. Another
+// user's attempt leaves it in place.
+func (d *sendDrafts) take(id string, chatID, userID int64) (*sendDraft, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	draft, ok := d.pending[id]
+	if !ok || draft.chatID != chatID || time.Since(draft.created) > sendDraftTTL {
+		return nil, errActionExpired
+	}
+	if draft.userID != userID {
+		return nil, errActionNotYours
+	}
+	delete(d.pending, id)
+	return draft, nil
+}
+
+// handleSendTo manages the targets of the sender and drafts messages for them:
+//
+//	/sendto                     lists the saved targets
+//	/sendto allow <name>        opts the current chat in as a target, for its admins
+//	/sendto revoke              removes every target pointing at the current chat
+//	/sendto remove <name>       forgets a saved target
+//	/sendto <name> <request>    drafts a message for the target
+func (a *App) handleSendTo(c tele.Context) error {
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	reply := func(body string) error {
+		_, err := a.sendWithFallback(c.Chat(), body, &tele.SendOptions{ThreadID: messageTopic(c.Message()), DisableWebPagePreview: true})
+		return err
+	}
+	userID := userIDOf(c.Sender())
+	if userID == 0 {
+		return nil
+	}
+
+	command, rest, _ := strings.Cut(strings.TrimSpace(c.Message().Payload), " ")
+	rest = strings.TrimSpace(rest)
+	name := strings.ToLower(rest)
+	switch strings.ToLower(command) {
+	case "":
+		names := a.sendTargets.list(userID)
+		if len(names) == 0 {
+			return reply(tr(lang, "sendto_none"))
+		}
+		return reply(tr(lang, "sendto_list", strings.Join(names, ", ")))
+	case "allow":
+		if !sendTargetName.MatchString(name) {
+			return reply(tr(lang, "sendto_usage"))
+		}
+		if isGroupChat(c.Chat()) && !a.isChatAdmin(c) {
+			return reply(tr(lang, "sendto_admins_only"))
+		}
+		title := strings.TrimSpace(c.Chat().Title)
+		if title == "" {
+			title = name
+		}
+		if err := a.sendTargets.save(userID, name, sendTarget{ChatID: c.Chat().ID, Title: title}); err != nil {
+			a.chatLog(c.Chat().ID).Warn("save send target failed", "chat_id", c.Chat().ID, "err", err)
+		}
+		return reply(tr(lang, "sendto_allowed", name))
+	case "revoke":
+		if isGroupChat(c.Chat()) && !a.isChatAdmin(c) {
+			return reply(tr(lang, "sendto_admins_only"))
+		}
+		removed, err := a.sendTargets.revoke(c.Chat().ID)
+		if err != nil {
+			a.chatLog(c.Chat().ID).Warn("save send targets failed", "chat_id", c.Chat().ID, "err", err)
+		}
+		return reply(tr(lang, "sendto_revoked", removed))
+	case "remove":
+		removed, err := a.sendTargets.remove(userID, name)
+		if err != nil {
+			slog.Warn("save send targets failed", "user_id", userID, "err", err)
+		}
+		if !removed {
+			return reply(tr(lang, "sendto_unknown", name))
+		}
+		return reply(tr(lang, "sendto_removed", name))
+	}
+
+	name = strings.ToLower(command)
+	target, ok := a.sendTargets.get(userID, name)
+	if !ok {
+		return reply(tr(lang, "sendto_unknown", name))
+	}
+	if rest == "" {
+		return reply(tr(lang, "sendto_usage"))
+	}
+	chat, session := c.Chat(), a.sessionOf(c.Message())
+	return a.enqueueJob(c.Message(), func(ctx context.Context) error {
+		return a.draftForTarget(ctx, chat, session, userID, lang, name, target, rest)
+	})
+}
+
+// draftForTarget writes a message for target with the chat's conversation as
+// context and shows it to the user with Send and Discard buttons.
+func (a *App) draftForTarget(ctx context.Context, chat *tele.Chat, session *sessionState, userID int64, lang language, name string, target sendTarget, request string) error {
+	session.mu.Lock()
+	contents := session.conversationWith(genai.NewContentFromText(request, genai.RoleUser))
+	session.mu.Unlock()
+	cfg := &genai.GenerateContentConfig{
+		SystemInstruction: genai.NewContentFromText(fmt.Sprintf(draftInstruction, target.Title), genai.Role("system")),
+		ThinkingConfig:    &genai.ThinkingConfig{IncludeThoughts: true},
+	}
+	resp, model, err := a.generate(ctx, contents, cfg)
+	var text string
+	if err == nil {
+		a.recordUsage(ctx, chat.ID, model, resp.UsageMetadata)
+		text, _ = a.renderResponse(resp)
+	}
+	if text == "" {
+		logFrom(ctx).Warn("draft failed", "target", name, "err", err)
+		_, sendErr := a.sendWithFallback(chatTopic(chat, threadFromContext(ctx)), tr(lang, "sendto_no_draft"), &tele.SendOptions{DisableWebPagePreview: true})
+		return sendErr
+	}
+
+	id := a.sendDrafts.put(&sendDraft{name: name, target: target, text: text, chatID: chat.ID, userID: userID, created: time.Now()})
+	markup := &tele.ReplyMarkup{}
+	markup.Inline(markup.Row(
+		markup.Data(tr(lang, "btn_send_draft"), sendDraftUnique, id),
+		markup.Data(tr(lang, "btn_discard_draft"), discardDraftUnique, id),
+	))
+	body := tr(lang, "sendto_preview", target.Title) + "\n\n" + text
+	_, err = a.sendWithFallback(chatTopic(chat, threadFromContext(ctx)), body, &tele.SendOptions{ReplyMarkup: markup, DisableWebPagePreview: true})
+	return err
+}
+
+// takeDraft takes the draft a Send or Discard button names, telling anyone
+// but its author that it is not theirs.
+func (a *App) takeDraft(c tele.Context, lang language) (*sendDraft, error) {
+	draft, err := a.sendDrafts.take(c.Callback().Data, c.Chat().ID, userIDOf(c.Sender()))
+	if errors.Is(err, errActionNotYours) {
+		if err := c.Respond(&tele.CallbackResponse{Text: tr(lang, "sendto_not_yours")}); err != nil {
+			slog.Warn("callback acknowledge failed", "err", err)
+		}
+		return nil, err
+	}
+	if err := c.Respond(); err != nil {
+		slog.Warn("callback acknowledge failed", "err", err)
+	}
+	return draft, err
+}
+
+func (a *App) handleSendDraft(c tele.Context) error {
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	draft, err := a.takeDraft(c, lang)
+	if errors.Is(err, errActionNotYours) {
+		return nil
+	}
+	body := tr(lang, "sendto_expired")
+	if err == nil {
+		// The target may have been revoked since the draft was written.
+		if target, ok := a.sendTargets.get(draft.userID, draft.name); !ok || target.ChatID != draft.target.ChatID {
+			body = tr(lang, "sendto_unknown", draft.name)
+		} else if _, err := a.sendWithFallback(tele.ChatID(target.ChatID), draft.text, &tele.SendOptions{DisableWebPagePreview: true}); err != nil {
+			a.chatLog(c.Chat().ID).Warn("deliver draft failed", "chat_id", c.Chat().ID, "target_chat_id", target.ChatID, "err", err)
+			body = tr(lang, "sendto_failed", target.Title)
+		} else {
+			body = tr(lang, "sendto_sent", target.Title)
+		}
+	}
+	_, err = a.sendWithFallback(inTopic(c.Chat(), c.Message()), body, &tele.SendOptions{DisableWebPagePreview: true})
+	return err
+}
+
+func (a *App) handleDiscardDraft(c tele.Context) error {
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	_, err := a.takeDraft(c, lang)
+	if errors.Is(err, errActionNotYours) {
+		return nil
+	}
+	body := tr(lang, "sendto_discarded")
+	if err != nil {
+		body = tr(lang, "sendto_expired")
+	}
+	_, err = a.sendWithFallback(inTopic(c.Chat(), c.Message()), body, &tele.SendOptions{DisableWebPagePreview: true})
+	return err
+}
@@ -0,0 +1,99 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestSendToDraftsAndDelivers(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.reply = "The office is closed on Monday."
+	sendto := func(msg *tele.Message, payload string) {
+		t.Helper()
+		msg.Text = "/sendto " + payload
+		msg.Payload = payload
+		if err := app.handleSendTo(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("handleSendTo(%q): %v", payload, err)
+		}
+	}
+	group := func(admin bool) *tele.Message {
+		msg := testMessage(-300, "")
+		msg.Chat = &tele.Chat{ID: -300, Type: tele.ChatSuperGroup, Title: "Team"}
+		msg.Sender = &tele.User{ID: 42, FirstName: "Test"}
+		if admin {
+			msg.SenderChat = msg.Chat
+		}
+		return msg
+	}
+	lastText := func() string {
+		texts := apis.sentTexts()
+		return texts[len(texts)-1]
+	}
+
+	sendto(group(false), "allow team")
+	if !strings.Contains(lastText(), "Only group admins") {
+		t.Fatalf("non-admin allow answered %q", lastText())
+	}
+	sendto(group(true), "allow team")
+	if names := app.sendTargets.list(42); len(names) != 1 || names[0] != "team" {
+		t.Fatalf("targets = %v, want team", names)
+	}
+
+	sendto(testMessage(42, ""), "team Tell everyone the office is closed on Monday")
+	app.queue.drain(context.Background())
+	app.queue.resume()
+	if !strings.Contains(lastText(), "Draft for Team") {
+		t.Fatalf("draft answered %q", lastText())
+	}
+	var id string
+	for key := range app.sendDrafts.pending {
+		id = key
+	}
+
+	if err := app.handleSendDraft(actionCallback(app, 42, 7, id)); err != nil {
+		t.Fatalf("handleSendDraft by another user: %v", err)
+	}
+	if len(app.sendDrafts.pending) != 1 {
+		t.Fatal("another user took the draft")
+	}
+
+	callback := actionCallback(app, 42, 42, id)
+	callback.Callback().Message.Chat.Type = tele.ChatPrivate
+	if err := app.handleSendDraft(callback); err != nil {
+		t.Fatalf("handleSendDraft: %v", err)
+	}
+	delivered := false
+	for _, call := range apis.callsTo(telegramHost, "sendMessage") {
+		var params struct {
+			ChatID string `json:"chat_id"`
+			Text   string `json:"text"`
+		}
+		if json.Unmarshal(call.body, &params) == nil && params.ChatID == "-300" && strings.Contains(params.Text, "closed on Monday") {
+			delivered = true
+		}
+	}
+	if !delivered {
+		t.Error("draft not delivered to the target chat")
+	}
+	if !strings.Contains(lastText(), "Sent to Team") {
+		t.Errorf("send answered %q", lastText())
+	}
+
+	reopened, err := openSendTargetStore(filepath.Join(app.dataDir, "sendto.json"))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if target, ok := reopened.get(42, "team"); !ok || target.ChatID != -300 {
+		t.Errorf("persisted target = %+v, %v", target, ok)
+	}
+
+	sendto(group(true), "revoke")
+	if names := app.sendTargets.list(42); len(names) != 0 {
+		t.Errorf("targets after revoke = %v", names)
+	}
+}
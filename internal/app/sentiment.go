@@ -0,0 +1,188 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	sentimentModel   = "gemini-2.5-flash-lite"
+	sentimentTimeout = 10 * time.Second
+	// sentimentWindow is how many recent messages of a chat the trend covers.
+	sentimentWindow = 6
+	// sentimentAlertLevel is the frustration, from 0 to 10, that a rising
+	// trend has to reach before the operators hear of it.
+	sentimentAlertLevel = 7
+	// sentimentCooldown keeps a chat from alerting again while the operators
+	// are likely still on it.
+	sentimentCooldown = time.Hour
+	// sentimentQuoteRunes bounds the quoted message in an alert.
+	sentimentQuoteRunes = 300
+)
+
+const sentimentInstruction = `You watch a support conversation for the operators. Rate how frustrated the user is in their latest message, from 0 (calm or happy) to 10 (angry, about to give up). Earlier messages are context only. Set wants_human when the user asks for a person, an agent or a manager.`
+
+func sentimentSchema() *genai.Schema {
+	return &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"frustration": {Type: genai.TypeInteger, Minimum: genai.Ptr[float64](0), Maximum: genai.Ptr[float64](10)},
+			"wants_human": {Type: genai.TypeBoolean},
+		},
+		Required: []string{"frustration", "wants_human"},
+	}
+}
+
+type sentimentScore struct {
+	Frustration int  `json:"frustration"`
+	WantsHuman  bool `json:"wants_human"`
+}
+
+// sentimentTracker keeps the recent frustration scores of each chat.
+type sentimentTracker struct {
+	mu    sync.Mutex
+	chats map[int64]*sentimentTrend
+}
+
+type sentimentTrend struct {
+	scores   []int
+	messages []string
+	alerted  time.Time
+}
+
+func newSentimentTracker() *sentimentTracker {
+	return &sentimentTracker{chats: make(map[int64]*sentimentTrend)}
+}
+
+// add records a score with its message and reports whether the chat should
+// alert now, with the scores that led to it.
+func (t *sentimentTracker) add(chatID int64, text string, score sentimentScore, now time.Time) ([]int, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	trend, ok := t.chats[chatID]
+	if !ok {
+		trend = &sentimentTrend{}
+		t.chats[chatID] = trend
+	}
+	trend.scores = append(trend.scores, score.Frustration)
+	trend.messages = append(trend.messages, text)
+	if n := len(trend.scores); n > sentimentWindow {
+		trend.scores = trend.scores[n-sentimentWindow:]
+		trend.messages = trend.messages[n-sentimentWindow:]
+	}
+	if !score.WantsHuman && !rising(trend.scores) {
+		return nil, false
+	}
+	if !trend.alerted.IsZero() && now.Sub(trend.alerted) < sentimentCooldown {
+		return nil, false
+	}
+	trend.alerted = now
+	return append([]int(nil), trend.scores...), true
+}
+
+// rising reports whether the last three scores climb to sentimentAlertLevel
+// without falling back, so one angry message after a calm chat is not enough.
+func rising(scores []int) bool {
+	n := len(scores)
+	if n < 3 {
+		return false
+	}
+	a, b, c := scores[n-3], scores[n-2], scores[n-1]
+	return a <= b && b <= c && c > a && c >= sentimentAlertLevel
+}
+
+// trackSentiment rates the frustration of msg and alerts the operators when
+// the chat has grown increasingly frustrated or asks for a person.
+func (a *App) trackSentiment(ctx context.Context, msg *tele.Message) {
+	text := messagePrompt(msg)
+	if text == "" || strings.HasPrefix(text, "/") {
+		return
+	}
+	score, err := a.rateSentiment(ctx, msg.Chat.ID, text)
+	if err != nil {
+		logFrom(ctx).Warn("rate sentiment failed", "err", err)
+		return
+	}
+	scores, alert := a.sentiment.add(msg.Chat.ID, text, score, time.Now())
+	if !alert {
+		return
+	}
+	logFrom(ctx).Info("frustrated user, alerting the operators", "frustration", score.Frustration, "wants_human", score.WantsHuman)
+	note := sentimentAlert(msg, text, scores, score.WantsHuman)
+	for _, id := range a.announceChats() {
+		if _, err := a.sendWithFallback(&tele.Chat{ID: id}, note, &tele.SendOptions{DisableWebPagePreview: true}); err != nil {
+			logFrom(ctx).Warn("send sentiment alert failed", "chat_id", id, "err", err)
+		}
+	}
+}
+
+func (a *App) rateSentiment(ctx context.Context, chatID int64, text string) (sentimentScore, error) {
+	ctx, cancel := context.WithTimeout(ctx, sentimentTimeout)
+	defer cancel()
+	var earlier []string
+	a.sentiment.mu.Lock()
+	if trend, ok := a.sentiment.chats[chatID]; ok {
+		earlier = append(earlier, trend.messages[max(0, len(trend.messages)-2):]...)
+	}
+	a.sentiment.mu.Unlock()
+
+	var prompt strings.Builder
+	for _, line := range earlier {
+		fmt.Fprintf(&prompt, "Earlier: %s\n", line)
+	}
+	fmt.Fprintf(&prompt, "Latest: %s", text)
+	cfg := &genai.GenerateContentConfig{
+		SystemInstruction: genai.NewContentFromText(sentimentInstruction, genai.Role("system")),
+		Temperature:       genai.Ptr[float32](0),
+		ThinkingConfig:    &genai.ThinkingConfig{ThinkingBudget: genai.Ptr[int32](0)},
+		MaxOutputTokens:   64,
+		ResponseMIMEType:  "application/json",
+		ResponseSchema:    sentimentSchema(),
+	}
+	resp, err := a.generateWithRetry(ctx, sentimentModel, []*genai.Content{genai.NewContentFromText(prompt.String(), genai.RoleUser)}, cfg)
+	if err != nil {
+		return sentimentScore{}, err
+	}
+	a.recordUsage(ctx, chatID, sentimentModel, resp.UsageMetadata)
+	var score sentimentScore
+	if err := json.Unmarshal([]byte(resp.Text()), &score); err != nil {
+		return sentimentScore{}, fmt.Errorf("decode sentiment: %w", err)
+	}
+	score.Frustration = min(max(score.Frustration, 0), 10)
+	return score, nil
+}
+
+// sentimentAlert tells the operators which chat to look at and why. Like the
+// other operator messages it is in English.
+func sentimentAlert(msg *tele.Message, text string, scores []int, wantsHuman bool) string {
+	who := fmt.Sprintf("chat %d", msg.Chat.ID)
+	switch {
+	case msg.Chat.Title != "":
+		who = fmt.Sprintf("%s (chat %d)", msg.Chat.Title, msg.Chat.ID)
+	case msg.Sender != nil && msg.Sender.Username != "":
+		who = fmt.Sprintf("@%s (chat %d)", msg.Sender.Username, msg.Chat.ID)
+	case msg.Sender != nil && displayName(msg.Sender) != "":
+		who = fmt.Sprintf("%s (chat %d)", displayName(msg.Sender), msg.Chat.ID)
+	}
+	trend := make([]string, len(scores))
+	for i, s := range scores {
+		trend[i] = fmt.Sprint(s)
+	}
+	reason := "is getting increasingly frustrated"
+	if wantsHuman {
+		reason = "asks for a person"
+	}
+	quote := []rune(text)
+	if len(quote) > sentimentQuoteRunes {
+		quote = append(quote[:sentimentQuoteRunes-1], '…')
+	}
+	return fmt.Sprintf("The user in %s %s. Frustration over the last messages, 0 to 10: %s.\nLatest message: %q\nConsider taking over the conversation.",
+		who, reason, strings.Join(trend, " → "), string(quote))
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSentimentAlertOnRisingFrustration(t *testing.T) {
+	app, apis := newTestApp(t, Config{SentimentAlerts: true, AdminChatID: -100})
+	var (
+		mu     sync.Mutex
+		scores = []int{2, 5, 8}
+	)
+	apis.answer = func(_ string, body []byte) []any {
+		if !strings.Contains(string(body), "support conversation") {
+			return []any{map[string]any{"text": "Let me check that."}}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		score, _ := json.Marshal(sentimentScore{Frustration: scores[0]})
+		scores = scores[1:]
+		return []any{map[string]any{"text": string(score)}}
+	}
+
+	for i, text := range []string{"My order has not arrived.", "It is still not here!", "This is the third time I ask. Useless!"} {
+		if err := app.processMessage(context.Background(), testMessage(42, text), turnOptions{}); err != nil {
+			t.Fatalf("processMessage: %v", err)
+		}
+		alerts := 0
+		for _, sent := range apis.sentTexts() {
+			if strings.Contains(sent, "increasingly frustrated") {
+				alerts++
+			}
+		}
+		if want := map[bool]int{true: 1, false: 0}[i == 2]; alerts != want {
+			t.Fatalf("after message %d: %d alerts, want %d", i+1, alerts, want)
+		}
+	}
+	texts := apis.sentTexts()
+	if alert := texts[len(texts)-1]; !strings.Contains(alert, "2 → 5 → 8") || !strings.Contains(alert, "chat 42") {
+		t.Errorf("alert = %q, want the trend and the chat", alert)
+	}
+}
+
+func TestSentimentTracker(t *testing.T) {
+	tracker := newSentimentTracker()
+	now := time.Now()
+	for _, s := range []int{9, 3, 8} {
+		if _, alert := tracker.add(1, "", sentimentScore{Frustration: s}, now); alert {
+			t.Errorf("alerted on %d without a rising trend", s)
+		}
+	}
+	if _, alert := tracker.add(1, "", sentimentScore{Frustration: 8}, now); !alert {
+		t.Error("3, 8, 8 did not alert")
+	}
+	if _, alert := tracker.add(1, "", sentimentScore{Frustration: 10, WantsHuman: true}, now.Add(time.Minute)); alert {
+		t.Error("alerted again within the cooldown")
+	}
+	if _, alert := tracker.add(2, "", sentimentScore{Frustration: 1, WantsHuman: true}, now); !alert {
+		t.Error("a request for a person did not alert")
+	}
+}
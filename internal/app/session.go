@@ -1,129 +1,386 @@
-package app
-
-import (
-    "google.golang.org/genai"
-    "sync"
-)
-
-const maxHistoryEntries = 20
-
-type thinkingMode string
-
-const (
-    thinkingModeLow     thinkingMode = "low"
-    thinkingModeMedium  thinkingMode = "medium"
-    thinkingModeHigh    thinkingMode = "high"
-    thinkingModeDynamic thinkingMode = "dynamic"
-)
-
-func defaultThinkingMode() thinkingMode {
-    return thinkingModeMedium
-}
-
-func parseThinkingMode(v string) thinkingMode {
-    switch thinkingMode(v) {
-    case thinkingModeLow, thinkingModeMedium, thinkingModeHigh, thinkingModeDynamic:
-        return thinkingMode(v)
-    default:
-        return defaultThinkingMode()
-    }
-}
-
-func (m thinkingMode) budgetTokens() *int32 {
-    switch m {
-    case thinkingModeLow:
-        v := int32(4096)
-        return &v
-    case thinkingModeMedium:
-        v := int32(16384)
-        return &v
-    case thinkingModeHigh:
-        v := int32(32768)
-        return &v
-    case thinkingModeDynamic:
-        v := int32(-1)
-        return &v
-    default:
-        v := int32(16384)
-        return &v
-    }
-}
-
-func (m thinkingMode) label() string {
-    switch m {
-    case thinkingModeLow:
-        return "Low - 4,096 tokens"
-    case thinkingModeMedium:
-        return "Medium - 16,384 tokens"
-    case thinkingModeHigh:
-        return "High - 32,768 tokens"
-    case thinkingModeDynamic:
-        return "Dynamic reasoning"
-    default:
-        return "Medium - 16,384 tokens"
-    }
-}
-
-type sessionManager struct {
-    mu          sync.RWMutex
-    sessions    map[int64]*sessionState
-    defaultMode thinkingMode
-}
-
-type sessionState struct {
-    mu       sync.Mutex
-    history  []*genai.Content
-    thinking thinkingMode
-}
-
-func newSessionManager(defaultMode thinkingMode) *sessionManager {
-    return &sessionManager{
-        sessions:    make(map[int64]*sessionState),
-        defaultMode: defaultMode,
-    }
-}
-
-func (m *sessionManager) get(chatID int64) *sessionState {
-    m.mu.RLock()
-    session, ok := m.sessions[chatID]
-    m.mu.RUnlock()
-    if ok {
-        return session
-    }
-
-    session = &sessionState{thinking: m.defaultMode}
-    m.mu.Lock()
-    m.sessions[chatID] = session
-    m.mu.Unlock()
-    return session
-}
-
-func (s *sessionState) conversationWith(user *genai.Content) []*genai.Content {
-    convo := make([]*genai.Content, 0, len(s.history)+1)
-    convo = append(convo, s.history...)
-    convo = append(convo, user)
-    return convo
-}
-
-func (s *sessionState) appendTurn(user *genai.Content, model *genai.Content) {
-    if user != nil {
-        s.history = append(s.history, user)
-    }
-    if model != nil {
-        s.history = append(s.history, model)
-    }
-    if len(s.history) > maxHistoryEntries {
-        s.history = append([]*genai.Content{}, s.history[len(s.history)-maxHistoryEntries:]...)
-    }
-}
-
-func (s *sessionState) currentThinking() thinkingMode {
-    if s.thinking == "" {
-        s.thinking = defaultThinkingMode()
-    }
-    return s.thinking
-}
-
-func (s *sessionState) setThinking(mode thinkingMode) {
-    s.thinking = mode
-}
+package app
+
+import (
+    "context"
+    "google.golang.org/genai"
+    tele "gopkg.in/telebot.v4"
+    "log/slog"
+    "slices"
+    "sync"
+    "time"
+)
+
+type thinkingMode string
+
+const (
+    thinkingModeLow     thinkingMode = "low"
+    thinkingModeMedium  thinkingMode = "medium"
+    thinkingModeHigh    thinkingMode = "high"
+    thinkingModeDynamic thinkingMode = "dynamic"
+)
+
+func defaultThinkingMode() thinkingMode {
+    return thinkingModeMedium
+}
+
+func parseThinkingMode(v string) thinkingMode {
+    switch thinkingMode(v) {
+    case thinkingModeLow, thinkingModeMedium, thinkingModeHigh, thinkingModeDynamic:
+        return thinkingMode(v)
+    default:
+        return defaultThinkingMode()
+    }
+}
+
+func (m thinkingMode) budgetTokens() *int32 {
+    switch m {
+    case thinkingModeLow:
+        v := int32(4096)
+        return &v
+    case thinkingModeMedium:
+        v := int32(16384)
+        return &v
+    case thinkingModeHigh:
+        v := int32(32768)
+        return &v
+    case thinkingModeDynamic:
+        v := int32(-1)
+        return &v
+    default:
+        v := int32(16384)
+        return &v
+    }
+}
+
+func (m thinkingMode) label(lang language) string {
+    switch m {
+    case thinkingModeLow:
+        return tr(lang, "thinking_low")
+    case thinkingModeHigh:
+        return tr(lang, "thinking_high")
+    case thinkingModeDynamic:
+        return tr(lang, "thinking_dynamic")
+    default:
+        return tr(lang, "thinking_medium")
+    }
+}
+
+type sessionManager struct {
+    mu          sync.RWMutex
+    sessions    map[sessionKey]*sessionState
+    defaultMode thinkingMode
+    // shared, when set, keeps the sessions in Redis for other instances of
+    // the bot, or in SQLite for the next start of this one; sessions then
+    // caches the ones this instance has used.
+    shared sessionBackend
+}
+
+// sessionKey identifies a conversation: a chat, or one forum topic of it.
+// Thread is zero for the chat itself and for the General topic.
+type sessionKey struct {
+    chatID int64
+    thread int
+}
+
+type sessionState struct {
+    mu      sync.Mutex
+    history []*genai.Content
+    // historyTokens holds the counted size of each history entry, zero until counted.
+    historyTokens []int
+    // historyMeta records when each history entry was added and by which model.
+    historyMeta []historyMeta
+    summary       string
+    thinking      thinkingMode
+    selfCheck     bool
+    persona       string
+    verbosity     verbosity
+    tone          tone
+    overrides     generationOverrides
+    deterministic bool
+    // devMode answers pasted errors and stack traces with a debugging outline.
+    devMode bool
+    // contractReview runs the contract review on documents sent without a caption.
+    contractReview bool
+    // model answers the turns of the chat in place of the configured model.
+    model string
+    // toolsOff names the built-in tools switched off for the chat.
+    toolsOff []string
+    // jsonMode is the JSON schema or shape description replies follow, set
+    // with /json; empty for normal replies.
+    jsonMode string
+    // groupContext describes a group from its description and pinned message.
+    groupContext       string
+    groupContextLoaded bool
+    // language is the language of bot messages chosen with /language; empty
+    // follows each user's Telegram language.
+    language language
+    // lastTurn is the latest answered prompt, which an edit can re-answer.
+    lastTurn lastTurn
+    // checkpoints are the saved points of the history /rollback returns to,
+    // oldest first; checkpointSeq numbers the unnamed ones.
+    checkpoints   []checkpoint
+    checkpointSeq int
+    // thread names the active conversation, empty for the default one; the
+    // others wait in threads until /chat switch brings them back.
+    thread  string
+    threads map[string]*conversationThread
+    // sharedRev is the revision in the shared store this copy matches and
+    // sharedDigest the digest of what was last loaded or written.
+    sharedRev    int64
+    sharedDigest [32]byte
+}
+
+// historyMeta describes a history entry for transcripts; model is empty for
+// the user's entries.
+type historyMeta struct {
+    at    time.Time
+    model string
+}
+
+// lastTurn locates the latest turn in the chat and in the history.
+type lastTurn struct {
+    promptID int
+    replyID  int
+    // prompt and opts are what the Regenerate button runs again.
+    prompt *tele.Message
+    opts   turnOptions
+    // entries is how many history entries at the end the turn added.
+    entries int
+}
+
+// chatPrefs is a copy of the per-chat settings that shape a single request, taken
+// under the session lock so the request can run without holding it.
+type chatPrefs struct {
+    thinking      thinkingMode
+    selfCheck     bool
+    persona       string
+    verbosity     verbosity
+    tone          tone
+    overrides     generationOverrides
+    deterministic bool
+    devMode       bool
+    groupContext  string
+    model         string
+    toolsOff      []string
+    jsonMode      string
+}
+
+func newSessionManager(defaultMode thinkingMode) *sessionManager {
+    return &sessionManager{
+        sessions:    make(map[sessionKey]*sessionState),
+        defaultMode: defaultMode,
+    }
+}
+
+// get returns the session of a chat, which also holds the chat-wide settings
+// such as the language.
+func (m *sessionManager) get(chatID int64) *sessionState {
+    return m.topic(chatID, 0)
+}
+
+// topic returns the session of a forum topic, thread zero being the chat's
+// own. A new topic session starts with the settings of the chat session but
+// none of its history.
+func (m *sessionManager) topic(chatID int64, thread int) *sessionState {
+    key := sessionKey{chatID: chatID, thread: thread}
+    m.mu.RLock()
+    session, ok := m.sessions[key]
+    m.mu.RUnlock()
+    if ok {
+        return session
+    }
+
+    session = &sessionState{thinking: m.defaultMode}
+    loaded := m.shared != nil && m.loadShared(key, session)
+    if !loaded && thread != 0 {
+        session.inheritSettings(m.get(chatID))
+    }
+    m.mu.Lock()
+    defer m.mu.Unlock()
+    if existing, ok := m.sessions[key]; ok {
+        return existing
+    }
+    m.sessions[key] = session
+    return session
+}
+
+// inheritSettings copies the settings of parent into a new session.
+func (s *sessionState) inheritSettings(parent *sessionState) {
+    parent.mu.Lock()
+    defer parent.mu.Unlock()
+    s.thinking = parent.thinking
+    s.selfCheck = parent.selfCheck
+    s.persona = parent.persona
+    s.verbosity = parent.verbosity
+    s.tone = parent.tone
+    s.overrides = parent.overrides
+    s.deterministic = parent.deterministic
+    s.devMode = parent.devMode
+    s.contractReview = parent.contractReview
+    s.model = parent.model
+    s.toolsOff = slices.Clone(parent.toolsOff)
+    s.jsonMode = parent.jsonMode
+    s.language = parent.language
+}
+
+// ofChat returns the sessions of a chat and its topics.
+func (m *sessionManager) ofChat(chatID int64) []*sessionState {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    var sessions []*sessionState
+    for key, session := range m.sessions {
+        if key.chatID == chatID {
+            sessions = append(sessions, session)
+        }
+    }
+    return sessions
+}
+
+// remove forgets the sessions of a chat and its topics.
+func (m *sessionManager) remove(chatID int64) {
+    m.mu.Lock()
+    for key := range m.sessions {
+        if key.chatID == chatID {
+            delete(m.sessions, key)
+        }
+    }
+    m.mu.Unlock()
+    if m.shared != nil {
+        ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+        defer cancel()
+        if err := m.shared.deleteSessions(ctx, chatID); err != nil {
+            slog.Warn("delete shared sessions failed", "chat_id", chatID, "err", err)
+        }
+    }
+}
+
+// migrate moves the sessions of chat from to chat to, keeping any session to
+// already has.
+func (m *sessionManager) migrate(from, to int64) {
+    m.mu.Lock()
+    for key, session := range m.sessions {
+        if key.chatID != from {
+            continue
+        }
+        delete(m.sessions, key)
+        moved := sessionKey{chatID: to, thread: key.thread}
+        if _, ok := m.sessions[moved]; !ok {
+            m.sessions[moved] = session
+        }
+    }
+    m.mu.Unlock()
+    if m.shared != nil {
+        ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+        defer cancel()
+        if err := m.shared.migrateSessions(ctx, from, to); err != nil {
+            slog.Warn("migrate shared sessions failed", "from", from, "to", to, "err", err)
+        }
+    }
+}
+
+// count returns how many chats and forum topics have a session on this
+// instance.
+func (m *sessionManager) count() int {
+    m.mu.RLock()
+    defer m.mu.RUnlock()
+    return len(m.sessions)
+}
+
+func (s *sessionState) conversationWith(user *genai.Content) []*genai.Content {
+    return s.conversation(s.history, user)
+}
+
+// conversationReplacingLast is conversationWith for a new version of the last
+// prompt: the turn it replaces is left out.
+func (s *sessionState) conversationReplacingLast(user *genai.Content) []*genai.Content {
+    return s.conversation(s.history[:len(s.history)-s.lastTurn.entries], user)
+}
+
+func (s *sessionState) conversation(history []*genai.Content, user *genai.Content) []*genai.Content {
+    convo := make([]*genai.Content, 0, len(history)+2)
+    if s.summary != "" {
+        convo = append(convo, genai.NewContentFromText(summaryPrefix+s.summary, genai.RoleUser))
+    }
+    convo = append(convo, history...)
+    convo = append(convo, user)
+    return convo
+}
+
+// appendTurn adds a prompt and its answer, which modelName produced, to the
+// history.
+func (s *sessionState) appendTurn(user *genai.Content, model *genai.Content, modelName string) {
+    s.lastTurn = lastTurn{}
+    now := time.Now()
+    if user != nil {
+        s.history = append(s.history, user)
+        s.historyTokens = append(s.historyTokens, 0)
+        s.historyMeta = append(s.historyMeta, historyMeta{at: now})
+        s.lastTurn.entries++
+    }
+    if model != nil {
+        s.history = append(s.history, model)
+        s.historyTokens = append(s.historyTokens, 0)
+        s.historyMeta = append(s.historyMeta, historyMeta{at: now, model: modelName})
+        s.lastTurn.entries++
+    }
+}
+
+// dropLastTurn removes the entries the latest turn added to the history.
+func (s *sessionState) dropLastTurn() {
+    keep := len(s.history) - s.lastTurn.entries
+    s.history = s.history[:keep]
+    s.historyTokens = s.historyTokens[:keep]
+    s.historyMeta = s.historyMeta[:keep]
+    s.lastTurn = lastTurn{}
+}
+
+// dropOldest removes the first n history entries.
+func (s *sessionState) dropOldest(n int) {
+    s.history = append([]*genai.Content{}, s.history[n:]...)
+    s.historyTokens = append([]int{}, s.historyTokens[n:]...)
+    s.historyMeta = append([]historyMeta{}, s.historyMeta[n:]...)
+    // A turn folded into the summary can no longer be replaced in full.
+    s.lastTurn.entries = min(s.lastTurn.entries, len(s.history))
+}
+
+func (s *sessionState) currentThinking() thinkingMode {
+    if s.thinking == "" {
+        s.thinking = defaultThinkingMode()
+    }
+    return s.thinking
+}
+
+func (s *sessionState) setThinking(mode thinkingMode) {
+    s.thinking = mode
+}
+
+func (s *sessionState) currentVerbosity() verbosity {
+    if s.verbosity == "" {
+        return verbosityNormal
+    }
+    return s.verbosity
+}
+
+func (s *sessionState) currentTone() tone {
+    if s.tone == "" {
+        return toneNeutral
+    }
+    return s.tone
+}
+
+func (s *sessionState) prefs() chatPrefs {
+    return chatPrefs{
+        thinking:      s.currentThinking(),
+        selfCheck:     s.selfCheck,
+        persona:       s.persona,
+        verbosity:     s.currentVerbosity(),
+        tone:          s.currentTone(),
+        overrides:     s.overrides,
+        deterministic: s.deterministic,
+        devMode:       s.devMode,
+        groupContext:  s.groupContext,
+        model:         s.model,
+        toolsOff:      slices.Clone(s.toolsOff),
+        jsonMode:      s.jsonMode,
+    }
+}
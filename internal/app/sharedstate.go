@@ -0,0 +1,324 @@
+package app
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+// sessionSnapshot is the part of a session other instances need, as stored in
+// Redis or SQLite. The latest turn and the group context stay with the instance that
+// made them: an edit or regenerate of a turn answered elsewhere starts anew,
+// and the group context is loaded again on the next message.
+type sessionSnapshot struct {
+	Conversation  conversationSnapshot            `json:"conversation"`
+	Thinking      thinkingMode                    `json:"thinking,omitempty"`
+	SelfCheck     bool                            `json:"self_check,omitempty"`
+	Persona       string                          `json:"persona,omitempty"`
+	Verbosity     verbosity                       `json:"verbosity,omitempty"`
+	Tone          tone                            `json:"tone,omitempty"`
+	Overrides     overridesSnapshot               `json:"overrides"`
+	Deterministic bool                            `json:"deterministic,omitempty"`
+	DevMode       bool                            `json:"dev_mode,omitempty"`
+	Contract      bool                            `json:"contract_review,omitempty"`
+	Model         string                          `json:"model,omitempty"`
+	ToolsOff      []string                        `json:"tools_off,omitempty"`
+	JSONMode      string                          `json:"json_mode,omitempty"`
+	Language      language                        `json:"language,omitempty"`
+	Checkpoints   []checkpointSnapshot            `json:"checkpoints,omitempty"`
+	CheckpointSeq int                             `json:"checkpoint_seq,omitempty"`
+	Thread        string                          `json:"thread,omitempty"`
+	Threads       map[string]conversationSnapshot `json:"threads,omitempty"`
+}
+
+// conversationSnapshot is a history with its summary, as kept by the active
+// conversation, a checkpoint or a parked thread.
+type conversationSnapshot struct {
+	Summary       string               `json:"summary,omitempty"`
+	History       []*genai.Content     `json:"history,omitempty"`
+	Tokens        []int                `json:"tokens,omitempty"`
+	Meta          []historyMetaJSON    `json:"meta,omitempty"`
+	Checkpoints   []checkpointSnapshot `json:"checkpoints,omitempty"`
+	CheckpointSeq int                  `json:"checkpoint_seq,omitempty"`
+	Parked        time.Time            `json:"parked,omitzero"`
+}
+
+type historyMetaJSON struct {
+	At    time.Time `json:"at,omitzero"`
+	Model string    `json:"model,omitempty"`
+}
+
+type checkpointSnapshot struct {
+	Name         string               `json:"name"`
+	At           time.Time            `json:"at"`
+	Conversation conversationSnapshot `json:"conversation"`
+}
+
+type overridesSnapshot struct {
+	StopSequences    []string `json:"stop_sequences,omitempty"`
+	PresencePenalty  *float32 `json:"presence_penalty,omitempty"`
+	FrequencyPenalty *float32 `json:"frequency_penalty,omitempty"`
+	Seed             *int32   `json:"seed,omitempty"`
+}
+
+func snapshotConversation(summary string, history []*genai.Content, tokens []int, meta []historyMeta) conversationSnapshot {
+	c := conversationSnapshot{Summary: summary, History: slices.Clone(history), Tokens: slices.Clone(tokens)}
+	for _, m := range meta {
+		c.Meta = append(c.Meta, historyMetaJSON{At: m.at, Model: m.model})
+	}
+	return c
+}
+
+func (c conversationSnapshot) meta() []historyMeta {
+	var meta []historyMeta
+	for _, m := range c.Meta {
+		meta = append(meta, historyMeta{at: m.At, model: m.Model})
+	}
+	return meta
+}
+
+func snapshotCheckpoints(cps []checkpoint) []checkpointSnapshot {
+	var out []checkpointSnapshot
+	for _, cp := range cps {
+		out = append(out, checkpointSnapshot{
+			Name:         cp.name,
+			At:           cp.at,
+			Conversation: snapshotConversation(cp.summary, cp.history, cp.historyTokens, cp.historyMeta),
+		})
+	}
+	return out
+}
+
+func restoreCheckpoints(snaps []checkpointSnapshot) []checkpoint {
+	var out []checkpoint
+	for _, cp := range snaps {
+		out = append(out, checkpoint{
+			name:          cp.Name,
+			at:            cp.At,
+			summary:       cp.Conversation.Summary,
+			history:       cp.Conversation.History,
+			historyTokens: cp.Conversation.Tokens,
+			historyMeta:   cp.Conversation.meta(),
+		})
+	}
+	return out
+}
+
+// snapshot copies the shared part of the session; the caller holds s.mu.
+func (s *sessionState) snapshot() sessionSnapshot {
+	snap := sessionSnapshot{
+		Conversation:  snapshotConversation(s.summary, s.history, s.historyTokens, s.historyMeta),
+		Thinking:      s.thinking,
+		SelfCheck:     s.selfCheck,
+		Persona:       s.persona,
+		Verbosity:     s.verbosity,
+		Tone:          s.tone,
+		Deterministic: s.deterministic,
+		DevMode:       s.devMode,
+		Contract:      s.contractReview,
+		Model:         s.model,
+		ToolsOff:      s.toolsOff,
+		JSONMode:      s.jsonMode,
+		Language:      s.language,
+		Checkpoints:   snapshotCheckpoints(s.checkpoints),
+		CheckpointSeq: s.checkpointSeq,
+		Thread:        s.thread,
+		Overrides: overridesSnapshot{
+			StopSequences:    s.overrides.stopSequences,
+			PresencePenalty:  s.overrides.presencePenalty,
+			FrequencyPenalty: s.overrides.frequencyPenalty,
+			Seed:             s.overrides.seed,
+		},
+	}
+	for name, t := range s.threads {
+		if snap.Threads == nil {
+			snap.Threads = make(map[string]conversationSnapshot)
+		}
+		c := snapshotConversation(t.summary, t.history, t.historyTokens, t.historyMeta)
+		c.Checkpoints = snapshotCheckpoints(t.checkpoints)
+		c.CheckpointSeq = t.checkpointSeq
+		c.Parked = t.parked
+		snap.Threads[name] = c
+	}
+	return snap
+}
+
+// restoreSnapshot replaces the shared part of the session with snap and
+// forgets the latest turn; the caller holds s.mu.
+func (s *sessionState) restoreSnapshot(snap sessionSnapshot) {
+	s.summary = snap.Conversation.Summary
+	s.history = snap.Conversation.History
+	s.historyTokens = snap.Conversation.Tokens
+	s.historyMeta = snap.Conversation.meta()
+	s.thinking = parseThinkingMode(string(snap.Thinking))
+	s.selfCheck = snap.SelfCheck
+	s.persona = snap.Persona
+	s.verbosity = snap.Verbosity
+	s.tone = snap.Tone
+	s.overrides = generationOverrides{
+		stopSequences:    snap.Overrides.StopSequences,
+		presencePenalty:  snap.Overrides.PresencePenalty,
+		frequencyPenalty: snap.Overrides.FrequencyPenalty,
+		seed:             snap.Overrides.Seed,
+	}
+	s.deterministic = snap.Deterministic
+	s.devMode = snap.DevMode
+	s.contractReview = snap.Contract
+	s.model = snap.Model
+	s.toolsOff = snap.ToolsOff
+	s.jsonMode = snap.JSONMode
+	s.language = snap.Language
+	s.checkpoints = restoreCheckpoints(snap.Checkpoints)
+	s.checkpointSeq = snap.CheckpointSeq
+	s.thread = snap.Thread
+	s.threads = nil
+	for name, c := range snap.Threads {
+		if s.threads == nil {
+			s.threads = make(map[string]*conversationThread)
+		}
+		s.threads[name] = &conversationThread{
+			summary:       c.Summary,
+			history:       c.History,
+			historyTokens: c.Tokens,
+			historyMeta:   c.meta(),
+			checkpoints:   restoreCheckpoints(c.Checkpoints),
+			checkpointSeq: c.CheckpointSeq,
+			parked:        c.Parked,
+		}
+	}
+	s.lastTurn = lastTurn{}
+}
+
+// fetch reads a session from the shared store with its revision and the
+// digest of its encoding.
+func (m *sessionManager) fetch(key sessionKey) (sessionSnapshot, int64, [32]byte, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	var snap sessionSnapshot
+	raw, rev, err := m.shared.loadSession(ctx, key)
+	if err != nil {
+		slog.Warn("load shared session failed", "chat_id", key.chatID, "thread", key.thread, "err", err)
+		return snap, 0, [32]byte{}, false
+	}
+	if raw == nil {
+		return snap, 0, [32]byte{}, false
+	}
+	if err := json.Unmarshal(raw, &snap); err != nil {
+		slog.Warn("decode shared session failed", "chat_id", key.chatID, "thread", key.thread, "err", err)
+		return snap, 0, [32]byte{}, false
+	}
+	return snap, rev, sha256.Sum256(raw), true
+}
+
+// loadShared fills a new session from the shared store and reports whether
+// the store had it.
+func (m *sessionManager) loadShared(key sessionKey, session *sessionState) bool {
+	snap, rev, digest, ok := m.fetch(key)
+	if !ok {
+		return false
+	}
+	session.restoreSnapshot(snap)
+	session.sharedRev, session.sharedDigest = rev, digest
+	return true
+}
+
+// cached returns the sessions of a chat this instance holds, with their keys.
+func (m *sessionManager) cached(chatID int64) map[sessionKey]*sessionState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	sessions := make(map[sessionKey]*sessionState)
+	for key, session := range m.sessions {
+		if key.chatID == chatID {
+			sessions[key] = session
+		}
+	}
+	return sessions
+}
+
+// refresh brings the sessions of a chat this instance holds up to date with
+// the shared store, where another instance may have changed them.
+func (m *sessionManager) refresh(chatID int64) {
+	if m.shared == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	for key, session := range m.cached(chatID) {
+		rev, err := m.shared.sessionRevision(ctx, key)
+		if err != nil {
+			slog.Warn("check shared session failed", "chat_id", chatID, "thread", key.thread, "err", err)
+			return
+		}
+		session.mu.Lock()
+		stale := rev > session.sharedRev
+		session.mu.Unlock()
+		if !stale {
+			continue
+		}
+		snap, rev, digest, ok := m.fetch(key)
+		if !ok {
+			continue
+		}
+		session.mu.Lock()
+		session.restoreSnapshot(snap)
+		session.sharedRev, session.sharedDigest = rev, digest
+		session.mu.Unlock()
+	}
+}
+
+// persist writes the sessions of a chat this instance holds to the shared
+// store when they changed since they were loaded or last written.
+func (m *sessionManager) persist(chatID int64) {
+	if m.shared == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	for key, session := range m.cached(chatID) {
+		session.mu.Lock()
+		raw, err := json.Marshal(session.snapshot())
+		session.mu.Unlock()
+		if err != nil {
+			slog.Warn("encode shared session failed", "chat_id", chatID, "thread", key.thread, "err", err)
+			continue
+		}
+		digest := sha256.Sum256(raw)
+		session.mu.Lock()
+		unchanged := digest == session.sharedDigest
+		session.mu.Unlock()
+		if unchanged {
+			continue
+		}
+		rev, err := m.shared.saveSession(ctx, key, raw)
+		if err != nil {
+			slog.Warn("save shared session failed", "chat_id", chatID, "thread", key.thread, "err", err)
+			return
+		}
+		session.mu.Lock()
+		session.sharedRev, session.sharedDigest = rev, digest
+		session.mu.Unlock()
+	}
+}
+
+// sharedStateMiddleware refreshes the sessions of the chat of an update from
+// the shared store before the handler runs and writes back what it changed.
+// Turns that run on the chat queue are written back when they finish.
+func (a *App) sharedStateMiddleware(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		chat := c.Chat()
+		if a.sessions.shared == nil || chat == nil {
+			return next(c)
+		}
+		if !a.queue.busy(chat.ID) {
+			a.sessions.refresh(chat.ID)
+		}
+		defer a.sessions.persist(chat.ID)
+		return next(c)
+	}
+}
@@ -0,0 +1,120 @@
+package app
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	spokenLanguagesPrefKey = "spoken_languages"
+	maxSpokenLanguages     = 5
+	maxLanguageNameLength  = 40
+)
+
+// errTooManyLanguages is returned by parseSpokenLanguages for a list of more
+// than maxSpokenLanguages languages.
+var errTooManyLanguages = errors.New("too many spoken languages")
+
+// languageNameError is a list entry too long to be a language name.
+type languageNameError string
+
+func (e languageNameError) Error() string {
+	return "not a language name: " + string(e)
+}
+
+// parseSpokenLanguages splits a comma separated list of languages, dropping duplicates.
+func parseSpokenLanguages(payload string) ([]string, error) {
+	fields := strings.FieldsFunc(payload, func(r rune) bool { return r == ',' || r == ';' || r == '\n' })
+	var langs []string
+	seen := make(map[string]bool)
+	for _, f := range fields {
+		lang := strings.TrimSpace(f)
+		if lang == "" || seen[strings.ToLower(lang)] {
+			continue
+		}
+		if utf8.RuneCountInString(lang) > maxLanguageNameLength {
+			return nil, languageNameError(lang)
+		}
+		seen[strings.ToLower(lang)] = true
+		langs = append(langs, lang)
+	}
+	if len(langs) > maxSpokenLanguages {
+		return nil, errTooManyLanguages
+	}
+	return langs, nil
+}
+
+func hasSpeech(msg *tele.Message) bool {
+	if msg == nil {
+		return false
+	}
+	return msg.Voice != nil || msg.Audio != nil || msg.VideoNote != nil || msg.Video != nil
+}
+
+// speechInstruction returns transcription guidance in lang for the sender's
+// spoken languages when the message, or the one it replies to, carries audio.
+// The languages are per-user preferences: without PrefsEncryptionKey none can
+// be saved, /languages says so and this returns nothing.
+func (a *App) speechInstruction(lang language, msg *tele.Message) string {
+	if a.prefs == nil || msg.Sender == nil || (!hasSpeech(msg) && !hasSpeech(msg.ReplyTo)) {
+		return ""
+	}
+	var langs []string
+	if ok, err := a.prefs.get(msg.Sender.ID, spokenLanguagesPrefKey, &langs); err != nil {
+		a.chatLog(msg.Chat.ID).Warn("read spoken languages failed", "chat_id", msg.Chat.ID, "err", err)
+		return ""
+	} else if !ok || len(langs) == 0 {
+		return ""
+	}
+	return tr(lang, "speech_hint", strings.Join(langs, ", "))
+}
+
+func (a *App) handleLanguages(c tele.Context) error {
+	msg := c.Message()
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	if a.prefs == nil {
+		_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), tr(lang, "prefs_off"), &tele.SendOptions{DisableWebPagePreview: true})
+		return err
+	}
+	if msg.Sender == nil {
+		return nil
+	}
+
+	payload := strings.TrimSpace(msg.Payload)
+	var body string
+	switch {
+	case payload == "":
+		var langs []string
+		if ok, err := a.prefs.get(msg.Sender.ID, spokenLanguagesPrefKey, &langs); err == nil && ok && len(langs) > 0 {
+			body = tr(lang, "speech_current", strings.Join(langs, ", "))
+		} else {
+			body = tr(lang, "speech_none")
+		}
+	case strings.EqualFold(payload, "reset"):
+		if err := a.prefs.delete(msg.Sender.ID, spokenLanguagesPrefKey); err != nil {
+			return err
+		}
+		body = tr(lang, "speech_cleared")
+	default:
+		langs, err := parseSpokenLanguages(payload)
+		var badName languageNameError
+		switch {
+		case errors.As(err, &badName):
+			body = tr(lang, "speech_invalid", tr(lang, "speech_bad_name", string(badName)))
+		case err != nil:
+			body = tr(lang, "speech_invalid", tr(lang, "speech_too_many", maxSpokenLanguages))
+		}
+		if err != nil {
+			break
+		}
+		if err := a.prefs.set(msg.Sender.ID, spokenLanguagesPrefKey, langs); err != nil {
+			return err
+		}
+		body = tr(lang, "speech_set", strings.Join(langs, ", "))
+	}
+	_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), body, &tele.SendOptions{DisableWebPagePreview: true})
+	return err
+}
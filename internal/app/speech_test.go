@@ -0,0 +1,105 @@
+package app
+
+import (
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestParseSpokenLanguages(t *testing.T) {
+	tests := []struct {
+		payload string
+		want    []string
+		err     error
+	}{
+		{"English, Ukrainian", []string{"English", "Ukrainian"}, nil},
+		{"English; english,\nDeutsch, ", []string{"English", "Deutsch"}, nil},
+		{" , ;", nil, nil},
+		{"a, b, c, d, e, f", nil, errTooManyLanguages},
+		{"English, " + strings.Repeat("x", maxLanguageNameLength+1), nil, languageNameError(strings.Repeat("x", maxLanguageNameLength+1))},
+	}
+	for _, tt := range tests {
+		got, err := parseSpokenLanguages(tt.payload)
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") || err != tt.err {
+			t.Errorf("parseSpokenLanguages(%q) = %q, %v; want %q, %v", tt.payload, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestSpeechInstruction(t *testing.T) {
+	app, _ := newTestApp(t, Config{PrefsEncryptionKey: "test-key"})
+	if err := app.prefs.set(7, spokenLanguagesPrefKey, []string{"English", "Українська"}); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	chat := &tele.Chat{ID: 42, Type: tele.ChatPrivate}
+	voice := &tele.Voice{File: tele.File{FileID: "voice1"}}
+	tests := []struct {
+		name string
+		lang language
+		msg  *tele.Message
+		want string
+	}{
+		{"voice note", defaultLanguage, &tele.Message{Chat: chat, Sender: &tele.User{ID: 7}, Voice: voice}, "any of these languages, possibly mixed within one recording: English, Українська."},
+		{"reply to a voice note", defaultLanguage, &tele.Message{Chat: chat, Sender: &tele.User{ID: 7}, Text: "What did she say?", ReplyTo: &tele.Message{Chat: chat, Voice: voice}}, "English, Українська"},
+		{"in the chat language", "de", &tele.Message{Chat: chat, Sender: &tele.User{ID: 7}, Voice: voice}, "auch gemischt in einer Aufnahme: English, Українська."},
+		{"text only", defaultLanguage, &tele.Message{Chat: chat, Sender: &tele.User{ID: 7}, Text: "Hi"}, ""},
+		{"sender without languages", defaultLanguage, &tele.Message{Chat: chat, Sender: &tele.User{ID: 8}, Voice: voice}, ""},
+	}
+	for _, tt := range tests {
+		got := app.speechInstruction(tt.lang, tt.msg)
+		if tt.want == "" && got != "" || !strings.Contains(got, tt.want) {
+			t.Errorf("%s: speechInstruction = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	plain, _ := newTestApp(t, Config{})
+	if got := plain.speechInstruction(defaultLanguage, &tele.Message{Chat: chat, Sender: &tele.User{ID: 7}, Voice: voice}); got != "" {
+		t.Errorf("without preferences: speechInstruction = %q", got)
+	}
+}
+
+func TestLanguagesCommand(t *testing.T) {
+	app, apis := newTestApp(t, Config{PrefsEncryptionKey: "test-key"})
+	languages := func(payload string) string {
+		t.Helper()
+		msg := &tele.Message{ID: 5, Chat: &tele.Chat{ID: 42, Type: tele.ChatPrivate}, Sender: &tele.User{ID: 7}, Text: "/languages " + payload, Payload: payload}
+		if err := app.handleLanguages(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("handleLanguages(%q): %v", payload, err)
+		}
+		texts := apis.sentTexts()
+		return strings.ReplaceAll(texts[len(texts)-1], `\`, "")
+	}
+
+	if got := languages(""); !strings.Contains(got, "No spoken languages set") {
+		t.Errorf("/languages answered %q", got)
+	}
+	if got := languages("English, Ukrainian, english"); !strings.Contains(got, "Spoken languages set to English, Ukrainian.") {
+		t.Errorf("/languages English, Ukrainian answered %q", got)
+	}
+	var saved []string
+	if ok, err := app.prefs.get(7, spokenLanguagesPrefKey, &saved); !ok || err != nil || len(saved) != 2 {
+		t.Errorf("saved %q, %v, %v", saved, ok, err)
+	}
+	if got := languages("a, b, c, d, e, f"); !strings.Contains(got, "at most 5 languages") {
+		t.Errorf("six languages answered %q", got)
+	}
+	if got := languages(""); !strings.Contains(got, "Your spoken languages: English, Ukrainian.") {
+		t.Errorf("/languages after a refused change answered %q", got)
+	}
+	if got := languages("reset"); !strings.Contains(got, "Spoken languages cleared.") {
+		t.Errorf("/languages reset answered %q", got)
+	}
+	if ok, _ := app.prefs.get(7, spokenLanguagesPrefKey, &saved); ok {
+		t.Error("reset kept the languages")
+	}
+
+	plain, plainAPIs := newTestApp(t, Config{})
+	msg := &tele.Message{ID: 5, Chat: &tele.Chat{ID: 42, Type: tele.ChatPrivate}, Sender: &tele.User{ID: 7}, Text: "/languages English", Payload: "English"}
+	if err := plain.handleLanguages(plain.bot.NewContext(tele.Update{Message: msg})); err != nil {
+		t.Fatalf("handleLanguages: %v", err)
+	}
+	if texts := plainAPIs.sentTexts(); !containsText(texts, "not enabled") {
+		t.Errorf("without preferences /languages answered %q", texts)
+	}
+}
@@ -0,0 +1,472 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// defaultSQLiteFile is the database in the data directory that keeps the
+// sessions when Config.SQLitePath is empty.
+const defaultSQLiteFile = "eteon.db"
+
+// sessionBackend keeps the sessions outside the process: Redis shares them
+// between instances, SQLite keeps them across restarts of a single one.
+// Revisions grow with every save, so an instance can tell that its copy is
+// stale.
+type sessionBackend interface {
+	// loadSession returns the stored session and its revision, or nil data
+	// when there is none.
+	loadSession(ctx context.Context, key sessionKey) ([]byte, int64, error)
+	// sessionRevision returns the revision of the stored session, zero when
+	// there is none.
+	sessionRevision(ctx context.Context, key sessionKey) (int64, error)
+	// saveSession stores data as the session and returns its new revision.
+	saveSession(ctx context.Context, key sessionKey, data []byte) (int64, error)
+	// deleteSessions drops the sessions of a chat and its topics.
+	deleteSessions(ctx context.Context, chatID int64) error
+	// migrateSessions moves the sessions of chat from to chat to, keeping any
+	// session to already has.
+	migrateSessions(ctx context.Context, from, to int64) error
+}
+
+// openSQLite keeps the sessions and reply artifacts in the SQLite database at
+// path, the default one when empty. The default is best effort: a binary
+// built without cgo, or a read-only data directory, keeps them in memory as
+// before.
+func (a *App) openSQLite(ctx context.Context, path string) error {
+	path = strings.TrimSpace(path)
+	if strings.EqualFold(path, "none") {
+		return nil
+	}
+	explicit := path != ""
+	if !explicit {
+		path = filepath.Join(a.dataDir, defaultSQLiteFile)
+	}
+	state, err := openSQLiteState(ctx, path)
+	if err != nil {
+		if explicit {
+			return fmt.Errorf("open sqlite: %w", err)
+		}
+		slog.Warn("keeping sessions in memory only", "path", path, "err", err)
+		return nil
+	}
+	if err := a.usage.load(ctx, state); err != nil {
+		state.Close()
+		return fmt.Errorf("load usage from %s: %w", path, err)
+	}
+	a.sqlite = state
+	a.sessions.shared = state
+	a.artifacts.shared = state
+	a.noteStartup("Keeping sessions and replies in SQLite at %s", path)
+	return nil
+}
+
+// chatSettingsBackend keeps the chat settings every instance has to agree
+// on, such as privacy mode, next to the sessions.
+type chatSettingsBackend interface {
+	privacyBackend
+	replyToBackend
+}
+
+// settingsBackend returns the Redis or SQLite state that keeps the sessions,
+// or nil when they live in memory and the settings in files.
+func (a *App) settingsBackend() chatSettingsBackend {
+	switch {
+	case a.shared != nil:
+		return a.shared
+	case a.sqlite != nil:
+		return a.sqlite
+	}
+	return nil
+}
+
+// sqliteState keeps the sessions, reply artifacts, usage, privacy and reply
+// modes in an SQLite file, so history, chat settings, the buttons under
+// replies and the budgets survive restarts without any other infrastructure.
+type sqliteState struct {
+	db *sql.DB
+}
+
+// openSQLiteState opens or creates the database at path.
+func openSQLiteState(ctx context.Context, path string) (*sqliteState, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", "file:"+path+"?_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate")
+	if err != nil {
+		return nil, err
+	}
+	// One connection serialises the writes, which SQLite does anyway.
+	db.SetMaxOpenConns(1)
+	_, err = db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS sessions (
+		chat_id INTEGER NOT NULL,
+		thread  INTEGER NOT NULL,
+		data    BLOB    NOT NULL,
+		rev     INTEGER NOT NULL,
+		PRIMARY KEY (chat_id, thread)
+	);
+	CREATE TABLE IF NOT EXISTS artifacts (
+		id      TEXT    PRIMARY KEY,
+		chat_id INTEGER NOT NULL,
+		data    BLOB    NOT NULL,
+		expires INTEGER NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS artifacts_chat ON artifacts (chat_id);
+	CREATE TABLE IF NOT EXISTS usage (
+		ledger     TEXT    NOT NULL,
+		period     TEXT    NOT NULL,
+		requests   INTEGER NOT NULL,
+		prompt     INTEGER NOT NULL,
+		candidates INTEGER NOT NULL,
+		thoughts   INTEGER NOT NULL,
+		cost_usd   REAL    NOT NULL,
+		PRIMARY KEY (ledger, period)
+	);
+	CREATE TABLE IF NOT EXISTS privacy (
+		chat_id INTEGER PRIMARY KEY,
+		since   INTEGER NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS reply_to (
+		chat_id INTEGER PRIMARY KEY,
+		enabled INTEGER NOT NULL
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create tables in %s: %w", path, err)
+	}
+	return &sqliteState{db: db}, nil
+}
+
+func (s *sqliteState) Close() error {
+	return s.db.Close()
+}
+
+func (s *sqliteState) loadSession(ctx context.Context, key sessionKey) ([]byte, int64, error) {
+	var (
+		data []byte
+		rev  int64
+	)
+	err := s.db.QueryRowContext(ctx, `SELECT data, rev FROM sessions WHERE chat_id = ? AND thread = ?`, key.chatID, key.thread).Scan(&data, &rev)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, 0, nil
+	}
+	return data, rev, err
+}
+
+func (s *sqliteState) sessionRevision(ctx context.Context, key sessionKey) (int64, error) {
+	var rev int64
+	err := s.db.QueryRowContext(ctx, `SELECT rev FROM sessions WHERE chat_id = ? AND thread = ?`, key.chatID, key.thread).Scan(&rev)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	return rev, err
+}
+
+func (s *sqliteState) saveSession(ctx context.Context, key sessionKey, data []byte) (int64, error) {
+	var rev int64
+	err := s.db.QueryRowContext(ctx, `INSERT INTO sessions (chat_id, thread, data, rev) VALUES (?, ?, ?, 1)
+		ON CONFLICT (chat_id, thread) DO UPDATE SET data = excluded.data, rev = rev + 1
+		RETURNING rev`, key.chatID, key.thread, data).Scan(&rev)
+	return rev, err
+}
+
+func (s *sqliteState) deleteSessions(ctx context.Context, chatID int64) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE chat_id = ?`, chatID)
+	return err
+}
+
+func (s *sqliteState) migrateSessions(ctx context.Context, from, to int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO sessions (chat_id, thread, data, rev)
+		SELECT ?, thread, data, rev FROM sessions WHERE chat_id = ?`, to, from); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM sessions WHERE chat_id = ?`, from); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// saveArtifact stores art under id until ttl passes, dropping the entries
+// that expired on the way.
+func (s *sqliteState) saveArtifact(ctx context.Context, id string, art *responseArtifacts, ttl time.Duration) error {
+	raw, err := json.Marshal(art)
+	if err != nil {
+		return err
+	}
+	var expires int64
+	if ttl > 0 {
+		expires = art.CreatedAt.Add(ttl).Unix()
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM artifacts WHERE expires > 0 AND expires <= ?`, time.Now().Unix()); err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `INSERT INTO artifacts (id, chat_id, data, expires) VALUES (?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET chat_id = excluded.chat_id, data = excluded.data, expires = excluded.expires`,
+		id, art.ChatID, raw, expires)
+	return err
+}
+
+func (s *sqliteState) loadArtifact(ctx context.Context, id string) (*responseArtifacts, error) {
+	var raw []byte
+	err := s.db.QueryRowContext(ctx, `SELECT data FROM artifacts WHERE id = ? AND (expires = 0 OR expires > ?)`, id, time.Now().Unix()).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	art := &responseArtifacts{}
+	if err := json.Unmarshal(raw, art); err != nil {
+		return nil, fmt.Errorf("decode artifact %s: %w", id, err)
+	}
+	return art, nil
+}
+
+func (s *sqliteState) purgeArtifacts(ctx context.Context, chatID int64) (int, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM artifacts WHERE chat_id = ?`, chatID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// migrateArtifacts moves the artifacts of chat from to chat to, keeping
+// their expiry.
+func (s *sqliteState) migrateArtifacts(ctx context.Context, from, to int64, _ time.Duration) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	rows, err := tx.QueryContext(ctx, `SELECT id, data FROM artifacts WHERE chat_id = ?`, from)
+	if err != nil {
+		return err
+	}
+	moved := make(map[string][]byte)
+	for rows.Next() {
+		var (
+			id  string
+			raw []byte
+		)
+		if err := rows.Scan(&id, &raw); err != nil {
+			rows.Close()
+			return err
+		}
+		art := &responseArtifacts{}
+		if err := json.Unmarshal(raw, art); err != nil {
+			rows.Close()
+			return fmt.Errorf("decode artifact %s: %w", id, err)
+		}
+		art.ChatID = to
+		if moved[id], err = json.Marshal(art); err != nil {
+			rows.Close()
+			return err
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for id, raw := range moved {
+		if _, err := tx.ExecContext(ctx, `UPDATE artifacts SET chat_id = ?, data = ? WHERE id = ?`, to, raw, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// addUsage adds u to the totals of ledger for day and month.
+func (s *sqliteState) addUsage(ctx context.Context, ledger, day, month string, u tokenUsage) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, period := range []string{day, month} {
+		_, err := tx.ExecContext(ctx, `INSERT INTO usage (ledger, period, requests, prompt, candidates, thoughts, cost_usd)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT (ledger, period) DO UPDATE SET
+				requests = requests + excluded.requests,
+				prompt = prompt + excluded.prompt,
+				candidates = candidates + excluded.candidates,
+				thoughts = thoughts + excluded.thoughts,
+				cost_usd = cost_usd + excluded.cost_usd`,
+			ledger, period, u.Requests, u.Prompt, u.Candidates, u.Thoughts, u.CostUSD)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// loadUsage returns the stored totals by ledger and period after dropping
+// the days and months that are no longer kept.
+func (s *sqliteState) loadUsage(ctx context.Context, dayCutoff, monthCutoff string) (map[string]map[string]tokenUsage, error) {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM usage WHERE (length(period) = ? AND period < ?) OR (length(period) = ? AND period < ?)`,
+		len(usageDayLayout), dayCutoff, len(usageMonthLayout), monthCutoff)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := s.db.QueryContext(ctx, `SELECT ledger, period, requests, prompt, candidates, thoughts, cost_usd FROM usage`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	stored := make(map[string]map[string]tokenUsage)
+	for rows.Next() {
+		var (
+			ledger, period string
+			u              tokenUsage
+		)
+		if err := rows.Scan(&ledger, &period, &u.Requests, &u.Prompt, &u.Candidates, &u.Thoughts, &u.CostUSD); err != nil {
+			return nil, err
+		}
+		if stored[ledger] == nil {
+			stored[ledger] = make(map[string]tokenUsage)
+		}
+		stored[ledger][period] = u
+	}
+	return stored, rows.Err()
+}
+
+// migrateUsage moves the totals of ledger from to ledger to, keeping the
+// periods to already has.
+func (s *sqliteState) migrateUsage(ctx context.Context, from, to string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `UPDATE OR IGNORE usage SET ledger = ? WHERE ledger = ?`, to, from); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM usage WHERE ledger = ?`, from); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteState) privacyChats(ctx context.Context) (map[int64]time.Time, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT chat_id, since FROM privacy`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	chats := make(map[int64]time.Time)
+	for rows.Next() {
+		var chatID, since int64
+		if err := rows.Scan(&chatID, &since); err != nil {
+			return nil, err
+		}
+		chats[chatID] = time.Unix(since, 0).UTC()
+	}
+	return chats, rows.Err()
+}
+
+func (s *sqliteState) privacySince(ctx context.Context, chatID int64) (time.Time, bool, error) {
+	var since int64
+	err := s.db.QueryRowContext(ctx, `SELECT since FROM privacy WHERE chat_id = ?`, chatID).Scan(&since)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return time.Unix(since, 0).UTC(), true, nil
+}
+
+func (s *sqliteState) enablePrivacy(ctx context.Context, chatID int64, since time.Time) error {
+	_, err := s.db.ExecContext(ctx, `INSERT OR IGNORE INTO privacy (chat_id, since) VALUES (?, ?)`, chatID, since.Unix())
+	return err
+}
+
+func (s *sqliteState) disablePrivacy(ctx context.Context, chatID int64) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM privacy WHERE chat_id = ?`, chatID)
+	return err
+}
+
+func (s *sqliteState) migratePrivacy(ctx context.Context, from, to int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO privacy (chat_id, since) SELECT ?, since FROM privacy WHERE chat_id = ?`, to, from); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM privacy WHERE chat_id = ?`, from); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *sqliteState) replyToChoices(ctx context.Context) (map[int64]bool, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT chat_id, enabled FROM reply_to`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	chats := make(map[int64]bool)
+	for rows.Next() {
+		var (
+			chatID int64
+			on     bool
+		)
+		if err := rows.Scan(&chatID, &on); err != nil {
+			return nil, err
+		}
+		chats[chatID] = on
+	}
+	return chats, rows.Err()
+}
+
+func (s *sqliteState) replyToChoice(ctx context.Context, chatID int64) (bool, bool, error) {
+	var on bool
+	err := s.db.QueryRowContext(ctx, `SELECT enabled FROM reply_to WHERE chat_id = ?`, chatID).Scan(&on)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, false, nil
+	}
+	return on, err == nil, err
+}
+
+func (s *sqliteState) setReplyTo(ctx context.Context, chatID int64, on *bool) error {
+	var err error
+	if on == nil {
+		_, err = s.db.ExecContext(ctx, `DELETE FROM reply_to WHERE chat_id = ?`, chatID)
+	} else {
+		_, err = s.db.ExecContext(ctx, `INSERT INTO reply_to (chat_id, enabled) VALUES (?, ?)
+			ON CONFLICT (chat_id) DO UPDATE SET enabled = excluded.enabled`, chatID, *on)
+	}
+	return err
+}
+
+func (s *sqliteState) migrateReplyTo(ctx context.Context, from, to int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO reply_to (chat_id, enabled) SELECT ?, enabled FROM reply_to WHERE chat_id = ?`, to, from); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM reply_to WHERE chat_id = ?`, from); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,155 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSQLiteKeepsSessionsAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+	app, _ := newTestApp(t, Config{DataDir: dir})
+	if app.sqlite == nil {
+		t.Fatal("no SQLite database by default")
+	}
+	if err := app.processMessage(context.Background(), testMessage(42, "Remember the number 7."), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	app.sessions.get(42).setThinking(thinkingModeHigh)
+	app.sessions.persist(42)
+	app.sqlite.Close()
+
+	restarted, _ := newTestApp(t, Config{DataDir: dir})
+	session := restarted.sessions.get(42)
+	session.mu.Lock()
+	history := len(session.history)
+	session.mu.Unlock()
+	if history != 2 {
+		t.Errorf("history after restart has %d entries, want the turn", history)
+	}
+	if got := session.currentThinking(); got != thinkingModeHigh {
+		t.Errorf("thinking after restart = %q, want %q", got, thinkingModeHigh)
+	}
+}
+
+func TestSQLiteKeepsReplyButtonsAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+	app, apis := newTestApp(t, Config{DataDir: dir})
+	apis.reply = "Seven it is."
+	msg := testMessage(42, "Pick a number.")
+	msg.ID = 17
+	if err := app.processMessage(context.Background(), msg, turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	infos := app.artifacts.forChat(42)
+	if len(infos) != 1 || infos[0].ID != "42_17" {
+		t.Fatalf("stored replies = %+v, want one keyed by chat and prompt", infos)
+	}
+	app.sqlite.Close()
+
+	restarted, _ := newTestApp(t, Config{DataDir: dir})
+	if art, ok := restarted.artifacts.get("42_17"); !ok || art.Reply != "Seven it is." || art.MessageID != 17 {
+		t.Fatalf("reply after restart = %+v, %v", art, ok)
+	}
+	if _, ok := restarted.artifacts.get("42_18"); ok {
+		t.Error("an unknown reply was found")
+	}
+}
+
+func TestSQLiteKeepsUsageAcrossRestarts(t *testing.T) {
+	dir := t.TempDir()
+	app, _ := newTestApp(t, Config{DataDir: dir, UserDailyBudgetUSD: 1})
+	if err := app.processMessage(context.Background(), testMessage(42, "Hi"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	app.usage.charge(42, tokenUsage{CostUSD: 1})
+	spent := app.usage.userSpentToday(42)
+	day, month := app.usage.chatSnapshot(42)
+	app.sqlite.Close()
+
+	restarted, _ := newTestApp(t, Config{DataDir: dir, UserDailyBudgetUSD: 1})
+	if got := restarted.usage.userSpentToday(42); got != spent {
+		t.Errorf("user spend after restart = $%g, want $%g", got, spent)
+	}
+	if gotDay, gotMonth := restarted.usage.chatSnapshot(42); gotDay != day || gotMonth != month {
+		t.Errorf("chat usage after restart = %+v / %+v, want %+v / %+v", gotDay, gotMonth, day, month)
+	}
+	if over := restarted.budgetExceeded(42); over != budgetUser {
+		t.Errorf("budget after restart = %q, want the user over it", over)
+	}
+}
+
+func TestSQLiteOptOut(t *testing.T) {
+	app, _ := newTestApp(t, Config{SQLitePath: "none"})
+	if app.sqlite != nil || app.sessions.shared != nil {
+		t.Error("SQLITE_PATH=none still keeps the sessions in SQLite")
+	}
+}
+
+func TestSQLiteStateMigratesAndDeletes(t *testing.T) {
+	ctx := context.Background()
+	state, err := openSQLiteState(ctx, filepath.Join(t.TempDir(), "nested", "eteon.db"))
+	if err != nil {
+		t.Fatalf("openSQLiteState: %v", err)
+	}
+	defer state.Close()
+
+	for _, rev := range []int64{1, 2} {
+		got, err := state.saveSession(ctx, sessionKey{chatID: 1}, []byte(`{"old":true}`))
+		if err != nil || got != rev {
+			t.Fatalf("saveSession = %d, %v, want revision %d", got, err, rev)
+		}
+	}
+	state.saveSession(ctx, sessionKey{chatID: 1, thread: 5}, []byte(`{"topic":true}`))
+	state.saveSession(ctx, sessionKey{chatID: 2, thread: 5}, []byte(`{"kept":true}`))
+
+	if err := state.migrateSessions(ctx, 1, 2); err != nil {
+		t.Fatalf("migrateSessions: %v", err)
+	}
+	if data, rev, _ := state.loadSession(ctx, sessionKey{chatID: 2}); string(data) != `{"old":true}` || rev != 2 {
+		t.Errorf("migrated session = %s at %d", data, rev)
+	}
+	if data, _, _ := state.loadSession(ctx, sessionKey{chatID: 2, thread: 5}); string(data) != `{"kept":true}` {
+		t.Errorf("migration replaced the existing topic with %s", data)
+	}
+	if data, _, _ := state.loadSession(ctx, sessionKey{chatID: 1}); data != nil {
+		t.Error("the old chat still has its session")
+	}
+
+	if err := state.deleteSessions(ctx, 2); err != nil {
+		t.Fatalf("deleteSessions: %v", err)
+	}
+	if rev, err := state.sessionRevision(ctx, sessionKey{chatID: 2, thread: 5}); err != nil || rev != 0 {
+		t.Errorf("revision after delete = %d, %v", rev, err)
+	}
+}
+
+func TestSQLiteStateExpiresAndMovesArtifacts(t *testing.T) {
+	ctx := context.Background()
+	state, err := openSQLiteState(ctx, filepath.Join(t.TempDir(), "eteon.db"))
+	if err != nil {
+		t.Fatalf("openSQLiteState: %v", err)
+	}
+	defer state.Close()
+
+	now := time.Now()
+	state.saveArtifact(ctx, "1_1", &responseArtifacts{ChatID: 1, Reply: "old", CreatedAt: now.Add(-artifactTTL - time.Minute)}, artifactTTL)
+	state.saveArtifact(ctx, "1_2", &responseArtifacts{ChatID: 1, Reply: "fresh", CreatedAt: now}, artifactTTL)
+	if art, err := state.loadArtifact(ctx, "1_1"); err != nil || art != nil {
+		t.Errorf("expired artifact = %+v, %v", art, err)
+	}
+
+	if err := state.migrateArtifacts(ctx, 1, 2, artifactTTL); err != nil {
+		t.Fatalf("migrateArtifacts: %v", err)
+	}
+	if art, err := state.loadArtifact(ctx, "1_2"); err != nil || art == nil || art.ChatID != 2 {
+		t.Fatalf("migrated artifact = %+v, %v", art, err)
+	}
+	if n, err := state.purgeArtifacts(ctx, 1); err != nil || n != 0 {
+		t.Errorf("purged %d artifacts of the old chat, %v", n, err)
+	}
+	if n, err := state.purgeArtifacts(ctx, 2); err != nil || n != 1 {
+		t.Errorf("purged %d artifacts, %v; want 1", n, err)
+	}
+}
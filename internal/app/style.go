@@ -0,0 +1,336 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	likeReplyUnique    = "like_reply"
+	dislikeReplyUnique = "dislike_reply"
+	// maxStyleVotes is how many of a user's latest votes the profile keeps;
+	// older ones drop out so the profile follows changing taste.
+	maxStyleVotes = 100
+	// minStyleVotes is how many votes a user casts before the bot infers a
+	// style from them.
+	minStyleVotes = 3
+)
+
+// styleVote is a 👍 or 👎 on a reply with the traits of that reply.
+type styleVote struct {
+	Reply      string    `json:"reply"`
+	At         time.Time `json:"at"`
+	Liked      bool      `json:"liked"`
+	Runes      int       `json:"runes"`
+	Casual     bool      `json:"casual,omitempty"`
+	Structured bool      `json:"structured,omitempty"`
+}
+
+// styleProfile is what the bot knows of how a user likes to be answered from
+// their votes on replies. Notes are the preferences /remember kept before it
+// saved memories; they move to the memory store on start.
+type styleProfile struct {
+	Votes []styleVote `json:"votes,omitempty"`
+	Notes []string    `json:"notes,omitempty"`
+}
+
+var (
+	structuredLine = regexp.MustCompile(`(?m)^\s*(#{1,6} |[-*•] |\d+[.)] |\|)`)
+	casualMarker   = regexp.MustCompile(`!|:\)|;\)|\blol\b|\bhaha\b`)
+)
+
+// replyTraits measures the traits of a reply a vote teaches.
+func replyTraits(reply string) styleVote {
+	vote := styleVote{Runes: utf8.RuneCountInString(reply)}
+	emoji := strings.IndexFunc(reply, func(r rune) bool {
+		return unicode.Is(unicode.So, r) && r >= 0x2600
+	}) >= 0
+	vote.Casual = emoji || len(casualMarker.FindAllString(reply, 3)) >= 3
+	vote.Structured = len(structuredLine.FindAllString(reply, 3)) >= 3
+	return vote
+}
+
+// styleLean compares a trait between liked and disliked replies and returns
+// +1 when the user leans towards it, -1 when away from it and 0 when the votes
+// do not say.
+func styleLean(votes []styleVote, has func(styleVote) bool) int {
+	var liked, likedWith, disliked, dislikedWith int
+	for _, v := range votes {
+		switch {
+		case v.Liked:
+			liked++
+			if has(v) {
+				likedWith++
+			}
+		default:
+			disliked++
+			if has(v) {
+				dislikedWith++
+			}
+		}
+	}
+	if liked == 0 || disliked == 0 {
+		return 0
+	}
+	diff := float64(likedWith)/float64(liked) - float64(dislikedWith)/float64(disliked)
+	switch {
+	case diff >= 0.5:
+		return 1
+	case diff <= -0.5:
+		return -1
+	}
+	return 0
+}
+
+// lengthLean returns -1 when the user likes clearly shorter replies than those
+// they dislike, +1 when clearly longer and 0 otherwise. Dislikes alone lean
+// short when the disliked replies were long.
+func lengthLean(votes []styleVote) int {
+	var liked, likedRunes, disliked, dislikedRunes int
+	for _, v := range votes {
+		if v.Liked {
+			liked++
+			likedRunes += v.Runes
+		} else {
+			disliked++
+			dislikedRunes += v.Runes
+		}
+	}
+	switch {
+	case disliked == 0:
+		return 0
+	case liked == 0:
+		if dislikedRunes/disliked > 1500 {
+			return -1
+		}
+		return 0
+	}
+	likedAvg, dislikedAvg := float64(likedRunes)/float64(liked), float64(dislikedRunes)/float64(disliked)
+	switch {
+	case likedAvg < dislikedAvg*0.6:
+		return -1
+	case likedAvg > dislikedAvg*1.6:
+		return 1
+	}
+	return 0
+}
+
+// learned describes the style inferred from the votes, one trait per line, or
+// nothing while there are too few votes.
+func (p styleProfile) learned() []string {
+	if len(p.Votes) < minStyleVotes {
+		return nil
+	}
+	var traits []string
+	switch lengthLean(p.Votes) {
+	case -1:
+		traits = append(traits, "prefers short, to-the-point answers")
+	case 1:
+		traits = append(traits, "prefers detailed, thorough answers")
+	}
+	switch styleLean(p.Votes, func(v styleVote) bool { return v.Casual }) {
+	case -1:
+		traits = append(traits, "prefers a formal, matter-of-fact tone without emoji")
+	case 1:
+		traits = append(traits, "likes a relaxed, friendly tone")
+	}
+	switch styleLean(p.Votes, func(v styleVote) bool { return v.Structured }) {
+	case -1:
+		traits = append(traits, "prefers flowing prose over lists and headings")
+	case 1:
+		traits = append(traits, "likes answers organised with lists and headings")
+	}
+	return traits
+}
+
+// instruction is the system prompt addition for the profile, empty when there
+// is nothing to say.
+func (p styleProfile) instruction() string {
+	traits := p.learned()
+	if len(traits) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Adapt your answers to this user's style preferences unless the current request asks otherwise.")
+	b.WriteString("\nFrom their feedback on earlier answers, the user:")
+	for _, trait := range traits {
+		b.WriteString("\n- " + trait)
+	}
+	return b.String()
+}
+
+// styleStore keeps the style profile of every user across restarts in a JSON
+// file. Every change rewrites the file atomically.
+type styleStore struct {
+	mu    sync.Mutex
+	path  string
+	users map[int64]*styleProfile
+}
+
+func openStyleStore(path string) (*styleStore, error) {
+	s := &styleStore{path: path, users: make(map[int64]*styleProfile)}
+	if err := readJSONFile(path, &s.users); err != nil {
+		return nil, err
+	}
+	if s.users == nil {
+		s.users = make(map[int64]*styleProfile)
+	}
+	return s, nil
+}
+
+// save writes the store; the caller holds s.mu.
+func (s *styleStore) save() error {
+	return writeJSONFile(s.path, s.users)
+}
+
+// get returns a copy of the profile of userID.
+func (s *styleStore) get(userID int64) styleProfile {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.users[userID]
+	if !ok {
+		return styleProfile{}
+	}
+	return styleProfile{Votes: slices.Clone(p.Votes)}
+}
+
+// update changes the profile of userID with fn and saves the store when fn
+// reports a change. Empty profiles are dropped.
+func (s *styleStore) update(userID int64, fn func(p *styleProfile) bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.users[userID]
+	if !ok {
+		p = &styleProfile{}
+	}
+	if !fn(p) {
+		return nil
+	}
+	if len(p.Votes) == 0 && len(p.Notes) == 0 {
+		delete(s.users, userID)
+	} else {
+		s.users[userID] = p
+	}
+	return s.save()
+}
+
+// vote records a vote of userID on a reply, replacing an earlier vote on the
+// same reply. It reports whether the vote changed anything.
+func (s *styleStore) vote(userID int64, vote styleVote) (bool, error) {
+	changed := false
+	err := s.update(userID, func(p *styleProfile) bool {
+		i := slices.IndexFunc(p.Votes, func(v styleVote) bool { return v.Reply == vote.Reply })
+		switch {
+		case i >= 0 && p.Votes[i].Liked == vote.Liked:
+			return false
+		case i >= 0:
+			p.Votes = slices.Delete(p.Votes, i, i+1)
+		}
+		p.Votes = append(p.Votes, vote)
+		if len(p.Votes) > maxStyleVotes {
+			p.Votes = slices.Delete(p.Votes, 0, len(p.Votes)-maxStyleVotes)
+		}
+		changed = true
+		return true
+	})
+	return changed, err
+}
+
+// forgetUser removes the profile of userID.
+func (s *styleStore) forgetUser(userID int64) error {
+	return s.update(userID, func(p *styleProfile) bool {
+		if len(p.Votes) == 0 && len(p.Notes) == 0 {
+			return false
+		}
+		*p = styleProfile{}
+		return true
+	})
+}
+
+// styleInstruction is the system prompt addition for the style of the sender
+// of msg.
+func (a *App) styleInstruction(msg *tele.Message) string {
+	if msg.Sender == nil {
+		return ""
+	}
+	return a.styles.get(msg.Sender.ID).instruction()
+}
+
+// handleLikeReply and handleDislikeReply record a 👍 or 👎 on the reply under
+// the pressed button in the presser's style profile.
+func (a *App) handleLikeReply(c tele.Context) error    { return a.recordStyleVote(c, true) }
+func (a *App) handleDislikeReply(c tele.Context) error { return a.recordStyleVote(c, false) }
+
+func (a *App) recordStyleVote(c tele.Context, liked bool) error {
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	art, ok := a.artifacts.get(c.Callback().Data)
+	if !ok {
+		return c.Respond(&tele.CallbackResponse{Text: tr(lang, "feedback_expired")})
+	}
+	vote := replyTraits(art.Reply)
+	vote.Reply = c.Callback().Data
+	vote.At = time.Now().UTC()
+	vote.Liked = liked
+	changed, err := a.styles.vote(userIDOf(c.Sender()), vote)
+	notice := tr(lang, "feedback_thanks")
+	switch {
+	case err != nil:
+		a.chatLog(c.Chat().ID).Warn("save styles failed", "chat_id", c.Chat().ID, "err", err)
+		notice = tr(lang, "feedback_failed")
+	case !changed:
+		notice = tr(lang, "feedback_same")
+	}
+	return c.Respond(&tele.CallbackResponse{Text: notice})
+}
+
+// handleRemember shows and edits what the bot knows of the sender:
+//
+//	/remember                 shows the memories and the learned style
+//	/remember <fact>          remembers a fact, e.g. "I am vegetarian"
+//	/remember forget <id>     forgets a memory, like /forget <id>
+//	/remember reset           forgets the memories and the feedback
+func (a *App) handleRemember(c tele.Context) error {
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	userID := userIDOf(c.Sender())
+	if userID == 0 {
+		return a.replyMemories(c, tr(lang, "remember_usage"))
+	}
+	payload := strings.TrimSpace(c.Message().Payload)
+	command, rest, _ := strings.Cut(payload, " ")
+
+	switch strings.ToLower(command) {
+	case "":
+		p := a.styles.get(userID)
+		notes, learned := tr(lang, "remember_no_notes"), tr(lang, "remember_unrated", minStyleVotes)
+		if memories := a.memories.list(userID); len(memories) > 0 {
+			lines := make([]string, len(memories))
+			for i, m := range memories {
+				lines[i] = fmt.Sprintf("#%d %s", m.ID, m.Text)
+			}
+			notes = strings.Join(lines, "\n")
+		}
+		if traits := p.learned(); len(traits) > 0 {
+			learned = "- " + strings.Join(traits, "\n- ")
+		}
+		return a.replyMemories(c, tr(lang, "remember_show", notes, len(p.Votes), learned))
+	case "forget":
+		return a.forgetMemory(c, strings.TrimSpace(rest))
+	case "reset":
+		err := errors.Join(a.memories.clear(userID), a.styles.forgetUser(userID))
+		if err != nil {
+			a.chatLog(c.Chat().ID).Warn("save memories failed", "chat_id", c.Chat().ID, "err", err)
+			return a.replyMemories(c, tr(lang, "remember_failed"))
+		}
+		return a.replyMemories(c, tr(lang, "remember_reset"))
+	}
+	return a.rememberFact(c, payload)
+}
@@ -0,0 +1,113 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestStyleProfileLearnsFromFeedback(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	long := strings.Repeat("This is a long and winding explanation. ", 60)
+	ask := func(reply string) string {
+		t.Helper()
+		apis.reply = reply
+		seen := make(map[string]bool)
+		for id := range app.artifacts.items {
+			seen[id] = true
+		}
+		msg := testMessage(42, "Explain it.")
+		msg.ID = len(seen) + 1
+		if err := app.processMessage(context.Background(), msg, turnOptions{}); err != nil {
+			t.Fatalf("processMessage: %v", err)
+		}
+		for id := range app.artifacts.items {
+			if !seen[id] {
+				return id
+			}
+		}
+		t.Fatal("reply stored no artifacts")
+		return ""
+	}
+	vote := func(id string, liked bool) string {
+		t.Helper()
+		handle := app.handleDislikeReply
+		if liked {
+			handle = app.handleLikeReply
+		}
+		if err := handle(actionCallback(app, 42, 42, id)); err != nil {
+			t.Fatalf("vote: %v", err)
+		}
+		calls := apis.callsTo(telegramHost, "answerCallbackQuery")
+		return string(calls[len(calls)-1].body)
+	}
+	remember := func(payload string) string {
+		t.Helper()
+		msg := testMessage(42, "/remember "+payload)
+		msg.Payload = payload
+		if err := app.handleRemember(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("handleRemember(%q): %v", payload, err)
+		}
+		texts := apis.sentTexts()
+		return texts[len(texts)-1]
+	}
+
+	first := ask(long)
+	if got := vote(first, false); !strings.Contains(got, "Thanks") {
+		t.Errorf("vote answered %s", got)
+	}
+	if got := vote(first, false); !strings.Contains(got, "already rated") {
+		t.Errorf("repeated vote answered %s", got)
+	}
+	vote(ask(long+"Even more."), false)
+	vote(ask("Short answer."), true)
+	if got := remember("answer in German"); !strings.Contains(got, "remember that in every chat") {
+		t.Errorf("remember answered %q", got)
+	}
+	if got := remember(""); !strings.Contains(got, "answer in German") || !strings.Contains(got, "prefers short") {
+		t.Errorf("profile shows %q", got)
+	}
+
+	ask("Fine.")
+	calls := apis.callsTo(geminiHost, ":generateContent")
+	body := string(calls[len(calls)-1].body)
+	if !strings.Contains(body, "answer in German") || !strings.Contains(body, "short, to-the-point") {
+		t.Errorf("style missing from the request: %s", body)
+	}
+
+	reopened, err := openStyleStore(filepath.Join(app.dataDir, "styles.json"))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if p := reopened.get(42); len(p.Votes) != 3 {
+		t.Errorf("persisted profile = %+v", p)
+	}
+	if p := reopened.get(7); len(p.Votes) != 0 {
+		t.Errorf("another user has a profile: %+v", p)
+	}
+
+	if got := remember("forget 1"); !strings.Contains(got, "1 forgotten") {
+		t.Errorf("forget answered %q", got)
+	}
+	if got := remember("reset"); !strings.Contains(got, "forgotten") {
+		t.Errorf("reset answered %q", got)
+	}
+	if p := app.styles.get(42); p.instruction() != "" || len(app.memories.list(42)) != 0 {
+		t.Errorf("after reset instruction = %q, memories = %v", p.instruction(), app.memories.list(42))
+	}
+}
+
+func TestReplyTraits(t *testing.T) {
+	if v := replyTraits("Sure thing! Happy to help! Have fun! 🎉"); !v.Casual || v.Structured {
+		t.Errorf("casual reply traits = %+v", v)
+	}
+	if v := replyTraits("Steps:\n- one\n- two\n- three"); v.Casual || !v.Structured {
+		t.Errorf("list reply traits = %+v", v)
+	}
+	if v := replyTraits("The result is 42."); v.Casual || v.Structured || v.Runes != 17 {
+		t.Errorf("plain reply traits = %+v", v)
+	}
+}
@@ -0,0 +1,290 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const summarizeCommand = "/summarize"
+
+// summaryLengths are the presets of /summarize with the shape each asks for.
+var summaryLengths = map[string]string{
+	"short":    "exactly 3 bullets in a single section",
+	"medium":   "5 to 7 bullets in a single section",
+	"detailed": "up to 12 bullets grouped into 2 to 4 sections with short headings",
+}
+
+const summarizeInstruction = "You summarize material for a chat. Write in %s, whatever the language of the material. " +
+	"Give a title of at most eight words and %s. Each bullet is one sentence with a concrete fact, figure or " +
+	"conclusion from the material; leave out filler, your own opinions and anything the material does not say. " +
+	"Sections of a single-section summary have an empty heading. %s"
+
+const (
+	summarizeWithQuotes = "Add 2 to 4 short key quotes copied word for word from the material, in its own language. " +
+		"Leave the quotes empty for material without quotable text, such as a video without speech."
+	summarizeNoQuotes = "Leave the quotes empty."
+)
+
+func summarySchema() *genai.Schema {
+	return &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"title": {Type: genai.TypeString},
+			"sections": {
+				Type: genai.TypeArray,
+				Items: &genai.Schema{
+					Type: genai.TypeObject,
+					Properties: map[string]*genai.Schema{
+						"heading": {Type: genai.TypeString},
+						"bullets": {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeString}},
+					},
+					Required:         []string{"heading", "bullets"},
+					PropertyOrdering: []string{"heading", "bullets"},
+				},
+			},
+			"quotes": {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeString}},
+		},
+		Required:         []string{"title", "sections", "quotes"},
+		PropertyOrdering: []string{"title", "sections", "quotes"},
+	}
+}
+
+type summary struct {
+	Title    string `json:"title"`
+	Sections []struct {
+		Heading string   `json:"heading"`
+		Bullets []string `json:"bullets"`
+	} `json:"sections"`
+	Quotes []string `json:"quotes"`
+}
+
+// summaryRequest is what one /summarize reads and how it answers.
+type summaryRequest struct {
+	length string
+	quotes bool
+	// source carries the attachment to read, if any.
+	source *tele.Message
+	text   string
+	links  []string
+}
+
+// parseSummarize reads the leading preset and "quotes" words of a
+// /summarize payload and returns the rest.
+func parseSummarize(payload string) (length string, quotes bool, rest string) {
+	length, rest = "medium", strings.TrimSpace(payload)
+	for rest != "" {
+		word, after, _ := strings.Cut(rest, " ")
+		switch word = strings.ToLower(word); {
+		case summaryLengths[word] != "":
+			length = word
+		case word == "quotes":
+			quotes = true
+		default:
+			return length, quotes, rest
+		}
+		rest = strings.TrimSpace(after)
+	}
+	return length, quotes, rest
+}
+
+// handleSummarize sends a bullet summary:
+//
+//	/summarize [short|medium|detailed] [quotes] <text or link>
+//	/summarize [short|medium|detailed] [quotes] as a reply to a message,
+//	link, document, photo or voice note
+//	a document captioned /summarize
+//
+// Links to pages are fetched and read; YouTube and direct video links are
+// watched. The summary is written in the chat's language.
+func (a *App) handleSummarize(c tele.Context) error {
+	msg := c.Message()
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	payload := msg.Payload
+	if _, rest := captionCommand(msg); msg.Caption != "" {
+		payload = rest
+	}
+	req := summaryRequest{}
+	req.length, req.quotes, req.text = parseSummarize(payload)
+	for _, link := range replyLinkPattern.FindAllString(req.text, -1) {
+		req.links = append(req.links, strings.TrimRight(link, ".,;:!?"))
+	}
+	req.text = withoutLinks(req.text)
+
+	switch reply := msg.ReplyTo; {
+	case hasAttachment(msg):
+		req.source = msg
+	case req.text != "" || len(req.links) > 0:
+	case reply != nil && (hasAttachment(reply) || strings.TrimSpace(reply.Text+reply.Caption) != ""):
+		if hasAttachment(reply) {
+			req.source = reply
+		}
+		req.links = messageLinks(reply)
+		req.text = strings.TrimSpace(reply.Text + "\n" + reply.Caption)
+	default:
+		_, err := a.sendWithFallback(c.Chat(), tr(lang, "summarize_usage"), &tele.SendOptions{ThreadID: messageTopic(msg), DisableWebPagePreview: true})
+		return err
+	}
+	return a.enqueueJob(msg, func(ctx context.Context) error {
+		return a.summarize(ctx, msg, req)
+	})
+}
+
+// summarize sends the summary of req to the chat of msg: as a message when
+// it is short, else as a Markdown document.
+func (a *App) summarize(ctx context.Context, msg *tele.Message, req summaryRequest) error {
+	lang := a.chatLanguage(msg.Chat, msg.Sender)
+	replyTo := msg
+	if req.source != nil {
+		replyTo = req.source
+	}
+	opts := &tele.SendOptions{ThreadID: messageTopic(msg), ReplyTo: replyTo, DisableWebPagePreview: true}
+	fail := func(key string) error {
+		_, err := a.sendWithFallback(msg.Chat, tr(lang, key), opts)
+		return err
+	}
+	ctx, cancel := context.WithTimeout(withChatID(ctx, msg.Chat.ID), 5*time.Minute)
+	defer cancel()
+
+	var parts []*genai.Part
+	if req.source != nil {
+		attached, err := a.attachmentParts(ctx, req.source)
+		if err != nil {
+			logFrom(ctx).Warn("read summary source failed", "err", err)
+			if errors.Is(err, errFileTooLarge) {
+				return fail("file_too_large")
+			}
+			return fail("input_failed")
+		}
+		parts = append(parts, attached...)
+	}
+	linked := a.summaryLinkParts(ctx, req.links)
+	if len(req.links) > 0 && len(linked) == 0 && req.source == nil && withoutLinks(req.text) == "" {
+		return fail("summarize_bad_link")
+	}
+	parts = append(parts, linked...)
+	if req.text != "" {
+		parts = append(parts, genai.NewPartFromText(req.text))
+	}
+
+	quotes := summarizeNoQuotes
+	if req.quotes {
+		quotes = summarizeWithQuotes
+	}
+	cfg := &genai.GenerateContentConfig{
+		SystemInstruction: genai.NewContentFromText(fmt.Sprintf(summarizeInstruction, languageNames[lang], summaryLengths[req.length], quotes), genai.Role("system")),
+		ResponseMIMEType:  "application/json",
+		ResponseSchema:    summarySchema(),
+		Temperature:       genai.Ptr[float32](0.2),
+	}
+	parts = append(parts, genai.NewPartFromText("Summarize this."))
+	contents := []*genai.Content{genai.NewContentFromParts(parts, genai.RoleUser)}
+	resp, model, err := a.generate(ctx, contents, cfg)
+	if err != nil {
+		logFrom(ctx).Warn("summarize failed", "err", err)
+		return fail("summarize_failed")
+	}
+	a.recordUsage(ctx, msg.Chat.ID, model, resp.UsageMetadata)
+	var result summary
+	if err := json.Unmarshal([]byte(resp.Text()), &result); err != nil {
+		logFrom(ctx).Warn("decode summary failed", "err", err)
+		return fail("summarize_failed")
+	}
+	if !req.quotes {
+		result.Quotes = nil
+	}
+	text := summaryText(lang, result)
+	if text == "" {
+		return fail("summarize_failed")
+	}
+	if len(text) <= longMessagePart {
+		_, err := a.sendWithFallback(msg.Chat, text, opts)
+		return err
+	}
+	return a.sendTextDocument(msg.Chat, "summary.md", "text/markdown", text+"\n", tr(lang, "summarize_caption"), opts)
+}
+
+// summaryLinkParts reads the links for a summary: YouTube and direct video
+// links as video, other links as the readable text of the page. Links that
+// cannot be read are skipped.
+func (a *App) summaryLinkParts(ctx context.Context, links []string) []*genai.Part {
+	var parts []*genai.Part
+	for _, link := range links {
+		if len(parts) == pageFetchLimit {
+			break
+		}
+		if watch, ok := youtubeWatchURL(link); ok {
+			parts = append(parts, genai.NewPartFromURI(watch, "video/mp4"))
+			continue
+		}
+		if videoLinkMIME(link, "") != "" {
+			part, err := a.videoLinkPart(ctx, link)
+			if err != nil {
+				logFrom(ctx).Info("read video link failed", "url", link, "err", err)
+				continue
+			}
+			parts = append(parts, part)
+			continue
+		}
+		title, text, err := a.fetchPage(ctx, link)
+		if err != nil || strings.TrimSpace(text) == "" {
+			logFrom(ctx).Info("fetch page failed", "url", link, "err", err)
+			continue
+		}
+		header := "Text of " + link
+		if title != "" {
+			header += "\nTitle: " + title
+		}
+		parts = append(parts, genai.NewPartFromText(header+"\n\n"+text))
+	}
+	return parts
+}
+
+// withoutLinks returns text with its links removed.
+func withoutLinks(text string) string {
+	return strings.TrimSpace(replyLinkPattern.ReplaceAllString(text, ""))
+}
+
+// summaryText renders a summary in the bot's Markdown: the title, the
+// bullets under their section headings, and the key quotes if any.
+func summaryText(lang language, s summary) string {
+	var b strings.Builder
+	for _, section := range s.Sections {
+		var bullets []string
+		for _, bullet := range section.Bullets {
+			if bullet = strings.TrimSpace(bullet); bullet != "" {
+				bullets = append(bullets, "- "+bullet)
+			}
+		}
+		if len(bullets) == 0 {
+			continue
+		}
+		if heading := strings.TrimSpace(section.Heading); heading != "" && len(s.Sections) > 1 {
+			fmt.Fprintf(&b, "### %s\n", heading)
+		}
+		b.WriteString(strings.Join(bullets, "\n") + "\n\n")
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	var quotes []string
+	for _, quote := range s.Quotes {
+		if quote = strings.Trim(strings.TrimSpace(quote), `"“”«»„`); quote != "" {
+			quotes = append(quotes, "> “"+quote+"”")
+		}
+	}
+	if len(quotes) > 0 {
+		fmt.Fprintf(&b, "### %s\n%s\n", tr(lang, "summarize_quotes"), strings.Join(quotes, "\n"))
+	}
+	body := strings.TrimSpace(b.String())
+	if title := strings.TrimSpace(s.Title); title != "" {
+		return "## " + title + "\n\n" + body
+	}
+	return body
+}
@@ -0,0 +1,90 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestParseSummarize(t *testing.T) {
+	for payload, want := range map[string]struct {
+		length string
+		quotes bool
+		rest   string
+	}{
+		"":                            {"medium", false, ""},
+		"short":                       {"short", false, ""},
+		"Detailed quotes https://x.y": {"detailed", true, "https://x.y"},
+		"quotes short a short note":   {"short", true, "a short note"},
+	} {
+		length, quotes, rest := parseSummarize(payload)
+		if length != want.length || quotes != want.quotes || rest != want.rest {
+			t.Errorf("parseSummarize(%q) = %q, %v, %q, want %+v", payload, length, quotes, rest, want)
+		}
+	}
+}
+
+func TestSummarizeLinkWithQuotes(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.handle("news.example.com", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Rates</title></head><body><article><p>The central bank held rates at 4% on Tuesday, citing slowing inflation.</p></article></body></html>`))
+	}))
+	apis.answer = func(string, []byte) []any {
+		return []any{map[string]any{"text": `{"title":"Rates on hold","sections":[{"heading":"","bullets":["Rates stay at 4%.","Inflation is slowing."]}],"quotes":["citing slowing inflation"]}`}}
+	}
+
+	msg := testMessage(42, "/summarize short quotes https://news.example.com/rates.")
+	msg.Payload = "short quotes https://news.example.com/rates."
+	if err := app.handleSummarize(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+		t.Fatalf("handleSummarize: %v", err)
+	}
+	app.queue.drain(context.Background())
+
+	calls := apis.callsTo(geminiHost, ":generateContent")
+	if len(calls) != 1 {
+		t.Fatalf("generateContent called %d times, want once", len(calls))
+	}
+	body := string(calls[0].body)
+	for _, want := range []string{"held rates at 4%", "exactly 3 bullets", "key quotes", "Write in English"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("request misses %q: %s", want, body)
+		}
+	}
+	want := "*Rates on hold*\n\n• Rates stay at 4%\\.\n• Inflation is slowing\\.\n\n*Key quotes*\n>“citing slowing inflation”"
+	if texts := apis.sentTexts(); len(texts) != 1 || texts[0] != want {
+		t.Errorf("sent %q, want %q", texts, want)
+	}
+}
+
+func TestSummarizeReplyAndUsage(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.answer = func(string, []byte) []any {
+		return []any{map[string]any{"text": `{"title":"Plan","sections":[{"heading":"Now","bullets":["Ship on Friday."]},{"heading":"Later","bullets":["Write docs."]}],"quotes":["ignored"]}`}}
+	}
+	if err := app.handleSummarize(app.bot.NewContext(tele.Update{Message: testMessage(42, "/summarize")})); err != nil {
+		t.Fatalf("handleSummarize: %v", err)
+	}
+	msg := testMessage(42, "/summarize detailed")
+	msg.Payload = "detailed"
+	msg.ReplyTo = testMessage(42, "We ship on Friday and write the docs next week.")
+	if err := app.handleSummarize(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+		t.Fatalf("handleSummarize: %v", err)
+	}
+	app.queue.drain(context.Background())
+
+	calls := apis.callsTo(geminiHost, ":generateContent")
+	if len(calls) != 1 || !strings.Contains(string(calls[0].body), "ship on Friday") || !strings.Contains(string(calls[0].body), "Leave the quotes empty") {
+		t.Fatalf("generateContent calls = %d, want one summary of the reply without quotes", len(calls))
+	}
+	texts := apis.sentTexts()
+	if len(texts) != 2 || !strings.Contains(texts[0], "Send /summarize") {
+		t.Fatalf("sent %q, want the usage and the summary", texts)
+	}
+	if want := "*Plan*\n\n*Now*\n• Ship on Friday\\.\n\n*Later*\n• Write docs\\."; texts[1] != want {
+		t.Errorf("summary = %q, want %q", texts[1], want)
+	}
+}
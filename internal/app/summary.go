@@ -0,0 +1,249 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/genai"
+)
+
+const (
+	summaryModel = "gemini-2.5-flash-lite"
+	// historyTokenBudget is the size of the stored history above which older turns
+	// are folded into the rolling summary.
+	historyTokenBudget = 24000
+	// historyKeepTokens is how much recent history stays verbatim after a compaction.
+	historyKeepTokens = 8000
+	// maxHistoryTokens bounds the history when summarisation keeps failing; the
+	// older turns are then dropped instead of summarised.
+	maxHistoryTokens = 4 * historyTokenBudget
+	// mediaTokenEstimate approximates what an image or file part costs when its
+	// tokens could not be counted; it is what Gemini charges for a small image.
+	mediaTokenEstimate = 258
+	maxSummaryTokens   = 1024
+	summaryPrefix      = "Summary of the earlier conversation in this chat:\n"
+)
+
+// estimateTokens roughly sizes contents at four characters per token. It stands
+// in for CountTokens when the API cannot be reached.
+func estimateTokens(contents ...*genai.Content) int {
+	chars, media := 0, 0
+	for _, content := range contents {
+		if content == nil {
+			continue
+		}
+		for _, part := range content.Parts {
+			if part == nil {
+				continue
+			}
+			chars += utf8.RuneCountInString(part.Text)
+			if part.ExecutableCode != nil {
+				chars += utf8.RuneCountInString(part.ExecutableCode.Code)
+			}
+			if part.CodeExecutionResult != nil {
+				chars += utf8.RuneCountInString(part.CodeExecutionResult.Output)
+			}
+			if part.InlineData != nil || part.FileData != nil {
+				media++
+			}
+		}
+	}
+	return chars/4 + media*mediaTokenEstimate
+}
+
+// entryTokens returns the counted size of history entry i, or an estimate while
+// it has not been counted.
+func (s *sessionState) entryTokens(i int) int {
+	if i < len(s.historyTokens) && s.historyTokens[i] > 0 {
+		return s.historyTokens[i]
+	}
+	return estimateTokens(s.history[i])
+}
+
+func (s *sessionState) totalTokens() int {
+	total := 0
+	for i := range s.history {
+		total += s.entryTokens(i)
+	}
+	return total
+}
+
+// compactionCut returns how many of the oldest history entries should be
+// summarised, or 0 while the history fits the budget. The cut always lands on a
+// user turn so the verbatim history keeps starting with the user.
+func (s *sessionState) compactionCut() int {
+	if s.totalTokens() <= historyTokenBudget {
+		return 0
+	}
+	kept, cut := 0, 0
+	for i := len(s.history) - 1; i > 0; i-- {
+		kept += s.entryTokens(i)
+		if s.history[i].Role != genai.RoleUser {
+			continue
+		}
+		// The latest user turn is always kept, even when it alone exceeds the budget.
+		if cut != 0 && kept > historyKeepTokens {
+			break
+		}
+		cut = i
+	}
+	return cut
+}
+
+// countHistoryTokens sizes the history entries that have not been counted yet
+// with CountTokens, so media and documents weigh what the model charges for them.
+func (a *App) countHistoryTokens(ctx context.Context, session *sessionState) {
+	session.mu.Lock()
+	var pending []*genai.Content
+	for i, n := range session.historyTokens {
+		if n == 0 {
+			pending = append(pending, session.history[i])
+		}
+	}
+	session.mu.Unlock()
+	if len(pending) == 0 {
+		return
+	}
+
+	// Other providers count nothing ahead; Gemini's count is close enough.
+	counter := a.tuned().model
+	if !a.isGeminiModel(counter) {
+		counter = geminiModel
+	}
+	counts := make(map[*genai.Content]int, len(pending))
+	for _, content := range pending {
+		resp, err := a.client.Models.CountTokens(ctx, counter, []*genai.Content{content}, nil)
+		if err != nil {
+			logFrom(ctx).Warn("count tokens failed", "err", err)
+			counts[content] = max(estimateTokens(content), 1)
+			continue
+		}
+		counts[content] = max(int(resp.TotalTokens), 1)
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	for i, content := range session.history {
+		if n, ok := counts[content]; ok && session.historyTokens[i] == 0 {
+			session.historyTokens[i] = n
+		}
+	}
+}
+
+// trimHistory keeps the stored history of a chat within historyTokenBudget by
+// folding older turns into the rolling summary. When summarising fails, the
+// older turns are dropped once the history outgrows maxHistoryTokens. Either way
+// the turns that leave the history are archived for /recall. It runs on the
+// chat's queue, so the history can only have grown at the tail meanwhile.
+func (a *App) trimHistory(ctx context.Context, chatID int64, session *sessionState) {
+	a.countHistoryTokens(ctx, session)
+
+	session.mu.Lock()
+	cut := session.compactionCut()
+	if cut == 0 {
+		session.mu.Unlock()
+		return
+	}
+	older := slices.Clone(session.history[:cut])
+	previous := session.summary
+	session.mu.Unlock()
+
+	summary, err := a.summarizeTurns(ctx, chatID, previous, older)
+	if err != nil {
+		logFrom(ctx).Warn("summarise history failed", "err", err)
+	}
+
+	session.mu.Lock()
+	if len(session.history) < cut || session.history[0] != older[0] {
+		session.mu.Unlock()
+		return
+	}
+	switch {
+	case err == nil:
+		session.summary = summary
+	case session.totalTokens() <= maxHistoryTokens:
+		session.mu.Unlock()
+		return
+	}
+	session.dropOldest(cut)
+	session.mu.Unlock()
+	a.archiveTurns(ctx, chatID, older)
+}
+
+func (a *App) summarizeTurns(ctx context.Context, chatID int64, previous string, turns []*genai.Content) (string, error) {
+	instruction := strings.Join([]string{
+		"You maintain the long-term memory of a chat between a user and an assistant.",
+		"Merge the existing summary and the new turns into one updated summary.",
+		"Keep facts about the user, decisions, open questions, names, numbers and commitments; drop small talk.",
+		"Write compact bullet points in the language of the conversation, at most 300 words, without any preamble.",
+	}, " ")
+
+	var b strings.Builder
+	if previous != "" {
+		b.WriteString("Existing summary:\n")
+		b.WriteString(previous)
+		b.WriteString("\n\n")
+	}
+	b.WriteString("New turns:\n")
+	b.WriteString(transcript(turns))
+
+	contents := []*genai.Content{genai.NewContentFromText(b.String(), genai.RoleUser)}
+	cfg := &genai.GenerateContentConfig{
+		SystemInstruction: genai.NewContentFromText(instruction, genai.Role("system")),
+		MaxOutputTokens:   maxSummaryTokens,
+	}
+	resp, err := a.generateWithRetry(ctx, summaryModel, contents, cfg)
+	if err != nil {
+		return "", err
+	}
+	a.recordUsage(ctx, chatID, summaryModel, resp.UsageMetadata)
+
+	summary, _ := a.renderResponse(resp)
+	if summary == "" {
+		return "", fmt.Errorf("empty summary from %s", summaryModel)
+	}
+	return summary, nil
+}
+
+// transcript renders turns as a plain-text dialogue for the summariser.
+func transcript(turns []*genai.Content) string {
+	var b strings.Builder
+	for _, turn := range turns {
+		if turn == nil {
+			continue
+		}
+		speaker := "User"
+		if turn.Role == genai.RoleModel {
+			speaker = "Assistant"
+		}
+		text := contentText(turn)
+		if text == "" {
+			continue
+		}
+		b.WriteString(speaker)
+		b.WriteString(": ")
+		b.WriteString(text)
+		b.WriteString("\n\n")
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// contentText joins the visible text of content, naming attachments instead of including them.
+func contentText(content *genai.Content) string {
+	var texts []string
+	for _, part := range content.Parts {
+		switch {
+		case part == nil || part.Thought:
+		case strings.TrimSpace(part.Text) != "":
+			texts = append(texts, strings.TrimSpace(part.Text))
+		case part.InlineData != nil:
+			texts = append(texts, fmt.Sprintf("[attachment: %s]", part.InlineData.MIMEType))
+		case part.FileData != nil:
+			texts = append(texts, fmt.Sprintf("[attachment: %s]", part.FileData.MIMEType))
+		}
+	}
+	return strings.Join(texts, "\n")
+}
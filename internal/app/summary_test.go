@@ -0,0 +1,79 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestLongHistoryIsSummarised(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.answer = func(model string, body []byte) []any {
+		if model == summaryModel {
+			return []any{map[string]any{"text": "- The user plans a trip to Lisbon."}}
+		}
+		return []any{map[string]any{"text": "Sure."}}
+	}
+
+	session := app.sessions.get(42)
+	filler := strings.Repeat("word ", 4000)
+	for i := 0; i < 6; i++ {
+		session.appendTurn(genai.NewContentFromText(filler, genai.RoleUser), genai.NewContentFromText(filler, genai.RoleModel), "")
+	}
+
+	if err := app.processMessage(context.Background(), testMessage(42, "Hi"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	if session.summary == "" {
+		t.Fatal("history was not summarised")
+	}
+	if got := estimateTokens(session.history...); got > historyKeepTokens {
+		t.Fatalf("kept %d tokens of history, want at most %d", got, historyKeepTokens)
+	}
+	if len(session.history) == 0 || session.history[0].Role != genai.RoleUser {
+		t.Fatal("kept history does not start with a user turn")
+	}
+
+	if err := app.processMessage(context.Background(), testMessage(42, "Where am I going?"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	calls := apis.callsTo(geminiHost, geminiModel+":generateContent")
+	if last := calls[len(calls)-1]; !bytes.Contains(last.body, []byte("trip to Lisbon")) {
+		t.Fatalf("follow-up request lacks the summary: %s", last.body)
+	}
+}
+
+func TestHistoryTrimsByCountedTokens(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.answer = func(model string, body []byte) []any {
+		if model == summaryModel {
+			return []any{map[string]any{"text": "- The user shared a long report."}}
+		}
+		return []any{map[string]any{"text": "Sure."}}
+	}
+	apis.tokens = func(body []byte) int {
+		if bytes.Contains(body, []byte("report.pdf")) {
+			return 30000
+		}
+		return 10
+	}
+
+	session := app.sessions.get(42)
+	session.appendTurn(genai.NewContentFromText("report.pdf", genai.RoleUser), genai.NewContentFromText("Got it.", genai.RoleModel), "")
+	if session.compactionCut() != 0 {
+		t.Fatal("short text turns already exceed the estimated budget")
+	}
+
+	if err := app.processMessage(context.Background(), testMessage(42, "Hi"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	if session.summary == "" || len(session.history) != 2 {
+		t.Fatalf("history of %d entries was not trimmed by its counted size", len(session.history))
+	}
+	if got := session.totalTokens(); got != 20 {
+		t.Fatalf("kept history counts %d tokens, want 20", got)
+	}
+}
@@ -0,0 +1,196 @@
+package app
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	// maxFloodWait is the longest flood wait a send sits out before retrying
+	// once; longer waits fail the send.
+	maxFloodWait = 10 * time.Second
+	// longMessagePart is the size, before formatting, of the parts a message
+	// Telegram rejects as too long is split into.
+	longMessagePart = 3500
+)
+
+// errChatInactive is returned for sends to a chat Telegram said the bot can no
+// longer reach. The chat becomes active again when it next writes to the bot.
+var errChatInactive = errors.New("chat is inactive")
+
+// telegramFault is the class of a failed Telegram API call.
+type telegramFault string
+
+const (
+	faultNone telegramFault = ""
+	// faultBlocked: the user blocked the bot, deleted their account or never
+	// started it.
+	faultBlocked telegramFault = "blocked"
+	// faultChatGone: the chat does not exist or the bot was removed from it.
+	faultChatGone  telegramFault = "chat_not_found"
+	faultFloodWait telegramFault = "flood_wait"
+	// faultMigrated: the group became a supergroup with a new chat ID.
+	faultMigrated telegramFault = "migrated"
+	faultTooLong  telegramFault = "message_too_long"
+	faultOther    telegramFault = "other"
+)
+
+// unreachable reports whether the fault means the chat cannot be sent to.
+func (f telegramFault) unreachable() bool {
+	return f == faultBlocked || f == faultChatGone
+}
+
+// classifyTelegramError sorts an error of the Bot API into a fault, with the
+// wait Telegram asked for on a flood wait.
+func classifyTelegramError(err error) (telegramFault, time.Duration) {
+	if err == nil {
+		return faultNone, 0
+	}
+	var flood tele.FloodError
+	if errors.As(err, &flood) {
+		return faultFloodWait, time.Duration(flood.RetryAfter) * time.Second
+	}
+	if errors.Is(err, tele.ErrGroupMigrated) || errors.As(err, new(tele.GroupError)) {
+		return faultMigrated, 0
+	}
+	switch {
+	case errors.Is(err, tele.ErrBlockedByUser), errors.Is(err, tele.ErrUserIsDeactivated), errors.Is(err, tele.ErrNotStartedByUser):
+		return faultBlocked, 0
+	case errors.Is(err, tele.ErrChatNotFound), errors.Is(err, tele.ErrKickedFromGroup),
+		errors.Is(err, tele.ErrKickedFromSuperGroup), errors.Is(err, tele.ErrKickedFromChannel),
+		errors.Is(err, tele.ErrNotChannelMember):
+		return faultChatGone, 0
+	case errors.Is(err, tele.ErrTooLongMessage):
+		return faultTooLong, 0
+	}
+	// Descriptions telebot does not know come back as plain errors.
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "bot was blocked"), strings.Contains(msg, "user is deactivated"):
+		return faultBlocked, 0
+	case strings.Contains(msg, "chat not found"), strings.Contains(msg, "bot was kicked"):
+		return faultChatGone, 0
+	case strings.Contains(msg, "too many requests"):
+		return faultFloodWait, 0
+	case strings.Contains(msg, "message is too long"):
+		return faultTooLong, 0
+	}
+	return faultOther, 0
+}
+
+// telegramFailed logs a failed Bot API call with its class, marks the chat
+// inactive when Telegram says the bot can no longer reach it and follows a
+// group to its new supergroup. It returns err.
+func (a *App) telegramFailed(op string, chatID int64, err error) error {
+	if err == nil || errors.Is(err, errChatInactive) {
+		return err
+	}
+	fault, wait := classifyTelegramError(err)
+	attrs := []any{"op", op, "chat_id", chatID, "fault", string(fault), "err", err}
+	logger := a.chatLog(chatID)
+	switch {
+	case fault.unreachable() && chatID != 0:
+		logger.Info("telegram chat unreachable, marking it inactive", attrs...)
+		if err := a.operator.markInactive(chatID, string(fault)); err != nil {
+			logger.Warn("save inactive chat failed", "chat_id", chatID, "err", err)
+		}
+	case fault == faultMigrated && chatID != 0:
+		var migrated tele.GroupError
+		if errors.As(err, &migrated) && migrated.MigratedTo != 0 {
+			a.migrateChat(chatID, migrated.MigratedTo)
+		} else {
+			logger.Warn("telegram call failed", attrs...)
+		}
+	case fault == faultFloodWait:
+		logger.Warn("telegram flood wait", append(attrs, "retry_after", wait)...)
+	default:
+		logger.Warn("telegram call failed", attrs...)
+	}
+	return err
+}
+
+// recipientID returns the chat ID of a recipient, or zero for a username.
+func recipientID(recipient tele.Recipient) int64 {
+	id, _ := strconv.ParseInt(recipient.Recipient(), 10, 64)
+	return id
+}
+
+// botSend sends what to recipient unless the chat is inactive, sits out one
+// short flood wait and classifies a failure. A recipient from inTopic is
+// posted to in its forum topic. Parse errors are left to the caller, which
+// retries them with escaped text. In ephemeral chats the sent message is
+// queued for deletion.
+func (a *App) botSend(recipient tele.Recipient, what any, opts ...any) (*tele.Message, error) {
+	chatID := recipientID(recipient)
+	if chatID != 0 && a.operator.isInactive(chatID) {
+		return nil, errChatInactive
+	}
+	opts = topicOptions(recipient, opts)
+	msg, err := a.bot.Send(recipient, what, opts...)
+	if fault, wait := classifyTelegramError(err); fault == faultFloodWait && wait > 0 && wait <= maxFloodWait {
+		time.Sleep(wait)
+		msg, err = a.bot.Send(recipient, what, opts...)
+	}
+	if err != nil && !isParseError(err) {
+		a.telegramFailed("send", chatID, err)
+	}
+	if err == nil {
+		a.scheduleDeletion(msg, false)
+	}
+	return msg, err
+}
+
+// botEdit is botSend for edits of msg.
+func (a *App) botEdit(msg tele.Editable, what any, opts ...any) (*tele.Message, error) {
+	_, chatID := msg.MessageSig()
+	edited, err := a.bot.Edit(msg, what, opts...)
+	if fault, wait := classifyTelegramError(err); fault == faultFloodWait && wait > 0 && wait <= maxFloodWait {
+		time.Sleep(wait)
+		edited, err = a.bot.Edit(msg, what, opts...)
+	}
+	if err != nil && !isParseError(err) && !errors.Is(err, tele.ErrSameMessageContent) && !errors.Is(err, tele.ErrMessageNotModified) {
+		a.telegramFailed("edit", chatID, err)
+	}
+	return edited, err
+}
+
+// splitLongText cuts text into parts of at most limit bytes, preferring
+// paragraph, line and word boundaries.
+func splitLongText(text string, limit int) []string {
+	var parts []string
+	for len(text) > limit {
+		cut := strings.LastIndex(text[:limit], "\n\n")
+		if cut <= 0 {
+			cut = strings.LastIndex(text[:limit], "\n")
+		}
+		if cut <= 0 {
+			cut = strings.LastIndex(text[:limit], " ")
+		}
+		if cut <= 0 {
+			cut = limit
+			for cut > 0 && !utf8.RuneStart(text[cut]) {
+				cut--
+			}
+		}
+		parts = append(parts, strings.TrimSpace(text[:cut]))
+		text = strings.TrimSpace(text[cut:])
+	}
+	return append(parts, text)
+}
+
+// truncateText shortens text to at most limit bytes, ending it with an ellipsis.
+func truncateText(text string, limit int) string {
+	if len(text) <= limit {
+		return text
+	}
+	cut := limit
+	for cut > 0 && !utf8.RuneStart(text[cut]) {
+		cut--
+	}
+	return strings.TrimSpace(text[:cut]) + "…"
+}
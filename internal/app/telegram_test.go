@@ -0,0 +1,112 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestClassifyTelegramError(t *testing.T) {
+	tests := []struct {
+		err   error
+		fault telegramFault
+	}{
+		{nil, faultNone},
+		{tele.ErrBlockedByUser, faultBlocked},
+		{tele.ErrUserIsDeactivated, faultBlocked},
+		{tele.ErrChatNotFound, faultChatGone},
+		{tele.ErrKickedFromSuperGroup, faultChatGone},
+		{tele.ErrTooLongMessage, faultTooLong},
+		{fmt.Errorf("send: %w", tele.ErrTooLongMessage), faultTooLong},
+		{errors.New("telegram: Forbidden: bot was blocked by the user (403)"), faultBlocked},
+		{errors.New("telegram: Too Many Requests: retry later (429)"), faultFloodWait},
+		{tele.ErrNoRightsToSend, faultOther},
+	}
+	for _, tt := range tests {
+		if got, _ := classifyTelegramError(tt.err); got != tt.fault {
+			t.Errorf("classify(%v) = %q, want %q", tt.err, got, tt.fault)
+		}
+	}
+}
+
+func TestBlockedChatBecomesInactive(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.telegramError = func(method string, body []byte) (int, string) {
+		if method == "sendMessage" && strings.Contains(string(body), `"chat_id":"42"`) {
+			return 403, "Forbidden: bot was blocked by the user"
+		}
+		return 0, ""
+	}
+	if err := app.operator.seeChat(42); err != nil {
+		t.Fatalf("seeChat: %v", err)
+	}
+
+	if _, err := app.sendWithFallback(&tele.Chat{ID: 42}, "Hello", nil); !errors.Is(err, tele.ErrBlockedByUser) {
+		t.Fatalf("send = %v, want the block reported", err)
+	}
+	if !app.operator.isInactive(42) || len(app.operator.knownChats()) != 0 {
+		t.Fatal("blocked chat still counted as reachable")
+	}
+	before := len(apis.callsTo(telegramHost, "sendMessage"))
+	if _, err := app.sendWithFallback(&tele.Chat{ID: 42}, "Hello again", nil); !errors.Is(err, errChatInactive) {
+		t.Errorf("second send = %v, want errChatInactive", err)
+	}
+	if after := len(apis.callsTo(telegramHost, "sendMessage")); after != before {
+		t.Error("the bot kept messaging a chat that blocked it")
+	}
+
+	reopened, err := openOperatorState(app.operator.path)
+	if err != nil {
+		t.Fatalf("openOperatorState: %v", err)
+	}
+	if !reopened.isInactive(42) {
+		t.Error("inactive chat not persisted")
+	}
+
+	handler := app.operatorMiddleware(func(tele.Context) error { return nil })
+	if err := handler(app.bot.NewContext(tele.Update{Message: testMessage(42, "I'm back")})); err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	if app.operator.isInactive(42) {
+		t.Error("a chat writing again stayed inactive")
+	}
+}
+
+func TestTooLongMessageIsSplit(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.telegramError = func(method string, body []byte) (int, string) {
+		var params struct {
+			Text string `json:"text"`
+		}
+		_ = json.Unmarshal(body, &params)
+		if len([]rune(params.Text)) > 4096 {
+			return 400, "Bad Request: message is too long"
+		}
+		return 0, ""
+	}
+	paragraph := strings.Repeat("word ", 300)
+	text := strings.TrimSpace(strings.Repeat(paragraph+"\n\n", 6))
+
+	if _, err := app.sendWithFallback(&tele.Chat{ID: 42}, text, nil); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+	var texts []string
+	for _, text := range apis.sentTexts() {
+		if len([]rune(text)) <= 4096 {
+			texts = append(texts, text)
+		}
+	}
+	if len(texts) < 3 {
+		t.Fatalf("sent %d messages, want the text split", len(texts))
+	}
+	if got := strings.Count(strings.Join(texts, " "), "word"); got != 1800 {
+		t.Errorf("parts hold %d words, want 1800", got)
+	}
+	if app.operator.isInactive(42) {
+		t.Error("a long message marked the chat inactive")
+	}
+}
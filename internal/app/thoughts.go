@@ -0,0 +1,61 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const fullThoughtsUnique = "full_thoughts"
+
+// thoughtsSummarySteps is how many sentences the "Show thoughts" summary
+// keeps; the full trace is one button away.
+const thoughtsSummarySteps = 5
+
+// fullThoughtsMarkup offers the complete reasoning trace of answer id under
+// its summary.
+func fullThoughtsMarkup(lang language, id string) *tele.ReplyMarkup {
+	markup := &tele.ReplyMarkup{}
+	markup.Inline(markup.Row(markup.Data(tr(lang, "btn_thoughts_full"), fullThoughtsUnique, id)))
+	return markup
+}
+
+// handleFullThoughts sends the complete reasoning trace of an answer: as a
+// message when it fits, as a Markdown document otherwise.
+func (a *App) handleFullThoughts(c tele.Context) error {
+	if err := c.Respond(); err != nil {
+		slog.Warn("callback acknowledge failed", "err", err)
+	}
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	id := c.Callback().Data
+	art, ok := a.artifacts.get(id)
+	trace := thoughtsTrace(art.Thoughts)
+	if !ok || trace == "" {
+		_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), tr(lang, "thoughts_none"), &tele.SendOptions{DisableWebPagePreview: true})
+		return err
+	}
+	if len(trace) <= longMessagePart {
+		_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), trace, &tele.SendOptions{DisableWebPagePreview: true})
+		return err
+	}
+	var doc strings.Builder
+	doc.WriteString("# " + tr(lang, "thoughts_full") + "\n\n")
+	if prompt := strings.TrimSpace(art.Prompt); prompt != "" {
+		fmt.Fprintf(&doc, "> %s\n\n", strings.ReplaceAll(prompt, "\n", "\n> "))
+	}
+	doc.WriteString(trace + "\n")
+	return a.sendTextDocument(inTopic(c.Chat(), c.Message()), "reasoning-"+id+".md", "text/markdown", doc.String(), tr(lang, "thoughts_full"))
+}
+
+// thoughtsTrace joins the thought parts of an answer into one text.
+func thoughtsTrace(thoughts []string) string {
+	var parts []string
+	for _, thought := range thoughts {
+		if thought = strings.TrimSpace(thought); thought != "" {
+			parts = append(parts, thought)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
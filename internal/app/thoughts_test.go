@@ -0,0 +1,47 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestShowThoughtsOffersFullTrace(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	var thoughts []string
+	for i := range 200 {
+		thoughts = append(thoughts, fmt.Sprintf("Step %d checks the premise once more.", i))
+	}
+	id := app.artifacts.put(&responseArtifacts{ChatID: 42, Prompt: "Why?", Thoughts: thoughts})
+
+	if err := app.handleShowThoughts(actionCallback(app, 42, 7, id)); err != nil {
+		t.Fatalf("handleShowThoughts: %v", err)
+	}
+	edits := apis.callsTo(telegramHost, "editMessageText")
+	if len(edits) != 1 || !strings.Contains(string(edits[0].body), fullThoughtsUnique) {
+		t.Fatalf("summary does not offer the full trace: %v", edits)
+	}
+
+	if err := app.handleFullThoughts(actionCallback(app, 42, 7, id)); err != nil {
+		t.Fatalf("handleFullThoughts: %v", err)
+	}
+	docs := apis.callsTo(telegramHost, "sendDocument")
+	if len(docs) != 1 {
+		t.Fatalf("sent %d documents, want the trace", len(docs))
+	}
+	body := string(docs[0].body)
+	if !strings.Contains(body, "reasoning-"+id+".md") || !strings.Contains(body, "Step 199 checks the premise once more.") {
+		t.Errorf("document misses the trace: %.300s", body)
+	}
+}
+
+func TestShortThoughtsStaySummary(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	id := app.artifacts.put(&responseArtifacts{ChatID: 42, Thoughts: []string{"Only one step."}})
+	if err := app.handleShowThoughts(actionCallback(app, 42, 7, id)); err != nil {
+		t.Fatalf("handleShowThoughts: %v", err)
+	}
+	if edits := apis.callsTo(telegramHost, "editMessageText"); len(edits) != 1 || strings.Contains(string(edits[0].body), fullThoughtsUnique) {
+		t.Errorf("a short trace offers the full one: %v", edits)
+	}
+}
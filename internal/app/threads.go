@@ -0,0 +1,162 @@
+package app
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	// defaultThread names the conversation a chat starts with.
+	defaultThread = "main"
+	// maxThreads bounds the conversations of a chat, the active one included.
+	maxThreads = 10
+)
+
+// conversationThread is a parked conversation of a chat: its history, summary
+// and checkpoints, set aside while another one is active.
+type conversationThread struct {
+	summary       string
+	history       []*genai.Content
+	historyTokens []int
+	historyMeta   []historyMeta
+	checkpoints   []checkpoint
+	checkpointSeq int
+	parked        time.Time
+}
+
+// activeThread returns the name of the active conversation; the caller holds
+// session.mu.
+func (s *sessionState) activeThread() string {
+	if s.thread == "" {
+		return defaultThread
+	}
+	return s.thread
+}
+
+// threadNames lists the conversations of a chat, the active one included, in
+// name order; the caller holds session.mu.
+func (s *sessionState) threadNames() []string {
+	names := []string{s.activeThread()}
+	for name := range s.threads {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func (s *sessionState) hasThread(name string) bool {
+	_, ok := s.threads[name]
+	return ok || name == s.activeThread()
+}
+
+// switchThread parks the active conversation and makes name the active one,
+// starting it empty when it does not exist; the caller holds session.mu.
+func (s *sessionState) switchThread(name string) {
+	if name == s.activeThread() {
+		return
+	}
+	if s.threads == nil {
+		s.threads = make(map[string]*conversationThread)
+	}
+	s.threads[s.activeThread()] = &conversationThread{
+		summary:       s.summary,
+		history:       s.history,
+		historyTokens: s.historyTokens,
+		historyMeta:   s.historyMeta,
+		checkpoints:   s.checkpoints,
+		checkpointSeq: s.checkpointSeq,
+		parked:        time.Now(),
+	}
+	next := s.threads[name]
+	delete(s.threads, name)
+	if next == nil {
+		next = &conversationThread{}
+	}
+	s.thread = name
+	s.summary = next.summary
+	s.history = next.history
+	s.historyTokens = next.historyTokens
+	s.historyMeta = next.historyMeta
+	s.checkpoints = next.checkpoints
+	s.checkpointSeq = next.checkpointSeq
+	s.lastTurn = lastTurn{}
+}
+
+// threadSize returns how many history entries a conversation holds; the caller
+// holds session.mu.
+func (s *sessionState) threadSize(name string) int {
+	if name == s.activeThread() {
+		return len(s.history)
+	}
+	if t := s.threads[name]; t != nil {
+		return len(t.history)
+	}
+	return 0
+}
+
+// handleChat keeps several named conversations in one chat:
+//
+//	/chat                  lists them
+//	/chat new <name>       starts an empty one and switches to it
+//	/chat switch <name>    returns to one
+//	/chat delete <name>    deletes one that is not active
+func (a *App) handleChat(c tele.Context) error {
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	reply := func(body string) error {
+		_, err := a.sendWithFallback(c.Chat(), body, &tele.SendOptions{ThreadID: messageTopic(c.Message()), DisableWebPagePreview: true})
+		return err
+	}
+	command, name, _ := strings.Cut(strings.TrimSpace(c.Message().Payload), " ")
+	command = strings.ToLower(command)
+	name = strings.ToLower(strings.TrimSpace(name))
+	if command != "" && command != "list" && !checkpointName.MatchString(name) {
+		return reply(tr(lang, "chat_usage"))
+	}
+
+	session := a.sessionOf(c.Message())
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	switch command {
+	case "", "list":
+		active := session.activeThread()
+		lines := make([]string, 0, len(session.threads)+1)
+		for _, n := range session.threadNames() {
+			marker := "·"
+			if n == active {
+				marker = "▶"
+			}
+			lines = append(lines, fmt.Sprintf("%s %s · %d", marker, n, session.threadSize(n)))
+		}
+		return reply(tr(lang, "chat_list", strings.Join(lines, "\n")))
+	case "new":
+		if session.hasThread(name) {
+			return reply(tr(lang, "chat_exists", name, name))
+		}
+		if len(session.threads)+1 >= maxThreads {
+			return reply(tr(lang, "chat_too_many", maxThreads))
+		}
+		session.switchThread(name)
+		return reply(tr(lang, "chat_started", name))
+	case "switch":
+		if !session.hasThread(name) {
+			return reply(tr(lang, "chat_unknown", name))
+		}
+		session.switchThread(name)
+		return reply(tr(lang, "chat_switched", name, len(session.history)))
+	case "delete":
+		switch {
+		case name == session.activeThread():
+			return reply(tr(lang, "chat_delete_active"))
+		case !session.hasThread(name):
+			return reply(tr(lang, "chat_unknown", name))
+		}
+		delete(session.threads, name)
+		return reply(tr(lang, "chat_deleted", name))
+	}
+	return reply(tr(lang, "chat_usage"))
+}
@@ -0,0 +1,69 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestChatKeepsSeparateConversations(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	chat := func(payload string) string {
+		t.Helper()
+		msg := testMessage(42, "/chat "+payload)
+		msg.Payload = payload
+		if err := app.handleChat(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("handleChat(%q): %v", payload, err)
+		}
+		texts := apis.sentTexts()
+		return texts[len(texts)-1]
+	}
+	ask := func(prompt string) {
+		t.Helper()
+		apis.reply = "Answer to " + prompt
+		if err := app.processMessage(context.Background(), testMessage(42, prompt), turnOptions{}); err != nil {
+			t.Fatalf("processMessage: %v", err)
+		}
+	}
+	lastPrompt := func() string {
+		t.Helper()
+		calls := apis.callsTo(geminiHost, ":generateContent")
+		return string(calls[len(calls)-1].body)
+	}
+
+	ask("Review my pull request.")
+	if got := chat("new personal"); !strings.Contains(got, "Started the conversation personal") {
+		t.Fatalf("new answered %q", got)
+	}
+	ask("Suggest a birthday gift.")
+	if strings.Contains(lastPrompt(), "pull request") {
+		t.Error("the new conversation saw the previous one")
+	}
+	if got := chat("new main"); !strings.Contains(got, "already a conversation main") {
+		t.Errorf("duplicate name answered %q", got)
+	}
+	if got := chat("switch main"); !strings.Contains(got, "main with 2 messages") {
+		t.Fatalf("switch answered %q", got)
+	}
+	ask("Any more comments?")
+	if body := lastPrompt(); !strings.Contains(body, "pull request") || strings.Contains(body, "birthday") {
+		t.Errorf("main conversation request: %s", body)
+	}
+	if got := chat("list"); !strings.Contains(got, "main · 4") || !strings.Contains(got, "personal · 2") {
+		t.Errorf("list answered %q", got)
+	}
+	if got := chat("delete main"); !strings.Contains(got, "before deleting") {
+		t.Errorf("deleting the active one answered %q", got)
+	}
+	if got := chat("delete personal"); !strings.Contains(got, "personal deleted") {
+		t.Errorf("delete answered %q", got)
+	}
+	if got := chat("switch personal"); !strings.Contains(got, "no conversation personal") {
+		t.Errorf("switch to a deleted one answered %q", got)
+	}
+	if got := chat("new two words"); !strings.Contains(got, "Usage") {
+		t.Errorf("bad name answered %q", got)
+	}
+}
@@ -0,0 +1,174 @@
+package app
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const selectToneUnique = "set_tone"
+
+// tone is how casual the replies of a chat are. Communities disagree on emoji
+// and rough language, so the professional tone also filters replies instead of
+// trusting the prompt alone.
+type tone string
+
+const (
+	toneProfessional tone = "professional"
+	toneNeutral      tone = "neutral"
+	tonePlayful      tone = "playful"
+)
+
+func parseTone(v string) (tone, bool) {
+	switch tone(strings.ToLower(strings.TrimSpace(v))) {
+	case toneProfessional:
+		return toneProfessional, true
+	case toneNeutral:
+		return toneNeutral, true
+	case tonePlayful:
+		return tonePlayful, true
+	}
+	return "", false
+}
+
+func (t tone) instruction() string {
+	switch t {
+	case toneProfessional:
+		return "Keep a strictly professional tone: no emoji, emoticons, slang, jokes or profanity."
+	case tonePlayful:
+		return "Use a playful, upbeat tone and liven up replies with fitting emoji, without letting them crowd out the content."
+	default:
+		return ""
+	}
+}
+
+func (t tone) label(lang language) string {
+	switch t {
+	case toneProfessional:
+		return tr(lang, "tone_professional")
+	case tonePlayful:
+		return tr(lang, "tone_playful")
+	default:
+		return tr(lang, "tone_neutral")
+	}
+}
+
+// filter enforces the tone on a reply the model wrote. Only the professional
+// tone changes anything: it drops emoji and masks profanity outside code
+// blocks.
+func (t tone) filter(reply string) string {
+	if t != toneProfessional {
+		return reply
+	}
+	parts := strings.Split(reply, "```")
+	for i := 0; i < len(parts); i += 2 {
+		parts[i] = maskProfanity(stripEmoji(parts[i]))
+	}
+	return strings.Join(parts, "```")
+}
+
+// isEmoji reports whether r is a pictograph, a symbol drawn as emoji or a
+// regional indicator.
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF, r >= 0x2600 && r <= 0x27BF, r >= 0x2B00 && r <= 0x2BFF:
+		return true
+	case r == 0x203C, r == 0x2049, r == 0x231A, r == 0x231B, r == 0x23F0, r == 0x23F3:
+		return true
+	}
+	return false
+}
+
+var (
+	spaceBeforePunct = regexp.MustCompile(`[ \t]+([.,!?;:)])`)
+	innerSpaces      = regexp.MustCompile(`(\S)[ \t]{2,}`)
+	trailingSpaces   = regexp.MustCompile(`(?m)[ \t]+$`)
+)
+
+// stripEmoji removes emoji with their modifiers and joiners and tidies the
+// spaces they leave behind.
+func stripEmoji(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	dropping, changed := false, false
+	for _, r := range text {
+		switch {
+		case isEmoji(r):
+			dropping, changed = true, true
+			continue
+		case r == 0xFE0F || r == 0x20E3:
+			changed = true
+			continue
+		case r == 0x200D && dropping:
+			continue
+		}
+		dropping = false
+		b.WriteRune(r)
+	}
+	if !changed {
+		return text
+	}
+	out := spaceBeforePunct.ReplaceAllString(b.String(), "$1")
+	out = innerSpaces.ReplaceAllString(out, "$1 ")
+	return trailingSpaces.ReplaceAllString(out, "")
+}
+
+var profanity = regexp.MustCompile(`(?i)\b(fuck(?:ing|ed|er|s)?|motherfuck\w*|shit(?:ty|s)?|bullshit|asshole(?:s)?|bitch(?:es)?|bastard(?:s)?|damn(?:ed|it)?|crap(?:py)?|piss(?:ed)?)\b`)
+
+// maskProfanity keeps the first letter of a swear word and stars the rest.
+func maskProfanity(text string) string {
+	return profanity.ReplaceAllStringFunc(text, func(word string) string {
+		first, size := utf8.DecodeRuneInString(word)
+		return string(first) + strings.Repeat("*", utf8.RuneCountInString(word[size:]))
+	})
+}
+
+func (a *App) handleTone(c tele.Context) error {
+	session := a.sessionOf(c.Message())
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	thread := messageTopic(c.Message())
+
+	if t, ok := parseTone(c.Message().Payload); ok {
+		session.mu.Lock()
+		session.tone = t
+		session.mu.Unlock()
+		_, err := a.sendWithFallback(c.Chat(), tr(lang, "tone_set", t.label(lang)), &tele.SendOptions{ThreadID: thread, DisableWebPagePreview: true})
+		return err
+	}
+
+	session.mu.Lock()
+	current := session.currentTone()
+	session.mu.Unlock()
+
+	menu := &tele.ReplyMarkup{}
+	menu.Inline(menu.Row(
+		menu.Data(toneProfessional.label(lang), selectToneUnique, string(toneProfessional)),
+		menu.Data(toneNeutral.label(lang), selectToneUnique, string(toneNeutral)),
+		menu.Data(tonePlayful.label(lang), selectToneUnique, string(tonePlayful)),
+	))
+	body := tr(lang, "tone_current", current.label(lang))
+	_, err := a.sendWithFallback(c.Chat(), body, &tele.SendOptions{ReplyMarkup: menu, ThreadID: thread, DisableWebPagePreview: true})
+	return err
+}
+
+func (a *App) handleToneSelection(c tele.Context) error {
+	if err := c.Respond(); err != nil {
+		slog.Warn("callback acknowledge failed", "err", err)
+	}
+
+	t, ok := parseTone(c.Callback().Data)
+	if !ok {
+		return nil
+	}
+	session := a.sessionOf(c.Message())
+	session.mu.Lock()
+	session.tone = t
+	session.mu.Unlock()
+
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	_, err := a.sendWithFallback(c.Chat(), tr(lang, "tone_set", t.label(lang)), &tele.SendOptions{ThreadID: messageTopic(c.Message()), DisableWebPagePreview: true})
+	return err
+}
@@ -0,0 +1,53 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestToneFilter(t *testing.T) {
+	cases := []struct {
+		tone     tone
+		in, want string
+	}{
+		{toneProfessional, "Done 🎉! The 👨‍💻 team shipped it ✅.", "Done! The team shipped it."},
+		{toneProfessional, "That damn bug is fixed, no more bullshit.", "That d*** bug is fixed, no more b*******."},
+		{toneProfessional, "Keep ```\nprint(\"🎉 damn\")\n``` as is 🙂", "Keep ```\nprint(\"🎉 damn\")\n``` as is"},
+		{toneProfessional, "Scunthorpe and Dickens stay.", "Scunthorpe and Dickens stay."},
+		{toneNeutral, "Done 🎉!", "Done 🎉!"},
+		{tonePlayful, "Done 🎉!", "Done 🎉!"},
+	}
+	for _, c := range cases {
+		if got := c.tone.filter(c.in); got != c.want {
+			t.Errorf("%s.filter(%q) = %q, want %q", c.tone, c.in, got, c.want)
+		}
+	}
+}
+
+func TestToneShapesTurns(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	msg := testMessage(42, "/tone professional")
+	msg.Payload = "professional"
+	if err := app.handleTone(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+		t.Fatalf("handleTone: %v", err)
+	}
+	if texts := apis.sentTexts(); !strings.Contains(texts[len(texts)-1], "Professional") {
+		t.Errorf("tone answered %q", texts[len(texts)-1])
+	}
+
+	apis.reply = "Great question 🎉! That damn bug is fixed."
+	if err := app.processMessage(context.Background(), testMessage(42, "Is it fixed?"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	calls := apis.callsTo(geminiHost, ":generateContent")
+	if body := string(calls[len(calls)-1].body); !strings.Contains(body, "strictly professional") {
+		t.Error("tone instruction missing from the request")
+	}
+	texts := apis.sentTexts()
+	if reply := texts[len(texts)-1]; strings.Contains(reply, "🎉") || strings.Contains(reply, "damn") || !strings.Contains(reply, "bug is fixed") {
+		t.Errorf("reply not filtered: %q", reply)
+	}
+}
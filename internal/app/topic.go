@@ -0,0 +1,305 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+// topicTools are the tools a forum topic can allow, in display order. "repo"
+// is the knowledge base: the repository indexed with /repo and the documents
+// added with /kb.
+var topicTools = []string{"search", "urls", "code", "functions", "repo"}
+
+// topicProfile overrides the persona and the tools of the chat within one
+// forum topic. An empty persona keeps the chat persona and nil tools keep all.
+type topicProfile struct {
+	Persona string   `json:"persona,omitempty"`
+	Tools   []string `json:"tools"`
+}
+
+func (p topicProfile) isZero() bool {
+	return p.Persona == "" && p.Tools == nil
+}
+
+// allows reports whether the topic offers tool.
+func (p topicProfile) allows(tool string) bool {
+	return p.Tools == nil || slices.Contains(p.Tools, tool)
+}
+
+// restrict removes the tools the topic does not allow from cfg.
+func (p topicProfile) restrict(cfg *genai.GenerateContentConfig) {
+	if p.Tools == nil {
+		return
+	}
+	var tools []*genai.Tool
+	for _, tool := range cfg.Tools {
+		if len(tool.FunctionDeclarations) > 0 {
+			if p.allows("functions") {
+				tools = append(tools, tool)
+			}
+			continue
+		}
+		kept := &genai.Tool{}
+		if tool.GoogleSearch != nil && p.allows("search") {
+			kept.GoogleSearch = tool.GoogleSearch
+		}
+		if tool.URLContext != nil && p.allows("urls") {
+			kept.URLContext = tool.URLContext
+		}
+		if tool.CodeExecution != nil && p.allows("code") {
+			kept.CodeExecution = tool.CodeExecution
+		}
+		if kept.GoogleSearch != nil || kept.URLContext != nil || kept.CodeExecution != nil {
+			tools = append(tools, kept)
+		}
+	}
+	cfg.Tools = tools
+}
+
+func (p topicProfile) describe(lang language) string {
+	persona := p.Persona
+	if persona == "" {
+		persona = tr(lang, "topic_default")
+	}
+	tools := tr(lang, "topic_default")
+	switch {
+	case p.Tools == nil:
+	case len(p.Tools) == 0:
+		tools = "none"
+	default:
+		tools = strings.Join(p.Tools, ", ")
+	}
+	return tr(lang, "topic_current", persona, tools)
+}
+
+// parseTopicTools reads "all", "none" or a comma or space separated list of
+// topicTools.
+func parseTopicTools(value string) ([]string, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "all":
+		return nil, nil
+	case "none":
+		return []string{}, nil
+	}
+	tools := []string{}
+	for _, name := range strings.FieldsFunc(strings.ToLower(value), func(r rune) bool { return r == ',' || r == ' ' }) {
+		if !slices.Contains(topicTools, name) {
+			return nil, fmt.Errorf("unknown tool %q", name)
+		}
+		if !slices.Contains(tools, name) {
+			tools = append(tools, name)
+		}
+	}
+	if len(tools) == 0 {
+		return nil, errors.New("no tools named")
+	}
+	return tools, nil
+}
+
+// topicStore keeps the profiles of forum topics across restarts in a JSON
+// file, keyed by chat and thread. Every change rewrites the file atomically.
+type topicStore struct {
+	mu     sync.Mutex
+	path   string
+	topics map[string]topicProfile
+}
+
+func topicKey(chatID int64, threadID int) string {
+	return fmt.Sprintf("%d:%d", chatID, threadID)
+}
+
+func openTopicStore(path string) (*topicStore, error) {
+	s := &topicStore{path: path, topics: make(map[string]topicProfile)}
+	if err := readJSONFile(path, &s.topics); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// get returns the profile of a topic; messages outside topics have none.
+func (s *topicStore) get(chatID int64, threadID int) topicProfile {
+	if threadID == 0 {
+		return topicProfile{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.topics[topicKey(chatID, threadID)]
+}
+
+// update changes the profile of a topic with fn and saves it.
+func (s *topicStore) update(chatID int64, threadID int, fn func(*topicProfile)) (topicProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := topicKey(chatID, threadID)
+	profile := s.topics[key]
+	fn(&profile)
+	if profile.isZero() {
+		delete(s.topics, key)
+	} else {
+		s.topics[key] = profile
+	}
+	return profile, s.saveLocked()
+}
+
+// saveLocked writes the profiles; the caller holds s.mu.
+func (s *topicStore) saveLocked() error {
+	return writeJSONFile(s.path, s.topics)
+}
+
+// purgeChat removes the profiles of every topic of chatID.
+func (s *topicStore) purgeChat(chatID int64) error {
+	prefix := fmt.Sprintf("%d:", chatID)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := false
+	for key := range s.topics {
+		if strings.HasPrefix(key, prefix) {
+			delete(s.topics, key)
+			removed = true
+		}
+	}
+	if !removed {
+		return nil
+	}
+	return s.saveLocked()
+}
+
+// migrate moves the topic profiles of chat from to chat to.
+func (s *topicStore) migrate(from, to int64) error {
+	prefix := fmt.Sprintf("%d:", from)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	moved := false
+	for key, profile := range s.topics {
+		if thread, ok := strings.CutPrefix(key, prefix); ok {
+			delete(s.topics, key)
+			s.topics[fmt.Sprintf("%d:%s", to, thread)] = profile
+			moved = true
+		}
+	}
+	if !moved {
+		return nil
+	}
+	return s.saveLocked()
+}
+
+// messageTopic returns the forum topic a message was posted in, or zero.
+func messageTopic(msg *tele.Message) int {
+	if msg == nil || !msg.TopicMessage {
+		return 0
+	}
+	return msg.ThreadID
+}
+
+// topicChat is a chat as the recipient of messages that belong in one of its
+// forum topics. botSend posts them there unless the options name a topic.
+type topicChat struct {
+	*tele.Chat
+	thread int
+}
+
+// inTopic returns chat as the recipient of the answers to msg: the forum
+// topic msg was posted in, or the chat itself outside topics.
+func inTopic(chat *tele.Chat, msg *tele.Message) tele.Recipient {
+	return chatTopic(chat, messageTopic(msg))
+}
+
+// chatTopic returns chat as the recipient of messages for its forum topic
+// thread, or the chat itself when thread is zero.
+func chatTopic(chat *tele.Chat, thread int) tele.Recipient {
+	if thread != 0 && chat != nil {
+		return topicChat{Chat: chat, thread: thread}
+	}
+	return chat
+}
+
+// topicOptions returns opts with the forum topic of recipient, if it has one
+// and opts name none. opts is not changed.
+func topicOptions(recipient tele.Recipient, opts []any) []any {
+	topic, ok := recipient.(topicChat)
+	if !ok {
+		return opts
+	}
+	out := make([]any, 0, len(opts)+1)
+	found := false
+	for _, opt := range opts {
+		if send, ok := opt.(*tele.SendOptions); ok && send != nil {
+			found = true
+			if send.ThreadID == 0 {
+				cloned := *send
+				cloned.ThreadID = topic.thread
+				opt = &cloned
+			}
+		}
+		out = append(out, opt)
+	}
+	if !found {
+		out = append(out, &tele.SendOptions{ThreadID: topic.thread})
+	}
+	return out
+}
+
+// sessionOf returns the session of the conversation msg belongs to: its forum
+// topic's own session in a forum, the chat's otherwise.
+func (a *App) sessionOf(msg *tele.Message) *sessionState {
+	return a.sessions.topic(msg.Chat.ID, messageTopic(msg))
+}
+
+// handleTopic shows or changes the profile of the forum topic it is sent in.
+// Group admins may change it with "persona <text>", "tools <list>" or "reset".
+func (a *App) handleTopic(c tele.Context) error {
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	thread := messageTopic(c.Message())
+	reply := func(body string) error {
+		_, err := a.sendWithFallback(c.Chat(), body, &tele.SendOptions{ThreadID: thread, DisableWebPagePreview: true})
+		return err
+	}
+	if thread == 0 {
+		return reply(tr(lang, "topic_not_forum"))
+	}
+
+	payload := strings.TrimSpace(c.Message().Payload)
+	if payload == "" {
+		return reply(a.topics.get(c.Chat().ID, thread).describe(lang))
+	}
+	if !a.isChatAdmin(c) {
+		return reply(tr(lang, "topic_admins_only"))
+	}
+
+	action, value, _ := strings.Cut(payload, " ")
+	value = strings.TrimSpace(value)
+	var change func(*topicProfile)
+	switch strings.ToLower(action) {
+	case "persona":
+		if n := utf8.RuneCountInString(value); n > maxPersonaLength {
+			return reply(tr(lang, "topic_too_long", n, maxPersonaLength))
+		}
+		if strings.EqualFold(value, "default") {
+			value = ""
+		}
+		change = func(p *topicProfile) { p.Persona = value }
+	case "tools":
+		tools, err := parseTopicTools(value)
+		if err != nil {
+			return reply(tr(lang, "topic_usage", strings.Join(topicTools, ", ")))
+		}
+		change = func(p *topicProfile) { p.Tools = tools }
+	case "reset":
+		change = func(p *topicProfile) { *p = topicProfile{} }
+	default:
+		return reply(tr(lang, "topic_usage", strings.Join(topicTools, ", ")))
+	}
+
+	profile, err := a.topics.update(c.Chat().ID, thread, change)
+	if err != nil {
+		a.chatLog(c.Chat().ID).Warn("save topic settings failed", "chat_id", c.Chat().ID, "thread_id", thread, "err", err)
+	}
+	return reply(profile.describe(lang))
+}
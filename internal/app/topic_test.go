@@ -0,0 +1,168 @@
+package app
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+// topicMessage is a message from a user in topic 7 of a forum group.
+func topicMessage(text string) *tele.Message {
+	msg := testMessage(-100, text)
+	msg.Chat.Type = tele.ChatSuperGroup
+	msg.ThreadID = 7
+	msg.TopicMessage = true
+	return msg
+}
+
+func TestTopicProfileShapesTurns(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	topic := func(payload string, admin bool) {
+		t.Helper()
+		msg := topicMessage("/topic " + payload)
+		msg.Payload = payload
+		if admin {
+			msg.SenderChat = msg.Chat
+		}
+		if err := app.handleTopic(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("handleTopic(%q): %v", payload, err)
+		}
+	}
+
+	topic("persona Answer as a formal support agent.", false)
+	if texts := apis.sentTexts(); !strings.Contains(texts[len(texts)-1], "Only group admins") {
+		t.Fatalf("non-admin change answered %q", texts[len(texts)-1])
+	}
+	topic("persona Answer as a formal support agent.", true)
+	topic("tools code, functions", true)
+	topic("tools browse", true)
+	if texts := apis.sentTexts(); !strings.Contains(texts[len(texts)-1], "Usage") {
+		t.Errorf("unknown tool answered %q, want the usage", texts[len(texts)-1])
+	}
+
+	if err := app.processMessage(context.Background(), topicMessage("How do I reset my password?"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	calls := apis.callsTo(geminiHost, ":generateContent")
+	body := string(calls[len(calls)-1].body)
+	if !strings.Contains(body, "formal support agent") {
+		t.Error("topic persona missing from the request")
+	}
+	if strings.Contains(body, "googleSearch") || !strings.Contains(body, "codeExecution") {
+		t.Errorf("request tools not restricted to the topic: %s", body)
+	}
+	sent := apis.callsTo(telegramHost, "sendMessage")
+	if reply := string(sent[len(sent)-1].body); !strings.Contains(reply, `"message_thread_id":"7"`) {
+		t.Errorf("reply not sent to the topic: %s", reply)
+	}
+
+	reopened, err := openTopicStore(filepath.Join(app.dataDir, "topics.json"))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if got := reopened.get(-100, 7); got.Persona == "" || len(got.Tools) != 2 {
+		t.Errorf("persisted profile = %+v", got)
+	}
+	if got := reopened.get(-100, 8); !got.isZero() {
+		t.Errorf("other topic profile = %+v, want none", got)
+	}
+
+	topic("reset", true)
+	if got := app.topics.get(-100, 7); !got.isZero() {
+		t.Errorf("after reset profile = %+v", got)
+	}
+}
+
+func TestTopicsKeepSeparateConversations(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	chat := app.sessions.get(-100)
+	chat.mu.Lock()
+	chat.persona = "Answer like a pirate."
+	chat.mu.Unlock()
+
+	ask := func(msg *tele.Message) string {
+		t.Helper()
+		if err := app.processMessage(context.Background(), msg, turnOptions{}); err != nil {
+			t.Fatalf("processMessage: %v", err)
+		}
+		calls := apis.callsTo(geminiHost, ":generateContent")
+		return string(calls[len(calls)-1].body)
+	}
+	ask(topicMessage("Remember the word walrus."))
+	other := topicMessage("Which word did I ask you to remember?")
+	other.ThreadID = 8
+	if body := ask(other); strings.Contains(body, "walrus") {
+		t.Errorf("topic 8 request carries the history of topic 7: %s", body)
+	} else if !strings.Contains(body, "pirate") {
+		t.Error("topic session did not start with the chat's persona")
+	}
+	if body := ask(topicMessage("And again?")); !strings.Contains(body, "walrus") {
+		t.Errorf("topic 7 lost its history: %s", body)
+	}
+	sent := apis.callsTo(telegramHost, "sendMessage")
+	if reply := string(sent[len(sent)-1].body); !strings.Contains(reply, `"message_thread_id":"7"`) {
+		t.Errorf("reply not sent to the topic: %s", reply)
+	}
+
+	chat.mu.Lock()
+	entries := len(chat.history)
+	chat.mu.Unlock()
+	if entries != 0 {
+		t.Errorf("chat session has %d history entries, want none", entries)
+	}
+	if got := app.sessions.count(); got != 3 {
+		t.Errorf("sessions = %d, want the chat and two topics", got)
+	}
+	app.sessions.migrate(-100, -200)
+	app.sessions.remove(-200)
+	if got := app.sessions.count(); got != 0 {
+		t.Errorf("after remove sessions = %d, want 0", got)
+	}
+}
+
+func TestParseTopicTools(t *testing.T) {
+	if tools, err := parseTopicTools("all"); err != nil || tools != nil {
+		t.Errorf("all = %v, %v; want nil", tools, err)
+	}
+	if tools, err := parseTopicTools("none"); err != nil || tools == nil || len(tools) != 0 {
+		t.Errorf("none = %v, %v; want empty", tools, err)
+	}
+	if tools, err := parseTopicTools("Search,repo search"); err != nil || strings.Join(tools, ",") != "search,repo" {
+		t.Errorf("list = %v, %v", tools, err)
+	}
+	if _, err := parseTopicTools(""); err == nil {
+		t.Error("empty list accepted")
+	}
+}
+
+func TestTopicCommandsAndButtonsAnswerInTheTopic(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	for name, handler := range map[string]tele.HandlerFunc{"/usage": app.handleUsage, "/settings": app.handleSettings} {
+		if err := handler(app.bot.NewContext(tele.Update{Message: topicMessage(name)})); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+	// A button press carries the bot's reply, which sits in the topic.
+	reply := topicMessage("")
+	reply.Sender = &tele.User{ID: 1, IsBot: true}
+	callback := &tele.Callback{Sender: &tele.User{ID: -100}, Message: reply, Data: "missing"}
+	if err := app.handleShowSources(app.bot.NewContext(tele.Update{Callback: callback})); err != nil {
+		t.Fatalf("handleShowSources: %v", err)
+	}
+	if err := app.handleShowCode(app.bot.NewContext(tele.Update{Callback: callback})); err != nil {
+		t.Fatalf("handleShowCode: %v", err)
+	}
+
+	sent := apis.callsTo(telegramHost, "sendMessage")
+	if len(sent) != 4 {
+		t.Fatalf("sent %d messages, want the usage, the menu and the two button answers", len(sent))
+	}
+	for _, call := range sent {
+		if !strings.Contains(string(call.body), `"message_thread_id":"7"`) {
+			t.Errorf("message not sent to the topic: %s", call.body)
+		}
+	}
+}
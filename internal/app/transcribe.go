@@ -0,0 +1,249 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const transcribeCommand = "/transcribe"
+
+// transcribeInlineLimit is the longest transcript sent as a message; longer
+// ones arrive as .txt and .srt documents.
+const transcribeInlineLimit = 3500
+
+const transcribeInstruction = "Transcribe the recording word for word in the language spoken; do not translate, " +
+	"summarize or correct it. Start a new segment at every change of speaker and at least every 15 seconds, " +
+	"with its start and end in seconds from the beginning. Label the speakers Speaker 1, Speaker 2 and so on in " +
+	"the order they first speak, or by name once they introduce themselves. Mark inaudible passages as [inaudible]. " +
+	"Return no segments when nobody speaks."
+
+func transcriptSchema() *genai.Schema {
+	return &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"segments": {
+				Type: genai.TypeArray,
+				Items: &genai.Schema{
+					Type: genai.TypeObject,
+					Properties: map[string]*genai.Schema{
+						"start":   {Type: genai.TypeNumber},
+						"end":     {Type: genai.TypeNumber},
+						"speaker": {Type: genai.TypeString},
+						"text":    {Type: genai.TypeString},
+					},
+					Required:         []string{"start", "end", "speaker", "text"},
+					PropertyOrdering: []string{"start", "end", "speaker", "text"},
+				},
+			},
+		},
+		Required: []string{"segments"},
+	}
+}
+
+type transcriptSegment struct {
+	Start   float64 `json:"start"`
+	End     float64 `json:"end"`
+	Speaker string  `json:"speaker"`
+	Text    string  `json:"text"`
+}
+
+// recording returns the voice note, audio, video or audio and video document
+// of msg with its MIME type.
+func recording(msg *tele.Message) (*tele.File, string, bool) {
+	switch {
+	case msg == nil:
+	case msg.Voice != nil:
+		return msg.Voice.MediaFile(), msg.Voice.MIME, true
+	case msg.Audio != nil:
+		return msg.Audio.MediaFile(), msg.Audio.MIME, true
+	case msg.Video != nil:
+		return msg.Video.MediaFile(), msg.Video.MIME, true
+	case msg.VideoNote != nil:
+		return msg.VideoNote.MediaFile(), "video/mp4", true
+	case msg.Document != nil && (strings.HasPrefix(msg.Document.MIME, "audio/") || strings.HasPrefix(msg.Document.MIME, "video/")):
+		return msg.Document.MediaFile(), msg.Document.MIME, true
+	}
+	return nil, "", false
+}
+
+// handleTranscribe returns the full transcript of a recording with speakers
+// and timestamps:
+//
+//	/transcribe [srt] as a reply to a voice note, audio or video
+//	a recording captioned /transcribe [srt]
+//
+// With srt the transcript always arrives as subtitles.
+func (a *App) handleTranscribe(c tele.Context) error {
+	msg := c.Message()
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	payload := msg.Payload
+	if _, rest := captionCommand(msg); rest != "" {
+		payload = rest
+	}
+	subtitles := strings.EqualFold(strings.TrimSpace(payload), "srt")
+
+	source := msg
+	if _, _, ok := recording(source); !ok {
+		source = msg.ReplyTo
+	}
+	if _, _, ok := recording(source); !ok {
+		_, err := a.sendWithFallback(c.Chat(), tr(lang, "transcribe_usage"), &tele.SendOptions{ThreadID: messageTopic(msg), DisableWebPagePreview: true})
+		return err
+	}
+	return a.enqueueJob(msg, func(ctx context.Context) error {
+		return a.transcribe(ctx, msg, source, subtitles)
+	})
+}
+
+// transcribe sends the transcript of the recording in source to the chat of
+// msg: as a message when it is short, else as .txt and .srt documents.
+func (a *App) transcribe(ctx context.Context, msg, source *tele.Message, subtitles bool) error {
+	lang := a.chatLanguage(msg.Chat, msg.Sender)
+	opts := &tele.SendOptions{ThreadID: messageTopic(msg), ReplyTo: source, DisableWebPagePreview: true}
+	fail := func(key string) error {
+		_, err := a.sendWithFallback(msg.Chat, tr(lang, key), opts)
+		return err
+	}
+
+	file, mimeType, _ := recording(source)
+	ctx, cancel := context.WithTimeout(withChatID(ctx, msg.Chat.ID), 5*time.Minute)
+	defer cancel()
+	part, err := a.partFromFile(ctx, file, mimeType)
+	if err != nil {
+		logFrom(ctx).Warn("read recording failed", "err", err)
+		if errors.Is(err, errFileTooLarge) {
+			return fail("file_too_large")
+		}
+		return fail("input_failed")
+	}
+	cfg := &genai.GenerateContentConfig{
+		SystemInstruction: genai.NewContentFromText(transcribeInstruction, genai.Role("system")),
+		ResponseMIMEType:  "application/json",
+		ResponseSchema:    transcriptSchema(),
+		Temperature:       genai.Ptr[float32](0),
+	}
+	contents := []*genai.Content{genai.NewContentFromParts([]*genai.Part{part, genai.NewPartFromText("Transcribe this recording.")}, genai.RoleUser)}
+	resp, model, err := a.generate(ctx, contents, cfg)
+	if err != nil {
+		logFrom(ctx).Warn("transcribe failed", "err", err)
+		return fail("transcribe_failed")
+	}
+	a.recordUsage(ctx, msg.Chat.ID, model, resp.UsageMetadata)
+	var result struct {
+		Segments []transcriptSegment `json:"segments"`
+	}
+	if err := json.Unmarshal([]byte(resp.Text()), &result); err != nil {
+		logFrom(ctx).Warn("decode transcript failed", "err", err)
+		return fail("transcribe_failed")
+	}
+	segments := cleanSegments(result.Segments)
+	if len(segments) == 0 {
+		return fail("transcribe_empty")
+	}
+
+	text := transcriptText(segments)
+	base := strings.TrimSuffix(transcriptName(source), filepath.Ext(transcriptName(source)))
+	if subtitles {
+		return a.sendTextDocument(msg.Chat, base+".srt", "application/x-subrip", transcriptSRT(segments), "", opts)
+	}
+	if len(text) <= transcribeInlineLimit {
+		_, err := a.sendWithFallback(msg.Chat, text, opts)
+		return err
+	}
+	if err := a.sendTextDocument(msg.Chat, base+".txt", "text/plain", text, tr(lang, "transcribe_caption"), opts); err != nil {
+		return err
+	}
+	return a.sendTextDocument(msg.Chat, base+".srt", "application/x-subrip", transcriptSRT(segments), "", opts)
+}
+
+// cleanSegments drops empty segments and keeps the times in order: an end
+// before its start becomes the next start, or the start itself.
+func cleanSegments(segments []transcriptSegment) []transcriptSegment {
+	var out []transcriptSegment
+	for _, s := range segments {
+		s.Text, s.Speaker = strings.TrimSpace(s.Text), strings.TrimSpace(s.Speaker)
+		if s.Text == "" {
+			continue
+		}
+		s.Start = max(s.Start, 0)
+		if n := len(out); n > 0 {
+			s.Start = max(s.Start, out[n-1].Start)
+			if out[n-1].End <= out[n-1].Start {
+				out[n-1].End = s.Start
+			}
+		}
+		out = append(out, s)
+	}
+	if n := len(out); n > 0 && out[n-1].End < out[n-1].Start {
+		out[n-1].End = out[n-1].Start
+	}
+	return out
+}
+
+// transcriptText writes a line per segment with its start time, naming the
+// speaker when it changes.
+func transcriptText(segments []transcriptSegment) string {
+	var b strings.Builder
+	speaker := ""
+	for i, s := range segments {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "[%s] ", clockTime(s.Start))
+		if s.Speaker != "" && s.Speaker != speaker {
+			b.WriteString(s.Speaker + ": ")
+			speaker = s.Speaker
+		}
+		b.WriteString(s.Text)
+	}
+	return b.String()
+}
+
+// transcriptSRT writes the segments as SubRip subtitles.
+func transcriptSRT(segments []transcriptSegment) string {
+	var b strings.Builder
+	for i, s := range segments {
+		text := s.Text
+		if s.Speaker != "" {
+			text = s.Speaker + ": " + text
+		}
+		fmt.Fprintf(&b, "%d\n%s --> %s\n%s\n\n", i+1, srtTime(s.Start), srtTime(s.End), text)
+	}
+	return b.String()
+}
+
+// clockTime formats seconds as m:ss, or h:mm:ss from an hour on.
+func clockTime(seconds float64) string {
+	total := int(seconds)
+	if total >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", total/3600, total/60%60, total%60)
+	}
+	return fmt.Sprintf("%d:%02d", total/60, total%60)
+}
+
+// srtTime formats seconds as hh:mm:ss,mmm.
+func srtTime(seconds float64) string {
+	ms := int64(seconds*1000 + 0.5)
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// transcriptName names the transcript after the recording's file.
+func transcriptName(msg *tele.Message) string {
+	switch {
+	case msg.Audio != nil && msg.Audio.FileName != "":
+		return msg.Audio.FileName
+	case msg.Video != nil && msg.Video.FileName != "":
+		return msg.Video.FileName
+	case msg.Document != nil && msg.Document.FileName != "":
+		return msg.Document.FileName
+	}
+	return "transcript"
+}
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const testTranscript = `{"segments":[
+	{"start":0,"end":4.2,"speaker":"Speaker 1","text":"Hi, are we still on for Friday?"},
+	{"start":4.2,"end":7,"speaker":"Speaker 2","text":"Yes, at ten."},
+	{"start":7,"end":9.5,"speaker":"Speaker 2","text":"I will bring the slides."}]}`
+
+func TestTranscribeRepliesWithTimestamps(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.files = map[string]string{"voice": "OggS"}
+	apis.answer = func(string, []byte) []any { return []any{map[string]any{"text": testTranscript}} }
+
+	voice := testMessage(42, "")
+	voice.Voice = &tele.Voice{File: tele.File{FileID: "voice", UniqueID: "voice"}, MIME: "audio/ogg", Duration: 10}
+	msg := testMessage(42, "/transcribe")
+	msg.ReplyTo = voice
+	if err := app.handleTranscribe(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+		t.Fatalf("handleTranscribe: %v", err)
+	}
+	app.queue.drain(context.Background())
+
+	if calls := apis.callsTo(geminiHost, ":generateContent"); len(calls) != 1 || !strings.Contains(string(calls[0].body), "word for word") {
+		t.Fatalf("generateContent calls = %d, want one transcription request", len(calls))
+	}
+	texts := apis.sentTexts()
+	if len(texts) != 1 {
+		t.Fatalf("sent %q, want the transcript", texts)
+	}
+	for _, want := range []string{`\[0:00\] Speaker 1: Hi`, `\[0:04\] Speaker 2: Yes`, `\[0:07\] I will bring`} {
+		if !strings.Contains(texts[0], want) {
+			t.Errorf("transcript %q misses %q", texts[0], want)
+		}
+	}
+}
+
+func TestTranscribeCaptionSendsSubtitles(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.files = map[string]string{"call": "ID3"}
+	apis.answer = func(string, []byte) []any { return []any{map[string]any{"text": testTranscript}} }
+
+	msg := testMessage(42, "")
+	msg.Caption = "/transcribe srt"
+	msg.Audio = &tele.Audio{File: tele.File{FileID: "call", UniqueID: "call"}, FileName: "call.mp3", MIME: "audio/mpeg"}
+	if err := app.handleUserMessage(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+		t.Fatalf("handleUserMessage: %v", err)
+	}
+	app.queue.drain(context.Background())
+
+	docs := apis.callsTo(telegramHost, "sendDocument")
+	if len(docs) != 1 {
+		t.Fatalf("sent %d documents, want the subtitles", len(docs))
+	}
+	body := string(docs[0].body)
+	for _, want := range []string{"call.srt", "1\n00:00:00,000 --> 00:00:04,200\nSpeaker 1: Hi", "3\n00:00:07,000 --> 00:00:09,500\nSpeaker 2: I will"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("subtitles miss %q", want)
+		}
+	}
+}
+
+func TestTranscribeWithoutRecording(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	if err := app.handleTranscribe(app.bot.NewContext(tele.Update{Message: testMessage(42, "/transcribe")})); err != nil {
+		t.Fatalf("handleTranscribe: %v", err)
+	}
+	if texts := apis.sentTexts(); len(texts) != 1 || !strings.Contains(texts[0], "voice note") {
+		t.Errorf("sent %q, want the usage", texts)
+	}
+}
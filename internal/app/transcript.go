@@ -0,0 +1,292 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+// transcriptImportLimit bounds the size of a transcript accepted by /import.
+const transcriptImportLimit = 1 << 20
+
+// transcriptFormats are the /export formats with their extension and MIME type.
+var transcriptFormats = map[string]struct{ ext, mime string }{
+	"md":   {".md", "text/markdown"},
+	"json": {".json", "application/json"},
+	"html": {".html", "text/html"},
+}
+
+// chatTranscript is the exported history of a chat. Summary holds the turns
+// that were folded into the running summary and are no longer kept verbatim.
+type chatTranscript struct {
+	ChatID     int64             `json:"chat_id"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Summary    string            `json:"summary,omitempty"`
+	Turns      []transcriptEntry `json:"turns"`
+}
+
+type transcriptEntry struct {
+	Role  string    `json:"role"`
+	Model string    `json:"model,omitempty"`
+	Time  time.Time `json:"time,omitzero"`
+	Text  string    `json:"text"`
+}
+
+// speaker names the author of an entry in the Markdown and HTML transcripts.
+func (e transcriptEntry) speaker() string {
+	if e.Role != string(genai.RoleModel) {
+		return "User"
+	}
+	if e.Model != "" {
+		return "Eteon (" + e.Model + ")"
+	}
+	return "Eteon"
+}
+
+func (e transcriptEntry) stamp() string {
+	if e.Time.IsZero() {
+		return ""
+	}
+	return e.Time.UTC().Format("2006-01-02 15:04 UTC")
+}
+
+// transcript copies the history of a session; the caller holds session.mu.
+func (s *sessionState) transcript(chatID int64) chatTranscript {
+	t := chatTranscript{ChatID: chatID, ExportedAt: time.Now(), Summary: s.summary}
+	for i, content := range s.history {
+		if content == nil {
+			continue
+		}
+		entry := transcriptEntry{Role: string(content.Role), Text: contentText(content)}
+		if entry.Role == "" {
+			entry.Role = string(genai.RoleUser)
+		}
+		if i < len(s.historyMeta) {
+			entry.Time = s.historyMeta[i].at
+			entry.Model = s.historyMeta[i].model
+		}
+		if entry.Text != "" {
+			t.Turns = append(t.Turns, entry)
+		}
+	}
+	return t
+}
+
+func (t chatTranscript) markdown() string {
+	var b strings.Builder
+	b.WriteString("# Conversation\n\n")
+	fmt.Fprintf(&b, "Exported %s.\n\n", t.ExportedAt.UTC().Format("2006-01-02 15:04 UTC"))
+	if t.Summary != "" {
+		fmt.Fprintf(&b, "## Earlier conversation (summary)\n\n%s\n\n", t.Summary)
+	}
+	for _, e := range t.Turns {
+		b.WriteString("## " + e.speaker())
+		if stamp := e.stamp(); stamp != "" {
+			b.WriteString(" · " + stamp)
+		}
+		b.WriteString("\n\n" + e.Text + "\n\n")
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+func (t chatTranscript) json() (string, error) {
+	raw, err := json.MarshalIndent(t, "", "  ")
+	return string(raw) + "\n", err
+}
+
+var transcriptHTML = template.Must(template.New("transcript").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Conversation</title>
+<style>
+body { font-family: sans-serif; max-width: 48rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+.turn { margin: 1rem 0; padding: .75rem 1rem; border-radius: .5rem; background: #f3f3f3; }
+.turn.model { background: #e8f0fe; }
+.meta { font-size: .85rem; color: #555; margin-bottom: .5rem; }
+.text { white-space: pre-wrap; margin: 0; font-family: inherit; }
+</style>
+</head>
+<body>
+<h1>Conversation</h1>
+<p class="meta">Exported {{.Exported}}</p>
+{{if .Summary}}<div class="turn"><div class="meta">Earlier conversation (summary)</div><pre class="text">{{.Summary}}</pre></div>
+{{end}}{{range .Turns}}<div class="turn {{.Role}}"><div class="meta">{{.Speaker}}{{if .Stamp}} · {{.Stamp}}{{end}}</div><pre class="text">{{.Text}}</pre></div>
+{{end}}</body>
+</html>
+`))
+
+func (t chatTranscript) html() (string, error) {
+	type turn struct{ Role, Speaker, Stamp, Text string }
+	data := struct {
+		Exported, Summary string
+		Turns             []turn
+	}{Exported: t.ExportedAt.UTC().Format("2006-01-02 15:04 UTC"), Summary: t.Summary}
+	for _, e := range t.Turns {
+		data.Turns = append(data.Turns, turn{Role: e.Role, Speaker: e.speaker(), Stamp: e.stamp(), Text: e.Text})
+	}
+	var b bytes.Buffer
+	err := transcriptHTML.Execute(&b, data)
+	return b.String(), err
+}
+
+// render returns the transcript in format, one of transcriptFormats.
+func (t chatTranscript) render(format string) (string, error) {
+	switch format {
+	case "json":
+		return t.json()
+	case "html":
+		return t.html()
+	default:
+		return t.markdown(), nil
+	}
+}
+
+// handleExport sends the session history as a Markdown, JSON or HTML document.
+func (a *App) handleExport(c tele.Context) error {
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	format := strings.ToLower(strings.TrimSpace(c.Message().Payload))
+	if format == "" || format == "markdown" {
+		format = "md"
+	}
+	kind, ok := transcriptFormats[format]
+	if !ok {
+		_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), tr(lang, "export_usage"), &tele.SendOptions{DisableWebPagePreview: true})
+		return err
+	}
+
+	session := a.sessionOf(c.Message())
+	session.mu.Lock()
+	t := session.transcript(c.Chat().ID)
+	session.mu.Unlock()
+	if len(t.Turns) == 0 && t.Summary == "" {
+		_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), tr(lang, "export_empty"), &tele.SendOptions{DisableWebPagePreview: true})
+		return err
+	}
+
+	content, err := t.render(format)
+	if err != nil {
+		return fmt.Errorf("render transcript: %w", err)
+	}
+	name := a.exportName(context.Background(), c.Chat().ID, "conversation", kind.ext, t.markdown())
+	return a.sendTextDocument(inTopic(c.Chat(), c.Message()), name, kind.mime, content, "")
+}
+
+// parseTranscript reads a JSON transcript written by /export.
+func parseTranscript(data []byte) (chatTranscript, error) {
+	var t chatTranscript
+	if err := json.Unmarshal(data, &t); err != nil {
+		return t, err
+	}
+	for i, e := range t.Turns {
+		if e.Role != string(genai.RoleUser) && e.Role != string(genai.RoleModel) {
+			return t, fmt.Errorf("turn %d: unknown role %q", i, e.Role)
+		}
+		if strings.TrimSpace(e.Text) == "" {
+			return t, fmt.Errorf("turn %d: empty text", i)
+		}
+	}
+	if len(t.Turns) == 0 && strings.TrimSpace(t.Summary) == "" {
+		return t, errors.New("no turns")
+	}
+	return t, nil
+}
+
+// restore replaces the history of a session with a transcript, passing each
+// entry through mask; the caller holds session.mu.
+func (s *sessionState) restore(t chatTranscript, mask func(*genai.Content) *genai.Content) {
+	s.history = nil
+	s.historyTokens = nil
+	s.historyMeta = nil
+	s.summary = strings.TrimSpace(t.Summary)
+	s.lastTurn = lastTurn{}
+	for _, e := range t.Turns {
+		content := mask(genai.NewContentFromText(e.Text, genai.Role(e.Role)))
+		s.history = append(s.history, content)
+		s.historyTokens = append(s.historyTokens, 0)
+		s.historyMeta = append(s.historyMeta, historyMeta{at: e.Time, model: e.Model})
+	}
+}
+
+// importDocument returns the document /import applies to: the one it was sent
+// as the caption of, or the one it replies to.
+func importDocument(msg *tele.Message) *tele.Document {
+	if msg.Document != nil {
+		return msg.Document
+	}
+	if msg.ReplyTo != nil {
+		return msg.ReplyTo.Document
+	}
+	return nil
+}
+
+// captionCommand returns the command a document or photo was sent with as
+// its caption, without the bot's username, and the rest of the caption.
+func captionCommand(msg *tele.Message) (string, string) {
+	command, rest, _ := strings.Cut(strings.TrimSpace(msg.Caption), " ")
+	command, _, _ = strings.Cut(command, "@")
+	return strings.ToLower(command), strings.TrimSpace(rest)
+}
+
+// isImportCaption reports whether a document was sent with /import as its caption.
+func isImportCaption(msg *tele.Message) bool {
+	if msg.Document == nil {
+		return false
+	}
+	command, _ := captionCommand(msg)
+	return command == "/import"
+}
+
+// handleImport replaces the session history with a JSON transcript from /export.
+func (a *App) handleImport(c tele.Context) error {
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	reply := func(body string) error {
+		_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), body, &tele.SendOptions{DisableWebPagePreview: true})
+		return err
+	}
+	doc := importDocument(c.Message())
+	if doc == nil {
+		return reply(tr(lang, "import_usage"))
+	}
+	if doc.FileSize > transcriptImportLimit {
+		return reply(tr(lang, "import_too_large", transcriptImportLimit>>10))
+	}
+
+	chat, session := c.Chat(), a.sessionOf(c.Message())
+	return a.enqueueJob(c.Message(), func(ctx context.Context) error {
+		reader, err := a.bot.File(&doc.File)
+		if err != nil {
+			logFrom(ctx).Warn("download transcript failed", "err", err)
+			return reply(tr(lang, "input_failed"))
+		}
+		data, err := io.ReadAll(io.LimitReader(reader, transcriptImportLimit+1))
+		reader.Close()
+		if err != nil {
+			logFrom(ctx).Warn("read transcript failed", "err", err)
+			return reply(tr(lang, "input_failed"))
+		}
+		if len(data) > transcriptImportLimit {
+			return reply(tr(lang, "import_too_large", transcriptImportLimit>>10))
+		}
+		t, err := parseTranscript(data)
+		if err != nil {
+			logFrom(ctx).Info("transcript rejected", "err", err)
+			return reply(tr(lang, "import_invalid"))
+		}
+
+		session.mu.Lock()
+		session.restore(t, a.pii.maskContent)
+		session.mu.Unlock()
+		a.trimHistory(ctx, chat.ID, session)
+		return reply(tr(lang, "import_done", len(t.Turns)))
+	})
+}
@@ -0,0 +1,125 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestExportSendsTranscript(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.reply = "Paris is the capital of France."
+	export := func(payload string) {
+		t.Helper()
+		msg := testMessage(42, "/export "+payload)
+		msg.Payload = payload
+		if err := app.handleExport(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+			t.Fatalf("handleExport(%q): %v", payload, err)
+		}
+	}
+
+	export("")
+	if texts := apis.sentTexts(); !strings.Contains(texts[len(texts)-1], "no conversation") {
+		t.Fatalf("empty export answered %q", texts[len(texts)-1])
+	}
+	export("pdf")
+	if texts := apis.sentTexts(); !strings.Contains(texts[len(texts)-1], "Usage") {
+		t.Errorf("unknown format answered %q, want the usage", texts[len(texts)-1])
+	}
+
+	if err := app.processMessage(context.Background(), testMessage(42, "What is the capital of France?"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	session := app.sessions.get(42)
+	session.mu.Lock()
+	transcript := session.transcript(42)
+	session.mu.Unlock()
+	if len(transcript.Turns) != 2 {
+		t.Fatalf("transcript has %d turns, want 2", len(transcript.Turns))
+	}
+	answer := transcript.Turns[1]
+	if answer.Model == "" || answer.Time.IsZero() || transcript.Turns[0].Model != "" {
+		t.Errorf("turn metadata = %+v, %+v", transcript.Turns[0], answer)
+	}
+
+	md := transcript.markdown()
+	if !strings.Contains(md, "## User · ") || !strings.Contains(md, "## Eteon ("+answer.Model+") · ") {
+		t.Errorf("markdown headings missing:\n%s", md)
+	}
+	raw, err := transcript.json()
+	if err != nil {
+		t.Fatalf("json: %v", err)
+	}
+	var decoded chatTranscript
+	if err := json.Unmarshal([]byte(raw), &decoded); err != nil || decoded.Turns[1].Text != apis.reply {
+		t.Errorf("json round trip = %+v, %v", decoded, err)
+	}
+	page, err := transcript.html()
+	if err != nil || !strings.Contains(page, `class="turn model"`) {
+		t.Errorf("html = %q, %v", page, err)
+	}
+
+	export("html")
+	docs := apis.callsTo(telegramHost, "sendDocument")
+	if len(docs) != 1 || !strings.Contains(string(docs[0].body), "Paris is the capital of France.") {
+		t.Fatalf("sent %d documents, want the HTML transcript", len(docs))
+	}
+}
+
+func TestImportRestoresExportedTranscript(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.reply = "Noted, the budget is 5000."
+	if err := app.processMessage(context.Background(), testMessage(42, "Our budget is 5000."), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	source := app.sessions.get(42)
+	source.mu.Lock()
+	exported := source.transcript(42)
+	source.mu.Unlock()
+	raw, err := exported.json()
+	if err != nil {
+		t.Fatalf("json: %v", err)
+	}
+
+	parsed, err := parseTranscript([]byte(raw))
+	if err != nil {
+		t.Fatalf("parseTranscript: %v", err)
+	}
+	target := app.sessions.get(43)
+	target.mu.Lock()
+	target.restore(parsed, app.pii.maskContent)
+	restored := target.transcript(43)
+	history := len(target.history)
+	target.mu.Unlock()
+	if history != 2 || restored.Turns[1].Text != apis.reply || restored.Turns[1].Model != exported.Turns[1].Model {
+		t.Errorf("restored %d entries: %+v", history, restored.Turns)
+	}
+	if !restored.Turns[0].Time.Equal(exported.Turns[0].Time) {
+		t.Errorf("restored time %v, want %v", restored.Turns[0].Time, exported.Turns[0].Time)
+	}
+
+	for _, bad := range []string{`not json`, `{"turns":[]}`, `{"turns":[{"role":"system","text":"x"}]}`, `{"turns":[{"role":"user","text":" "}]}`} {
+		if _, err := parseTranscript([]byte(bad)); err == nil {
+			t.Errorf("parseTranscript(%s) accepted", bad)
+		}
+	}
+
+	doc := testMessage(42, "")
+	doc.Document = &tele.Document{File: tele.File{FileID: "f"}, FileName: "conversation.json"}
+	for caption, want := range map[string]bool{"/import": true, "/import@eteon_bot": true, "/imports": false, "": false} {
+		doc.Caption = caption
+		if got := isImportCaption(doc); got != want {
+			t.Errorf("isImportCaption(%q) = %v, want %v", caption, got, want)
+		}
+	}
+
+	if err := app.handleImport(app.bot.NewContext(tele.Update{Message: testMessage(42, "/import")})); err != nil {
+		t.Fatalf("handleImport: %v", err)
+	}
+	if texts := apis.sentTexts(); !strings.Contains(texts[len(texts)-1], "JSON transcript") {
+		t.Errorf("import without a document answered %q", texts[len(texts)-1])
+	}
+}
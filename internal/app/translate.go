@@ -0,0 +1,187 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const translateCommand = "/translate"
+
+// translateSideBySide is the longest text translated line by line next to
+// the original.
+const translateSideBySide = 400
+
+const translateInstruction = "You are a translator. Translate the material into %s%s. Keep the formatting exactly: " +
+	"Markdown, line breaks, lists, tables, links and emoji stay where they are, and code stays untranslated. " +
+	"For speech, translate what is said. Reply with the translation only, without notes or the original."
+
+// languageCodes names the languages /translate takes as codes.
+var languageCodes = map[string]string{
+	"ar": "Arabic", "cs": "Czech", "de": "German", "en": "English", "es": "Spanish", "fr": "French",
+	"he": "Hebrew", "hi": "Hindi", "it": "Italian", "ja": "Japanese", "ko": "Korean", "nl": "Dutch",
+	"pl": "Polish", "pt": "Portuguese", "ru": "Russian", "sv": "Swedish", "tr": "Turkish", "uk": "Ukrainian",
+	"zh": "Chinese",
+}
+
+// translationLanguages reads "de", "German" or a pair such as "en-de" or
+// "en>de" into the source and target language; the source is empty when the
+// model should detect it.
+func translationLanguages(arg string) (from, to string) {
+	name := func(code string) string {
+		code = strings.TrimSpace(code)
+		if full, ok := languageCodes[strings.ToLower(code)]; ok {
+			return full
+		}
+		return code
+	}
+	for _, sep := range []string{"->", "→", ">", "-", ":"} {
+		if source, target, ok := strings.Cut(arg, sep); ok && source != "" && target != "" {
+			return name(source), name(target)
+		}
+	}
+	return "", name(arg)
+}
+
+// hasAttachment reports whether msg carries a document, recording or photo
+// for /translate and /summarize to read.
+func hasAttachment(msg *tele.Message) bool {
+	if msg == nil {
+		return false
+	}
+	_, _, isRecording := recording(msg)
+	return msg.Document != nil || msg.Photo != nil || isRecording
+}
+
+// handleTranslate translates into a language:
+//
+//	/translate <lang> <text>
+//	/translate <lang> as a reply to a message, document or voice note
+//	a document or voice note captioned /translate <lang>
+//
+// The language is a code, a name or a pair such as en-de; without one the
+// text goes into the chat's language. Short texts come back line by line
+// next to the original.
+func (a *App) handleTranslate(c tele.Context) error {
+	msg := c.Message()
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	payload := strings.TrimSpace(msg.Payload)
+	if _, rest := captionCommand(msg); msg.Caption != "" {
+		payload = rest
+	}
+	arg, text, _ := strings.Cut(payload, " ")
+	if arg == "" {
+		arg = languageNames[lang]
+	}
+	text = strings.TrimSpace(text)
+
+	source := msg
+	switch {
+	case text != "" || hasAttachment(msg):
+	case msg.ReplyTo != nil && (hasAttachment(msg.ReplyTo) || strings.TrimSpace(msg.ReplyTo.Text+msg.ReplyTo.Caption) != ""):
+		source = msg.ReplyTo
+		text = strings.TrimSpace(source.Text)
+		if text == "" && !hasAttachment(source) {
+			text = strings.TrimSpace(source.Caption)
+		}
+	default:
+		_, err := a.sendWithFallback(c.Chat(), tr(lang, "translate_usage"), &tele.SendOptions{ThreadID: messageTopic(msg), DisableWebPagePreview: true})
+		return err
+	}
+	from, to := translationLanguages(arg)
+	return a.enqueueJob(msg, func(ctx context.Context) error {
+		return a.translate(ctx, msg, source, text, from, to)
+	})
+}
+
+// translate sends the translation of text, or of the media of source when
+// text is empty, to the chat of msg.
+func (a *App) translate(ctx context.Context, msg, source *tele.Message, text, from, to string) error {
+	lang := a.chatLanguage(msg.Chat, msg.Sender)
+	opts := &tele.SendOptions{ThreadID: messageTopic(msg), ReplyTo: source, DisableWebPagePreview: true}
+	fail := func(key string) error {
+		_, err := a.sendWithFallback(msg.Chat, tr(lang, key), opts)
+		return err
+	}
+	ctx, cancel := context.WithTimeout(withChatID(ctx, msg.Chat.ID), 5*time.Minute)
+	defer cancel()
+
+	var parts []*genai.Part
+	if text != "" {
+		parts = append(parts, genai.NewPartFromText(text))
+	} else {
+		var err error
+		if parts, err = a.attachmentParts(ctx, source); err != nil {
+			logFrom(ctx).Warn("read translation source failed", "err", err)
+			if errors.Is(err, errFileTooLarge) {
+				return fail("file_too_large")
+			}
+			return fail("input_failed")
+		}
+	}
+	fromClause := ""
+	if from != "" {
+		fromClause = " from " + from
+	}
+	cfg := &genai.GenerateContentConfig{
+		SystemInstruction: genai.NewContentFromText(fmt.Sprintf(translateInstruction, to, fromClause), genai.Role("system")),
+		Temperature:       genai.Ptr[float32](0.2),
+	}
+	contents := []*genai.Content{genai.NewContentFromParts(parts, genai.RoleUser)}
+	resp, model, err := a.generate(ctx, contents, cfg)
+	if err != nil {
+		logFrom(ctx).Warn("translate failed", "err", err)
+		return fail("translate_failed")
+	}
+	a.recordUsage(ctx, msg.Chat.ID, model, resp.UsageMetadata)
+	translation := strings.TrimSpace(resp.Text())
+	if translation == "" {
+		return fail("translate_failed")
+	}
+
+	if text != "" && len(text) <= translateSideBySide {
+		translation = sideBySide(text, translation)
+	}
+	if len(translation) <= longMessagePart {
+		_, err := a.sendWithFallback(msg.Chat, translation, opts)
+		return err
+	}
+	name := "translation-" + strings.ToLower(strings.ReplaceAll(to, " ", "-")) + ".txt"
+	return a.sendTextDocument(msg.Chat, name, "text/plain", translation+"\n", tr(lang, "translate_caption", to), opts)
+}
+
+// attachmentParts reads the document, recording or photo of source.
+func (a *App) attachmentParts(ctx context.Context, source *tele.Message) ([]*genai.Part, error) {
+	if source.Document != nil && !strings.HasPrefix(source.Document.MIME, "audio/") && !strings.HasPrefix(source.Document.MIME, "video/") {
+		return a.documentParts(ctx, source.Document)
+	}
+	if file, mimeType, ok := recording(source); ok {
+		part, err := a.partFromFile(ctx, file, mimeType)
+		return []*genai.Part{part}, err
+	}
+	part, err := a.partFromFile(ctx, source.Photo.MediaFile(), "")
+	return []*genai.Part{part}, err
+}
+
+// sideBySide sets each line of a short text above its translation; texts
+// whose line counts differ get the whole translation under the original.
+func sideBySide(original, translation string) string {
+	source, target := strings.Split(original, "\n"), strings.Split(translation, "\n")
+	if len(source) != len(target) {
+		return original + "\n\n→ " + strings.ReplaceAll(translation, "\n", "\n→ ")
+	}
+	var b strings.Builder
+	for i, line := range source {
+		if strings.TrimSpace(line) == "" {
+			b.WriteString("\n")
+			continue
+		}
+		fmt.Fprintf(&b, "%s\n→ %s\n", line, target[i])
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
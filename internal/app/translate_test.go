@@ -0,0 +1,70 @@
+package app
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+func TestTranslationLanguages(t *testing.T) {
+	for arg, want := range map[string][2]string{
+		"de":          {"", "German"},
+		"Klingon":     {"", "Klingon"},
+		"en-de":       {"English", "German"},
+		"fr>uk":       {"French", "Ukrainian"},
+		"en->Spanish": {"English", "Spanish"},
+	} {
+		if from, to := translationLanguages(arg); from != want[0] || to != want[1] {
+			t.Errorf("translationLanguages(%q) = %q, %q, want %q, %q", arg, from, to, want[0], want[1])
+		}
+	}
+}
+
+func TestTranslateReplySideBySide(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.reply = "Guten Morgen\nWie geht es dir?"
+	msg := testMessage(42, "/translate en-de")
+	msg.Payload = "en-de"
+	msg.ReplyTo = testMessage(42, "Good morning\nHow are you?")
+	if err := app.handleTranslate(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+		t.Fatalf("handleTranslate: %v", err)
+	}
+	app.queue.drain(context.Background())
+
+	calls := apis.callsTo(geminiHost, "gemini-2.5-pro:generateContent")
+	if len(calls) != 1 || !strings.Contains(string(calls[0].body), "into German from English") {
+		t.Fatalf("translation request: %v", calls)
+	}
+	want := escapeMarkdownV2("Good morning\n→ Guten Morgen\nHow are you?\n→ Wie geht es dir?")
+	if texts := apis.sentTexts(); len(texts) != 1 || texts[0] != want {
+		t.Errorf("sent %q, want %q", texts, want)
+	}
+}
+
+func TestTranslateLongTextAsDocument(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.reply = strings.Repeat("Ein langer Satz. ", 300)
+	text := strings.Repeat("A long sentence. ", 300)
+	msg := testMessage(42, "/translate de "+text)
+	msg.Payload = "de " + text
+	if err := app.handleTranslate(app.bot.NewContext(tele.Update{Message: msg})); err != nil {
+		t.Fatalf("handleTranslate: %v", err)
+	}
+	app.queue.drain(context.Background())
+	docs := apis.callsTo(telegramHost, "sendDocument")
+	if len(docs) != 1 || !strings.Contains(string(docs[0].body), `filename="translation-german.txt"`) {
+		t.Fatalf("sent %d documents, want the translation", len(docs))
+	}
+}
+
+func TestTranslateUsage(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	if err := app.handleTranslate(app.bot.NewContext(tele.Update{Message: testMessage(42, "/translate")})); err != nil {
+		t.Fatalf("handleTranslate: %v", err)
+	}
+	if texts := apis.sentTexts(); len(texts) != 1 || !strings.Contains(texts[0], "Usage: /translate") {
+		t.Errorf("sent %q, want the usage", texts)
+	}
+}
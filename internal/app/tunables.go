@@ -0,0 +1,147 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// tunables are the settings Reload changes while the bot runs. They are
+// replaced as a whole, so a turn sees either the old or the new values.
+type tunables struct {
+	model             string
+	fallbackModel     string
+	prompt            string
+	systemInstruction *genai.Content
+	// shedDepth is the queue depth from which requests go light, zero for
+	// never, and monthlyBudget the spend per month that does the same.
+	shedDepth     int
+	monthlyBudget float64
+	// dailyBudget and userDailyBudget are the daily spend limits of the bot
+	// and of each user, and overBudget what requests do beyond them.
+	dailyBudget     float64
+	userDailyBudget float64
+	overBudget      string
+}
+
+func newTunables(cfg Config) *tunables {
+	t := &tunables{
+		model:             strings.TrimSpace(cfg.Model),
+		prompt:            strings.TrimSpace(cfg.SystemPrompt),
+		systemInstruction: buildSystemInstruction(cfg.SystemPrompt),
+		shedDepth:         sheddingDepth(cfg.LoadSheddingDepth),
+		monthlyBudget:     cfg.MonthlyBudgetUSD,
+		dailyBudget:       cfg.DailyBudgetUSD,
+		userDailyBudget:   cfg.UserDailyBudgetUSD,
+	}
+	t.overBudget, _ = parseOverBudget(cfg.OverBudget)
+	if t.model == "" {
+		t.model = geminiModel
+	}
+	switch fallback := strings.TrimSpace(cfg.FallbackModel); {
+	case fallback == "":
+		t.fallbackModel = defaultFallbackModel
+	case !strings.EqualFold(fallback, "none"):
+		t.fallbackModel = fallback
+	}
+	return t
+}
+
+func (a *App) tuned() *tunables {
+	return a.tuning.Load()
+}
+
+// updateTunables applies fn to a copy of the tunables and stores it.
+func (a *App) updateTunables(fn func(*tunables)) {
+	for {
+		old := a.tuning.Load()
+		next := *old
+		fn(&next)
+		if a.tuning.CompareAndSwap(old, &next) {
+			return
+		}
+	}
+}
+
+// applyConfig takes over the tunables of cfg: the model, the fallback model,
+// the system prompt, the load shedding depth, the monthly and daily budgets,
+// the rate limit and the allowlists. The other settings
+// need a restart. It describes what changed.
+func (a *App) applyConfig(cfg Config) ([]string, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	old, next := a.tuned(), newTunables(cfg)
+	var changes []string
+	if next.model != old.model {
+		changes = append(changes, fmt.Sprintf("Model: %s → %s", old.model, next.model))
+	}
+	if next.fallbackModel != old.fallbackModel {
+		changes = append(changes, fmt.Sprintf("Fallback model: %s → %s", orNone(old.fallbackModel), orNone(next.fallbackModel)))
+	}
+	if next.prompt != old.prompt {
+		changes = append(changes, "System prompt updated")
+	}
+	if next.shedDepth != old.shedDepth {
+		changes = append(changes, fmt.Sprintf("Load shedding depth: %d → %d", old.shedDepth, next.shedDepth))
+	}
+	if next.monthlyBudget != old.monthlyBudget {
+		changes = append(changes, fmt.Sprintf("Monthly budget: $%.2f → $%.2f", old.monthlyBudget, next.monthlyBudget))
+	}
+	if next.dailyBudget != old.dailyBudget {
+		changes = append(changes, fmt.Sprintf("Daily budget: $%.2f → $%.2f", old.dailyBudget, next.dailyBudget))
+	}
+	if next.userDailyBudget != old.userDailyBudget {
+		changes = append(changes, fmt.Sprintf("Daily budget per user: $%.2f → $%.2f", old.userDailyBudget, next.userDailyBudget))
+	}
+	if next.overBudget != old.overBudget {
+		changes = append(changes, fmt.Sprintf("Over budget: %s → %s", old.overBudget, next.overBudget))
+	}
+	a.tuning.Store(next)
+
+	allowChanged, limitChanged := a.access.configure(cfg.AllowedUserIDs, cfg.AllowedChatIDs, cfg.RateLimit)
+	if allowChanged {
+		changes = append(changes, fmt.Sprintf("Allowlists: %d users, %d chats", len(cfg.AllowedUserIDs), len(cfg.AllowedChatIDs)))
+	}
+	if limitChanged {
+		changes = append(changes, fmt.Sprintf("Rate limit: %d updates per user and minute", cfg.RateLimit))
+	}
+	return changes, nil
+}
+
+// reloadTunables reads the configuration through Config.Reload and applies
+// its tunables.
+func (a *App) reloadTunables(lang language) []string {
+	cfg, err := a.reload()
+	var changes []string
+	if err == nil {
+		changes, err = a.applyConfig(cfg)
+	}
+	if err != nil {
+		slog.Warn("configuration reload failed", "err", err)
+		return []string{tr(lang, "reload_cfg_err", err.Error())}
+	}
+	if len(changes) == 0 {
+		return []string{tr(lang, "reload_cfg_same")}
+	}
+	slog.Info("configuration reloaded", "changes", changes)
+	return append([]string{tr(lang, "reload_cfg")}, changes...)
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// sameIDs reports whether a and b hold the same IDs in any order.
+func sameIDs(a, b []int64) bool {
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.Sort(a)
+	slices.Sort(b)
+	return slices.Equal(slices.Compact(a), slices.Compact(b))
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReloadAppliesTunables(t *testing.T) {
+	next := Config{TelegramToken: "t", GeminiAPIKey: "k"}
+	var loadErr error
+	app, apis := newTestApp(t, Config{Reload: func() (Config, error) { return next, loadErr }})
+
+	next.Model = "gemini-2.5-flash"
+	next.SystemPrompt = "You are Ada, a terse assistant."
+	next.AllowedChatIDs = []int64{42}
+	next.RateLimit = 30
+	report := app.Reload()
+	for _, want := range []string{"Model: gemini-2.5-pro → gemini-2.5-flash", "System prompt updated", "Allowlists: 0 users, 1 chats", "Rate limit: 30"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report %q misses %q", report, want)
+		}
+	}
+	if !app.access.allows(0, 42) || app.access.allows(0, 7) {
+		t.Error("allowlist not applied")
+	}
+
+	if err := app.processMessage(context.Background(), testMessage(42, "Hi"), turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+	calls := apis.callsTo(geminiHost, "gemini-2.5-flash:generateContent")
+	if len(calls) == 0 {
+		t.Fatal("turn did not use the reloaded model")
+	}
+	if body := string(calls[len(calls)-1].body); !strings.Contains(body, "You are Ada") {
+		t.Error("turn did not use the reloaded system prompt")
+	}
+
+	if report := app.Reload(); !strings.Contains(report, "no tunables changed") {
+		t.Errorf("second reload reported %q", report)
+	}
+	loadErr = errors.New("config eteon.yaml: gemini.thinking: unknown mode")
+	if report := app.Reload(); !strings.Contains(report, "gemini.thinking") || app.tuned().model != "gemini-2.5-flash" {
+		t.Errorf("failed reload reported %q and left model %s", report, app.tuned().model)
+	}
+}
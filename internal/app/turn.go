@@ -0,0 +1,70 @@
+package app
+
+import (
+	"strings"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+// turnOptions adjusts how a single turn is generated, letting commands reuse the
+// regular message pipeline with their own guidance and tools.
+type turnOptions struct {
+	// instruction is appended to the system instruction for this turn only.
+	instruction string
+	// tools replaces the default tool set when non-nil.
+	tools []*genai.Tool
+	// requireCode rejects answers that were not backed by successfully executed code.
+	requireCode bool
+	// requireSearch retries answers that were not grounded in a web search
+	// and lists the sources under the reply.
+	requireSearch bool
+	// edited answers a new version of the last prompt, replacing its turn in the
+	// history and editing the previous reply in place.
+	edited bool
+	// regenerate asks for another take on the turn; previous holds the texts
+	// the new reply replaces.
+	regenerate bool
+	previous   []string
+	// recall answers from the chat's closest archived exchanges, however
+	// loosely they match.
+	recall bool
+}
+
+func (o turnOptions) apply(cfg *genai.GenerateContentConfig) {
+	if o.instruction != "" {
+		cfg.SystemInstruction = appendInstruction(cfg.SystemInstruction, o.instruction)
+	}
+	if o.tools != nil {
+		// A command that needs its tools answers in text even in JSON mode.
+		cfg.Tools = o.tools
+		cfg.ResponseMIMEType = ""
+		cfg.ResponseJsonSchema = nil
+	}
+	if o.regenerate {
+		applyRegenerate(cfg)
+	}
+}
+
+// appendInstruction returns a copy of base with extra guidance added as a new part.
+func appendInstruction(base *genai.Content, extra string) *genai.Content {
+	extra = strings.TrimSpace(extra)
+	if extra == "" {
+		return base
+	}
+	merged := &genai.Content{Role: "system"}
+	if base != nil {
+		merged.Role = base.Role
+		merged.Parts = append(merged.Parts, base.Parts...)
+	}
+	merged.Parts = append(merged.Parts, genai.NewPartFromText(extra))
+	return merged
+}
+
+// withText returns a shallow copy of msg whose text is replaced, used to feed a
+// command payload through the regular pipeline.
+func withText(msg *tele.Message, text string) *tele.Message {
+	cp := *msg
+	cp.Text = text
+	return &cp
+}
@@ -0,0 +1,385 @@
+package app
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	usageDayLayout   = "2006-01-02"
+	usageMonthLayout = "2006-01"
+	usageKeepDays    = 31
+	usageKeepMonths  = 12
+)
+
+// modelPrice is the list price in USD per million tokens; thinking tokens bill as output.
+type modelPrice struct {
+	Input  float64
+	Output float64
+}
+
+var modelPricing = map[string]modelPrice{
+	"gemini-2.5-pro":        {Input: 1.25, Output: 10},
+	"gemini-2.5-flash":      {Input: 0.30, Output: 2.50},
+	"gemini-2.5-flash-lite": {Input: 0.10, Output: 0.40},
+	"gemini-embedding-001":  {Input: 0.15},
+}
+
+// cachedInputShare is the part of the input price that prompt tokens served
+// from a context cache bill at.
+const cachedInputShare = 0.25
+
+func estimateCost(model string, prompt, cached, output int64) float64 {
+	price, ok := modelPricing[model]
+	if !ok {
+		return 0
+	}
+	input := float64(prompt-cached) + float64(cached)*cachedInputShare
+	return (input*price.Input + float64(output)*price.Output) / 1_000_000
+}
+
+type tokenUsage struct {
+	Requests   int64
+	Prompt     int64
+	Candidates int64
+	Thoughts   int64
+	CostUSD    float64
+}
+
+func (u *tokenUsage) add(other tokenUsage) {
+	u.Requests += other.Requests
+	u.Prompt += other.Prompt
+	u.Candidates += other.Candidates
+	u.Thoughts += other.Thoughts
+	u.CostUSD += other.CostUSD
+}
+
+func (u tokenUsage) total() int64 {
+	return u.Prompt + u.Candidates + u.Thoughts
+}
+
+type usageLedger struct {
+	daily   map[string]*tokenUsage
+	monthly map[string]*tokenUsage
+}
+
+func newUsageLedger() *usageLedger {
+	return &usageLedger{
+		daily:   make(map[string]*tokenUsage),
+		monthly: make(map[string]*tokenUsage),
+	}
+}
+
+func (l *usageLedger) add(now time.Time, u tokenUsage) {
+	day := now.Format(usageDayLayout)
+	month := now.Format(usageMonthLayout)
+	if l.daily[day] == nil {
+		l.daily[day] = &tokenUsage{}
+	}
+	if l.monthly[month] == nil {
+		l.monthly[month] = &tokenUsage{}
+	}
+	l.daily[day].add(u)
+	l.monthly[month].add(u)
+
+	dayCutoff := now.AddDate(0, 0, -usageKeepDays).Format(usageDayLayout)
+	for key := range l.daily {
+		if key < dayCutoff {
+			delete(l.daily, key)
+		}
+	}
+	monthCutoff := now.AddDate(0, -usageKeepMonths, 0).Format(usageMonthLayout)
+	for key := range l.monthly {
+		if key < monthCutoff {
+			delete(l.monthly, key)
+		}
+	}
+}
+
+func (l *usageLedger) snapshot(now time.Time) (day, month tokenUsage) {
+	if u := l.daily[now.Format(usageDayLayout)]; u != nil {
+		day = *u
+	}
+	if u := l.monthly[now.Format(usageMonthLayout)]; u != nil {
+		month = *u
+	}
+	return day, month
+}
+
+// usageBackend keeps the usage ledgers outside the process, so the budgets
+// and /usage survive restarts. Ledgers are named globalUsageLedger, or by
+// chatUsageLedger and userUsageLedger; periods are days and months in the
+// usage layouts.
+type usageBackend interface {
+	// addUsage adds u to the totals of ledger for day and month.
+	addUsage(ctx context.Context, ledger, day, month string, u tokenUsage) error
+	// loadUsage returns the stored totals by ledger and period, dropping the
+	// days before dayCutoff and the months before monthCutoff.
+	loadUsage(ctx context.Context, dayCutoff, monthCutoff string) (map[string]map[string]tokenUsage, error)
+	// migrateUsage moves the totals of ledger from to ledger to, keeping the
+	// periods to already has.
+	migrateUsage(ctx context.Context, from, to string) error
+}
+
+const globalUsageLedger = "global"
+
+func chatUsageLedger(chatID int64) string {
+	return "chat:" + strconv.FormatInt(chatID, 10)
+}
+
+func userUsageLedger(userID int64) string {
+	return "user:" + strconv.FormatInt(userID, 10)
+}
+
+// usageTracker aggregates token consumption per chat and across the whole bot,
+// and the spend of the turns each user starts for UserDailyBudgetUSD.
+type usageTracker struct {
+	mu     sync.Mutex
+	chats  map[int64]*usageLedger
+	users  map[int64]*usageLedger
+	global *usageLedger
+	now    func() time.Time
+	// store, when set, is written through on every change.
+	store usageBackend
+}
+
+func newUsageTracker() *usageTracker {
+	return &usageTracker{
+		chats:  make(map[int64]*usageLedger),
+		users:  make(map[int64]*usageLedger),
+		global: newUsageLedger(),
+		now:    time.Now,
+	}
+}
+
+// load fills the tracker from store and keeps writing to it from then on.
+func (t *usageTracker) load(ctx context.Context, store usageBackend) error {
+	now := t.now()
+	stored, err := store.loadUsage(ctx,
+		now.AddDate(0, 0, -usageKeepDays).Format(usageDayLayout),
+		now.AddDate(0, -usageKeepMonths, 0).Format(usageMonthLayout))
+	if err != nil {
+		return err
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for name, periods := range stored {
+		ledger := t.ledger(name)
+		if ledger == nil {
+			continue
+		}
+		for period, u := range periods {
+			if len(period) == len(usageDayLayout) {
+				ledger.daily[period] = &u
+			} else {
+				ledger.monthly[period] = &u
+			}
+		}
+	}
+	t.store = store
+	return nil
+}
+
+// ledger returns the ledger called name, creating it, or nil when name is
+// not one.
+func (t *usageTracker) ledger(name string) *usageLedger {
+	if name == globalUsageLedger {
+		return t.global
+	}
+	kind, rawID, _ := strings.Cut(name, ":")
+	id, err := strconv.ParseInt(rawID, 10, 64)
+	if err != nil {
+		return nil
+	}
+	var ledgers map[int64]*usageLedger
+	switch kind {
+	case "chat":
+		ledgers = t.chats
+	case "user":
+		ledgers = t.users
+	default:
+		return nil
+	}
+	ledger, ok := ledgers[id]
+	if !ok {
+		ledger = newUsageLedger()
+		ledgers[id] = ledger
+	}
+	return ledger
+}
+
+// persist adds u to the stored ledgers. It is best effort: usage that fails
+// to be stored only counts until the next restart.
+func (t *usageTracker) persist(now time.Time, u tokenUsage, ledgers ...string) {
+	if t.store == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	day, month := now.Format(usageDayLayout), now.Format(usageMonthLayout)
+	for _, ledger := range ledgers {
+		if err := t.store.addUsage(ctx, ledger, day, month, u); err != nil {
+			slog.Warn("store usage failed", "ledger", ledger, "err", err)
+			return
+		}
+	}
+}
+
+func (t *usageTracker) record(chatID int64, model string, meta *genai.GenerateContentResponseUsageMetadata) tokenUsage {
+	u := tokenUsage{Requests: 1}
+	var cached int64
+	if meta != nil {
+		cached = int64(meta.CachedContentTokenCount)
+		u.Prompt = int64(meta.PromptTokenCount) + int64(meta.ToolUsePromptTokenCount)
+		u.Candidates = int64(meta.CandidatesTokenCount)
+		u.Thoughts = int64(meta.ThoughtsTokenCount)
+	}
+	u.CostUSD = estimateCost(model, u.Prompt, cached, u.Candidates+u.Thoughts)
+
+	now := t.now()
+	t.mu.Lock()
+	ledger, ok := t.chats[chatID]
+	if !ok {
+		ledger = newUsageLedger()
+		t.chats[chatID] = ledger
+	}
+	ledger.add(now, u)
+	t.global.add(now, u)
+	t.mu.Unlock()
+	t.persist(now, u, chatUsageLedger(chatID), globalUsageLedger)
+	return u
+}
+
+// charge adds usage already recorded for a chat to the user who caused it.
+func (t *usageTracker) charge(userID int64, u tokenUsage) {
+	if userID == 0 {
+		return
+	}
+	now := t.now()
+	t.mu.Lock()
+	ledger, ok := t.users[userID]
+	if !ok {
+		ledger = newUsageLedger()
+		t.users[userID] = ledger
+	}
+	ledger.add(now, u)
+	t.mu.Unlock()
+	t.persist(now, u, userUsageLedger(userID))
+}
+
+func (t *usageTracker) userSpentToday(userID int64) float64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ledger, ok := t.users[userID]
+	if !ok {
+		return 0
+	}
+	day, _ := ledger.snapshot(t.now())
+	return day.CostUSD
+}
+
+// nextDay is when the day the usage is counted in ends, and the daily
+// limits start over.
+func (t *usageTracker) nextDay() time.Time {
+	now := t.now()
+	y, m, d := now.Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, now.Location())
+}
+
+// migrate moves the usage of chat from to chat to, so quotas carry over.
+func (t *usageTracker) migrate(from, to int64) {
+	t.mu.Lock()
+	moveKey(t.chats, from, to)
+	t.mu.Unlock()
+	if t.store == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := t.store.migrateUsage(ctx, chatUsageLedger(from), chatUsageLedger(to)); err != nil {
+		slog.Warn("move stored usage failed", "from", from, "to", to, "err", err)
+	}
+}
+
+// recordUsage records the usage of a model call for chatID and charges it to
+// the user behind ctx, if any. Every model call goes through it, so each one
+// counts against the budgets.
+func (a *App) recordUsage(ctx context.Context, chatID int64, model string, meta *genai.GenerateContentResponseUsageMetadata) tokenUsage {
+	u := a.usage.record(chatID, model, meta)
+	if userID, ok := userIDFromContext(ctx); ok {
+		a.usage.charge(userID, u)
+	}
+	return u
+}
+
+func (t *usageTracker) chatSnapshot(chatID int64) (day, month tokenUsage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ledger, ok := t.chats[chatID]
+	if !ok {
+		return tokenUsage{}, tokenUsage{}
+	}
+	return ledger.snapshot(t.now())
+}
+
+// globalSnapshot returns the totals of all chats. They are not shown in /usage,
+// which chat members of any chat can run.
+func (t *usageTracker) globalSnapshot() (day, month tokenUsage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.global.snapshot(t.now())
+}
+
+func (a *App) handleUsage(c tele.Context) error {
+	chatDay, chatMonth := a.usage.chatSnapshot(c.Chat().ID)
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+
+	var b strings.Builder
+	b.WriteString(tr(lang, "usage_title") + "\n")
+	b.WriteString(formatUsageLine(lang, tr(lang, "usage_today"), chatDay))
+	b.WriteString("\n")
+	b.WriteString(formatUsageLine(lang, tr(lang, "usage_month"), chatMonth))
+	b.WriteString("\n\n" + tr(lang, "usage_estimate"))
+
+	_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), b.String(), &tele.SendOptions{DisableWebPagePreview: true})
+	return err
+}
+
+func formatUsageLine(lang language, label string, u tokenUsage) string {
+	if u.Requests == 0 {
+		return tr(lang, "usage_none", label)
+	}
+	return tr(lang, "usage_line",
+		label,
+		formatThousands(u.Requests),
+		formatThousands(u.total()),
+		formatThousands(u.Prompt),
+		formatThousands(u.Candidates),
+		formatThousands(u.Thoughts),
+		u.CostUSD,
+	)
+}
+
+func formatThousands(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	neg := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+	var b strings.Builder
+	for i, r := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	if neg {
+		return "-" + b.String()
+	}
+	return b.String()
+}
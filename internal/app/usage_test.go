@@ -0,0 +1,108 @@
+package app
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+func TestUsageTrackerRecord(t *testing.T) {
+	tracker := newUsageTracker()
+	now := time.Date(2026, 3, 31, 23, 30, 0, 0, time.UTC)
+	tracker.now = func() time.Time { return now }
+
+	// 700 tokens of input at $1.25 and 300 of output at $10 per million: 400
+	// of the 1000 prompt tokens came from a cache and bill at a quarter.
+	u := tracker.record(42, "gemini-2.5-pro", &genai.GenerateContentResponseUsageMetadata{
+		PromptTokenCount: 900, ToolUsePromptTokenCount: 100, CachedContentTokenCount: 400,
+		CandidatesTokenCount: 200, ThoughtsTokenCount: 100,
+	})
+	if u.Requests != 1 || u.Prompt != 1000 || u.Candidates != 200 || u.Thoughts != 100 || u.total() != 1300 {
+		t.Errorf("usage = %+v", u)
+	}
+	if want := 0.003875; math.Abs(u.CostUSD-want) > 1e-12 {
+		t.Errorf("cost = %g, want %g", u.CostUSD, want)
+	}
+	if u := tracker.record(42, "unknown-model", nil); u.Requests != 1 || u.CostUSD != 0 {
+		t.Errorf("usage without metadata = %+v, want one free request", u)
+	}
+	tracker.record(7, "gemini-2.5-flash", &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 10})
+
+	if day, month := tracker.chatSnapshot(42); day.Requests != 2 || month.Prompt != 1000 {
+		t.Errorf("chat 42 = %+v, %+v", day, month)
+	}
+	if day, _ := tracker.globalSnapshot(); day.Requests != 3 || day.Prompt != 1010 {
+		t.Errorf("global = %+v, want all chats", day)
+	}
+	if day, _ := tracker.chatSnapshot(8); day.Requests != 0 {
+		t.Errorf("unknown chat = %+v", day)
+	}
+
+	// The day and the month both end an hour later.
+	now = now.Add(time.Hour)
+	if day, month := tracker.chatSnapshot(42); day.Requests != 0 || month.Requests != 0 {
+		t.Errorf("after midnight = %+v, %+v; want both periods started over", day, month)
+	}
+	if next := tracker.nextDay(); !next.Equal(time.Date(2026, 4, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("nextDay = %s", next)
+	}
+	now = now.AddDate(0, 0, usageKeepDays+1)
+	tracker.record(42, "gemini-2.5-pro", nil)
+	if _, ok := tracker.chats[42].daily["2026-03-31"]; ok {
+		t.Errorf("a day older than %d days is kept", usageKeepDays)
+	}
+}
+
+func TestUsageTrackerCharge(t *testing.T) {
+	tracker := newUsageTracker()
+	tracker.charge(42, tokenUsage{Requests: 1, CostUSD: 0.25})
+	tracker.charge(42, tokenUsage{Requests: 1, CostUSD: 0.5})
+	tracker.charge(0, tokenUsage{Requests: 1, CostUSD: 3})
+	if spent := tracker.userSpentToday(42); spent != 0.75 {
+		t.Errorf("user 42 spent $%g, want $0.75", spent)
+	}
+	if spent := tracker.userSpentToday(7); spent != 0 {
+		t.Errorf("user 7 spent $%g", spent)
+	}
+	if _, ok := tracker.users[0]; ok {
+		t.Error("usage without a user was charged")
+	}
+	if day, _ := tracker.globalSnapshot(); day.Requests != 0 {
+		t.Errorf("charging counted %d requests globally, want them left to record", day.Requests)
+	}
+}
+
+func TestBudgetExceededAtTheLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    Config
+		user   int64
+		spent  float64
+		tokens int32
+		want   string
+	}{
+		{"below the user limit", Config{UserDailyBudgetUSD: 1}, 42, 0.75, 0, ""},
+		{"at the user limit", Config{UserDailyBudgetUSD: 1}, 42, 1, 0, budgetUser},
+		{"over the user limit", Config{UserDailyBudgetUSD: 1}, 42, 1.5, 0, budgetUser},
+		{"admins have no limit", Config{UserDailyBudgetUSD: 1, AdminUserIDs: []int64{42}}, 42, 2, 0, ""},
+		{"no limit configured", Config{}, 42, 100, 100, ""},
+		// 100k output tokens of gemini-2.5-pro cost $1.
+		{"at the daily limit", Config{DailyBudgetUSD: 1}, 42, 0, 100_000, budgetDaily},
+		{"below the daily limit", Config{DailyBudgetUSD: 1}, 42, 0, 99_999, ""},
+		{"the daily limit comes first", Config{DailyBudgetUSD: 1, UserDailyBudgetUSD: 1}, 42, 1, 100_000, budgetDaily},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, _ := newTestApp(t, tt.cfg)
+			app.usage.charge(tt.user, tokenUsage{CostUSD: tt.spent})
+			if tt.tokens > 0 {
+				app.usage.record(1, "gemini-2.5-pro", &genai.GenerateContentResponseUsageMetadata{CandidatesTokenCount: tt.tokens})
+			}
+			if got := app.budgetExceeded(tt.user); got != tt.want {
+				t.Errorf("budgetExceeded = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"log/slog"
+	"strings"
+
+	tele "gopkg.in/telebot.v4"
+)
+
+const selectVerbosityUnique = "set_verbosity"
+
+type verbosity string
+
+const (
+	verbosityShort  verbosity = "short"
+	verbosityNormal verbosity = "normal"
+	verbosityLong   verbosity = "long"
+)
+
+func parseVerbosity(v string) (verbosity, bool) {
+	switch verbosity(strings.ToLower(strings.TrimSpace(v))) {
+	case verbosityShort:
+		return verbosityShort, true
+	case verbosityNormal:
+		return verbosityNormal, true
+	case verbosityLong:
+		return verbosityLong, true
+	}
+	return "", false
+}
+
+// replyTokens caps the visible part of a reply, or is zero when the model's
+// own limit applies. Thinking tokens count against MaxOutputTokens as well, so
+// buildGenerateConfig adds the thinking budget on top.
+func (v verbosity) replyTokens() int32 {
+	switch v {
+	case verbosityShort:
+		return 1024
+	case verbosityLong:
+		return 16384
+	default:
+		return 0
+	}
+}
+
+func (v verbosity) instruction() string {
+	switch v {
+	case verbosityShort:
+		return "The user prefers short replies. Answer in at most three sentences or five bullet points, and skip preambles and summaries."
+	case verbosityLong:
+		return "The user prefers thorough replies. Cover the topic in full detail with examples where they help."
+	default:
+		return ""
+	}
+}
+
+func (v verbosity) label(lang language) string {
+	switch v {
+	case verbosityShort:
+		return tr(lang, "verbosity_short")
+	case verbosityLong:
+		return tr(lang, "verbosity_long")
+	default:
+		return tr(lang, "verbosity_normal")
+	}
+}
+
+// maxOutputTokens combines the reply cap with the thinking budget. Dynamic
+// thinking has no fixed budget, so the largest fixed budget is reserved. It
+// is zero, leaving MaxOutputTokens unset, for normal replies.
+func maxOutputTokens(v verbosity, thinking thinkingMode) int32 {
+	reply := v.replyTokens()
+	if reply == 0 {
+		return 0
+	}
+	return reply + thinkingReserve(thinking)
+}
+
+func thinkingReserve(thinking thinkingMode) int32 {
+	reserve := *thinking.budgetTokens()
+	if reserve < 0 {
+		reserve = *thinkingModeHigh.budgetTokens()
+	}
+	return reserve
+}
+
+func (a *App) handleVerbosity(c tele.Context) error {
+	session := a.sessionOf(c.Message())
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+
+	if v, ok := parseVerbosity(c.Message().Payload); ok {
+		session.mu.Lock()
+		session.verbosity = v
+		session.mu.Unlock()
+		_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), tr(lang, "verbosity_set", v.label(lang)), &tele.SendOptions{DisableWebPagePreview: true})
+		return err
+	}
+
+	session.mu.Lock()
+	current := session.currentVerbosity()
+	session.mu.Unlock()
+
+	menu := &tele.ReplyMarkup{}
+	menu.Inline(menu.Row(
+		menu.Data(verbosityShort.label(lang), selectVerbosityUnique, string(verbosityShort)),
+		menu.Data(verbosityNormal.label(lang), selectVerbosityUnique, string(verbosityNormal)),
+		menu.Data(verbosityLong.label(lang), selectVerbosityUnique, string(verbosityLong)),
+	))
+	body := tr(lang, "verbosity_current", current.label(lang))
+	_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), body, &tele.SendOptions{ReplyMarkup: menu, DisableWebPagePreview: true})
+	return err
+}
+
+func (a *App) handleVerbositySelection(c tele.Context) error {
+	if err := c.Respond(); err != nil {
+		slog.Warn("callback acknowledge failed", "err", err)
+	}
+
+	v, ok := parseVerbosity(c.Callback().Data)
+	if !ok {
+		return nil
+	}
+	session := a.sessionOf(c.Message())
+	session.mu.Lock()
+	session.verbosity = v
+	session.mu.Unlock()
+
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), tr(lang, "verbosity_set", v.label(lang)), &tele.SendOptions{DisableWebPagePreview: true})
+	return err
+}
@@ -0,0 +1,71 @@
+package app
+
+import (
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestParseVerbosity(t *testing.T) {
+	tests := []struct {
+		in   string
+		want verbosity
+		ok   bool
+	}{
+		{"short", verbosityShort, true},
+		{" Long ", verbosityLong, true},
+		{"NORMAL", verbosityNormal, true},
+		{"brief", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		if got, ok := parseVerbosity(tt.in); got != tt.want || ok != tt.ok {
+			t.Errorf("parseVerbosity(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestMaxOutputTokens(t *testing.T) {
+	tests := []struct {
+		verbosity verbosity
+		thinking  thinkingMode
+		want      int32
+	}{
+		{verbosityShort, thinkingModeLow, 1024 + 4096},
+		{verbosityShort, thinkingModeMedium, 1024 + 16384},
+		{verbosityShort, thinkingModeHigh, 1024 + 32768},
+		{verbosityShort, thinkingModeDynamic, 1024 + 32768},
+		{verbosityLong, thinkingModeLow, 16384 + 4096},
+		{verbosityLong, thinkingModeDynamic, 16384 + 32768},
+		{verbosityNormal, thinkingModeLow, 0},
+		{verbosityNormal, thinkingModeHigh, 0},
+		{verbosityNormal, thinkingModeDynamic, 0},
+		{"", thinkingModeMedium, 0},
+	}
+	for _, tt := range tests {
+		if got := maxOutputTokens(tt.verbosity, tt.thinking); got != tt.want {
+			t.Errorf("maxOutputTokens(%q, %q) = %d, want %d", tt.verbosity, tt.thinking, got, tt.want)
+		}
+	}
+}
+
+func TestNormalVerbosityLeavesTheOutputLimitUnset(t *testing.T) {
+	app, _ := newTestApp(t, Config{})
+	for _, tt := range []struct {
+		verbosity verbosity
+		want      int32
+	}{{verbosityNormal, 0}, {verbosityShort, 1024 + 16384}} {
+		cfg := app.buildGenerateConfig(42, chatPrefs{verbosity: tt.verbosity, thinking: thinkingModeMedium})
+		if cfg.MaxOutputTokens != tt.want {
+			t.Errorf("%s: MaxOutputTokens = %d, want %d", tt.verbosity, cfg.MaxOutputTokens, tt.want)
+		}
+	}
+
+	// A reply cap from /settings still applies on top of normal replies.
+	if _, err := app.sampling.update(42, func(s *samplingSettings) { s.MaxReplyTokens = genai.Ptr[int32](2000) }); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if cfg := app.buildGenerateConfig(42, chatPrefs{verbosity: verbosityNormal, thinking: thinkingModeLow}); cfg.MaxOutputTokens != 2000+4096 {
+		t.Errorf("with a reply cap: MaxOutputTokens = %d, want %d", cfg.MaxOutputTokens, 2000+4096)
+	}
+}
@@ -0,0 +1,159 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const verificationModel = "gemini-2.5-flash"
+
+type verificationVerdict string
+
+const (
+	verdictSupported verificationVerdict = "SUPPORTED"
+	verdictCorrected verificationVerdict = "CORRECTED"
+	verdictUncertain verificationVerdict = "UNCERTAIN"
+)
+
+type verificationResult struct {
+	Verdict verificationVerdict
+	Note    string
+	Answer  string
+}
+
+var factualQuestionPattern = regexp.MustCompile(`(?i)^\s*(who|what|when|where|which|how (many|much|old|long|far|big)|is|are|was|were|did|does|do)\b`)
+
+// looksFactual is a cheap heuristic deciding whether a prompt warrants a verification pass.
+func looksFactual(prompt string) bool {
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		return false
+	}
+	return strings.Contains(prompt, "?") || factualQuestionPattern.MatchString(prompt)
+}
+
+func (a *App) handleSelfCheck(c tele.Context) error {
+	session := a.sessionOf(c.Message())
+
+	session.mu.Lock()
+	switch strings.ToLower(strings.TrimSpace(c.Message().Payload)) {
+	case "on":
+		session.selfCheck = true
+	case "off":
+		session.selfCheck = false
+	default:
+		session.selfCheck = !session.selfCheck
+	}
+	enabled := session.selfCheck
+	session.mu.Unlock()
+
+	lang := a.chatLanguage(c.Chat(), c.Sender())
+	body := tr(lang, "selfcheck_off")
+	if enabled {
+		body = tr(lang, "selfcheck_on")
+	}
+	_, err := a.sendWithFallback(inTopic(c.Chat(), c.Message()), body, &tele.SendOptions{DisableWebPagePreview: true})
+	return err
+}
+
+// verifyAnswer runs a grounded second pass over a reply and returns the checker's verdict.
+func (a *App) verifyAnswer(ctx context.Context, chatID int64, question, answer string) (*verificationResult, error) {
+	instruction := strings.Join([]string{
+		"You are a meticulous fact checker.",
+		"Use web search to verify every factual claim in the answer to the user's question.",
+		"Reply using exactly this format:",
+		"VERDICT: SUPPORTED, CORRECTED or UNCERTAIN",
+		"NOTE: one sentence naming any unsupported or wrong claims",
+		"ANSWER: only when the verdict is CORRECTED, the full corrected answer in the original language and format",
+	}, "\n")
+
+	contents := []*genai.Content{
+		genai.NewContentFromText(fmt.Sprintf("Question:\n%s\n\nAnswer to check:\n%s", question, answer), genai.RoleUser),
+	}
+	cfg := &genai.GenerateContentConfig{
+		SystemInstruction: genai.NewContentFromText(instruction, genai.Role("system")),
+		Tools:             []*genai.Tool{{GoogleSearch: &genai.GoogleSearch{}}},
+	}
+
+	resp, err := a.generateWithRetry(ctx, verificationModel, contents, cfg)
+	if err != nil {
+		return nil, err
+	}
+	a.recordUsage(ctx, chatID, verificationModel, resp.UsageMetadata)
+
+	text, _ := a.renderResponse(resp)
+	return parseVerification(text), nil
+}
+
+func parseVerification(text string) *verificationResult {
+	res := &verificationResult{Verdict: verdictUncertain}
+	var answer []string
+	inAnswer := false
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		upper := strings.ToUpper(trimmed)
+		switch {
+		case !inAnswer && strings.HasPrefix(upper, "VERDICT:"):
+			v := verificationVerdict(strings.ToUpper(strings.TrimSpace(trimmed[len("VERDICT:"):])))
+			switch v {
+			case verdictSupported, verdictCorrected, verdictUncertain:
+				res.Verdict = v
+			}
+		case !inAnswer && strings.HasPrefix(upper, "NOTE:"):
+			res.Note = strings.TrimSpace(trimmed[len("NOTE:"):])
+		case !inAnswer && strings.HasPrefix(upper, "ANSWER:"):
+			inAnswer = true
+			if rest := strings.TrimSpace(trimmed[len("ANSWER:"):]); rest != "" {
+				answer = append(answer, rest)
+			}
+		case inAnswer:
+			answer = append(answer, line)
+		}
+	}
+	res.Answer = strings.TrimSpace(strings.Join(answer, "\n"))
+	if res.Verdict == verdictCorrected && res.Answer == "" {
+		res.Verdict = verdictUncertain
+	}
+	return res
+}
+
+// applyVerification folds the checker's verdict into the reply. It returns the reply to
+// send and whether the answer text itself was replaced.
+func applyVerification(lang language, reply string, res *verificationResult) (string, bool) {
+	if res == nil {
+		return reply, false
+	}
+	switch res.Verdict {
+	case verdictSupported:
+		return reply + "\n\n" + tr(lang, "verify_ok"), false
+	case verdictCorrected:
+		note := tr(lang, "verify_fixed")
+		if res.Note != "" {
+			note = tr(lang, "verify_fixed_note", res.Note)
+		}
+		return res.Answer + "\n\n" + note, true
+	default:
+		note := tr(lang, "verify_unsure")
+		if res.Note != "" {
+			note = tr(lang, "verify_unsure_note", res.Note)
+		}
+		return reply + "\n\n" + note, false
+	}
+}
+
+func (a *App) maybeVerify(ctx context.Context, lang language, enabled bool, chatID int64, prompt, reply string) (string, bool) {
+	if !enabled || reply == "" || !looksFactual(prompt) {
+		return reply, false
+	}
+	res, err := a.verifyAnswer(ctx, chatID, prompt, reply)
+	if err != nil {
+		logFrom(ctx).Warn("verification pass failed", "err", err)
+		return reply, false
+	}
+	return applyVerification(lang, reply, res)
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestLooksFactual(t *testing.T) {
+	for prompt, want := range map[string]bool{
+		"Who wrote Dune":            true,
+		"how many moons has Mars":   true,
+		"Tell me a joke?":           true,
+		"Write a poem about autumn": false,
+		"Howdy":                     false,
+		"   ":                       false,
+	} {
+		if got := looksFactual(prompt); got != want {
+			t.Errorf("looksFactual(%q) = %v, want %v", prompt, got, want)
+		}
+	}
+}
+
+func TestParseVerification(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want verificationResult
+	}{
+		{"supported", "VERDICT: SUPPORTED\nNOTE: All claims check out.", verificationResult{verdictSupported, "All claims check out.", ""}},
+		{"corrected", "verdict: corrected\nNote: The year was wrong.\nANSWER: Dune was published\nin 1965.\n", verificationResult{verdictCorrected, "The year was wrong.", "Dune was published\nin 1965."}},
+		{"answer keeps its labels", "VERDICT: CORRECTED\nANSWER:\nNOTE: this line is part of the answer", verificationResult{verdictCorrected, "", "NOTE: this line is part of the answer"}},
+		{"corrected without an answer", "VERDICT: CORRECTED\nNOTE: Unsure.", verificationResult{verdictUncertain, "Unsure.", ""}},
+		{"unknown verdict", "VERDICT: MAYBE", verificationResult{verdictUncertain, "", ""}},
+		{"free text", "I could not check this.", verificationResult{verdictUncertain, "", ""}},
+	}
+	for _, tt := range tests {
+		if got := parseVerification(tt.text); *got != tt.want {
+			t.Errorf("%s: parseVerification = %+v, want %+v", tt.name, *got, tt.want)
+		}
+	}
+}
+
+func TestMaybeVerify(t *testing.T) {
+	const question, reply = "When was Dune published?", "Dune was published in 1966."
+	tests := []struct {
+		name     string
+		verdict  string
+		status   int
+		want     string
+		replaced bool
+	}{
+		{"supported", "VERDICT: SUPPORTED\nNOTE: Correct.", 0, reply + "\n\nVerified against web sources.", false},
+		{"corrected", "VERDICT: CORRECTED\nNOTE: It was 1965.\nANSWER: Dune was published in 1965.", 0, "Dune was published in 1965.\n\nCorrected after verification: It was 1965.", true},
+		{"uncertain", "VERDICT: UNCERTAIN", 0, reply + "\n\nConfidence note: some claims could not be verified.", false},
+		{"model error", "", http.StatusBadRequest, reply, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, apis := newTestApp(t, Config{})
+			apis.reply = tt.verdict
+			apis.geminiError = func(path string, body []byte) int {
+				return tt.status
+			}
+			got, replaced := app.maybeVerify(context.Background(), defaultLanguage, true, 42, question, reply)
+			if got != tt.want || replaced != tt.replaced {
+				t.Errorf("maybeVerify = %q, %v; want %q, %v", got, replaced, tt.want, tt.replaced)
+			}
+			calls := apis.callsTo(geminiHost, verificationModel+":generateContent")
+			if len(calls) != 1 || !strings.Contains(string(calls[0].body), "googleSearch") {
+				t.Fatalf("checker got %d requests, want one with web search", len(calls))
+			}
+			if day, _ := app.usage.chatSnapshot(42); (day.Requests == 1) != (tt.status == 0) {
+				t.Errorf("recorded %d requests", day.Requests)
+			}
+		})
+	}
+}
+
+func TestMaybeVerifySkipsWhenOffOrNotFactual(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	for _, tt := range []struct {
+		enabled bool
+		prompt  string
+	}{{false, "When was Dune published?"}, {true, "Write a haiku"}} {
+		if got, replaced := app.maybeVerify(context.Background(), defaultLanguage, tt.enabled, 42, tt.prompt, "reply"); got != "reply" || replaced {
+			t.Errorf("maybeVerify(%v, %q) = %q, %v", tt.enabled, tt.prompt, got, replaced)
+		}
+	}
+	if calls := apis.callsTo(geminiHost, ":generateContent"); len(calls) != 0 {
+		t.Errorf("Gemini got %d requests, want none", len(calls))
+	}
+}
+
+func TestApplyVerificationInChatLanguage(t *testing.T) {
+	got, replaced := applyVerification("es", "Respuesta.", &verificationResult{Verdict: verdictCorrected, Note: "El año era 1965.", Answer: "Respuesta corregida."})
+	if want := "Respuesta corregida.\n\nCorregido tras la verificación: El año era 1965."; got != want || !replaced {
+		t.Errorf("applyVerification = %q, %v; want %q, true", got, replaced, want)
+	}
+}
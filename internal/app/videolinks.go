@@ -0,0 +1,211 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+	tele "gopkg.in/telebot.v4"
+)
+
+const (
+	// videoLinksPerMessage bounds the videos one message adds to a request.
+	videoLinksPerMessage = 3
+	// videoLinkMaxBytes bounds a video downloaded from a direct link.
+	videoLinkMaxBytes = 100 << 20
+	videoLinkTimeout  = 2 * time.Minute
+)
+
+// videoLinkHTTPClient downloads the videos of direct links in messages.
+var videoLinkHTTPClient = publicHTTPClient(videoLinkTimeout)
+
+var youtubeIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// videoExtensions are the file types of direct video links Gemini reads.
+var videoExtensions = map[string]string{
+	".mp4":  "video/mp4",
+	".m4v":  "video/mp4",
+	".webm": "video/webm",
+	".mov":  "video/quicktime",
+	".mpeg": "video/mpeg",
+	".mpg":  "video/mpeg",
+	".avi":  "video/x-msvideo",
+	".wmv":  "video/x-ms-wmv",
+	".flv":  "video/x-flv",
+	".3gp":  "video/3gpp",
+}
+
+// videoLinkParts turns the YouTube and direct video links in msg into video
+// parts, so "summarize this" works with just a link; URL context reads pages
+// but not videos. YouTube videos are passed by URL for Gemini to fetch, other
+// videos are downloaded and sent inline or through the Files API. A link that
+// fails stays a plain link in the text.
+func (a *App) videoLinkParts(ctx context.Context, msg *tele.Message) []*genai.Part {
+	var parts []*genai.Part
+	for _, link := range messageLinks(msg) {
+		if len(parts) == videoLinksPerMessage {
+			break
+		}
+		if watch, ok := youtubeWatchURL(link); ok {
+			parts = append(parts, genai.NewPartFromURI(watch, "video/mp4"))
+			continue
+		}
+		if videoLinkMIME(link, "") == "" {
+			continue
+		}
+		part, err := a.videoLinkPart(ctx, link)
+		if err != nil {
+			logFrom(ctx).Warn("read video link failed", "err", err)
+			continue
+		}
+		parts = append(parts, part)
+	}
+	return parts
+}
+
+// messageLinks returns the links in the text or caption of msg, both the
+// written ones and those behind text, without duplicates.
+func messageLinks(msg *tele.Message) []string {
+	text, entities := msg.Text, msg.Entities
+	if text == "" {
+		text, entities = msg.Caption, msg.CaptionEntities
+	}
+	var links []string
+	seen := make(map[string]bool)
+	add := func(link string) {
+		if link != "" && !seen[link] {
+			seen[link] = true
+			links = append(links, link)
+		}
+	}
+	for _, entity := range entities {
+		switch entity.Type {
+		case tele.EntityTextLink:
+			add(entity.URL)
+		case tele.EntityURL:
+			link := msg.EntityText(entity)
+			if !strings.Contains(link, "://") {
+				link = "https://" + link
+			}
+			add(link)
+		}
+	}
+	for _, link := range replyLinkPattern.FindAllString(text, -1) {
+		add(strings.TrimRight(link, ".,;:!?"))
+	}
+	return links
+}
+
+// youtubeWatchURL returns the canonical watch URL of a YouTube video link:
+// watch pages, youtu.be links, shorts, live streams and embeds.
+func youtubeWatchURL(link string) (string, bool) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", false
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	var id string
+	switch host {
+	case "youtu.be":
+		id = strings.Trim(u.Path, "/")
+	case "youtube.com", "m.youtube.com", "music.youtube.com", "youtube-nocookie.com":
+		if u.Path == "/watch" {
+			id = u.Query().Get("v")
+			break
+		}
+		for _, prefix := range []string{"/shorts/", "/live/", "/embed/", "/v/"} {
+			if rest, ok := strings.CutPrefix(u.Path, prefix); ok {
+				id, _, _ = strings.Cut(rest, "/")
+			}
+		}
+	}
+	if !youtubeIDPattern.MatchString(id) {
+		return "", false
+	}
+	return "https://www.youtube.com/watch?v=" + id, true
+}
+
+// videoLinkMIME returns the video type of link from the server's content type
+// or the file extension, or "" when it is no video.
+func videoLinkMIME(link, contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && strings.HasPrefix(mediaType, "video/") {
+		return mediaType
+	}
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return ""
+	}
+	return videoExtensions[strings.ToLower(path.Ext(u.Path))]
+}
+
+// videoLinkPart downloads the video at link, inlining it when it is small
+// and uploading it to the Files API otherwise.
+func (a *App) videoLinkPart(ctx context.Context, link string) (*genai.Part, error) {
+	sum := sha256.Sum256([]byte(link))
+	name := "link-" + hex.EncodeToString(sum[:8])
+	key := "url|" + link
+	if part, ok := a.media.get(key); ok {
+		return part, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+	if a.geo.userAgent != "" {
+		req.Header.Set("User-Agent", a.geo.userAgent)
+	}
+	resp, err := videoLinkHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download video: %s", resp.Status)
+	}
+	mimeType := videoLinkMIME(link, resp.Header.Get("Content-Type"))
+	if mimeType == "" {
+		return nil, fmt.Errorf("download video: %s is no video", resp.Header.Get("Content-Type"))
+	}
+	if resp.ContentLength > videoLinkMaxBytes {
+		return nil, fmt.Errorf("download video: %d bytes is over the limit", resp.ContentLength)
+	}
+	body := http.MaxBytesReader(nil, resp.Body, videoLinkMaxBytes)
+
+	inline := a.inlineMediaLimit
+	if inline <= 0 {
+		inline = videoLinkMaxBytes
+	}
+	head, err := io.ReadAll(io.LimitReader(body, inline+1))
+	if err != nil {
+		return nil, fmt.Errorf("download video: %w", err)
+	}
+	if len(head) == 0 {
+		return nil, errors.New("download video: empty body")
+	}
+	var part *genai.Part
+	if int64(len(head)) <= inline {
+		part = &genai.Part{InlineData: &genai.Blob{Data: head, MIMEType: mimeType}}
+	} else {
+		file := &tele.File{UniqueID: name, FileURL: link}
+		if part, err = a.uploadPart(ctx, io.MultiReader(bytes.NewReader(head), body), file, mimeType); err != nil {
+			return nil, err
+		}
+	}
+	if !isPrivate(ctx) {
+		a.media.put(key, part)
+	}
+	return part, nil
+}
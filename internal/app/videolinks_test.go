@@ -0,0 +1,69 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestYoutubeWatchURL(t *testing.T) {
+	for link, want := range map[string]string{
+		"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42": "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		"https://youtu.be/dQw4w9WgXcQ?si=abc":              "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		"https://m.youtube.com/shorts/dQw4w9WgXcQ":         "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		"https://youtube.com/live/dQw4w9WgXcQ/extra":       "https://www.youtube.com/watch?v=dQw4w9WgXcQ",
+		"https://www.youtube.com/@somechannel":             "",
+		"https://example.com/watch?v=dQw4w9WgXcQ":          "",
+	} {
+		got, ok := youtubeWatchURL(link)
+		if got != want || ok != (want != "") {
+			t.Errorf("youtubeWatchURL(%q) = %q, %v, want %q", link, got, ok, want)
+		}
+	}
+}
+
+func TestVideoLinksBecomeVideoParts(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.handle("videos.example.com", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "video/webm")
+		w.Write([]byte("\x1aE\xdf\xa3webm"))
+	}))
+
+	msg := testMessage(42, "Summarize https://youtu.be/dQw4w9WgXcQ and compare it with https://videos.example.com/talk.webm, see https://example.com/notes")
+	if err := app.processMessage(context.Background(), msg, turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+
+	calls := apis.callsTo(geminiHost, "gemini-2.5-pro:generateContent")
+	if len(calls) != 1 {
+		t.Fatalf("generateContent called %d times, want once", len(calls))
+	}
+	body := string(calls[0].body)
+	if !strings.Contains(body, `"fileUri":"https://www.youtube.com/watch?v=dQw4w9WgXcQ"`) {
+		t.Errorf("request misses the YouTube video: %s", body)
+	}
+	if !strings.Contains(body, `"mimeType":"video/webm"`) {
+		t.Errorf("request misses the downloaded video: %s", body)
+	}
+	if got := len(apis.callsTo("videos.example.com", "/talk.webm")); got != 1 {
+		t.Errorf("downloaded the video %d times, want once", got)
+	}
+}
+
+func TestVideoLinkRefusesLocalServers(t *testing.T) {
+	app, _ := newTestApp(t, Config{})
+	videoLinkHTTPClient.Transport = publicTransport()
+	reached := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		w.Header().Set("Content-Type", "video/mp4")
+		w.Write([]byte("video"))
+	}))
+	defer server.Close()
+
+	if _, err := app.videoLinkPart(context.Background(), server.URL+"/clip.mp4"); err == nil || !strings.Contains(err.Error(), "refusing") || reached {
+		t.Fatalf("videoLinkPart on %s: err = %v, reached = %v, want the connection refused", server.URL, err, reached)
+	}
+}
@@ -0,0 +1,421 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"google.golang.org/genai"
+)
+
+const (
+	// pageFetchLimit bounds the pages fetched for one request.
+	pageFetchLimit   = 3
+	pageMaxBytes     = 2 << 20
+	pageMaxChars     = 20000
+	pageFetchTimeout = 15 * time.Second
+	// pageMinReadable is the paragraph text below which the best block of a
+	// page is taken for navigation and the whole body is read instead.
+	pageMinReadable = 200
+	robotsTTL       = time.Hour
+	robotsMaxBytes  = 512 << 10
+)
+
+// pageHTTPClient fetches the pages and robots.txt files of links in messages.
+var pageHTTPClient = publicHTTPClient(pageFetchTimeout)
+
+var errRobotsDisallow = errors.New("disallowed by robots.txt")
+
+// pageSkipElements hold navigation, scripts and forms rather than content.
+var pageSkipElements = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "svg": true, "iframe": true,
+	"nav": true, "header": true, "footer": true, "aside": true, "form": true, "button": true, "head": true,
+}
+
+var pageBlockElements = map[string]bool{
+	"p": true, "div": true, "br": true, "li": true, "tr": true, "section": true, "article": true, "main": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "blockquote": true, "pre": true,
+	"figcaption": true, "dt": true, "dd": true, "table": true, "ul": true, "ol": true,
+}
+
+// failedURLs returns the links the URL context tool could not read, because
+// of an error or a paywall. Unsafe links are left alone.
+func failedURLs(resp *genai.GenerateContentResponse) []string {
+	var links []string
+	if resp == nil {
+		return nil
+	}
+	for _, cand := range resp.Candidates {
+		if cand == nil || cand.URLContextMetadata == nil {
+			continue
+		}
+		for _, m := range cand.URLContextMetadata.URLMetadata {
+			if m == nil || m.RetrievedURL == "" || slices.Contains(links, m.RetrievedURL) {
+				continue
+			}
+			switch m.URLRetrievalStatus {
+			case genai.URLRetrievalStatusError, genai.URLRetrievalStatusPaywall:
+				links = append(links, m.RetrievedURL)
+			}
+		}
+	}
+	return links
+}
+
+// fetchFailedPages downloads the pages the URL context tool could not read
+// and returns their readable text as parts for the prompt, so the model can
+// answer from them on a second try. It honours robots.txt and sends the
+// tools' user agent.
+func (a *App) fetchFailedPages(ctx context.Context, resp *genai.GenerateContentResponse) []*genai.Part {
+	var parts []*genai.Part
+	for _, link := range failedURLs(resp) {
+		if len(parts) == pageFetchLimit {
+			break
+		}
+		title, text, err := a.fetchPage(ctx, link)
+		if err != nil {
+			logFrom(ctx).Info("fetch page failed", "url", link, "err", err)
+			continue
+		}
+		header := fmt.Sprintf("Text of %s, fetched by the bot because the URL context tool could not read it:", link)
+		if title != "" {
+			header += "\nTitle: " + title
+		}
+		parts = append(parts, genai.NewPartFromText(header+"\n\n"+text))
+	}
+	return parts
+}
+
+// fetchPage downloads link and returns the title and readable text of the
+// page.
+func (a *App) fetchPage(ctx context.Context, link string) (string, string, error) {
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", "", fmt.Errorf("not a web link")
+	}
+	if !a.robots.allows(ctx, u, a.geo.userAgent) {
+		return "", "", errRobotsDisallow
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("User-Agent", a.geo.userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain;q=0.9")
+	resp, err := pageHTTPClient.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("status %s", resp.Status)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	body, err := charset.NewReader(io.LimitReader(resp.Body, pageMaxBytes), contentType)
+	if err != nil {
+		return "", "", err
+	}
+
+	var title, text string
+	switch mediaType {
+	case "text/html", "application/xhtml+xml", "":
+		doc, err := html.Parse(body)
+		if err != nil {
+			return "", "", err
+		}
+		title, text = readablePage(doc)
+	case "text/plain", "text/markdown":
+		raw, err := io.ReadAll(body)
+		if err != nil {
+			return "", "", err
+		}
+		text = strings.TrimSpace(string(raw))
+	default:
+		return "", "", fmt.Errorf("unsupported content type %s", mediaType)
+	}
+	if text == "" {
+		return "", "", errors.New("no readable text")
+	}
+	if runes := []rune(text); len(runes) > pageMaxChars {
+		text = string(runes[:pageMaxChars]) + "\n[…]"
+	}
+	return title, text, nil
+}
+
+// readablePage finds the main text of a page the way readability tools do:
+// the element whose paragraphs hold the most text, or the article, main or
+// body when no element stands out. Navigation, scripts and forms are left
+// out.
+func readablePage(doc *html.Node) (string, string) {
+	var (
+		title    string
+		best     *html.Node
+		bestSize int
+		fallback *html.Node
+	)
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if pageSkipElements[n.Data] {
+				if n.Data == "head" {
+					title = strings.Join(strings.Fields(nodeText(findElement(n, "title"))), " ")
+				}
+				return
+			}
+			switch n.Data {
+			case "article", "main":
+				if fallback == nil || fallback.Data == "body" {
+					fallback = n
+				}
+			case "body":
+				if fallback == nil {
+					fallback = n
+				}
+			}
+			size := 0
+			for c := n.FirstChild; c != nil; c = c.NextSibling {
+				if c.Type == html.ElementNode && (c.Data == "p" || c.Data == "pre" || c.Data == "blockquote") {
+					size += len(strings.TrimSpace(nodeText(c)))
+				}
+			}
+			if size > bestSize {
+				best, bestSize = n, size
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(doc)
+
+	root := best
+	if bestSize < pageMinReadable && fallback != nil {
+		root = fallback
+	}
+	if root == nil {
+		return title, ""
+	}
+	var b strings.Builder
+	writeReadable(&b, root)
+	var lines []string
+	for line := range strings.SplitSeq(b.String(), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return title, strings.Join(lines, "\n")
+}
+
+// writeReadable writes the text under n, a line per block.
+func writeReadable(b *strings.Builder, n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		b.WriteString(n.Data)
+		return
+	case html.ElementNode:
+		if pageSkipElements[n.Data] {
+			return
+		}
+		if pageBlockElements[n.Data] {
+			b.WriteString("\n")
+			defer b.WriteString("\n")
+		}
+		if n.Data == "li" {
+			b.WriteString("- ")
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		writeReadable(b, c)
+	}
+}
+
+func findElement(n *html.Node, name string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == name {
+		return n
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if found := findElement(c, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// nodeText returns the text under n.
+func nodeText(n *html.Node) string {
+	if n == nil {
+		return ""
+	}
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && pageSkipElements[c.Data] {
+			continue
+		}
+		b.WriteString(nodeText(c))
+	}
+	return b.String()
+}
+
+// robotsCache remembers the robots.txt rules of the hosts pages were fetched
+// from.
+type robotsCache struct {
+	mu    sync.Mutex
+	hosts map[string]robotsEntry
+}
+
+type robotsEntry struct {
+	rules   robotsRules
+	expires time.Time
+}
+
+func newRobotsCache() *robotsCache {
+	return &robotsCache{hosts: make(map[string]robotsEntry)}
+}
+
+// allows reports whether robots.txt lets userAgent fetch u. A missing file
+// allows everything; a server that fails to answer allows nothing.
+func (c *robotsCache) allows(ctx context.Context, u *url.URL, userAgent string) bool {
+	origin := u.Scheme + "://" + u.Host
+	c.mu.Lock()
+	entry, ok := c.hosts[origin]
+	c.mu.Unlock()
+	if !ok || time.Now().After(entry.expires) {
+		entry = robotsEntry{rules: fetchRobots(ctx, origin, userAgent), expires: time.Now().Add(robotsTTL)}
+		c.mu.Lock()
+		c.hosts[origin] = entry
+		c.mu.Unlock()
+	}
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return entry.rules.allows(path)
+}
+
+func fetchRobots(ctx context.Context, origin, userAgent string) robotsRules {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, origin+"/robots.txt", nil)
+	if err != nil {
+		return robotsRules{disallowAll: true}
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := pageHTTPClient.Do(req)
+	if err != nil {
+		return robotsRules{disallowAll: true}
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500:
+		return robotsRules{disallowAll: true}
+	case resp.StatusCode != http.StatusOK:
+		return robotsRules{}
+	}
+	return parseRobots(io.LimitReader(resp.Body, robotsMaxBytes), userAgent)
+}
+
+// robotsRules are the Allow and Disallow lines of the robots.txt group that
+// applies to the bot.
+type robotsRules struct {
+	disallowAll bool
+	rules       []robotsRule
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// parseRobots reads the group of robots.txt naming the product token of
+// userAgent, or the * group when none does.
+func parseRobots(r io.Reader, userAgent string) robotsRules {
+	token, _, _ := strings.Cut(strings.ToLower(userAgent), "/")
+	token = strings.TrimSpace(token)
+	var (
+		named, wildcard []robotsRule
+		foundNamed      bool
+		agents          []string
+		inRules         bool
+	)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if inRules {
+				agents, inRules = nil, false
+			}
+			agents = append(agents, strings.ToLower(value))
+		case "allow", "disallow":
+			inRules = true
+			if value == "" {
+				continue
+			}
+			rule := robotsRule{allow: key == "allow", pattern: value}
+			for _, agent := range agents {
+				switch {
+				case agent == "*":
+					wildcard = append(wildcard, rule)
+				case token != "" && strings.Contains(token, agent):
+					named = append(named, rule)
+					foundNamed = true
+				}
+			}
+		}
+	}
+	if foundNamed {
+		return robotsRules{rules: named}
+	}
+	return robotsRules{rules: wildcard}
+}
+
+// allows applies the longest matching rule, Allow winning a tie.
+func (r robotsRules) allows(path string) bool {
+	if r.disallowAll {
+		return false
+	}
+	allowed, longest := true, -1
+	for _, rule := range r.rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if n := len(rule.pattern); n > longest || (n == longest && rule.allow) {
+			allowed, longest = rule.allow, n
+		}
+	}
+	return allowed
+}
+
+// robotsMatch matches path against a robots.txt pattern, where * stands for
+// any characters and a final $ anchors the end.
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	expr := strings.ReplaceAll(regexp.QuoteMeta(strings.TrimSuffix(pattern, "$")), `\*`, ".*")
+	if anchored {
+		expr += "$"
+	}
+	re, err := regexp.Compile("^" + expr)
+	return err == nil && re.MatchString(path)
+}
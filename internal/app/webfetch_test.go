@@ -0,0 +1,136 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+func TestReadablePage(t *testing.T) {
+	page := `<html><head><title> The  Title </title><style>p{}</style></head><body>
+<nav><a href="/">Home</a> <a href="/about">About</a></nav>
+<div class="content"><h1>Heading</h1>
+<p>` + strings.Repeat("The first paragraph is long enough to be the content. ", 4) + `</p>
+<p>Second <b>bold</b> paragraph.</p><ul><li>one</li><li>two</li></ul>
+<script>alert("x")</script></div>
+<footer><p>Copyright notice</p></footer></body></html>`
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	title, text := readablePage(doc)
+	if title != "The Title" {
+		t.Errorf("title = %q", title)
+	}
+	for _, want := range []string{"Heading", "The first paragraph", "Second bold paragraph.", "- one\n- two"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text misses %q:\n%s", want, text)
+		}
+	}
+	for _, unwanted := range []string{"Home", "alert", "Copyright"} {
+		if strings.Contains(text, unwanted) {
+			t.Errorf("text keeps %q:\n%s", unwanted, text)
+		}
+	}
+}
+
+func TestParseRobots(t *testing.T) {
+	robots := `User-agent: *
+Disallow: /
+
+User-agent: otherbot
+User-agent: eteonbot
+Disallow: /private
+Allow: /private/open$
+Disallow: /*.pdf$
+`
+	rules := parseRobots(strings.NewReader(robots), "eteonbot/1.0")
+	for path, want := range map[string]bool{
+		"/":                 true,
+		"/news/today":       true,
+		"/private/page":     false,
+		"/private/open":     true,
+		"/private/open/sub": false,
+		"/docs/paper.pdf":   false,
+		"/docs/paper.pdfx":  true,
+	} {
+		if got := rules.allows(path); got != want {
+			t.Errorf("allows(%q) = %v, want %v", path, got, want)
+		}
+	}
+	if parseRobots(strings.NewReader(robots), "somebot/2.0").allows("/news") {
+		t.Error("the * group does not apply to other agents")
+	}
+}
+
+func TestFailedURLContextFallsBackToFetchedPage(t *testing.T) {
+	app, apis := newTestApp(t, Config{})
+	apis.handle("blocked.example.com", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.Write([]byte("User-agent: *\nDisallow: /secret\n"))
+			return
+		}
+		if ua := r.Header.Get("User-Agent"); ua != defaultToolsUserAgent {
+			t.Errorf("fetched with user agent %q", ua)
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(`<html><head><title>Launch</title></head><body><article><p>The rocket launches on Friday at noon.</p></article></body></html>`))
+	}))
+	apis.metadata = func(body []byte) map[string]any {
+		if strings.Contains(string(body), "fetched by the bot") {
+			return nil
+		}
+		return map[string]any{"urlContextMetadata": map[string]any{"urlMetadata": []any{
+			map[string]any{"retrievedUrl": "https://blocked.example.com/news", "urlRetrievalStatus": "URL_RETRIEVAL_STATUS_ERROR"},
+			map[string]any{"retrievedUrl": "https://blocked.example.com/secret", "urlRetrievalStatus": "URL_RETRIEVAL_STATUS_ERROR"},
+		}}}
+	}
+
+	msg := testMessage(42, "When is the launch in https://blocked.example.com/news and https://blocked.example.com/secret?")
+	if err := app.processMessage(context.Background(), msg, turnOptions{}); err != nil {
+		t.Fatalf("processMessage: %v", err)
+	}
+
+	calls := apis.callsTo(geminiHost, "gemini-2.5-pro:generateContent")
+	if len(calls) != 2 {
+		t.Fatalf("generateContent called %d times, want a retry with the page", len(calls))
+	}
+	if body := string(calls[1].body); !strings.Contains(body, "The rocket launches on Friday at noon.") || !strings.Contains(body, "Title: Launch") {
+		t.Errorf("retry misses the fetched page: %s", body)
+	}
+	if got := len(apis.callsTo("blocked.example.com", "/secret")); got != 0 {
+		t.Error("fetched a page robots.txt disallows")
+	}
+	if got := len(apis.callsTo("blocked.example.com", "/robots.txt")); got != 1 {
+		t.Errorf("fetched robots.txt %d times, want once", got)
+	}
+
+	session := app.sessions.get(42)
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if prompt := session.history[0]; len(prompt.Parts) < 2 || !strings.Contains(prompt.Parts[len(prompt.Parts)-1].Text, "rocket") {
+		t.Error("the fetched page is not kept with the prompt")
+	}
+}
+
+func TestFetchPageRefusesLocalServers(t *testing.T) {
+	app, _ := newTestApp(t, Config{})
+	pageHTTPClient.Transport = publicTransport()
+	reached := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		w.Write([]byte("<html><body><p>internal</p></body></html>"))
+	}))
+	defer server.Close()
+
+	if _, _, err := app.fetchPage(context.Background(), server.URL+"/admin"); err == nil || reached {
+		t.Fatalf("fetchPage on %s: err = %v, reached = %v, want the page refused", server.URL, err, reached)
+	}
+	if _, err := pageHTTPClient.Get(server.URL + "/admin"); err == nil || !strings.Contains(err.Error(), "refusing") || reached {
+		t.Fatalf("get %s: err = %v, reached = %v, want the connection refused", server.URL, err, reached)
+	}
+}